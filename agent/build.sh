@@ -45,9 +45,33 @@ for TARGET in ${TARGETS}; do
         -o "${MULTI_ARCH_DIR}/bin/${BINARY_NAME}" \
         ./cmd/main.go)
 
+    # 离线签名：UPGRADE_SIGNING_KEY 指向发布环境保存的 ed25519 私钥（PEM），
+    # 服务端与Agent只配置对应公钥，缺少 .sig 的安装包无法远程升级。
+    # 签名对象为升级清单（版本、平台、SHA-256），格式须与 pkg/agentproto.UpgradeManifest 一致，
+    # 旧版本的已签名二进制无法被重新打包冒充新版本
+    if [ -n "${UPGRADE_SIGNING_KEY}" ]; then
+        SHA256=$(openssl dgst -sha256 -r "${MULTI_ARCH_DIR}/bin/${BINARY_NAME}" | cut -d' ' -f1)
+        MANIFEST=$(mktemp)
+        printf 'srehub-agent-upgrade\nversion=%s\nos=%s\narch=%s\nsha256=%s\n' \
+            "${VERSION#v}" "${OS}" "${ARCH}" "${SHA256}" > "${MANIFEST}"
+        openssl pkeyutl -sign -rawin -inkey "${UPGRADE_SIGNING_KEY}" \
+            -in "${MANIFEST}" \
+            -out "${MULTI_ARCH_DIR}/bin/${BINARY_NAME}.sig"
+        rm -f "${MANIFEST}"
+        echo "  已签名: bin/${BINARY_NAME}.sig"
+    fi
+
     echo "  完成: bin/${BINARY_NAME}"
 done
 
+if [ -z "${UPGRADE_SIGNING_KEY}" ]; then
+    echo ""
+    echo ">>> 警告: 未设置 UPGRADE_SIGNING_KEY，安装包不含发布签名，无法用于远程升级"
+    echo "    生成签名密钥（仅保存在离线发布环境）: openssl genpkey -algorithm ed25519 -out upgrade-key.pem"
+    echo "    导出公钥（填入服务端 agent.upgrade_public_key）:"
+    echo "    openssl pkey -in upgrade-key.pem -pubout -outform DER | tail -c 32 | base64"
+fi
+
 # 复制配置模板到多架构目录
 cp "${AGENT_DIR}/config/agent.yaml" "${MULTI_ARCH_DIR}/agent.yaml"
 cp "${AGENT_DIR}/config/srehub-agent.service" "${MULTI_ARCH_DIR}/srehub-agent.service"
@@ -430,7 +454,7 @@ echo "  构建完成！输出目录: ${BUILD_DIR}"
 echo "========================================="
 echo "多架构安装包: ${PACKAGE_NAME}.tar.gz"
 echo "包含平台:"
-ls -1 "${MULTI_ARCH_DIR}/bin/" | sed 's/srehub-agent-/  - /'
+ls -1 "${MULTI_ARCH_DIR}/bin/" | grep -v '\.sig$' | sed 's/srehub-agent-/  - /'
 echo ""
 echo "已复制到: ${DATA_DIR}/"
 
@@ -8,17 +8,26 @@ import (
 	"syscall"
 
 	"github.com/spf13/cobra"
+	"github.com/ydcloud-dy/opshub/agent/internal/certrenew"
 	"github.com/ydcloud-dy/opshub/agent/internal/client"
+	"github.com/ydcloud-dy/opshub/agent/internal/collector"
 	"github.com/ydcloud-dy/opshub/agent/internal/config"
+	"github.com/ydcloud-dy/opshub/agent/internal/discovery"
 	"github.com/ydcloud-dy/opshub/agent/internal/executor"
 	"github.com/ydcloud-dy/opshub/agent/internal/filemanager"
 	"github.com/ydcloud-dy/opshub/agent/internal/logger"
+	"github.com/ydcloud-dy/opshub/agent/internal/policy"
+	"github.com/ydcloud-dy/opshub/agent/internal/portforward"
 	"github.com/ydcloud-dy/opshub/agent/internal/prober"
 	"github.com/ydcloud-dy/opshub/agent/internal/terminal"
+	"github.com/ydcloud-dy/opshub/agent/internal/upgrader"
 )
 
 var configFile string
 
+// Version 版本号，构建时通过 -ldflags "-X main.Version=..." 注入
+var Version = "1.0.0"
+
 var rootCmd = &cobra.Command{
 	Use:   "srehub-agent",
 	Short: "SREHub Agent - 运维管理Agent",
@@ -44,12 +53,21 @@ var runCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		logger.Info("SREHub Agent 启动中...")
+		logger.Info("SREHub Agent %s 启动中...", Version)
 		logger.Info("AgentID: %s", cfg.AgentID)
 		logger.Info("Server: %s", cfg.ServerAddr)
 		logger.Info("日志文件: %s (最大: %dMB, 保留: %d个备份, 最大 %d 天)", logFile, cfg.LogMaxSize, cfg.LogMaxBackups, cfg.LogMaxAge)
 
 		grpcClient := client.NewGRPCClient(cfg)
+		grpcClient.SetVersion(Version)
+
+		// 检查未确认的升级，必要时回滚
+		if upg, err := upgrader.New(cfg, Version); err != nil {
+			logger.Warn("远程升级不可用: %v", err)
+		} else {
+			upg.Boot()
+			grpcClient.SetUpgradeHandler(upg)
+		}
 
 		// 初始化处理器
 		ptyMgr := terminal.NewPTYManager(grpcClient)
@@ -61,6 +79,22 @@ var runCmd = &cobra.Command{
 		grpcClient.SetHandlers(ptyMgr, fileMgr, cmdExec)
 		grpcClient.SetProbeHandler(probeMgr)
 		grpcClient.SetWsSessionHandler(wsSessionMgr)
+		grpcClient.SetMetricsCollector(collector.New(cfg.TopProcesses))
+		grpcClient.SetDiscoveryHandler(discovery.New())
+		grpcClient.SetTunnelHandler(portforward.NewManager(grpcClient))
+		grpcClient.SetCertRenewer(certrenew.New(cfg))
+
+		// 本地安全策略
+		localPolicy, err := policy.New(cfg.Policy)
+		if err != nil {
+			fmt.Printf("加载本地策略失败: %v\n", err)
+			os.Exit(1)
+		}
+		if localPolicy.Enabled() {
+			grpcClient.SetPolicy(localPolicy)
+			logger.Info("本地安全策略已启用: 允许命令 %d 条, 禁止路径 %d 条, 禁用终端=%v, 只读=%v",
+				len(cfg.Policy.AllowedCommands), len(cfg.Policy.ForbiddenPaths), cfg.Policy.DisableTerminal, cfg.Policy.ReadOnly)
+		}
 
 		logger.Info("拨测功能已启用")
 		logger.Info("WebSocket 会话管理已启用")
@@ -92,7 +126,7 @@ var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "显示版本信息",
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("srehub-agent v1.0.0")
+		fmt.Println("srehub-agent v" + Version)
 	},
 }
 
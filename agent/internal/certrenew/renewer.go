@@ -0,0 +1,119 @@
+package certrenew
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ydcloud-dy/opshub/agent/internal/config"
+	"github.com/ydcloud-dy/opshub/agent/internal/logger"
+)
+
+// Renewer 证书续期：本地生成新私钥和证书签名请求，收到服务端签发的证书后替换证书文件，
+// 私钥始终不离开本机
+type Renewer struct {
+	certDir string
+	agentID string
+
+	mu      sync.Mutex
+	pending map[string]*ecdsa.PrivateKey // requestID -> 新私钥
+}
+
+// New 创建证书续期器
+func New(cfg *config.Config) *Renewer {
+	return &Renewer{certDir: cfg.CertDir, agentID: cfg.AgentID, pending: make(map[string]*ecdsa.PrivateKey)}
+}
+
+// CreateCSR 生成新私钥并返回证书签名请求（PEM），私钥保存在内存中等待证书下发
+func (r *Renewer) CreateCSR(requestID string) ([]byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("生成私钥失败: %w", err)
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: r.agentID},
+	}, key)
+	if err != nil {
+		return nil, fmt.Errorf("生成证书签名请求失败: %w", err)
+	}
+
+	r.mu.Lock()
+	// 只保留最近一次续期的私钥
+	r.pending = map[string]*ecdsa.PrivateKey{requestID: key}
+	r.mu.Unlock()
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}), nil
+}
+
+// Install 校验新证书与私钥匹配且由 CA 签发后写入证书目录，下次连接时生效
+func (r *Renewer) Install(requestID string, certPEM, caPEM []byte) error {
+	r.mu.Lock()
+	key, ok := r.pending[requestID]
+	delete(r.pending, requestID)
+	r.mu.Unlock()
+	if !ok {
+		return errors.New("没有对应的续期请求")
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("序列化私钥失败: %w", err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return fmt.Errorf("新证书与私钥不匹配: %w", err)
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("解析新证书失败: %w", err)
+	}
+	if cert.Subject.CommonName != r.agentID {
+		return fmt.Errorf("新证书身份 %s 与本机AgentID不一致", cert.Subject.CommonName)
+	}
+
+	if len(caPEM) == 0 {
+		if caPEM, err = os.ReadFile(filepath.Join(r.certDir, "ca.pem")); err != nil {
+			return fmt.Errorf("读取CA证书失败: %w", err)
+		}
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caPEM) {
+		return errors.New("解析CA证书失败")
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}); err != nil {
+		return fmt.Errorf("新证书校验失败: %w", err)
+	}
+
+	// 先全部写入临时文件再依次替换，避免写到一半时证书和私钥不配套
+	files := []struct {
+		name string
+		data []byte
+		perm os.FileMode
+	}{
+		{"ca.pem", caPEM, 0644},
+		{"key.pem", keyPEM, 0600},
+		{"cert.pem", certPEM, 0644},
+	}
+	for _, f := range files {
+		if err := os.WriteFile(filepath.Join(r.certDir, f.name+".new"), f.data, f.perm); err != nil {
+			return fmt.Errorf("写入 %s 失败: %w", f.name, err)
+		}
+	}
+	for _, f := range files {
+		path := filepath.Join(r.certDir, f.name)
+		if err := os.Rename(path+".new", path); err != nil {
+			return fmt.Errorf("替换 %s 失败: %w", f.name, err)
+		}
+	}
+	logger.Info("证书已续期，有效期至 %s", cert.NotAfter.Format("2006-01-02 15:04:05"))
+	return nil
+}
@@ -17,7 +17,9 @@ import (
 	"time"
 
 	"github.com/ydcloud-dy/opshub/agent/internal/config"
+	"github.com/ydcloud-dy/opshub/agent/internal/discovery"
 	"github.com/ydcloud-dy/opshub/agent/internal/logger"
+	"github.com/ydcloud-dy/opshub/agent/internal/policy"
 	pb "github.com/ydcloud-dy/opshub/pkg/agentproto"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
@@ -33,12 +35,14 @@ type TerminalHandler interface {
 
 // FileHandler 文件操作回调
 type FileHandler interface {
-	HandleRequest(requestID, action, path, filename string, data []byte) (*pb.AgentMessage, error)
+	HandleRequest(req *pb.FileRequest) (*pb.AgentMessage, error)
 }
 
 // CommandHandler 命令执行回调
 type CommandHandler interface {
 	Execute(requestID, command string, timeout int32) *pb.AgentMessage
+	ExecuteStream(req *pb.CommandRequest, send func(*pb.AgentMessage) error)
+	Cancel(requestID string) bool
 }
 
 // ProbeHandler 拨测回调
@@ -54,6 +58,48 @@ type WsSessionHandler interface {
 	CloseSession(sessionID string) error
 }
 
+// UpgradeHandler 自升级回调
+type UpgradeHandler interface {
+	Upgrade(req *pb.AgentUpgrade) error
+	Restart()
+	PendingUpgrade() (upgradeID string, rolledBack bool, errMsg string)
+	Confirm()
+}
+
+// MetricsCollector 心跳指标采集回调
+type MetricsCollector interface {
+	Collect() *pb.HeartbeatRequest
+}
+
+// DiscoveryHandler 本机服务发现回调
+type DiscoveryHandler interface {
+	Discover() []*pb.DiscoveredService
+}
+
+// TunnelHandler TCP 隧道回调，除 Open 外均在消息接收协程中按序调用，不能阻塞
+type TunnelHandler interface {
+	Open(req *pb.TunnelOpen)
+	Data(connID string, data []byte)
+	Ack(connID string, n int)
+	Close(connID, reason string)
+	CloseAll()
+}
+
+// CertRenewer 证书续期回调
+type CertRenewer interface {
+	CreateCSR(requestID string) ([]byte, error)
+	Install(requestID string, certPEM, caPEM []byte) error
+}
+
+// PolicyChecker 本地安全策略，违反策略的请求在本地拒绝
+type PolicyChecker interface {
+	CheckCommand(command string) error
+	CheckFile(action, path, filename string) error
+	CheckTerminal() error
+	CheckTunnel(target string) error
+	CheckUpgrade(version string) error
+}
+
 // GRPCClient Agent gRPC客户端
 type GRPCClient struct {
 	cfg               *config.Config
@@ -65,6 +111,14 @@ type GRPCClient struct {
 	cmdHandler        CommandHandler
 	probeHandler      ProbeHandler
 	wsSessionHandler  WsSessionHandler
+	upgradeHandler    UpgradeHandler
+	metricsCollector  MetricsCollector
+	discoveryHandler  DiscoveryHandler
+	discoveryTrigger  chan struct{}
+	tunnelHandler     TunnelHandler
+	certRenewer       CertRenewer
+	policy            PolicyChecker
+	version           string
 	heartbeatInterval int32
 	intervalMu        sync.RWMutex
 	heartbeatCancel   context.CancelFunc
@@ -73,7 +127,7 @@ type GRPCClient struct {
 
 // NewGRPCClient 创建gRPC客户端
 func NewGRPCClient(cfg *config.Config) *GRPCClient {
-	return &GRPCClient{cfg: cfg, heartbeatInterval: 30}
+	return &GRPCClient{cfg: cfg, heartbeatInterval: 30, version: "1.0.0", discoveryTrigger: make(chan struct{}, 1)}
 }
 
 // SetHandlers 设置处理器
@@ -93,6 +147,41 @@ func (c *GRPCClient) SetWsSessionHandler(wsSession WsSessionHandler) {
 	c.wsSessionHandler = wsSession
 }
 
+// SetUpgradeHandler 设置自升级处理器
+func (c *GRPCClient) SetUpgradeHandler(upgrade UpgradeHandler) {
+	c.upgradeHandler = upgrade
+}
+
+// SetMetricsCollector 设置心跳指标采集器
+func (c *GRPCClient) SetMetricsCollector(collector MetricsCollector) {
+	c.metricsCollector = collector
+}
+
+// SetDiscoveryHandler 设置服务发现处理器
+func (c *GRPCClient) SetDiscoveryHandler(discovery DiscoveryHandler) {
+	c.discoveryHandler = discovery
+}
+
+// SetTunnelHandler 设置 TCP 隧道处理器
+func (c *GRPCClient) SetTunnelHandler(tunnel TunnelHandler) {
+	c.tunnelHandler = tunnel
+}
+
+// SetCertRenewer 设置证书续期处理器
+func (c *GRPCClient) SetCertRenewer(renewer CertRenewer) {
+	c.certRenewer = renewer
+}
+
+// SetPolicy 设置本地安全策略
+func (c *GRPCClient) SetPolicy(p PolicyChecker) {
+	c.policy = p
+}
+
+// SetVersion 设置注册时上报的版本号
+func (c *GRPCClient) SetVersion(version string) {
+	c.version = version
+}
+
 // SendMessage 发送消息到服务端
 func (c *GRPCClient) SendMessage(msg *pb.AgentMessage) error {
 	c.mu.Lock()
@@ -136,7 +225,9 @@ func (c *GRPCClient) connectAndServe(ctx context.Context) error {
 		return fmt.Errorf("gRPC连接失败: %w", err)
 	}
 	defer conn.Close()
+	c.mu.Lock()
 	c.conn = conn
+	c.mu.Unlock()
 
 	client := pb.NewAgentHubClient(conn)
 	stream, err := client.Connect(ctx)
@@ -147,6 +238,10 @@ func (c *GRPCClient) connectAndServe(ctx context.Context) error {
 	c.mu.Lock()
 	c.stream = stream
 	c.mu.Unlock()
+	if c.tunnelHandler != nil {
+		// 隧道连接依附于当前流，断线后服务端侧已失效
+		defer c.tunnelHandler.CloseAll()
+	}
 
 	// 发送注册
 	hostname, _ := os.Hostname()
@@ -154,17 +249,20 @@ func (c *GRPCClient) connectAndServe(ctx context.Context) error {
 	logger.Info("发送注册请求 - AgentID: %s, Hostname: %s, OS: %s, Arch: %s",
 		c.cfg.AgentID, hostname, runtime.GOOS, runtime.GOARCH)
 
+	register := &pb.RegisterRequest{
+		AgentId:  c.cfg.AgentID,
+		Hostname: hostname,
+		Os:       runtime.GOOS,
+		Arch:     runtime.GOARCH,
+		Version:  c.version,
+		Ips:      ips,
+	}
+	// 升级后首次注册时上报升级结果
+	if c.upgradeHandler != nil {
+		register.UpgradeId, register.RolledBack, register.UpgradeError = c.upgradeHandler.PendingUpgrade()
+	}
 	c.SendMessage(&pb.AgentMessage{
-		Payload: &pb.AgentMessage_Register{
-			Register: &pb.RegisterRequest{
-				AgentId:  c.cfg.AgentID,
-				Hostname: hostname,
-				Os:       runtime.GOOS,
-				Arch:     runtime.GOARCH,
-				Version:  "1.0.0",
-				Ips:      ips,
-			},
-		},
+		Payload: &pb.AgentMessage_Register{Register: register},
 	})
 
 	// 停止旧的心跳循环（如果存在）
@@ -183,6 +281,9 @@ func (c *GRPCClient) connectAndServe(ctx context.Context) error {
 	c.intervalMu.RUnlock()
 	logger.Info("启动心跳循环，间隔: %d秒", interval)
 	go c.heartbeatLoop(heartbeatCtx)
+	if c.discoveryHandler != nil && c.cfg.DiscoveryInterval > 0 {
+		go c.discoveryLoop(heartbeatCtx)
+	}
 
 	// 接收消息循环
 	for {
@@ -191,6 +292,10 @@ func (c *GRPCClient) connectAndServe(ctx context.Context) error {
 			logger.Error("接收消息失败: %v", err)
 			return fmt.Errorf("接收消息失败: %w", err)
 		}
+		// 隧道数据必须按序处理，不能像其他消息一样分发到独立协程
+		if c.handleTunnelMessage(msg) {
+			continue
+		}
 		go c.handleServerMessage(msg)
 	}
 }
@@ -245,11 +350,14 @@ func (c *GRPCClient) heartbeatLoop(ctx context.Context) {
 			return
 		case <-ticker.C:
 			logger.Debug("发送心跳 - AgentID: %s", c.cfg.AgentID)
+			heartbeat := &pb.HeartbeatRequest{}
+			if c.metricsCollector != nil {
+				heartbeat = c.metricsCollector.Collect()
+			}
+			heartbeat.AgentId = c.cfg.AgentID
 			err := c.SendMessage(&pb.AgentMessage{
 				Payload: &pb.AgentMessage_Heartbeat{
-					Heartbeat: &pb.HeartbeatRequest{
-						AgentId: c.cfg.AgentID,
-					},
+					Heartbeat: heartbeat,
 				},
 			})
 			if err != nil {
@@ -259,6 +367,47 @@ func (c *GRPCClient) heartbeatLoop(ctx context.Context) {
 	}
 }
 
+// discoveryLoop 服务发现循环：连接后上报一次，之后按间隔扫描，服务有变化或服务端要求时才上报
+func (c *GRPCClient) discoveryLoop(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(c.cfg.DiscoveryInterval) * time.Second)
+	defer ticker.Stop()
+
+	// 等待服务端处理完注册再上报
+	first := time.NewTimer(10 * time.Second)
+	defer first.Stop()
+
+	var last string
+	force := true
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Debug("服务发现循环退出")
+			return
+		case <-first.C:
+		case <-ticker.C:
+		case <-c.discoveryTrigger:
+			force = true
+		}
+
+		services := c.discoveryHandler.Discover()
+		fingerprint := discovery.Fingerprint(services)
+		if !force && fingerprint == last {
+			continue
+		}
+		err := c.SendMessage(&pb.AgentMessage{
+			Payload: &pb.AgentMessage_ServiceDiscovery{
+				ServiceDiscovery: &pb.ServiceDiscovery{Services: services, Timestamp: time.Now().Unix()},
+			},
+		})
+		if err != nil {
+			logger.Error("上报服务发现结果失败: %v", err)
+			continue
+		}
+		logger.Debug("上报服务发现结果: %d 个服务", len(services))
+		last, force = fingerprint, false
+	}
+}
+
 // handleServerMessage 处理服务端消息
 func (c *GRPCClient) handleServerMessage(msg *pb.ServerMessage) {
 	switch payload := msg.Payload.(type) {
@@ -271,6 +420,9 @@ func (c *GRPCClient) handleServerMessage(msg *pb.ServerMessage) {
 				c.intervalMu.Unlock()
 				logger.Info("心跳间隔已更新为: %d 秒", payload.RegisterAck.HeartbeatInterval)
 			}
+			if c.upgradeHandler != nil {
+				c.upgradeHandler.Confirm()
+			}
 		} else {
 			logger.Warn("注册失败: %s", payload.RegisterAck.Message)
 		}
@@ -278,8 +430,25 @@ func (c *GRPCClient) handleServerMessage(msg *pb.ServerMessage) {
 	case *pb.ServerMessage_HeartbeatAck:
 		logger.Debug("收到心跳确认")
 
+	case *pb.ServerMessage_CertRenewRequest:
+		c.handleCertRenewRequest(payload.CertRenewRequest)
+
+	case *pb.ServerMessage_CertRenewResponse:
+		c.handleCertRenewResponse(payload.CertRenewResponse)
+
 	case *pb.ServerMessage_TermOpen:
 		if c.termHandler != nil {
+			if c.policy != nil {
+				if err := c.policy.CheckTerminal(); err != nil {
+					c.rejectByPolicy(payload.TermOpen.SessionId, err)
+					c.SendMessage(&pb.AgentMessage{
+						Payload: &pb.AgentMessage_TermOutput{
+							TermOutput: &pb.TerminalOutput{SessionId: payload.TermOpen.SessionId, Data: []byte(err.Error() + "\r\n")},
+						},
+					})
+					return
+				}
+			}
 			logger.Info("打开终端会话: sessionID=%s, cols=%d, rows=%d", payload.TermOpen.SessionId, payload.TermOpen.Cols, payload.TermOpen.Rows)
 			c.termHandler.Open(payload.TermOpen.SessionId, payload.TermOpen.Cols, payload.TermOpen.Rows)
 		}
@@ -302,9 +471,32 @@ func (c *GRPCClient) handleServerMessage(msg *pb.ServerMessage) {
 	case *pb.ServerMessage_FileRequest:
 		if c.fileHandler != nil {
 			req := payload.FileRequest
-			logger.Info("收到文件请求: action=%s, path=%s, filename=%s, requestID=%s", req.Action, req.Path, req.Filename, req.RequestId)
-			resp, err := c.fileHandler.HandleRequest(req.RequestId, req.Action, req.Path, req.Filename, req.Data)
+			if req.Action == "upload_chunk" || req.Action == "read" {
+				logger.Debug("收到文件分片请求: action=%s, path=%s, offset=%d, requestID=%s", req.Action, req.Path, req.Offset, req.RequestId)
+			} else {
+				logger.Info("收到文件请求: action=%s, path=%s, filename=%s, requestID=%s", req.Action, req.Path, req.Filename, req.RequestId)
+			}
+			var resp *pb.AgentMessage
+			var err error
+			if c.policy != nil {
+				err = c.policy.CheckFile(req.Action, req.Path, req.Filename)
+			}
 			if err != nil {
+				c.rejectByPolicy(req.RequestId, err)
+				if req.Action == "list" {
+					resp = &pb.AgentMessage{
+						Payload: &pb.AgentMessage_FileList{
+							FileList: &pb.FileListResult{RequestId: req.RequestId, Error: err.Error()},
+						},
+					}
+				} else {
+					resp = &pb.AgentMessage{
+						Payload: &pb.AgentMessage_FileChunk{
+							FileChunk: &pb.FileChunk{RequestId: req.RequestId, Error: err.Error()},
+						},
+					}
+				}
+			} else if resp, err = c.fileHandler.HandleRequest(req); err != nil {
 				logger.Error("文件操作失败: action=%s, path=%s, err=%v", req.Action, req.Path, err)
 				resp = &pb.AgentMessage{
 					Payload: &pb.AgentMessage_FileChunk{
@@ -317,14 +509,61 @@ func (c *GRPCClient) handleServerMessage(msg *pb.ServerMessage) {
 			c.SendMessage(resp)
 		}
 
+	case *pb.ServerMessage_AgentUpgrade:
+		req := payload.AgentUpgrade
+		logger.Info("收到升级请求: version=%s, requestID=%s", req.Version, req.RequestId)
+		err := fmt.Errorf("当前Agent不支持远程升级")
+		if c.upgradeHandler != nil {
+			err = nil
+			if c.policy != nil {
+				if err = c.policy.CheckUpgrade(req.Version); err != nil {
+					c.rejectByPolicy(req.RequestId, err)
+				}
+			}
+			if err == nil {
+				err = c.upgradeHandler.Upgrade(req)
+			}
+		}
+		result := &pb.AgentUpgradeResult{RequestId: req.RequestId, Success: err == nil}
+		if err != nil {
+			logger.Error("升级失败: %v", err)
+			result.Error = err.Error()
+		}
+		c.SendMessage(&pb.AgentMessage{
+			Payload: &pb.AgentMessage_UpgradeResult{UpgradeResult: result},
+		})
+		if err == nil {
+			// 等待升级结果发送完成后再切换进程
+			time.Sleep(time.Second)
+			c.upgradeHandler.Restart()
+		}
+
 	case *pb.ServerMessage_CmdRequest:
 		if c.cmdHandler != nil {
-			logger.Info("收到命令请求: requestID=%s, command=%s", payload.CmdRequest.RequestId, payload.CmdRequest.Command)
+			logger.Info("收到命令请求: requestID=%s, command=%s, stream=%v", payload.CmdRequest.RequestId, payload.CmdRequest.Command, payload.CmdRequest.Stream)
+			if c.policy != nil {
+				if err := c.policy.CheckCommand(payload.CmdRequest.Command); err != nil {
+					c.rejectByPolicy(payload.CmdRequest.RequestId, err)
+					c.sendCommandRejected(payload.CmdRequest, err)
+					return
+				}
+			}
+			if payload.CmdRequest.Stream {
+				c.cmdHandler.ExecuteStream(payload.CmdRequest, c.SendMessage)
+				logger.Debug("流式命令执行完成: requestID=%s", payload.CmdRequest.RequestId)
+				return
+			}
 			resp := c.cmdHandler.Execute(payload.CmdRequest.RequestId, payload.CmdRequest.Command, payload.CmdRequest.Timeout)
 			logger.Debug("命令执行完成: requestID=%s", payload.CmdRequest.RequestId)
 			c.SendMessage(resp)
 		}
 
+	case *pb.ServerMessage_CmdCancel:
+		if c.cmdHandler != nil {
+			found := c.cmdHandler.Cancel(payload.CmdCancel.RequestId)
+			logger.Info("取消命令: requestID=%s, found=%v", payload.CmdCancel.RequestId, found)
+		}
+
 	case *pb.ServerMessage_ProbeRequest:
 		if c.probeHandler != nil {
 			logger.Info("收到拨测请求: type=%s, target=%s, url=%s, requestID=%s",
@@ -374,9 +613,128 @@ func (c *GRPCClient) handleServerMessage(msg *pb.ServerMessage) {
 		// 处理 WebSocket 会话关闭请求
 		logger.Info("收到 WebSocket 会话关闭请求: sessionID=%s", payload.WsSessionClose.SessionId)
 		go c.handleWsSessionClose(payload.WsSessionClose)
+
+	case *pb.ServerMessage_DiscoveryRequest:
+		logger.Info("收到服务发现请求")
+		select {
+		case c.discoveryTrigger <- struct{}{}:
+		default: // 已有待处理的请求
+		}
+	}
+}
+
+// handleCertRenewRequest 证书临近过期，生成新私钥并提交证书签名请求
+func (c *GRPCClient) handleCertRenewRequest(req *pb.CertRenewRequest) {
+	if c.certRenewer == nil {
+		return
+	}
+	logger.Info("证书将于 %s 过期，开始续期", time.Unix(req.NotAfter, 0).Format("2006-01-02 15:04:05"))
+	csrPEM, err := c.certRenewer.CreateCSR(req.RequestId)
+	if err != nil {
+		logger.Error("证书续期失败: %v", err)
+		return
+	}
+	if err := c.SendMessage(&pb.AgentMessage{Payload: &pb.AgentMessage_CertCsr{
+		CertCsr: &pb.CertSigningRequest{RequestId: req.RequestId, CsrPem: csrPEM},
+	}}); err != nil {
+		logger.Error("提交证书签名请求失败: %v", err)
+	}
+}
+
+// handleCertRenewResponse 保存新证书后断开连接，重连时使用新证书
+func (c *GRPCClient) handleCertRenewResponse(resp *pb.CertRenewResponse) {
+	if c.certRenewer == nil {
+		return
+	}
+	if !resp.Success {
+		logger.Warn("服务端拒绝证书续期: %s", resp.Error)
+		return
+	}
+	if err := c.certRenewer.Install(resp.RequestId, resp.CertPem, resp.CaPem); err != nil {
+		logger.Error("保存续期证书失败: %v", err)
+		return
+	}
+	logger.Info("使用新证书重新连接")
+	c.mu.Lock()
+	conn := c.conn
+	c.mu.Unlock()
+	if conn != nil {
+		conn.Close()
 	}
 }
 
+// handleTunnelMessage 处理隧道消息，非隧道消息返回 false
+func (c *GRPCClient) handleTunnelMessage(msg *pb.ServerMessage) bool {
+	switch payload := msg.Payload.(type) {
+	case *pb.ServerMessage_TunnelOpen:
+		req := payload.TunnelOpen
+		reject := func(errMsg string) {
+			c.SendMessage(&pb.AgentMessage{Payload: &pb.AgentMessage_TunnelOpenResult{
+				TunnelOpenResult: &pb.TunnelOpenResult{ConnId: req.ConnId, Error: errMsg},
+			}})
+		}
+		if c.tunnelHandler == nil {
+			reject("Agent不支持端口转发")
+			return true
+		}
+		if c.policy != nil {
+			if err := c.policy.CheckTunnel(req.Target); err != nil {
+				c.rejectByPolicy(req.ConnId, err)
+				reject(err.Error())
+				return true
+			}
+		}
+		logger.Info("打开隧道: connID=%s, target=%s", req.ConnId, req.Target)
+		go c.tunnelHandler.Open(req)
+	case *pb.ServerMessage_TunnelData:
+		if c.tunnelHandler != nil {
+			c.tunnelHandler.Data(payload.TunnelData.ConnId, payload.TunnelData.Data)
+		}
+	case *pb.ServerMessage_TunnelAck:
+		if c.tunnelHandler != nil {
+			c.tunnelHandler.Ack(payload.TunnelAck.ConnId, int(payload.TunnelAck.Bytes))
+		}
+	case *pb.ServerMessage_TunnelClose:
+		if c.tunnelHandler != nil {
+			c.tunnelHandler.Close(payload.TunnelClose.ConnId, payload.TunnelClose.Error)
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// rejectByPolicy 记录并上报被本地策略拒绝的请求
+func (c *GRPCClient) rejectByPolicy(requestID string, err error) {
+	event := &pb.PolicyViolation{RequestId: requestID, Reason: err.Error(), Timestamp: time.Now().Unix()}
+	if v, ok := policy.AsViolation(err); ok {
+		event.Kind, event.Action, event.Target, event.Reason = v.Kind, v.Action, v.Target, v.Reason
+	}
+	logger.Warn("本地策略拒绝请求: kind=%s, action=%s, target=%s, reason=%s, requestID=%s", event.Kind, event.Action, event.Target, event.Reason, requestID)
+	if err := c.SendMessage(&pb.AgentMessage{
+		Payload: &pb.AgentMessage_PolicyViolation{PolicyViolation: event},
+	}); err != nil {
+		logger.Error("上报策略违规失败: %v", err)
+	}
+}
+
+// sendCommandRejected 以命令结果的形式返回策略拒绝
+func (c *GRPCClient) sendCommandRejected(req *pb.CommandRequest, err error) {
+	if req.Stream {
+		c.SendMessage(&pb.AgentMessage{
+			Payload: &pb.AgentMessage_CmdOutput{
+				CmdOutput: &pb.CommandOutputChunk{RequestId: req.RequestId, Eof: true, ExitCode: -1, Error: err.Error()},
+			},
+		})
+		return
+	}
+	c.SendMessage(&pb.AgentMessage{
+		Payload: &pb.AgentMessage_CmdResult{
+			CmdResult: &pb.CommandResult{RequestId: req.RequestId, ExitCode: -1, Error: err.Error()},
+		},
+	})
+}
+
 // getLocalIPs 获取本机非loopback IP列表
 func getLocalIPs() []string {
 	var ips []string
@@ -0,0 +1,85 @@
+package collector
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	pb "github.com/ydcloud-dy/opshub/pkg/agentproto"
+)
+
+// sample 上一次采集的累计计数，用于计算 CPU 使用率和网络速率
+type sample struct {
+	at       time.Time
+	cpuTotal uint64
+	cpuIdle  uint64
+	net      map[string]*pb.NetworkStats
+	procs    map[int32]uint64 // pid -> utime+stime（时钟节拍）
+}
+
+// Collector 主机指标采集器，随心跳上报
+type Collector struct {
+	topN int
+
+	mu   sync.Mutex
+	prev *sample
+}
+
+// New 创建采集器，topN 为上报的 CPU 占用最高进程数
+func New(topN int) *Collector {
+	return &Collector{topN: topN}
+}
+
+// Collect 采集一次主机指标；速率类指标需要两次采集，首次为 0
+func (c *Collector) Collect() *pb.HeartbeatRequest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	req, cur := collect(c.prev, c.topN)
+	c.prev = cur
+	return req
+}
+
+// rate 计算累计计数的每秒增量，计数器回绕或重置时返回 0
+func rate(cur, prev uint64, elapsed float64) float64 {
+	if cur < prev || elapsed <= 0 {
+		return 0
+	}
+	return float64(cur-prev) / elapsed
+}
+
+// rootDiskUsage 兼容旧字段 disk_usage：优先取根分区，否则取使用率最高的分区
+func rootDiskUsage(disks []*pb.DiskStats) float64 {
+	var highest float64
+	for _, d := range disks {
+		if d.Mountpoint == "/" {
+			return d.Usage
+		}
+		highest = max(highest, d.Usage)
+	}
+	return highest
+}
+
+// topProcesses 按 CPU 使用率（其次内存）取前 n 个进程
+func topProcesses(procs []*pb.ProcessStats, n int) []*pb.ProcessStats {
+	if n <= 0 {
+		return nil
+	}
+	sort.Slice(procs, func(i, j int) bool {
+		if procs[i].CpuUsage != procs[j].CpuUsage {
+			return procs[i].CpuUsage > procs[j].CpuUsage
+		}
+		return procs[i].MemoryRss > procs[j].MemoryRss
+	})
+	if len(procs) > n {
+		procs = procs[:n]
+	}
+	return procs
+}
+
+func percent(part, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total) * 100
+}
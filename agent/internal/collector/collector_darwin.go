@@ -0,0 +1,62 @@
+package collector
+
+import (
+	"context"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	pb "github.com/ydcloud-dy/opshub/pkg/agentproto"
+)
+
+// collect macOS 仅采集负载、内存总量、运行时长和根分区，其余指标不上报
+func collect(prev *sample, topN int) (*pb.HeartbeatRequest, *sample) {
+	req := &pb.HeartbeatRequest{}
+
+	// vm.loadavg 输出形如 "{ 1.23 1.45 1.67 }"
+	if load := strings.Fields(strings.Trim(sysctl("vm.loadavg"), "{} ")); len(load) >= 3 {
+		req.Load1, _ = strconv.ParseFloat(load[0], 64)
+		req.Load5, _ = strconv.ParseFloat(load[1], 64)
+		req.Load15, _ = strconv.ParseFloat(load[2], 64)
+	}
+	req.MemoryTotal, _ = strconv.ParseUint(sysctl("hw.memsize"), 10, 64)
+
+	// kern.boottime 输出形如 "{ sec = 1700000000, usec = 0 } ..."
+	if _, rest, ok := strings.Cut(sysctl("kern.boottime"), "sec = "); ok {
+		sec, _, _ := strings.Cut(rest, ",")
+		if boot, err := strconv.ParseInt(sec, 10, 64); err == nil {
+			req.UptimeSeconds = time.Now().Unix() - boot
+		}
+	}
+
+	var st syscall.Statfs_t
+	if err := syscall.Statfs("/", &st); err == nil && st.Blocks > 0 {
+		bsize := uint64(st.Bsize)
+		used := (st.Blocks - st.Bfree) * bsize
+		disk := &pb.DiskStats{
+			Mountpoint: "/",
+			TotalBytes: st.Blocks * bsize,
+			UsedBytes:  used,
+			Usage:      percent(used, used+st.Bavail*bsize),
+		}
+		if st.Files > 0 {
+			disk.InodesUsage = percent(st.Files-st.Ffree, st.Files)
+		}
+		req.Disks = append(req.Disks, disk)
+	}
+	req.DiskUsage = rootDiskUsage(req.Disks)
+
+	return req, &sample{at: time.Now()}
+}
+
+func sysctl(name string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "sysctl", "-n", name).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
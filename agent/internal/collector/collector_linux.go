@@ -0,0 +1,360 @@
+package collector
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ydcloud-dy/opshub/agent/internal/logger"
+	pb "github.com/ydcloud-dy/opshub/pkg/agentproto"
+)
+
+// clockTicks /proc 中 CPU 时间的单位（USER_HZ），Linux 上几乎总是 100
+const clockTicks = 100
+
+// tcpStateNames /proc/net/tcp 中 st 字段的十六进制取值
+var tcpStateNames = map[string]string{
+	"01": "ESTABLISHED",
+	"02": "SYN_SENT",
+	"03": "SYN_RECV",
+	"04": "FIN_WAIT1",
+	"05": "FIN_WAIT2",
+	"06": "TIME_WAIT",
+	"07": "CLOSE",
+	"08": "CLOSE_WAIT",
+	"09": "LAST_ACK",
+	"0A": "LISTEN",
+	"0B": "CLOSING",
+}
+
+// ignoredFSTypes 不统计的只读镜像类文件系统
+var ignoredFSTypes = map[string]bool{
+	"squashfs": true,
+	"iso9660":  true,
+}
+
+func collect(prev *sample, topN int) (*pb.HeartbeatRequest, *sample) {
+	cur := &sample{at: time.Now()}
+	req := &pb.HeartbeatRequest{}
+
+	var elapsed float64
+	if prev != nil {
+		elapsed = cur.at.Sub(prev.at).Seconds()
+	}
+
+	var err error
+	if cur.cpuTotal, cur.cpuIdle, err = readCPU(); err != nil {
+		logger.Debug("采集CPU失败: %v", err)
+	} else if prev != nil && cur.cpuTotal > prev.cpuTotal {
+		delta := cur.cpuTotal - prev.cpuTotal
+		var idle uint64
+		// iowait 在部分内核上可能回退
+		if cur.cpuIdle > prev.cpuIdle {
+			idle = min(cur.cpuIdle-prev.cpuIdle, delta)
+		}
+		req.CpuUsage = percent(delta-idle, delta)
+	} else if cur.cpuTotal > 0 {
+		// 首次采集使用开机以来的平均值
+		req.CpuUsage = percent(cur.cpuTotal-cur.cpuIdle, cur.cpuTotal)
+	}
+
+	if total, available, err := readMemInfo(); err != nil {
+		logger.Debug("采集内存失败: %v", err)
+	} else {
+		req.MemoryTotal = total
+		req.MemoryUsed = total - min(available, total)
+		req.MemoryUsage = percent(req.MemoryUsed, total)
+	}
+
+	if load, err := readFields("/proc/loadavg"); err == nil && len(load) >= 3 {
+		req.Load1, _ = strconv.ParseFloat(load[0], 64)
+		req.Load5, _ = strconv.ParseFloat(load[1], 64)
+		req.Load15, _ = strconv.ParseFloat(load[2], 64)
+	}
+	if up, err := readFields("/proc/uptime"); err == nil && len(up) >= 1 {
+		secs, _ := strconv.ParseFloat(up[0], 64)
+		req.UptimeSeconds = int64(secs)
+	}
+
+	if req.Disks, err = readDisks(); err != nil {
+		logger.Debug("采集磁盘失败: %v", err)
+	}
+	req.DiskUsage = rootDiskUsage(req.Disks)
+
+	if cur.net, err = readNetDev(); err != nil {
+		logger.Debug("采集网络失败: %v", err)
+	}
+	for name, n := range cur.net {
+		stat := &pb.NetworkStats{
+			Name:     name,
+			RxBytes:  n.RxBytes,
+			TxBytes:  n.TxBytes,
+			RxErrors: n.RxErrors,
+			TxErrors: n.TxErrors,
+		}
+		if prev != nil {
+			if p, ok := prev.net[name]; ok {
+				stat.RxBytesPerSec = rate(n.RxBytes, p.RxBytes, elapsed)
+				stat.TxBytesPerSec = rate(n.TxBytes, p.TxBytes, elapsed)
+			}
+		}
+		req.Networks = append(req.Networks, stat)
+	}
+	sort.Slice(req.Networks, func(i, j int) bool { return req.Networks[i].Name < req.Networks[j].Name })
+
+	req.TcpStates = readTCPStates()
+
+	if topN > 0 {
+		var procs []*pb.ProcessStats
+		procs, cur.procs = readProcesses(prev, elapsed, req.MemoryTotal)
+		req.TopProcesses = topProcesses(procs, topN)
+	}
+	return req, cur
+}
+
+func readFields(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(data)), nil
+}
+
+// readCPU 读取 /proc/stat 汇总行，返回累计总节拍和空闲节拍（含 iowait）
+func readCPU() (total, idle uint64, err error) {
+	f, err := os.Open("/proc/stat")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 5 || fields[0] != "cpu" {
+			continue
+		}
+		// user nice system idle iowait irq softirq steal，guest 已计入 user
+		for i := 1; i < len(fields) && i <= 8; i++ {
+			v, _ := strconv.ParseUint(fields[i], 10, 64)
+			total += v
+			if i == 4 || i == 5 {
+				idle += v
+			}
+		}
+		return total, idle, nil
+	}
+	return 0, 0, sc.Err()
+}
+
+// readMemInfo 返回总内存和可用内存（字节）
+func readMemInfo() (total, available uint64, err error) {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	var free, buffers, cached uint64
+	hasAvailable := false
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		v, _ := strconv.ParseUint(fields[1], 10, 64)
+		v *= 1024
+		switch fields[0] {
+		case "MemTotal:":
+			total = v
+		case "MemAvailable:":
+			available, hasAvailable = v, true
+		case "MemFree:":
+			free = v
+		case "Buffers:":
+			buffers = v
+		case "Cached:":
+			cached = v
+		}
+	}
+	// 3.14 之前的内核没有 MemAvailable
+	if !hasAvailable {
+		available = free + buffers + cached
+	}
+	return total, available, sc.Err()
+}
+
+// readDisks 统计块设备挂载点，同一设备的多个挂载（bind mount）或同一挂载点的覆盖挂载只取第一个
+func readDisks() ([]*pb.DiskStats, error) {
+	f, err := os.Open("/proc/self/mounts")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	seen := make(map[string]bool)
+	var disks []*pb.DiskStats
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		device, mountpoint, fstype := fields[0], unescapeMount(fields[1]), fields[2]
+		if !strings.HasPrefix(device, "/dev/") || ignoredFSTypes[fstype] || seen[device] || seen[mountpoint] {
+			continue
+		}
+		seen[device], seen[mountpoint] = true, true
+
+		var st syscall.Statfs_t
+		if err := syscall.Statfs(mountpoint, &st); err != nil || st.Blocks == 0 {
+			continue
+		}
+		bsize := uint64(st.Bsize)
+		used := (st.Blocks - st.Bfree) * bsize
+		avail := st.Bavail * bsize
+		disk := &pb.DiskStats{
+			Mountpoint: mountpoint,
+			Device:     device,
+			Fstype:     fstype,
+			TotalBytes: st.Blocks * bsize,
+			UsedBytes:  used,
+			// 与 df 一致：按普通用户可用空间计算，不含 root 保留块
+			Usage: percent(used, used+avail),
+		}
+		if st.Files > 0 {
+			disk.InodesUsage = percent(st.Files-st.Ffree, st.Files)
+		}
+		disks = append(disks, disk)
+	}
+	return disks, sc.Err()
+}
+
+// unescapeMount 还原 /proc/mounts 中的八进制转义（如空格 \040）
+func unescapeMount(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if v, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(v))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// readNetDev 读取 /proc/net/dev 的累计收发字节和错误数，忽略回环网卡
+func readNetDev() (map[string]*pb.NetworkStats, error) {
+	f, err := os.Open("/proc/net/dev")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	stats := make(map[string]*pb.NetworkStats)
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		name, rest, ok := strings.Cut(sc.Text(), ":")
+		if !ok {
+			continue
+		}
+		name = strings.TrimSpace(name)
+		fields := strings.Fields(rest)
+		if name == "lo" || len(fields) < 11 {
+			continue
+		}
+		n := &pb.NetworkStats{Name: name}
+		n.RxBytes, _ = strconv.ParseUint(fields[0], 10, 64)
+		n.RxErrors, _ = strconv.ParseUint(fields[2], 10, 64)
+		n.TxBytes, _ = strconv.ParseUint(fields[8], 10, 64)
+		n.TxErrors, _ = strconv.ParseUint(fields[10], 10, 64)
+		stats[name] = n
+	}
+	return stats, sc.Err()
+}
+
+// readTCPStates 按状态统计 IPv4/IPv6 TCP 连接数
+func readTCPStates() map[string]int32 {
+	states := make(map[string]int32)
+	for _, path := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		sc := bufio.NewScanner(f)
+		sc.Scan() // 表头
+		for sc.Scan() {
+			fields := strings.Fields(sc.Text())
+			if len(fields) < 4 {
+				continue
+			}
+			if name, ok := tcpStateNames[strings.ToUpper(fields[3])]; ok {
+				states[name]++
+			}
+		}
+		f.Close()
+	}
+	return states
+}
+
+// readProcesses 遍历 /proc/[pid]/stat，按两次采集间的节拍差计算进程 CPU 使用率
+func readProcesses(prev *sample, elapsed float64, memTotal uint64) ([]*pb.ProcessStats, map[int32]uint64) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		logger.Debug("采集进程失败: %v", err)
+		return nil, nil
+	}
+	pageSize := uint64(os.Getpagesize())
+	ticks := make(map[int32]uint64, len(entries))
+	var procs []*pb.ProcessStats
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join("/proc", e.Name(), "stat"))
+		if err != nil {
+			continue // 进程已退出
+		}
+		// 格式: pid (comm) state ppid ...，comm 可能包含空格和括号
+		line := string(data)
+		open, end := strings.IndexByte(line, '('), strings.LastIndexByte(line, ')')
+		if open < 0 || end < open {
+			continue
+		}
+		fields := strings.Fields(line[end+1:])
+		if len(fields) < 22 {
+			continue
+		}
+		utime, _ := strconv.ParseUint(fields[11], 10, 64)
+		stime, _ := strconv.ParseUint(fields[12], 10, 64)
+		rssPages, _ := strconv.ParseUint(fields[21], 10, 64)
+
+		p := &pb.ProcessStats{
+			Pid:       int32(pid),
+			Name:      line[open+1 : end],
+			MemoryRss: rssPages * pageSize,
+		}
+		p.MemoryUsage = percent(p.MemoryRss, memTotal)
+		ticks[p.Pid] = utime + stime
+		if prev != nil {
+			if last, ok := prev.procs[p.Pid]; ok {
+				p.CpuUsage = rate(utime+stime, last, elapsed) / clockTicks * 100
+			}
+		}
+		procs = append(procs, p)
+	}
+	return procs, ticks
+}
@@ -17,6 +17,34 @@ type Config struct {
 	LogMaxBackups int    `yaml:"log_max_backups"` // 保留的旧日志文件数量，默认 3
 	LogMaxAge     int    `yaml:"log_max_age"`     // 日志文件最大保留天数，默认 30 天
 	LogLevel      string `yaml:"log_level"`       // 日志级别：debug, info, warn, error，默认 info
+	// UpgradePublicKey 校验升级包签名的 ed25519 公钥（base64），为空时拒绝远程升级
+	UpgradePublicKey string `yaml:"upgrade_public_key"`
+	// TopProcesses 心跳上报的 CPU 占用最高进程数，默认 5，负数表示不上报
+	TopProcesses int `yaml:"top_processes"`
+	// DiscoveryInterval 本机服务发现间隔（秒），默认 300，负数表示关闭
+	DiscoveryInterval int `yaml:"discovery_interval"`
+	// Policy 本地安全策略，服务端下发的请求违反策略时在本地拒绝
+	Policy Policy `yaml:"policy"`
+	// PolicyFile 独立的策略文件，配置后覆盖 policy，便于由主机管理员单独维护
+	PolicyFile string `yaml:"policy_file"`
+}
+
+// Policy Agent本地安全策略，零值表示不做限制
+type Policy struct {
+	// AllowedCommands 允许执行的命令（正则，需完整匹配），为空表示不限制；
+	// 命令按 ; && || | 换行拆分后每一段都必须匹配，且禁止命令替换
+	AllowedCommands []string `yaml:"allowed_commands"`
+	// ForbiddenPaths 文件管理禁止访问的路径，包含其下所有子路径
+	ForbiddenPaths []string `yaml:"forbidden_paths"`
+	// DisableTerminal 禁止打开 PTY 终端
+	DisableTerminal bool `yaml:"disable_terminal"`
+	// DisableTunnel 禁止 TCP 端口转发
+	DisableTunnel bool `yaml:"disable_tunnel"`
+	// DisableUpgrade 禁止服务端下发的远程升级
+	DisableUpgrade bool `yaml:"disable_upgrade"`
+	// ReadOnly 只读模式：禁止终端、端口转发、远程升级、文件写入和删除、输出重定向到文件，
+	// 且只允许执行 allowed_commands 中的命令
+	ReadOnly bool `yaml:"read_only"`
 }
 
 // Load 加载配置
@@ -43,6 +71,23 @@ func Load(path string) (*Config, error) {
 	if cfg.LogLevel == "" {
 		cfg.LogLevel = "info" // 默认 info 级别
 	}
+	if cfg.TopProcesses == 0 {
+		cfg.TopProcesses = 5
+	}
+	if cfg.DiscoveryInterval == 0 {
+		cfg.DiscoveryInterval = 300
+	}
+
+	if cfg.PolicyFile != "" {
+		data, err := os.ReadFile(cfg.PolicyFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取策略文件失败: %w", err)
+		}
+		cfg.Policy = Policy{}
+		if err := yaml.Unmarshal(data, &cfg.Policy); err != nil {
+			return nil, fmt.Errorf("解析策略文件失败: %w", err)
+		}
+	}
 
 	return cfg, nil
 }
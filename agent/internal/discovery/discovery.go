@@ -0,0 +1,273 @@
+package discovery
+
+import (
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	pb "github.com/ydcloud-dy/opshub/pkg/agentproto"
+)
+
+// maxCmdlineLen 上报命令行的最大长度
+const maxCmdlineLen = 512
+
+// 服务类型，与服务端 Middleware 类型保持一致的部分可一键导入
+const (
+	TypeMySQL         = "mysql"
+	TypeRedis         = "redis"
+	TypeRedisSentinel = "redis-sentinel"
+	TypeMongoDB       = "mongodb"
+	TypeClickHouse    = "clickhouse"
+	TypeKafka         = "kafka"
+	TypeMilvus        = "milvus"
+	TypeZookeeper     = "zookeeper"
+	TypeElasticsearch = "elasticsearch"
+	TypeNginx         = "nginx"
+	TypeJVM           = "jvm"
+	TypeExporter      = "exporter"
+)
+
+// processTypes 按进程名识别的服务
+var processTypes = map[string]string{
+	"mysqld":            TypeMySQL,
+	"mariadbd":          TypeMySQL,
+	"redis-server":      TypeRedis,
+	"redis-sentinel":    TypeRedisSentinel,
+	"mongod":            TypeMongoDB,
+	"clickhouse-server": TypeClickHouse,
+	"milvus":            TypeMilvus,
+	"nginx":             TypeNginx,
+}
+
+// javaMainClasses 按 Java 主类（或命令行关键字）识别的服务
+var javaMainClasses = []struct {
+	keyword string
+	typ     string
+}{
+	{"kafka.Kafka", TypeKafka},
+	{"org.apache.zookeeper.server", TypeZookeeper},
+	{"org.elasticsearch.bootstrap", TypeElasticsearch},
+}
+
+// exporterTargets exporter 名称前缀到被监控服务类型
+var exporterTargets = map[string]string{
+	"node":          "node",
+	"mysqld":        TypeMySQL,
+	"mysql":         TypeMySQL,
+	"redis":         TypeRedis,
+	"mongodb":       TypeMongoDB,
+	"kafka":         TypeKafka,
+	"clickhouse":    TypeClickHouse,
+	"nginx":         TypeNginx,
+	"zookeeper":     TypeZookeeper,
+	"elasticsearch": TypeElasticsearch,
+}
+
+// jmxAgentPattern JMX exporter javaagent 参数，如 -javaagent:/opt/jmx_prometheus_javaagent.jar=9404:/opt/config.yaml
+var jmxAgentPattern = regexp.MustCompile(`jmx_prometheus_javaagent[^=]*\.jar=(?:[^:]+:)?(\d+)`)
+
+// sensitiveArgPattern 可能携带凭据的参数名
+var sensitiveArgPattern = regexp.MustCompile(`(?i)(pass|secret|token|credential|auth|key)`)
+
+// process 监听端口的进程
+type process struct {
+	pid    int32
+	comm   string
+	args   []string
+	listen []*pb.ListenAddr
+}
+
+// name 优先使用 argv[0]，comm 最长只有 15 个字符；
+// redis、nginx 等会改写 argv[0]，如 "nginx: master process /usr/sbin/nginx"
+func (p *process) name() string {
+	if len(p.args) > 0 {
+		if fields := strings.Fields(p.args[0]); len(fields) > 0 {
+			return strings.TrimSuffix(filepath.Base(fields[0]), ":")
+		}
+	}
+	return p.comm
+}
+
+// classify 识别进程提供的服务，未识别的进程返回空
+func classify(p *process) []*pb.DiscoveredService {
+	name := p.name()
+	service := func(typ, display string, listen []*pb.ListenAddr) *pb.DiscoveredService {
+		return &pb.DiscoveredService{
+			Type:    typ,
+			Name:    display,
+			Pid:     p.pid,
+			Process: name,
+			Cmdline: redactCmdline(p.args),
+			Listen:  listen,
+		}
+	}
+
+	if target, ok := exporterTarget(name); ok {
+		s := service(TypeExporter, name, p.listen)
+		s.ExporterFor = target
+		return []*pb.DiscoveredService{s}
+	}
+	if typ, ok := processTypes[name]; ok {
+		return []*pb.DiscoveredService{service(typ, typ, p.listen)}
+	}
+	// clickhouse 单二进制通过子命令启动
+	if name == "clickhouse" && len(p.args) > 1 && p.args[1] == "server" {
+		return []*pb.DiscoveredService{service(TypeClickHouse, TypeClickHouse, p.listen)}
+	}
+	if name != "java" {
+		return nil
+	}
+
+	typ, display := TypeJVM, javaMain(p.args)
+	cmdline := strings.Join(p.args, " ")
+	for _, m := range javaMainClasses {
+		if strings.Contains(cmdline, m.keyword) {
+			typ, display = m.typ, m.typ
+			break
+		}
+	}
+
+	listen := p.listen
+	var result []*pb.DiscoveredService
+	// JMX exporter 与应用在同一进程内，端口单独作为 exporter 上报
+	if match := jmxAgentPattern.FindStringSubmatch(cmdline); match != nil {
+		port, _ := strconv.Atoi(match[1])
+		var rest []*pb.ListenAddr
+		var exporterListen []*pb.ListenAddr
+		for _, l := range listen {
+			if int(l.Port) == port {
+				exporterListen = append(exporterListen, l)
+			} else {
+				rest = append(rest, l)
+			}
+		}
+		if len(exporterListen) > 0 {
+			exporter := service(TypeExporter, "jmx_exporter", exporterListen)
+			exporter.ExporterFor = typ
+			result = append(result, exporter)
+			listen = rest
+		}
+	}
+	if len(listen) > 0 {
+		result = append(result, service(typ, display, listen))
+	}
+	return result
+}
+
+// exporterTarget 根据进程名判断是否为 Prometheus exporter，返回被监控的服务类型
+func exporterTarget(name string) (string, bool) {
+	var prefix string
+	switch {
+	case strings.HasSuffix(name, "_exporter"):
+		prefix = strings.TrimSuffix(name, "_exporter")
+	case strings.HasSuffix(name, "-prometheus-exporter"):
+		prefix = strings.TrimSuffix(name, "-prometheus-exporter")
+	case strings.HasSuffix(name, "-exporter"):
+		prefix = strings.TrimSuffix(name, "-exporter")
+	default:
+		return "", false
+	}
+	if target, ok := exporterTargets[prefix]; ok {
+		return target, true
+	}
+	return prefix, true
+}
+
+// javaMain 返回 Java 进程的主类或 jar 名称
+func javaMain(args []string) string {
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "-jar" && i+1 < len(args):
+			return filepath.Base(args[i+1])
+		case arg == "-cp" || arg == "-classpath" || arg == "--class-path" || arg == "-p" || arg == "--module-path":
+			i++ // 跳过参数值
+		case strings.HasPrefix(arg, "-"):
+		default:
+			return arg
+		}
+	}
+	return TypeJVM
+}
+
+// redactCmdline 隐藏命令行中的凭据并截断
+func redactCmdline(args []string) string {
+	redacted := make([]string, 0, len(args))
+	maskNext := false
+	for _, arg := range args {
+		switch {
+		case maskNext:
+			arg = "***"
+			maskNext = false
+		case strings.HasPrefix(arg, "-") && sensitiveArgPattern.MatchString(arg):
+			if key, _, ok := strings.Cut(arg, "="); ok {
+				arg = key + "=***"
+			} else {
+				maskNext = true
+			}
+		case strings.Contains(arg, "=") && sensitiveArgPattern.MatchString(strings.SplitN(arg, "=", 2)[0]):
+			arg = strings.SplitN(arg, "=", 2)[0] + "=***"
+		}
+		redacted = append(redacted, arg)
+	}
+	cmdline := strings.Join(redacted, " ")
+	if len(cmdline) > maxCmdlineLen {
+		cmdline = cmdline[:maxCmdlineLen] + "..."
+	}
+	return cmdline
+}
+
+// sortServices 按类型、名称、端口排序，保证上报顺序稳定
+func sortServices(services []*pb.DiscoveredService) {
+	for _, s := range services {
+		sort.Slice(s.Listen, func(i, j int) bool {
+			if s.Listen[i].Port != s.Listen[j].Port {
+				return s.Listen[i].Port < s.Listen[j].Port
+			}
+			return s.Listen[i].Ip < s.Listen[j].Ip
+		})
+	}
+	sort.Slice(services, func(i, j int) bool {
+		a, b := services[i], services[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Pid < b.Pid
+	})
+}
+
+// Fingerprint 服务集合的指纹，不含 PID，进程重启但服务不变时指纹不变
+func Fingerprint(services []*pb.DiscoveredService) string {
+	var b strings.Builder
+	for _, s := range services {
+		b.WriteString(s.Type + "/" + s.Name + "/" + s.ExporterFor)
+		for _, l := range s.Listen {
+			b.WriteString(" " + l.Ip + ":" + strconv.Itoa(int(l.Port)))
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// Discoverer 本机服务发现
+type Discoverer struct{}
+
+// New 创建服务发现器
+func New() *Discoverer {
+	return &Discoverer{}
+}
+
+// Discover 扫描本机监听端口的进程并识别服务
+func (d *Discoverer) Discover() []*pb.DiscoveredService {
+	var services []*pb.DiscoveredService
+	for _, p := range listeningProcesses() {
+		services = append(services, classify(p)...)
+	}
+	sortServices(services)
+	return services
+}
@@ -0,0 +1,6 @@
+package discovery
+
+// listeningProcesses macOS 暂不支持服务发现
+func listeningProcesses() []*process {
+	return nil
+}
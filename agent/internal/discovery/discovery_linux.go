@@ -0,0 +1,148 @@
+package discovery
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	pb "github.com/ydcloud-dy/opshub/pkg/agentproto"
+)
+
+// tcpListen /proc/net/tcp 中 LISTEN 状态的取值
+const tcpListen = "0A"
+
+// listeningProcesses 返回所有监听 TCP 端口的进程
+func listeningProcesses() []*process {
+	sockets := make(map[string][]*pb.ListenAddr) // inode -> 监听地址
+	readListenSockets("/proc/net/tcp", sockets)
+	readListenSockets("/proc/net/tcp6", sockets)
+	if len(sockets) == 0 {
+		return nil
+	}
+
+	// 同一 socket 可能被父子进程（如 nginx master/worker）共享，取 PID 最小的进程
+	owners := make(map[string]int32)
+	entries, _ := os.ReadDir("/proc")
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		fds, err := os.ReadDir(filepath.Join("/proc", e.Name(), "fd"))
+		if err != nil {
+			continue // 进程已退出或无权限
+		}
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join("/proc", e.Name(), "fd", fd.Name()))
+			if err != nil || !strings.HasPrefix(link, "socket:[") {
+				continue
+			}
+			inode := strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]")
+			if _, ok := sockets[inode]; !ok {
+				continue
+			}
+			if owner, ok := owners[inode]; !ok || int32(pid) < owner {
+				owners[inode] = int32(pid)
+			}
+		}
+	}
+
+	byPID := make(map[int32]*process)
+	for inode, pid := range owners {
+		p, ok := byPID[pid]
+		if !ok {
+			p = readProcess(pid)
+			byPID[pid] = p
+		}
+		p.listen = appendUnique(p.listen, sockets[inode]...)
+	}
+
+	processes := make([]*process, 0, len(byPID))
+	for _, p := range byPID {
+		processes = append(processes, p)
+	}
+	return processes
+}
+
+// readListenSockets 解析 /proc/net/tcp{,6}，记录 LISTEN 状态 socket 的 inode 和地址
+func readListenSockets(path string, sockets map[string][]*pb.ListenAddr) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // 表头
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[3] != tcpListen || fields[9] == "0" {
+			continue
+		}
+		hexIP, hexPort, ok := strings.Cut(fields[1], ":")
+		if !ok {
+			continue
+		}
+		port, err := strconv.ParseUint(hexPort, 16, 16)
+		if err != nil {
+			continue
+		}
+		ip := parseHexIP(hexIP)
+		if ip == nil {
+			continue
+		}
+		sockets[fields[9]] = append(sockets[fields[9]], &pb.ListenAddr{Ip: ip.String(), Port: int32(port)})
+	}
+}
+
+// parseHexIP 解析内核以主机字节序（按 32 位分组）输出的十六进制地址
+func parseHexIP(s string) net.IP {
+	raw, err := hex.DecodeString(s)
+	if err != nil || (len(raw) != net.IPv4len && len(raw) != net.IPv6len) {
+		return nil
+	}
+	for i := 0; i < len(raw); i += 4 {
+		raw[i], raw[i+1], raw[i+2], raw[i+3] = raw[i+3], raw[i+2], raw[i+1], raw[i]
+	}
+	ip := net.IP(raw)
+	if v4 := ip.To4(); v4 != nil {
+		return v4
+	}
+	return ip
+}
+
+// readProcess 读取进程名和命令行
+func readProcess(pid int32) *process {
+	dir := filepath.Join("/proc", strconv.Itoa(int(pid)))
+	p := &process{pid: pid}
+	if comm, err := os.ReadFile(filepath.Join(dir, "comm")); err == nil {
+		p.comm = strings.TrimSpace(string(comm))
+	}
+	if cmdline, err := os.ReadFile(filepath.Join(dir, "cmdline")); err == nil {
+		for _, arg := range bytes.Split(bytes.TrimRight(cmdline, "\x00"), []byte{0}) {
+			p.args = append(p.args, string(arg))
+		}
+	}
+	return p
+}
+
+func appendUnique(list []*pb.ListenAddr, addrs ...*pb.ListenAddr) []*pb.ListenAddr {
+	for _, a := range addrs {
+		dup := false
+		for _, l := range list {
+			if l.Ip == a.Ip && l.Port == a.Port {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			list = append(list, a)
+		}
+	}
+	return list
+}
@@ -4,13 +4,17 @@ import (
 	"bytes"
 	"context"
 	"os/exec"
+	"sync"
 	"time"
 
 	pb "github.com/ydcloud-dy/opshub/pkg/agentproto"
 )
 
 // CommandExecutor 命令执行器
-type CommandExecutor struct{}
+type CommandExecutor struct {
+	// jobs 正在执行的流式命令: requestID -> *streamJob
+	jobs sync.Map
+}
 
 // NewCommandExecutor 创建命令执行器
 func NewCommandExecutor() *CommandExecutor {
@@ -0,0 +1,164 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+
+	pb "github.com/ydcloud-dy/opshub/pkg/agentproto"
+)
+
+const (
+	// defaultMaxOutputBytes 流式命令默认输出上限
+	defaultMaxOutputBytes = 10 << 20
+	// outputChunkSize 单个输出分片大小
+	outputChunkSize = 16 << 10
+	// killWaitDelay 进程组被杀后等待输出管道关闭的时间
+	killWaitDelay = 5 * time.Second
+)
+
+// streamJob 一个正在执行的流式命令
+type streamJob struct {
+	cancel   context.CancelFunc
+	mu       sync.Mutex
+	canceled bool
+}
+
+// ExecuteStream 流式执行命令，输出以 CommandOutputChunk 增量发送，最后一条消息携带退出信息
+// 命令在独立进程组中运行，超时或取消时结束整个进程组
+func (e *CommandExecutor) ExecuteStream(req *pb.CommandRequest, send func(*pb.AgentMessage) error) {
+	timeout := req.Timeout
+	if timeout <= 0 {
+		timeout = 60
+	}
+	maxOutput := req.MaxOutputBytes
+	if maxOutput <= 0 {
+		maxOutput = defaultMaxOutputBytes
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
+	job := &streamJob{cancel: cancel}
+	e.jobs.Store(req.RequestId, job)
+	defer e.jobs.Delete(req.RequestId)
+
+	out := &chunkWriter{requestID: req.RequestId, send: send, remaining: maxOutput}
+	final := &pb.CommandOutputChunk{RequestId: req.RequestId, Eof: true}
+
+	cmd := exec.CommandContext(ctx, "/bin/bash", "-c", req.Command)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		// 负 PID 表示向整个进程组发送信号
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = killWaitDelay
+	cmd.Stdout = out.stream("stdout")
+	cmd.Stderr = out.stream("stderr")
+
+	err := cmd.Run()
+	final.Seq = out.nextSeq()
+	final.Truncated = out.truncated()
+	job.mu.Lock()
+	final.Canceled = job.canceled
+	job.mu.Unlock()
+	final.TimedOut = !final.Canceled && errors.Is(ctx.Err(), context.DeadlineExceeded)
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr):
+		final.ExitCode = int32(exitErr.ExitCode())
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			final.Signal = status.Signal().String()
+		}
+	default:
+		final.ExitCode = -1
+		final.Error = err.Error()
+	}
+	if final.TimedOut && final.Error == "" {
+		final.Error = "命令执行超时"
+	}
+	send(&pb.AgentMessage{Payload: &pb.AgentMessage_CmdOutput{CmdOutput: final}})
+}
+
+// Cancel 取消正在执行的流式命令，返回是否找到该命令
+func (e *CommandExecutor) Cancel(requestID string) bool {
+	v, ok := e.jobs.Load(requestID)
+	if !ok {
+		return false
+	}
+	job := v.(*streamJob)
+	job.mu.Lock()
+	job.canceled = true
+	job.mu.Unlock()
+	job.cancel()
+	return true
+}
+
+// chunkWriter 将 stdout/stderr 切分为有序的输出分片，并限制总输出大小
+type chunkWriter struct {
+	requestID string
+	send      func(*pb.AgentMessage) error
+	mu        sync.Mutex
+	seq       int64
+	remaining int64
+	cut       bool
+}
+
+func (w *chunkWriter) stream(name string) io.Writer {
+	return writerFunc(func(p []byte) (int, error) {
+		w.write(name, p)
+		// 超出上限后继续消费输出，避免子进程因管道写满而阻塞
+		return len(p), nil
+	})
+}
+
+func (w *chunkWriter) write(name string, p []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for len(p) > 0 && w.remaining > 0 {
+		n := min(len(p), outputChunkSize)
+		if int64(n) > w.remaining {
+			n = int(w.remaining)
+		}
+		data := make([]byte, n)
+		copy(data, p[:n])
+		w.seq++
+		w.send(&pb.AgentMessage{
+			Payload: &pb.AgentMessage_CmdOutput{
+				CmdOutput: &pb.CommandOutputChunk{
+					RequestId: w.requestID,
+					Seq:       w.seq,
+					Stream:    name,
+					Data:      data,
+				},
+			},
+		})
+		w.remaining -= int64(n)
+		p = p[n:]
+	}
+	if len(p) > 0 {
+		w.cut = true
+	}
+}
+
+func (w *chunkWriter) nextSeq() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.seq++
+	return w.seq
+}
+
+func (w *chunkWriter) truncated() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.cut
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
@@ -35,19 +35,36 @@ func expandPath(path string) string {
 }
 
 // HandleRequest 处理文件操作请求
-func (f *FileManager) HandleRequest(requestID, action, path, filename string, data []byte) (*pb.AgentMessage, error) {
-	path = expandPath(path)
-	switch action {
+func (f *FileManager) HandleRequest(req *pb.FileRequest) (*pb.AgentMessage, error) {
+	requestID := req.RequestId
+	path := expandPath(req.Path)
+	switch req.Action {
 	case "list":
 		return f.listFiles(requestID, path)
 	case "upload":
-		return f.uploadFile(requestID, path, filename, data)
+		return f.uploadFile(requestID, path, req.Filename, req.Data)
 	case "download":
 		return f.downloadFile(requestID, path)
 	case "delete":
 		return f.deleteFile(requestID, path)
+	case "stat":
+		return f.statFile(requestID, path)
+	case "upload_init":
+		return f.uploadInit(req, path)
+	case "upload_chunk":
+		return f.uploadChunk(req, path)
+	case "upload_commit":
+		return f.uploadCommit(req, path)
+	case "read":
+		return f.readFile(req, path)
+	case "checksum":
+		return f.checksumFile(requestID, path)
+	case "pack":
+		return f.packDir(req, path)
+	case "pack_cleanup":
+		return f.packCleanup(req)
 	default:
-		return nil, fmt.Errorf("未知操作: %s", action)
+		return nil, fmt.Errorf("未知操作: %s", req.Action)
 	}
 }
 
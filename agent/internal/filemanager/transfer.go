@@ -0,0 +1,512 @@
+package filemanager
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	pb "github.com/ydcloud-dy/opshub/pkg/agentproto"
+)
+
+const (
+	// defaultReadSize 未指定读取长度时的分片大小
+	defaultReadSize = 1 << 20
+	// maxReadSize 单次读取上限，需低于 gRPC 默认 4MB 消息限制
+	maxReadSize = 3 << 20
+)
+
+var transferIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// partPath 上传中的临时文件，与目标文件位于同一目录以便原子重命名
+// 路径只由目录、文件名和传输标识决定，Agent 重启后依然可以续传
+func partPath(dir, filename, transferID string) string {
+	return filepath.Join(dir, "."+filename+"."+transferID+".part")
+}
+
+// packPath 目录下载时的临时打包文件
+func packPath(transferID string) string {
+	return filepath.Join(os.TempDir(), "opshub-pack-"+transferID+".tar.gz")
+}
+
+func validateTransfer(req *pb.FileRequest) error {
+	if !transferIDPattern.MatchString(req.TransferId) {
+		return fmt.Errorf("无效的传输标识: %q", req.TransferId)
+	}
+	if req.Filename != "" && (req.Filename != filepath.Base(req.Filename) || req.Filename == "." || req.Filename == "..") {
+		return fmt.Errorf("无效的文件名: %q", req.Filename)
+	}
+	return nil
+}
+
+func chunkMessage(requestID string, chunk *pb.FileChunk) *pb.AgentMessage {
+	chunk.RequestId = requestID
+	return &pb.AgentMessage{Payload: &pb.AgentMessage_FileChunk{FileChunk: chunk}}
+}
+
+// statFile 返回文件元数据，包括权限和属主
+func (f *FileManager) statFile(requestID, path string) (*pb.AgentMessage, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return chunkMessage(requestID, fileInfoChunk(info)), nil
+}
+
+func fileInfoChunk(info fs.FileInfo) *pb.FileChunk {
+	chunk := &pb.FileChunk{
+		Size:    info.Size(),
+		Mode:    uint32(info.Mode().Perm()),
+		ModTime: info.ModTime().Unix(),
+		IsDir:   info.IsDir(),
+	}
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		chunk.Owner = strconv.FormatUint(uint64(st.Uid), 10)
+		if u, err := user.LookupId(chunk.Owner); err == nil {
+			chunk.Owner = u.Username
+		}
+		chunk.Group = strconv.FormatUint(uint64(st.Gid), 10)
+		if g, err := user.LookupGroupId(chunk.Group); err == nil {
+			chunk.Group = g.Name
+		}
+	}
+	return chunk
+}
+
+// uploadInit 开始或恢复一次分片上传，返回已写入的字节数及其 SHA-256，调用方据此决定续传位置
+func (f *FileManager) uploadInit(req *pb.FileRequest, dir string) (*pb.AgentMessage, error) {
+	if err := validateTransfer(req); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建目录失败: %w", err)
+	}
+	part := partPath(dir, req.Filename, req.TransferId)
+	file, err := os.OpenFile(part, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > req.Size {
+		if err := file.Truncate(0); err != nil {
+			return nil, err
+		}
+	}
+	h := sha256.New()
+	n, err := io.Copy(h, file)
+	if err != nil {
+		return nil, err
+	}
+	return chunkMessage(req.RequestId, &pb.FileChunk{Offset: n, Sha256: hex.EncodeToString(h.Sum(nil))}), nil
+}
+
+// uploadChunk 在 offset 处写入分片，写入前截断到 offset，因此重发或回退到 0 都是安全的
+func (f *FileManager) uploadChunk(req *pb.FileRequest, dir string) (*pb.AgentMessage, error) {
+	if err := validateTransfer(req); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(partPath(dir, req.Filename, req.TransferId), os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("上传会话不存在: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if req.Offset < 0 || req.Offset > info.Size() {
+		return nil, fmt.Errorf("分片不连续: offset=%d, 已写入=%d", req.Offset, info.Size())
+	}
+	if err := file.Truncate(req.Offset); err != nil {
+		return nil, err
+	}
+	if _, err := file.WriteAt(req.Data, req.Offset); err != nil {
+		return nil, err
+	}
+	return chunkMessage(req.RequestId, &pb.FileChunk{Offset: req.Offset + int64(len(req.Data))}), nil
+}
+
+// uploadCommit 校验大小和 SHA-256 后将临时文件落盘，或按 tar.gz 解压到目录
+func (f *FileManager) uploadCommit(req *pb.FileRequest, dir string) (*pb.AgentMessage, error) {
+	if err := validateTransfer(req); err != nil {
+		return nil, err
+	}
+	part := partPath(dir, req.Filename, req.TransferId)
+	size, sum, err := fileSHA256(part)
+	if err != nil {
+		return nil, fmt.Errorf("上传会话不存在: %w", err)
+	}
+	if size != req.Size {
+		return nil, fmt.Errorf("文件大小不一致: 期望 %d, 实际 %d", req.Size, size)
+	}
+	if req.Sha256 != "" && !strings.EqualFold(sum, req.Sha256) {
+		os.Remove(part)
+		return nil, fmt.Errorf("SHA-256 校验失败: 期望 %s, 实际 %s", req.Sha256, sum)
+	}
+
+	target := filepath.Join(dir, req.Filename)
+	if req.Extract {
+		defer os.Remove(part)
+		if err := extractTarGz(part, target); err != nil {
+			return nil, fmt.Errorf("解压失败: %w", err)
+		}
+		info, err := os.Stat(target)
+		if err != nil {
+			return nil, err
+		}
+		chunk := fileInfoChunk(info)
+		chunk.Eof, chunk.Sha256 = true, sum
+		return chunkMessage(req.RequestId, chunk), nil
+	}
+
+	// 覆盖已有文件时默认沿用原有权限和属主
+	mode := fs.FileMode(req.Mode).Perm()
+	uid, gid := -1, -1
+	if prev, err := os.Lstat(target); err == nil {
+		if prev.IsDir() {
+			os.Remove(part)
+			return nil, fmt.Errorf("目标是目录: %s", target)
+		}
+		if mode == 0 {
+			mode = prev.Mode().Perm()
+		}
+		if st, ok := prev.Sys().(*syscall.Stat_t); ok {
+			uid, gid = int(st.Uid), int(st.Gid)
+		}
+	}
+	if mode == 0 {
+		mode = 0644
+	}
+	if err := os.Chmod(part, mode); err != nil {
+		return nil, err
+	}
+	if req.Owner != "" || req.Group != "" {
+		if uid, gid, err = lookupOwner(req.Owner, req.Group); err != nil {
+			return nil, err
+		}
+		if err := os.Lchown(part, uid, gid); err != nil {
+			return nil, fmt.Errorf("设置属主失败: %w", err)
+		}
+	} else if uid >= 0 {
+		os.Lchown(part, uid, gid)
+	}
+	if req.ModTime > 0 {
+		mtime := time.Unix(req.ModTime, 0)
+		os.Chtimes(part, mtime, mtime)
+	}
+	if err := os.Rename(part, target); err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		return nil, err
+	}
+	chunk := fileInfoChunk(info)
+	chunk.Eof, chunk.Sha256 = true, sum
+	return chunkMessage(req.RequestId, chunk), nil
+}
+
+// readFile 读取 [offset, offset+size) 区间，到达文件末尾时 Eof 为 true
+func (f *FileManager) readFile(req *pb.FileRequest, path string) (*pb.AgentMessage, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s 是目录，请先打包", path)
+	}
+	size := req.Size
+	if size <= 0 {
+		size = defaultReadSize
+	}
+	size = min(size, maxReadSize)
+	if req.Offset < 0 || req.Offset > info.Size() {
+		return nil, fmt.Errorf("offset 超出文件大小: %d > %d", req.Offset, info.Size())
+	}
+	buf := make([]byte, min(size, info.Size()-req.Offset))
+	n, err := file.ReadAt(buf, req.Offset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return chunkMessage(req.RequestId, &pb.FileChunk{
+		Data:   buf[:n],
+		Offset: req.Offset,
+		Size:   info.Size(),
+		Eof:    req.Offset+int64(n) >= info.Size(),
+	}), nil
+}
+
+// checksumFile 计算文件 SHA-256
+func (f *FileManager) checksumFile(requestID, path string) (*pb.AgentMessage, error) {
+	size, sum, err := fileSHA256(path)
+	if err != nil {
+		return nil, err
+	}
+	return chunkMessage(requestID, &pb.FileChunk{Size: size, Sha256: sum}), nil
+}
+
+// packDir 将目录打包为 tar.gz 临时文件，之后通过 read 分片下载
+func (f *FileManager) packDir(req *pb.FileRequest, path string) (*pb.AgentMessage, error) {
+	if err := validateTransfer(req); err != nil {
+		return nil, err
+	}
+	archive := packPath(req.TransferId)
+	if err := createTarGz(path, archive); err != nil {
+		os.Remove(archive)
+		return nil, fmt.Errorf("打包失败: %w", err)
+	}
+	size, sum, err := fileSHA256(archive)
+	if err != nil {
+		return nil, err
+	}
+	return chunkMessage(req.RequestId, &pb.FileChunk{Path: archive, Size: size, Sha256: sum, IsDir: true}), nil
+}
+
+// packCleanup 删除打包临时文件
+func (f *FileManager) packCleanup(req *pb.FileRequest) (*pb.AgentMessage, error) {
+	if err := validateTransfer(req); err != nil {
+		return nil, err
+	}
+	if err := os.Remove(packPath(req.TransferId)); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return chunkMessage(req.RequestId, &pb.FileChunk{Eof: true}), nil
+}
+
+func fileSHA256(path string) (int64, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer file.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, file)
+	if err != nil {
+		return 0, "", err
+	}
+	return n, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// lookupOwner 将用户名/组名（或数字 ID）解析为 uid/gid，未指定的返回 -1
+func lookupOwner(owner, group string) (int, int, error) {
+	uid, gid := -1, -1
+	if owner != "" {
+		id := owner
+		if _, err := strconv.Atoi(owner); err != nil {
+			u, err := user.Lookup(owner)
+			if err != nil {
+				return 0, 0, fmt.Errorf("用户不存在: %s", owner)
+			}
+			id = u.Uid
+		}
+		uid, _ = strconv.Atoi(id)
+	}
+	if group != "" {
+		id := group
+		if _, err := strconv.Atoi(group); err != nil {
+			g, err := user.LookupGroup(group)
+			if err != nil {
+				return 0, 0, fmt.Errorf("用户组不存在: %s", group)
+			}
+			id = g.Gid
+		}
+		gid, _ = strconv.Atoi(id)
+	}
+	return uid, gid, nil
+}
+
+// createTarGz 打包目录，条目以目录名为前缀并保留权限、属主和修改时间
+func createTarGz(dir, archive string) error {
+	out, err := os.OpenFile(archive, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+
+	dir = filepath.Clean(dir)
+	base := filepath.Base(dir)
+	err = filepath.Walk(dir, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		link := ""
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(filepath.Join(base, rel))
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(tw, file)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// extractTarGz 解压到 dest，拒绝越出目标目录的条目，并恢复权限、属主和修改时间
+func extractTarGz(archive, dest string) error {
+	in, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	gz, err := gzip.NewReader(in)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(gz)
+
+	dest = filepath.Clean(dest)
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return err
+	}
+	type dirTime struct {
+		path  string
+		mtime time.Time
+	}
+	var dirs []dirTime
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		target, err := safeJoin(dest, hdr.Name)
+		if err != nil {
+			return err
+		}
+		mode := fs.FileMode(hdr.Mode).Perm()
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			// 已存在的同名符号链接会让 MkdirAll/Chmod 作用到链接指向的目录
+			if fi, err := os.Lstat(target); err == nil && fi.Mode()&os.ModeSymlink != 0 {
+				return fmt.Errorf("非法路径: %s", hdr.Name)
+			}
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			os.Chmod(target, mode)
+			dirs = append(dirs, dirTime{target, hdr.ModTime})
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			os.Remove(target)
+			file, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(file, tr)
+			file.Close()
+			if err != nil {
+				return err
+			}
+			os.Chmod(target, mode)
+			os.Chtimes(target, hdr.ModTime, hdr.ModTime)
+		case tar.TypeSymlink:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			os.Remove(target)
+			if err := os.Symlink(hdr.Linkname, target); err != nil {
+				return err
+			}
+		default:
+			continue
+		}
+		// 以 root 运行时恢复属主，优先按名称解析
+		uid, gid, err := lookupOwner(hdr.Uname, hdr.Gname)
+		if err != nil || hdr.Uname == "" {
+			uid, gid = hdr.Uid, hdr.Gid
+		}
+		os.Lchown(target, uid, gid)
+	}
+	// 目录修改时间在写入子项后才恢复
+	for i := len(dirs) - 1; i >= 0; i-- {
+		os.Chtimes(dirs[i].path, dirs[i].mtime, dirs[i].mtime)
+	}
+	return nil
+}
+
+// safeJoin 拼接 tar 条目路径，防止通过 .. 或已解压的符号链接写到目标目录之外
+// 目标目录下的每一级已存在的父路径都必须是真实目录（不能是符号链接），任何检查错误均视为非法
+func safeJoin(dest, name string) (string, error) {
+	target := filepath.Join(dest, filepath.FromSlash(name))
+	if target != dest && !strings.HasPrefix(target, dest+string(filepath.Separator)) {
+		return "", fmt.Errorf("非法路径: %s", name)
+	}
+	rel, err := filepath.Rel(dest, filepath.Dir(target))
+	if err != nil {
+		return "", fmt.Errorf("非法路径: %s", name)
+	}
+	if rel == "." {
+		return target, nil
+	}
+	cur := dest
+	for _, elem := range strings.Split(rel, string(filepath.Separator)) {
+		cur = filepath.Join(cur, elem)
+		fi, err := os.Lstat(cur)
+		if errors.Is(err, fs.ErrNotExist) {
+			// 其余各级尚不存在，将由 MkdirAll 创建为普通目录
+			break
+		}
+		if err != nil || !fi.IsDir() {
+			return "", fmt.Errorf("非法路径: %s", name)
+		}
+	}
+	return target, nil
+}
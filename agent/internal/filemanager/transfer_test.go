@@ -0,0 +1,99 @@
+package filemanager
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTarGz 按顺序写入 tar 条目，用于构造恶意归档
+func writeTarGz(t *testing.T, path string, headers []*tar.Header) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for _, hdr := range headers {
+		if hdr.Typeflag == tar.TypeReg {
+			hdr.Size = int64(len("data"))
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			tw.Write([]byte("data"))
+		}
+	}
+	tw.Close()
+	gz.Close()
+}
+
+func TestExtractTarGz_RoundTrip(t *testing.T) {
+	src := t.TempDir()
+	os.MkdirAll(filepath.Join(src, "conf", "sub"), 0755)
+	os.WriteFile(filepath.Join(src, "conf", "sub", "app.yaml"), []byte("a: 1"), 0644)
+	os.Symlink("sub/app.yaml", filepath.Join(src, "conf", "link"))
+
+	archive := filepath.Join(t.TempDir(), "pack.tar.gz")
+	if err := createTarGz(src, archive); err != nil {
+		t.Fatalf("createTarGz() error: %v", err)
+	}
+	dest := t.TempDir()
+	if err := extractTarGz(archive, dest); err != nil {
+		t.Fatalf("extractTarGz() error: %v", err)
+	}
+	// 归档内包含源目录名
+	if data, err := os.ReadFile(filepath.Join(dest, filepath.Base(src), "conf", "link")); err != nil || string(data) != "a: 1" {
+		t.Errorf("extracted file = %q, %v", data, err)
+	}
+}
+
+func TestExtractTarGz_RejectsEscape(t *testing.T) {
+	cases := map[string][]*tar.Header{
+		"dotdot": {
+			{Name: "../evil", Typeflag: tar.TypeReg, Mode: 0644},
+		},
+		// 先写入指向目录外的符号链接，再经由该链接写入尚不存在的子目录
+		"symlink-new-dir": {
+			{Name: "a", Typeflag: tar.TypeSymlink, Linkname: "OUTSIDE"},
+			{Name: "a/newdir/file", Typeflag: tar.TypeReg, Mode: 0644},
+		},
+		"symlink-file": {
+			{Name: "a", Typeflag: tar.TypeSymlink, Linkname: "OUTSIDE"},
+			{Name: "a/file", Typeflag: tar.TypeReg, Mode: 0644},
+		},
+		"symlink-dir-entry": {
+			{Name: "a", Typeflag: tar.TypeSymlink, Linkname: "OUTSIDE"},
+			{Name: "a/", Typeflag: tar.TypeDir, Mode: 0777},
+		},
+	}
+	for name, headers := range cases {
+		t.Run(name, func(t *testing.T) {
+			outside := t.TempDir()
+			os.Chmod(outside, 0700)
+			for _, hdr := range headers {
+				if hdr.Linkname == "OUTSIDE" {
+					hdr.Linkname = outside
+				}
+			}
+			archive := filepath.Join(t.TempDir(), "evil.tar.gz")
+			writeTarGz(t, archive, headers)
+
+			if err := extractTarGz(archive, t.TempDir()); err == nil {
+				t.Fatal("extractTarGz() accepted an escaping entry")
+			}
+			entries, _ := os.ReadDir(outside)
+			if len(entries) != 0 {
+				t.Errorf("wrote outside dest: %v", entries)
+			}
+			if fi, _ := os.Stat(outside); fi.Mode().Perm() != 0700 {
+				t.Errorf("outside dir mode changed to %v", fi.Mode().Perm())
+			}
+		})
+	}
+}
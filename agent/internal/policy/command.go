@@ -0,0 +1,131 @@
+package policy
+
+import "strings"
+
+// parsedCommand 按 shell 语法粗略拆分的命令
+type parsedCommand struct {
+	segments     []string // 按 ; && || | & 换行拆分后的各条命令，已去除重定向
+	redirects    []string // 输出重定向的目标文件
+	substitution bool     // 包含 `...`、$(...)、<(...) 或 >(...)
+}
+
+// parseCommand 识别引号和转义，只在引号外拆分命令和识别重定向；
+// 双引号内的命令替换同样会被执行，因此也会被识别
+func parseCommand(command string) parsedCommand {
+	var (
+		result   parsedCommand
+		segment  strings.Builder
+		inSingle bool
+		inDouble bool
+	)
+	flush := func() {
+		if s := strings.Join(strings.Fields(segment.String()), " "); s != "" {
+			result.segments = append(result.segments, s)
+		}
+		segment.Reset()
+	}
+
+	runes := []rune(command)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		next := rune(0)
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+
+		switch {
+		case inSingle:
+			if r == '\'' {
+				inSingle = false
+			}
+			segment.WriteRune(r)
+			continue
+		case r == '\\':
+			segment.WriteRune(r)
+			if next != 0 {
+				segment.WriteRune(next)
+				i++
+			}
+			continue
+		case r == '`' || (r == '$' && next == '('):
+			result.substitution = true
+		case inDouble:
+			if r == '"' {
+				inDouble = false
+			}
+			segment.WriteRune(r)
+			continue
+		}
+
+		switch {
+		case r == '\'':
+			inSingle = true
+			segment.WriteRune(r)
+		case r == '"':
+			inDouble = true
+			segment.WriteRune(r)
+		case (r == '<' || r == '>') && next == '(':
+			result.substitution = true
+			segment.WriteRune(r)
+		case r == '>' || r == '<' || (r == '&' && next == '>'):
+			trimFD(&segment)
+			i = readRedirect(runes, i, &result)
+		case r == ';' || r == '\n' || r == '|' || r == '&':
+			flush()
+		default:
+			segment.WriteRune(r)
+		}
+	}
+	flush()
+	return result
+}
+
+// readRedirect 读取从 runes[i] 开始的重定向，返回最后消费的位置
+func readRedirect(runes []rune, i int, result *parsedCommand) int {
+	// 运算符：> >> >| &> &>> < << <<< <>，含 > 的会写文件
+	output := runes[i] != '<'
+	for i+1 < len(runes) && strings.ContainsRune("<>|&", runes[i+1]) {
+		if runes[i+1] == '&' {
+			// >&2、<&0、>&- 复制或关闭文件描述符；其后不是完整的编号时（>&file、>& file）与 &> 相同，写入文件
+			j := i + 2
+			for j < len(runes) && (runes[j] == '-' || (runes[j] >= '0' && runes[j] <= '9')) {
+				j++
+			}
+			if j > i+2 && (j == len(runes) || strings.ContainsRune(" \t\n;|&<>", runes[j])) {
+				return j - 1
+			}
+			i++
+			break
+		}
+		if runes[i+1] == '|' && !output {
+			break // "<|" 中的 | 是管道
+		}
+		output = output || runes[i+1] == '>'
+		i++
+	}
+	for i+1 < len(runes) && (runes[i+1] == ' ' || runes[i+1] == '\t') {
+		i++
+	}
+	var target strings.Builder
+	for i+1 < len(runes) && !strings.ContainsRune(" \t\n;|&<>", runes[i+1]) {
+		target.WriteRune(runes[i+1])
+		i++
+	}
+	if output {
+		result.redirects = append(result.redirects, strings.Trim(target.String(), `'"`))
+	}
+	return i
+}
+
+// trimFD 去除紧贴在重定向前的文件描述符编号（如 2>/dev/null 中的 2）
+func trimFD(segment *strings.Builder) {
+	s := segment.String()
+	j := len(s)
+	for j > 0 && s[j-1] >= '0' && s[j-1] <= '9' {
+		j--
+	}
+	if j < len(s) && (j == 0 || s[j-1] == ' ' || s[j-1] == '\t') {
+		segment.Reset()
+		segment.WriteString(s[:j])
+	}
+}
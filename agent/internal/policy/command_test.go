@@ -0,0 +1,58 @@
+package policy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		command      string
+		segments     []string
+		redirects    []string
+		substitution bool
+	}{
+		{"uptime", []string{"uptime"}, nil, false},
+		{"a;b", []string{"a", "b"}, nil, false},
+		{"a && b || c", []string{"a", "b", "c"}, nil, false},
+		{"a|sh", []string{"a", "sh"}, nil, false},
+		{"a & b", []string{"a", "b"}, nil, false},
+		{"a\nb", []string{"a", "b"}, nil, false},
+		{"echo $(id)", []string{"echo $(id)"}, nil, true},
+		{"echo `id`", []string{"echo `id`"}, nil, true},
+		{`echo "$(id)"`, []string{`echo "$(id)"`}, nil, true},
+		{"diff <(ls a) b", []string{"diff <(ls a) b"}, nil, true},
+		{"tee >(sh)", []string{"tee >(sh)"}, nil, true},
+		{"echo x >file", []string{"echo x"}, []string{"file"}, false},
+		{"echo x >> /tmp/f", []string{"echo x"}, []string{"/tmp/f"}, false},
+		{"echo x &>file", []string{"echo x"}, []string{"file"}, false},
+		{"echo x >|file", []string{"echo x"}, []string{"file"}, false},
+		{`echo x > "/etc/cron.d/a"`, []string{"echo x"}, []string{"/etc/cron.d/a"}, false},
+		{"ls 2>/dev/null", []string{"ls"}, []string{"/dev/null"}, false},
+		{"echo x >&2", []string{"echo x"}, nil, false},
+		{"ls 2>&1", []string{"ls"}, nil, false},
+		{"ls >&-", []string{"ls"}, nil, false},
+		{"cat <&0", []string{"cat"}, nil, false},
+		// >& 后不是完整的描述符编号时写入文件
+		{"ls >&/etc/cron.d/x", []string{"ls"}, []string{"/etc/cron.d/x"}, false},
+		{"ls >& /root/.ssh/authorized_keys", []string{"ls"}, []string{"/root/.ssh/authorized_keys"}, false},
+		{"ls >&2x", []string{"ls"}, []string{"2x"}, false},
+		{`ls >&"/tmp/f"`, []string{"ls"}, []string{"/tmp/f"}, false},
+		{"sort < in", []string{"sort"}, nil, false},
+		// 引号和转义内的分隔符、替换和重定向不生效（单引号内的命令替换不会执行）
+		{`echo "a;rm -rf /"`, []string{`echo "a;rm -rf /"`}, nil, false},
+		{"echo 'a && b | sh'", []string{"echo 'a && b | sh'"}, nil, false},
+		{"echo '$(id)' '`id`'", []string{"echo '$(id)' '`id`'"}, nil, false},
+		{`echo a\;rm`, []string{`echo a\;rm`}, nil, false},
+		{`echo "x > y"`, []string{`echo "x > y"`}, nil, false},
+		// 引号闭合后分隔符恢复生效
+		{`echo "a";rm -rf /`, []string{`echo "a"`, "rm -rf /"}, nil, false},
+	}
+	for _, tt := range tests {
+		got := parseCommand(tt.command)
+		if !reflect.DeepEqual(got.segments, tt.segments) || !reflect.DeepEqual(got.redirects, tt.redirects) || got.substitution != tt.substitution {
+			t.Errorf("parseCommand(%q) = %q %q %v, want %q %q %v", tt.command,
+				got.segments, got.redirects, got.substitution, tt.segments, tt.redirects, tt.substitution)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ydcloud-dy/opshub/agent/internal/config"
+)
+
+// 违规类型
+const (
+	KindCommand  = "command"
+	KindFile     = "file"
+	KindTerminal = "terminal"
+	KindTunnel   = "tunnel"
+	KindUpgrade  = "upgrade"
+)
+
+// writeActions 会修改文件系统的文件操作
+var writeActions = map[string]bool{
+	"upload":        true,
+	"delete":        true,
+	"upload_init":   true,
+	"upload_chunk":  true,
+	"upload_commit": true,
+}
+
+// recursiveActions 作用于目录下全部内容的文件操作，目标目录内含禁止路径时同样拒绝
+var recursiveActions = map[string]bool{
+	"delete":        true,
+	"pack":          true,
+	"upload_commit": true,
+}
+
+// Violation 违反本地策略的请求
+type Violation struct {
+	Kind   string
+	Action string
+	Target string
+	Reason string
+}
+
+func (v *Violation) Error() string {
+	return "本地策略拒绝: " + v.Reason
+}
+
+// Policy 已编译的本地安全策略
+type Policy struct {
+	allowed   []*regexp.Regexp
+	forbidden []string
+	cfg       config.Policy
+}
+
+// New 编译策略，命令正则非法时返回错误
+func New(cfg config.Policy) (*Policy, error) {
+	p := &Policy{cfg: cfg}
+	for _, pattern := range cfg.AllowedCommands {
+		re, err := regexp.Compile(`^(?:` + pattern + `)$`)
+		if err != nil {
+			return nil, fmt.Errorf("allowed_commands 正则 %q 无效: %w", pattern, err)
+		}
+		p.allowed = append(p.allowed, re)
+	}
+	for _, path := range cfg.ForbiddenPaths {
+		if !filepath.IsAbs(path) {
+			return nil, fmt.Errorf("forbidden_paths 必须是绝对路径: %q", path)
+		}
+		p.forbidden = append(p.forbidden, filepath.Clean(path))
+	}
+	return p, nil
+}
+
+// Enabled 是否配置了任何限制
+func (p *Policy) Enabled() bool {
+	return len(p.allowed) > 0 || len(p.forbidden) > 0 || p.cfg.DisableTerminal || p.cfg.DisableTunnel || p.cfg.DisableUpgrade || p.cfg.ReadOnly
+}
+
+// CheckTerminal 检查是否允许打开终端
+func (p *Policy) CheckTerminal() error {
+	switch {
+	case p.cfg.ReadOnly:
+		return &Violation{Kind: KindTerminal, Reason: "只读模式禁止打开终端"}
+	case p.cfg.DisableTerminal:
+		return &Violation{Kind: KindTerminal, Reason: "已禁止打开终端"}
+	}
+	return nil
+}
+
+// CheckTunnel 检查是否允许建立到 target 的端口转发
+func (p *Policy) CheckTunnel(target string) error {
+	switch {
+	case p.cfg.ReadOnly:
+		return &Violation{Kind: KindTunnel, Target: target, Reason: "只读模式禁止端口转发"}
+	case p.cfg.DisableTunnel:
+		return &Violation{Kind: KindTunnel, Target: target, Reason: "已禁止端口转发"}
+	}
+	return nil
+}
+
+// CheckUpgrade 检查是否允许服务端下发的远程升级，升级会替换Agent二进制，只读模式下一律拒绝
+func (p *Policy) CheckUpgrade(version string) error {
+	switch {
+	case p.cfg.ReadOnly:
+		return &Violation{Kind: KindUpgrade, Target: version, Reason: "只读模式禁止远程升级"}
+	case p.cfg.DisableUpgrade:
+		return &Violation{Kind: KindUpgrade, Target: version, Reason: "已禁止远程升级"}
+	}
+	return nil
+}
+
+// CheckCommand 检查命令是否在允许列表内
+func (p *Policy) CheckCommand(command string) error {
+	if len(p.allowed) == 0 {
+		if p.cfg.ReadOnly {
+			return &Violation{Kind: KindCommand, Target: command, Reason: "只读模式下未配置 allowed_commands，禁止执行命令"}
+		}
+		return nil
+	}
+
+	parsed := parseCommand(command)
+	if parsed.substitution {
+		return &Violation{Kind: KindCommand, Target: command, Reason: "不允许使用命令替换"}
+	}
+	for _, target := range parsed.redirects {
+		if target != "/dev/null" {
+			return &Violation{Kind: KindCommand, Target: command, Reason: fmt.Sprintf("不允许重定向输出到 %s", target)}
+		}
+	}
+	for _, segment := range parsed.segments {
+		if !p.commandAllowed(segment) {
+			return &Violation{Kind: KindCommand, Target: command, Reason: fmt.Sprintf("命令 %q 不在允许列表中", segment)}
+		}
+	}
+	return nil
+}
+
+func (p *Policy) commandAllowed(segment string) bool {
+	for _, re := range p.allowed {
+		if re.MatchString(segment) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckFile 检查文件操作，path 为请求路径，上传类操作的目标为 path/filename
+func (p *Policy) CheckFile(action, path, filename string) error {
+	if action == "pack_cleanup" {
+		return nil // 只清理 Agent 自己生成的临时文件
+	}
+	if p.cfg.ReadOnly && writeActions[action] {
+		return &Violation{Kind: KindFile, Action: action, Target: path, Reason: "只读模式禁止修改文件"}
+	}
+	if len(p.forbidden) == 0 {
+		return nil
+	}
+
+	target := expandPath(path)
+	if filename != "" && strings.HasPrefix(action, "upload") {
+		target = filepath.Join(target, filename)
+	}
+	for _, candidate := range resolvePaths(target) {
+		for _, forbidden := range p.forbidden {
+			if within(candidate, forbidden) || (recursiveActions[action] && within(forbidden, candidate)) {
+				return &Violation{Kind: KindFile, Action: action, Target: target, Reason: fmt.Sprintf("禁止访问 %s", forbidden)}
+			}
+		}
+	}
+	return nil
+}
+
+// AsViolation 判断错误是否为策略违规
+func AsViolation(err error) (*Violation, bool) {
+	var v *Violation
+	ok := errors.As(err, &v)
+	return v, ok
+}
+
+// within path 是否等于 dir 或位于 dir 之下
+func within(path, dir string) bool {
+	if dir == "/" {
+		return true
+	}
+	return path == dir || strings.HasPrefix(path, dir+"/")
+}
+
+func expandPath(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = home + path[1:]
+		}
+	}
+	if path == "" {
+		return "/"
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// resolvePaths 返回路径本身及解析符号链接后的真实路径，防止通过软链接绕过
+// 目标不存在时解析最近的已存在上级目录
+func resolvePaths(path string) []string {
+	paths := []string{path}
+	dir, rest := path, ""
+	for {
+		if real, err := filepath.EvalSymlinks(dir); err == nil {
+			real = filepath.Join(real, rest)
+			if real != path {
+				paths = append(paths, real)
+			}
+			return paths
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return paths
+		}
+		rest = filepath.Join(filepath.Base(dir), rest)
+		dir = parent
+	}
+}
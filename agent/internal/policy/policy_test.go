@@ -0,0 +1,167 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ydcloud-dy/opshub/agent/internal/config"
+)
+
+func newPolicy(t *testing.T, cfg config.Policy) *Policy {
+	t.Helper()
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestNew_Invalid(t *testing.T) {
+	if _, err := New(config.Policy{AllowedCommands: []string{"("}}); err == nil {
+		t.Error("invalid regexp accepted")
+	}
+	if _, err := New(config.Policy{ForbiddenPaths: []string{"etc/shadow"}}); err == nil {
+		t.Error("relative forbidden path accepted")
+	}
+}
+
+func TestCheckCommand(t *testing.T) {
+	p := newPolicy(t, config.Policy{AllowedCommands: []string{`uptime`, `df -h`, `echo .*`, `cat /var/log/[a-z]+\.log`}})
+	tests := []struct {
+		command string
+		allowed bool
+	}{
+		{"uptime", true},
+		{"  uptime  ", true},
+		{"uptime; df -h", true},
+		{"echo hi 2>/dev/null", true},
+		{"echo hi >&2", true},
+		{`echo "a;rm -rf /"`, true},
+		{"echo 'a && reboot'", true},
+		{"echo 'a | sh'", true},
+		{"echo '$(id)'", true},
+		{`echo a\;reboot`, true},
+		// 正则完整匹配，不能借前缀或后缀夹带
+		{"uptime -p", false},
+		{"cat /var/log/../../etc/shadow", false},
+		// 分隔符拼接的每一段都需允许
+		{"uptime;reboot", false},
+		{"uptime && reboot", false},
+		{"uptime || reboot", false},
+		{"uptime | sh", false},
+		{"echo id|sh", false},
+		{"uptime & reboot", false},
+		{"uptime\nreboot", false},
+		{`echo "a";reboot`, false},
+		// 命令替换
+		{"echo $(reboot)", false},
+		{"echo `reboot`", false},
+		{`echo "$(reboot)"`, false},
+		{"echo <(reboot)", false},
+		// 重定向到文件
+		{"echo x >/etc/cron.d/x", false},
+		{"echo x>>/root/.ssh/authorized_keys", false},
+		{"echo x &>/tmp/f", false},
+		{"echo x 2>/tmp/f", false},
+		{"echo x >|/tmp/f", false},
+		{`echo x > "/tmp/f"`, false},
+		{"echo x >&/etc/cron.d/x", false},
+		{"echo x >& /root/.ssh/authorized_keys", false},
+	}
+	for _, tt := range tests {
+		err := p.CheckCommand(tt.command)
+		if (err == nil) != tt.allowed {
+			t.Errorf("CheckCommand(%q) = %v, want allowed=%v", tt.command, err, tt.allowed)
+		}
+		if err != nil {
+			if v, ok := AsViolation(err); !ok || v.Kind != KindCommand {
+				t.Errorf("CheckCommand(%q) error %v is not a command violation", tt.command, err)
+			}
+		}
+	}
+
+	// 未配置允许列表时不限制，只读模式下未配置则全部拒绝
+	if err := newPolicy(t, config.Policy{}).CheckCommand("rm -rf /tmp/x"); err != nil {
+		t.Errorf("no allow list = %v", err)
+	}
+	if err := newPolicy(t, config.Policy{ReadOnly: true}).CheckCommand("uptime"); err == nil {
+		t.Error("read-only without allow list should reject commands")
+	}
+}
+
+func TestCheckFile_Forbidden(t *testing.T) {
+	root := t.TempDir()
+	secret := filepath.Join(root, "secret")
+	public := filepath.Join(root, "public")
+	os.MkdirAll(filepath.Join(secret, "keys"), 0755)
+	os.MkdirAll(public, 0755)
+	os.WriteFile(filepath.Join(secret, "keys", "id_rsa"), []byte("key"), 0600)
+	os.WriteFile(filepath.Join(public, "readme"), []byte("hi"), 0644)
+	// 指向禁止路径的文件和目录软链接
+	os.Symlink(filepath.Join(secret, "keys", "id_rsa"), filepath.Join(public, "key-link"))
+	os.Symlink(secret, filepath.Join(public, "dir-link"))
+	os.Symlink(filepath.Join(secret, "keys"), filepath.Join(root, "keys-link"))
+
+	p := newPolicy(t, config.Policy{ForbiddenPaths: []string{secret}})
+	tests := []struct {
+		action, path, filename string
+		allowed                bool
+	}{
+		{"download", filepath.Join(public, "readme"), "", true},
+		{"list", public, "", true},
+		{"upload", public, "new.txt", true},
+		{"download", filepath.Join(secret, "keys", "id_rsa"), "", false},
+		{"list", secret, "", false},
+		{"download", secret + "/keys/../keys/id_rsa", "", false},
+		{"download", public + "/../secret/keys/id_rsa", "", false},
+		// 软链接解析后位于禁止路径
+		{"download", filepath.Join(public, "key-link"), "", false},
+		{"list", filepath.Join(public, "dir-link"), "", false},
+		{"download", filepath.Join(public, "dir-link", "keys", "id_rsa"), "", false},
+		{"upload", filepath.Join(root, "keys-link"), "authorized_keys", false},
+		// 目标尚不存在时按最近的已存在上级目录解析
+		{"upload", filepath.Join(public, "dir-link", "new"), "x", false},
+		{"upload", public, "../secret/x", false},
+		// 递归操作的目标目录包含禁止路径
+		{"delete", root, "", false},
+		{"pack", root, "", false},
+		{"list", root, "", true},
+		{"pack_cleanup", filepath.Join(secret, "keys"), "", true},
+	}
+	for _, tt := range tests {
+		err := p.CheckFile(tt.action, tt.path, tt.filename)
+		if (err == nil) != tt.allowed {
+			t.Errorf("CheckFile(%s, %s, %s) = %v, want allowed=%v", tt.action, tt.path, tt.filename, err, tt.allowed)
+		}
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	p := newPolicy(t, config.Policy{ReadOnly: true, AllowedCommands: []string{`cat .*`}})
+	dir := t.TempDir()
+	for action := range writeActions {
+		if err := p.CheckFile(action, dir, "f"); err == nil {
+			t.Errorf("read-only allowed %s", action)
+		}
+	}
+	for _, action := range []string{"list", "download", "pack", "pack_cleanup"} {
+		if err := p.CheckFile(action, dir, ""); err != nil {
+			t.Errorf("read-only rejected %s: %v", action, err)
+		}
+	}
+	if err := p.CheckCommand("cat /etc/hosts"); err != nil {
+		t.Errorf("read-only rejected allowed command: %v", err)
+	}
+	for _, command := range []string{"cat /etc/hosts > /tmp/hosts", "cat /etc/hosts >&/tmp/hosts", "cat /etc/hosts >& /tmp/hosts"} {
+		if err := p.CheckCommand(command); err == nil {
+			t.Errorf("read-only allowed output redirect: %q", command)
+		}
+	}
+	if p.CheckTerminal() == nil || p.CheckTunnel("127.0.0.1:22") == nil || p.CheckUpgrade("v2") == nil {
+		t.Error("read-only should reject terminal, tunnel and upgrade")
+	}
+	if !p.Enabled() || newPolicy(t, config.Policy{}).Enabled() {
+		t.Error("Enabled() mismatch")
+	}
+}
@@ -0,0 +1,133 @@
+package portforward
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ydcloud-dy/opshub/agent/internal/client"
+	"github.com/ydcloud-dy/opshub/agent/internal/logger"
+	pb "github.com/ydcloud-dy/opshub/pkg/agentproto"
+	"github.com/ydcloud-dy/opshub/pkg/tunnel"
+)
+
+// defaultConnectTimeout 服务端未指定时的连接超时
+const defaultConnectTimeout = 10 * time.Second
+
+// Manager 管理 Agent 侧的 TCP 隧道连接
+type Manager struct {
+	grpc  *client.GRPCClient
+	mu    sync.Mutex
+	conns map[string]*tunnel.Endpoint
+}
+
+// NewManager 创建隧道管理器
+func NewManager(grpc *client.GRPCClient) *Manager {
+	return &Manager{grpc: grpc, conns: make(map[string]*tunnel.Endpoint)}
+}
+
+// Open 连接目标地址，成功后开始双向转发
+func (m *Manager) Open(req *pb.TunnelOpen) {
+	timeout := time.Duration(req.ConnectTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = defaultConnectTimeout
+	}
+	conn, err := net.DialTimeout("tcp", req.Target, timeout)
+	if err != nil {
+		logger.Warn("隧道连接目标失败: connID=%s, target=%s, error=%v", req.ConnId, req.Target, err)
+		m.send(&pb.AgentMessage{Payload: &pb.AgentMessage_TunnelOpenResult{
+			TunnelOpenResult: &pb.TunnelOpenResult{ConnId: req.ConnId, Error: err.Error()},
+		}})
+		return
+	}
+
+	e := tunnel.NewEndpoint(req.ConnId, conn, m, tunnel.DefaultWindow)
+	m.mu.Lock()
+	m.conns[req.ConnId] = e
+	m.mu.Unlock()
+
+	// 先回复结果再开始读取，保证服务端先收到结果再收到数据
+	m.send(&pb.AgentMessage{Payload: &pb.AgentMessage_TunnelOpenResult{
+		TunnelOpenResult: &pb.TunnelOpenResult{ConnId: req.ConnId, Success: true, Window: tunnel.DefaultWindow},
+	}})
+	logger.Info("隧道已建立: connID=%s, target=%s", req.ConnId, req.Target)
+	e.Start(int(req.Window))
+
+	go func() {
+		<-e.Done()
+		in, out := e.Stats()
+		logger.Info("隧道已关闭: connID=%s, in=%d, out=%d, reason=%s", req.ConnId, in, out, e.Reason())
+		m.mu.Lock()
+		delete(m.conns, req.ConnId)
+		m.mu.Unlock()
+	}()
+}
+
+// Data 服务端发来的数据
+func (m *Manager) Data(connID string, data []byte) {
+	if e := m.get(connID); e != nil {
+		e.Deliver(data)
+	}
+}
+
+// Ack 服务端已写出的字节数
+func (m *Manager) Ack(connID string, n int) {
+	if e := m.get(connID); e != nil {
+		e.Ack(n)
+	}
+}
+
+// Close 服务端关闭连接
+func (m *Manager) Close(connID, reason string) {
+	if e := m.get(connID); e != nil {
+		e.Shutdown(reason)
+	}
+}
+
+// CloseAll 与服务端断开时关闭所有连接
+func (m *Manager) CloseAll() {
+	m.mu.Lock()
+	conns := make([]*tunnel.Endpoint, 0, len(m.conns))
+	for _, e := range m.conns {
+		conns = append(conns, e)
+	}
+	m.mu.Unlock()
+	for _, e := range conns {
+		e.Close("与服务端断开连接")
+	}
+}
+
+func (m *Manager) get(connID string) *tunnel.Endpoint {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.conns[connID]
+}
+
+func (m *Manager) send(msg *pb.AgentMessage) error {
+	err := m.grpc.SendMessage(msg)
+	if err != nil {
+		logger.Debug("发送隧道消息失败: %v", err)
+	}
+	return err
+}
+
+// SendData 实现 tunnel.Peer
+func (m *Manager) SendData(connID string, data []byte) error {
+	return m.send(&pb.AgentMessage{Payload: &pb.AgentMessage_TunnelData{
+		TunnelData: &pb.TunnelData{ConnId: connID, Data: data},
+	}})
+}
+
+// SendAck 实现 tunnel.Peer
+func (m *Manager) SendAck(connID string, n int) error {
+	return m.send(&pb.AgentMessage{Payload: &pb.AgentMessage_TunnelAck{
+		TunnelAck: &pb.TunnelAck{ConnId: connID, Bytes: int32(n)},
+	}})
+}
+
+// SendClose 实现 tunnel.Peer
+func (m *Manager) SendClose(connID, reason string) error {
+	return m.send(&pb.AgentMessage{Payload: &pb.AgentMessage_TunnelClose{
+		TunnelClose: &pb.TunnelClose{ConnId: connID, Error: reason},
+	}})
+}
@@ -0,0 +1,362 @@
+package upgrader
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ydcloud-dy/opshub/agent/internal/config"
+	"github.com/ydcloud-dy/opshub/agent/internal/logger"
+	pb "github.com/ydcloud-dy/opshub/pkg/agentproto"
+)
+
+const (
+	// defaultRollbackTimeout 未指定时新版本完成注册的期限（秒）
+	defaultRollbackTimeout = 120
+	// minConfirmWait 启动后至少等待的确认时间，避免停机较久后刚启动就回滚
+	minConfirmWait = 30 * time.Second
+	// maxBootAttempts 新版本最多启动次数，超过仍未确认则直接回滚
+	maxBootAttempts = 3
+	// stateFileName 升级状态文件，与可执行文件同目录
+	stateFileName = ".srehub-agent-upgrade.json"
+	// stageDir 服务端上传升级包的暂存目录，与服务端保持一致
+	stageDir = "/tmp"
+)
+
+// State 待确认的升级，重启后由新版本读取
+type State struct {
+	UpgradeID   string    `json:"upgrade_id"`
+	FromVersion string    `json:"from_version"`
+	ToVersion   string    `json:"to_version"`
+	Backup      string    `json:"backup"`
+	Deadline    time.Time `json:"deadline"`
+	Attempts    int       `json:"attempts"`
+	RolledBack  bool      `json:"rolled_back"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// Upgrader Agent自升级：校验签名后原子替换二进制并原地重启，新版本未能重新注册时回滚
+type Upgrader struct {
+	cfg     *config.Config
+	version string
+	exe     string
+
+	mu    sync.Mutex
+	state *State
+	timer *time.Timer
+}
+
+// New 创建升级器
+func New(cfg *config.Config, version string) (*Upgrader, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("获取可执行文件路径失败: %w", err)
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return nil, fmt.Errorf("解析可执行文件路径失败: %w", err)
+	}
+	return &Upgrader{cfg: cfg, version: version, exe: exe}, nil
+}
+
+func (u *Upgrader) statePath() string {
+	return filepath.Join(filepath.Dir(u.exe), stateFileName)
+}
+
+// Boot 启动时检查未确认的升级：多次启动仍未确认则直接回滚，否则到期未确认时回滚
+func (u *Upgrader) Boot() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	data, err := os.ReadFile(u.statePath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Warn("读取升级状态失败: %v", err)
+		}
+		return
+	}
+	st := &State{}
+	if err := json.Unmarshal(data, st); err != nil {
+		logger.Warn("解析升级状态失败，忽略: %v", err)
+		os.Remove(u.statePath())
+		return
+	}
+	u.state = st
+	if st.RolledBack {
+		logger.Warn("升级到 %s 失败，已回滚到 %s: %s", st.ToVersion, st.FromVersion, st.Error)
+		return
+	}
+
+	st.Attempts++
+	if st.Attempts > maxBootAttempts {
+		u.rollbackLocked(fmt.Sprintf("新版本启动 %d 次仍未完成注册", maxBootAttempts))
+		return
+	}
+	if err := u.saveState(st); err != nil {
+		logger.Warn("保存升级状态失败: %v", err)
+	}
+	wait := max(time.Until(st.Deadline), minConfirmWait)
+	logger.Info("升级到 %s 待确认，%s 内未完成注册将回滚", st.ToVersion, wait.Round(time.Second))
+	u.timer = time.AfterFunc(wait, func() {
+		u.mu.Lock()
+		defer u.mu.Unlock()
+		u.rollbackLocked("新版本未在限定时间内完成注册")
+	})
+}
+
+// PendingUpgrade 返回注册时需要上报的升级结果
+func (u *Upgrader) PendingUpgrade() (upgradeID string, rolledBack bool, errMsg string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.state == nil {
+		return "", false, ""
+	}
+	return u.state.UpgradeID, u.state.RolledBack, u.state.Error
+}
+
+// Confirm 注册成功后确认升级结果：取消回滚计时并清理状态文件
+func (u *Upgrader) Confirm() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.state == nil {
+		return
+	}
+	if u.timer != nil {
+		u.timer.Stop()
+		u.timer = nil
+	}
+	if err := os.Remove(u.statePath()); err != nil && !os.IsNotExist(err) {
+		logger.Warn("清理升级状态失败: %v", err)
+	}
+	if !u.state.RolledBack {
+		logger.Info("升级到 %s 已确认", u.state.ToVersion)
+	}
+	u.state = nil
+}
+
+// Upgrade 校验并安装新版本，成功后需调用 Restart 切换到新二进制
+func (u *Upgrader) Upgrade(req *pb.AgentUpgrade) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	// 以 root 运行时 req.Path 可指向任意文件，只接受暂存目录下的升级包
+	if err := checkStagedPath(req.Path); err != nil {
+		return err
+	}
+	if u.state != nil && !u.state.RolledBack {
+		return fmt.Errorf("升级到 %s 尚未确认", u.state.ToVersion)
+	}
+	if u.cfg.UpgradePublicKey == "" {
+		return errors.New("未配置 upgrade_public_key，拒绝远程升级")
+	}
+	pub, err := base64.StdEncoding.DecodeString(u.cfg.UpgradePublicKey)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return errors.New("upgrade_public_key 格式错误")
+	}
+	// 策略与公钥检查通过后才清理暂存的升级包
+	defer os.Remove(req.Path)
+
+	if pb.IsDowngrade(u.version, req.Version) && !req.AllowDowngrade {
+		return fmt.Errorf("拒绝从 %s 降级到 %s", u.version, req.Version)
+	}
+	data, err := readStaged(req.Path)
+	if err != nil {
+		return fmt.Errorf("读取升级包失败: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+	if digest != req.Sha256 {
+		return fmt.Errorf("SHA-256 校验失败: 期望 %s, 实际 %s", req.Sha256, digest)
+	}
+	// 签名覆盖版本、平台与摘要，旧版本或其他平台的已签名二进制无法冒充
+	if !ed25519.Verify(pub, pb.UpgradeManifest(req.Version, runtime.GOOS, runtime.GOARCH, digest), req.Signature) {
+		return errors.New("签名校验失败")
+	}
+
+	// 写入同目录临时文件，确保最后的重命名是原子操作
+	staged := u.exe + ".new"
+	if err := writeFileSync(staged, data, 0755); err != nil {
+		return fmt.Errorf("写入新版本失败: %w", err)
+	}
+	if err := preflight(staged, req.Version); err != nil {
+		os.Remove(staged)
+		return err
+	}
+
+	backup := u.exe + ".bak"
+	if err := copyFile(u.exe, backup); err != nil {
+		os.Remove(staged)
+		return fmt.Errorf("备份当前版本失败: %w", err)
+	}
+
+	timeout := req.RollbackTimeout
+	if timeout <= 0 {
+		timeout = defaultRollbackTimeout
+	}
+	st := &State{
+		UpgradeID:   req.RequestId,
+		FromVersion: u.version,
+		ToVersion:   req.Version,
+		Backup:      backup,
+		Deadline:    time.Now().Add(time.Duration(timeout) * time.Second),
+	}
+	// 先写状态再替换，替换后任何时刻重启都能由新版本接管回滚
+	if err := u.saveState(st); err != nil {
+		os.Remove(staged)
+		return fmt.Errorf("保存升级状态失败: %w", err)
+	}
+	if err := os.Rename(staged, u.exe); err != nil {
+		os.Remove(staged)
+		os.Remove(u.statePath())
+		return fmt.Errorf("替换二进制失败: %w", err)
+	}
+	u.state = st
+	logger.Info("已安装新版本 %s（备份 %s），即将重启", req.Version, backup)
+	return nil
+}
+
+// Restart 以当前路径的二进制替换进程，PID 不变，systemd 等进程管理器无感知
+func (u *Upgrader) Restart() {
+	logger.Info("重新启动Agent: %s", u.exe)
+	err := syscall.Exec(u.exe, os.Args, os.Environ())
+	// Exec 成功不会返回，失败时退出交由进程管理器拉起
+	logger.Error("重新启动失败: %v", err)
+	os.Exit(1)
+}
+
+// rollbackLocked 恢复备份的二进制并重启，调用方需持有锁
+func (u *Upgrader) rollbackLocked(reason string) {
+	st := u.state
+	if st == nil || st.RolledBack {
+		return
+	}
+	logger.Error("升级到 %s 失败，回滚到 %s: %s", st.ToVersion, st.FromVersion, reason)
+	data, err := os.ReadFile(st.Backup)
+	if err == nil {
+		err = writeFileSync(u.exe+".new", data, 0755)
+	}
+	if err == nil {
+		err = os.Rename(u.exe+".new", u.exe)
+	}
+	if err != nil {
+		logger.Error("回滚失败: %v", err)
+		return
+	}
+	st.RolledBack, st.Error = true, reason
+	if err := u.saveState(st); err != nil {
+		logger.Warn("保存升级状态失败: %v", err)
+	}
+	u.Restart()
+}
+
+func (u *Upgrader) saveState(st *State) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	tmp := u.statePath() + ".tmp"
+	if err := writeFileSync(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, u.statePath())
+}
+
+// preflight 确认新版本能在本机执行且版本号一致
+func preflight(path, version string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, path, "version").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("新版本无法执行: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	if version != "" && !strings.Contains(string(out), version) {
+		return fmt.Errorf("新版本号不一致: 期望 %s, 实际 %s", version, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// checkStagedPath 升级包必须是暂存目录下直接存放的普通文件（不能是符号链接），文件名由服务端生成
+func checkStagedPath(path string) error {
+	name := filepath.Base(path)
+	if path != filepath.Join(stageDir, name) || !strings.HasPrefix(name, "srehub-agent-") || !strings.HasSuffix(name, ".upgrade") {
+		return fmt.Errorf("非法的升级包路径: %s", path)
+	}
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return fmt.Errorf("读取升级包失败: %w", err)
+	}
+	if !fi.Mode().IsRegular() {
+		return fmt.Errorf("升级包不是普通文件: %s", path)
+	}
+	return nil
+}
+
+// readStaged 不跟随符号链接读取升级包，避免检查后被替换
+func readStaged(path string) ([]byte, error) {
+	f, err := os.OpenFile(path, os.O_RDONLY|syscall.O_NOFOLLOW, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if !fi.Mode().IsRegular() {
+		return nil, fmt.Errorf("升级包不是普通文件: %s", path)
+	}
+	return io.ReadAll(f)
+}
+
+func writeFileSync(path string, data []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Chmod(path, perm)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,104 @@
+package upgrader
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ydcloud-dy/opshub/agent/internal/config"
+	pb "github.com/ydcloud-dy/opshub/pkg/agentproto"
+)
+
+func newTestUpgrader(t *testing.T, publicKey string) *Upgrader {
+	t.Helper()
+	u, err := New(&config.Config{UpgradePublicKey: publicKey}, "1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 指向临时二进制，避免测试中替换真实可执行文件
+	u.exe = filepath.Join(t.TempDir(), "srehub-agent")
+	return u
+}
+
+// stageFile 在暂存目录下创建升级包
+func stageFile(t *testing.T) string {
+	t.Helper()
+	f, err := os.CreateTemp(stageDir, "srehub-agent-test-*.upgrade")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("binary")
+	f.Close()
+	t.Cleanup(func() { os.Remove(f.Name()) })
+	return f.Name()
+}
+
+func TestUpgrade_RejectsPathOutsideStageDir(t *testing.T) {
+	pub, _, _ := ed25519.GenerateKey(rand.Reader)
+	u := newTestUpgrader(t, base64.StdEncoding.EncodeToString(pub))
+
+	victim := filepath.Join(t.TempDir(), "important.conf")
+	os.WriteFile(victim, []byte("keep"), 0644)
+	link := filepath.Join(stageDir, "srehub-agent-test-link.upgrade")
+	os.Remove(link)
+	if err := os.Symlink(victim, link); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(link)
+	other, _ := os.CreateTemp(stageDir, "other-*.conf")
+	other.Close()
+	defer os.Remove(other.Name())
+
+	for _, path := range []string{victim, link, other.Name(), stageDir + "/../" + filepath.Base(victim), "srehub-agent-1.upgrade"} {
+		if err := u.Upgrade(&pb.AgentUpgrade{Path: path, Version: "1.2.0"}); err == nil {
+			t.Errorf("Upgrade(%q) accepted", path)
+		}
+	}
+	if data, err := os.ReadFile(victim); err != nil || string(data) != "keep" {
+		t.Errorf("victim file changed: %q, %v", data, err)
+	}
+	if _, err := os.Lstat(link); err != nil {
+		t.Errorf("symlink in stage dir removed: %v", err)
+	}
+	if _, err := os.Stat(other.Name()); err != nil {
+		t.Errorf("unrelated staged file removed: %v", err)
+	}
+}
+
+func TestUpgrade_KeepsStagedFileWithoutPublicKey(t *testing.T) {
+	u := newTestUpgrader(t, "")
+	path := stageFile(t)
+	if err := u.Upgrade(&pb.AgentUpgrade{Path: path, Version: "1.2.0"}); err == nil {
+		t.Fatal("upgrade accepted without upgrade_public_key")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("staged file removed before key check: %v", err)
+	}
+}
+
+func TestUpgrade_SignatureBindsVersionAndPlatform(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	u := newTestUpgrader(t, base64.StdEncoding.EncodeToString(pub))
+	const digest = "9a3a45d01531a20e89ac6ae10b0b0beb0492acd7216a368aa062d1a5fecaf9cd" // sha256("binary")
+
+	tests := map[string]*pb.AgentUpgrade{
+		// 旧版本的签名被重放为新版本
+		"replayed version": {Version: "1.2.0", Sha256: digest, Signature: ed25519.Sign(priv, pb.UpgradeManifest("1.0.0", "linux", "amd64", digest))},
+		"other platform":   {Version: "1.2.0", Sha256: digest, Signature: ed25519.Sign(priv, pb.UpgradeManifest("1.2.0", "plan9", "mips", digest))},
+		"raw binary":       {Version: "1.2.0", Sha256: digest, Signature: ed25519.Sign(priv, []byte("binary"))},
+		// 未明确回退时拒绝降级，即使签名有效
+		"downgrade": {Version: "1.0.0", Sha256: digest, Signature: ed25519.Sign(priv, pb.UpgradeManifest("1.0.0", "linux", "amd64", digest))},
+	}
+	for name, req := range tests {
+		req.Path = stageFile(t)
+		if err := u.Upgrade(req); err == nil {
+			t.Errorf("%s: upgrade accepted", name)
+		}
+		if _, err := os.Stat(req.Path); !os.IsNotExist(err) {
+			t.Errorf("%s: staged file not cleaned up", name)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2026 DYCloud J.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ruletest
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/ydcloud-dy/opshub/cmd/root"
+	alertsvc "github.com/ydcloud-dy/opshub/internal/service/alert"
+	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
+)
+
+var Cmd = &cobra.Command{
+	Use:   "rule-test [测试文件...]",
+	Short: "告警规则单元测试",
+	Long: `按 promtool test rules 风格的测试文件，用合成序列回放告警规则评估，
+校验阈值条件、持续时长、标签合并与注解渲染；存在未通过的用例时以非 0 状态退出`,
+	Args:          cobra.MinimumNArgs(1),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// 模板渲染失败时引擎会记录日志，命令行下不输出
+		if appLogger.Log == nil {
+			appLogger.Log = zap.NewNop()
+		}
+
+		failed := 0
+		for _, path := range args {
+			fmt.Printf("测试文件: %s\n", path)
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("读取测试文件失败: %w", err)
+			}
+			f, err := alertsvc.ParseRuleTestFile(data)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			result, err := alertsvc.RunRuleTests(f, nil)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			for _, tc := range result.Cases {
+				if tc.Passed {
+					fmt.Printf("  ✓ %s\n", tc.Name)
+					continue
+				}
+				failed++
+				fmt.Printf("  ✗ %s\n", tc.Name)
+				for _, msg := range tc.Failures {
+					fmt.Printf("      %s\n", msg)
+				}
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d 个用例未通过", failed)
+		}
+		fmt.Println("全部用例通过")
+		return nil
+	},
+}
+
+func init() {
+	root.Cmd.AddCommand(Cmd)
+}
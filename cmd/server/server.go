@@ -21,6 +21,7 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
 	"net/http"
@@ -48,6 +49,7 @@ import (
 	"github.com/ydcloud-dy/opshub/internal/service"
 	rbacservice "github.com/ydcloud-dy/opshub/internal/service/rbac"
 	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
+	"github.com/ydcloud-dy/opshub/pkg/secret"
 	"github.com/ydcloud-dy/opshub/plugins/kubernetes/data/models"
 	k8smodel "github.com/ydcloud-dy/opshub/plugins/kubernetes/model"
 	"go.uber.org/zap"
@@ -135,6 +137,11 @@ func runServer() (*conf.Config, error) {
 		zap.String("mode", cfg.Server.Mode),
 	)
 
+	// 初始化敏感信息存储
+	if err := initSecretStore(cfg); err != nil {
+		return nil, fmt.Errorf("初始化密钥存储失败: %w", err)
+	}
+
 	// 初始化数据层
 	data, err := dataPkg.NewData(cfg)
 	if err != nil {
@@ -168,13 +175,16 @@ func runServer() (*conf.Config, error) {
 		return nil, fmt.Errorf("初始化默认数据失败: %w", err)
 	}
 
-	// 每次启动都幂等执行菜单初始化（确保菜单数据最新）
+	// 每次启动按编码补齐告警管理菜单和按钮权限，已有安装同样执行
 	initAlertMenus(data.DB())
+	// 为插件已有按钮补充新增接口的绑定
+	initPluginMenuAPIs(data.DB())
 
 	// 启动Agent gRPC服务器（在HTTP服务器之前创建，以便注册路由）
 	var grpcServer *agentserver.GRPCServer
 	if cfg.Agent.Enabled {
 		grpcServer = agentserver.NewGRPCServer(cfg, data.DB())
+		grpcServer.EnableCluster(redis.Get())
 		globalGRPCServer = grpcServer
 	}
 
@@ -204,8 +214,54 @@ func runServer() (*conf.Config, error) {
 	return cfg, nil
 }
 
+// initSecretStore 按配置设置全局密钥存储，未配置Vault时只使用内置后端
+func initSecretStore(cfg *conf.Config) error {
+	secretCfg := cfg.Secret
+	var vault *secret.VaultStore
+	if secretCfg.Vault.Address != "" {
+		token := secretCfg.Vault.Token
+		if token == "" {
+			token = os.Getenv("VAULT_TOKEN")
+		}
+		vault = secret.NewVaultStore(secret.VaultOptions{
+			Address:         secretCfg.Vault.Address,
+			Token:           token,
+			Namespace:       secretCfg.Vault.Namespace,
+			KVMount:         secretCfg.Vault.KVMount,
+			PathPrefix:      secretCfg.Vault.PathPrefix,
+			AllowedPaths:    secretCfg.Vault.AllowedPaths,
+			AllowedSSHRoles: secretCfg.Vault.AllowedSSHRoles,
+			Timeout:         time.Duration(secretCfg.Vault.Timeout) * time.Second,
+		})
+	}
+	switch secretCfg.Backend {
+	case "", secret.BackendDB:
+	case secret.BackendVault:
+		if vault == nil {
+			return fmt.Errorf("secret.backend 为 vault 时必须配置 secret.vault.address")
+		}
+	default:
+		return fmt.Errorf("不支持的密钥存储后端: %s", secretCfg.Backend)
+	}
+
+	secret.SetDefault(secret.NewManager(secret.Builtin(), vault, secretCfg.Backend, time.Duration(secretCfg.CacheTTL)*time.Second))
+	appLogger.Info("密钥存储已初始化",
+		zap.String("backend", secretCfg.Backend),
+		zap.Bool("vault", vault != nil),
+	)
+	return nil
+}
+
 // autoMigrate 自动迁移数据库表
 func autoMigrate(db *gorm.DB) error {
+	// 告警指纹表新增唯一索引 uk_fp_sub_rule 前，先清理并发写入遗留的重复记录（保留 id 最大的一条）
+	if db.Migrator().HasTable(&alertbiz.AlertFingerprint{}) && !db.Migrator().HasIndex(&alertbiz.AlertFingerprint{}, "uk_fp_sub_rule") {
+		if err := db.Exec(`DELETE FROM alert_fingerprints WHERE id NOT IN (
+			SELECT id FROM (SELECT MAX(id) AS id FROM alert_fingerprints GROUP BY subscription_id, alert_rule_id, fingerprint) AS keep_ids)`).Error; err != nil {
+			appLogger.Warn("清理重复告警指纹记录失败", zap.Error(err))
+		}
+	}
+
 	// 自动迁移表结构
 	if err := db.AutoMigrate(
 		&rbacmodel.SysUser{},
@@ -246,8 +302,22 @@ func autoMigrate(db *gorm.DB) error {
 		&inspectionbiz.ProbeVariable{},
 		// Agent相关表
 		&agentmodel.AgentInfo{},
+		&agentmodel.AgentUpgradeRollout{},
+		&agentmodel.AgentUpgradeTask{},
+		&agentmodel.AgentPolicyViolation{},
+		&agentmodel.AgentDiscoveredService{},
+		&agentmodel.AgentTunnelSession{},
+		&agentmodel.AgentCertificate{},
+		&agentmodel.AgentCertRevocation{},
 		// 终端会话审计表
 		&assetbiz.TerminalSession{},
+		// SSH已知主机密钥
+		&assetbiz.HostKey{},
+		&assetbiz.HostKeyAlert{},
+		// 跳板链
+		&assetbiz.JumpHop{},
+		// SSH CA
+		&assetbiz.SSHCA{},
 		// 服务标签表
 		&assetbiz.ServiceLabel{},
 		// Web站点管理表
@@ -270,6 +340,14 @@ func autoMigrate(db *gorm.DB) error {
 		&alertbiz.AlertSubscriptionChannel{},
 		&alertbiz.AlertSubscriptionUser{},
 		&alertbiz.AlertSilenceRule{},
+		&alertbiz.AlertReceiver{},
+		&alertbiz.AlertNotifyDelivery{},
+		&alertbiz.AlertNotifyDeadLetter{},
+		&alertbiz.AlertOnCallSchedule{},
+		&alertbiz.AlertOnCallOverride{},
+		&alertbiz.AlertEscalationPolicy{},
+		&alertbiz.AlertEventEscalation{},
+		&alertbiz.AlertEventTimeline{},
 		// 告警治理表
 		&alertbiz.AlertDedupRule{},
 		&alertbiz.AlertFingerprint{},
@@ -396,9 +474,6 @@ func initDefaultData(db *gorm.DB) error {
 	// 初始化服务标签菜单
 	initServiceLabelMenus(db)
 
-	// 初始化告警管理菜单
-	initAlertMenus(db)
-
 	return nil
 }
 
@@ -499,14 +574,9 @@ func initServiceLabelMenus(db *gorm.DB) {
 	appLogger.Info("服务标签菜单初始化完成")
 }
 
-// initAlertMenus 初始化告警管理菜单（幂等）
+// initAlertMenus 初始化告警管理菜单、按钮及其API绑定（幂等）
+// 每次启动按编码补齐，已有安装也能注册后续新增的按钮和接口，避免写接口因未注册而对所有登录用户放行
 func initAlertMenus(db *gorm.DB) {
-	var count int64
-	db.Model(&rbacmodel.SysMenu{}).Where("code = ?", "alert-management").Count(&count)
-	if count > 0 {
-		return
-	}
-
 	// 获取管理员角色
 	var adminRole rbacmodel.SysRole
 	hasAdmin := db.Where("code = ?", "admin").First(&adminRole).Error == nil
@@ -522,67 +592,104 @@ func initAlertMenus(db *gorm.DB) {
 		Name: "告警管理", Code: "alert-management", Type: 1,
 		ParentID: 0, Path: "/alert", Icon: "IconBell", Sort: 55, Visible: 1, Status: 1,
 	}
-	db.Create(root)
+	if err := upsertMenu(db, root); err != nil {
+		appLogger.Warn("初始化告警管理菜单失败", zap.Error(err))
+		return
+	}
 	assignToAdmin(root.ID)
 
-	// 二级菜单 helper
+	// 二级菜单 helper，extraAPIs 为按钮额外绑定的接口
+	type apiDef struct{ path, method string }
 	type btnDef struct {
 		name, code, apiPath, apiMethod string
 		sort                           int
+		extraAPIs                      []apiDef
 	}
 	type menuDef struct {
-		name, code, path, component string
-		sort                         int
-		buttons                      []btnDef
+		name, code, path, component, icon string
+		sort                              int
+		buttons                           []btnDef
 	}
 
 	menus := []menuDef{
 		{
-			name: "告警规则", code: "alert-rules", path: "/alert/rules", component: "alert/RuleManagement", sort: 1,
+			name: "告警规则", code: "alert-rules", path: "/alert/rules", component: "alert/RuleManagement", icon: "Robot", sort: 1,
 			buttons: []btnDef{
-				{"新增规则", "alert:rules:create", "/api/v1/alert/rules", "POST", 1},
-				{"编辑规则", "alert:rules:edit", "/api/v1/alert/rules/:id", "PUT", 2},
-				{"删除规则", "alert:rules:delete", "/api/v1/alert/rules/:id", "DELETE", 3},
-				{"启用禁用", "alert:rules:toggle", "/api/v1/alert/rules/:id/toggle", "PUT", 4},
-				{"测试规则", "alert:rules:test", "/api/v1/alert/rules/:id/test", "POST", 5},
-				{"克隆规则", "alert:rules:clone", "/api/v1/alert/rules/:id/clone", "POST", 6},
-				{"导入导出", "alert:rules:import-export", "/api/v1/alert/rules/import", "POST", 7},
+				{name: "新增规则", code: "alert:rules:create", apiPath: "/api/v1/alert/rules", apiMethod: "POST", sort: 1},
+				{name: "编辑规则", code: "alert:rules:edit", apiPath: "/api/v1/alert/rules/:id", apiMethod: "PUT", sort: 2},
+				{name: "删除规则", code: "alert:rules:delete", apiPath: "/api/v1/alert/rules/:id", apiMethod: "DELETE", sort: 3},
+				{name: "启用禁用", code: "alert:rules:toggle", apiPath: "/api/v1/alert/rules/:id/toggle", apiMethod: "PUT", sort: 4},
+				{name: "测试规则", code: "alert:rules:test", apiPath: "/api/v1/alert/rules/:id/test", apiMethod: "POST", sort: 5},
+				{name: "克隆规则", code: "alert:rules:clone", apiPath: "/api/v1/alert/rules/:id/clone", apiMethod: "POST", sort: 6},
+				{name: "导入导出", code: "alert:rules:import-export", apiPath: "/api/v1/alert/rules/import", apiMethod: "POST", sort: 7, extraAPIs: []apiDef{
+					{"/api/v1/alert/rules/export", "GET"},
+					{"/api/v1/alert/rule-groups/:id/prometheus/preview", "POST"},
+					{"/api/v1/alert/rule-groups/:id/prometheus/import", "POST"},
+					{"/api/v1/alert/rule-groups/:id/prometheus/export", "GET"},
+				}},
+				{name: "单元测试", code: "alert:rules:unit-test", apiPath: "/api/v1/alert/rules/unit-test", apiMethod: "POST", sort: 8},
 			},
 		},
 		{
-			name: "数据源管理", code: "alert-datasources", path: "/alert/datasources", component: "alert/DataSources", sort: 2,
+			name: "数据源管理", code: "alert-datasources", path: "/alert/datasources", component: "alert/DataSources", icon: "Storage", sort: 2,
 			buttons: []btnDef{
-				{"新增数据源", "alert:datasources:create", "/api/v1/alert/datasources", "POST", 1},
-				{"编辑数据源", "alert:datasources:edit", "/api/v1/alert/datasources/:id", "PUT", 2},
-				{"删除数据源", "alert:datasources:delete", "/api/v1/alert/datasources/:id", "DELETE", 3},
+				{name: "新增数据源", code: "alert:datasources:create", apiPath: "/api/v1/alert/datasources", apiMethod: "POST", sort: 1},
+				{name: "编辑数据源", code: "alert:datasources:edit", apiPath: "/api/v1/alert/datasources/:id", apiMethod: "PUT", sort: 2},
+				{name: "删除数据源", code: "alert:datasources:delete", apiPath: "/api/v1/alert/datasources/:id", apiMethod: "DELETE", sort: 3},
 			},
 		},
 		{
-			name: "实时告警", code: "alert-events", path: "/alert/events", component: "alert/ActiveEvents", sort: 3,
+			name: "实时告警", code: "alert-events", path: "/alert/events", component: "alert/ActiveEvents", icon: "Alert", sort: 3,
 			buttons: []btnDef{
-				{"屏蔽告警", "alert:events:silence", "/api/v1/alert/events/:id/silence", "POST", 1},
-				{"手动处理", "alert:events:handle", "/api/v1/alert/events/:id/handle", "POST", 2},
-				{"批量屏蔽", "alert:events:batch-silence", "/api/v1/alert/events/batch-silence", "POST", 3},
-				{"批量取消屏蔽", "alert:events:batch-unsilence", "/api/v1/alert/events/batch-unsilence", "POST", 4},
+				{name: "屏蔽告警", code: "alert:events:silence", apiPath: "/api/v1/alert/events/:id/silence", apiMethod: "POST", sort: 1},
+				{name: "手动处理", code: "alert:events:handle", apiPath: "/api/v1/alert/events/:id/handle", apiMethod: "POST", sort: 2},
+				{name: "批量屏蔽", code: "alert:events:batch-silence", apiPath: "/api/v1/alert/events/batch-silence", apiMethod: "POST", sort: 3},
+				{name: "批量取消屏蔽", code: "alert:events:batch-unsilence", apiPath: "/api/v1/alert/events/batch-unsilence", apiMethod: "POST", sort: 4},
+				{name: "确认告警", code: "alert:events:ack", apiPath: "/api/v1/alert/events/:id/ack", apiMethod: "POST", sort: 5},
+				{name: "取消确认", code: "alert:events:unack", apiPath: "/api/v1/alert/events/:id/unack", apiMethod: "POST", sort: 6},
+				{name: "认领告警", code: "alert:events:claim", apiPath: "/api/v1/alert/events/:id/claim", apiMethod: "POST", sort: 7},
+				{name: "指派告警", code: "alert:events:assign", apiPath: "/api/v1/alert/events/:id/assign", apiMethod: "POST", sort: 8},
+				{name: "手动恢复", code: "alert:events:resolve", apiPath: "/api/v1/alert/events/:id/resolve", apiMethod: "POST", sort: 9},
 			},
 		},
 		{
-			name: "历史告警", code: "alert-history", path: "/alert/history", component: "alert/HistoryEvents", sort: 4,
+			name: "历史告警", code: "alert-history", path: "/alert/history", component: "alert/HistoryEvents", icon: "Archive", sort: 4,
+		},
+		{
+			name: "告警通道", code: "alert-channels", path: "/alert/channels", component: "alert/Channels", icon: "Notification", sort: 5,
+			buttons: []btnDef{
+				{name: "新增通道", code: "alert:channels:create", apiPath: "/api/v1/alert/channels", apiMethod: "POST", sort: 1},
+				{name: "编辑通道", code: "alert:channels:edit", apiPath: "/api/v1/alert/channels/:id", apiMethod: "PUT", sort: 2},
+				{name: "删除通道", code: "alert:channels:delete", apiPath: "/api/v1/alert/channels/:id", apiMethod: "DELETE", sort: 3},
+				{name: "新增接收器", code: "alert:receivers:create", apiPath: "/api/v1/alert/receivers", apiMethod: "POST", sort: 4},
+				{name: "编辑接收器", code: "alert:receivers:edit", apiPath: "/api/v1/alert/receivers/:id", apiMethod: "PUT", sort: 5, extraAPIs: []apiDef{
+					{"/api/v1/alert/receivers/:id/token", "PUT"},
+				}},
+				{name: "删除接收器", code: "alert:receivers:delete", apiPath: "/api/v1/alert/receivers/:id", apiMethod: "DELETE", sort: 6},
+				{name: "重发死信", code: "alert:dead-letters:resend", apiPath: "/api/v1/alert/dead-letters/:id/resend", apiMethod: "POST", sort: 7},
+			},
 		},
 		{
-			name: "告警通道", code: "alert-channels", path: "/alert/channels", component: "alert/Channels", sort: 5,
+			name: "告警订阅", code: "alert-subscriptions", path: "/alert/subscriptions", component: "alert/Subscriptions", icon: "Bookmark", sort: 6,
 			buttons: []btnDef{
-				{"新增通道", "alert:channels:create", "/api/v1/alert/channels", "POST", 1},
-				{"编辑通道", "alert:channels:edit", "/api/v1/alert/channels/:id", "PUT", 2},
-				{"删除通道", "alert:channels:delete", "/api/v1/alert/channels/:id", "DELETE", 3},
+				{name: "新增订阅", code: "alert:subscriptions:create", apiPath: "/api/v1/alert/subscriptions", apiMethod: "POST", sort: 1},
+				{name: "编辑订阅", code: "alert:subscriptions:edit", apiPath: "/api/v1/alert/subscriptions/:id", apiMethod: "PUT", sort: 2},
+				{name: "删除订阅", code: "alert:subscriptions:delete", apiPath: "/api/v1/alert/subscriptions/:id", apiMethod: "DELETE", sort: 3},
 			},
 		},
 		{
-			name: "告警订阅", code: "alert-subscriptions", path: "/alert/subscriptions", component: "alert/Subscriptions", sort: 6,
+			name: "值班与升级", code: "alert-oncall", path: "/alert/oncall", component: "alert/OnCall", icon: "Schedule", sort: 7,
 			buttons: []btnDef{
-				{"新增订阅", "alert:subscriptions:create", "/api/v1/alert/subscriptions", "POST", 1},
-				{"编辑订阅", "alert:subscriptions:edit", "/api/v1/alert/subscriptions/:id", "PUT", 2},
-				{"删除订阅", "alert:subscriptions:delete", "/api/v1/alert/subscriptions/:id", "DELETE", 3},
+				{name: "新增值班表", code: "alert:oncall:create", apiPath: "/api/v1/alert/oncall-schedules", apiMethod: "POST", sort: 1},
+				{name: "编辑值班表", code: "alert:oncall:edit", apiPath: "/api/v1/alert/oncall-schedules/:id", apiMethod: "PUT", sort: 2, extraAPIs: []apiDef{
+					// 临时换班属于编辑值班表
+					{"/api/v1/alert/oncall-schedules/:id/overrides", "POST"},
+					{"/api/v1/alert/oncall-schedules/overrides/:id", "DELETE"},
+				}},
+				{name: "删除值班表", code: "alert:oncall:delete", apiPath: "/api/v1/alert/oncall-schedules/:id", apiMethod: "DELETE", sort: 3},
+				{name: "新增升级策略", code: "alert:escalations:create", apiPath: "/api/v1/alert/escalation-policies", apiMethod: "POST", sort: 4},
+				{name: "编辑升级策略", code: "alert:escalations:edit", apiPath: "/api/v1/alert/escalation-policies/:id", apiMethod: "PUT", sort: 5},
+				{name: "删除升级策略", code: "alert:escalations:delete", apiPath: "/api/v1/alert/escalation-policies/:id", apiMethod: "DELETE", sort: 6},
 			},
 		},
 	}
@@ -590,25 +697,81 @@ func initAlertMenus(db *gorm.DB) {
 	for _, m := range menus {
 		menu := &rbacmodel.SysMenu{
 			Name: m.name, Code: m.code, Type: 2, ParentID: root.ID,
-			Path: m.path, Component: m.component, Sort: m.sort, Visible: 1, Status: 1,
+			Path: m.path, Component: m.component, Icon: m.icon, Sort: m.sort, Visible: 1, Status: 1,
+		}
+		if err := upsertMenu(db, menu); err != nil {
+			appLogger.Warn("初始化告警菜单失败", zap.String("code", m.code), zap.Error(err))
+			continue
 		}
-		db.Create(menu)
 		assignToAdmin(menu.ID)
 		for _, b := range m.buttons {
 			btn := &rbacmodel.SysMenu{
 				Name: b.name, Code: b.code, Type: 3, ParentID: menu.ID,
 				Sort: b.sort, Visible: 1, Status: 1, ApiPath: b.apiPath, ApiMethod: b.apiMethod,
 			}
-			db.Create(btn)
+			if err := upsertMenu(db, btn); err != nil {
+				appLogger.Warn("初始化告警按钮失败", zap.String("code", b.code), zap.Error(err))
+				continue
+			}
 			assignToAdmin(btn.ID)
-			if b.apiPath != "" {
-				db.Create(&rbacmodel.SysMenuAPI{MenuID: btn.ID, ApiPath: b.apiPath, ApiMethod: b.apiMethod})
+			ensureMenuAPI(db, btn.ID, b.apiPath, b.apiMethod)
+			for _, api := range b.extraAPIs {
+				ensureMenuAPI(db, btn.ID, api.path, api.method)
 			}
 		}
 	}
 	appLogger.Info("告警管理菜单初始化完成")
 }
 
+// initPluginMenuAPIs 为插件已有按钮补充后续新增接口的绑定（幂等）
+func initPluginMenuAPIs(db *gorm.DB) {
+	bindings := []struct{ code, apiPath, apiMethod string }{
+		// 实时输出执行与执行任务共用按钮权限
+		{"tasks:execute", "/api/v1/plugins/task/execute/stream", "GET"},
+	}
+	for _, b := range bindings {
+		var btn rbacmodel.SysMenu
+		if err := db.Where("code = ?", b.code).First(&btn).Error; err != nil {
+			continue
+		}
+		ensureMenuAPI(db, btn.ID, b.apiPath, b.apiMethod)
+	}
+}
+
+// upsertMenu 按编码创建菜单；已存在时保留名称和排序等自定义，只校正层级和按钮绑定的接口，
+// 被删除的内置按钮会恢复，否则其接口不再注册而对所有登录用户放行
+func upsertMenu(db *gorm.DB, menu *rbacmodel.SysMenu) error {
+	var existing rbacmodel.SysMenu
+	err := db.Unscoped().Where("code = ?", menu.Code).First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return db.Create(menu).Error
+	}
+	if err != nil {
+		return err
+	}
+	updates := map[string]interface{}{"parent_id": menu.ParentID, "deleted_at": nil}
+	if menu.Type == 3 {
+		updates["api_path"], updates["api_method"] = menu.ApiPath, menu.ApiMethod
+	}
+	if err := db.Unscoped().Model(&existing).Updates(updates).Error; err != nil {
+		return err
+	}
+	menu.ID = existing.ID
+	return nil
+}
+
+// ensureMenuAPI 按钮未绑定该接口时补充绑定
+func ensureMenuAPI(db *gorm.DB, menuID uint, apiPath, apiMethod string) {
+	if apiPath == "" {
+		return
+	}
+	var count int64
+	db.Model(&rbacmodel.SysMenuAPI{}).Where("menu_id = ? AND api_path = ? AND api_method = ?", menuID, apiPath, apiMethod).Count(&count)
+	if count == 0 {
+		db.Create(&rbacmodel.SysMenuAPI{MenuID: menuID, ApiPath: apiPath, ApiMethod: apiMethod})
+	}
+}
+
 func stopServer(ctx context.Context, cfg *conf.Config) error {
 	appLogger.Info("服务正在关闭...")
 
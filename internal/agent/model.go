@@ -21,3 +21,191 @@ type AgentInfo struct {
 func (AgentInfo) TableName() string {
 	return "agent_info"
 }
+
+// 升级批次状态
+const (
+	RolloutStatusRunning   = "running"
+	RolloutStatusHalted    = "halted" // 失败率超过阈值或服务重启，可恢复
+	RolloutStatusCanceled  = "canceled"
+	RolloutStatusCompleted = "completed"
+)
+
+// 单台主机升级状态
+const (
+	UpgradeTaskPending   = "pending"
+	UpgradeTaskRunning   = "running"
+	UpgradeTaskSucceeded = "succeeded"
+	UpgradeTaskFailed    = "failed"
+	UpgradeTaskSkipped   = "skipped"
+)
+
+// AgentUpgradeRollout Agent分阶段升级批次
+type AgentUpgradeRollout struct {
+	ID      uint   `gorm:"primarykey" json:"id"`
+	Version string `gorm:"type:varchar(50)" json:"version"`
+	// GroupIDs 目标资产分组（含子分组），逗号分隔，为空表示全部在线Agent
+	GroupIDs string `gorm:"type:varchar(500)" json:"groupIds"`
+	// Stages 各阶段累计升级比例（百分比），逗号分隔，如 "10,50,100"
+	Stages          string     `gorm:"type:varchar(100)" json:"stages"`
+	CurrentStage    int        `json:"currentStage"`
+	MaxFailureRate  float64    `json:"maxFailureRate"` // 失败率超过该值（0-1）时暂停
+	Concurrency     int        `json:"concurrency"`
+	RollbackTimeout int        `json:"rollbackTimeout"` // 新版本重新注册期限（秒）
+	StageInterval   int        `json:"stageInterval"`   // 阶段之间的观察时间（秒）
+	Status          string     `gorm:"type:varchar(20);index" json:"status"`
+	Message         string     `gorm:"type:varchar(500)" json:"message"`
+	Total           int        `json:"total"`
+	Succeeded       int        `json:"succeeded"`
+	Failed          int        `json:"failed"`
+	Skipped         int        `json:"skipped"`
+	CreatedBy       uint       `json:"createdBy"`
+	FinishedAt      *time.Time `json:"finishedAt,omitempty"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
+}
+
+func (AgentUpgradeRollout) TableName() string {
+	return "agent_upgrade_rollouts"
+}
+
+// AgentUpgradeTask 升级批次中单台主机的升级记录
+type AgentUpgradeTask struct {
+	ID          uint       `gorm:"primarykey" json:"id"`
+	RolloutID   uint       `gorm:"index;not null" json:"rolloutId"`
+	HostID      uint       `gorm:"index;not null" json:"hostId"`
+	Stage       int        `json:"stage"`
+	FromVersion string     `gorm:"type:varchar(50)" json:"fromVersion"`
+	Status      string     `gorm:"type:varchar(20)" json:"status"`
+	Error       string     `gorm:"type:varchar(1000)" json:"error"`
+	StartedAt   *time.Time `json:"startedAt,omitempty"`
+	FinishedAt  *time.Time `json:"finishedAt,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+func (AgentUpgradeTask) TableName() string {
+	return "agent_upgrade_tasks"
+}
+
+// 策略违规类型
+const (
+	PolicyViolationCommand  = "command"
+	PolicyViolationFile     = "file"
+	PolicyViolationTerminal = "terminal"
+	PolicyViolationTunnel   = "tunnel"
+)
+
+// AgentPolicyViolation Agent按本地策略拒绝请求的审计记录
+type AgentPolicyViolation struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	HostID     uint      `gorm:"index" json:"hostId"`
+	AgentID    string    `gorm:"type:varchar(64);index" json:"agentId"`
+	RequestID  string    `gorm:"type:varchar(64)" json:"requestId"`
+	Kind       string    `gorm:"type:varchar(20);index" json:"kind"`
+	Action     string    `gorm:"type:varchar(30)" json:"action"`
+	Target     string    `gorm:"type:text" json:"target"`
+	Reason     string    `gorm:"type:varchar(500)" json:"reason"`
+	OccurredAt time.Time `gorm:"index" json:"occurredAt"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+func (AgentPolicyViolation) TableName() string {
+	return "agent_policy_violations"
+}
+
+// 发现服务状态
+const (
+	DiscoveredServiceActive = "active"
+	DiscoveredServiceGone   = "gone"
+)
+
+// AgentDiscoveredService Agent在主机上发现的服务，按 主机+服务键 去重
+type AgentDiscoveredService struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	HostID      uint      `gorm:"uniqueIndex:idx_discovered_host_key" json:"hostId"`
+	ServiceKey  string    `gorm:"type:varchar(191);uniqueIndex:idx_discovered_host_key" json:"serviceKey"`
+	Type        string    `gorm:"type:varchar(30);index" json:"type"`
+	Name        string    `gorm:"type:varchar(100)" json:"name"`
+	Process     string    `gorm:"type:varchar(100)" json:"process"`
+	PID         int32     `gorm:"column:pid" json:"pid"`
+	Cmdline     string    `gorm:"type:text" json:"cmdline"`
+	Port        int       `json:"port"`
+	ListenAddrs string    `gorm:"type:varchar(1000)" json:"listenAddrs"`
+	ExporterFor string    `gorm:"type:varchar(50)" json:"exporterFor"`
+	Status      string    `gorm:"type:varchar(20);index" json:"status"`
+	FirstSeen   time.Time `json:"firstSeen"`
+	LastSeen    time.Time `json:"lastSeen"`
+}
+
+func (AgentDiscoveredService) TableName() string {
+	return "agent_discovered_services"
+}
+
+// 端口转发会话状态
+const (
+	TunnelSessionActive  = "active"
+	TunnelSessionClosed  = "closed"
+	TunnelSessionExpired = "expired"
+)
+
+// AgentTunnelSession 端口转发会话审计记录：服务端监听 ListenAddr，来自 ClientIP 的连接经Agent转发到 Target
+type AgentTunnelSession struct {
+	ID            uint       `gorm:"primarykey" json:"id"`
+	SessionID     string     `gorm:"type:varchar(64);uniqueIndex" json:"sessionId"`
+	HostID        uint       `gorm:"index" json:"hostId"`
+	UserID        uint       `gorm:"index" json:"userId"`
+	Username      string     `gorm:"type:varchar(100)" json:"username"`
+	Target        string     `gorm:"type:varchar(255)" json:"target"`
+	ClientIP      string     `gorm:"type:varchar(64)" json:"clientIp"` // 会话发起方地址，只接受来自该地址的连接
+	ListenAddr    string     `gorm:"type:varchar(100)" json:"listenAddr"`
+	Status        string     `gorm:"type:varchar(20);index" json:"status"`
+	Connections   int        `json:"connections"`
+	BytesSent     int64      `json:"bytesSent"`     // 客户端 → 目标
+	BytesReceived int64      `json:"bytesReceived"` // 目标 → 客户端
+	ExpiresAt     time.Time  `json:"expiresAt"`
+	ClosedAt      *time.Time `json:"closedAt"`
+	CloseReason   string     `gorm:"type:varchar(255)" json:"closeReason"`
+	CreatedAt     time.Time  `json:"createdAt"`
+}
+
+func (AgentTunnelSession) TableName() string {
+	return "agent_tunnel_sessions"
+}
+
+// 证书签发方式
+const (
+	CertIssueDeploy = "deploy" // 部署或生成安装包时签发
+	CertIssueRenew  = "renew"  // 临近过期时经连接续期
+	CertIssueLegacy = "legacy" // 启用证书登记前签发，首次连接时补录
+)
+
+// AgentCertificate Agent客户端证书登记，按序列号吊销
+type AgentCertificate struct {
+	ID           uint       `gorm:"primarykey" json:"id"`
+	AgentID      string     `gorm:"type:varchar(100);index" json:"agentId"`
+	SerialNumber string     `gorm:"type:varchar(64);uniqueIndex" json:"serialNumber"` // 十六进制
+	IssuedBy     string     `gorm:"type:varchar(20)" json:"issuedBy"`
+	NotBefore    time.Time  `json:"notBefore"`
+	NotAfter     time.Time  `gorm:"index" json:"notAfter"`
+	RevokedAt    *time.Time `gorm:"index" json:"revokedAt"`
+	RevokedBy    string     `gorm:"type:varchar(100)" json:"revokedBy"`
+	RevokeReason string     `gorm:"type:varchar(255)" json:"revokeReason"`
+	CreatedAt    time.Time  `json:"createdAt"`
+}
+
+func (AgentCertificate) TableName() string {
+	return "agent_certificates"
+}
+
+// AgentCertRevocation Agent证书吊销标记：吊销过证书的Agent不再补录未登记的证书（启用登记前签发的旧证书）
+type AgentCertRevocation struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	AgentID   string    `gorm:"type:varchar(100);uniqueIndex" json:"agentId"`
+	RevokedAt time.Time `json:"revokedAt"`
+	RevokedBy string    `gorm:"type:varchar(100)" json:"revokedBy"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (AgentCertRevocation) TableName() string {
+	return "agent_cert_revocations"
+}
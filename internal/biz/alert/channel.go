@@ -13,7 +13,7 @@ type AlertNotifyChannel struct {
 	UpdatedAt       time.Time      `json:"updatedAt"`
 	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
 	Name            string         `gorm:"size:100;not null" json:"name"`
-	Type            string         `gorm:"size:30;not null" json:"type"` // wechat_work, dingtalk, sms, phone, ai_agent
+	Type            string         `gorm:"size:30;not null" json:"type"` // email, wechat_work, dingtalk, feishu, slack, teams, webhook, sms, phone, ai_agent
 	Config          string         `gorm:"type:text" json:"config"`         // JSON 通道配置
 	AlertTemplate   string         `gorm:"type:text" json:"alertTemplate"`  // 告警通知模板
 	ResolveTemplate string         `gorm:"type:text" json:"resolveTemplate"` // 恢复通知模板
@@ -13,7 +13,7 @@ type AlertDataSource struct {
 	UpdatedAt      time.Time      `json:"updated_at"`
 	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
 	Name           string         `gorm:"size:100;not null" json:"name"`
-	Type           string         `gorm:"size:30;not null" json:"type"` // prometheus, victoriametrics, influxdb
+	Type           string         `gorm:"size:30;not null" json:"type"` // prometheus, victoriametrics, influxdb, loki, elasticsearch, clickhouse
 	URL            string         `gorm:"size:500" json:"url"`           // 直连模式时存储完整URL
 	Username       string         `gorm:"size:100" json:"username"`
 	Password       string         `gorm:"size:200" json:"password"`
@@ -63,7 +63,7 @@ func (DataSourceGroupRelation) TableName() string {
 // CreateDataSourceRequest 创建数据源的请求体
 type CreateDataSourceRequest struct {
 	Name        string `json:"name" binding:"required,max=100"`
-	Type        string `json:"type" binding:"required,oneof=prometheus victoriametrics influxdb"`
+	Type        string `json:"type" binding:"required,oneof=prometheus victoriametrics influxdb loki elasticsearch clickhouse"`
 	AccessMode  string `json:"access_mode" binding:"required,oneof=direct agent"`
 
 	// URL字段（两种模式都使用）
@@ -83,7 +83,7 @@ type CreateDataSourceRequest struct {
 // UpdateDataSourceRequest 更新数据源的请求体
 type UpdateDataSourceRequest struct {
 	Name        string `json:"name" binding:"omitempty,max=100"`
-	Type        string `json:"type" binding:"omitempty,oneof=prometheus victoriametrics influxdb"`
+	Type        string `json:"type" binding:"omitempty,oneof=prometheus victoriametrics influxdb loki elasticsearch clickhouse"`
 
 	// URL字段（两种模式都使用）
 	URL         string `json:"url" binding:"omitempty,max=500"`
@@ -0,0 +1,53 @@
+package alert
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 事件升级状态（AlertEventEscalation.Status）
+const (
+	EscalationActive       = "active"       // 升级进行中
+	EscalationAcknowledged = "acknowledged" // 已人工介入，停止升级
+	EscalationResolved     = "resolved"     // 告警已恢复，停止升级
+	EscalationCompleted    = "completed"    // 所有升级步骤已执行完毕
+)
+
+// AlertEscalationPolicy 升级策略
+type AlertEscalationPolicy struct {
+	ID          uint           `gorm:"primarykey" json:"id"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	UpdatedAt   time.Time      `json:"updatedAt"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	Name        string         `gorm:"size:100;not null" json:"name"`
+	Description string         `gorm:"size:500" json:"description"`
+	Enabled     bool           `gorm:"default:true" json:"enabled"`
+	// 升级步骤，JSON格式：[{"delayMinutes":10,"scheduleId":2,"userIds":[],"channelIds":[]}]
+	// 首次通知发给订阅接收人；之后每一步在上一步通知后 delayMinutes 内仍未人工介入则通知该步接收人
+	// channelIds 为空时沿用首次通知的通道
+	Steps string `gorm:"type:text" json:"steps"`
+}
+
+func (AlertEscalationPolicy) TableName() string {
+	return "alert_escalation_policies"
+}
+
+// AlertEventEscalation 告警事件的升级进度（事件 × 订阅）
+type AlertEventEscalation struct {
+	ID             uint       `gorm:"primarykey" json:"id"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+	EventID        uint       `gorm:"uniqueIndex:idx_event_sub;not null" json:"eventId"`
+	SubscriptionID uint       `gorm:"uniqueIndex:idx_event_sub;not null" json:"subscriptionId"`
+	PolicyID       uint       `gorm:"index;not null" json:"policyId"`
+	ChannelIDs     string     `gorm:"type:text" json:"channelIds"` // 首次通知使用的通道 JSON 数组
+	Step           int        `gorm:"default:0" json:"step"`       // 已执行的升级步骤数
+	Status         string     `gorm:"size:20;index;not null" json:"status"`
+	NextAt         *time.Time `gorm:"index" json:"nextAt"` // 下一步升级时间
+	LastNotifiedAt *time.Time `json:"lastNotifiedAt"`
+}
+
+func (AlertEventEscalation) TableName() string {
+	return "alert_event_escalations"
+}
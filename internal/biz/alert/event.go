@@ -29,6 +29,12 @@ type AlertEvent struct {
 	HandledBy     *uint      `json:"handledBy"`  // sys_user.id
 	HandledAt     *time.Time `json:"handledAt"`
 	HandledNote   string     `gorm:"size:1000" json:"handledNote"`
+	// 确认与认领（确认表示已有人跟进，不改变 status）
+	Acknowledged bool       `gorm:"default:false" json:"acknowledged"`
+	AckedBy      *uint      `json:"ackedBy"` // sys_user.id，通过链接或 IM 回调确认时为空
+	AckedAt      *time.Time `json:"ackedAt"`
+	AssigneeID   *uint      `gorm:"index" json:"assigneeId"` // 处理人 sys_user.id
+	AssignedAt   *time.Time `json:"assignedAt"`
 
 	// 屏蔽维度（用于匹配同类告警）
 	SilenceDimension string `gorm:"type:text" json:"silenceDimension"` // JSON: {"severity":"critical","ruleName":"CPU高","labels":{"job":"prometheus"}}
@@ -39,6 +45,9 @@ type AlertEvent struct {
 	// 展示用虚拟字段（不存储，查询时回填）
 	AssetGroupName string `gorm:"-" json:"assetGroupName"`
 	RuleGroupName  string `gorm:"-" json:"ruleGroupName"`
+	Deliveries     []*AlertNotifyDelivery `gorm:"-" json:"deliveries,omitempty"` // 通知投递记录（详情接口回填）
+	Escalations    []*AlertEventEscalation `gorm:"-" json:"escalations,omitempty"` // 升级进度（详情接口回填）
+	Timeline       []*AlertEventTimeline   `gorm:"-" json:"timeline,omitempty"`    // 处理时间线（详情接口回填）
 }
 
 func (AlertEvent) TableName() string {
@@ -0,0 +1,41 @@
+package alert
+
+import "time"
+
+// 告警事件操作（AlertEventTimeline.Action）
+const (
+	EventActionAck      = "ack"      // 确认
+	EventActionUnack    = "unack"    // 取消确认
+	EventActionClaim    = "claim"    // 认领（指派给自己并确认）
+	EventActionAssign   = "assign"   // 指派
+	EventActionSilence  = "silence"  // 屏蔽
+	EventActionResolve  = "resolve"  // 手动恢复
+	EventActionHandle   = "handle"   // 人工介入
+	EventActionEscalate = "escalate" // 升级通知
+)
+
+// 操作来源（AlertEventTimeline.Source）
+const (
+	EventActionSourceWeb        = "web"         // 控制台
+	EventActionSourceLink       = "link"        // 通知中的一键处理链接
+	EventActionSourceDingTalk   = "dingtalk"    // 钉钉机器人回调
+	EventActionSourceWechatWork = "wechat_work" // 企业微信应用回调
+	EventActionSourceSystem     = "system"      // 系统自动
+)
+
+// AlertEventTimeline 告警事件处理时间线
+type AlertEventTimeline struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	CreatedAt  time.Time `gorm:"index" json:"createdAt"`
+	EventID    uint      `gorm:"index;not null" json:"eventId"`
+	Action     string    `gorm:"size:20" json:"action"`
+	Source     string    `gorm:"size:20" json:"source"`
+	OperatorID uint      `json:"operatorId"`               // sys_user.id，链接/IM 回调操作时为 0
+	Operator   string    `gorm:"size:100" json:"operator"` // 用户名或 IM 用户标识
+	AssigneeID uint      `json:"assigneeId"`               // assign/claim 时的处理人
+	Note       string    `gorm:"size:1000" json:"note"`
+}
+
+func (AlertEventTimeline) TableName() string {
+	return "alert_event_timelines"
+}
@@ -2,11 +2,19 @@ package alert
 
 import "time"
 
+// 评估状态（AlertFingerprint.EvalState）
+const (
+	EvalStatePending = "pending" // 已命中，等待满足持续时长
+	EvalStateFiring  = "firing"  // 已触发
+)
+
 // AlertFingerprint 告警指纹记录
+// SubscriptionID > 0 的记录用于订阅去重；SubscriptionID = 0 的记录为评估引擎持久化的
+// pending/firing 状态（按 AlertRuleID + Fingerprint 唯一，由 uk_fp_sub_rule 约束），用于重启或切换实例后恢复 for 计时
 type AlertFingerprint struct {
 	ID              uint       `gorm:"primarykey" json:"id"`
-	SubscriptionID  uint       `gorm:"index;not null" json:"subscriptionId"`
-	Fingerprint     string     `gorm:"size:64;not null" json:"fingerprint"`
+	SubscriptionID  uint       `gorm:"index;uniqueIndex:uk_fp_sub_rule,priority:1;not null" json:"subscriptionId"`
+	Fingerprint     string     `gorm:"size:64;not null;uniqueIndex:uk_fp_sub_rule,priority:3" json:"fingerprint"`
 	RuleName        string     `gorm:"size:200;not null" json:"ruleName"`
 	Severity        string     `gorm:"size:20;not null" json:"severity"`
 	Labels          string     `gorm:"type:text" json:"labels"`
@@ -14,6 +22,13 @@ type AlertFingerprint struct {
 	LastSeenAt      time.Time  `gorm:"not null" json:"lastSeenAt"`
 	OccurrenceCount int        `gorm:"default:1" json:"occurrenceCount"`
 	LastSentAt      *time.Time `json:"lastSentAt"`
+
+	// 评估状态（仅 SubscriptionID = 0 的记录使用）
+	AlertRuleID     uint       `gorm:"index;uniqueIndex:uk_fp_sub_rule,priority:2;default:0" json:"alertRuleId"`
+	EvalState       string     `gorm:"size:20" json:"evalState"` // pending, firing
+	PendingSince    *time.Time `json:"pendingSince"`             // 首次命中时间（for 计时起点）
+	FiredAt         *time.Time `json:"firedAt"`                  // 触发时间
+	KeepFiringSince *time.Time `json:"keepFiringSince"`          // 条件不再满足的起始时间（keep_firing_for 计时起点）
 }
 
 func (AlertFingerprint) TableName() string {
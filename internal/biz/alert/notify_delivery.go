@@ -0,0 +1,61 @@
+package alert
+
+import "time"
+
+// 通知投递状态（AlertNotifyDelivery.Status）
+const (
+	NotifyDeliveryPending = "pending" // 待发送
+	NotifyDeliverySent    = "sent"    // 已发送
+	NotifyDeliveryFailed  = "failed"  // 发送失败，等待重试
+	NotifyDeliveryDead    = "dead"    // 超过最大重试次数，已进入死信
+)
+
+// AlertNotifyDelivery 通知投递记录（事件 × 订阅 × 通道）
+type AlertNotifyDelivery struct {
+	ID                uint       `gorm:"primarykey" json:"id"`
+	CreatedAt         time.Time  `json:"createdAt"`
+	UpdatedAt         time.Time  `json:"updatedAt"`
+	EventID           uint       `gorm:"index;not null" json:"eventId"`
+	SubscriptionID    uint       `gorm:"index" json:"subscriptionId"`
+	SubscriptionLogID uint       `gorm:"index" json:"subscriptionLogId"` // 0 表示非订阅匹配发送（如分组聚合）
+	ChannelID         uint       `gorm:"not null" json:"channelId"`
+	ChannelName       string     `gorm:"size:100" json:"channelName"`
+	ChannelType       string     `gorm:"size:30" json:"channelType"`
+	IsResolve         bool       `gorm:"default:false" json:"isResolve"`
+	Phones            string     `gorm:"type:text" json:"-"`                   // JSON 数组，null 表示 @all；短信/电话部分失败后仅保留待重试号码
+	UserIDs           string     `gorm:"type:text" json:"-"`                   // JSON 数组
+	Status            string     `gorm:"size:20;index;not null" json:"status"` // pending, sent, failed, dead
+	Attempts          int        `gorm:"default:0" json:"attempts"`
+	MaxAttempts       int        `gorm:"default:5" json:"maxAttempts"`
+	LastError         string     `gorm:"size:1000" json:"lastError"`
+	Result            string     `gorm:"type:text" json:"result"` // 最近一次投递结果 JSON（含短信/电话按号码结果）
+	NextRetryAt       *time.Time `gorm:"index" json:"nextRetryAt"`
+	ClaimedUntil      *time.Time `gorm:"index" json:"claimedUntil"` // 发送租约到期时间，发送中由处理实例续期，为空表示未被占用
+	SentAt            *time.Time `json:"sentAt"`
+}
+
+func (AlertNotifyDelivery) TableName() string {
+	return "alert_notify_deliveries"
+}
+
+// AlertNotifyDeadLetter 通知死信，重试耗尽的投递记录，可手动重发
+type AlertNotifyDeadLetter struct {
+	ID          uint       `gorm:"primarykey" json:"id"`
+	CreatedAt   time.Time  `gorm:"index" json:"createdAt"`
+	DeliveryID  uint       `gorm:"index;not null" json:"deliveryId"`
+	EventID     uint       `gorm:"index;not null" json:"eventId"`
+	RuleName    string     `gorm:"size:200" json:"ruleName"`
+	ChannelID   uint       `json:"channelId"`
+	ChannelName string     `gorm:"size:100" json:"channelName"`
+	ChannelType string     `gorm:"size:30" json:"channelType"`
+	IsResolve   bool       `json:"isResolve"`
+	Attempts    int        `json:"attempts"`
+	LastError   string     `gorm:"size:1000" json:"lastError"`
+	Resent      bool       `gorm:"default:false;index" json:"resent"`
+	ResentAt    *time.Time `json:"resentAt"`
+	ResentBy    uint       `json:"resentBy"` // sys_user.id
+}
+
+func (AlertNotifyDeadLetter) TableName() string {
+	return "alert_notify_dead_letters"
+}
@@ -0,0 +1,48 @@
+package alert
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 值班轮换类型（OnCall 层的 rotationType）
+const (
+	OnCallRotationDaily  = "daily"  // 按天轮换
+	OnCallRotationWeekly = "weekly" // 按周轮换
+)
+
+// AlertOnCallSchedule 值班表
+type AlertOnCallSchedule struct {
+	ID          uint           `gorm:"primarykey" json:"id"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	UpdatedAt   time.Time      `json:"updatedAt"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	Name        string         `gorm:"size:100;not null" json:"name"`
+	Description string         `gorm:"size:500" json:"description"`
+	Timezone    string         `gorm:"size:50" json:"timezone"` // 如 Asia/Shanghai，空=服务器时区
+	// 轮换层，JSON格式：[{"name":"主值班","rotationType":"weekly","interval":1,"start":"2026-01-05 09:00","userIds":[1,2,3]}]
+	// start 同时决定交接时刻（按天轮换在每天该时刻交接，按周轮换在该星期几的该时刻交接）
+	// 多层时靠后的层覆盖靠前的层
+	Layers string `gorm:"type:text" json:"layers"`
+}
+
+func (AlertOnCallSchedule) TableName() string {
+	return "alert_oncall_schedules"
+}
+
+// AlertOnCallOverride 值班临时替换（时间段内由指定用户代班）
+type AlertOnCallOverride struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	CreatedAt  time.Time `json:"createdAt"`
+	ScheduleID uint      `gorm:"index;not null" json:"scheduleId"`
+	UserID     uint      `gorm:"not null" json:"userId"` // sys_user.id
+	StartAt    time.Time `gorm:"index" json:"startAt"`
+	EndAt      time.Time `gorm:"index" json:"endAt"`
+	Reason     string    `gorm:"size:500" json:"reason"`
+	CreatedBy  uint      `json:"createdBy"` // sys_user.id
+}
+
+func (AlertOnCallOverride) TableName() string {
+	return "alert_oncall_overrides"
+}
@@ -0,0 +1,51 @@
+package alert
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 接收器类型
+const (
+	ReceiverTypeAlertmanager = "alertmanager" // Alertmanager webhook v4 格式
+	ReceiverTypeGeneric      = "generic"      // 通用 JSON（按字段映射解析）
+)
+
+// AlertReceiver 外部告警接收器（Prometheus/Alertmanager、Zabbix、Grafana 等通过 webhook 推送告警）
+type AlertReceiver struct {
+	ID              uint           `gorm:"primarykey" json:"id"`
+	CreatedAt       time.Time      `json:"createdAt"`
+	UpdatedAt       time.Time      `json:"updatedAt"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
+	Name            string         `gorm:"size:100;not null" json:"name"`
+	Type            string         `gorm:"size:30;default:'alertmanager'" json:"type"` // alertmanager, generic
+	Token           string         `gorm:"size:100;uniqueIndex" json:"token"`          // 接收地址 /api/v1/alert/receivers/{token}
+	AssetGroupID    uint           `gorm:"index" json:"assetGroupId"`
+	Enabled         bool           `gorm:"default:true" json:"enabled"`
+	NotifyOnResolve bool           `gorm:"default:true" json:"notifyOnResolve"`
+	DefaultSeverity string         `gorm:"size:20;default:'warning'" json:"defaultSeverity"` // 告警未携带 severity 时使用
+	Labels          string         `gorm:"type:text" json:"labels"`                          // JSON 附加标签 {"key":"val"}
+	FieldMapping    string         `gorm:"type:text" json:"fieldMapping"`                    // 通用 JSON 字段映射（GenericFieldMapping）
+	Description     string         `gorm:"size:500" json:"description"`
+	LastReceivedAt  *time.Time     `json:"lastReceivedAt"`
+}
+
+func (AlertReceiver) TableName() string {
+	return "alert_webhook_receivers"
+}
+
+// GenericFieldMapping 通用 JSON 字段映射，路径使用点分隔（如 "data.alerts"、"tags.host"）
+type GenericFieldMapping struct {
+	AlertsPath      string            `json:"alertsPath"`      // 告警数组路径，为空时请求体本身为单条告警或告警数组
+	NamePath        string            `json:"namePath"`        // 告警名称
+	StatusPath      string            `json:"statusPath"`      // 状态
+	ResolvedValues  []string          `json:"resolvedValues"`  // 视为恢复的状态值，默认 resolved/ok/recovered
+	SeverityPath    string            `json:"severityPath"`    // 级别
+	SeverityMap     map[string]string `json:"severityMap"`     // 外部级别 -> critical/warning/info
+	LabelsPath      string            `json:"labelsPath"`      // 标签对象
+	AnnotationsPath string            `json:"annotationsPath"` // 注解对象
+	ValuePath       string            `json:"valuePath"`       // 当前值
+	StartsAtPath    string            `json:"startsAtPath"`    // 触发时间（RFC3339 或 Unix 秒）
+	EndsAtPath      string            `json:"endsAtPath"`      // 恢复时间
+}
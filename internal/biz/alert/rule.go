@@ -39,6 +39,7 @@ type AlertRule struct {
 	Conditions      string         `gorm:"type:json" json:"conditions" yaml:"conditions"`    // 阈值条件 JSON（新格式）
 	EvalInterval    int            `gorm:"default:15" json:"evalInterval" yaml:"evalinterval"` // 采集频率(秒)，默认15
 	Duration        string         `gorm:"size:20;default:'0s'" json:"duration" yaml:"duration"`      // 持续触发时长 e.g. "5m"
+	KeepFiringFor   string         `gorm:"size:20;default:''" json:"keepFiringFor" yaml:"keepfiringfor"` // 条件不满足后保持触发的时长 e.g. "5m"，防止抖动
 	Severity        string         `gorm:"size:20;default:'warning'" json:"severity" yaml:"severity"` // critical, warning, info
 	Labels          string         `gorm:"type:text" json:"labels" yaml:"labels"`                  // JSON 额外标签 {"key":"val"}
 	Annotations     string         `gorm:"type:text" json:"annotations" yaml:"annotations"`             // JSON {"title":"...","description":"..."}
@@ -16,6 +16,10 @@ type AlertSubscription struct {
 	AssetGroupID uint           `gorm:"index" json:"assetGroupId"`
 	Description  string         `gorm:"size:500" json:"description"`
 	Enabled      bool           `gorm:"default:true" json:"enabled"`
+	// 值班表 ID，>0 时接收人为通知时刻的值班人员（替代固定接收用户）
+	ScheduleID uint `gorm:"default:0" json:"scheduleId"`
+	// 升级策略 ID，>0 时未人工介入的告警按策略逐级升级通知
+	EscalationPolicyID uint `gorm:"default:0" json:"escalationPolicyId"`
 }
 
 func (AlertSubscription) TableName() string {
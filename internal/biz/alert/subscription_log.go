@@ -16,6 +16,9 @@ type AlertSubscriptionLog struct {
 	DenoiseResult string `gorm:"type:text" json:"denoiseResult"`
 	// 推送结果详情，JSON格式：{"channels":[{"id":1,"name":"企微","status":"success"}],"users":[1,2,3]}
 	NotifyResult string `gorm:"type:text" json:"notifyResult"`
+
+	// 展示用虚拟字段：各通道投递状态（pending/sent/failed/dead），查询时回填
+	Deliveries []*AlertNotifyDelivery `gorm:"-" json:"deliveries"`
 }
 
 func (AlertSubscriptionLog) TableName() string {
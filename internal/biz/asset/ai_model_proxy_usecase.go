@@ -21,15 +21,11 @@ package asset
 
 import (
 	"context"
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/rand"
-	"encoding/base64"
 	"errors"
 	"fmt"
-	"io"
 
 	"github.com/google/uuid"
+	"github.com/ydcloud-dy/opshub/pkg/secret"
 	"gorm.io/gorm"
 )
 
@@ -37,80 +33,16 @@ type AIModelProxyUseCase struct {
 	proxyRepo      AIModelProxyRepo
 	assetGroupRepo AssetGroupRepo
 	hostRepo       HostRepo
-	encryptionKey  []byte
 }
 
 func NewAIModelProxyUseCase(proxyRepo AIModelProxyRepo, assetGroupRepo AssetGroupRepo, hostRepo HostRepo) *AIModelProxyUseCase {
-	// AES-256要求密钥长度必须是32字节（256位）
-	encryptionKey := []byte("opshub-enc-key-32-bytes-long!!!!")
 	return &AIModelProxyUseCase{
 		proxyRepo:      proxyRepo,
 		assetGroupRepo: assetGroupRepo,
 		hostRepo:       hostRepo,
-		encryptionKey:  encryptionKey,
 	}
 }
 
-// encrypt 加密
-func (uc *AIModelProxyUseCase) encrypt(plaintext string) (string, error) {
-	if plaintext == "" {
-		return "", nil
-	}
-
-	block, err := aes.NewCipher(uc.encryptionKey)
-	if err != nil {
-		return "", err
-	}
-
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return "", err
-	}
-
-	nonce := make([]byte, gcm.NonceSize())
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		return "", err
-	}
-
-	ciphertext := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
-	return base64.StdEncoding.EncodeToString(ciphertext), nil
-}
-
-// decrypt 解密
-func (uc *AIModelProxyUseCase) decrypt(ciphertext string) (string, error) {
-	if ciphertext == "" {
-		return "", nil
-	}
-
-	data, err := base64.StdEncoding.DecodeString(ciphertext)
-	if err != nil {
-		return "", err
-	}
-
-	block, err := aes.NewCipher(uc.encryptionKey)
-	if err != nil {
-		return "", err
-	}
-
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return "", err
-	}
-
-	nonceSize := gcm.NonceSize()
-	if len(data) < nonceSize {
-		return "", errors.New("ciphertext too short")
-	}
-
-	nonce, ciphertextBytes := data[:nonceSize], data[nonceSize:]
-	plaintext, err := gcm.Open(nil, nonce, ciphertextBytes, nil)
-	if err != nil {
-		return "", err
-	}
-
-	return string(plaintext), nil
-}
-
 // Create 创建AI模型代理
 func (uc *AIModelProxyUseCase) Create(req *AIModelProxyRequest) (*AIModelProxyVO, error) {
 	ctx := context.Background()
@@ -132,7 +64,7 @@ func (uc *AIModelProxyUseCase) Create(req *AIModelProxyRequest) (*AIModelProxyVO
 
 	// 加密API密钥
 	if proxy.APIKey != "" {
-		encrypted, err := uc.encrypt(proxy.APIKey)
+		encrypted, err := secret.Encrypt(proxy.APIKey)
 		if err != nil {
 			return nil, fmt.Errorf("加密API密钥失败: %v", err)
 		}
@@ -191,7 +123,7 @@ func (uc *AIModelProxyUseCase) Update(req *AIModelProxyRequest) (*AIModelProxyVO
 
 	// 更新API密钥（如果提供了新的）
 	if req.APIKey != "" {
-		encrypted, err := uc.encrypt(req.APIKey)
+		encrypted, err := secret.Encrypt(req.APIKey)
 		if err != nil {
 			return nil, fmt.Errorf("加密API密钥失败: %v", err)
 		}
@@ -262,7 +194,7 @@ func (uc *AIModelProxyUseCase) GetByToken(token string) (*AIModelProxy, []uint,
 
 	// 解密API密钥
 	if proxy.APIKey != "" {
-		decrypted, err := uc.decrypt(proxy.APIKey)
+		decrypted, err := secret.Decrypt(proxy.APIKey)
 		if err != nil {
 			return nil, nil, fmt.Errorf("解密API密钥失败: %v", err)
 		}
@@ -390,7 +322,7 @@ func (uc *AIModelProxyUseCase) toVO(proxy *AIModelProxy, agentHostIDs []uint) (*
 
 	// API密钥脱敏（仅显示前4位和后4位）
 	if proxy.APIKey != "" {
-		decrypted, err := uc.decrypt(proxy.APIKey)
+		decrypted, err := secret.Decrypt(proxy.APIKey)
 		if err == nil && len(decrypted) > 8 {
 			vo.APIKey = decrypted[:4] + "****" + decrypted[len(decrypted)-4:]
 		} else {
@@ -0,0 +1,159 @@
+// Copyright (c) 2026 DYCloud J.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package asset
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// awsDefaultRegion 账号未配置默认区域时用来查询区域列表
+const awsDefaultRegion = "us-east-1"
+
+// newEC2Client 创建指定区域的EC2客户端，只使用账号中的AccessKey，不读取本机的AWS配置
+func (uc *CloudAccountUseCase) newEC2Client(account *CloudAccount, region string) *ec2.Client {
+	cfg := aws.Config{
+		Region:      region,
+		Credentials: credentials.NewStaticCredentialsProvider(account.AccessKey, account.SecretKey, ""),
+	}
+	return ec2.NewFromConfig(cfg, func(o *ec2.Options) {
+		if uc.endpoints.AWS != "" {
+			o.BaseEndpoint = aws.String(uc.endpoints.AWS)
+		}
+	})
+}
+
+// listAWSRegions 获取AWS账号已启用的区域列表
+func (uc *CloudAccountUseCase) listAWSRegions(ctx context.Context, account *CloudAccount) ([]CloudRegion, error) {
+	region := account.Region
+	if region == "" {
+		region = awsDefaultRegion
+	}
+
+	response, err := uc.newEC2Client(account, region).DescribeRegions(ctx, &ec2.DescribeRegionsInput{})
+	if err != nil {
+		return nil, fmt.Errorf("获取AWS区域列表失败: %w", err)
+	}
+
+	var regions []CloudRegion
+	for _, r := range response.Regions {
+		name := aws.ToString(r.RegionName)
+		if name == "" {
+			continue
+		}
+		regions = append(regions, CloudRegion{Value: name, Label: name})
+	}
+	return regions, nil
+}
+
+// listAWSInstances 获取AWS EC2实例列表，CPU和内存按实例规格查询
+func (uc *CloudAccountUseCase) listAWSInstances(ctx context.Context, account *CloudAccount, region string) ([]CloudInstance, error) {
+	client := uc.newEC2Client(account, region)
+
+	var instances []CloudInstance
+	paginator := ec2.NewDescribeInstancesPaginator(client, &ec2.DescribeInstancesInput{MaxResults: aws.Int32(1000)})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("获取AWS实例失败: %w", err)
+		}
+		for _, reservation := range page.Reservations {
+			for _, inst := range reservation.Instances {
+				instance := CloudInstance{
+					InstanceID:   aws.ToString(inst.InstanceId),
+					PublicIP:     aws.ToString(inst.PublicIpAddress),
+					PrivateIP:    aws.ToString(inst.PrivateIpAddress),
+					OS:           aws.ToString(inst.PlatformDetails),
+					InstanceType: string(inst.InstanceType),
+				}
+				if inst.State != nil {
+					instance.Status = string(inst.State.Name)
+				}
+				for _, tag := range inst.Tags {
+					if aws.ToString(tag.Key) == "Name" {
+						instance.Name = aws.ToString(tag.Value)
+					}
+				}
+				if inst.CpuOptions != nil {
+					instance.CPU = int(aws.ToInt32(inst.CpuOptions.CoreCount) * aws.ToInt32(inst.CpuOptions.ThreadsPerCore))
+				}
+				instances = append(instances, instance)
+			}
+		}
+	}
+
+	// 规格查询失败不影响实例列表
+	if err := uc.fillAWSInstanceSpecs(ctx, client, instances); err != nil {
+		appLogger.Warn("查询AWS实例规格失败", zap.String("region", region), zap.Error(err))
+	}
+	return instances, nil
+}
+
+// fillAWSInstanceSpecs 按实例规格补充CPU核数和内存
+func (uc *CloudAccountUseCase) fillAWSInstanceSpecs(ctx context.Context, client *ec2.Client, instances []CloudInstance) error {
+	var instanceTypes []ec2types.InstanceType
+	seen := make(map[string]bool)
+	for _, instance := range instances {
+		if instance.InstanceType != "" && !seen[instance.InstanceType] {
+			seen[instance.InstanceType] = true
+			instanceTypes = append(instanceTypes, ec2types.InstanceType(instance.InstanceType))
+		}
+	}
+
+	type spec struct{ cpu, memoryMB int }
+	specs := make(map[string]spec, len(instanceTypes))
+	// 每次最多查询100种规格
+	for start := 0; start < len(instanceTypes); start += 100 {
+		end := min(start+100, len(instanceTypes))
+		response, err := client.DescribeInstanceTypes(ctx, &ec2.DescribeInstanceTypesInput{InstanceTypes: instanceTypes[start:end]})
+		if err != nil {
+			return err
+		}
+		for _, info := range response.InstanceTypes {
+			var s spec
+			if info.VCpuInfo != nil {
+				s.cpu = int(aws.ToInt32(info.VCpuInfo.DefaultVCpus))
+			}
+			if info.MemoryInfo != nil {
+				s.memoryMB = int(aws.ToInt64(info.MemoryInfo.SizeInMiB))
+			}
+			specs[string(info.InstanceType)] = s
+		}
+	}
+
+	for i := range instances {
+		s, ok := specs[instances[i].InstanceType]
+		if !ok {
+			continue
+		}
+		if instances[i].CPU == 0 {
+			instances[i].CPU = s.cpu
+		}
+		instances[i].MemoryMB = s.memoryMB
+	}
+	return nil
+}
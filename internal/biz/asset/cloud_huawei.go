@@ -0,0 +1,193 @@
+// Copyright (c) 2026 DYCloud J.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package asset
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/auth/basic"
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/auth/global"
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/region"
+	huaweiecs "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ecs/v2"
+	ecsmodel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ecs/v2/model"
+	ecsregion "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ecs/v2/region"
+	huaweiiam "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/iam/v3"
+	iammodel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/iam/v3/model"
+)
+
+// huaweiIAMEndpoint 华为云全局IAM地址
+const huaweiIAMEndpoint = "https://iam.myhuaweicloud.com"
+
+// listHuaweiRegions 获取华为云账号下的区域列表
+// 华为云每个区域对应一个同名项目，子项目和系统项目不作为区域返回
+func (uc *CloudAccountUseCase) listHuaweiRegions(account *CloudAccount) ([]CloudRegion, error) {
+	cred, err := global.NewCredentialsBuilder().
+		WithAk(account.AccessKey).
+		WithSk(account.SecretKey).
+		SafeBuild()
+	if err != nil {
+		return nil, fmt.Errorf("创建华为云凭证失败: %w", err)
+	}
+
+	endpoint := uc.endpoints.HuaweiIAM
+	if endpoint == "" {
+		endpoint = huaweiIAMEndpoint
+	}
+	hcClient, err := huaweiiam.IamClientBuilder().
+		WithEndpoints([]string{endpoint}).
+		WithCredential(cred).
+		SafeBuild()
+	if err != nil {
+		return nil, fmt.Errorf("创建华为云客户端失败: %w", err)
+	}
+
+	response, err := huaweiiam.NewIamClient(hcClient).KeystoneListAuthProjects(&iammodel.KeystoneListAuthProjectsRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("获取华为云区域列表失败: %w", err)
+	}
+
+	var regions []CloudRegion
+	if response.Projects != nil {
+		for _, project := range *response.Projects {
+			if !project.Enabled {
+				continue
+			}
+			if _, err := ecsregion.SafeValueOf(project.Name); err != nil {
+				continue
+			}
+			regions = append(regions, CloudRegion{Value: project.Name, Label: project.Name})
+		}
+	}
+	sort.Slice(regions, func(i, j int) bool { return regions[i].Value < regions[j].Value })
+	return regions, nil
+}
+
+// newHuaweiECSClient 创建指定区域的ECS客户端，项目ID由SDK按区域自动查询
+func (uc *CloudAccountUseCase) newHuaweiECSClient(account *CloudAccount, regionID string) (*huaweiecs.EcsClient, error) {
+	builder := basic.NewCredentialsBuilder().
+		WithAk(account.AccessKey).
+		WithSk(account.SecretKey)
+	if uc.endpoints.HuaweiIAM != "" {
+		builder.WithIamEndpointOverride(uc.endpoints.HuaweiIAM)
+	}
+	cred, err := builder.SafeBuild()
+	if err != nil {
+		return nil, fmt.Errorf("创建华为云凭证失败: %w", err)
+	}
+
+	var reg *region.Region
+	if uc.endpoints.Huawei != "" {
+		reg = region.NewRegion(regionID, uc.endpoints.Huawei)
+	} else if reg, err = ecsregion.SafeValueOf(regionID); err != nil {
+		return nil, fmt.Errorf("不支持的华为云区域: %s", regionID)
+	}
+
+	hcClient, err := huaweiecs.EcsClientBuilder().
+		WithRegion(reg).
+		WithCredential(cred).
+		SafeBuild()
+	if err != nil {
+		return nil, fmt.Errorf("创建华为云客户端失败: %w", err)
+	}
+	return huaweiecs.NewEcsClient(hcClient), nil
+}
+
+// listHuaweiInstances 获取华为云ECS实例列表
+func (uc *CloudAccountUseCase) listHuaweiInstances(account *CloudAccount, region string) ([]CloudInstance, error) {
+	client, err := uc.newHuaweiECSClient(account, region)
+	if err != nil {
+		return nil, err
+	}
+
+	var instances []CloudInstance
+	limit := int32(100)
+	// 华为云的 offset 为页码，从1开始
+	for page := int32(1); ; page++ {
+		offset := page
+		response, err := client.ListServersDetails(&ecsmodel.ListServersDetailsRequest{
+			Limit:  &limit,
+			Offset: &offset,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("获取华为云实例失败: %w", err)
+		}
+		if response.Servers == nil {
+			break
+		}
+
+		for _, server := range *response.Servers {
+			instance := CloudInstance{
+				InstanceID: server.Id,
+				Name:       server.Name,
+				Status:     server.Status,
+				OS:         server.Metadata["image_name"],
+			}
+			if instance.OS == "" {
+				instance.OS = server.Metadata["os_type"]
+			}
+			instance.PublicIP, instance.PrivateIP = huaweiServerIPs(server.Addresses)
+			if server.Flavor != nil {
+				instance.InstanceType = server.Flavor.Id
+				instance.CPU, _ = strconv.Atoi(server.Flavor.Vcpus)
+				instance.MemoryMB, _ = strconv.Atoi(server.Flavor.Ram)
+			}
+			instances = append(instances, instance)
+		}
+
+		if len(*response.Servers) < int(limit) {
+			break
+		}
+	}
+
+	return instances, nil
+}
+
+// huaweiServerIPs 从网卡地址中取公网（弹性）IP和私网IP，主网卡优先
+func huaweiServerIPs(addresses map[string][]ecsmodel.ServerAddress) (publicIP, privateIP string) {
+	vpcIDs := make([]string, 0, len(addresses))
+	for vpcID := range addresses {
+		vpcIDs = append(vpcIDs, vpcID)
+	}
+	sort.Strings(vpcIDs)
+
+	floating := ecsmodel.GetServerAddressOSEXTIPStypeEnum().FLOATING
+	primaryFound := false
+	for _, vpcID := range vpcIDs {
+		for _, addr := range addresses[vpcID] {
+			if addr.Version != "4" {
+				continue
+			}
+			if addr.OSEXTIPStype != nil && *addr.OSEXTIPStype == floating {
+				if publicIP == "" {
+					publicIP = addr.Addr
+				}
+				continue
+			}
+			primary := addr.Primary != nil && *addr.Primary
+			if privateIP == "" || (primary && !primaryFound) {
+				privateIP = addr.Addr
+				primaryFound = primary
+			}
+		}
+	}
+	return publicIP, privateIP
+}
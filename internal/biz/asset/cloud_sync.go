@@ -0,0 +1,388 @@
+// Copyright (c) 2026 DYCloud J.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package asset
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
+	"github.com/ydcloud-dy/opshub/pkg/scheduler"
+	"go.uber.org/zap"
+)
+
+// 云实例状态，各云厂商返回的实例状态统一为以下几种
+const (
+	CloudStatusRunning    = "running"
+	CloudStatusStopped    = "stopped"
+	CloudStatusPending    = "pending"
+	CloudStatusTerminated = "terminated"
+	// CloudStatusDecommissioned 实例已在云上释放，主机记录保留用于追溯
+	CloudStatusDecommissioned = "decommissioned"
+)
+
+// cloudSyncProviders 支持同步实例清单的云厂商
+var cloudSyncProviders = map[string]bool{
+	"aliyun":  true,
+	"tencent": true,
+	"aws":     true,
+	"huawei":  true,
+}
+
+// CloudEndpoints 云厂商接口地址，为空时使用公有云默认地址。
+// 用于对接兼容接口的私有云或代理
+type CloudEndpoints struct {
+	AWS       string // EC2 接口地址
+	Huawei    string // ECS 接口地址
+	HuaweiIAM string // IAM 接口地址，用于查询区域和项目ID
+}
+
+// SetEndpoints 设置云厂商接口地址
+func (uc *CloudAccountUseCase) SetEndpoints(endpoints CloudEndpoints) {
+	uc.endpoints = endpoints
+}
+
+// CloudSyncResult 云主机同步结果
+type CloudSyncResult struct {
+	AccountID      uint     `json:"accountId"`
+	AccountName    string   `json:"accountName"`
+	Regions        int      `json:"regions"`
+	Created        int      `json:"created"`
+	Updated        int      `json:"updated"`
+	Decommissioned int      `json:"decommissioned"`
+	Errors         []string `json:"errors,omitempty"`
+}
+
+// listRegions 按云厂商获取区域列表
+func (uc *CloudAccountUseCase) listRegions(ctx context.Context, account *CloudAccount) ([]CloudRegion, error) {
+	switch account.Provider {
+	case "aliyun":
+		return uc.listAliyunRegions(account)
+	case "tencent":
+		return uc.listTencentRegions(account)
+	case "aws":
+		return uc.listAWSRegions(ctx, account)
+	case "huawei":
+		return uc.listHuaweiRegions(account)
+	case "jdcloud":
+		return uc.listJDCloudRegions(account)
+	default:
+		return nil, fmt.Errorf("暂不支持该云平台")
+	}
+}
+
+// listInstances 按云厂商获取区域内的实例列表
+func (uc *CloudAccountUseCase) listInstances(ctx context.Context, account *CloudAccount, region string) ([]CloudInstance, error) {
+	switch account.Provider {
+	case "aliyun":
+		return uc.listAliyunInstances(account, region)
+	case "tencent":
+		return uc.listTencentInstances(account, region)
+	case "aws":
+		return uc.listAWSInstances(ctx, account, region)
+	case "huawei":
+		return uc.listHuaweiInstances(account, region)
+	default:
+		return nil, fmt.Errorf("暂不支持该云平台")
+	}
+}
+
+// Sync 同步云账号下所有区域的实例到主机：新实例创建主机，已有主机更新IP、规格和状态，
+// 云上已释放的实例标记为已下线。区域拉取失败时不下线该区域的主机，留到下次同步
+func (uc *CloudAccountUseCase) Sync(ctx context.Context, accountID uint, hostRepo HostRepo) (*CloudSyncResult, error) {
+	account, err := uc.repo.GetByIDDecrypted(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("获取云平台账号失败: %w", err)
+	}
+	if !cloudSyncProviders[account.Provider] {
+		return nil, fmt.Errorf("暂不支持同步该云平台")
+	}
+
+	regions, err := uc.listRegions(ctx, account)
+	if err != nil {
+		return nil, fmt.Errorf("获取区域列表失败: %w", err)
+	}
+	// 没有区域时无法区分账号无权限和确实没有实例，不做下线
+	if len(regions) == 0 {
+		return nil, fmt.Errorf("未获取到可用区域")
+	}
+
+	hosts, err := hostRepo.GetByCloudAccountID(ctx, account.ID)
+	if err != nil {
+		return nil, fmt.Errorf("获取云主机失败: %w", err)
+	}
+	hostByInstance := make(map[string]*Host, len(hosts))
+	for _, host := range hosts {
+		if host.CloudInstanceID != "" {
+			hostByInstance[host.CloudInstanceID] = host
+		}
+	}
+
+	result := &CloudSyncResult{AccountID: account.ID, AccountName: account.Name, Regions: len(regions)}
+	listed := make(map[string]bool, len(regions))
+	for _, region := range regions {
+		listed[region.Value] = true
+	}
+	scanned := make(map[string]bool, len(regions))
+	alive := make(map[string]bool)
+	for _, region := range regions {
+		instances, err := uc.listInstances(ctx, account, region.Value)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("区域 %s: %v", region.Value, err))
+			continue
+		}
+		scanned[region.Value] = true
+
+		for _, instance := range instances {
+			// 已释放但仍在列表中短暂保留的实例按不存在处理
+			if normalizeCloudStatus(instance.Status) == CloudStatusTerminated {
+				continue
+			}
+			alive[instance.InstanceID] = true
+			if err := uc.syncInstance(ctx, hostRepo, account, region.Value, instance, hostByInstance[instance.InstanceID], result); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("实例 %s: %v", instance.InstanceID, err))
+			}
+		}
+	}
+
+	allScanned := len(scanned) == len(regions)
+	now := time.Now()
+	for _, host := range hosts {
+		if host.CloudInstanceID == "" || alive[host.CloudInstanceID] || host.CloudStatus == CloudStatusDecommissioned {
+			continue
+		}
+		// 早期导入的主机没有记录区域，全部区域都拉取成功才能确认实例已释放；
+		// 区域已不在账号的区域列表中（关闭或不再授权）时其中的实例按已释放处理
+		if listed[host.CloudRegion] && !scanned[host.CloudRegion] || host.CloudRegion == "" && !allScanned {
+			continue
+		}
+		host.CloudStatus = CloudStatusDecommissioned
+		host.DecommissionedAt = &now
+		host.Status = 0
+		if err := hostRepo.Update(ctx, host); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("主机 %s 下线失败: %v", host.Name, err))
+			continue
+		}
+		result.Decommissioned++
+	}
+
+	return result, nil
+}
+
+// syncInstance 同步单个实例，host 为该账号下已关联此实例的主机
+func (uc *CloudAccountUseCase) syncInstance(ctx context.Context, hostRepo HostRepo, account *CloudAccount, region string, instance CloudInstance, host *Host, result *CloudSyncResult) error {
+	ip := instance.PublicIP
+	if ip == "" {
+		ip = instance.PrivateIP
+	}
+
+	if host == nil && instance.PublicIP != "" {
+		// 只按公网IP登记过的自建主机关联到实例。私网IP在不同VPC、不同账号间会重复，不用于关联；
+		// 同一公网IP有多台未关联的主机时无法确定是哪一台，也不关联
+		unlinked, err := hostRepo.ListUnlinkedByIP(ctx, instance.PublicIP)
+		if err != nil {
+			return fmt.Errorf("查询主机失败: %w", err)
+		}
+		if len(unlinked) == 1 {
+			host = unlinked[0]
+			host.Type = "cloud"
+			host.CloudProvider = account.Provider
+			host.CloudInstanceID = instance.InstanceID
+			host.CloudAccountID = account.ID
+		}
+	}
+
+	if host == nil {
+		if ip == "" {
+			return fmt.Errorf("没有IP地址，跳过")
+		}
+		name := instance.Name
+		if name == "" {
+			name = instance.InstanceID
+		}
+		hostReq := &HostRequest{
+			Name:            name,
+			Type:            "cloud",
+			CloudProvider:   account.Provider,
+			CloudInstanceID: instance.InstanceID,
+			CloudAccountID:  account.ID,
+			SSHUser:         "root",
+			IP:              ip,
+			Port:            22,
+			Description:     fmt.Sprintf("从%s同步", account.Name),
+		}
+		host = hostReq.ToModel()
+		host.OS = instance.OS
+		applyCloudInstance(host, instance, region)
+		if err := hostRepo.Create(ctx, host); err != nil {
+			return fmt.Errorf("创建主机失败: %w", err)
+		}
+		result.Created++
+		return nil
+	}
+
+	changed := host.CloudAccountID != account.ID || host.CloudInstanceID != instance.InstanceID
+	host.CloudAccountID = account.ID
+	if instance.Name != "" && host.Name != instance.Name {
+		host.Name = instance.Name
+		changed = true
+	}
+	// 主机使用的IP已不属于该实例（如释放弹性IP）时改用实例当前的IP
+	if ip != "" && host.IP != instance.PublicIP && host.IP != instance.PrivateIP {
+		host.IP = ip
+		changed = true
+	}
+	if host.OS == "" && instance.OS != "" {
+		host.OS = instance.OS
+		changed = true
+	}
+	if applyCloudInstance(host, instance, region) {
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	if err := hostRepo.Update(ctx, host); err != nil {
+		return fmt.Errorf("更新主机失败: %w", err)
+	}
+	result.Updated++
+	return nil
+}
+
+// applyCloudInstance 将实例的区域、规格和状态写入主机，返回是否有变化。
+// CPU和内存以主机上采集的实际值为准，只在规格变更或尚未采集时覆盖
+func applyCloudInstance(host *Host, instance CloudInstance, region string) bool {
+	changed := false
+	if region != "" && host.CloudRegion != region {
+		host.CloudRegion = region
+		changed = true
+	}
+	if status := normalizeCloudStatus(instance.Status); host.CloudStatus != status {
+		host.CloudStatus = status
+		changed = true
+	}
+	if host.DecommissionedAt != nil {
+		host.DecommissionedAt = nil
+		changed = true
+	}
+
+	resized := false
+	if instance.InstanceType != "" && host.CloudInstanceType != instance.InstanceType {
+		resized = host.CloudInstanceType != ""
+		host.CloudInstanceType = instance.InstanceType
+		changed = true
+	}
+	if instance.CPU > 0 && (resized || host.CPUCores == 0) && host.CPUCores != instance.CPU {
+		host.CPUCores = instance.CPU
+		changed = true
+	}
+	memoryTotal := uint64(instance.MemoryMB) * 1024 * 1024
+	if memoryTotal > 0 && (resized || host.MemoryTotal == 0) && host.MemoryTotal != memoryTotal {
+		host.MemoryTotal = memoryTotal
+		changed = true
+	}
+	return changed
+}
+
+// normalizeCloudStatus 将各云厂商的实例状态统一为 running/stopped/pending/terminated
+func normalizeCloudStatus(status string) string {
+	switch s := strings.ToLower(status); s {
+	case "running", "active":
+		return CloudStatusRunning
+	case "stopped", "stopping", "shutoff", "shutdown":
+		return CloudStatusStopped
+	case "terminated", "shutting-down", "terminating", "deleted", "soft_deleted", "hard_deleted":
+		return CloudStatusTerminated
+	case "pending", "starting", "build", "rebooting", "reboot", "hard_reboot", "resize", "verify_resize", "rebuild", "migrating":
+		return CloudStatusPending
+	default:
+		return s
+	}
+}
+
+// cloudStatusText 云实例状态显示文本
+func cloudStatusText(status string) string {
+	switch status {
+	case CloudStatusRunning:
+		return "运行中"
+	case CloudStatusStopped:
+		return "已停止"
+	case CloudStatusPending:
+		return "变更中"
+	case CloudStatusDecommissioned:
+		return "已下线"
+	default:
+		return status
+	}
+}
+
+// CloudSyncExecutor 云主机清单同步执行器，定时同步所有启用的云账号
+type CloudSyncExecutor struct {
+	cloudUseCase *CloudAccountUseCase
+	hostRepo     HostRepo
+}
+
+// NewCloudSyncExecutor 创建云主机同步执行器
+func NewCloudSyncExecutor(cloudUseCase *CloudAccountUseCase, hostRepo HostRepo) *CloudSyncExecutor {
+	return &CloudSyncExecutor{
+		cloudUseCase: cloudUseCase,
+		hostRepo:     hostRepo,
+	}
+}
+
+// Type 返回执行器类型
+func (e *CloudSyncExecutor) Type() string {
+	return "cloud_inventory_sync"
+}
+
+// Execute 依次同步所有启用的云账号，单个账号失败不影响其他账号
+func (e *CloudSyncExecutor) Execute(ctx context.Context, task scheduler.Task) error {
+	accounts, err := e.cloudUseCase.repo.GetAll(ctx)
+	if err != nil {
+		return fmt.Errorf("获取云平台账号失败: %w", err)
+	}
+
+	var failed []string
+	for _, account := range accounts {
+		if account.Status != 1 || !cloudSyncProviders[account.Provider] {
+			continue
+		}
+		result, err := e.cloudUseCase.Sync(ctx, account.ID, e.hostRepo)
+		if err != nil {
+			appLogger.Error("云主机同步失败", zap.String("account", account.Name), zap.Error(err))
+			failed = append(failed, fmt.Sprintf("%s: %v", account.Name, err))
+			continue
+		}
+		appLogger.Info("云主机同步完成",
+			zap.String("account", account.Name),
+			zap.Int("created", result.Created),
+			zap.Int("updated", result.Updated),
+			zap.Int("decommissioned", result.Decommissioned),
+			zap.Strings("errors", result.Errors),
+		)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("云主机同步失败: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
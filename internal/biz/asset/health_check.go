@@ -17,6 +17,7 @@ type HostHealthExecutor struct {
 	hostRepo       HostRepo
 	credentialRepo CredentialRepo
 	agentFactory   AgentCommandFactory
+	jumpChains     *JumpChainUseCase
 }
 
 // NewHostHealthExecutor 创建主机健康检查执行器
@@ -32,6 +33,11 @@ func (e *HostHealthExecutor) SetAgentCommandFactory(f AgentCommandFactory) {
 	e.agentFactory = f
 }
 
+// SetJumpChainUseCase 设置跳板链，设置后SSH检查经主机配置的跳板连接
+func (e *HostHealthExecutor) SetJumpChainUseCase(jumpChains *JumpChainUseCase) {
+	e.jumpChains = jumpChains
+}
+
 // Type 返回执行器类型
 func (e *HostHealthExecutor) Type() string {
 	return "host_health_check"
@@ -53,6 +59,10 @@ func (e *HostHealthExecutor) Execute(ctx context.Context, task scheduler.Task) e
 	sem := make(chan struct{}, 20) // 并发信号量
 
 	for _, host := range hosts {
+		// 云上已释放的主机不再检测
+		if host.CloudStatus == CloudStatusDecommissioned {
+			continue
+		}
 		wg.Add(1)
 		sem <- struct{}{}
 		go func(h *Host) {
@@ -103,7 +113,7 @@ func (e *HostHealthExecutor) checkHost(ctx context.Context, host *Host) {
 
 // testSSH 通过 SSH 测试主机连通性
 func (e *HostHealthExecutor) testSSH(ctx context.Context, host *Host) bool {
-	cred, err := e.credentialRepo.GetByIDDecrypted(ctx, host.CredentialID)
+	cred, err := e.credentialRepo.GetByIDDecrypted(WithSSHSystem(ctx, "health-check"), host.CredentialID)
 	if err != nil {
 		return false
 	}
@@ -121,12 +131,18 @@ func (e *HostHealthExecutor) testSSH(ctx context.Context, host *Host) bool {
 		port = 22
 	}
 
-	var privateKey []byte
-	if cred.PrivateKey != "" {
-		privateKey = []byte(cred.PrivateKey)
+	var client *sshclient.Client
+	if e.jumpChains != nil {
+		target := *host
+		target.SSHUser, target.Port = user, port
+		client, err = e.jumpChains.Connect(ctx, &target, cred)
+	} else {
+		var target sshclient.Endpoint
+		if target, err = credentialEndpoint(host.IP, port, user, cred); err == nil {
+			target.HostID = host.ID
+			client, err = sshclient.Dial(target)
+		}
 	}
-
-	client, err := sshclient.NewClient(host.IP, port, user, cred.Password, privateKey, cred.Passphrase)
 	if err != nil {
 		return false
 	}
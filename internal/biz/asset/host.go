@@ -35,6 +35,10 @@ type Host struct {
 	CloudProvider    string        `gorm:"type:varchar(50);comment:云厂商 aliyun/tencent/aws" json:"cloudProvider,omitempty"`
 	CloudInstanceID  string        `gorm:"type:varchar(100);comment:云实例ID" json:"cloudInstanceId,omitempty"`
 	CloudAccountID   uint          `gorm:"column:cloud_account_id;comment:云账号ID" json:"cloudAccountId,omitempty"`
+	CloudRegion      string        `gorm:"type:varchar(50);comment:云区域" json:"cloudRegion,omitempty"`
+	CloudInstanceType string       `gorm:"type:varchar(100);comment:云实例规格" json:"cloudInstanceType,omitempty"`
+	CloudStatus      string        `gorm:"type:varchar(20);comment:云实例状态 running/stopped/pending/decommissioned" json:"cloudStatus,omitempty"`
+	DecommissionedAt *time.Time    `gorm:"column:decommissioned_at;comment:云实例下线时间" json:"decommissionedAt,omitempty"`
 	SSHUser          string        `gorm:"type:varchar(50);not null;comment:SSH用户名" json:"sshUser"`
 	IP               string        `gorm:"type:varchar(50);not null;comment:IP地址" json:"ip"`
 	Port             int           `gorm:"type:int;default:22;comment:SSH端口" json:"port"`
@@ -107,6 +111,11 @@ type HostInfoVO struct {
 	CloudProvider    string         `json:"cloudProvider,omitempty"`
 	CloudProviderText string        `json:"cloudProviderText,omitempty"`
 	CloudInstanceID  string         `json:"cloudInstanceId,omitempty"`
+	CloudRegion      string         `json:"cloudRegion,omitempty"`
+	CloudInstanceType string        `json:"cloudInstanceType,omitempty"`
+	CloudStatus      string         `json:"cloudStatus,omitempty"`
+	CloudStatusText  string         `json:"cloudStatusText,omitempty"`
+	DecommissionedAt string         `json:"decommissionedAt,omitempty"`
 	SSHUser          string         `json:"sshUser"`
 	IP               string         `json:"ip"`
 	Port             int            `json:"port"`
@@ -186,19 +195,21 @@ type Credential struct {
 	UpdatedAt   time.Time `json:"updatedAt"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"deletedAt,omitempty"`
 	Name        string `gorm:"type:varchar(100);not null;comment:凭证名称" json:"name"`
-	Type        string `gorm:"type:varchar(20);not null;comment:认证方式 password/key" json:"type"`
+	Type        string `gorm:"type:varchar(20);not null;comment:认证方式 password/key/ca" json:"type"`
 	Username    string `gorm:"type:varchar(100);comment:用户名" json:"username"`
 	Password    string `gorm:"type:varchar(500);comment:密码(加密)" json:"password,omitempty"`
 	PrivateKey  string `gorm:"type:text;comment:私钥(加密)" json:"privateKey,omitempty"`
 	Passphrase  string `gorm:"type:varchar(500);comment:私钥密码(加密)" json:"passphrase,omitempty"`
 	Description string `gorm:"type:varchar(500);comment:备注" json:"description"`
+	// Certificate 私钥引用Vault SSH签名角色或凭证为CA签发时，使用时签发的临时证书，不保存
+	Certificate string `gorm:"-" json:"-"`
 }
 
 // CredentialRequest 凭证请求
 type CredentialRequest struct {
 	ID          uint   `json:"id"`
 	Name        string `json:"name" binding:"required,min=2,max=100"`
-	Type        string `json:"type" binding:"required,oneof=password key"`
+	Type        string `json:"type" binding:"required,oneof=password key ca"`
 	Username    string `json:"username"`
 	Password    string `json:"password"`
 	PrivateKey  string `json:"privateKey"`
@@ -297,6 +308,10 @@ type CloudInstanceVO struct {
 	PrivateIP  string `json:"privateIp"`
 	OS         string `json:"os"`
 	Status     string `json:"status"`
+	// 规格信息，云厂商未返回时为空
+	InstanceType string `json:"instanceType,omitempty"`
+	CPU          int    `json:"cpu,omitempty"`
+	MemoryMB     int    `json:"memoryMb,omitempty"`
 }
 
 // CloudRegionVO 云区域VO
@@ -0,0 +1,278 @@
+// Copyright (c) 2026 DYCloud J.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package asset
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
+	sshclient "github.com/ydcloud-dy/opshub/pkg/ssh"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/ssh"
+	"gorm.io/gorm"
+)
+
+// HostKeyTrustedFirstUse 首次连接时自动信任
+const HostKeyTrustedFirstUse = "首次连接"
+
+// 主机密钥变更告警状态
+const (
+	HostKeyAlertOpen      = "open"
+	HostKeyAlertRetrusted = "retrusted"
+)
+
+// HostKey 已信任的SSH主机密钥。资产主机按主机ID记录，IP变化不影响校验；
+// 不属于资产主机的地址按 host:port 记录
+type HostKey struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	Scope       string    `gorm:"type:varchar(191);uniqueIndex;comment:host:<主机ID> 或 addr:<地址>" json:"scope"`
+	HostID      uint      `gorm:"index;comment:主机ID，非资产主机为0" json:"hostId"`
+	Address     string    `gorm:"type:varchar(150);comment:最近一次连接的地址" json:"address"`
+	KeyType     string    `gorm:"type:varchar(50)" json:"keyType"`
+	PublicKey   string    `gorm:"type:text;comment:authorized_keys 格式" json:"publicKey"`
+	Fingerprint string    `gorm:"type:varchar(100)" json:"fingerprint"`
+	TrustedBy   string    `gorm:"type:varchar(100);comment:首次连接或重新信任的操作人" json:"trustedBy"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// TableName 表名
+func (HostKey) TableName() string {
+	return "ssh_host_keys"
+}
+
+// HostKeyAlert 主机密钥变更告警，同一主机提供的同一个新密钥只记录一条并累计次数
+type HostKeyAlert struct {
+	ID                   uint       `gorm:"primarykey" json:"id"`
+	Scope                string     `gorm:"type:varchar(191);index" json:"scope"`
+	HostID               uint       `gorm:"index" json:"hostId"`
+	Address              string     `gorm:"type:varchar(150)" json:"address"`
+	TrustedFingerprint   string     `gorm:"type:varchar(100)" json:"trustedFingerprint"`
+	PresentedKeyType     string     `gorm:"type:varchar(50)" json:"presentedKeyType"`
+	PresentedKey         string     `gorm:"type:text" json:"presentedKey"`
+	PresentedFingerprint string     `gorm:"type:varchar(100)" json:"presentedFingerprint"`
+	Status               string     `gorm:"type:varchar(20);index" json:"status"`
+	Count                int        `json:"count"`
+	LastSeenAt           time.Time  `json:"lastSeenAt"`
+	ResolvedBy           string     `gorm:"type:varchar(100)" json:"resolvedBy"`
+	ResolvedAt           *time.Time `json:"resolvedAt"`
+	CreatedAt            time.Time  `json:"createdAt"`
+}
+
+// TableName 表名
+func (HostKeyAlert) TableName() string {
+	return "ssh_host_key_alerts"
+}
+
+// HostKeyRepo 主机密钥存储
+type HostKeyRepo interface {
+	GetByScope(ctx context.Context, scope string) (*HostKey, error)
+	Create(ctx context.Context, key *HostKey) error
+	Update(ctx context.Context, key *HostKey) error
+	// RecordAlert 记录密钥变更，相同范围和指纹的未处理告警累计次数
+	RecordAlert(ctx context.Context, alert *HostKeyAlert) error
+	GetAlert(ctx context.Context, id uint) (*HostKeyAlert, error)
+	ListAlerts(ctx context.Context, page, pageSize int, hostID uint, status string) ([]*HostKeyAlert, int64, error)
+	// ResolveAlerts 把范围内的未处理告警标记为已处理
+	ResolveAlerts(ctx context.Context, scope, status, operator string) error
+	// ListUntrustedHostIDs 尚未记录密钥的资产主机
+	ListUntrustedHostIDs(ctx context.Context) ([]uint, error)
+}
+
+// HostKeyTrustResult 批量信任现有主机密钥时单台主机的结果，连接认证失败时密钥也可能已记录
+type HostKeyTrustResult struct {
+	HostID      uint   `json:"hostId"`
+	Trusted     bool   `json:"trusted"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// HostKeyUseCase 已知主机密钥：测试连接或采集主机信息时首次信任并记录，之后密钥变化即拒绝连接并告警，由管理员核实后重新信任
+type HostKeyUseCase struct {
+	repo HostKeyRepo
+}
+
+var _ sshclient.KnownHosts = (*HostKeyUseCase)(nil)
+
+// NewHostKeyUseCase 创建主机密钥用例
+func NewHostKeyUseCase(repo HostKeyRepo) *HostKeyUseCase {
+	return &HostKeyUseCase{repo: repo}
+}
+
+// hostKeyScope 资产主机按ID，其他地址按 host:port
+func hostKeyScope(hostID uint, addr string) string {
+	if hostID > 0 {
+		return "host:" + strconv.FormatUint(uint64(hostID), 10)
+	}
+	return "addr:" + addr
+}
+
+// Verify 实现 sshclient.KnownHosts
+func (uc *HostKeyUseCase) Verify(hostID uint, addr string, key ssh.PublicKey, trustOnFirstUse bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	scope := hostKeyScope(hostID, addr)
+	presented := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
+	fingerprint := ssh.FingerprintSHA256(key)
+
+	known, err := uc.repo.GetByScope(ctx, scope)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if !trustOnFirstUse {
+			appLogger.Warn("主机密钥尚未信任，拒绝连接",
+				zap.Uint("hostID", hostID), zap.String("addr", addr), zap.String("fingerprint", fingerprint))
+			return fmt.Errorf("%w（%s）", sshclient.ErrHostKeyUnknown, fingerprint)
+		}
+		known = &HostKey{
+			Scope:       scope,
+			HostID:      hostID,
+			Address:     addr,
+			KeyType:     key.Type(),
+			PublicKey:   presented,
+			Fingerprint: fingerprint,
+			TrustedBy:   HostKeyTrustedFirstUse,
+		}
+		if err := uc.repo.Create(ctx, known); err != nil {
+			// 并发的首次连接可能已先记录，按已记录的密钥校验
+			if known, err = uc.repo.GetByScope(ctx, scope); err != nil {
+				return fmt.Errorf("记录主机密钥失败: %w", err)
+			}
+		} else {
+			appLogger.Info("首次连接，已信任主机密钥",
+				zap.Uint("hostID", hostID), zap.String("addr", addr), zap.String("fingerprint", fingerprint))
+			return nil
+		}
+	} else if err != nil {
+		return fmt.Errorf("查询已知主机密钥失败: %w", err)
+	}
+
+	if known.PublicKey == presented {
+		return nil
+	}
+
+	appLogger.Warn("SSH主机密钥已变更，拒绝连接",
+		zap.Uint("hostID", hostID),
+		zap.String("addr", addr),
+		zap.String("trusted", known.Fingerprint),
+		zap.String("presented", fingerprint))
+	alert := &HostKeyAlert{
+		Scope:                scope,
+		HostID:               hostID,
+		Address:              addr,
+		TrustedFingerprint:   known.Fingerprint,
+		PresentedKeyType:     key.Type(),
+		PresentedKey:         presented,
+		PresentedFingerprint: fingerprint,
+		Status:               HostKeyAlertOpen,
+		Count:                1,
+		LastSeenAt:           time.Now(),
+	}
+	if err := uc.repo.RecordAlert(ctx, alert); err != nil {
+		appLogger.Error("记录主机密钥变更告警失败", zap.Error(err))
+	}
+	return fmt.Errorf("%w（已信任 %s，实际 %s）", sshclient.ErrHostKeyMismatch, known.Fingerprint, fingerprint)
+}
+
+// GetByHostID 获取主机已信任的密钥
+func (uc *HostKeyUseCase) GetByHostID(ctx context.Context, hostID uint) (*HostKey, error) {
+	return uc.repo.GetByScope(ctx, hostKeyScope(hostID, ""))
+}
+
+// ListAlerts 主机密钥变更告警列表
+func (uc *HostKeyUseCase) ListAlerts(ctx context.Context, page, pageSize int, hostID uint, status string) ([]*HostKeyAlert, int64, error) {
+	return uc.repo.ListAlerts(ctx, page, pageSize, hostID, status)
+}
+
+// Retrust 管理员核实后信任告警中的新密钥。fingerprint 须与告警中的指纹一致，
+// 避免信任到核实之后才出现的另一个密钥
+func (uc *HostKeyUseCase) Retrust(ctx context.Context, alertID uint, fingerprint, operator string) (*HostKey, error) {
+	alert, err := uc.repo.GetAlert(ctx, alertID)
+	if err != nil {
+		return nil, fmt.Errorf("告警不存在: %w", err)
+	}
+	if alert.Status != HostKeyAlertOpen {
+		return nil, errors.New("告警已处理")
+	}
+	if fingerprint != alert.PresentedFingerprint {
+		return nil, errors.New("指纹与告警中的密钥不一致")
+	}
+
+	key, err := uc.repo.GetByScope(ctx, alert.Scope)
+	if err != nil {
+		return nil, fmt.Errorf("获取已信任密钥失败: %w", err)
+	}
+	key.Address = alert.Address
+	key.KeyType = alert.PresentedKeyType
+	key.PublicKey = alert.PresentedKey
+	key.Fingerprint = alert.PresentedFingerprint
+	key.TrustedBy = operator
+	if err := uc.repo.Update(ctx, key); err != nil {
+		return nil, fmt.Errorf("更新主机密钥失败: %w", err)
+	}
+	if err := uc.repo.ResolveAlerts(ctx, alert.Scope, HostKeyAlertRetrusted, operator); err != nil {
+		return nil, fmt.Errorf("更新告警状态失败: %w", err)
+	}
+	appLogger.Warn("主机密钥已重新信任",
+		zap.Uint("hostID", key.HostID),
+		zap.String("scope", key.Scope),
+		zap.String("fingerprint", key.Fingerprint),
+		zap.String("operator", operator))
+	return key, nil
+}
+
+// TrustExisting 启用主机密钥校验前已存在的主机没有记录密钥，终端、任务和文件传输会拒绝连接。
+// 管理员确认网络可信后，对所有尚未记录密钥的主机测试连接，首次信任并记录当前密钥
+func (uc *HostKeyUseCase) TrustExisting(ctx context.Context, hosts *HostUseCase) ([]*HostKeyTrustResult, error) {
+	hostIDs, err := uc.repo.ListUntrustedHostIDs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("查询未信任主机失败: %w", err)
+	}
+
+	results := make([]*HostKeyTrustResult, len(hostIDs))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 10)
+	for i, hostID := range hostIDs {
+		wg.Add(1)
+		go func(i int, hostID uint) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			result := &HostKeyTrustResult{HostID: hostID}
+			results[i] = result
+			// 主机密钥在认证之前校验，认证失败时密钥同样已记录
+			if err := hosts.TestConnection(ctx, hostID); err != nil {
+				result.Error = err.Error()
+			}
+			if key, err := uc.GetByHostID(ctx, hostID); err == nil {
+				result.Trusted, result.Fingerprint = true, key.Fingerprint
+			}
+		}(i, hostID)
+	}
+	wg.Wait()
+	appLogger.Warn("已批量信任现有主机密钥", zap.Int("hosts", len(hostIDs)))
+	return results, nil
+}
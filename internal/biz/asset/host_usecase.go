@@ -38,6 +38,7 @@ import (
 	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
 	"github.com/xuri/excelize/v2"
 	"github.com/ydcloud-dy/opshub/pkg/collector"
+	"github.com/ydcloud-dy/opshub/pkg/secret"
 	sshclient "github.com/ydcloud-dy/opshub/pkg/ssh"
 	"github.com/ydcloud-dy/opshub/pkg/utils"
 )
@@ -54,6 +55,7 @@ type HostUseCase struct {
 	groupRepo       AssetGroupRepo
 	cloudRepo       CloudAccountRepo
 	agentCmdFactory AgentCommandFactory
+	jumpChains      *JumpChainUseCase
 }
 
 func NewHostUseCase(hostRepo HostRepo, credentialRepo CredentialRepo, groupRepo AssetGroupRepo, cloudRepo CloudAccountRepo) *HostUseCase {
@@ -70,6 +72,11 @@ func (uc *HostUseCase) SetAgentCommandFactory(factory AgentCommandFactory) {
 	uc.agentCmdFactory = factory
 }
 
+// SetJumpChainUseCase 设置跳板链，设置后主机的SSH连接经跳板建立
+func (uc *HostUseCase) SetJumpChainUseCase(jumpChains *JumpChainUseCase) {
+	uc.jumpChains = jumpChains
+}
+
 // Create 创建主机
 func (uc *HostUseCase) Create(ctx context.Context, req *HostRequest) (*Host, error) {
 	host := req.ToModel()
@@ -229,6 +236,11 @@ func (uc *HostUseCase) toInfoVO(host *Host) *HostInfoVO {
 		lastSeen = host.LastSeen.Format("2006-01-02 15:04:05")
 	}
 
+	var decommissionedAt string
+	if host.DecommissionedAt != nil {
+		decommissionedAt = host.DecommissionedAt.Format("2006-01-02 15:04:05")
+	}
+
 	return &HostInfoVO{
 		ID:               host.ID,
 		Name:             host.Name,
@@ -238,6 +250,11 @@ func (uc *HostUseCase) toInfoVO(host *Host) *HostInfoVO {
 		CloudProvider:    host.CloudProvider,
 		CloudProviderText: cloudProviderText,
 		CloudInstanceID:  host.CloudInstanceID,
+		CloudRegion:      host.CloudRegion,
+		CloudInstanceType: host.CloudInstanceType,
+		CloudStatus:      host.CloudStatus,
+		CloudStatusText:  cloudStatusText(host.CloudStatus),
+		DecommissionedAt: decommissionedAt,
 		SSHUser:          host.SSHUser,
 		IP:               host.IP,
 		Port:             host.Port,
@@ -300,7 +317,7 @@ func (uc *HostUseCase) CollectHostInfo(ctx context.Context, hostID uint) error {
 			return fmt.Errorf("获取凭证失败: %w", err)
 		}
 
-		sshClient, err := uc.createSSHClient(host, credential)
+		sshClient, err := uc.createSSHClient(ctx, host, credential, true)
 		if err != nil {
 			host.Status = 0
 			uc.hostRepo.Update(ctx, host)
@@ -380,40 +397,61 @@ func (uc *HostUseCase) CollectHostInfo(ctx context.Context, hostID uint) error {
 	return uc.hostRepo.Update(ctx, host)
 }
 
-// createSSHClient 创建SSH客户端
-func (uc *HostUseCase) createSSHClient(host *Host, credential *Credential) (*sshclient.Client, error) {
-	var privateKey []byte
-
-	// 检查凭证信息是否完整
-	if credential.Type == "password" && credential.Password == "" {
-		return nil, fmt.Errorf("凭证类型为密码认证，但未填写密码")
+// createSSHClient 创建SSH客户端，主机或所属分组配置了跳板链时经跳板连接。
+// trustOnFirstUse 为 true 时主机密钥尚未记录则信任并记录，只有测试连接和采集主机信息使用
+func (uc *HostUseCase) createSSHClient(ctx context.Context, host *Host, credential *Credential, trustOnFirstUse bool) (*sshclient.Client, error) {
+	var client *sshclient.Client
+	var err error
+	if uc.jumpChains != nil {
+		if trustOnFirstUse {
+			client, err = uc.jumpChains.ConnectTrustOnFirstUse(ctx, host, credential)
+		} else {
+			client, err = uc.jumpChains.Connect(ctx, host, credential)
+		}
+	} else {
+		var target sshclient.Endpoint
+		if target, err = credentialEndpoint(host.IP, host.Port, host.SSHUser, credential); err == nil {
+			target.HostID = host.ID
+			target.TrustOnFirstUse = trustOnFirstUse
+			client, err = sshclient.Dial(target)
+		}
 	}
-	if credential.Type == "key" && credential.PrivateKey == "" {
-		return nil, fmt.Errorf("凭证类型为密钥认证，但未填写私钥")
+	if err != nil {
+		return nil, fmt.Errorf("创建SSH客户端失败: %w", err)
 	}
 
-	// 如果是密钥认证，需要解密私钥
-	if credential.Type == "key" && credential.PrivateKey != "" {
-		// 这里私钥是从数据库读取的加密数据，需要先解密
-		// 但在credentialRepo中已经返回了解密后的数据
-		// 实际上我们需要修改CredentialRepo的GetByID方法来返回解密后的数据
-		// 或者在这里解密
-		privateKey = []byte(credential.PrivateKey)
-	}
+	return client, nil
+}
 
-	client, err := sshclient.NewClient(
-		host.IP,
-		host.Port,
-		host.SSHUser,
-		credential.Password,
-		privateKey,
-		credential.Passphrase,
-	)
+// ConnectHost 使用主机配置的凭证和跳板链建立SSH连接
+func (uc *HostUseCase) ConnectHost(ctx context.Context, hostID uint) (*sshclient.Client, error) {
+	host, err := uc.hostRepo.GetByID(ctx, hostID)
 	if err != nil {
-		return nil, fmt.Errorf("创建SSH客户端失败: %w", err)
+		return nil, fmt.Errorf("获取主机信息失败: %w", err)
 	}
+	if host.CredentialID == 0 {
+		return nil, fmt.Errorf("主机未配置凭证")
+	}
+	credential, err := uc.credentialRepo.GetByIDDecrypted(ctx, host.CredentialID)
+	if err != nil {
+		return nil, fmt.Errorf("获取凭证失败: %w", err)
+	}
+	return uc.createSSHClient(ctx, host, credential, false)
+}
 
-	return client, nil
+// RunCommand 在主机上执行命令，Agent在线时经Agent执行，否则经SSH执行
+func (uc *HostUseCase) RunCommand(ctx context.Context, hostID uint, cmd string) (string, error) {
+	if uc.agentCmdFactory != nil && uc.agentCmdFactory.IsOnline(hostID) {
+		if executor, err := uc.agentCmdFactory.NewExecutor(hostID); err == nil {
+			return executor.Execute(cmd)
+		}
+	}
+	client, err := uc.ConnectHost(ctx, hostID)
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+	return client.Execute(cmd)
 }
 
 func min(a, b int) int {
@@ -449,7 +487,7 @@ func (uc *HostUseCase) TestConnection(ctx context.Context, hostID uint) error {
 	}
 
 	// 创建SSH客户端
-	sshClient, err := uc.createSSHClient(host, credential)
+	sshClient, err := uc.createSSHClient(ctx, host, credential, true)
 	if err != nil {
 		return fmt.Errorf("创建SSH连接失败: %w", err)
 	}
@@ -484,22 +522,29 @@ func (uc *HostUseCase) BatchDelete(ctx context.Context, hostIDs []uint) error {
 
 // toCredentialVO 转换为CredentialVO
 func (uc *HostUseCase) toCredentialVO(credential *Credential) *CredentialVO {
-	typeText := "密码"
-	if credential.Type == "key" {
-		typeText = "密钥"
-	}
-
 	return &CredentialVO{
 		ID:          credential.ID,
 		Name:        credential.Name,
 		Type:        credential.Type,
-		TypeText:    typeText,
+		TypeText:    credentialTypeText(credential.Type),
 		Username:    credential.Username,
 		Description: credential.Description,
 		CreateTime:  credential.CreatedAt.Format("2006-01-02 15:04:05"),
 	}
 }
 
+// credentialTypeText 凭证认证方式名称
+func credentialTypeText(credentialType string) string {
+	switch credentialType {
+	case "key":
+		return "密钥"
+	case CredentialTypeCA:
+		return "CA签发"
+	default:
+		return "密码"
+	}
+}
+
 // GetCredentialRepo 获取凭证Repo（用于终端功能）
 func (uc *HostUseCase) GetCredentialRepo() CredentialRepo {
 	return uc.credentialRepo
@@ -510,13 +555,24 @@ func (uc *HostUseCase) GetHostRepo() HostRepo {
 	return uc.hostRepo
 }
 
-// GetByIDDecrypted 根据ID获取凭证（解密后的，用于编辑时回显）
+// GetByIDDecrypted 根据ID获取凭证（解密后的，用于编辑时回显）。
+// 引用外部后端的字段回显引用路径，不回显外部后端中的内容
 func (uc *CredentialUseCase) GetByIDDecrypted(ctx context.Context, id uint) (*Credential, error) {
-	credential, err := uc.repo.GetByIDDecrypted(ctx, id)
+	credential, err := uc.repo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
+	store := secret.Default()
+	for _, field := range []*string{&credential.Password, &credential.PrivateKey, &credential.Passphrase} {
+		if secret.IsReference(*field) {
+			continue
+		}
+		if *field, err = store.Get(ctx, *field); err != nil {
+			return nil, fmt.Errorf("解密凭证失败: %w", err)
+		}
+	}
+
 	return credential, nil
 }
 
@@ -536,6 +592,10 @@ func NewCredentialUseCase(repo CredentialRepo, hostRepo HostRepo) *CredentialUse
 // Create 创建凭证
 func (uc *CredentialUseCase) Create(ctx context.Context, req *CredentialRequest) (*Credential, error) {
 	credential := req.ToModel()
+	// CA签发的凭证每次使用时签发临时证书，不保存密码和私钥
+	if credential.Type == CredentialTypeCA {
+		credential.Password, credential.PrivateKey, credential.Passphrase = "", "", ""
+	}
 
 	if err := uc.repo.Create(ctx, credential); err != nil {
 		return nil, err
@@ -566,6 +626,9 @@ func (uc *CredentialUseCase) Update(ctx context.Context, req *CredentialRequest)
 	if req.Passphrase != "" {
 		credential.Passphrase = req.Passphrase
 	}
+	if credential.Type == CredentialTypeCA {
+		credential.Password, credential.PrivateKey, credential.Passphrase = "", "", ""
+	}
 
 	return uc.repo.Update(ctx, credential)
 }
@@ -589,11 +652,6 @@ func (uc *CredentialUseCase) List(ctx context.Context, page, pageSize int, keywo
 
 	var vos []*CredentialVO
 	for _, cred := range credentials {
-		typeText := "密码"
-		if cred.Type == "key" {
-			typeText = "密钥"
-		}
-
 		// 统计使用该凭证的主机数量
 		usedCount, _ := uc.hostRepo.CountByCredentialID(ctx, cred.ID)
 
@@ -601,7 +659,7 @@ func (uc *CredentialUseCase) List(ctx context.Context, page, pageSize int, keywo
 			ID:          cred.ID,
 			Name:        cred.Name,
 			Type:        cred.Type,
-			TypeText:    typeText,
+			TypeText:    credentialTypeText(cred.Type),
 			Username:    cred.Username,
 			Description: cred.Description,
 			CreateTime:  cred.CreatedAt.Format("2006-01-02 15:04:05"),
@@ -622,11 +680,6 @@ func (uc *CredentialUseCase) GetAll(ctx context.Context) ([]*CredentialVO, error
 
 	var vos []*CredentialVO
 	for _, cred := range credentials {
-		typeText := "密码"
-		if cred.Type == "key" {
-			typeText = "密钥"
-		}
-
 		// 统计使用该凭证的主机数量
 		usedCount, _ := uc.hostRepo.CountByCredentialID(ctx, cred.ID)
 
@@ -634,7 +687,7 @@ func (uc *CredentialUseCase) GetAll(ctx context.Context) ([]*CredentialVO, error
 			ID:          cred.ID,
 			Name:        cred.Name,
 			Type:        cred.Type,
-			TypeText:    typeText,
+			TypeText:    credentialTypeText(cred.Type),
 			Username:    cred.Username,
 			Description: cred.Description,
 			CreateTime:  cred.CreatedAt.Format("2006-01-02 15:04:05"),
@@ -648,7 +701,8 @@ func (uc *CredentialUseCase) GetAll(ctx context.Context) ([]*CredentialVO, error
 
 // CloudAccountUseCase 云平台账号用例
 type CloudAccountUseCase struct {
-	repo CloudAccountRepo
+	repo      CloudAccountRepo
+	endpoints CloudEndpoints
 }
 
 func NewCloudAccountUseCase(repo CloudAccountRepo) *CloudAccountUseCase {
@@ -741,25 +795,12 @@ func (uc *CloudAccountUseCase) GetAll(ctx context.Context) ([]*CloudAccountVO, e
 
 // GetRegions 获取云平台的区域列表
 func (uc *CloudAccountUseCase) GetRegions(ctx context.Context, accountID uint) ([]*CloudRegionVO, error) {
-	account, err := uc.repo.GetByID(ctx, accountID)
+	account, err := uc.repo.GetByIDDecrypted(ctx, accountID)
 	if err != nil {
-		return nil, fmt.Errorf("云平台账号不存在")
-	}
-
-	// 根据不同的云厂商调用不同的SDK获取区域列表
-	var regions []CloudRegion
-
-	switch account.Provider {
-	case "aliyun":
-		regions, err = uc.listAliyunRegions(account)
-	case "tencent":
-		regions, err = uc.listTencentRegions(account)
-	case "jdcloud":
-		regions, err = uc.listJDCloudRegions(account)
-	default:
-		return nil, fmt.Errorf("暂不支持该云平台")
+		return nil, fmt.Errorf("获取云平台账号失败: %w", err)
 	}
 
+	regions, err := uc.listRegions(ctx, account)
 	if err != nil {
 		return nil, fmt.Errorf("获取区域列表失败: %w", err)
 	}
@@ -778,23 +819,12 @@ func (uc *CloudAccountUseCase) GetRegions(ctx context.Context, accountID uint) (
 
 // GetInstances 获取云平台的实例列表
 func (uc *CloudAccountUseCase) GetInstances(ctx context.Context, accountID uint, region string) ([]*CloudInstanceVO, error) {
-	account, err := uc.repo.GetByID(ctx, accountID)
+	account, err := uc.repo.GetByIDDecrypted(ctx, accountID)
 	if err != nil {
-		return nil, fmt.Errorf("云平台账号不存在")
-	}
-
-	// 根据不同的云厂商调用不同的SDK获取实例列表
-	var instances []CloudInstance
-
-	switch account.Provider {
-	case "aliyun":
-		instances, err = uc.listAliyunInstances(account, region)
-	case "tencent":
-		instances, err = uc.listTencentInstances(account, region)
-	default:
-		return nil, fmt.Errorf("暂不支持该云平台")
+		return nil, fmt.Errorf("获取云平台账号失败: %w", err)
 	}
 
+	instances, err := uc.listInstances(ctx, account, region)
 	if err != nil {
 		return nil, fmt.Errorf("获取云主机列表失败: %w", err)
 	}
@@ -809,6 +839,9 @@ func (uc *CloudAccountUseCase) GetInstances(ctx context.Context, accountID uint,
 			PrivateIP:  inst.PrivateIP,
 			OS:         inst.OS,
 			Status:     inst.Status,
+			InstanceType: inst.InstanceType,
+			CPU:          inst.CPU,
+			MemoryMB:     inst.MemoryMB,
 		})
 	}
 
@@ -841,24 +874,12 @@ func (uc *CloudAccountUseCase) toVO(account *CloudAccount) *CloudAccountVO {
 
 // ImportFromCloud 从云平台导入主机
 func (uc *CloudAccountUseCase) ImportFromCloud(ctx context.Context, req *CloudImportRequest, hostUseCase *HostUseCase) error {
-	account, err := uc.repo.GetByID(ctx, req.AccountID)
+	account, err := uc.repo.GetByIDDecrypted(ctx, req.AccountID)
 	if err != nil {
-		return fmt.Errorf("云平台账号不存在")
-	}
-
-	// 根据不同的云厂商调用不同的SDK获取实例列表
-	// 这里先实现阿里云的导入
-	var instances []CloudInstance
-
-	switch account.Provider {
-	case "aliyun":
-		instances, err = uc.listAliyunInstances(account, req.Region)
-	case "tencent":
-		instances, err = uc.listTencentInstances(account, req.Region)
-	default:
-		return fmt.Errorf("暂不支持该云平台")
+		return fmt.Errorf("获取云平台账号失败: %w", err)
 	}
 
+	instances, err := uc.listInstances(ctx, account, req.Region)
 	if err != nil {
 		return fmt.Errorf("获取云主机列表失败: %w", err)
 	}
@@ -879,6 +900,7 @@ func (uc *CloudAccountUseCase) ImportFromCloud(ctx context.Context, req *CloudIm
 			// 主机已存在，更新分组
 			existHost.GroupID = req.GroupID
 			existHost.Name = instance.Name
+			applyCloudInstance(existHost, instance, req.Region)
 			if err := hostUseCase.hostRepo.Update(ctx, existHost); err != nil {
 				importErrors = append(importErrors, fmt.Sprintf("实例 %s 更新失败: %v", instance.InstanceID, err))
 			} else {
@@ -912,6 +934,7 @@ func (uc *CloudAccountUseCase) ImportFromCloud(ctx context.Context, req *CloudIm
 			if instance.OS != "" {
 				existByIP.OS = instance.OS
 			}
+			applyCloudInstance(existByIP, instance, req.Region)
 			if err := hostUseCase.hostRepo.Update(ctx, existByIP); err != nil {
 				importErrors = append(importErrors, fmt.Sprintf("实例 %s 关联IP失败: %v", instance.InstanceID, err))
 			} else {
@@ -937,6 +960,7 @@ func (uc *CloudAccountUseCase) ImportFromCloud(ctx context.Context, req *CloudIm
 		host := hostReq.ToModel()
 		host.Status = -1 // 初始状态未知
 		host.OS = instance.OS
+		applyCloudInstance(host, instance, req.Region)
 
 		if err := hostUseCase.hostRepo.Create(ctx, host); err != nil {
 			importErrors = append(importErrors, fmt.Sprintf("实例 %s 创建失败: %v", instance.InstanceID, err))
@@ -960,6 +984,10 @@ type CloudInstance struct {
 	PrivateIP  string
 	OS         string
 	Status     string
+	// InstanceType 实例规格，CPU 为核数，MemoryMB 为内存大小（MB）
+	InstanceType string
+	CPU          int
+	MemoryMB     int
 }
 
 // CloudRegion 云区域
@@ -1109,6 +1137,9 @@ func (uc *CloudAccountUseCase) listAliyunInstances(account *CloudAccount, region
 				PrivateIP: privateIP,
 				OS:        instance.OSName,
 				Status:    instance.Status,
+				InstanceType: instance.InstanceType,
+				CPU:          instance.Cpu,
+				MemoryMB:     instance.Memory,
 			})
 		}
 
@@ -1118,11 +1149,6 @@ func (uc *CloudAccountUseCase) listAliyunInstances(account *CloudAccount, region
 			break
 		}
 		pageNumber++
-
-		// 最多获取10页（1000条）
-		if pageNumber > 10 {
-			break
-		}
 	}
 
 	return allInstances, nil
@@ -1184,23 +1210,30 @@ func (uc *CloudAccountUseCase) listTencentInstances(account *CloudAccount, regio
 				status = *inst.InstanceState
 			}
 
-			allInstances = append(allInstances, CloudInstance{
+			instance := CloudInstance{
 				InstanceID: instanceID,
 				Name:       instanceName,
 				PublicIP:   publicIP,
 				PrivateIP:  privateIP,
 				OS:         osName,
 				Status:     status,
-			})
+			}
+			if inst.InstanceType != nil {
+				instance.InstanceType = *inst.InstanceType
+			}
+			if inst.CPU != nil {
+				instance.CPU = int(*inst.CPU)
+			}
+			if inst.Memory != nil {
+				instance.MemoryMB = int(*inst.Memory) * 1024 // 腾讯云内存单位为GB
+			}
+			allInstances = append(allInstances, instance)
 		}
 
 		if len(response.Response.InstanceSet) < 100 {
 			break
 		}
 		offset += 100
-		if offset >= 1000 { // 最多获取1000个实例
-			break
-		}
 	}
 
 	return allInstances, nil
@@ -1569,7 +1602,7 @@ func (uc *HostUseCase) ListFiles(ctx context.Context, hostID uint, remotePath st
 		return nil, fmt.Errorf("获取凭证失败: %w", err)
 	}
 
-	sshClient, err := uc.createSSHClient(host, credential)
+	sshClient, err := uc.createSSHClient(ctx, host, credential, false)
 	if err != nil {
 		return nil, fmt.Errorf("创建SSH连接失败: %w", err)
 	}
@@ -1619,7 +1652,7 @@ func (uc *HostUseCase) UploadFile(ctx context.Context, hostID uint, reader io.Re
 		return fmt.Errorf("获取凭证失败: %w", err)
 	}
 
-	sshClient, err := uc.createSSHClient(host, credential)
+	sshClient, err := uc.createSSHClient(ctx, host, credential, false)
 	if err != nil {
 		return fmt.Errorf("创建SSH连接失败: %w", err)
 	}
@@ -1662,7 +1695,7 @@ func (uc *HostUseCase) DownloadFile(ctx context.Context, hostID uint, remotePath
 		return fmt.Errorf("获取凭证失败: %w", err)
 	}
 
-	sshClient, err := uc.createSSHClient(host, credential)
+	sshClient, err := uc.createSSHClient(ctx, host, credential, false)
 	if err != nil {
 		return fmt.Errorf("创建SSH连接失败: %w", err)
 	}
@@ -1702,7 +1735,7 @@ func (uc *HostUseCase) DeleteFile(ctx context.Context, hostID uint, remotePath s
 		return fmt.Errorf("获取凭证失败: %w", err)
 	}
 
-	sshClient, err := uc.createSSHClient(host, credential)
+	sshClient, err := uc.createSSHClient(ctx, host, credential, false)
 	if err != nil {
 		return fmt.Errorf("创建SSH连接失败: %w", err)
 	}
@@ -0,0 +1,251 @@
+// Copyright (c) 2026 DYCloud J.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package asset
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sshclient "github.com/ydcloud-dy/opshub/pkg/ssh"
+)
+
+// 跳板链归属
+const (
+	JumpOwnerHost  = "host"
+	JumpOwnerGroup = "group"
+)
+
+// maxJumpHops 跳板链最多的跳数
+const maxJumpHops = 5
+
+// JumpHop 跳板链中的一跳，按 Seq 从小到大依次连接，每跳使用自己的凭证。
+// 主机配置的跳板链优先，未配置时使用最近的上级分组的跳板链
+type JumpHop struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	OwnerType    string    `gorm:"type:varchar(20);not null;index:idx_jump_owner;comment:归属类型 host/group" json:"ownerType"`
+	OwnerID      uint      `gorm:"not null;index:idx_jump_owner;comment:主机ID或分组ID" json:"ownerId"`
+	Seq          int       `gorm:"not null;comment:连接顺序" json:"seq"`
+	IP           string    `gorm:"type:varchar(50);not null;comment:跳板地址" json:"ip"`
+	Port         int       `gorm:"type:int;default:22;comment:跳板SSH端口" json:"port"`
+	SSHUser      string    `gorm:"type:varchar(50);not null;comment:跳板SSH用户名" json:"sshUser"`
+	CredentialID uint      `gorm:"not null;comment:跳板凭证ID" json:"credentialId"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+// TableName 表名
+func (JumpHop) TableName() string {
+	return "asset_jump_hops"
+}
+
+// JumpHopRequest 跳板配置请求
+type JumpHopRequest struct {
+	IP           string `json:"ip" binding:"required"`
+	Port         int    `json:"port"`
+	SSHUser      string `json:"sshUser" binding:"required"`
+	CredentialID uint   `json:"credentialId" binding:"required"`
+}
+
+// JumpChainVO 跳板链，Inherited 表示主机未单独配置、沿用分组的跳板链
+type JumpChainVO struct {
+	OwnerType string     `json:"ownerType"`
+	OwnerID   uint       `json:"ownerId"`
+	Inherited bool       `json:"inherited"`
+	Hops      []*JumpHop `json:"hops"`
+}
+
+// JumpHopRepo 跳板链仓储
+type JumpHopRepo interface {
+	GetChain(ctx context.Context, ownerType string, ownerID uint) ([]*JumpHop, error)
+	ReplaceChain(ctx context.Context, ownerType string, ownerID uint, hops []*JumpHop) error
+}
+
+// JumpChainUseCase 跳板链配置，并按跳板链建立到主机的SSH连接
+type JumpChainUseCase struct {
+	repo           JumpHopRepo
+	hostRepo       HostRepo
+	groupRepo      AssetGroupRepo
+	credentialRepo CredentialRepo
+}
+
+// NewJumpChainUseCase 创建跳板链用例
+func NewJumpChainUseCase(repo JumpHopRepo, hostRepo HostRepo, groupRepo AssetGroupRepo, credentialRepo CredentialRepo) *JumpChainUseCase {
+	return &JumpChainUseCase{
+		repo:           repo,
+		hostRepo:       hostRepo,
+		groupRepo:      groupRepo,
+		credentialRepo: credentialRepo,
+	}
+}
+
+// GetHostChain 获取主机生效的跳板链
+func (uc *JumpChainUseCase) GetHostChain(ctx context.Context, hostID uint) (*JumpChainVO, error) {
+	host, err := uc.hostRepo.GetByID(ctx, hostID)
+	if err != nil {
+		return nil, fmt.Errorf("获取主机信息失败: %w", err)
+	}
+	return uc.resolve(ctx, host)
+}
+
+// GetGroupChain 获取分组配置的跳板链，不含上级分组
+func (uc *JumpChainUseCase) GetGroupChain(ctx context.Context, groupID uint) (*JumpChainVO, error) {
+	hops, err := uc.repo.GetChain(ctx, JumpOwnerGroup, groupID)
+	if err != nil {
+		return nil, err
+	}
+	return &JumpChainVO{OwnerType: JumpOwnerGroup, OwnerID: groupID, Hops: hops}, nil
+}
+
+// SetHostChain 设置主机的跳板链，为空时沿用分组的跳板链
+func (uc *JumpChainUseCase) SetHostChain(ctx context.Context, hostID uint, reqs []JumpHopRequest) error {
+	if _, err := uc.hostRepo.GetByID(ctx, hostID); err != nil {
+		return fmt.Errorf("获取主机信息失败: %w", err)
+	}
+	return uc.setChain(ctx, JumpOwnerHost, hostID, reqs)
+}
+
+// SetGroupChain 设置分组的跳板链，对分组及下级分组中未单独配置的主机生效
+func (uc *JumpChainUseCase) SetGroupChain(ctx context.Context, groupID uint, reqs []JumpHopRequest) error {
+	if _, err := uc.groupRepo.GetByID(ctx, groupID); err != nil {
+		return fmt.Errorf("获取分组信息失败: %w", err)
+	}
+	return uc.setChain(ctx, JumpOwnerGroup, groupID, reqs)
+}
+
+func (uc *JumpChainUseCase) setChain(ctx context.Context, ownerType string, ownerID uint, reqs []JumpHopRequest) error {
+	if len(reqs) > maxJumpHops {
+		return fmt.Errorf("跳板最多 %d 跳", maxJumpHops)
+	}
+	hops := make([]*JumpHop, 0, len(reqs))
+	for i, req := range reqs {
+		if req.IP == "" || req.SSHUser == "" {
+			return fmt.Errorf("第 %d 跳未填写地址或用户名", i+1)
+		}
+		if _, err := uc.credentialRepo.GetByID(ctx, req.CredentialID); err != nil {
+			return fmt.Errorf("第 %d 跳的凭证不存在", i+1)
+		}
+		port := req.Port
+		if port == 0 {
+			port = 22
+		}
+		hops = append(hops, &JumpHop{
+			OwnerType:    ownerType,
+			OwnerID:      ownerID,
+			Seq:          i + 1,
+			IP:           req.IP,
+			Port:         port,
+			SSHUser:      req.SSHUser,
+			CredentialID: req.CredentialID,
+		})
+	}
+	return uc.repo.ReplaceChain(ctx, ownerType, ownerID, hops)
+}
+
+// resolve 查找主机生效的跳板链：主机自身的优先，其次由近及远查找所属分组及上级分组
+func (uc *JumpChainUseCase) resolve(ctx context.Context, host *Host) (*JumpChainVO, error) {
+	hops, err := uc.repo.GetChain(ctx, JumpOwnerHost, host.ID)
+	if err != nil {
+		return nil, fmt.Errorf("获取跳板链失败: %w", err)
+	}
+	if len(hops) > 0 {
+		return &JumpChainVO{OwnerType: JumpOwnerHost, OwnerID: host.ID, Hops: hops}, nil
+	}
+
+	visited := make(map[uint]bool)
+	for groupID := host.GroupID; groupID != 0 && !visited[groupID]; {
+		visited[groupID] = true
+		hops, err := uc.repo.GetChain(ctx, JumpOwnerGroup, groupID)
+		if err != nil {
+			return nil, fmt.Errorf("获取跳板链失败: %w", err)
+		}
+		if len(hops) > 0 {
+			return &JumpChainVO{OwnerType: JumpOwnerGroup, OwnerID: groupID, Inherited: true, Hops: hops}, nil
+		}
+		group, err := uc.groupRepo.GetByID(ctx, groupID)
+		if err != nil {
+			break
+		}
+		groupID = group.ParentID
+	}
+	return &JumpChainVO{OwnerType: JumpOwnerHost, OwnerID: host.ID}, nil
+}
+
+// Connect 按主机生效的跳板链建立SSH连接，没有跳板时直连。主机密钥须已信任
+func (uc *JumpChainUseCase) Connect(ctx context.Context, host *Host, credential *Credential) (*sshclient.Client, error) {
+	return uc.connect(ctx, host, credential, false)
+}
+
+// ConnectTrustOnFirstUse 同 Connect，目标主机和跳板的密钥尚未记录时信任并记录，仅用于测试连接和采集主机信息
+func (uc *JumpChainUseCase) ConnectTrustOnFirstUse(ctx context.Context, host *Host, credential *Credential) (*sshclient.Client, error) {
+	return uc.connect(ctx, host, credential, true)
+}
+
+func (uc *JumpChainUseCase) connect(ctx context.Context, host *Host, credential *Credential, trustOnFirstUse bool) (*sshclient.Client, error) {
+	target, err := credentialEndpoint(host.IP, host.Port, host.SSHUser, credential)
+	if err != nil {
+		return nil, err
+	}
+	target.HostID = host.ID
+	target.TrustOnFirstUse = trustOnFirstUse
+
+	chain, err := uc.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	jumps := make([]sshclient.Endpoint, 0, len(chain.Hops))
+	for _, hop := range chain.Hops {
+		cred, err := uc.credentialRepo.GetByIDDecrypted(ctx, hop.CredentialID)
+		if err != nil {
+			return nil, fmt.Errorf("获取跳板 %s 的凭证失败: %w", hop.IP, err)
+		}
+		endpoint, err := credentialEndpoint(hop.IP, hop.Port, hop.SSHUser, cred)
+		if err != nil {
+			return nil, fmt.Errorf("跳板 %s: %w", hop.IP, err)
+		}
+		endpoint.TrustOnFirstUse = trustOnFirstUse
+		jumps = append(jumps, endpoint)
+	}
+	return sshclient.Dial(target, jumps...)
+}
+
+// credentialEndpoint 使用解密后的凭证构建连接端点
+func credentialEndpoint(ip string, port int, user string, credential *Credential) (sshclient.Endpoint, error) {
+	if credential.Type == "password" && credential.Password == "" {
+		return sshclient.Endpoint{}, fmt.Errorf("凭证类型为密码认证，但未填写密码")
+	}
+	if credential.Type == "key" && credential.PrivateKey == "" {
+		return sshclient.Endpoint{}, fmt.Errorf("凭证类型为密钥认证，但未填写私钥")
+	}
+	endpoint := sshclient.Endpoint{
+		Host:       ip,
+		Port:       port,
+		Username:   user,
+		Password:   credential.Password,
+		Passphrase: credential.Passphrase,
+	}
+	if credential.PrivateKey != "" {
+		endpoint.PrivateKey = []byte(credential.PrivateKey)
+	}
+	if credential.Certificate != "" {
+		endpoint.Certificate = []byte(credential.Certificate)
+	}
+	return endpoint, nil
+}
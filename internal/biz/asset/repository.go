@@ -43,6 +43,9 @@ type HostRepo interface {
 	GetByGroupID(ctx context.Context, groupID uint) ([]*Host, error)
 	GetByIP(ctx context.Context, ip string) (*Host, error)
 	GetByCloudInstanceID(ctx context.Context, instanceID string) (*Host, error)
+	GetByCloudAccountID(ctx context.Context, accountID uint) ([]*Host, error)
+	// ListUnlinkedByIP 指定IP上尚未关联云实例的主机
+	ListUnlinkedByIP(ctx context.Context, ip string) ([]*Host, error)
 	CountByCredentialID(ctx context.Context, credentialID uint) (int64, error)
 	GetAll(ctx context.Context) ([]*Host, error)
 	GetStatistics(ctx context.Context, keyword string, groupIDs []uint, status *int, tags []string) (*HostStatistics, error)
@@ -63,6 +66,7 @@ type CloudAccountRepo interface {
 	Update(ctx context.Context, account *CloudAccount) error
 	Delete(ctx context.Context, id uint) error
 	GetByID(ctx context.Context, id uint) (*CloudAccount, error)
+	GetByIDDecrypted(ctx context.Context, id uint) (*CloudAccount, error)
 	List(ctx context.Context, page, pageSize int) ([]*CloudAccount, int64, error)
 	GetAll(ctx context.Context) ([]*CloudAccount, error)
 }
@@ -0,0 +1,573 @@
+// Copyright (c) 2026 DYCloud J.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package asset
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	rbacbiz "github.com/ydcloud-dy/opshub/internal/biz/rbac"
+	"golang.org/x/crypto/ssh"
+	"gorm.io/gorm"
+)
+
+// CredentialTypeCA 由 OpsHub SSH CA 在每次建立会话时签发临时证书的凭证，不保存密码和私钥
+const CredentialTypeCA = "ca"
+
+// 证书主体：主机上 AuthorizedPrincipalsFile 按这些名称授权登录账号
+const (
+	sshPrincipalPrefix    = "opshub-"
+	SSHPrincipalSystem    = sshPrincipalPrefix + "system" // 无用户的自动化任务（定时巡检、定时任务等）
+	sshPrincipalUserScope = sshPrincipalPrefix + "user-"
+	sshPrincipalRoleScope = sshPrincipalPrefix + "role-"
+)
+
+// 证书有效期（分钟）
+const (
+	defaultSSHCertTTL = 5
+	maxSSHCertTTL     = 60
+)
+
+// 安装到主机上的文件
+const (
+	sshCAKeyFile       = "/etc/ssh/opshub_user_ca.pub"
+	sshCAPrincipalsDir = "/etc/ssh/opshub_principals"
+)
+
+// SSHCA OpsHub SSH 证书颁发机构，只有一条记录，轮换时原地替换
+type SSHCA struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	PublicKey   string    `gorm:"type:text;not null;comment:CA公钥" json:"publicKey"`
+	PrivateKey  string    `gorm:"type:text;not null;comment:CA私钥(加密)" json:"-"`
+	Fingerprint string    `gorm:"type:varchar(100);comment:CA公钥指纹" json:"fingerprint"`
+	CertTTL     int       `gorm:"type:int;default:5;comment:签发证书有效期(分钟)" json:"certTtl"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// TableName 表名
+func (SSHCA) TableName() string {
+	return "asset_ssh_ca"
+}
+
+// SSHCAVO CA 信息，未生成时 Initialized 为 false
+type SSHCAVO struct {
+	Initialized bool       `json:"initialized"`
+	PublicKey   string     `json:"publicKey"`
+	Fingerprint string     `json:"fingerprint"`
+	CertTTL     int        `json:"certTtl"`
+	UpdatedAt   *time.Time `json:"updatedAt,omitempty"`
+}
+
+// SSHCAAccount 主机登录账号允许的证书主体
+type SSHCAAccount struct {
+	Account    string   `json:"account" binding:"required"`
+	Principals []string `json:"principals" binding:"required,min=1"`
+}
+
+// SSHCAInstallRequest 安装 CA 公钥请求，未指定账号时允许管理员角色和自动化任务登录 root
+type SSHCAInstallRequest struct {
+	HostIDs  []uint         `json:"hostIds"`
+	Accounts []SSHCAAccount `json:"accounts"`
+}
+
+// SSHCAInstallResult 单台主机的安装结果
+type SSHCAInstallResult struct {
+	HostID   uint   `json:"hostId"`
+	HostName string `json:"hostName"`
+	Success  bool   `json:"success"`
+	Conflict bool   `json:"conflict,omitempty"` // sshd 已配置其他 CA 或主体文件，未安装
+	Output   string `json:"output"`
+	Error    string `json:"error,omitempty"`
+}
+
+// SSHCARepo SSH CA 仓储，私钥经密钥存储保存
+type SSHCARepo interface {
+	Get(ctx context.Context) (*SSHCA, error)
+	Save(ctx context.Context, ca *SSHCA) error
+	GetUserRoleCodes(ctx context.Context, userID uint) ([]string, error)
+}
+
+// SSHIdentity 发起SSH会话的 OpsHub 用户，写入证书用于主机侧的授权和审计
+type SSHIdentity struct {
+	UserID   uint
+	Username string
+	Source   string // terminal/sftp/inspection/task 等
+	// System 定时任务等没有发起用户的会话，显式使用自动化任务主体
+	System bool
+}
+
+type sshIdentityKey struct{}
+
+// WithSSHIdentity 在上下文中记录发起会话的用户
+func WithSSHIdentity(ctx context.Context, identity SSHIdentity) context.Context {
+	return context.WithValue(ctx, sshIdentityKey{}, identity)
+}
+
+// WithSSHSystem 定时任务等没有发起用户的会话，声明使用自动化任务主体签发证书
+func WithSSHSystem(ctx context.Context, source string) context.Context {
+	return WithSSHIdentity(ctx, SSHIdentity{Source: source, System: true})
+}
+
+// WithSSHSource 记录会话来源，保留上下文中已有的用户
+func WithSSHSource(ctx context.Context, source string) context.Context {
+	identity, _ := SSHIdentityFromContext(ctx)
+	identity.Source = source
+	return WithSSHIdentity(ctx, identity)
+}
+
+// SSHIdentityFromContext 取出发起会话的用户
+func SSHIdentityFromContext(ctx context.Context) (SSHIdentity, bool) {
+	identity, ok := ctx.Value(sshIdentityKey{}).(SSHIdentity)
+	return identity, ok
+}
+
+// SSHCertificate 临时私钥及签发的用户证书
+type SSHCertificate struct {
+	PrivateKey  string // PEM
+	Certificate string // authorized_keys 格式
+	KeyID       string
+	Principals  []string
+	ValidBefore time.Time
+}
+
+// HostPermissionChecker 用户对主机的操作权限
+type HostPermissionChecker interface {
+	CheckHostOperationPermission(ctx context.Context, userID, hostID uint, operation uint) (bool, error)
+}
+
+// SSHCAUseCase OpsHub SSH CA：为会话签发短期用户证书，并把CA公钥安装到主机
+type SSHCAUseCase struct {
+	repo        SSHCARepo
+	hosts       *HostUseCase
+	permissions HostPermissionChecker
+}
+
+// NewSSHCAUseCase 创建 SSH CA 用例
+func NewSSHCAUseCase(repo SSHCARepo, hosts *HostUseCase) *SSHCAUseCase {
+	return &SSHCAUseCase{repo: repo, hosts: hosts}
+}
+
+// SetHostPermissionChecker 设置安装CA公钥时的主机权限校验
+func (uc *SSHCAUseCase) SetHostPermissionChecker(checker HostPermissionChecker) {
+	uc.permissions = checker
+}
+
+// Get 获取CA信息
+func (uc *SSHCAUseCase) Get(ctx context.Context) (*SSHCAVO, error) {
+	ca, err := uc.repo.Get(ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &SSHCAVO{CertTTL: defaultSSHCertTTL}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &SSHCAVO{
+		Initialized: true,
+		PublicKey:   ca.PublicKey,
+		Fingerprint: ca.Fingerprint,
+		CertTTL:     ca.CertTTL,
+		UpdatedAt:   &ca.UpdatedAt,
+	}, nil
+}
+
+// Generate 生成CA密钥，已存在时轮换，轮换后需要重新安装公钥
+func (uc *SSHCAUseCase) Generate(ctx context.Context) (*SSHCAVO, error) {
+	ca, err := uc.repo.Get(ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ca, err = &SSHCA{CertTTL: defaultSSHCertTTL}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "opshub-ssh-ca")
+	if err != nil {
+		return nil, err
+	}
+	ca.PrivateKey = string(pem.EncodeToMemory(block))
+	ca.PublicKey = strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPub))) + " opshub-ssh-ca"
+	ca.Fingerprint = ssh.FingerprintSHA256(sshPub)
+	if err := uc.repo.Save(ctx, ca); err != nil {
+		return nil, fmt.Errorf("保存SSH CA失败: %w", err)
+	}
+	return uc.Get(ctx)
+}
+
+// UpdateCertTTL 设置签发证书的有效期（分钟）
+func (uc *SSHCAUseCase) UpdateCertTTL(ctx context.Context, minutes int) error {
+	if minutes < 1 || minutes > maxSSHCertTTL {
+		return fmt.Errorf("证书有效期需在 1-%d 分钟之间", maxSSHCertTTL)
+	}
+	ca, err := uc.repo.Get(ctx)
+	if err != nil {
+		return fmt.Errorf("SSH CA 未生成: %w", err)
+	}
+	ca.CertTTL = minutes
+	return uc.repo.Save(ctx, ca)
+}
+
+// Sign 为上下文中的用户生成临时密钥并签发证书，证书主体为用户名和所属角色
+func (uc *SSHCAUseCase) Sign(ctx context.Context) (*SSHCertificate, error) {
+	ca, err := uc.repo.Get(ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("SSH CA 未生成，无法签发证书")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("获取SSH CA失败: %w", err)
+	}
+	caSigner, err := ssh.ParsePrivateKey([]byte(ca.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("解析SSH CA私钥失败: %w", err)
+	}
+
+	identity, _ := SSHIdentityFromContext(ctx)
+	principals, err := uc.principals(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := ca.CertTTL
+	if ttl <= 0 {
+		ttl = defaultSSHCertTTL
+	}
+	now := time.Now()
+	validBefore := now.Add(time.Duration(ttl) * time.Minute)
+	cert := &ssh.Certificate{
+		Key:             sshPub,
+		Serial:          uint64(now.UnixNano()),
+		CertType:        ssh.UserCert,
+		KeyId:           identity.keyID(),
+		ValidPrincipals: principals,
+		// 允许少量时钟偏差
+		ValidAfter:  uint64(now.Add(-time.Minute).Unix()),
+		ValidBefore: uint64(validBefore.Unix()),
+		Permissions: ssh.Permissions{Extensions: map[string]string{
+			"permit-pty":             "",
+			"permit-port-forwarding": "",
+		}},
+	}
+	if err := cert.SignCert(rand.Reader, caSigner); err != nil {
+		return nil, fmt.Errorf("签发SSH证书失败: %w", err)
+	}
+
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		return nil, err
+	}
+	return &SSHCertificate{
+		PrivateKey:  string(pem.EncodeToMemory(block)),
+		Certificate: string(ssh.MarshalAuthorizedKey(cert)),
+		KeyID:       cert.KeyId,
+		Principals:  principals,
+		ValidBefore: validBefore,
+	}, nil
+}
+
+// principals 用户名和所属角色对应的证书主体；没有用户时只有声明为定时任务的会话使用自动化任务主体
+func (uc *SSHCAUseCase) principals(ctx context.Context, identity SSHIdentity) ([]string, error) {
+	if identity.UserID == 0 {
+		if !identity.System {
+			return nil, errors.New("会话未关联 OpsHub 用户，无法签发SSH证书")
+		}
+		return []string{SSHPrincipalSystem}, nil
+	}
+	// 替换字符后不同用户名可能得到相同主体，不签发
+	if identity.Username == "" || principalName(identity.Username) != identity.Username {
+		return nil, fmt.Errorf("用户名 %q 包含证书主体不支持的字符，无法签发SSH证书", identity.Username)
+	}
+	principals := []string{sshPrincipalUserScope + identity.Username}
+	roles, err := uc.repo.GetUserRoleCodes(ctx, identity.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("获取用户角色失败: %w", err)
+	}
+	sort.Strings(roles)
+	for _, role := range roles {
+		// 同理跳过无法原样写入的角色编码，不授予其他角色的主体
+		if role == "" || principalName(role) != role {
+			continue
+		}
+		principals = append(principals, sshPrincipalRoleScope+role)
+	}
+	return principals, nil
+}
+
+// keyID 证书ID，sshd 登录日志中记录，用于追溯到 OpsHub 用户
+func (i SSHIdentity) keyID() string {
+	user := "system"
+	if i.UserID != 0 {
+		user = principalName(i.Username)
+	}
+	source := i.Source
+	if source == "" {
+		source = "system"
+	}
+	return "opshub:" + user + ":" + source
+}
+
+var principalInvalidChars = regexp.MustCompile(`[^A-Za-z0-9._@-]`)
+
+// principalName 证书主体中只保留可以写入 principals 文件的字符
+func principalName(s string) string {
+	return principalInvalidChars.ReplaceAllString(s, "_")
+}
+
+var sshAccountPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9._-]{0,31}$`)
+
+// InstallScript 生成安装脚本：写入CA公钥和各账号允许的证书主体，配置 sshd 后重新加载
+func (uc *SSHCAUseCase) InstallScript(ctx context.Context, accounts []SSHCAAccount) (string, error) {
+	ca, err := uc.repo.Get(ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", errors.New("SSH CA 未生成")
+	}
+	if err != nil {
+		return "", err
+	}
+	if len(accounts) == 0 {
+		accounts = []SSHCAAccount{{Account: "root", Principals: []string{sshPrincipalRoleScope + "admin", SSHPrincipalSystem}}}
+	}
+
+	var principals strings.Builder
+	for _, account := range accounts {
+		if !sshAccountPattern.MatchString(account.Account) {
+			return "", fmt.Errorf("无效的登录账号: %s", account.Account)
+		}
+		if len(account.Principals) == 0 {
+			return "", fmt.Errorf("账号 %s 未指定证书主体", account.Account)
+		}
+		for _, p := range account.Principals {
+			if p == "" || principalName(p) != p {
+				return "", fmt.Errorf("无效的证书主体: %s", p)
+			}
+		}
+		fmt.Fprintf(&principals, "write_principals %s %s\n", account.Account, strings.Join(account.Principals, " "))
+	}
+	return fmt.Sprintf(sshCAInstallScript, sshCAKeyFile, sshCAPrincipalsDir, ca.PublicKey, principals.String(), sshCAConflictMark, sshCAConflictExit), nil
+}
+
+// Install 在主机上执行安装脚本，Agent在线时经Agent执行，否则经SSH执行
+func (uc *SSHCAUseCase) Install(ctx context.Context, req *SSHCAInstallRequest) ([]*SSHCAInstallResult, error) {
+	if len(req.HostIDs) == 0 {
+		return nil, errors.New("请选择主机")
+	}
+	if err := uc.checkInstallPermission(ctx, req.HostIDs); err != nil {
+		return nil, err
+	}
+	script, err := uc.InstallScript(ctx, req.Accounts)
+	if err != nil {
+		return nil, err
+	}
+	cmd := "sh -c " + shellQuote(script)
+
+	results := make([]*SSHCAInstallResult, len(req.HostIDs))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 10)
+	for i, hostID := range req.HostIDs {
+		wg.Add(1)
+		go func(i int, hostID uint) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			result := &SSHCAInstallResult{HostID: hostID}
+			results[i] = result
+			if host, err := uc.hosts.hostRepo.GetByID(ctx, hostID); err == nil {
+				result.HostName = host.Name
+			}
+			output, err := uc.hosts.RunCommand(ctx, hostID, cmd)
+			result.Output = strings.TrimSpace(output)
+			if err != nil {
+				result.Error = err.Error()
+				result.Conflict = strings.Contains(output+result.Error, sshCAConflictMark)
+				return
+			}
+			result.Success = true
+		}(i, hostID)
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// checkInstallPermission 安装会修改主机的 sshd 配置，需对每台主机都有编辑权限
+func (uc *SSHCAUseCase) checkInstallPermission(ctx context.Context, hostIDs []uint) error {
+	if uc.permissions == nil {
+		return nil
+	}
+	identity, _ := SSHIdentityFromContext(ctx)
+	if identity.UserID == 0 {
+		return errors.New("未登录")
+	}
+	for _, hostID := range hostIDs {
+		ok, err := uc.permissions.CheckHostOperationPermission(ctx, identity.UserID, hostID, rbacbiz.PermissionEdit)
+		if err != nil {
+			return fmt.Errorf("权限检查失败: %w", err)
+		}
+		if !ok {
+			return fmt.Errorf("无权在主机 %d 上安装CA公钥", hostID)
+		}
+	}
+	return nil
+}
+
+// shellQuote 单引号转义
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
+
+var (
+	sshCAMu      sync.RWMutex
+	sshCAUseCase *SSHCAUseCase
+)
+
+// SetSSHCA 设置全局的 SSH CA，CA 类型的凭证使用时由它签发证书
+func SetSSHCA(uc *SSHCAUseCase) {
+	sshCAMu.Lock()
+	defer sshCAMu.Unlock()
+	sshCAUseCase = uc
+}
+
+// IssueCertificate CA 类型的凭证：为本次会话生成临时私钥和证书，其他类型不处理
+func IssueCertificate(ctx context.Context, credential *Credential) error {
+	if credential.Type != CredentialTypeCA {
+		return nil
+	}
+	sshCAMu.RLock()
+	uc := sshCAUseCase
+	sshCAMu.RUnlock()
+	if uc == nil {
+		return errors.New("未启用SSH CA，无法使用CA签发凭证")
+	}
+	cert, err := uc.Sign(ctx)
+	if err != nil {
+		return err
+	}
+	credential.Password, credential.Passphrase = "", ""
+	credential.PrivateKey, credential.Certificate = cert.PrivateKey, cert.Certificate
+	return nil
+}
+
+const (
+	// sshCAConflictMark sshd 已配置其他 TrustedUserCAKeys 或 AuthorizedPrincipalsFile 时安装脚本输出的标记
+	sshCAConflictMark = "sshd 配置冲突"
+	// sshCAConflictExit 配置冲突时安装脚本的退出码
+	sshCAConflictExit = 3
+)
+
+// sshCAInstallScript 参数依次为CA公钥文件、principals目录、CA公钥、各账号的 write_principals 行、冲突标记和退出码
+const sshCAInstallScript = `#!/bin/sh
+# OpsHub SSH CA 安装脚本：信任 OpsHub 签发的短期用户证书
+set -e
+
+CA_FILE=%[1]s
+PRINCIPALS_DIR=%[2]s
+SSHD_CONFIG=${SSHD_CONFIG:-/etc/ssh/sshd_config}
+
+if [ "$(id -u)" != "0" ]; then
+    echo "需要 root 权限" >&2
+    exit 1
+fi
+
+current_option() {
+    grep -Ei "^[[:space:]]*$1[[:space:]]" "$SSHD_CONFIG" | head -n 1 | awk '{print $2}'
+}
+
+# 已配置为其他值时 sshd 不会信任 OpsHub CA：不做任何修改，报告冲突后退出，需手工合并配置
+conflict=0
+check_option() {
+    current=$(current_option "$1")
+    if [ -n "$current" ] && [ "$current" != "$2" ]; then
+        echo "%[5]s: $SSHD_CONFIG 已配置 $1 $current，需改为 $2 或手工合并后重试" >&2
+        conflict=1
+    fi
+}
+check_option TrustedUserCAKeys "$CA_FILE"
+check_option AuthorizedPrincipalsFile "$PRINCIPALS_DIR/%%u"
+if [ "$conflict" = 1 ]; then
+    exit %[6]d
+fi
+
+echo '%[3]s' > "$CA_FILE"
+chmod 644 "$CA_FILE"
+
+mkdir -p "$PRINCIPALS_DIR"
+chmod 755 "$PRINCIPALS_DIR"
+write_principals() {
+    account=$1
+    shift
+    printf '%%s\n' "$@" > "$PRINCIPALS_DIR/$account"
+    chmod 644 "$PRINCIPALS_DIR/$account"
+    echo "账号 $account 允许: $*"
+}
+%[4]s
+cp "$SSHD_CONFIG" "$SSHD_CONFIG.opshub.bak"
+
+# 选项写在文件开头，先于 Include 和 Match 生效；已配置的选项与期望值一致，无需修改
+set_option() {
+    if [ -z "$(current_option "$1")" ]; then
+        { echo "$1 $2"; cat "$SSHD_CONFIG"; } > "$SSHD_CONFIG.opshub.tmp"
+        cat "$SSHD_CONFIG.opshub.tmp" > "$SSHD_CONFIG"
+        rm -f "$SSHD_CONFIG.opshub.tmp"
+    fi
+}
+set_option TrustedUserCAKeys "$CA_FILE"
+set_option AuthorizedPrincipalsFile "$PRINCIPALS_DIR/%%u"
+
+SSHD=$(command -v sshd || echo /usr/sbin/sshd)
+if ! "$SSHD" -t; then
+    cp "$SSHD_CONFIG.opshub.bak" "$SSHD_CONFIG"
+    echo "sshd 配置校验失败，已恢复原配置" >&2
+    exit 1
+fi
+
+if command -v systemctl >/dev/null 2>&1; then
+    systemctl reload sshd 2>/dev/null || systemctl reload ssh
+elif command -v service >/dev/null 2>&1; then
+    service sshd reload 2>/dev/null || service ssh reload
+else
+    kill -HUP "$(cat /var/run/sshd.pid)"
+fi
+echo "OpsHub SSH CA 已安装"
+`
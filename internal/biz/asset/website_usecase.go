@@ -21,32 +21,26 @@ package asset
 
 import (
 	"context"
-	"crypto/aes"
-	"crypto/cipher"
 	"crypto/rand"
-	"encoding/base64"
 	"fmt"
-	"io"
 	"strings"
 	"time"
+
+	"github.com/ydcloud-dy/opshub/pkg/secret"
 )
 
 type WebsiteUseCase struct {
 	websiteRepo     WebsiteRepo
 	assetGroupRepo  AssetGroupRepo
 	hostRepo        HostRepo
-	encryptionKey   []byte
 	accessManager   *WebsiteAccessManager
 }
 
 func NewWebsiteUseCase(websiteRepo WebsiteRepo, assetGroupRepo AssetGroupRepo, hostRepo HostRepo) *WebsiteUseCase {
-	// AES-256要求密钥长度必须是32字节（256位）
-	encryptionKey := []byte("opshub-enc-key-32-bytes-long!!!!")
 	return &WebsiteUseCase{
 		websiteRepo:    websiteRepo,
 		assetGroupRepo: assetGroupRepo,
 		hostRepo:       hostRepo,
-		encryptionKey:  encryptionKey,
 		accessManager:  nil, // 延迟注入
 	}
 }
@@ -56,66 +50,6 @@ func (uc *WebsiteUseCase) SetAccessManager(manager *WebsiteAccessManager) {
 	uc.accessManager = manager
 }
 
-// encrypt 加密
-func (uc *WebsiteUseCase) encrypt(plaintext string) (string, error) {
-	if plaintext == "" {
-		return "", nil
-	}
-
-	block, err := aes.NewCipher(uc.encryptionKey)
-	if err != nil {
-		return "", err
-	}
-
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return "", err
-	}
-
-	nonce := make([]byte, gcm.NonceSize())
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		return "", err
-	}
-
-	ciphertext := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
-	return base64.StdEncoding.EncodeToString(ciphertext), nil
-}
-
-// decrypt 解密
-func (uc *WebsiteUseCase) decrypt(ciphertext string) (string, error) {
-	if ciphertext == "" {
-		return "", nil
-	}
-
-	data, err := base64.StdEncoding.DecodeString(ciphertext)
-	if err != nil {
-		return "", err
-	}
-
-	block, err := aes.NewCipher(uc.encryptionKey)
-	if err != nil {
-		return "", err
-	}
-
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return "", err
-	}
-
-	nonceSize := gcm.NonceSize()
-	if len(data) < nonceSize {
-		return "", fmt.Errorf("ciphertext too short")
-	}
-
-	nonce, cipherData := data[:nonceSize], data[nonceSize:]
-	plaintext, err := gcm.Open(nil, nonce, cipherData, nil)
-	if err != nil {
-		return "", err
-	}
-
-	return string(plaintext), nil
-}
-
 // Create 创建站点
 func (uc *WebsiteUseCase) Create(ctx context.Context, req *WebsiteRequest) error {
 	// 验证内部站点必须绑定Agent
@@ -130,7 +64,7 @@ func (uc *WebsiteUseCase) Create(ctx context.Context, req *WebsiteRequest) error
 
 	// 加密敏感信息
 	if website.AccessPassword != "" {
-		encrypted, err := uc.encrypt(website.AccessPassword)
+		encrypted, err := secret.Encrypt(website.AccessPassword)
 		if err != nil {
 			return fmt.Errorf("加密访问密码失败: %w", err)
 		}
@@ -138,7 +72,7 @@ func (uc *WebsiteUseCase) Create(ctx context.Context, req *WebsiteRequest) error
 	}
 
 	if website.Credential != "" {
-		encrypted, err := uc.encrypt(website.Credential)
+		encrypted, err := secret.Encrypt(website.Credential)
 		if err != nil {
 			return fmt.Errorf("加密凭据失败: %w", err)
 		}
@@ -224,7 +158,7 @@ func (uc *WebsiteUseCase) Update(ctx context.Context, id uint, req *WebsiteReque
 
 	// 加密敏感信息（只有明确提供了新密码才更新）
 	if req.AccessPassword != "" {
-		encrypted, err := uc.encrypt(req.AccessPassword)
+		encrypted, err := secret.Encrypt(req.AccessPassword)
 		if err != nil {
 			return fmt.Errorf("加密访问密码失败: %w", err)
 		}
@@ -233,7 +167,7 @@ func (uc *WebsiteUseCase) Update(ctx context.Context, id uint, req *WebsiteReque
 	// 注意：如果 req.AccessPassword 为空，保留原有密码不变
 
 	if req.Credential != "" {
-		encrypted, err := uc.encrypt(req.Credential)
+		encrypted, err := secret.Encrypt(req.Credential)
 		if err != nil {
 			return fmt.Errorf("加密凭据失败: %w", err)
 		}
@@ -400,7 +334,7 @@ func (uc *WebsiteUseCase) toVOWithPassword(ctx context.Context, website *Website
 
 	// 解密密码
 	if website.AccessPassword != "" {
-		decryptedPassword, err := uc.decrypt(website.AccessPassword)
+		decryptedPassword, err := secret.Decrypt(website.AccessPassword)
 		if err != nil {
 			// 解密失败时记录错误，但不中断流程
 			fmt.Printf("解密密码失败: %v, 原始密文: %s\n", err, website.AccessPassword)
@@ -22,6 +22,7 @@ import (
 type CommandExecutor struct {
 	agentHub       *agent.AgentHub
 	credentialRepo assetbiz.CredentialRepo
+	jumpChains     *assetbiz.JumpChainUseCase
 }
 
 // NewCommandExecutor 创建命令执行器
@@ -32,6 +33,11 @@ func NewCommandExecutor(agentHub *agent.AgentHub, credentialRepo assetbiz.Creden
 	}
 }
 
+// SetJumpChainUseCase 设置跳板链，设置后SSH执行经主机配置的跳板连接
+func (e *CommandExecutor) SetJumpChainUseCase(jumpChains *assetbiz.JumpChainUseCase) {
+	e.jumpChains = jumpChains
+}
+
 // ExecuteResult 执行结果
 type ExecuteResult struct {
 	Output   string
@@ -148,14 +154,14 @@ func (e *CommandExecutor) executeScriptViaSSH(ctx context.Context, host *assetbi
 		return "", fmt.Errorf("主机未配置 SSH 凭证")
 	}
 
-	// 获取解密后的凭证信息
-	credential, err := e.credentialRepo.GetByIDDecrypted(ctx, host.CredentialID)
+	// 获取解密后的凭证信息，CA签发的凭证此时签发临时证书
+	credential, err := e.credentialRepo.GetByIDDecrypted(assetbiz.WithSSHSource(ctx, "inspection"), host.CredentialID)
 	if err != nil {
 		return "", fmt.Errorf("获取凭证信息失败: %w", err)
 	}
 
 	// 创建 SSH 客户端
-	client, err := e.createSSHClient(host, credential)
+	client, err := e.createSSHClient(ctx, host, credential)
 	if err != nil {
 		return "", fmt.Errorf("创建 SSH 客户端失败: %w", err)
 	}
@@ -437,14 +443,14 @@ func (e *CommandExecutor) executeViaSSH(ctx context.Context, host *assetbiz.Host
 		return "", fmt.Errorf("主机未配置 SSH 凭证")
 	}
 
-	// 获取解密后的凭证信息
-	credential, err := e.credentialRepo.GetByIDDecrypted(ctx, host.CredentialID)
+	// 获取解密后的凭证信息，CA签发的凭证此时签发临时证书
+	credential, err := e.credentialRepo.GetByIDDecrypted(assetbiz.WithSSHSource(ctx, "inspection"), host.CredentialID)
 	if err != nil {
 		return "", fmt.Errorf("获取凭证信息失败: %w", err)
 	}
 
 	// 创建 SSH 客户端
-	client, err := e.createSSHClient(host, credential)
+	client, err := e.createSSHClient(ctx, host, credential)
 	if err != nil {
 		return "", fmt.Errorf("创建 SSH 客户端失败: %w", err)
 	}
@@ -460,8 +466,10 @@ func (e *CommandExecutor) executeViaSSH(ctx context.Context, host *assetbiz.Host
 }
 
 // createSSHClient 创建SSH客户端
-func (e *CommandExecutor) createSSHClient(host *assetbiz.Host, credential *assetbiz.Credential) (*sshclient.Client, error) {
-	var privateKey []byte
+func (e *CommandExecutor) createSSHClient(ctx context.Context, host *assetbiz.Host, credential *assetbiz.Credential) (*sshclient.Client, error) {
+	if e.jumpChains != nil {
+		return e.jumpChains.Connect(ctx, host, credential)
+	}
 
 	// 检查凭证信息是否完整
 	if credential.Type == "password" && credential.Password == "" {
@@ -471,19 +479,23 @@ func (e *CommandExecutor) createSSHClient(host *assetbiz.Host, credential *asset
 		return nil, fmt.Errorf("凭证类型为密钥认证，但未填写私钥")
 	}
 
-	// 如果是密钥认证，需要解密私钥
-	if credential.Type == "key" && credential.PrivateKey != "" {
-		privateKey = []byte(credential.PrivateKey)
+	endpoint := sshclient.Endpoint{
+		Host:       host.IP,
+		Port:       host.Port,
+		Username:   host.SSHUser,
+		Password:   credential.Password,
+		Passphrase: credential.Passphrase,
+		HostID:     host.ID,
+	}
+	// 密钥认证及CA签发的凭证使用私钥，签发了证书时一并使用
+	if credential.Type != "password" && credential.PrivateKey != "" {
+		endpoint.PrivateKey = []byte(credential.PrivateKey)
+		if credential.Certificate != "" {
+			endpoint.Certificate = []byte(credential.Certificate)
+		}
 	}
 
-	client, err := sshclient.NewClient(
-		host.IP,
-		host.Port,
-		host.SSHUser,
-		credential.Password,
-		privateKey,
-		credential.Passphrase,
-	)
+	client, err := sshclient.Dial(endpoint)
 	if err != nil {
 		return nil, fmt.Errorf("创建SSH客户端失败: %w", err)
 	}
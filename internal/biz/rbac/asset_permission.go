@@ -35,7 +35,8 @@ const (
 	PermissionTerminal = 1 << 3  // 8 (终端)
 	PermissionFile     = 1 << 4  // 16 (文件管理)
 	PermissionCollect  = 1 << 5  // 32 (采集信息)
-	PermissionAll      = 0x3F    // 63 (所有权限)
+	PermissionTunnel   = 1 << 6  // 64 (端口转发)
+	PermissionAll      = 0x7F    // 127 (所有权限)
 )
 
 // UintArray 用于处理JSON格式的uint数组
@@ -65,7 +66,7 @@ type SysRoleAssetPermission struct {
 	RoleID       uint           `gorm:"not null;index:idx_role_asset" json:"roleId"`        // 角色ID
 	AssetGroupID uint           `gorm:"not null;index:idx_role_asset" json:"assetGroupId"` // 资产分组ID
 	HostIDs      UintArray      `gorm:"type:json" json:"hostIds"`                          // 主机ID列表（为空表示整个分组）
-	Permissions  uint           `gorm:"type:int unsigned;default:1;comment:操作权限位掩码：1=查看,2=编辑,4=删除,8=终端,16=文件,32=采集;index" json:"permissions"`
+	Permissions  uint           `gorm:"type:int unsigned;default:1;comment:操作权限位掩码：1=查看,2=编辑,4=删除,8=终端,16=文件,32=采集,64=端口转发;index" json:"permissions"`
 }
 
 // TableName 指定表名
@@ -151,6 +152,8 @@ func GetPermissionName(perm uint) string {
 		return "文件管理"
 	case PermissionCollect:
 		return "采集信息"
+	case PermissionTunnel:
+		return "端口转发"
 	default:
 		return "未知"
 	}
@@ -177,6 +180,9 @@ func GetAllPermissionNames(permissions uint) []string {
 	if (permissions & PermissionCollect) > 0 {
 		names = append(names, "采集信息")
 	}
+	if (permissions & PermissionTunnel) > 0 {
+		names = append(names, "端口转发")
+	}
 	return names
 }
 
@@ -23,6 +23,7 @@ const (
 	HostInfoPrefix    = "host:info:"        // host:info:{host_id}
 	HostMetricsPrefix = "host:metrics:"     // host:metrics:{host_id}
 	HostListKey       = "host:list"         // 所有主机 ID 列表（Set）
+	HostMetricsIndex  = "host:metrics_index" // 上报过指标的主机（ZSet，score 为采集时间）
 
 	// 批量查询缓存
 	HostBatchPrefix   = "host:batch:"       // host:batch:{group_id}
@@ -30,7 +31,7 @@ const (
 	// TTL 配置
 	AgentStatusTTL    = 3 * time.Minute     // Agent 状态缓存 3 分钟
 	HostInfoTTL       = 10 * time.Minute    // 主机基本信息缓存 10 分钟
-	HostMetricsTTL    = 1 * time.Minute     // 主机监控指标缓存 1 分钟
+	HostMetricsTTL    = 3 * time.Minute     // 主机监控指标缓存 3 分钟（覆盖默认心跳间隔的数倍）
 	BatchQueryTTL     = 30 * time.Second    // 批量查询缓存 30 秒
 )
 
@@ -61,13 +62,54 @@ type HostInfoCache struct {
 
 // HostMetricsCache 主机监控指标缓存
 type HostMetricsCache struct {
-	HostID       uint      `json:"host_id"`
-	CPUUsage     float64   `json:"cpu_usage"`
-	MemoryUsage  float64   `json:"memory_usage"`
-	DiskUsage    float64   `json:"disk_usage"`
-	NetworkIn    uint64    `json:"network_in"`
-	NetworkOut   uint64    `json:"network_out"`
-	CollectedAt  time.Time `json:"collected_at"`
+	HostID        uint             `json:"host_id"`
+	CPUUsage      float64          `json:"cpu_usage"`
+	MemoryUsage   float64          `json:"memory_usage"`
+	DiskUsage     float64          `json:"disk_usage"`
+	NetworkIn     uint64           `json:"network_in"`  // 所有网卡接收速率之和（字节/秒）
+	NetworkOut    uint64           `json:"network_out"` // 所有网卡发送速率之和（字节/秒）
+	MemoryTotal   uint64           `json:"memory_total"`
+	MemoryUsed    uint64           `json:"memory_used"`
+	Load1         float64          `json:"load1"`
+	Load5         float64          `json:"load5"`
+	Load15        float64          `json:"load15"`
+	UptimeSeconds int64            `json:"uptime_seconds"`
+	Disks         []DiskMetrics    `json:"disks,omitempty"`
+	Networks      []NetworkMetrics `json:"networks,omitempty"`
+	TCPStates     map[string]int   `json:"tcp_states,omitempty"`
+	TopProcesses  []ProcessMetrics `json:"top_processes,omitempty"`
+	CollectedAt   time.Time        `json:"collected_at"`
+}
+
+// DiskMetrics 单个挂载点的磁盘使用情况
+type DiskMetrics struct {
+	Mountpoint  string  `json:"mountpoint"`
+	Device      string  `json:"device"`
+	FSType      string  `json:"fstype"`
+	TotalBytes  uint64  `json:"total_bytes"`
+	UsedBytes   uint64  `json:"used_bytes"`
+	Usage       float64 `json:"usage"`
+	InodesUsage float64 `json:"inodes_usage"`
+}
+
+// NetworkMetrics 单个网卡的流量
+type NetworkMetrics struct {
+	Name          string  `json:"name"`
+	RxBytes       uint64  `json:"rx_bytes"`
+	TxBytes       uint64  `json:"tx_bytes"`
+	RxBytesPerSec float64 `json:"rx_bytes_per_sec"`
+	TxBytesPerSec float64 `json:"tx_bytes_per_sec"`
+	RxErrors      uint64  `json:"rx_errors"`
+	TxErrors      uint64  `json:"tx_errors"`
+}
+
+// ProcessMetrics 进程资源占用
+type ProcessMetrics struct {
+	PID         int32   `json:"pid"`
+	Name        string  `json:"name"`
+	CPUUsage    float64 `json:"cpu_usage"`
+	MemoryRSS   uint64  `json:"memory_rss"`
+	MemoryUsage float64 `json:"memory_usage"`
 }
 
 // AgentCache Agent 缓存管理器
@@ -280,7 +322,16 @@ func (c *AgentCache) SetHostMetrics(ctx context.Context, hostID uint, metrics *H
 		return fmt.Errorf("序列化失败: %w", err)
 	}
 
-	return c.rdb.Set(ctx, key, data, HostMetricsTTL).Err()
+	collectedAt := metrics.CollectedAt
+	if collectedAt.IsZero() {
+		collectedAt = time.Now()
+	}
+
+	pipe := c.rdb.Pipeline()
+	pipe.Set(ctx, key, data, HostMetricsTTL)
+	pipe.ZAdd(ctx, HostMetricsIndex, redis.Z{Score: float64(collectedAt.Unix()), Member: hostID})
+	_, err = pipe.Exec(ctx)
+	return err
 }
 
 // GetHostMetrics 获取主机监控指标
@@ -303,6 +354,45 @@ func (c *AgentCache) GetHostMetrics(ctx context.Context, hostID uint) (*HostMetr
 	return &metrics, nil
 }
 
+// ListHostMetrics 获取所有未过期的主机监控指标，同时清理索引中的过期主机
+func (c *AgentCache) ListHostMetrics(ctx context.Context) ([]*HostMetricsCache, error) {
+	expired := time.Now().Add(-HostMetricsTTL).Unix()
+	if err := c.rdb.ZRemRangeByScore(ctx, HostMetricsIndex, "-inf", fmt.Sprintf("(%d", expired)).Err(); err != nil {
+		return nil, err
+	}
+
+	members, err := c.rdb.ZRange(ctx, HostMetricsIndex, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, len(members))
+	for i, m := range members {
+		keys[i] = HostMetricsPrefix + m
+	}
+	values, err := c.rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*HostMetricsCache, 0, len(values))
+	for _, v := range values {
+		str, ok := v.(string)
+		if !ok {
+			continue
+		}
+		var metrics HostMetricsCache
+		if err := json.Unmarshal([]byte(str), &metrics); err != nil {
+			continue
+		}
+		result = append(result, &metrics)
+	}
+	return result, nil
+}
+
 // InvalidateHostCache 使主机缓存失效
 func (c *AgentCache) InvalidateHostCache(ctx context.Context, hostID uint) error {
 	infoKey := fmt.Sprintf("%s%d", HostInfoPrefix, hostID)
@@ -312,6 +402,7 @@ func (c *AgentCache) InvalidateHostCache(ctx context.Context, hostID uint) error
 	pipe.Del(ctx, infoKey)
 	pipe.Del(ctx, metricsKey)
 	pipe.SRem(ctx, HostListKey, hostID)
+	pipe.ZRem(ctx, HostMetricsIndex, hostID)
 
 	_, err := pipe.Exec(ctx)
 	return err
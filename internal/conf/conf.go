@@ -34,6 +34,7 @@ type Config struct {
 	Log      LogConfig      `mapstructure:"log"`
 	Agent    AgentConfig    `mapstructure:"agent"`
 	Cache    CacheConfig    `mapstructure:"cache"`
+	Secret   SecretConfig   `mapstructure:"secret"`
 }
 
 // AgentConfig Agent配置
@@ -44,6 +45,12 @@ type AgentConfig struct {
 	HeartbeatTimeout int      `mapstructure:"heartbeat_timeout"`
 	DeployPath       string   `mapstructure:"deploy_path"`
 	ServerAddresses  []string `mapstructure:"server_addresses"` // 额外的服务端地址（用于证书SAN），支持IP和域名
+	TunnelBindAddr   string   `mapstructure:"tunnel_bind_addr"` // 端口转发在服务端监听的地址，默认 0.0.0.0
+	TunnelMaxTTL     int      `mapstructure:"tunnel_max_ttl"`   // 端口转发会话最长有效期（分钟），默认 240
+	RelayAddr        string   `mapstructure:"relay_addr"`       // 本副本供其他副本转发Agent消息的gRPC地址（host:port），多副本部署时配置
+	CertValidDays    int      `mapstructure:"cert_valid_days"`  // Agent证书有效期（天），默认 365
+	CertRenewDays    int      `mapstructure:"cert_renew_days"`  // 证书剩余有效期不足该天数时经连接自动续期，默认 30
+	UpgradePublicKey string   `mapstructure:"upgrade_public_key"` // 发布签名 ed25519 公钥（base64），远程升级包须附带用对应私钥离线签名的 .sig 文件
 }
 
 // ServerConfig 服务器配置
@@ -54,6 +61,20 @@ type ServerConfig struct {
 	ReadTimeout  int  `mapstructure:"read_timeout"`  // 毫秒
 	WriteTimeout int  `mapstructure:"write_timeout"` // 毫秒
 	JWTSecret  string `mapstructure:"jwt_secret"`    // JWT密钥
+	ExternalURL string `mapstructure:"external_url"` // 平台外部访问地址，用于生成通知中的一键处理链接
+	MetricsToken string `mapstructure:"metrics_token"` // /metrics 访问令牌（Bearer），为空时禁用 /metrics
+	TrustedProxies []string `mapstructure:"trusted_proxies"` // 可信反向代理地址或网段，只采信其传递的 X-Forwarded-For，默认仅本机
+}
+
+// defaultTrustedProxies 未配置可信代理时只信任本机反向代理
+var defaultTrustedProxies = []string{"127.0.0.1", "::1"}
+
+// TrustedProxyList 返回可信反向代理列表，未配置时为本机地址
+func (c ServerConfig) TrustedProxyList() []string {
+	if len(c.TrustedProxies) == 0 {
+		return defaultTrustedProxies
+	}
+	return c.TrustedProxies
 }
 
 // DatabaseConfig 数据库配置
@@ -104,6 +125,27 @@ type CacheConfig struct {
 	RuleEvalRedisTTL     int `mapstructure:"rule_eval_redis_ttl"`     // 规则评估时间 Redis TTL（秒）
 }
 
+// SecretConfig 凭证等敏感信息存储配置
+type SecretConfig struct {
+	Backend  string      `mapstructure:"backend"`   // 新保存内容写入的后端：db（默认）、vault
+	CacheTTL int         `mapstructure:"cache_ttl"` // Vault 读取结果内存缓存时长（秒），默认 60
+	Vault    VaultConfig `mapstructure:"vault"`
+}
+
+// VaultConfig HashiCorp Vault 配置，地址为空时不启用
+type VaultConfig struct {
+	Address    string `mapstructure:"address"`
+	Token      string `mapstructure:"token"`       // 为空时读取环境变量 VAULT_TOKEN
+	Namespace  string `mapstructure:"namespace"`   // 企业版命名空间
+	KVMount    string `mapstructure:"kv_mount"`    // KV v2 挂载点，默认 secret
+	PathPrefix string `mapstructure:"path_prefix"` // 新保存内容的路径前缀，默认 opshub
+	Timeout    int    `mapstructure:"timeout"`     // 请求超时（秒），默认 10
+	// AllowedPaths 凭证中允许引用的 KV 路径（<挂载点>[/<路径前缀>]），本系统写入的路径不能引用
+	AllowedPaths []string `mapstructure:"allowed_paths"`
+	// AllowedSSHRoles 允许引用的 SSH 签名角色（<挂载点>[/<角色>]），为空时不允许 vault-ssh 引用
+	AllowedSSHRoles []string `mapstructure:"allowed_ssh_roles"`
+}
+
 var globalConfig *Config
 
 // Load 加载配置
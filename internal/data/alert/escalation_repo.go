@@ -0,0 +1,83 @@
+package alert
+
+import (
+	"context"
+	"time"
+
+	biz "github.com/ydcloud-dy/opshub/internal/biz/alert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type EscalationPolicyRepo struct {
+	db *gorm.DB
+}
+
+func NewEscalationPolicyRepo(db *gorm.DB) *EscalationPolicyRepo {
+	return &EscalationPolicyRepo{db: db}
+}
+
+func (r *EscalationPolicyRepo) Create(ctx context.Context, p *biz.AlertEscalationPolicy) error {
+	return r.db.WithContext(ctx).Create(p).Error
+}
+
+func (r *EscalationPolicyRepo) Update(ctx context.Context, p *biz.AlertEscalationPolicy) error {
+	return r.db.WithContext(ctx).Model(p).Omit("created_at").Select("*").Updates(p).Error
+}
+
+func (r *EscalationPolicyRepo) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&biz.AlertEscalationPolicy{}, id).Error
+}
+
+func (r *EscalationPolicyRepo) GetByID(ctx context.Context, id uint) (*biz.AlertEscalationPolicy, error) {
+	var p biz.AlertEscalationPolicy
+	if err := r.db.WithContext(ctx).First(&p, id).Error; err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *EscalationPolicyRepo) List(ctx context.Context) ([]*biz.AlertEscalationPolicy, error) {
+	var list []*biz.AlertEscalationPolicy
+	err := r.db.WithContext(ctx).Order("id DESC").Find(&list).Error
+	return list, err
+}
+
+// --- EventEscalation ---
+
+type EventEscalationRepo struct {
+	db *gorm.DB
+}
+
+func NewEventEscalationRepo(db *gorm.DB) *EventEscalationRepo {
+	return &EventEscalationRepo{db: db}
+}
+
+// Create 创建升级进度，同一事件和订阅已存在时忽略
+func (r *EventEscalationRepo) Create(ctx context.Context, esc *biz.AlertEventEscalation) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(esc).Error
+}
+
+func (r *EventEscalationRepo) ListByEventID(ctx context.Context, eventID uint) ([]*biz.AlertEventEscalation, error) {
+	var list []*biz.AlertEventEscalation
+	err := r.db.WithContext(ctx).Where("event_id = ?", eventID).Order("id ASC").Find(&list).Error
+	return list, err
+}
+
+// ListDue 查询到期待升级的进度
+func (r *EventEscalationRepo) ListDue(ctx context.Context, before time.Time, limit int) ([]*biz.AlertEventEscalation, error) {
+	var list []*biz.AlertEventEscalation
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND next_at <= ?", biz.EscalationActive, before).
+		Order("next_at ASC").Limit(limit).Find(&list).Error
+	return list, err
+}
+
+// Advance 以乐观锁方式推进升级进度，仅当进度仍处于 fromStep 且进行中时更新成功
+// 返回 false 表示已被其他实例处理
+func (r *EventEscalationRepo) Advance(ctx context.Context, id uint, fromStep int, updates map[string]interface{}) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&biz.AlertEventEscalation{}).
+		Where("id = ? AND step = ? AND status = ?", id, fromStep, biz.EscalationActive).
+		Updates(updates)
+	return res.RowsAffected == 1, res.Error
+}
@@ -0,0 +1,26 @@
+package alert
+
+import (
+	"context"
+
+	biz "github.com/ydcloud-dy/opshub/internal/biz/alert"
+	"gorm.io/gorm"
+)
+
+type EventTimelineRepo struct {
+	db *gorm.DB
+}
+
+func NewEventTimelineRepo(db *gorm.DB) *EventTimelineRepo {
+	return &EventTimelineRepo{db: db}
+}
+
+func (r *EventTimelineRepo) Create(ctx context.Context, t *biz.AlertEventTimeline) error {
+	return r.db.WithContext(ctx).Create(t).Error
+}
+
+func (r *EventTimelineRepo) ListByEventID(ctx context.Context, eventID uint) ([]*biz.AlertEventTimeline, error) {
+	var list []*biz.AlertEventTimeline
+	err := r.db.WithContext(ctx).Where("event_id = ?", eventID).Order("id ASC").Find(&list).Error
+	return list, err
+}
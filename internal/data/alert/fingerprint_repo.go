@@ -6,6 +6,7 @@ import (
 
 	biz "github.com/ydcloud-dy/opshub/internal/biz/alert"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type FingerprintRepo struct {
@@ -47,9 +48,54 @@ func (r *FingerprintRepo) UpdateSent(ctx context.Context, id uint, sentAt time.T
 		}).Error
 }
 
-// CleanupExpired 清理过期指纹记录（保留30天）
+// CleanupExpired 清理过期指纹记录（保留30天，不含评估状态记录）
 func (r *FingerprintRepo) CleanupExpired(ctx context.Context, before time.Time) error {
 	return r.db.WithContext(ctx).
-		Where("last_seen_at < ?", before).
+		Where("subscription_id > 0 AND last_seen_at < ?", before).
+		Delete(&biz.AlertFingerprint{}).Error
+}
+
+// GetEvalState 获取评估引擎持久化的指纹状态
+func (r *FingerprintRepo) GetEvalState(ctx context.Context, ruleID uint, fingerprint string) (*biz.AlertFingerprint, error) {
+	var fp biz.AlertFingerprint
+	err := r.db.WithContext(ctx).
+		Where("subscription_id = 0 AND alert_rule_id = ? AND fingerprint = ?", ruleID, fingerprint).
+		First(&fp).Error
+	return &fp, err
+}
+
+// ListEvalStatesByRule 列出规则下所有持久化的评估状态
+func (r *FingerprintRepo) ListEvalStatesByRule(ctx context.Context, ruleID uint) ([]*biz.AlertFingerprint, error) {
+	var list []*biz.AlertFingerprint
+	err := r.db.WithContext(ctx).
+		Where("subscription_id = 0 AND alert_rule_id = ?", ruleID).
+		Find(&list).Error
+	return list, err
+}
+
+// SaveEvalState 保存评估状态（按 ruleID + fingerprint 覆盖）
+// 多实例并发评估同一序列时依赖唯一索引 uk_fp_sub_rule 做 upsert，避免先查后插产生重复记录
+func (r *FingerprintRepo) SaveEvalState(ctx context.Context, fp *biz.AlertFingerprint) error {
+	fp.ID = 0
+	fp.SubscriptionID = 0
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "subscription_id"}, {Name: "alert_rule_id"}, {Name: "fingerprint"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"eval_state", "pending_since", "fired_at", "keep_firing_since", "last_seen_at",
+		}),
+	}).Create(fp).Error
+}
+
+// DeleteEvalState 删除单个指纹的评估状态
+func (r *FingerprintRepo) DeleteEvalState(ctx context.Context, ruleID uint, fingerprint string) error {
+	return r.db.WithContext(ctx).
+		Where("subscription_id = 0 AND alert_rule_id = ? AND fingerprint = ?", ruleID, fingerprint).
+		Delete(&biz.AlertFingerprint{}).Error
+}
+
+// DeleteEvalStatesByRule 删除规则下所有评估状态
+func (r *FingerprintRepo) DeleteEvalStatesByRule(ctx context.Context, ruleID uint) error {
+	return r.db.WithContext(ctx).
+		Where("subscription_id = 0 AND alert_rule_id = ?", ruleID).
 		Delete(&biz.AlertFingerprint{}).Error
 }
@@ -0,0 +1,68 @@
+package alert
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	biz "github.com/ydcloud-dy/opshub/internal/biz/alert"
+	"github.com/ydcloud-dy/opshub/internal/testutil"
+)
+
+func TestFingerprintRepo_SaveEvalStateUpsert(t *testing.T) {
+	db := testutil.NewDB(t, &biz.AlertFingerprint{})
+	repo := NewFingerprintRepo(db)
+	ctx := context.Background()
+
+	pendingSince := time.Now().Add(-time.Minute).Truncate(time.Second)
+	newState := func(state string) *biz.AlertFingerprint {
+		return &biz.AlertFingerprint{
+			AlertRuleID:  7,
+			Fingerprint:  "abc",
+			RuleName:     "cpu",
+			Severity:     "warning",
+			FirstSeenAt:  pendingSince,
+			LastSeenAt:   time.Now(),
+			EvalState:    state,
+			PendingSince: &pendingSince,
+		}
+	}
+
+	// 同一订阅下的去重记录与评估状态互不影响
+	if err := repo.Create(ctx, &biz.AlertFingerprint{SubscriptionID: 1, Fingerprint: "abc", RuleName: "cpu", Severity: "warning", FirstSeenAt: pendingSince, LastSeenAt: pendingSince}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.SaveEvalState(ctx, newState(biz.EvalStatePending)); err != nil {
+		t.Fatal(err)
+	}
+	firing := newState(biz.EvalStateFiring)
+	firedAt := time.Now().Truncate(time.Second)
+	firing.FiredAt = &firedAt
+	if err := repo.SaveEvalState(ctx, firing); err != nil {
+		t.Fatal(err)
+	}
+	// 重复保存同一结构体（已带 ID）不应报错
+	if err := repo.SaveEvalState(ctx, firing); err != nil {
+		t.Fatal(err)
+	}
+
+	var count int64
+	db.Model(&biz.AlertFingerprint{}).Where("subscription_id = 0 AND alert_rule_id = ? AND fingerprint = ?", 7, "abc").Count(&count)
+	if count != 1 {
+		t.Fatalf("eval state rows = %d, want 1", count)
+	}
+	got, err := repo.GetEvalState(ctx, 7, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.EvalState != biz.EvalStateFiring || got.FiredAt == nil || !got.FiredAt.Equal(firedAt) {
+		t.Fatalf("eval state not updated: %+v", got)
+	}
+
+	// 直接插入重复评估状态应被唯一索引拒绝
+	dup := newState(biz.EvalStatePending)
+	if err := db.Create(dup).Error; err == nil {
+		t.Fatal("duplicate eval state should violate unique index")
+	}
+}
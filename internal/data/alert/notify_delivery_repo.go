@@ -0,0 +1,109 @@
+package alert
+
+import (
+	"context"
+	"time"
+
+	biz "github.com/ydcloud-dy/opshub/internal/biz/alert"
+	"gorm.io/gorm"
+)
+
+type NotifyDeliveryRepo struct {
+	db *gorm.DB
+}
+
+func NewNotifyDeliveryRepo(db *gorm.DB) *NotifyDeliveryRepo {
+	return &NotifyDeliveryRepo{db: db}
+}
+
+func (r *NotifyDeliveryRepo) Create(ctx context.Context, d *biz.AlertNotifyDelivery) error {
+	return r.db.WithContext(ctx).Create(d).Error
+}
+
+func (r *NotifyDeliveryRepo) Update(ctx context.Context, d *biz.AlertNotifyDelivery) error {
+	return r.db.WithContext(ctx).Save(d).Error
+}
+
+func (r *NotifyDeliveryRepo) GetByID(ctx context.Context, id uint) (*biz.AlertNotifyDelivery, error) {
+	var d biz.AlertNotifyDelivery
+	if err := r.db.WithContext(ctx).First(&d, id).Error; err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *NotifyDeliveryRepo) ListByEventID(ctx context.Context, eventID uint) ([]*biz.AlertNotifyDelivery, error) {
+	var list []*biz.AlertNotifyDelivery
+	err := r.db.WithContext(ctx).Where("event_id = ?", eventID).Order("id ASC").Find(&list).Error
+	return list, err
+}
+
+func (r *NotifyDeliveryRepo) ListBySubscriptionLogIDs(ctx context.Context, logIDs []uint) ([]*biz.AlertNotifyDelivery, error) {
+	var list []*biz.AlertNotifyDelivery
+	if len(logIDs) == 0 {
+		return list, nil
+	}
+	err := r.db.WithContext(ctx).Where("subscription_log_id IN ?", logIDs).Order("id ASC").Find(&list).Error
+	return list, err
+}
+
+// ListDue 查询应已发送但仍未完成、且未被其他实例占用的投递（用于补偿 Redis 队列丢失的任务）
+func (r *NotifyDeliveryRepo) ListDue(ctx context.Context, before time.Time, limit int) ([]*biz.AlertNotifyDelivery, error) {
+	var list []*biz.AlertNotifyDelivery
+	err := r.db.WithContext(ctx).
+		Where("status IN ? AND next_retry_at <= ?", []string{biz.NotifyDeliveryPending, biz.NotifyDeliveryFailed}, before).
+		Where("claimed_until IS NULL OR claimed_until < ?", time.Now()).
+		Order("next_retry_at ASC").Limit(limit).Find(&list).Error
+	return list, err
+}
+
+// Claim 抢占投递的发送租约，仅当投递未完成且无有效租约时成功
+func (r *NotifyDeliveryRepo) Claim(ctx context.Context, id uint, now, until time.Time) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&biz.AlertNotifyDelivery{}).
+		Where("id = ? AND status IN ?", id, []string{biz.NotifyDeliveryPending, biz.NotifyDeliveryFailed}).
+		Where("claimed_until IS NULL OR claimed_until < ?", now).
+		Update("claimed_until", until)
+	return res.RowsAffected == 1, res.Error
+}
+
+// RenewClaim 续期发送租约；租约已释放（发送完成）时不做任何修改
+func (r *NotifyDeliveryRepo) RenewClaim(ctx context.Context, id uint, until time.Time) error {
+	return r.db.WithContext(ctx).Model(&biz.AlertNotifyDelivery{}).
+		Where("id = ? AND claimed_until IS NOT NULL", id).
+		Update("claimed_until", until).Error
+}
+
+func (r *NotifyDeliveryRepo) CreateDeadLetter(ctx context.Context, dl *biz.AlertNotifyDeadLetter) error {
+	return r.db.WithContext(ctx).Create(dl).Error
+}
+
+func (r *NotifyDeliveryRepo) GetDeadLetter(ctx context.Context, id uint) (*biz.AlertNotifyDeadLetter, error) {
+	var dl biz.AlertNotifyDeadLetter
+	if err := r.db.WithContext(ctx).First(&dl, id).Error; err != nil {
+		return nil, err
+	}
+	return &dl, nil
+}
+
+func (r *NotifyDeliveryRepo) UpdateDeadLetter(ctx context.Context, dl *biz.AlertNotifyDeadLetter) error {
+	return r.db.WithContext(ctx).Save(dl).Error
+}
+
+// ListDeadLetters 分页查询死信，resent 为 nil 时不过滤
+func (r *NotifyDeliveryRepo) ListDeadLetters(ctx context.Context, page, pageSize int, eventID uint, resent *bool) ([]*biz.AlertNotifyDeadLetter, int64, error) {
+	var list []*biz.AlertNotifyDeadLetter
+	var total int64
+
+	q := r.db.WithContext(ctx).Model(&biz.AlertNotifyDeadLetter{})
+	if eventID > 0 {
+		q = q.Where("event_id = ?", eventID)
+	}
+	if resent != nil {
+		q = q.Where("resent = ?", *resent)
+	}
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := q.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&list).Error
+	return list, total, err
+}
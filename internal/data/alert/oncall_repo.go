@@ -0,0 +1,72 @@
+package alert
+
+import (
+	"context"
+	"time"
+
+	biz "github.com/ydcloud-dy/opshub/internal/biz/alert"
+	"gorm.io/gorm"
+)
+
+type OnCallScheduleRepo struct {
+	db *gorm.DB
+}
+
+func NewOnCallScheduleRepo(db *gorm.DB) *OnCallScheduleRepo {
+	return &OnCallScheduleRepo{db: db}
+}
+
+func (r *OnCallScheduleRepo) Create(ctx context.Context, s *biz.AlertOnCallSchedule) error {
+	return r.db.WithContext(ctx).Create(s).Error
+}
+
+func (r *OnCallScheduleRepo) Update(ctx context.Context, s *biz.AlertOnCallSchedule) error {
+	return r.db.WithContext(ctx).Model(s).Omit("created_at").Select("*").Updates(s).Error
+}
+
+func (r *OnCallScheduleRepo) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("schedule_id = ?", id).Delete(&biz.AlertOnCallOverride{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&biz.AlertOnCallSchedule{}, id).Error
+	})
+}
+
+func (r *OnCallScheduleRepo) GetByID(ctx context.Context, id uint) (*biz.AlertOnCallSchedule, error) {
+	var s biz.AlertOnCallSchedule
+	if err := r.db.WithContext(ctx).First(&s, id).Error; err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *OnCallScheduleRepo) List(ctx context.Context) ([]*biz.AlertOnCallSchedule, error) {
+	var list []*biz.AlertOnCallSchedule
+	err := r.db.WithContext(ctx).Order("id DESC").Find(&list).Error
+	return list, err
+}
+
+// --- Override ---
+
+func (r *OnCallScheduleRepo) CreateOverride(ctx context.Context, o *biz.AlertOnCallOverride) error {
+	return r.db.WithContext(ctx).Create(o).Error
+}
+
+func (r *OnCallScheduleRepo) DeleteOverride(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&biz.AlertOnCallOverride{}, id).Error
+}
+
+// ListOverrides 查询与 [from, to) 有交集的替换记录，from/to 为零值时不限
+func (r *OnCallScheduleRepo) ListOverrides(ctx context.Context, scheduleID uint, from, to time.Time) ([]*biz.AlertOnCallOverride, error) {
+	var list []*biz.AlertOnCallOverride
+	q := r.db.WithContext(ctx).Where("schedule_id = ?", scheduleID)
+	if !from.IsZero() {
+		q = q.Where("end_at > ?", from)
+	}
+	if !to.IsZero() {
+		q = q.Where("start_at < ?", to)
+	}
+	err := q.Order("start_at ASC, id ASC").Find(&list).Error
+	return list, err
+}
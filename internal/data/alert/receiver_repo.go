@@ -0,0 +1,54 @@
+package alert
+
+import (
+	"context"
+	"time"
+
+	biz "github.com/ydcloud-dy/opshub/internal/biz/alert"
+	"gorm.io/gorm"
+)
+
+type ReceiverRepo struct {
+	db *gorm.DB
+}
+
+func NewReceiverRepo(db *gorm.DB) *ReceiverRepo {
+	return &ReceiverRepo{db: db}
+}
+
+func (r *ReceiverRepo) Create(ctx context.Context, rc *biz.AlertReceiver) error {
+	return r.db.WithContext(ctx).Create(rc).Error
+}
+
+func (r *ReceiverRepo) Update(ctx context.Context, rc *biz.AlertReceiver) error {
+	return r.db.WithContext(ctx).Save(rc).Error
+}
+
+func (r *ReceiverRepo) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&biz.AlertReceiver{}, id).Error
+}
+
+func (r *ReceiverRepo) GetByID(ctx context.Context, id uint) (*biz.AlertReceiver, error) {
+	var rc biz.AlertReceiver
+	err := r.db.WithContext(ctx).First(&rc, id).Error
+	return &rc, err
+}
+
+func (r *ReceiverRepo) GetByToken(ctx context.Context, token string) (*biz.AlertReceiver, error) {
+	var rc biz.AlertReceiver
+	err := r.db.WithContext(ctx).Where("token = ?", token).First(&rc).Error
+	return &rc, err
+}
+
+func (r *ReceiverRepo) List(ctx context.Context) ([]*biz.AlertReceiver, error) {
+	var list []*biz.AlertReceiver
+	err := r.db.WithContext(ctx).Order("id DESC").Find(&list).Error
+	return list, err
+}
+
+// TouchReceived 更新最近接收时间
+func (r *ReceiverRepo) TouchReceived(ctx context.Context, id uint, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&biz.AlertReceiver{}).
+		Where("id = ?", id).
+		Update("last_received_at", at).Error
+}
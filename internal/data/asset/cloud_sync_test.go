@@ -0,0 +1,315 @@
+package asset
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ydcloud-dy/opshub/internal/biz/asset"
+	"github.com/ydcloud-dy/opshub/internal/testutil"
+	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
+	"github.com/ydcloud-dy/opshub/pkg/scheduler"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type fakeEC2Instance struct {
+	ID, Name, Type, State, PublicIP, PrivateIP string
+}
+
+// fakeEC2 EC2 Query 接口的本地替身，区域取自签名中的 credential scope
+type fakeEC2 struct {
+	mu        sync.Mutex
+	instances map[string][]fakeEC2Instance
+	denied    map[string]bool
+	// regions 为空时返回 eu-west-1 和 us-east-1
+	regions []string
+}
+
+var fakeEC2Specs = map[string][2]int{"t3.micro": {2, 1024}, "t3.large": {2, 8192}}
+
+func (f *fakeEC2) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := r.ParseForm(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	region := strings.Split(strings.Split(r.Header.Get("Authorization"), "Credential=")[1], "/")[2]
+	if f.denied[region] {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `<Response><Errors><Error><Code>UnauthorizedOperation</Code><Message>denied</Message></Error></Errors><RequestID>r</RequestID></Response>`)
+		return
+	}
+
+	var b strings.Builder
+	switch action := r.Form.Get("Action"); action {
+	case "DescribeRegions":
+		b.WriteString(`<regionInfo>`)
+		regions := f.regions
+		if len(regions) == 0 {
+			regions = []string{"eu-west-1", "us-east-1"}
+		}
+		for _, name := range regions {
+			fmt.Fprintf(&b, `<item><regionName>%s</regionName></item>`, name)
+		}
+		b.WriteString(`</regionInfo>`)
+	case "DescribeInstances":
+		b.WriteString(`<reservationSet><item><instancesSet>`)
+		for _, inst := range f.instances[region] {
+			fmt.Fprintf(&b, `<item><instanceId>%s</instanceId><instanceType>%s</instanceType><instanceState><name>%s</name></instanceState><privateIpAddress>%s</privateIpAddress>`,
+				inst.ID, inst.Type, inst.State, inst.PrivateIP)
+			if inst.PublicIP != "" {
+				fmt.Fprintf(&b, `<ipAddress>%s</ipAddress>`, inst.PublicIP)
+			}
+			fmt.Fprintf(&b, `<platformDetails>Linux/UNIX</platformDetails><tagSet><item><key>Name</key><value>%s</value></item></tagSet></item>`, inst.Name)
+		}
+		b.WriteString(`</instancesSet></item></reservationSet>`)
+	case "DescribeInstanceTypes":
+		b.WriteString(`<instanceTypeSet>`)
+		for key, values := range r.Form {
+			if !strings.HasPrefix(key, "InstanceType.") {
+				continue
+			}
+			spec := fakeEC2Specs[values[0]]
+			fmt.Fprintf(&b, `<item><instanceType>%s</instanceType><vCpuInfo><defaultVCpus>%d</defaultVCpus></vCpuInfo><memoryInfo><sizeInMiB>%d</sizeInMiB></memoryInfo></item>`,
+				values[0], spec[0], spec[1])
+		}
+		b.WriteString(`</instanceTypeSet>`)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "text/xml")
+	fmt.Fprintf(w, `<%[1]sResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/"><requestId>r</requestId>%[2]s</%[1]sResponse>`, r.Form.Get("Action"), b.String())
+}
+
+func newCloudSyncDB(t *testing.T) *gorm.DB {
+	appLogger.Log = zap.NewNop()
+	db := testutil.NewDB(t, &asset.Host{}, &asset.CloudAccount{})
+	return db
+}
+
+func hostByInstance(t *testing.T, db *gorm.DB, instanceID string) *asset.Host {
+	var host asset.Host
+	if err := db.Where("cloud_instance_id = ?", instanceID).First(&host).Error; err != nil {
+		t.Fatalf("host for %s: %v", instanceID, err)
+	}
+	return &host
+}
+
+func TestCloudSync_AWSReconcile(t *testing.T) {
+	db := newCloudSyncDB(t)
+	ctx := context.Background()
+
+	ec2 := &fakeEC2{instances: map[string][]fakeEC2Instance{
+		"us-east-1": {
+			{ID: "i-web", Name: "web-1", Type: "t3.micro", State: "running", PublicIP: "54.0.0.1", PrivateIP: "10.0.0.1"},
+			{ID: "i-db", Name: "db-1", Type: "t3.micro", State: "stopped", PrivateIP: "10.0.0.2"},
+			{ID: "i-gone", Name: "old-1", Type: "t3.micro", State: "terminated", PrivateIP: "10.0.0.3"},
+		},
+		"eu-west-1": {
+			{ID: "i-eu", Name: "eu-1", Type: "t3.micro", State: "running", PrivateIP: "10.1.0.1"},
+		},
+	}}
+	server := httptest.NewServer(ec2)
+	defer server.Close()
+
+	cloudRepo := NewCloudAccountRepo(db)
+	account := &asset.CloudAccount{Name: "aws-prod", Provider: "aws", AccessKey: "AKIDEXAMPLE", SecretKey: "secret", Status: 1}
+	if err := cloudRepo.Create(ctx, account); err != nil {
+		t.Fatal(err)
+	}
+	hostRepo := NewHostRepo(db)
+	uc := asset.NewCloudAccountUseCase(cloudRepo)
+	uc.SetEndpoints(asset.CloudEndpoints{AWS: server.URL})
+
+	result, err := uc.Sync(ctx, account.ID, hostRepo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Created != 3 || result.Updated != 0 || result.Decommissioned != 0 || len(result.Errors) != 0 {
+		t.Fatalf("first sync = %+v", result)
+	}
+	web := hostByInstance(t, db, "i-web")
+	if web.IP != "54.0.0.1" || web.CloudRegion != "us-east-1" || web.CloudStatus != asset.CloudStatusRunning ||
+		web.CPUCores != 2 || web.MemoryTotal != 1024<<20 || web.CloudInstanceType != "t3.micro" || web.Type != "cloud" {
+		t.Fatalf("web host = %+v", web)
+	}
+	if dbHost := hostByInstance(t, db, "i-db"); dbHost.CloudStatus != asset.CloudStatusStopped || dbHost.IP != "10.0.0.2" {
+		t.Fatalf("db host = %+v", dbHost)
+	}
+	if err := db.Where("cloud_instance_id = ?", "i-gone").First(&asset.Host{}).Error; err == nil {
+		t.Fatal("terminated instance imported")
+	}
+
+	// 规格变更并释放了公网IP，db-1 被释放；eu-west-1 拉取失败，其中的主机不能下线
+	ec2.mu.Lock()
+	ec2.instances["us-east-1"] = []fakeEC2Instance{
+		{ID: "i-web", Name: "web-1", Type: "t3.large", State: "running", PrivateIP: "10.0.0.1"},
+	}
+	ec2.denied = map[string]bool{"eu-west-1": true}
+	ec2.mu.Unlock()
+
+	executor := asset.NewCloudSyncExecutor(uc, hostRepo)
+	if err := executor.Execute(ctx, scheduler.Task{Type: executor.Type()}); err != nil {
+		t.Fatal(err)
+	}
+	web = hostByInstance(t, db, "i-web")
+	if web.IP != "10.0.0.1" || web.CloudInstanceType != "t3.large" || web.MemoryTotal != 8192<<20 {
+		t.Fatalf("web host after resize = %+v", web)
+	}
+	gone := hostByInstance(t, db, "i-db")
+	if gone.CloudStatus != asset.CloudStatusDecommissioned || gone.DecommissionedAt == nil || gone.Status != 0 {
+		t.Fatalf("db host not decommissioned: %+v", gone)
+	}
+	if eu := hostByInstance(t, db, "i-eu"); eu.CloudStatus != asset.CloudStatusRunning || eu.DecommissionedAt != nil {
+		t.Fatalf("host in failed region decommissioned: %+v", eu)
+	}
+
+	// 实例重新出现时恢复
+	ec2.mu.Lock()
+	ec2.instances["us-east-1"] = append(ec2.instances["us-east-1"],
+		fakeEC2Instance{ID: "i-db", Name: "db-1", Type: "t3.micro", State: "running", PrivateIP: "10.0.0.2"})
+	ec2.mu.Unlock()
+	if result, err = uc.Sync(ctx, account.ID, hostRepo); err != nil {
+		t.Fatal(err)
+	}
+	if restored := hostByInstance(t, db, "i-db"); restored.CloudStatus != asset.CloudStatusRunning || restored.DecommissionedAt != nil {
+		t.Fatalf("host not restored: %+v", restored)
+	}
+	if result.Updated != 1 || result.Created != 0 || len(result.Errors) != 1 {
+		t.Fatalf("third sync = %+v", result)
+	}
+
+	// 区域不再出现在区域列表中时，其中的主机下线
+	ec2.mu.Lock()
+	ec2.regions = []string{"us-east-1"}
+	ec2.mu.Unlock()
+	if result, err = uc.Sync(ctx, account.ID, hostRepo); err != nil {
+		t.Fatal(err)
+	}
+	if eu := hostByInstance(t, db, "i-eu"); eu.CloudStatus != asset.CloudStatusDecommissioned || result.Decommissioned != 1 {
+		t.Fatalf("host in dropped region not decommissioned: %+v, %+v", eu, result)
+	}
+}
+
+func TestCloudSync_HuaweiImportAndSync(t *testing.T) {
+	db := newCloudSyncDB(t)
+	ctx := context.Background()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v3/auth/projects", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"projects":[
+			{"id":"p-cn4","name":"cn-north-4","enabled":true},
+			{"id":"p-sub","name":"cn-north-4_test","enabled":true},
+			{"id":"p-mos","name":"MOS","enabled":true}]}`)
+	})
+	mux.HandleFunc("/v3/projects", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("name") != "cn-north-4" {
+			fmt.Fprint(w, `{"projects":[]}`)
+			return
+		}
+		fmt.Fprint(w, `{"projects":[{"id":"p-cn4","name":"cn-north-4"}]}`)
+	})
+	servers := []map[string]any{
+		{
+			"id": "s-app", "name": "app-1", "status": "ACTIVE",
+			"flavor":   map[string]any{"id": "s6.xlarge.2", "vcpus": "4", "ram": "8192"},
+			"metadata": map[string]string{"image_name": "CentOS 7.9 64bit"},
+			"addresses": map[string]any{"vpc-1": []map[string]any{
+				{"addr": "192.168.0.10", "version": "4", "OS-EXT-IPS:type": "fixed"},
+				{"addr": "121.36.0.10", "version": "4", "OS-EXT-IPS:type": "floating"},
+			}},
+		},
+		{
+			"id": "s-cache", "name": "cache-1", "status": "SHUTOFF",
+			"flavor": map[string]any{"id": "s6.large.2", "vcpus": "2", "ram": "4096"},
+			"addresses": map[string]any{"vpc-1": []map[string]any{
+				{"addr": "192.168.0.11", "version": "4", "OS-EXT-IPS:type": "fixed"},
+			}},
+		},
+	}
+	mux.HandleFunc("/v1/p-cn4/cloudservers/detail", func(w http.ResponseWriter, r *http.Request) {
+		page := servers
+		if r.URL.Query().Get("offset") != "1" {
+			page = nil
+		}
+		json.NewEncoder(w).Encode(map[string]any{"count": len(page), "servers": page})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	cloudRepo := NewCloudAccountRepo(db)
+	account := &asset.CloudAccount{Name: "hw-prod", Provider: "huawei", AccessKey: "HWAK" + t.Name(), SecretKey: "secret", Status: 1}
+	if err := cloudRepo.Create(ctx, account); err != nil {
+		t.Fatal(err)
+	}
+	// 只按公网IP登记过的自建主机会关联到云实例，而不是重复创建
+	manual := &asset.Host{Name: "app", Type: "self", SSHUser: "root", IP: "121.36.0.10", Port: 22, CPUCores: 4, MemoryTotal: 7900 << 20}
+	if err := db.Create(manual).Error; err != nil {
+		t.Fatal(err)
+	}
+	// 私网IP可能在其他VPC或账号中重复，相同私网IP的主机不关联
+	private := &asset.Host{Name: "other-vpc", Type: "self", SSHUser: "root", IP: "192.168.0.11", Port: 22}
+	if err := db.Create(private).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	hostRepo := NewHostRepo(db)
+	uc := asset.NewCloudAccountUseCase(cloudRepo)
+	uc.SetEndpoints(asset.CloudEndpoints{Huawei: server.URL, HuaweiIAM: server.URL})
+
+	regions, err := uc.GetRegions(ctx, account.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(regions) != 1 || regions[0].Value != "cn-north-4" {
+		t.Fatalf("regions = %+v", regions)
+	}
+	instances, err := uc.GetInstances(ctx, account.ID, "cn-north-4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(instances) != 2 || instances[0].PublicIP != "121.36.0.10" || instances[0].PrivateIP != "192.168.0.10" ||
+		instances[0].CPU != 4 || instances[0].MemoryMB != 8192 || instances[0].OS != "CentOS 7.9 64bit" {
+		t.Fatalf("instances = %+v", instances[0])
+	}
+
+	result, err := uc.Sync(ctx, account.ID, hostRepo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Created != 1 || result.Updated != 1 || len(result.Errors) != 0 {
+		t.Fatalf("sync = %+v", result)
+	}
+	app := hostByInstance(t, db, "s-app")
+	if app.ID != manual.ID || app.Type != "cloud" || app.CloudAccountID != account.ID || app.IP != "121.36.0.10" ||
+		app.CloudProvider != "huawei" || app.CloudStatus != asset.CloudStatusRunning || app.CPUCores != 4 {
+		t.Fatalf("app host = %+v", app)
+	}
+	// 已采集的内存以实际值为准，首次关联规格时不覆盖
+	if app.MemoryTotal != 7900<<20 {
+		t.Fatalf("collected memory overwritten: %d", app.MemoryTotal)
+	}
+	cache := hostByInstance(t, db, "s-cache")
+	if cache.ID == private.ID || cache.IP != "192.168.0.11" || cache.CloudStatus != asset.CloudStatusStopped || cache.MemoryTotal != 4096<<20 {
+		t.Fatalf("cache host = %+v", cache)
+	}
+	if err := db.First(private, private.ID).Error; err != nil || private.CloudInstanceID != "" || private.Type != "self" {
+		t.Fatalf("host with same private IP linked: %+v, %v", private, err)
+	}
+
+	// 再次同步没有变化
+	if result, err = uc.Sync(ctx, account.ID, hostRepo); err != nil {
+		t.Fatal(err)
+	}
+	if result.Created != 0 || result.Updated != 0 || result.Decommissioned != 0 {
+		t.Fatalf("idempotent sync = %+v", result)
+	}
+}
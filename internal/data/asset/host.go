@@ -21,15 +21,11 @@ package asset
 
 import (
 	"context"
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/rand"
-	"encoding/base64"
 	"fmt"
-	"io"
 	"time"
 
 	"github.com/ydcloud-dy/opshub/internal/biz/asset"
+	"github.com/ydcloud-dy/opshub/pkg/secret"
 	"gorm.io/gorm"
 )
 
@@ -182,6 +178,28 @@ func (r *hostRepo) GetByCloudInstanceID(ctx context.Context, instanceID string)
 	return &host, nil
 }
 
+// GetByCloudAccountID 获取从指定云账号导入的主机
+func (r *hostRepo) GetByCloudAccountID(ctx context.Context, accountID uint) ([]*asset.Host, error) {
+	var hosts []*asset.Host
+	err := r.db.WithContext(ctx).Where("cloud_account_id = ?", accountID).Find(&hosts).Error
+	if err != nil {
+		return nil, err
+	}
+	return hosts, nil
+}
+
+// ListUnlinkedByIP 获取指定IP上尚未关联云实例的主机
+func (r *hostRepo) ListUnlinkedByIP(ctx context.Context, ip string) ([]*asset.Host, error) {
+	var hosts []*asset.Host
+	err := r.db.WithContext(ctx).
+		Where("ip = ? AND (cloud_instance_id = '' OR cloud_instance_id IS NULL)", ip).
+		Find(&hosts).Error
+	if err != nil {
+		return nil, err
+	}
+	return hosts, nil
+}
+
 // CountByCredentialID 统计使用指定凭证的主机数量
 func (r *hostRepo) CountByCredentialID(ctx context.Context, credentialID uint) (int64, error) {
 	var count int64
@@ -201,137 +219,52 @@ func (r *hostRepo) GetAll(ctx context.Context) ([]*asset.Host, error) {
 	return hosts, nil
 }
 
-// credentialRepo 凭证仓库
+// credentialRepo 凭证仓库，密码、私钥和私钥密码保存在 secret.Default() 配置的后端中
 type credentialRepo struct {
-	db           *gorm.DB
-	encryptionKey []byte
+	db *gorm.DB
 }
 
 // NewCredentialRepo 创建凭证仓库
 func NewCredentialRepo(db *gorm.DB) asset.CredentialRepo {
-	// AES-256要求密钥长度必须是32字节（256位）
-	encryptionKey := []byte("opshub-enc-key-32-bytes-long!!!!")
-	return &credentialRepo{
-		db:           db,
-		encryptionKey: encryptionKey,
-	}
+	return &credentialRepo{db: db}
 }
 
-// encrypt 加密
-func (r *credentialRepo) encrypt(plaintext string) (string, error) {
-	if plaintext == "" {
-		return "", nil
-	}
-
-	block, err := aes.NewCipher(r.encryptionKey)
-	if err != nil {
-		return "", err
-	}
-
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return "", err
-	}
-
-	nonce := make([]byte, gcm.NonceSize())
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		return "", err
-	}
-
-	ciphertext := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
-	return base64.StdEncoding.EncodeToString(ciphertext), nil
-}
-
-// decrypt 解密
-func (r *credentialRepo) decrypt(ciphertext string) (string, error) {
-	if ciphertext == "" {
-		return "", nil
-	}
-
-	data, err := base64.StdEncoding.DecodeString(ciphertext)
-	if err != nil {
-		return "", err
-	}
-
-	block, err := aes.NewCipher(r.encryptionKey)
-	if err != nil {
-		return "", err
-	}
-
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return "", err
-	}
-
-	nonceSize := gcm.NonceSize()
-	if len(data) < nonceSize {
-		return "", fmt.Errorf("ciphertext too short")
-	}
-
-	nonce, cipherData := data[:nonceSize], data[nonceSize:]
-	plaintext, err := gcm.Open(nil, nonce, cipherData, nil)
-	if err != nil {
-		return "", err
-	}
-
-	return string(plaintext), nil
-}
+// credentialSecretPath 凭证在外部后端中的路径
+const credentialSecretPath = "credentials"
 
 // Create 创建凭证
 func (r *credentialRepo) Create(ctx context.Context, credential *asset.Credential) error {
-	// 加密敏感信息
-	if credential.Password != "" {
-		encrypted, err := r.encrypt(credential.Password)
-		if err != nil {
-			return fmt.Errorf("加密密码失败: %w", err)
-		}
-		credential.Password = encrypted
+	store := secret.Default()
+	var err error
+	if credential.Password, err = store.Put(ctx, credentialSecretPath, credential.Password); err != nil {
+		return fmt.Errorf("保存密码失败: %w", err)
 	}
-
-	if credential.PrivateKey != "" {
-		encrypted, err := r.encrypt(credential.PrivateKey)
-		if err != nil {
-			return fmt.Errorf("加密私钥失败: %w", err)
-		}
-		credential.PrivateKey = encrypted
+	if credential.PrivateKey, err = store.Put(ctx, credentialSecretPath, credential.PrivateKey); err != nil {
+		return fmt.Errorf("保存私钥失败: %w", err)
 	}
-
-	if credential.Passphrase != "" {
-		encrypted, err := r.encrypt(credential.Passphrase)
-		if err != nil {
-			return fmt.Errorf("加密私钥密码失败: %w", err)
-		}
-		credential.Passphrase = encrypted
+	if credential.Passphrase, err = store.Put(ctx, credentialSecretPath, credential.Passphrase); err != nil {
+		return fmt.Errorf("保存私钥密码失败: %w", err)
 	}
 
 	return r.db.WithContext(ctx).Create(credential).Error
 }
 
-// Update 更新凭证
+// Update 更新凭证，未修改的字段保持原有的引用
 func (r *credentialRepo) Update(ctx context.Context, credential *asset.Credential) error {
-	// 加密敏感信息
-	if credential.Password != "" {
-		encrypted, err := r.encrypt(credential.Password)
-		if err != nil {
-			return fmt.Errorf("加密密码失败: %w", err)
-		}
-		credential.Password = encrypted
+	stored, err := r.GetByID(ctx, credential.ID)
+	if err != nil {
+		return err
 	}
 
-	if credential.PrivateKey != "" {
-		encrypted, err := r.encrypt(credential.PrivateKey)
-		if err != nil {
-			return fmt.Errorf("加密私钥失败: %w", err)
-		}
-		credential.PrivateKey = encrypted
+	store := secret.Default()
+	if credential.Password, err = store.Replace(ctx, credentialSecretPath, stored.Password, credential.Password); err != nil {
+		return fmt.Errorf("保存密码失败: %w", err)
 	}
-
-	if credential.Passphrase != "" {
-		encrypted, err := r.encrypt(credential.Passphrase)
-		if err != nil {
-			return fmt.Errorf("加密私钥密码失败: %w", err)
-		}
-		credential.Passphrase = encrypted
+	if credential.PrivateKey, err = store.Replace(ctx, credentialSecretPath, stored.PrivateKey, credential.PrivateKey); err != nil {
+		return fmt.Errorf("保存私钥失败: %w", err)
+	}
+	if credential.Passphrase, err = store.Replace(ctx, credentialSecretPath, stored.Passphrase, credential.Passphrase); err != nil {
+		return fmt.Errorf("保存私钥密码失败: %w", err)
 	}
 
 	return r.db.WithContext(ctx).Save(credential).Error
@@ -348,10 +281,21 @@ func (r *credentialRepo) Delete(ctx context.Context, id uint) error {
 		return fmt.Errorf("该凭证正在被 %d 个主机使用，无法删除", count)
 	}
 
-	return r.db.WithContext(ctx).Delete(&asset.Credential{}, id).Error
+	stored, err := r.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := r.db.WithContext(ctx).Delete(&asset.Credential{}, id).Error; err != nil {
+		return err
+	}
+	store := secret.Default()
+	for _, ref := range []string{stored.Password, stored.PrivateKey, stored.Passphrase} {
+		store.Delete(ctx, credentialSecretPath, ref)
+	}
+	return nil
 }
 
-// GetByID 根据ID获取凭证
+// GetByID 根据ID获取凭证，敏感字段为保存的引用
 func (r *credentialRepo) GetByID(ctx context.Context, id uint) (*asset.Credential, error) {
 	var credential asset.Credential
 	err := r.db.WithContext(ctx).First(&credential, id).Error
@@ -361,36 +305,39 @@ func (r *credentialRepo) GetByID(ctx context.Context, id uint) (*asset.Credentia
 	return &credential, nil
 }
 
-// GetByIDDecrypted 根据ID获取凭证（解密后的）
+// GetByIDDecrypted 根据ID获取凭证，敏感字段从后端取回；
+// 私钥引用Vault SSH签名角色时生成临时私钥并签发证书
 func (r *credentialRepo) GetByIDDecrypted(ctx context.Context, id uint) (*asset.Credential, error) {
 	credential, err := r.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-
-	// 解密敏感信息
-	if credential.Password != "" {
-		decrypted, err := r.decrypt(credential.Password)
-		if err != nil {
-			return nil, fmt.Errorf("解密密码失败: %w", err)
+	if credential.Type == asset.CredentialTypeCA {
+		if err := asset.IssueCertificate(ctx, credential); err != nil {
+			return nil, err
 		}
-		credential.Password = decrypted
+		return credential, nil
 	}
 
-	if credential.PrivateKey != "" {
-		decrypted, err := r.decrypt(credential.PrivateKey)
-		if err != nil {
-			return nil, fmt.Errorf("解密私钥失败: %w", err)
-		}
-		credential.PrivateKey = decrypted
+	store := secret.Default()
+	if credential.Password, err = store.Get(ctx, credential.Password); err != nil {
+		return nil, fmt.Errorf("解密密码失败: %w", err)
 	}
 
-	if credential.Passphrase != "" {
-		decrypted, err := r.decrypt(credential.Passphrase)
+	if secret.IsSSHReference(credential.PrivateKey) {
+		key, err := store.SSHKey(ctx, credential.PrivateKey, credential.Username)
 		if err != nil {
-			return nil, fmt.Errorf("解密私钥密码失败: %w", err)
+			return nil, err
 		}
-		credential.Passphrase = decrypted
+		credential.PrivateKey, credential.Certificate, credential.Passphrase = key.PrivateKey, key.Certificate, ""
+		return credential, nil
+	}
+
+	if credential.PrivateKey, err = store.Get(ctx, credential.PrivateKey); err != nil {
+		return nil, fmt.Errorf("解密私钥失败: %w", err)
+	}
+	if credential.Passphrase, err = store.Get(ctx, credential.Passphrase); err != nil {
+		return nil, fmt.Errorf("解密私钥密码失败: %w", err)
 	}
 
 	return credential, nil
@@ -440,19 +387,60 @@ func NewCloudAccountRepo(db *gorm.DB) asset.CloudAccountRepo {
 	return &cloudAccountRepo{db: db}
 }
 
-// Create 创建云平台账号
+// cloudAccountSecretPath 云账号SecretKey在外部后端中的路径
+const cloudAccountSecretPath = "cloud-accounts"
+
+// Create 创建云平台账号，SecretKey 保存在 secret.Default() 配置的后端中
 func (r *cloudAccountRepo) Create(ctx context.Context, account *asset.CloudAccount) error {
+	ref, err := secret.Default().Put(ctx, cloudAccountSecretPath, account.SecretKey)
+	if err != nil {
+		return fmt.Errorf("保存SecretKey失败: %w", err)
+	}
+	account.SecretKey = ref
 	return r.db.WithContext(ctx).Create(account).Error
 }
 
-// Update 更新云平台账号
+// Update 更新云平台账号，未修改的 SecretKey 保持原有的引用
 func (r *cloudAccountRepo) Update(ctx context.Context, account *asset.CloudAccount) error {
+	stored, err := r.GetByID(ctx, account.ID)
+	if err != nil {
+		return err
+	}
+	if account.SecretKey, err = secret.Default().Replace(ctx, cloudAccountSecretPath, stored.SecretKey, account.SecretKey); err != nil {
+		return fmt.Errorf("保存SecretKey失败: %w", err)
+	}
 	return r.db.WithContext(ctx).Save(account).Error
 }
 
 // Delete 删除云平台账号
 func (r *cloudAccountRepo) Delete(ctx context.Context, id uint) error {
-	return r.db.WithContext(ctx).Delete(&asset.CloudAccount{}, id).Error
+	stored, err := r.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := r.db.WithContext(ctx).Delete(&asset.CloudAccount{}, id).Error; err != nil {
+		return err
+	}
+	secret.Default().Delete(ctx, cloudAccountSecretPath, stored.SecretKey)
+	return nil
+}
+
+// GetByIDDecrypted 根据ID获取云平台账号，SecretKey 从后端取回
+func (r *cloudAccountRepo) GetByIDDecrypted(ctx context.Context, id uint) (*asset.CloudAccount, error) {
+	account, err := r.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := secret.Default().Get(ctx, account.SecretKey)
+	if err != nil {
+		if secret.IsReference(account.SecretKey) {
+			return nil, fmt.Errorf("读取SecretKey失败: %w", err)
+		}
+		// 启用加密前保存的明文，下次修改时加密
+		plaintext = account.SecretKey
+	}
+	account.SecretKey = plaintext
+	return account, nil
 }
 
 // GetByID 根据ID获取云平台账号
@@ -0,0 +1,109 @@
+// Copyright (c) 2026 DYCloud J.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package asset
+
+import (
+	"context"
+	"time"
+
+	"github.com/ydcloud-dy/opshub/internal/biz/asset"
+	"gorm.io/gorm"
+)
+
+type hostKeyRepo struct {
+	db *gorm.DB
+}
+
+// NewHostKeyRepo 创建主机密钥仓库
+func NewHostKeyRepo(db *gorm.DB) asset.HostKeyRepo {
+	return &hostKeyRepo{db: db}
+}
+
+func (r *hostKeyRepo) GetByScope(ctx context.Context, scope string) (*asset.HostKey, error) {
+	var key asset.HostKey
+	if err := r.db.WithContext(ctx).Where("scope = ?", scope).First(&key).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *hostKeyRepo) Create(ctx context.Context, key *asset.HostKey) error {
+	return r.db.WithContext(ctx).Create(key).Error
+}
+
+func (r *hostKeyRepo) Update(ctx context.Context, key *asset.HostKey) error {
+	return r.db.WithContext(ctx).Save(key).Error
+}
+
+func (r *hostKeyRepo) RecordAlert(ctx context.Context, alert *asset.HostKeyAlert) error {
+	result := r.db.WithContext(ctx).Model(&asset.HostKeyAlert{}).
+		Where("scope = ? AND presented_fingerprint = ? AND status = ?", alert.Scope, alert.PresentedFingerprint, asset.HostKeyAlertOpen).
+		Updates(map[string]any{
+			"count":        gorm.Expr("count + 1"),
+			"last_seen_at": alert.LastSeenAt,
+			"address":      alert.Address,
+		})
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.Error
+	}
+	return r.db.WithContext(ctx).Create(alert).Error
+}
+
+func (r *hostKeyRepo) GetAlert(ctx context.Context, id uint) (*asset.HostKeyAlert, error) {
+	var alert asset.HostKeyAlert
+	if err := r.db.WithContext(ctx).First(&alert, id).Error; err != nil {
+		return nil, err
+	}
+	return &alert, nil
+}
+
+func (r *hostKeyRepo) ListAlerts(ctx context.Context, page, pageSize int, hostID uint, status string) ([]*asset.HostKeyAlert, int64, error) {
+	var alerts []*asset.HostKeyAlert
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&asset.HostKeyAlert{})
+	if hostID > 0 {
+		query = query.Where("host_id = ?", hostID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&alerts).Error; err != nil {
+		return nil, 0, err
+	}
+	return alerts, total, nil
+}
+
+func (r *hostKeyRepo) ResolveAlerts(ctx context.Context, scope, status, operator string) error {
+	now := time.Now()
+	return r.db.WithContext(ctx).Model(&asset.HostKeyAlert{}).
+		Where("scope = ? AND status = ?", scope, asset.HostKeyAlertOpen).
+		Updates(map[string]any{"status": status, "resolved_by": operator, "resolved_at": &now}).Error
+}
+
+func (r *hostKeyRepo) ListUntrustedHostIDs(ctx context.Context) ([]uint, error) {
+	var ids []uint
+	trusted := r.db.Model(&asset.HostKey{}).Select("host_id").Where("host_id > 0")
+	err := r.db.WithContext(ctx).Model(&asset.Host{}).Where("id NOT IN (?)", trusted).Order("id").Pluck("id", &ids).Error
+	return ids, err
+}
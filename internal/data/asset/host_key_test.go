@@ -0,0 +1,194 @@
+package asset
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/ydcloud-dy/opshub/internal/biz/asset"
+	"github.com/ydcloud-dy/opshub/internal/testutil"
+	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
+	sshclient "github.com/ydcloud-dy/opshub/pkg/ssh"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/ssh"
+)
+
+func newHostKey(t *testing.T) ssh.PublicKey {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestHostKey_TrustOnFirstUseAndRetrust(t *testing.T) {
+	appLogger.Log = zap.NewNop()
+	db := testutil.NewDB(t, &asset.Host{}, &asset.HostKey{}, &asset.HostKeyAlert{})
+	host := &asset.Host{Name: "web-1", IP: "10.0.0.7", Port: 22}
+	if err := db.Create(host).Error; err != nil {
+		t.Fatal(err)
+	}
+	uc := asset.NewHostKeyUseCase(NewHostKeyRepo(db))
+	sshclient.SetKnownHosts(uc)
+	defer sshclient.SetKnownHosts(nil)
+	verify := sshclient.HostKeyCallback(host.ID, false)
+	ctx := context.Background()
+
+	// 未记录密钥时普通连接拒绝，测试连接时首次信任，之后同一密钥通过
+	original := newHostKey(t)
+	if err := verify("10.0.0.7:22", nil, original); !errors.Is(err, sshclient.ErrHostKeyUnknown) {
+		t.Fatalf("未信任的密钥应拒绝, 实际 %v", err)
+	}
+	if err := sshclient.HostKeyCallback(host.ID, true)("10.0.0.7:22", nil, original); err != nil {
+		t.Fatal(err)
+	}
+	if err := verify("10.0.0.7:22", nil, original); err != nil {
+		t.Fatal(err)
+	}
+	trusted, err := uc.GetByHostID(ctx, host.ID)
+	if err != nil || trusted.Fingerprint != ssh.FingerprintSHA256(original) || trusted.TrustedBy != asset.HostKeyTrustedFirstUse {
+		t.Fatalf("应按主机ID记录首次连接的密钥: %+v, %v", trusted, err)
+	}
+
+	// 密钥变化时拒绝连接，重复出现只累计次数
+	changed := newHostKey(t)
+	for i := 0; i < 2; i++ {
+		if err := verify("10.0.0.7:22", nil, changed); !errors.Is(err, sshclient.ErrHostKeyMismatch) {
+			t.Fatalf("密钥变化时应拒绝, 实际 %v", err)
+		}
+	}
+	alerts, total, err := uc.ListAlerts(ctx, 1, 20, host.ID, asset.HostKeyAlertOpen)
+	if err != nil || total != 1 || alerts[0].Count != 2 {
+		t.Fatalf("应记录一条告警并累计次数: total=%d err=%v", total, err)
+	}
+
+	// 重新信任需核对指纹
+	if _, err := uc.Retrust(ctx, alerts[0].ID, ssh.FingerprintSHA256(newHostKey(t)), "admin"); err == nil {
+		t.Fatal("指纹不一致时不应重新信任")
+	}
+	if _, err := uc.Retrust(ctx, alerts[0].ID, ssh.FingerprintSHA256(changed), "admin"); err != nil {
+		t.Fatal(err)
+	}
+	if err := verify("10.0.0.7:22", nil, changed); err != nil {
+		t.Fatalf("重新信任后应通过, 实际 %v", err)
+	}
+	if err := verify("10.0.0.7:22", nil, original); !errors.Is(err, sshclient.ErrHostKeyMismatch) {
+		t.Fatal("重新信任后旧密钥应被拒绝")
+	}
+	if _, err := uc.Retrust(ctx, alerts[0].ID, ssh.FingerprintSHA256(changed), "admin"); err == nil {
+		t.Fatal("已处理的告警不能再次信任")
+	}
+
+	// 相同地址的另一台主机按自己的ID校验，不沿用已记录的密钥
+	other := &asset.Host{Name: "web-1-nat", IP: "10.0.0.7", Port: 22}
+	if err := db.Create(other).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := sshclient.HostKeyCallback(other.ID, false)("10.0.0.7:22", nil, changed); !errors.Is(err, sshclient.ErrHostKeyUnknown) {
+		t.Fatalf("另一台主机的密钥应单独信任, 实际 %v", err)
+	}
+
+	// 非资产主机按地址记录
+	if err := sshclient.HostKeyCallback(0, true)("192.168.1.9:2222", nil, original); err != nil {
+		t.Fatal(err)
+	}
+	var key asset.HostKey
+	db.Where("scope = ?", "addr:192.168.1.9:2222").First(&key)
+	if key.HostID != 0 || key.Fingerprint != ssh.FingerprintSHA256(original) {
+		t.Fatalf("非资产主机应按地址记录: %+v", key)
+	}
+}
+
+// startKeyServer 启动拒绝所有认证的 SSH 服务，返回地址和主机公钥
+func startKeyServer(t *testing.T) (*net.TCPAddr, ssh.PublicKey) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(ssh.ConnMetadata, []byte) (*ssh.Permissions, error) {
+			return nil, errors.New("denied")
+		},
+	}
+	config.AddHostKey(signer)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				ssh.NewServerConn(conn, config)
+				conn.Close()
+			}()
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr), signer.PublicKey()
+}
+
+// TestHostKey_TrustExisting 升级前已存在的主机由管理员批量信任当前密钥，已记录的主机不受影响
+func TestHostKey_TrustExisting(t *testing.T) {
+	appLogger.Log = zap.NewNop()
+	db := testutil.NewDB(t, &asset.Host{}, &asset.HostKey{}, &asset.HostKeyAlert{}, &asset.Credential{})
+	uc := asset.NewHostKeyUseCase(NewHostKeyRepo(db))
+	sshclient.SetKnownHosts(uc)
+	defer sshclient.SetKnownHosts(nil)
+	ctx := context.Background()
+
+	credRepo := NewCredentialRepo(db)
+	cred := &asset.Credential{Name: "root", Type: "password", Username: "root", Password: "secret"}
+	if err := credRepo.Create(ctx, cred); err != nil {
+		t.Fatal(err)
+	}
+	addr, hostKey := startKeyServer(t)
+	existing := &asset.Host{Name: "web-1", IP: addr.IP.String(), Port: addr.Port, CredentialID: cred.ID}
+	noCredential := &asset.Host{Name: "web-2", IP: "127.0.0.1", Port: 1}
+	trusted := &asset.Host{Name: "web-3", IP: "127.0.0.1", Port: 1, CredentialID: cred.ID}
+	for _, h := range []*asset.Host{existing, noCredential, trusted} {
+		if err := db.Create(h).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sshclient.HostKeyCallback(trusted.ID, true)("127.0.0.1:1", nil, newHostKey(t)); err != nil {
+		t.Fatal(err)
+	}
+	if err := sshclient.HostKeyCallback(existing.ID, false)(addr.String(), nil, hostKey); !errors.Is(err, sshclient.ErrHostKeyUnknown) {
+		t.Fatalf("升级前的主机未记录密钥时应拒绝, 实际 %v", err)
+	}
+
+	hosts := asset.NewHostUseCase(NewHostRepo(db), credRepo, nil, nil)
+	results, err := uc.TrustExisting(ctx, hosts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].HostID != existing.ID || results[1].HostID != noCredential.ID {
+		t.Fatalf("只处理未记录密钥的主机: %+v", results)
+	}
+	// 认证失败，但主机密钥已在认证前记录
+	if r := results[0]; !r.Trusted || r.Fingerprint != ssh.FingerprintSHA256(hostKey) || r.Error == "" {
+		t.Fatalf("existing = %+v", r)
+	}
+	if r := results[1]; r.Trusted || !strings.Contains(r.Error, "未配置凭证") {
+		t.Fatalf("noCredential = %+v", r)
+	}
+	if err := sshclient.HostKeyCallback(existing.ID, false)(addr.String(), nil, hostKey); err != nil {
+		t.Fatalf("批量信任后普通连接应通过, 实际 %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2026 DYCloud J.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package asset
+
+import (
+	"context"
+
+	"github.com/ydcloud-dy/opshub/internal/biz/asset"
+	"gorm.io/gorm"
+)
+
+type jumpHopRepo struct {
+	db *gorm.DB
+}
+
+// NewJumpHopRepo 创建跳板链仓库
+func NewJumpHopRepo(db *gorm.DB) asset.JumpHopRepo {
+	return &jumpHopRepo{db: db}
+}
+
+func (r *jumpHopRepo) GetChain(ctx context.Context, ownerType string, ownerID uint) ([]*asset.JumpHop, error) {
+	var hops []*asset.JumpHop
+	err := r.db.WithContext(ctx).
+		Where("owner_type = ? AND owner_id = ?", ownerType, ownerID).
+		Order("seq").Find(&hops).Error
+	return hops, err
+}
+
+func (r *jumpHopRepo) ReplaceChain(ctx context.Context, ownerType string, ownerID uint, hops []*asset.JumpHop) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("owner_type = ? AND owner_id = ?", ownerType, ownerID).Delete(&asset.JumpHop{}).Error; err != nil {
+			return err
+		}
+		if len(hops) == 0 {
+			return nil
+		}
+		return tx.Create(&hops).Error
+	})
+}
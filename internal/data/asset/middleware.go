@@ -21,82 +21,26 @@ package asset
 
 import (
 	"context"
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/rand"
-	"encoding/base64"
 	"fmt"
-	"io"
 
 	"github.com/ydcloud-dy/opshub/internal/biz/asset"
+	"github.com/ydcloud-dy/opshub/pkg/secret"
 	"gorm.io/gorm"
 )
 
 type middlewareRepo struct {
-	db            *gorm.DB
-	encryptionKey []byte
+	db *gorm.DB
 }
 
 // NewMiddlewareRepo 创建中间件仓库
 func NewMiddlewareRepo(db *gorm.DB) asset.MiddlewareRepo {
-	encryptionKey := []byte("opshub-enc-key-32-bytes-long!!!!")
-	return &middlewareRepo{db: db, encryptionKey: encryptionKey}
-}
-
-// encrypt 加密
-func (r *middlewareRepo) encrypt(plaintext string) (string, error) {
-	if plaintext == "" {
-		return "", nil
-	}
-	block, err := aes.NewCipher(r.encryptionKey)
-	if err != nil {
-		return "", err
-	}
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return "", err
-	}
-	nonce := make([]byte, gcm.NonceSize())
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		return "", err
-	}
-	ciphertext := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
-	return base64.StdEncoding.EncodeToString(ciphertext), nil
-}
-
-// decrypt 解密
-func (r *middlewareRepo) decrypt(ciphertext string) (string, error) {
-	if ciphertext == "" {
-		return "", nil
-	}
-	data, err := base64.StdEncoding.DecodeString(ciphertext)
-	if err != nil {
-		return "", err
-	}
-	block, err := aes.NewCipher(r.encryptionKey)
-	if err != nil {
-		return "", err
-	}
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return "", err
-	}
-	nonceSize := gcm.NonceSize()
-	if len(data) < nonceSize {
-		return "", fmt.Errorf("ciphertext too short")
-	}
-	nonce, cipherData := data[:nonceSize], data[nonceSize:]
-	plaintext, err := gcm.Open(nil, nonce, cipherData, nil)
-	if err != nil {
-		return "", err
-	}
-	return string(plaintext), nil
+	return &middlewareRepo{db: db}
 }
 
 // Create 创建中间件
 func (r *middlewareRepo) Create(ctx context.Context, mw *asset.Middleware) error {
 	if mw.Password != "" {
-		encrypted, err := r.encrypt(mw.Password)
+		encrypted, err := secret.Encrypt(mw.Password)
 		if err != nil {
 			return fmt.Errorf("加密密码失败: %w", err)
 		}
@@ -110,9 +54,9 @@ func (r *middlewareRepo) Update(ctx context.Context, mw *asset.Middleware) error
 	if mw.Password != "" {
 		// 检查密码是否已经是加密格式（base64 编码的 AES-GCM 密文）
 		// 如果能成功解密，说明是已加密的旧密码，不需要再次加密
-		if _, err := r.decrypt(mw.Password); err != nil {
+		if _, err := secret.Decrypt(mw.Password); err != nil {
 			// 解密失败，说明是新的明文密码，需要加密
-			encrypted, err := r.encrypt(mw.Password)
+			encrypted, err := secret.Encrypt(mw.Password)
 			if err != nil {
 				return fmt.Errorf("加密密码失败: %w", err)
 			}
@@ -144,7 +88,7 @@ func (r *middlewareRepo) GetByIDDecrypted(ctx context.Context, id uint) (*asset.
 		return nil, err
 	}
 	if mw.Password != "" {
-		decrypted, err := r.decrypt(mw.Password)
+		decrypted, err := secret.Decrypt(mw.Password)
 		if err != nil {
 			return nil, fmt.Errorf("解密密码失败: %w", err)
 		}
@@ -0,0 +1,93 @@
+// Copyright (c) 2026 DYCloud J.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package asset
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ydcloud-dy/opshub/internal/biz/asset"
+	"github.com/ydcloud-dy/opshub/pkg/secret"
+	"gorm.io/gorm"
+)
+
+// sshCASecretPath CA 私钥在密钥存储中的路径
+const sshCASecretPath = "ssh-ca"
+
+type sshCARepo struct {
+	db *gorm.DB
+}
+
+// NewSSHCARepo 创建 SSH CA 仓库
+func NewSSHCARepo(db *gorm.DB) asset.SSHCARepo {
+	return &sshCARepo{db: db}
+}
+
+// Get 获取CA，私钥已从密钥存储取回
+func (r *sshCARepo) Get(ctx context.Context) (*asset.SSHCA, error) {
+	var ca asset.SSHCA
+	if err := r.db.WithContext(ctx).Order("id").First(&ca).Error; err != nil {
+		return nil, err
+	}
+	privateKey, err := secret.Default().Get(ctx, ca.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("解密CA私钥失败: %w", err)
+	}
+	ca.PrivateKey = privateKey
+	return &ca, nil
+}
+
+// Save 保存CA，私钥变化时写入密钥存储并删除旧内容
+func (r *sshCARepo) Save(ctx context.Context, ca *asset.SSHCA) error {
+	var stored asset.SSHCA
+	if ca.ID != 0 {
+		if err := r.db.WithContext(ctx).First(&stored, ca.ID).Error; err != nil {
+			return err
+		}
+	}
+
+	store := secret.Default()
+	current, err := store.Get(ctx, stored.PrivateKey)
+	if err != nil {
+		return fmt.Errorf("解密CA私钥失败: %w", err)
+	}
+	toSave := *ca
+	toSave.PrivateKey = stored.PrivateKey
+	if ca.PrivateKey != current {
+		if toSave.PrivateKey, err = store.Replace(ctx, sshCASecretPath, stored.PrivateKey, ca.PrivateKey); err != nil {
+			return fmt.Errorf("保存CA私钥失败: %w", err)
+		}
+	}
+	if err := r.db.WithContext(ctx).Save(&toSave).Error; err != nil {
+		return err
+	}
+	ca.ID, ca.CreatedAt, ca.UpdatedAt = toSave.ID, toSave.CreatedAt, toSave.UpdatedAt
+	return nil
+}
+
+// GetUserRoleCodes 用户所属的启用状态角色编码
+func (r *sshCARepo) GetUserRoleCodes(ctx context.Context, userID uint) ([]string, error) {
+	var codes []string
+	err := r.db.WithContext(ctx).Table("sys_role").
+		Joins("JOIN sys_user_role ON sys_user_role.role_id = sys_role.id").
+		Where("sys_user_role.user_id = ? AND sys_role.status = 1 AND sys_role.deleted_at IS NULL", userID).
+		Pluck("sys_role.code", &codes).Error
+	return codes, err
+}
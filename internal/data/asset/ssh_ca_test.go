@@ -0,0 +1,259 @@
+package asset
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ydcloud-dy/opshub/internal/biz/asset"
+	"github.com/ydcloud-dy/opshub/internal/testutil"
+	"github.com/ydcloud-dy/opshub/pkg/collector"
+	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
+	sshclient "github.com/ydcloud-dy/opshub/pkg/ssh"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/ssh"
+)
+
+// startCertServer 只接受指定CA签发、且证书主体中包含 principal 的用户证书
+func startCertServer(t *testing.T, ca ssh.PublicKey, principal string) net.Addr {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostKey, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checker := &ssh.CertChecker{
+		IsUserAuthority: func(auth ssh.PublicKey) bool {
+			return string(auth.Marshal()) == string(ca.Marshal())
+		},
+	}
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			// 与 AuthorizedPrincipalsFile 相同：按允许的主体而不是登录账号校验
+			cert, ok := key.(*ssh.Certificate)
+			if !ok {
+				return nil, ssh.ErrNoAuth
+			}
+			if !checker.IsUserAuthority(cert.SignatureKey) {
+				return nil, ssh.ErrNoAuth
+			}
+			return nil, checker.CheckCert(principal, cert)
+		},
+	}
+	config.AddHostKey(hostKey)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, chans, reqs, err := ssh.NewServerConn(conn, config)
+				if err != nil {
+					conn.Close()
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+				for ch := range chans {
+					ch.Reject(ssh.Prohibited, "")
+				}
+			}()
+		}
+	}()
+	return ln.Addr()
+}
+
+func TestSSHCA_IssueCertificateForCredential(t *testing.T) {
+	appLogger.Log = zap.NewNop()
+	db := testutil.NewDB(t, &asset.SSHCA{}, &asset.Credential{})
+	for _, stmt := range []string{
+		"CREATE TABLE sys_role (id INTEGER PRIMARY KEY, code TEXT, status INTEGER, deleted_at DATETIME)",
+		"CREATE TABLE sys_user_role (user_id INTEGER, role_id INTEGER)",
+		"INSERT INTO sys_role (id, code, status) VALUES (1, 'ops', 1), (2, 'disabled', 0)",
+		"INSERT INTO sys_user_role (user_id, role_id) VALUES (7, 1), (7, 2)",
+	} {
+		if err := db.Exec(stmt).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := context.Background()
+
+	uc := asset.NewSSHCAUseCase(NewSSHCARepo(db), nil)
+	if err := asset.IssueCertificate(ctx, &asset.Credential{Type: asset.CredentialTypeCA}); err == nil {
+		t.Fatal("issued without CA")
+	}
+	asset.SetSSHCA(uc)
+	defer asset.SetSSHCA(nil)
+
+	if err := asset.IssueCertificate(ctx, &asset.Credential{Type: asset.CredentialTypeCA}); err == nil {
+		t.Fatal("issued before CA generated")
+	}
+	vo, err := uc.Generate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stored asset.SSHCA
+	if err := db.First(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ssh.ParsePrivateKey([]byte(stored.PrivateKey)); err == nil {
+		t.Fatal("CA private key stored in plaintext")
+	}
+	caKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(vo.PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	credRepo := NewCredentialRepo(db)
+	cred := &asset.Credential{Name: "ops-ca", Type: asset.CredentialTypeCA}
+	if err := credRepo.Create(ctx, cred); err != nil {
+		t.Fatal(err)
+	}
+
+	userCtx := asset.WithSSHIdentity(ctx, asset.SSHIdentity{UserID: 7, Username: "alice", Source: "terminal"})
+	issued, err := credRepo.GetByIDDecrypted(userCtx, cred.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(issued.Certificate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := pub.(*ssh.Certificate)
+	if cert.KeyId != "opshub:alice:terminal" {
+		t.Fatalf("key id = %q", cert.KeyId)
+	}
+	if len(cert.ValidPrincipals) != 2 || cert.ValidPrincipals[0] != "opshub-user-alice" || cert.ValidPrincipals[1] != "opshub-role-ops" {
+		t.Fatalf("principals = %v", cert.ValidPrincipals)
+	}
+	if ttl := time.Until(time.Unix(int64(cert.ValidBefore), 0)); ttl > 5*time.Minute || ttl < 4*time.Minute {
+		t.Fatalf("certificate ttl = %v", ttl)
+	}
+	if string(cert.SignatureKey.Marshal()) != string(caKey.Marshal()) {
+		t.Fatal("certificate not signed by CA")
+	}
+
+	// 角色对应的主体可以登录，其他用户的证书不行
+	addr := startCertServer(t, caKey, "opshub-role-ops")
+	tcpAddr := addr.(*net.TCPAddr)
+	endpoint := sshclient.Endpoint{Host: tcpAddr.IP.String(), Port: tcpAddr.Port, Username: "root", PrivateKey: []byte(issued.PrivateKey), Certificate: []byte(issued.Certificate)}
+	client, err := sshclient.Dial(endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Close()
+
+	// 没有发起用户且未声明为定时任务时不签发，不会退化为自动化任务主体
+	if _, err := credRepo.GetByIDDecrypted(asset.WithSSHSource(ctx, "inspection"), cred.ID); err == nil {
+		t.Fatal("issued certificate without user identity")
+	}
+	system, err := credRepo.GetByIDDecrypted(asset.WithSSHSource(asset.WithSSHSystem(ctx, "scheduled"), "inspection"), cred.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoint.PrivateKey, endpoint.Certificate = []byte(system.PrivateKey), []byte(system.Certificate)
+	if client, err := sshclient.Dial(endpoint); err == nil {
+		client.Close()
+		t.Fatal("system certificate accepted for role principal")
+	}
+	pub, _, _, _, _ = ssh.ParseAuthorizedKey([]byte(system.Certificate))
+	if cert := pub.(*ssh.Certificate); cert.KeyId != "opshub:system:inspection" || cert.ValidPrincipals[0] != asset.SSHPrincipalSystem {
+		t.Fatalf("system certificate = %q %v", cert.KeyId, cert.ValidPrincipals)
+	}
+
+	if _, err := uc.InstallScript(ctx, []asset.SSHCAAccount{{Account: "root", Principals: []string{"opshub-role-ops; reboot"}}}); err == nil {
+		t.Fatal("unsafe principal accepted")
+	}
+
+	// 替换字符后会与其他用户相同的用户名不签发
+	if _, err := credRepo.GetByIDDecrypted(asset.WithSSHIdentity(ctx, asset.SSHIdentity{UserID: 8, Username: "alice smith"}), cred.ID); err == nil {
+		t.Fatal("issued certificate for username that collides after sanitizing")
+	}
+
+	// 安装需对每台主机都有编辑权限
+	uc.SetHostPermissionChecker(hostPermissions{7: {1: true}})
+	if _, err := uc.Install(userCtx, &asset.SSHCAInstallRequest{HostIDs: []uint{1, 2}}); err == nil || !strings.Contains(err.Error(), "主机 2") {
+		t.Fatalf("install without permission on host 2: %v", err)
+	}
+	if _, err := uc.Install(ctx, &asset.SSHCAInstallRequest{HostIDs: []uint{1}}); err == nil {
+		t.Fatal("install without user accepted")
+	}
+}
+
+// hostPermissions 用户ID -> 有编辑权限的主机
+type hostPermissions map[uint]map[uint]bool
+
+func (p hostPermissions) CheckHostOperationPermission(ctx context.Context, userID, hostID uint, operation uint) (bool, error) {
+	return p[userID][hostID], nil
+}
+
+// TestSSHCA_InstallConflict sshd 已配置其他 CA 或主体文件时不修改配置，安装结果报告冲突而不是成功
+func TestSSHCA_InstallConflict(t *testing.T) {
+	appLogger.Log = zap.NewNop()
+	db := testutil.NewDB(t, &asset.SSHCA{})
+	ctx := context.Background()
+
+	// 模拟 root 执行，sshd 配置指向临时文件
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "id"), []byte("#!/bin/sh\necho 0\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	sshdConfig := filepath.Join(dir, "sshd_config")
+	hosts := asset.NewHostUseCase(NewHostRepo(db), nil, nil, nil)
+	hosts.SetAgentCommandFactory(localCommands{env: []string{"PATH=" + dir + ":" + os.Getenv("PATH"), "SSHD_CONFIG=" + sshdConfig}})
+	uc := asset.NewSSHCAUseCase(NewSSHCARepo(db), hosts)
+	if _, err := uc.Generate(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, config := range []string{
+		"TrustedUserCAKeys /etc/ssh/corp_ca.pub\nPermitRootLogin no\n",
+		"AuthorizedPrincipalsFile /etc/ssh/principals/%u\n",
+	} {
+		if err := os.WriteFile(sshdConfig, []byte(config), 0644); err != nil {
+			t.Fatal(err)
+		}
+		results, err := uc.Install(ctx, &asset.SSHCAInstallRequest{HostIDs: []uint{1}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := results[0]
+		if r.Success || !r.Conflict || !strings.Contains(r.Output, "已配置") {
+			t.Fatalf("config %q: result = %+v", config, r)
+		}
+		if data, _ := os.ReadFile(sshdConfig); string(data) != config {
+			t.Fatalf("冲突时不应修改 sshd 配置: %q", data)
+		}
+	}
+}
+
+// localCommands 在本机执行命令，模拟在线的 Agent：非零退出码返回输出和错误
+type localCommands struct {
+	env []string
+}
+
+func (l localCommands) IsOnline(hostID uint) bool { return true }
+
+func (l localCommands) NewExecutor(hostID uint) (collector.CommandExecutor, error) { return l, nil }
+
+func (l localCommands) Execute(command string) (string, error) {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Env = append(os.Environ(), l.env...)
+	output, err := cmd.CombinedOutput()
+	return string(output), err
+}
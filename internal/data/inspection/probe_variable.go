@@ -2,80 +2,26 @@ package inspection
 
 import (
 	"context"
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/rand"
-	"encoding/base64"
 	"fmt"
-	"io"
 	"strconv"
 	"strings"
 
 	biz "github.com/ydcloud-dy/opshub/internal/biz/inspection"
+	"github.com/ydcloud-dy/opshub/pkg/secret"
 	"gorm.io/gorm"
 )
 
 type probeVariableRepo struct {
-	db            *gorm.DB
-	encryptionKey []byte
+	db *gorm.DB
 }
 
 func NewProbeVariableRepo(db *gorm.DB) biz.ProbeVariableRepo {
-	encryptionKey := []byte("opshub-enc-key-32-bytes-long!!!!")
-	return &probeVariableRepo{db: db, encryptionKey: encryptionKey}
-}
-
-func (r *probeVariableRepo) encrypt(plaintext string) (string, error) {
-	if plaintext == "" {
-		return "", nil
-	}
-	block, err := aes.NewCipher(r.encryptionKey)
-	if err != nil {
-		return "", err
-	}
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return "", err
-	}
-	nonce := make([]byte, gcm.NonceSize())
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		return "", err
-	}
-	ciphertext := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
-	return base64.StdEncoding.EncodeToString(ciphertext), nil
-}
-
-func (r *probeVariableRepo) decrypt(ciphertext string) (string, error) {
-	if ciphertext == "" {
-		return "", nil
-	}
-	data, err := base64.StdEncoding.DecodeString(ciphertext)
-	if err != nil {
-		return "", err
-	}
-	block, err := aes.NewCipher(r.encryptionKey)
-	if err != nil {
-		return "", err
-	}
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return "", err
-	}
-	nonceSize := gcm.NonceSize()
-	if len(data) < nonceSize {
-		return "", fmt.Errorf("ciphertext too short")
-	}
-	nonce, cipherData := data[:nonceSize], data[nonceSize:]
-	plaintext, err := gcm.Open(nil, nonce, cipherData, nil)
-	if err != nil {
-		return "", err
-	}
-	return string(plaintext), nil
+	return &probeVariableRepo{db: db}
 }
 
 func (r *probeVariableRepo) Create(ctx context.Context, v *biz.ProbeVariable) error {
 	if v.VarType == biz.VariableTypeSecret {
-		encrypted, err := r.encrypt(v.Value)
+		encrypted, err := secret.Encrypt(v.Value)
 		if err != nil {
 			return fmt.Errorf("encrypt value: %w", err)
 		}
@@ -86,7 +32,7 @@ func (r *probeVariableRepo) Create(ctx context.Context, v *biz.ProbeVariable) er
 
 func (r *probeVariableRepo) Update(ctx context.Context, v *biz.ProbeVariable) error {
 	if v.VarType == biz.VariableTypeSecret && v.Value != "" {
-		encrypted, err := r.encrypt(v.Value)
+		encrypted, err := secret.Encrypt(v.Value)
 		if err != nil {
 			return fmt.Errorf("encrypt value: %w", err)
 		}
@@ -105,7 +51,7 @@ func (r *probeVariableRepo) GetByID(ctx context.Context, id uint) (*biz.ProbeVar
 		return nil, err
 	}
 	if v.VarType == biz.VariableTypeSecret {
-		decrypted, err := r.decrypt(v.Value)
+		decrypted, err := secret.Decrypt(v.Value)
 		if err == nil {
 			v.Value = decrypted
 		}
@@ -191,7 +137,7 @@ func (r *probeVariableRepo) GetByNames(ctx context.Context, names []string, allo
 
 func (r *probeVariableRepo) decryptIfSecret(v *biz.ProbeVariable) *biz.ProbeVariable {
 	if v.VarType == biz.VariableTypeSecret {
-		decrypted, err := r.decrypt(v.Value)
+		decrypted, err := secret.Decrypt(v.Value)
 		if err == nil {
 			v.Value = decrypted
 		}
@@ -2,6 +2,7 @@ package agent
 
 import (
 	"context"
+	"crypto/x509"
 	"fmt"
 	"io"
 	"net"
@@ -17,7 +18,9 @@ import (
 	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 	"gorm.io/gorm"
 )
 
@@ -31,6 +34,7 @@ type AgentService struct {
 	hostRepo         assetbiz.HostRepo
 	serviceLabelRepo assetbiz.ServiceLabelRepo
 	cacheManager     *cache.CacheManager // 缓存管理器
+	certs            *CertManager
 }
 
 // Connect 处理Agent双向流连接
@@ -65,71 +69,153 @@ func (s *AgentService) Connect(stream pb.AgentHub_ConnectServer) error {
 		}
 	}()
 
-	for {
-		msg, err := stream.Recv()
-		if err == io.EOF {
-			return nil
+	// 接收放在独立协程，服务端主动断开（如证书吊销）时无需等待Agent发来消息
+	msgCh := make(chan *pb.AgentMessage)
+	errCh := make(chan error, 1)
+	go func() {
+		for {
+			msg, err := stream.Recv()
+			if err != nil {
+				errCh <- err
+				return
+			}
+			select {
+			case msgCh <- msg:
+			case <-stream.Context().Done():
+				return
+			}
 		}
-		if err != nil {
+	}()
+
+	var kicked <-chan struct{}
+	for {
+		var msg *pb.AgentMessage
+		select {
+		case msg = <-msgCh:
+		case err := <-errCh:
+			if err == io.EOF {
+				return nil
+			}
 			appLogger.Error("Agent流接收错误", zap.Error(err), zap.String("agentID", agentID))
 			return err
+		case <-kicked:
+			appLogger.Warn("服务端主动断开Agent", zap.String("agentID", agentID), zap.String("reason", as.KickReason()))
+			return status.Error(codes.PermissionDenied, as.KickReason())
 		}
 
 		switch payload := msg.Payload.(type) {
 		case *pb.AgentMessage_Register:
+			// 证书身份必须与声明的AgentID一致，否则拒绝且不影响该AgentID的现有连接
+			var cert *x509.Certificate
+			if s.certs != nil {
+				var err error
+				if cert, err = s.certs.Authenticate(stream.Context(), payload.Register.AgentId); err != nil {
+					appLogger.Warn("Agent注册被拒绝", zap.String("agentID", payload.Register.AgentId), zap.Error(err))
+					stream.Send(&pb.ServerMessage{
+						Payload: &pb.ServerMessage_RegisterAck{
+							RegisterAck: &pb.RegisterResponse{Success: false, Message: "证书校验失败: " + err.Error()},
+						},
+					})
+					return status.Error(codes.PermissionDenied, err.Error())
+				}
+			}
 			agentID = payload.Register.AgentId
 			as = s.handleRegister(stream, payload.Register)
+			if as != nil {
+				kicked = as.kickCh
+				if cert != nil {
+					as.setCert(certSerial(cert), cert.NotAfter)
+				}
+			}
 
 		case *pb.AgentMessage_Heartbeat:
 			if as != nil {
 				s.handleHeartbeat(as, payload.Heartbeat)
+				if s.certs != nil {
+					s.certs.maybeRenew(as)
+				}
 			}
 
-		case *pb.AgentMessage_TermOutput:
-			if as != nil {
-				s.hub.HandleTerminalOutput(payload.TermOutput.SessionId, payload.TermOutput.Data)
+		case *pb.AgentMessage_CertCsr:
+			if as != nil && s.certs != nil {
+				s.certs.HandleCSR(as, payload.CertCsr)
 			}
 
-		case *pb.AgentMessage_FileList:
+		case *pb.AgentMessage_PolicyViolation:
 			if as != nil {
-				as.ResolvePending(payload.FileList.RequestId, payload.FileList)
+				s.handlePolicyViolation(as, payload.PolicyViolation)
 			}
 
-		case *pb.AgentMessage_FileChunk:
+		case *pb.AgentMessage_ServiceDiscovery:
 			if as != nil {
-				as.ResolvePending(payload.FileChunk.RequestId, payload.FileChunk)
+				s.handleServiceDiscovery(as, payload.ServiceDiscovery)
 			}
 
-		case *pb.AgentMessage_CmdResult:
+		default:
 			if as != nil {
-				as.ResolvePending(payload.CmdResult.RequestId, payload.CmdResult)
+				s.handleResponse(as, msg)
 			}
+		}
+	}
+}
 
-		case *pb.AgentMessage_ProbeResult:
-			if as != nil {
-				as.ResolvePending(payload.ProbeResult.RequestId, payload.ProbeResult)
-			}
+// handleResponse 其他副本发起的请求的响应沿转发流送回，其余在本副本分发
+func (s *AgentService) handleResponse(as *AgentStream, msg *pb.AgentMessage) {
+	if s.hub.cluster != nil && s.hub.cluster.relayResponse(msg) {
+		return
+	}
+	s.dispatchResponse(as, msg)
+}
 
-		case *pb.AgentMessage_HttpProxyResponse:
-			if as != nil {
-				as.ResolvePending(payload.HttpProxyResponse.RequestId, payload.HttpProxyResponse)
-			}
+// dispatchResponse 分发Agent对请求的响应，请求方副本也用它处理转发回来的响应
+func (s *AgentService) dispatchResponse(as *AgentStream, msg *pb.AgentMessage) {
+	switch payload := msg.Payload.(type) {
+	case *pb.AgentMessage_TermOutput:
+		s.hub.HandleTerminalOutput(payload.TermOutput.SessionId, payload.TermOutput.Data)
 
-		case *pb.AgentMessage_StreamProxyChunk:
-			if as != nil {
-				as.ResolveStreamChunk(payload.StreamProxyChunk)
-			}
+	case *pb.AgentMessage_FileList:
+		as.ResolvePending(payload.FileList.RequestId, payload.FileList)
 
-		case *pb.AgentMessage_WsSessionResult:
-			if as != nil {
-				// WebSocket 会话结果，使用 session_id + action_id 作为 key
-				key := payload.WsSessionResult.SessionId
-				if payload.WsSessionResult.ActionId != "" {
-					key = payload.WsSessionResult.SessionId + ":" + payload.WsSessionResult.ActionId
-				}
-				as.ResolvePending(key, payload.WsSessionResult)
-			}
+	case *pb.AgentMessage_FileChunk:
+		as.ResolvePending(payload.FileChunk.RequestId, payload.FileChunk)
+
+	case *pb.AgentMessage_CmdResult:
+		as.ResolvePending(payload.CmdResult.RequestId, payload.CmdResult)
+
+	case *pb.AgentMessage_CmdOutput:
+		s.hub.HandleCommandOutput(payload.CmdOutput)
+
+	case *pb.AgentMessage_UpgradeResult:
+		as.ResolvePending(payload.UpgradeResult.RequestId, payload.UpgradeResult)
+
+	case *pb.AgentMessage_TunnelOpenResult:
+		as.ResolvePending(payload.TunnelOpenResult.ConnId, payload.TunnelOpenResult)
+
+	case *pb.AgentMessage_TunnelData:
+		s.hub.HandleTunnelData(payload.TunnelData)
+
+	case *pb.AgentMessage_TunnelAck:
+		s.hub.HandleTunnelAck(payload.TunnelAck)
+
+	case *pb.AgentMessage_TunnelClose:
+		s.hub.HandleTunnelClose(payload.TunnelClose)
+
+	case *pb.AgentMessage_ProbeResult:
+		as.ResolvePending(payload.ProbeResult.RequestId, payload.ProbeResult)
+
+	case *pb.AgentMessage_HttpProxyResponse:
+		as.ResolvePending(payload.HttpProxyResponse.RequestId, payload.HttpProxyResponse)
+
+	case *pb.AgentMessage_StreamProxyChunk:
+		as.ResolveStreamChunk(payload.StreamProxyChunk)
+
+	case *pb.AgentMessage_WsSessionResult:
+		// WebSocket 会话结果，使用 session_id + action_id 作为 key
+		key := payload.WsSessionResult.SessionId
+		if payload.WsSessionResult.ActionId != "" {
+			key = payload.WsSessionResult.SessionId + ":" + payload.WsSessionResult.ActionId
 		}
+		as.ResolvePending(key, payload.WsSessionResult)
 	}
 }
 
@@ -351,6 +437,10 @@ func (s *AgentService) handleRegister(stream pb.AgentHub_ConnectServer, req *pb.
 		go s.detectAndApplyServiceLabels(as, agentInfo.HostID)
 	}
 
+	if req.UpgradeId != "" {
+		s.hub.HandleUpgradeRegister(req)
+	}
+
 	appLogger.Info("Agent注册成功",
 		zap.String("agentID", req.AgentId),
 		zap.Uint("hostID", agentInfo.HostID),
@@ -382,6 +472,16 @@ func (s *AgentService) handleHeartbeat(as *AgentStream, req *pb.HeartbeatRequest
 				zap.String("agentID", req.AgentId),
 				zap.Error(err))
 		}
+
+		// 主机指标写入缓存，由 /metrics 导出
+		if as.HostID > 0 {
+			metrics := heartbeatMetrics(as.HostID, req, now)
+			if err := s.cacheManager.GetAgentCache().SetHostMetrics(ctx, as.HostID, metrics); err != nil {
+				appLogger.Warn("缓存主机指标失败",
+					zap.Uint("hostID", as.HostID),
+					zap.Error(err))
+			}
+		}
 	} else {
 		// 降级：直接更新数据库
 		s.agentRepo.UpdateInfo(context.Background(), req.AgentId, map[string]any{
@@ -398,6 +498,35 @@ func (s *AgentService) handleHeartbeat(as *AgentStream, req *pb.HeartbeatRequest
 	})
 }
 
+// handlePolicyViolation 记录Agent本地策略拒绝的请求
+func (s *AgentService) handlePolicyViolation(as *AgentStream, v *pb.PolicyViolation) {
+	appLogger.Warn("Agent本地策略拒绝请求",
+		zap.String("agentID", as.AgentID),
+		zap.Uint("hostID", as.HostID),
+		zap.String("kind", v.Kind),
+		zap.String("action", v.Action),
+		zap.String("target", v.Target),
+		zap.String("reason", v.Reason))
+
+	occurredAt := time.Now()
+	if v.Timestamp > 0 {
+		occurredAt = time.Unix(v.Timestamp, 0)
+	}
+	record := &agentmodel.AgentPolicyViolation{
+		HostID:     as.HostID,
+		AgentID:    as.AgentID,
+		RequestID:  v.RequestId,
+		Kind:       v.Kind,
+		Action:     v.Action,
+		Target:     v.Target,
+		Reason:     v.Reason,
+		OccurredAt: occurredAt,
+	}
+	if err := s.db.Create(record).Error; err != nil {
+		appLogger.Error("保存策略违规记录失败", zap.Error(err))
+	}
+}
+
 // SetCacheManager 设置缓存管理器
 func (s *AgentService) SetCacheManager(manager *cache.CacheManager) {
 	s.cacheManager = manager
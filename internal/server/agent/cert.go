@@ -0,0 +1,380 @@
+package agent
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	agentmodel "github.com/ydcloud-dy/opshub/internal/agent"
+	pb "github.com/ydcloud-dy/opshub/pkg/agentproto"
+	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// defaultCertRenewBefore 未配置时证书剩余有效期不足该时长即发起续期
+	defaultCertRenewBefore = 30 * 24 * time.Hour
+	// certRenewRetry 同一Agent两次发起续期的最小间隔
+	certRenewRetry = 10 * time.Minute
+	// certReloadInterval 从数据库刷新吊销列表的间隔，未收到广播时其他副本吊销的证书最迟在一个间隔后生效
+	certReloadInterval = 30 * time.Second
+	// certRevokeChan 吊销广播通道，消息为被吊销的 AgentID，各副本收到后立即刷新吊销列表
+	certRevokeChan = "agent:cert:revoke"
+)
+
+var errCertRevoked = errors.New("证书已吊销")
+
+// CertManager 管理Agent证书的登记、续期和吊销，并校验连接证书与注册身份一致
+type CertManager struct {
+	tlsMgr      *TLSManager
+	hub         *AgentHub
+	db          *gorm.DB
+	renewBefore time.Duration
+	// rdb 多副本部署时用于广播吊销，为 nil 时只依赖定期刷新
+	rdb *redis.Client
+
+	mu      sync.RWMutex
+	revoked map[string]struct{} // 已吊销的证书序列号
+
+	renewMu  sync.Mutex
+	renewing map[string]certRenewal // agentID -> 进行中的续期
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+}
+
+// certRenewal 已下发但尚未收到签名请求的续期
+type certRenewal struct {
+	requestID string
+	startedAt time.Time
+}
+
+// NewCertManager 创建证书管理器，并接管TLS握手时的吊销检查
+func NewCertManager(tlsMgr *TLSManager, hub *AgentHub, db *gorm.DB, renewBefore time.Duration) *CertManager {
+	if renewBefore <= 0 {
+		renewBefore = defaultCertRenewBefore
+	}
+	m := &CertManager{
+		tlsMgr:      tlsMgr,
+		hub:         hub,
+		db:          db,
+		renewBefore: renewBefore,
+		revoked:     make(map[string]struct{}),
+		renewing:    make(map[string]certRenewal),
+		stopCh:      make(chan struct{}),
+	}
+	tlsMgr.SetPeerVerifier(m.verifyPeer)
+	return m
+}
+
+// EnableBroadcast 经 Redis Pub/Sub 向其他副本广播吊销，需在 Start 之前调用
+func (m *CertManager) EnableBroadcast(rdb *redis.Client) {
+	m.rdb = rdb
+}
+
+// Start 加载吊销列表并定期刷新，启用广播时同时订阅其他副本的吊销通知
+func (m *CertManager) Start() {
+	m.reload()
+	if m.rdb != nil {
+		go m.subscribe()
+	}
+	go func() {
+		ticker := time.NewTicker(certReloadInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.reload()
+			case <-m.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// subscribe 收到其他副本的吊销广播后立即刷新，断开连接在本副本上的同一Agent
+func (m *CertManager) subscribe() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-m.stopCh
+		cancel()
+	}()
+	pubsub := m.rdb.Subscribe(ctx, certRevokeChan)
+	defer pubsub.Close()
+	ch := pubsub.Channel()
+	for {
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			appLogger.Info("收到Agent证书吊销广播", zap.String("agentID", msg.Payload))
+			m.reload()
+		case <-m.stopCh:
+			return
+		}
+	}
+}
+
+// Stop 停止刷新吊销列表
+func (m *CertManager) Stop() {
+	m.stopOnce.Do(func() { close(m.stopCh) })
+}
+
+// reload 从数据库加载未过期的已吊销证书，并断开仍在使用这些证书的连接
+func (m *CertManager) reload() {
+	var serials []string
+	if err := m.db.Model(&agentmodel.AgentCertificate{}).
+		Where("revoked_at IS NOT NULL AND not_after > ?", time.Now()).
+		Pluck("serial_number", &serials).Error; err != nil {
+		appLogger.Warn("加载证书吊销列表失败", zap.Error(err))
+		return
+	}
+	revoked := make(map[string]struct{}, len(serials))
+	for _, serial := range serials {
+		revoked[serial] = struct{}{}
+	}
+	m.mu.Lock()
+	m.revoked = revoked
+	m.mu.Unlock()
+
+	for _, as := range m.hub.localStreams() {
+		if serial, _ := as.cert(); serial != "" && m.isRevoked(serial) {
+			appLogger.Warn("断开使用已吊销证书的Agent", zap.String("agentID", as.AgentID), zap.String("serial", serial))
+			as.kick(errCertRevoked.Error())
+		}
+	}
+}
+
+func (m *CertManager) isRevoked(serial string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.revoked[serial]
+	return ok
+}
+
+// verifyPeer TLS握手时拒绝已吊销的证书
+func (m *CertManager) verifyPeer(cert *x509.Certificate) error {
+	if m.isRevoked(certSerial(cert)) {
+		return errCertRevoked
+	}
+	return nil
+}
+
+// Issue 为Agent签发证书并登记，用于部署和生成安装包
+func (m *CertManager) Issue(agentID, hostIP string) (certPEM, keyPEM []byte, err error) {
+	certPEM, keyPEM, err = m.tlsMgr.SignAgentCert(agentID, hostIP)
+	if err != nil {
+		return nil, nil, err
+	}
+	block, _ := pem.Decode(certPEM)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("解析Agent证书失败: %w", err)
+	}
+	if err := m.record(agentID, cert, agentmodel.CertIssueDeploy); err != nil {
+		return nil, nil, err
+	}
+	return certPEM, keyPEM, nil
+}
+
+// record 登记证书并更新Agent的证书过期时间
+func (m *CertManager) record(agentID string, cert *x509.Certificate, issuedBy string) error {
+	record := &agentmodel.AgentCertificate{
+		AgentID:      agentID,
+		SerialNumber: certSerial(cert),
+		IssuedBy:     issuedBy,
+		NotBefore:    cert.NotBefore,
+		NotAfter:     cert.NotAfter,
+	}
+	if err := m.db.Create(record).Error; err != nil {
+		return fmt.Errorf("登记Agent证书失败: %w", err)
+	}
+	m.db.Model(&agentmodel.AgentInfo{}).Where("agent_id = ?", agentID).Update("cert_expiry", cert.NotAfter)
+	return nil
+}
+
+// Authenticate 校验连接的客户端证书属于注册的 agentID 且未被吊销，返回该证书
+func (m *CertManager) Authenticate(ctx context.Context, agentID string) (*x509.Certificate, error) {
+	cert := peerCertificate(ctx)
+	if cert == nil {
+		return nil, errors.New("未提供客户端证书")
+	}
+	if !slices.Contains(cert.Subject.Organization, agentCertOrganization) {
+		return nil, errors.New("不是Agent证书")
+	}
+	if cert.Subject.CommonName != agentID {
+		return nil, fmt.Errorf("证书身份 %s 与注册的AgentID %s 不一致", cert.Subject.CommonName, agentID)
+	}
+
+	serial := certSerial(cert)
+	var record agentmodel.AgentCertificate
+	err := m.db.Where("serial_number = ?", serial).First(&record).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		// 启用证书登记前签发的证书，补录后才能吊销；Agent吊销过证书时未登记的证书一律拒绝
+		var revocations int64
+		if err := m.db.Model(&agentmodel.AgentCertRevocation{}).Where("agent_id = ?", agentID).Count(&revocations).Error; err != nil {
+			return nil, fmt.Errorf("查询Agent证书吊销标记失败: %w", err)
+		}
+		if revocations > 0 {
+			appLogger.Warn("拒绝已吊销Agent的未登记证书", zap.String("agentID", agentID), zap.String("serial", serial))
+			return nil, errCertRevoked
+		}
+		if err := m.record(agentID, cert, agentmodel.CertIssueLegacy); err != nil {
+			appLogger.Warn("补录Agent证书失败", zap.String("agentID", agentID), zap.Error(err))
+		}
+	case err != nil:
+		// 数据库不可用时退回到内存中的吊销列表
+		appLogger.Warn("查询Agent证书失败", zap.String("agentID", agentID), zap.Error(err))
+		if m.isRevoked(serial) {
+			return nil, errCertRevoked
+		}
+	case record.RevokedAt != nil:
+		m.mu.Lock()
+		m.revoked[serial] = struct{}{}
+		m.mu.Unlock()
+		return nil, errCertRevoked
+	default:
+		m.db.Model(&agentmodel.AgentInfo{}).Where("agent_id = ?", agentID).Update("cert_expiry", cert.NotAfter)
+	}
+	return cert, nil
+}
+
+// Revoke 吊销Agent的全部证书并立即断开其在本副本上的连接，返回吊销的证书数。
+// 同时记录吊销标记，之后该Agent尚未登记的旧证书也不能接入
+func (m *CertManager) Revoke(ctx context.Context, agentID, operator, reason string) (int64, error) {
+	now := time.Now()
+	if err := m.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "agent_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"revoked_at", "revoked_by"}),
+	}).Create(&agentmodel.AgentCertRevocation{AgentID: agentID, RevokedAt: now, RevokedBy: operator}).Error; err != nil {
+		return 0, err
+	}
+	result := m.db.WithContext(ctx).Model(&agentmodel.AgentCertificate{}).
+		Where("agent_id = ? AND revoked_at IS NULL", agentID).
+		Updates(map[string]any{"revoked_at": now, "revoked_by": operator, "revoke_reason": reason})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	var serials []string
+	m.db.WithContext(ctx).Model(&agentmodel.AgentCertificate{}).
+		Where("agent_id = ? AND revoked_at IS NOT NULL", agentID).
+		Pluck("serial_number", &serials)
+	m.mu.Lock()
+	for _, serial := range serials {
+		m.revoked[serial] = struct{}{}
+	}
+	m.mu.Unlock()
+
+	m.renewMu.Lock()
+	delete(m.renewing, agentID)
+	m.renewMu.Unlock()
+
+	disconnected := m.hub.Disconnect(agentID, errCertRevoked.Error())
+	if m.rdb != nil {
+		if err := m.rdb.Publish(ctx, certRevokeChan, agentID).Err(); err != nil {
+			appLogger.Warn("广播证书吊销失败，其他副本将在下次刷新时生效", zap.String("agentID", agentID), zap.Error(err))
+		}
+	}
+	appLogger.Warn("Agent证书已吊销",
+		zap.String("agentID", agentID),
+		zap.String("operator", operator),
+		zap.String("reason", reason),
+		zap.Int64("count", result.RowsAffected),
+		zap.Bool("disconnected", disconnected))
+	return result.RowsAffected, nil
+}
+
+// maybeRenew 连接证书临近过期时要求Agent提交证书签名请求
+func (m *CertManager) maybeRenew(as *AgentStream) {
+	_, notAfter := as.cert()
+	if notAfter.IsZero() || time.Until(notAfter) > m.renewBefore {
+		return
+	}
+
+	m.renewMu.Lock()
+	if r, ok := m.renewing[as.AgentID]; ok && time.Since(r.startedAt) < certRenewRetry {
+		m.renewMu.Unlock()
+		return
+	}
+	requestID := uuid.New().String()
+	m.renewing[as.AgentID] = certRenewal{requestID: requestID, startedAt: time.Now()}
+	m.renewMu.Unlock()
+
+	appLogger.Info("Agent证书即将过期，发起续期", zap.String("agentID", as.AgentID), zap.Time("notAfter", notAfter))
+	as.Send(&pb.ServerMessage{Payload: &pb.ServerMessage_CertRenewRequest{CertRenewRequest: &pb.CertRenewRequest{
+		RequestId: requestID,
+		NotAfter:  notAfter.Unix(),
+	}}})
+}
+
+// HandleCSR 签发续期证书，只接受服务端发起的续期
+func (m *CertManager) HandleCSR(as *AgentStream, req *pb.CertSigningRequest) {
+	resp := &pb.CertRenewResponse{RequestId: req.RequestId}
+	if err := m.signCSR(as, req, resp); err != nil {
+		appLogger.Warn("Agent证书续期失败", zap.String("agentID", as.AgentID), zap.Error(err))
+		resp.Error = err.Error()
+	} else {
+		resp.Success = true
+		appLogger.Info("Agent证书续期完成", zap.String("agentID", as.AgentID))
+	}
+	as.Send(&pb.ServerMessage{Payload: &pb.ServerMessage_CertRenewResponse{CertRenewResponse: resp}})
+}
+
+func (m *CertManager) signCSR(as *AgentStream, req *pb.CertSigningRequest, resp *pb.CertRenewResponse) error {
+	m.renewMu.Lock()
+	r, ok := m.renewing[as.AgentID]
+	if ok && r.requestID == req.RequestId {
+		delete(m.renewing, as.AgentID)
+	}
+	m.renewMu.Unlock()
+	if !ok || r.requestID != req.RequestId {
+		return errors.New("没有进行中的证书续期")
+	}
+
+	certPEM, cert, err := m.tlsMgr.SignAgentCSR(as.AgentID, req.CsrPem)
+	if err != nil {
+		return err
+	}
+	caPEM, err := m.tlsMgr.GetCACertPEM()
+	if err != nil {
+		return fmt.Errorf("读取CA证书失败: %w", err)
+	}
+	if err := m.record(as.AgentID, cert, agentmodel.CertIssueRenew); err != nil {
+		return err
+	}
+	resp.CertPem, resp.CaPem = certPEM, caPEM
+	return nil
+}
+
+// peerCertificate 返回连接经过校验的客户端证书
+func peerCertificate(ctx context.Context) *x509.Certificate {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return info.State.VerifiedChains[0][0]
+}
+
+// certSerial 证书序列号的十六进制表示
+func certSerial(cert *x509.Certificate) string {
+	return cert.SerialNumber.Text(16)
+}
@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	agentmodel "github.com/ydcloud-dy/opshub/internal/agent"
+	rbacService "github.com/ydcloud-dy/opshub/internal/service/rbac"
+)
+
+// ListAgentCerts 主机当前Agent的证书签发和吊销记录
+func (s *HTTPServer) ListAgentCerts(c *gin.Context) {
+	info, ok := s.agentByHostParam(c)
+	if !ok {
+		return
+	}
+	var list []agentmodel.AgentCertificate
+	if err := s.db.Where("agent_id = ?", info.AgentID).Order("id DESC").Find(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success", "data": list})
+}
+
+// RevokeAgentCert 吊销主机当前Agent的全部证书并断开连接，需重新部署后才能接入
+func (s *HTTPServer) RevokeAgentCert(c *gin.Context) {
+	info, ok := s.agentByHostParam(c)
+	if !ok {
+		return
+	}
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	c.ShouldBindJSON(&req)
+
+	count, err := s.certs.Revoke(c.Request.Context(), info.AgentID, rbacService.GetUsername(c), req.Reason)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "吊销证书失败: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "证书已吊销", "data": gin.H{"agentId": info.AgentID, "revoked": count}})
+}
+
+// agentByHostParam 按路径中的 hostId 查询Agent，失败时已写入响应
+func (s *HTTPServer) agentByHostParam(c *gin.Context) (*agentmodel.AgentInfo, bool) {
+	hostID, err := strconv.ParseUint(c.Param("hostId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "无效的主机ID"})
+		return nil, false
+	}
+	info, err := s.grpcServer.AgentRepo().GetByHostID(c.Request.Context(), uint(hostID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "主机未安装Agent"})
+		return nil, false
+	}
+	return info, true
+}
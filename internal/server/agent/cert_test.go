@@ -0,0 +1,277 @@
+package agent
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	agentmodel "github.com/ydcloud-dy/opshub/internal/agent"
+	"github.com/ydcloud-dy/opshub/internal/testutil"
+	pb "github.com/ydcloud-dy/opshub/pkg/agentproto"
+	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"gorm.io/gorm"
+)
+
+func newTestCertManager(t *testing.T) (*CertManager, *AgentHub, *gorm.DB) {
+	appLogger.Log = zap.NewNop()
+	db := testutil.NewDB(t, &agentmodel.AgentInfo{}, &agentmodel.AgentCertificate{}, &agentmodel.AgentCertRevocation{})
+	tlsMgr := NewTLSManager(t.TempDir())
+	if err := tlsMgr.InitCA(); err != nil {
+		t.Fatal(err)
+	}
+	hub := NewAgentHub()
+	return NewCertManager(tlsMgr, hub, db, 0), hub, db
+}
+
+// peerContext 模拟握手校验通过后携带客户端证书的连接上下文
+func peerContext(t *testing.T, certPEM []byte) (context.Context, *x509.Certificate) {
+	block, _ := pem.Decode(certPEM)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{
+		State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}},
+	}})
+	return ctx, cert
+}
+
+func TestCert_AuthenticateAndRevoke(t *testing.T) {
+	m, hub, db := newTestCertManager(t)
+	db.Create(&agentmodel.AgentInfo{AgentID: "agent-1", HostID: 7})
+
+	certPEM, _, err := m.Issue("agent-1", "10.0.0.7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cert := peerContext(t, certPEM)
+
+	if _, err := m.Authenticate(ctx, "agent-2"); err == nil {
+		t.Fatal("证书CN与AgentID不一致时应拒绝")
+	}
+	if _, err := m.Authenticate(context.Background(), "agent-1"); err == nil {
+		t.Fatal("没有客户端证书时应拒绝")
+	}
+	if _, err := m.Authenticate(ctx, "agent-1"); err != nil {
+		t.Fatal(err)
+	}
+	var info agentmodel.AgentInfo
+	db.Where("agent_id = ?", "agent-1").First(&info)
+	if info.CertExpiry == nil || !info.CertExpiry.Equal(cert.NotAfter) {
+		t.Fatalf("应记录证书过期时间: %v", info.CertExpiry)
+	}
+
+	// 吊销后立即断开连接，握手和注册都被拒绝
+	as := hub.Register("agent-1", 7, nil)
+	as.setCert(certSerial(cert), cert.NotAfter)
+	count, err := m.Revoke(context.Background(), "agent-1", "admin", "主机失陷")
+	if err != nil || count != 1 {
+		t.Fatalf("吊销失败: count=%d err=%v", count, err)
+	}
+	select {
+	case <-as.kickCh:
+	default:
+		t.Fatal("吊销后应断开Agent连接")
+	}
+	if err := m.verifyPeer(cert); err != errCertRevoked {
+		t.Fatalf("握手应拒绝已吊销证书, 实际 %v", err)
+	}
+	if _, err := m.Authenticate(ctx, "agent-1"); err != errCertRevoked {
+		t.Fatalf("注册应拒绝已吊销证书, 实际 %v", err)
+	}
+
+	// 其他副本从数据库加载吊销列表
+	other := NewCertManager(m.tlsMgr, NewAgentHub(), db, 0)
+	other.reload()
+	if err := other.verifyPeer(cert); err != errCertRevoked {
+		t.Fatalf("刷新后应拒绝已吊销证书, 实际 %v", err)
+	}
+
+	// 重新部署签发的新证书可以接入
+	newPEM, _, err := m.Issue("agent-1", "10.0.0.7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newCtx, _ := peerContext(t, newPEM)
+	if _, err := m.Authenticate(newCtx, "agent-1"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestCert_RevokeLegacy 吊销后未登记过的旧证书不能补录接入，未吊销的Agent照常补录
+func TestCert_RevokeLegacy(t *testing.T) {
+	m, _, db := newTestCertManager(t)
+
+	// 启用登记前签发、从未连接过的证书
+	legacyPEM, _, err := m.tlsMgr.SignAgentCert("agent-1", "10.0.0.7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	count, err := m.Revoke(context.Background(), "agent-1", "admin", "主机失陷")
+	if err != nil || count != 0 {
+		t.Fatalf("吊销失败: count=%d err=%v", count, err)
+	}
+	ctx, _ := peerContext(t, legacyPEM)
+	if _, err := m.Authenticate(ctx, "agent-1"); err != errCertRevoked {
+		t.Fatalf("吊销后应拒绝未登记的旧证书, 实际 %v", err)
+	}
+	var recorded int64
+	db.Model(&agentmodel.AgentCertificate{}).Where("agent_id = ?", "agent-1").Count(&recorded)
+	if recorded != 0 {
+		t.Fatalf("被拒绝的证书不应补录, 实际 %d 条", recorded)
+	}
+	// 再次吊销更新标记
+	if _, err := m.Revoke(context.Background(), "agent-1", "admin2", "再次吊销"); err != nil {
+		t.Fatal(err)
+	}
+	var marker agentmodel.AgentCertRevocation
+	if err := db.Where("agent_id = ?", "agent-1").First(&marker).Error; err != nil || marker.RevokedBy != "admin2" {
+		t.Fatalf("吊销标记 = %+v, %v", marker, err)
+	}
+
+	// 重新部署签发的证书已登记，可以接入
+	newPEM, _, err := m.Issue("agent-1", "10.0.0.7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newCtx, _ := peerContext(t, newPEM)
+	if _, err := m.Authenticate(newCtx, "agent-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 未吊销过的Agent，旧证书首次连接时补录
+	otherPEM, _, err := m.tlsMgr.SignAgentCert("agent-2", "10.0.0.8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherCtx, other := peerContext(t, otherPEM)
+	if _, err := m.Authenticate(otherCtx, "agent-2"); err != nil {
+		t.Fatal(err)
+	}
+	var legacy agentmodel.AgentCertificate
+	if err := db.Where("serial_number = ?", certSerial(other)).First(&legacy).Error; err != nil || legacy.IssuedBy != agentmodel.CertIssueLegacy {
+		t.Fatalf("旧证书应补录: %+v, %v", legacy, err)
+	}
+}
+
+func TestCert_RenewOverStream(t *testing.T) {
+	m, hub, db := newTestCertManager(t)
+	m.tlsMgr.SetAgentCertTTL(24 * time.Hour) // 短于续期阈值
+
+	certPEM, _, err := m.Issue("agent-1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, cert := peerContext(t, certPEM)
+	as := hub.Register("agent-1", 7, nil)
+	as.setCert(certSerial(cert), cert.NotAfter)
+
+	m.maybeRenew(as)
+	var req *pb.CertRenewRequest
+	select {
+	case msg := <-as.SendCh:
+		req = msg.GetCertRenewRequest()
+	default:
+	}
+	if req == nil || req.NotAfter != cert.NotAfter.Unix() {
+		t.Fatalf("证书临近过期时应发起续期: %v", req)
+	}
+	m.maybeRenew(as)
+	if len(as.SendCh) != 0 {
+		t.Fatal("续期进行中不应重复发起")
+	}
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	// 签名请求中的主题不被采信
+	csrDER, _ := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "agent-2"}}, key)
+	csrPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrDER})
+
+	m.HandleCSR(as, &pb.CertSigningRequest{RequestId: "forged", CsrPem: csrPEM})
+	if resp := (<-as.SendCh).GetCertRenewResponse(); resp.Success {
+		t.Fatal("未由服务端发起的续期应拒绝")
+	}
+
+	m.HandleCSR(as, &pb.CertSigningRequest{RequestId: req.RequestId, CsrPem: csrPEM})
+	resp := (<-as.SendCh).GetCertRenewResponse()
+	if !resp.Success {
+		t.Fatalf("续期失败: %s", resp.Error)
+	}
+	if _, err := tls.X509KeyPair(resp.CertPem, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: mustMarshalEC(t, key)})); err != nil {
+		t.Fatalf("新证书应使用Agent提交的公钥: %v", err)
+	}
+	_, renewed := peerContext(t, resp.CertPem)
+	if renewed.Subject.CommonName != "agent-1" || len(resp.CaPem) == 0 {
+		t.Fatalf("新证书身份错误: %s", renewed.Subject.CommonName)
+	}
+	var count int64
+	db.Model(&agentmodel.AgentCertificate{}).Where("agent_id = ? AND issued_by = ?", "agent-1", agentmodel.CertIssueRenew).Count(&count)
+	if count != 1 {
+		t.Fatalf("续期证书应登记, 实际 %d 条", count)
+	}
+
+	// 同一请求不能重复签发
+	m.HandleCSR(as, &pb.CertSigningRequest{RequestId: req.RequestId, CsrPem: csrPEM})
+	if resp := (<-as.SendCh).GetCertRenewResponse(); resp.Success {
+		t.Fatal("续期请求只能使用一次")
+	}
+}
+
+func mustMarshalEC(t *testing.T, key *ecdsa.PrivateKey) []byte {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func TestCert_RevokeBroadcast(t *testing.T) {
+	m, _, db := newTestCertManager(t)
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+	db.Create(&agentmodel.AgentInfo{AgentID: "agent-1", HostID: 7})
+	certPEM, _, err := m.Issue("agent-1", "10.0.0.7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, cert := peerContext(t, certPEM)
+
+	// Agent连接在另一个副本上
+	otherHub := NewAgentHub()
+	other := NewCertManager(m.tlsMgr, otherHub, db, 0)
+	other.EnableBroadcast(rdb)
+	other.Start()
+	defer other.Stop()
+	as := otherHub.Register("agent-1", 7, nil)
+	as.setCert(certSerial(cert), cert.NotAfter)
+
+	m.EnableBroadcast(rdb)
+	deadline := time.Now().Add(5 * time.Second)
+	for mr.PubSubNumSub(certRevokeChan)[certRevokeChan] == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := m.Revoke(context.Background(), "agent-1", "admin", "主机失陷"); err != nil {
+		t.Fatal(err)
+	}
+	// 无需等待定期刷新，收到广播后立即断开并拒绝握手
+	select {
+	case <-as.kickCh:
+	case <-time.After(5 * time.Second):
+		t.Fatal("其他副本收到广播后应断开Agent连接")
+	}
+	if err := other.verifyPeer(cert); err != errCertRevoked {
+		t.Fatalf("其他副本应拒绝已吊销证书, 实际 %v", err)
+	}
+}
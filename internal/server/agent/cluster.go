@@ -0,0 +1,516 @@
+package agent
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	pb "github.com/ydcloud-dy/opshub/pkg/agentproto"
+	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// agentOwnerPrefix Agent所在副本登记表: agent:owner:{host_id} → hash{addr, agentId}
+	agentOwnerPrefix = "agent:owner:"
+	// agentOwnerTTL 登记有效期，副本异常退出后登记自动失效
+	agentOwnerTTL = 60 * time.Second
+	// clusterRefreshInterval 续期登记、清理空闲路由的间隔
+	clusterRefreshInterval = 20 * time.Second
+	// relayHandshakeTimeout 等待持有连接的副本确认的超时
+	relayHandshakeTimeout = 5 * time.Second
+	// relayRouteIdle 转发路由空闲超过该时长后清理
+	relayRouteIdle = 30 * time.Minute
+	// relaySendBuffer 每条转发流待发往请求方副本的消息队列长度
+	relaySendBuffer = 256
+)
+
+// luaReleaseAgentOwner 仅当登记仍属于本副本时删除，避免覆盖Agent已重连到的其他副本
+// KEYS[1]: agent:owner:{host_id}
+// ARGV[1]: 本副本地址
+const luaReleaseAgentOwner = `
+if redis.call('HGET', KEYS[1], 'addr') == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`
+
+// Cluster 多副本部署时的Agent连接转发。
+// 每个副本把自己持有的Agent连接登记到 Redis；请求落在未持有连接的副本上时，
+// GetByHostID 返回一个代理 AgentStream，消息经 AgentRelay 流交给持有连接的副本，
+// 后者按消息中的请求/会话ID记录路由，把Agent的响应沿原流送回，由请求方副本按本地流程分发
+type Cluster struct {
+	pb.UnimplementedAgentRelayServer
+
+	hub      *AgentHub
+	rdb      *redis.Client
+	addr     string
+	release  *redis.Script
+	dispatch func(as *AgentStream, msg *pb.AgentMessage)
+	// clientTLS 连接其他副本使用的客户端证书，gRPC 服务启动时签发
+	clientTLS *tls.Config
+
+	mu      sync.Mutex
+	conns   map[string]*grpc.ClientConn // 副本地址 → 连接
+	remotes map[uint]*AgentStream       // hostID → 代理流
+
+	routeMu sync.Mutex
+	routes  map[string]*relayRoute // 关联ID → 转发流
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+}
+
+// relayRoute 一个请求/会话的转发路由
+type relayRoute struct {
+	relay *relayConn
+	last  time.Time
+}
+
+// relayConn 本副本为其他副本转发的一条流
+type relayConn struct {
+	sendCh chan *pb.AgentMessage
+	done   chan struct{}
+}
+
+// NewCluster 创建多副本转发，addr 为本副本供其他副本访问的 gRPC 地址
+func NewCluster(hub *AgentHub, rdb *redis.Client, addr string) *Cluster {
+	c := &Cluster{
+		hub:     hub,
+		rdb:     rdb,
+		addr:    addr,
+		release: redis.NewScript(luaReleaseAgentOwner),
+		conns:   make(map[string]*grpc.ClientConn),
+		remotes: make(map[uint]*AgentStream),
+		routes:  make(map[string]*relayRoute),
+		stopCh:  make(chan struct{}),
+	}
+	hub.cluster = c
+	return c
+}
+
+// Start 定期续期本副本的登记并清理空闲路由
+func (c *Cluster) Start(clientTLS *tls.Config) {
+	c.clientTLS = clientTLS
+	c.refresh()
+	go func() {
+		ticker := time.NewTicker(clusterRefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.refresh()
+				c.sweepRoutes()
+			case <-c.stopCh:
+				return
+			}
+		}
+	}()
+	appLogger.Info("Agent多副本转发已启用", zap.String("addr", c.addr))
+}
+
+// Stop 注销本副本的全部登记并断开与其他副本的连接
+func (c *Cluster) Stop() {
+	c.stopOnce.Do(func() {
+		close(c.stopCh)
+		for hostID := range c.hub.localAgents() {
+			c.unclaim(hostID)
+		}
+		c.mu.Lock()
+		for addr, conn := range c.conns {
+			conn.Close()
+			delete(c.conns, addr)
+		}
+		c.mu.Unlock()
+	})
+}
+
+// claim 登记Agent连接在本副本上
+func (c *Cluster) claim(hostID uint, agentID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	key := agentOwnerPrefix + strconv.FormatUint(uint64(hostID), 10)
+	_, err := c.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, "addr", c.addr, "agentId", agentID)
+		pipe.Expire(ctx, key, agentOwnerTTL)
+		return nil
+	})
+	if err != nil {
+		appLogger.Warn("登记Agent所在副本失败", zap.Uint("hostID", hostID), zap.Error(err))
+	}
+}
+
+// unclaim 注销本副本的登记
+func (c *Cluster) unclaim(hostID uint) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	key := agentOwnerPrefix + strconv.FormatUint(uint64(hostID), 10)
+	if err := c.release.Run(ctx, c.rdb, []string{key}, c.addr).Err(); err != nil {
+		appLogger.Warn("注销Agent所在副本失败", zap.Uint("hostID", hostID), zap.Error(err))
+	}
+}
+
+func (c *Cluster) refresh() {
+	for hostID, agentID := range c.hub.localAgents() {
+		c.claim(hostID, agentID)
+	}
+}
+
+// lookup 查询持有Agent连接的副本，未登记时返回空地址
+func (c *Cluster) lookup(hostID uint) (addr, agentID string, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	vals, err := c.rdb.HGetAll(ctx, agentOwnerPrefix+strconv.FormatUint(uint64(hostID), 10)).Result()
+	if err != nil {
+		return "", "", err
+	}
+	return vals["addr"], vals["agentId"], nil
+}
+
+// isOnline Agent是否连接在其他副本上
+func (c *Cluster) isOnline(hostID uint) bool {
+	addr, _, err := c.lookup(hostID)
+	return err == nil && addr != "" && addr != c.addr
+}
+
+// remoteStream 返回连接在其他副本上的Agent的代理流
+func (c *Cluster) remoteStream(hostID uint) (*AgentStream, bool) {
+	c.mu.Lock()
+	as, ok := c.remotes[hostID]
+	c.mu.Unlock()
+	if ok {
+		return as, true
+	}
+
+	addr, agentID, err := c.lookup(hostID)
+	if err != nil {
+		appLogger.Warn("查询Agent所在副本失败", zap.Uint("hostID", hostID), zap.Error(err))
+		return nil, false
+	}
+	if addr == "" || addr == c.addr {
+		return nil, false
+	}
+	as, err = c.openRelay(hostID, addr, agentID)
+	if err != nil {
+		appLogger.Warn("连接Agent所在副本失败", zap.Uint("hostID", hostID), zap.String("replica", addr), zap.Error(err))
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if existing, ok := c.remotes[hostID]; ok {
+		// 并发建立了多条转发流，保留先建立的
+		close(as.DoneCh)
+		return existing, true
+	}
+	c.remotes[hostID] = as
+	return as, true
+}
+
+// openRelay 建立到持有连接的副本的转发流
+func (c *Cluster) openRelay(hostID uint, addr, agentID string) (*AgentStream, error) {
+	conn, err := c.dial(addr)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := pb.NewAgentRelayClient(conn).Relay(ctx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if err := stream.Send(&pb.RelayFrame{HostId: uint32(hostID)}); err != nil {
+		cancel()
+		return nil, err
+	}
+	// 对端确认Agent在线后先回一条空消息
+	ack := make(chan error, 1)
+	go func() {
+		_, err := stream.Recv()
+		ack <- err
+	}()
+	select {
+	case err = <-ack:
+	case <-time.After(relayHandshakeTimeout):
+		err = errors.New("等待副本确认超时")
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	as := &AgentStream{
+		AgentID: agentID,
+		HostID:  hostID,
+		SendCh:  make(chan *pb.ServerMessage, 64),
+		DoneCh:  make(chan struct{}),
+		pending: make(map[string]interface{}),
+	}
+	go func() {
+		for {
+			select {
+			case msg := <-as.SendCh:
+				if err := stream.Send(&pb.RelayFrame{Message: msg}); err != nil {
+					cancel()
+					return
+				}
+			case <-as.DoneCh:
+				cancel()
+				return
+			}
+		}
+	}()
+	go func() {
+		defer cancel()
+		for {
+			msg, err := stream.Recv()
+			if err != nil {
+				if status.Code(err) != codes.Canceled {
+					appLogger.Info("副本转发流已断开", zap.Uint("hostID", hostID), zap.String("replica", addr), zap.Error(err))
+				}
+				c.dropRemote(as)
+				return
+			}
+			c.dispatch(as, msg)
+		}
+	}()
+	appLogger.Info("已建立副本转发流", zap.Uint("hostID", hostID), zap.String("replica", addr))
+	return as, nil
+}
+
+// dropRemote 转发流断开后移除代理流，下次请求重新查询Agent所在副本
+func (c *Cluster) dropRemote(as *AgentStream) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.remotes[as.HostID] == as {
+		delete(c.remotes, as.HostID)
+	}
+	select {
+	case <-as.DoneCh:
+	default:
+		close(as.DoneCh)
+	}
+}
+
+func (c *Cluster) dial(addr string) (*grpc.ClientConn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if conn, ok := c.conns[addr]; ok {
+		return conn, nil
+	}
+	if c.clientTLS == nil {
+		return nil, errors.New("副本转发未启动")
+	}
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(credentials.NewTLS(c.clientTLS)))
+	if err != nil {
+		return nil, err
+	}
+	c.conns[addr] = conn
+	return conn, nil
+}
+
+// Relay 实现 AgentRelayServer：把其他副本的消息转发给本副本持有的Agent连接
+func (c *Cluster) Relay(stream pb.AgentRelay_RelayServer) error {
+	if !isRelayPeer(stream.Context()) {
+		return status.Error(codes.PermissionDenied, "仅允许OpsHub副本调用")
+	}
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	hostID := uint(first.HostId)
+	as, ok := c.hub.getLocal(hostID)
+	if !ok {
+		return status.Errorf(codes.NotFound, "Agent不在本副本: host_id=%d", hostID)
+	}
+	if err := stream.Send(&pb.AgentMessage{}); err != nil {
+		return err
+	}
+
+	r := &relayConn{sendCh: make(chan *pb.AgentMessage, relaySendBuffer), done: make(chan struct{})}
+	defer c.unbind(r)
+	recvErr := make(chan error, 1)
+	go func() {
+		frame := first
+		for {
+			if msg := frame.GetMessage(); msg != nil {
+				// 先记录路由再发给Agent，保证响应到达时能找到请求方
+				c.bind(serverMessageKey(msg), r)
+				if err := as.Send(msg); err != nil {
+					recvErr <- err
+					return
+				}
+			}
+			next, err := stream.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+			frame = next
+		}
+	}()
+
+	for {
+		select {
+		case msg := <-r.sendCh:
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+		case err := <-recvErr:
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		case <-as.DoneCh:
+			return status.Error(codes.Unavailable, "Agent连接已断开")
+		}
+	}
+}
+
+// relayResponse Agent的响应属于其他副本发起的请求时转发回去，返回是否已转发
+func (c *Cluster) relayResponse(msg *pb.AgentMessage) bool {
+	key := agentMessageKey(msg)
+	if key == "" {
+		return false
+	}
+	c.routeMu.Lock()
+	route, ok := c.routes[key]
+	if ok {
+		route.last = time.Now()
+	}
+	c.routeMu.Unlock()
+	if !ok {
+		return false
+	}
+	select {
+	case route.relay.sendCh <- msg:
+	case <-route.relay.done:
+	}
+	return true
+}
+
+func (c *Cluster) bind(key string, r *relayConn) {
+	if key == "" {
+		return
+	}
+	c.routeMu.Lock()
+	c.routes[key] = &relayRoute{relay: r, last: time.Now()}
+	c.routeMu.Unlock()
+}
+
+// unbind 转发流结束，移除其全部路由
+func (c *Cluster) unbind(r *relayConn) {
+	close(r.done)
+	c.routeMu.Lock()
+	defer c.routeMu.Unlock()
+	for key, route := range c.routes {
+		if route.relay == r {
+			delete(c.routes, key)
+		}
+	}
+}
+
+func (c *Cluster) sweepRoutes() {
+	deadline := time.Now().Add(-relayRouteIdle)
+	c.routeMu.Lock()
+	defer c.routeMu.Unlock()
+	for key, route := range c.routes {
+		if route.last.Before(deadline) {
+			delete(c.routes, key)
+		}
+	}
+}
+
+// isRelayPeer 调用方是否持有副本转发证书（而不是Agent证书）
+func isRelayPeer(ctx context.Context) bool {
+	cert := peerCertificate(ctx)
+	return cert != nil && slices.Contains(cert.Subject.Organization, relayCertOrganization)
+}
+
+// serverMessageKey 发往Agent的消息的关联ID，Agent的响应携带相同的ID
+func serverMessageKey(msg *pb.ServerMessage) string {
+	switch p := msg.Payload.(type) {
+	case *pb.ServerMessage_TermOpen:
+		return "term:" + p.TermOpen.SessionId
+	case *pb.ServerMessage_TermInput:
+		return "term:" + p.TermInput.SessionId
+	case *pb.ServerMessage_TermResize:
+		return "term:" + p.TermResize.SessionId
+	case *pb.ServerMessage_TermClose:
+		return "term:" + p.TermClose.SessionId
+	case *pb.ServerMessage_FileRequest:
+		return "req:" + p.FileRequest.RequestId
+	case *pb.ServerMessage_CmdRequest:
+		return "req:" + p.CmdRequest.RequestId
+	case *pb.ServerMessage_CmdCancel:
+		return "req:" + p.CmdCancel.RequestId
+	case *pb.ServerMessage_ProbeRequest:
+		return "req:" + p.ProbeRequest.RequestId
+	case *pb.ServerMessage_HttpProxyRequest:
+		return "req:" + p.HttpProxyRequest.RequestId
+	case *pb.ServerMessage_StreamProxyRequest:
+		return "req:" + p.StreamProxyRequest.RequestId
+	case *pb.ServerMessage_AgentUpgrade:
+		return "req:" + p.AgentUpgrade.RequestId
+	case *pb.ServerMessage_WsSessionOpen:
+		return "ws:" + p.WsSessionOpen.SessionId
+	case *pb.ServerMessage_WsSessionAction:
+		return "ws:" + p.WsSessionAction.SessionId
+	case *pb.ServerMessage_WsSessionClose:
+		return "ws:" + p.WsSessionClose.SessionId
+	case *pb.ServerMessage_TunnelOpen:
+		return "tunnel:" + p.TunnelOpen.ConnId
+	case *pb.ServerMessage_TunnelData:
+		return "tunnel:" + p.TunnelData.ConnId
+	case *pb.ServerMessage_TunnelAck:
+		return "tunnel:" + p.TunnelAck.ConnId
+	case *pb.ServerMessage_TunnelClose:
+		return "tunnel:" + p.TunnelClose.ConnId
+	}
+	return ""
+}
+
+// agentMessageKey Agent响应的关联ID，与 serverMessageKey 对应；注册、心跳等无关联ID
+func agentMessageKey(msg *pb.AgentMessage) string {
+	switch p := msg.Payload.(type) {
+	case *pb.AgentMessage_TermOutput:
+		return "term:" + p.TermOutput.SessionId
+	case *pb.AgentMessage_FileChunk:
+		return "req:" + p.FileChunk.RequestId
+	case *pb.AgentMessage_FileList:
+		return "req:" + p.FileList.RequestId
+	case *pb.AgentMessage_CmdResult:
+		return "req:" + p.CmdResult.RequestId
+	case *pb.AgentMessage_CmdOutput:
+		return "req:" + p.CmdOutput.RequestId
+	case *pb.AgentMessage_ProbeResult:
+		return "req:" + p.ProbeResult.RequestId
+	case *pb.AgentMessage_HttpProxyResponse:
+		return "req:" + p.HttpProxyResponse.RequestId
+	case *pb.AgentMessage_StreamProxyChunk:
+		return "req:" + p.StreamProxyChunk.RequestId
+	case *pb.AgentMessage_UpgradeResult:
+		return "req:" + p.UpgradeResult.RequestId
+	case *pb.AgentMessage_WsSessionResult:
+		return "ws:" + p.WsSessionResult.SessionId
+	case *pb.AgentMessage_TunnelOpenResult:
+		return "tunnel:" + p.TunnelOpenResult.ConnId
+	case *pb.AgentMessage_TunnelData:
+		return "tunnel:" + p.TunnelData.ConnId
+	case *pb.AgentMessage_TunnelAck:
+		return "tunnel:" + p.TunnelAck.ConnId
+	case *pb.AgentMessage_TunnelClose:
+		return "tunnel:" + p.TunnelClose.ConnId
+	}
+	return ""
+}
@@ -0,0 +1,259 @@
+package agent
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	pb "github.com/ydcloud-dy/opshub/pkg/agentproto"
+	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+// testReplica 进程内的一个 OpsHub 副本，只包含 hub、响应分发和转发服务
+type testReplica struct {
+	hub     *AgentHub
+	svc     *AgentService
+	cluster *Cluster
+	addr    string
+}
+
+func newTestReplica(t *testing.T, tlsMgr *TLSManager, rdb *redis.Client) *testReplica {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &testReplica{hub: NewAgentHub(), addr: lis.Addr().String()}
+	r.svc = &AgentService{hub: r.hub}
+	r.cluster = NewCluster(r.hub, rdb, r.addr)
+	r.cluster.dispatch = r.svc.dispatchResponse
+
+	serverTLS, err := tlsMgr.LoadServerTLSConfig(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer(grpc.Creds(credentials.NewTLS(serverTLS)))
+	pb.RegisterAgentRelayServer(srv, r.cluster)
+	go srv.Serve(lis)
+
+	clientTLS, err := tlsMgr.LoadRelayClientTLSConfig(r.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.cluster.Start(clientTLS)
+	t.Cleanup(func() {
+		r.cluster.Stop()
+		srv.Stop()
+	})
+	return r
+}
+
+// serveFakeAgent 模拟连接在副本上的Agent：应答拨测、回显终端输入、分块返回流式代理响应
+func serveFakeAgent(r *testReplica, as *AgentStream) {
+	reply := func(msg *pb.AgentMessage) { r.svc.handleResponse(as, msg) }
+	for {
+		select {
+		case msg := <-as.SendCh:
+			switch p := msg.Payload.(type) {
+			case *pb.ServerMessage_ProbeRequest:
+				reply(&pb.AgentMessage{Payload: &pb.AgentMessage_ProbeResult{ProbeResult: &pb.ProbeResult{
+					RequestId: p.ProbeRequest.RequestId, Success: true, FinalUrl: p.ProbeRequest.Url,
+				}}})
+			case *pb.ServerMessage_TermInput:
+				reply(&pb.AgentMessage{Payload: &pb.AgentMessage_TermOutput{TermOutput: &pb.TerminalOutput{
+					SessionId: p.TermInput.SessionId, Data: append([]byte("echo:"), p.TermInput.Data...),
+				}}})
+			case *pb.ServerMessage_StreamProxyRequest:
+				for i, part := range []string{"a", "b", "c"} {
+					reply(&pb.AgentMessage{Payload: &pb.AgentMessage_StreamProxyChunk{StreamProxyChunk: &pb.StreamProxyChunk{
+						RequestId: p.StreamProxyRequest.RequestId, Data: []byte(part), IsFinal: i == 2,
+					}}})
+				}
+			}
+		case <-as.DoneCh:
+			return
+		}
+	}
+}
+
+func newTestCluster(t *testing.T) (a, b *testReplica, mr *miniredis.Miniredis, tlsMgr *TLSManager) {
+	appLogger.Log = zap.NewNop()
+	mr = miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	tlsMgr = NewTLSManager(t.TempDir())
+	if err := tlsMgr.InitCA(); err != nil {
+		t.Fatal(err)
+	}
+	return newTestReplica(t, tlsMgr, rdb), newTestReplica(t, tlsMgr, rdb), mr, tlsMgr
+}
+
+func TestCluster_ForwardToOwnerReplica(t *testing.T) {
+	a, b, mr, _ := newTestCluster(t)
+
+	// Agent 连接在副本 B 上
+	as := b.hub.Register("agent-1", 7, nil)
+	go serveFakeAgent(b, as)
+	if owner := mr.HGet(agentOwnerPrefix+"7", "addr"); owner != b.addr {
+		t.Fatalf("登记的副本错误: %q", owner)
+	}
+	if !a.hub.IsOnline(7) || a.hub.IsOnline(8) {
+		t.Fatal("副本 A 应能查询到副本 B 上的Agent")
+	}
+
+	// 拨测请求 → WaitResponse
+	result, err := a.hub.SendProbeRequest(7, &pb.ProbeRequest{RequestId: "probe-1", Url: "http://example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Success || result.FinalUrl != "http://example.com" {
+		t.Fatalf("拨测结果错误: %+v", result)
+	}
+
+	// 终端输出经转发回到副本 A 注册的回调
+	output := make(chan string, 1)
+	a.hub.RegisterTerminalCallback("term-1", func(data []byte) { output <- string(data) })
+	proxy, ok := a.hub.GetByHostID(7)
+	if !ok {
+		t.Fatal("副本 A 应返回代理流")
+	}
+	proxy.Send(&pb.ServerMessage{Payload: &pb.ServerMessage_TermInput{TermInput: &pb.TerminalInput{SessionId: "term-1", Data: []byte("ls")}}})
+	select {
+	case data := <-output:
+		if data != "echo:ls" {
+			t.Fatalf("终端输出错误: %q", data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("未收到终端输出")
+	}
+
+	// 流式代理响应
+	chunks, err := a.hub.StreamResponse(proxy, "stream-1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy.Send(&pb.ServerMessage{Payload: &pb.ServerMessage_StreamProxyRequest{StreamProxyRequest: &pb.StreamProxyRequest{RequestId: "stream-1"}}})
+	var body string
+	timeout := time.After(5 * time.Second)
+	for done := false; !done; {
+		select {
+		case chunk, ok := <-chunks:
+			if !ok {
+				done = true
+				break
+			}
+			body += string(chunk.Data)
+		case <-timeout:
+			t.Fatal("流式响应超时")
+		}
+	}
+	if body != "abc" {
+		t.Fatalf("流式响应错误: %q", body)
+	}
+
+	// Agent 从副本 B 断开：代理流关闭，登记被清除
+	b.hub.Unregister("agent-1")
+	select {
+	case <-proxy.DoneCh:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Agent断开后代理流应关闭")
+	}
+	if mr.Exists(agentOwnerPrefix + "7") {
+		t.Fatal("Agent断开后应清除登记")
+	}
+	if _, ok := a.hub.GetByHostID(7); ok {
+		t.Fatal("Agent断开后不应再返回代理流")
+	}
+}
+
+func TestCluster_ReleaseKeepsNewOwner(t *testing.T) {
+	a, b, mr, _ := newTestCluster(t)
+
+	a.hub.Register("agent-1", 7, nil)
+	// Agent 重连到副本 B 后，副本 A 上的旧连接才注销
+	b.hub.Register("agent-1", 7, nil)
+	a.hub.Unregister("agent-1")
+	if owner := mr.HGet(agentOwnerPrefix+"7", "addr"); owner != b.addr {
+		t.Fatalf("旧副本注销不应清除新副本的登记, 实际 %q", owner)
+	}
+
+	// 登记过期后由续期恢复
+	mr.FastForward(agentOwnerTTL + time.Second)
+	if mr.Exists(agentOwnerPrefix + "7") {
+		t.Fatal("登记应过期")
+	}
+	b.cluster.refresh()
+	if owner := mr.HGet(agentOwnerPrefix+"7", "addr"); owner != b.addr {
+		t.Fatalf("续期后应恢复登记, 实际 %q", owner)
+	}
+}
+
+func TestCluster_RejectAgentCertificate(t *testing.T) {
+	_, b, _, tlsMgr := newTestCluster(t)
+	b.hub.Register("agent-1", 7, nil)
+
+	// 持有Agent证书的客户端不能调用转发服务
+	certPEM, keyPEM, err := tlsMgr.SignAgentCert("agent-evil", "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientTLS, err := tlsMgr.LoadRelayClientTLSConfig("unused")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientTLS.Certificates = []tls.Certificate{cert}
+	conn, err := grpc.NewClient(b.addr, grpc.WithTransportCredentials(credentials.NewTLS(clientTLS)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	stream, err := pb.NewAgentRelayClient(conn).Relay(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.Send(&pb.RelayFrame{HostId: 7})
+	if _, err := stream.Recv(); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("期望 PermissionDenied, 实际 %v", err)
+	}
+}
+
+func TestServerCertAddresses_IncludesRelayHost(t *testing.T) {
+	appLogger.Log = zap.NewNop()
+	if got := serverCertAddresses([]string{"srehub.agent"}, "10.0.0.5:9091"); len(got) != 2 || got[1] != "10.0.0.5" {
+		t.Fatalf("serverCertAddresses() = %v", got)
+	}
+	if got := serverCertAddresses([]string{"10.0.0.5"}, "10.0.0.5:9091"); len(got) != 1 {
+		t.Fatalf("重复地址不应再次加入: %v", got)
+	}
+
+	tlsMgr := NewTLSManager(t.TempDir())
+	if err := tlsMgr.InitCA(); err != nil {
+		t.Fatal(err)
+	}
+	serverTLS, err := tlsMgr.LoadServerTLSConfig(serverCertAddresses(nil, "opshub-1.internal:9091"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(serverTLS.Certificates[0].Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 其他副本按 relay_addr 拨号时以其主机名校验证书
+	if err := leaf.VerifyHostname("opshub-1.internal"); err != nil {
+		t.Fatalf("证书未包含 relay_addr 主机: %v", err)
+	}
+}
@@ -0,0 +1,294 @@
+package agent
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	pb "github.com/ydcloud-dy/opshub/pkg/agentproto"
+	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxStreamCommandTimeout 流式命令最长执行时间(秒)
+	maxStreamCommandTimeout = 24 * 3600
+	// streamWriteTimeout 向浏览器写出单条消息的超时
+	streamWriteTimeout = 10 * time.Second
+	// maxRecordedStreamOutput 执行记录保存的输出上限
+	maxRecordedStreamOutput = 1 << 20
+)
+
+// commandStreamStart 浏览器发送的第一条消息
+type commandStreamStart struct {
+	Command        string `json:"command"`
+	Timeout        int    `json:"timeout"`
+	MaxOutputBytes int64  `json:"maxOutputBytes"`
+}
+
+// commandStreamEvent 推送给浏览器的消息
+// type: started / output / exit / error
+type commandStreamEvent struct {
+	Type       string `json:"type"`
+	RequestID  string `json:"requestId,omitempty"`
+	Seq        int64  `json:"seq,omitempty"`
+	Stream     string `json:"stream,omitempty"`
+	Data       string `json:"data,omitempty"`
+	ExitCode   int32  `json:"exitCode"`
+	Signal     string `json:"signal,omitempty"`
+	Error      string `json:"error,omitempty"`
+	Truncated  bool   `json:"truncated,omitempty"`
+	Canceled   bool   `json:"canceled,omitempty"`
+	TimedOut   bool   `json:"timedOut,omitempty"`
+	DurationMs int64  `json:"durationMs,omitempty"`
+}
+
+// CommandStreamHooks 流式执行前的校验和结束后的记录，由任务中心提供
+type CommandStreamHooks struct {
+	// Begin 收到命令后、下发Agent前调用，返回错误时拒绝执行
+	Begin func(command string) error
+	// Finish 命令下发后调用一次，包括取消、超时和连接异常
+	Finish func(result *CommandStreamResult)
+}
+
+// CommandStreamResult 流式命令的执行结果，Output 为按到达顺序合并的 stdout/stderr
+type CommandStreamResult struct {
+	ExitCode  int32
+	Output    string
+	Error     string
+	Truncated bool
+	Canceled  bool
+	TimedOut  bool
+	Duration  time.Duration
+}
+
+// ServeCommandStream 通过WebSocket流式执行Agent命令
+// 连接建立后客户端先发送 {"command","timeout","maxOutputBytes"}，之后可发送 {"type":"cancel"} 取消，
+// 关闭连接同样会取消命令
+func ServeCommandStream(c *gin.Context, hub *AgentHub, hostID uint, hooks CommandStreamHooks) {
+	as, ok := hub.GetByHostID(hostID)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "Agent不在线"})
+		return
+	}
+
+	conn, err := wsUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		appLogger.Error("WebSocket升级失败", zap.Error(err))
+		return
+	}
+	defer conn.Close()
+
+	var connMu sync.Mutex
+	write := func(ev *commandStreamEvent) {
+		connMu.Lock()
+		defer connMu.Unlock()
+		conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		conn.WriteJSON(ev)
+	}
+
+	var start commandStreamStart
+	conn.SetReadDeadline(time.Now().Add(30 * time.Second))
+	if err := conn.ReadJSON(&start); err != nil || start.Command == "" {
+		write(&commandStreamEvent{Type: "error", Error: "参数错误：缺少 command"})
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+	if start.Timeout <= 0 {
+		start.Timeout = 60
+	}
+	if start.Timeout > maxStreamCommandTimeout {
+		start.Timeout = maxStreamCommandTimeout
+	}
+	if hooks.Begin != nil {
+		if err := hooks.Begin(start.Command); err != nil {
+			write(&commandStreamEvent{Type: "error", Error: err.Error()})
+			return
+		}
+	}
+
+	requestID := uuid.New().String()
+	done := make(chan struct{})
+	var doneOnce sync.Once
+	finish := func() { doneOnce.Do(func() { close(done) }) }
+	startedAt := time.Now()
+
+	// 执行记录保存的输出，超过上限的部分丢弃
+	var (
+		resultMu sync.Mutex
+		result   CommandStreamResult
+		output   []byte
+	)
+	record := func(data []byte) {
+		resultMu.Lock()
+		defer resultMu.Unlock()
+		if room := maxRecordedStreamOutput - len(output); room < len(data) {
+			data = data[:max(room, 0)]
+			result.Truncated = true
+		}
+		output = append(output, data...)
+	}
+	defer func() {
+		if hooks.Finish == nil {
+			return
+		}
+		resultMu.Lock()
+		result.Output = string(output)
+		result.Duration = time.Since(startedAt)
+		r := result
+		resultMu.Unlock()
+		hooks.Finish(&r)
+	}()
+
+	// 按 stdout/stderr 分别缓存不完整的 UTF-8 尾部，避免多字节字符被分片截断
+	carry := map[string][]byte{}
+	hub.RegisterCommandCallback(requestID, func(chunk *pb.CommandOutputChunk) {
+		if chunk.Eof {
+			for name, rest := range carry {
+				if len(rest) > 0 {
+					record(rest)
+					write(&commandStreamEvent{Type: "output", Stream: name, Data: string(rest)})
+				}
+			}
+			resultMu.Lock()
+			result.ExitCode, result.Error = chunk.ExitCode, chunk.Error
+			result.Truncated = result.Truncated || chunk.Truncated
+			result.Canceled, result.TimedOut = chunk.Canceled, chunk.TimedOut
+			resultMu.Unlock()
+			write(&commandStreamEvent{
+				Type:       "exit",
+				ExitCode:   chunk.ExitCode,
+				Signal:     chunk.Signal,
+				Error:      chunk.Error,
+				Truncated:  chunk.Truncated,
+				Canceled:   chunk.Canceled,
+				TimedOut:   chunk.TimedOut,
+				DurationMs: time.Since(startedAt).Milliseconds(),
+			})
+			finish()
+			return
+		}
+		data, rest := splitIncompleteUTF8(append(carry[chunk.Stream], chunk.Data...))
+		carry[chunk.Stream] = rest
+		if len(data) > 0 {
+			record(data)
+			write(&commandStreamEvent{Type: "output", Seq: chunk.Seq, Stream: chunk.Stream, Data: string(data)})
+		}
+	})
+	defer hub.UnregisterCommandCallback(requestID)
+
+	// 旧版本 Agent 不支持流式执行，会直接返回 CommandResult
+	legacy := as.RegisterPending(requestID)
+	defer func() {
+		as.pendMu.Lock()
+		delete(as.pending, requestID)
+		as.pendMu.Unlock()
+	}()
+
+	// 下发失败时同样记录结果
+	fail := func(msg string) {
+		resultMu.Lock()
+		result.ExitCode, result.Error = -1, msg
+		resultMu.Unlock()
+		write(&commandStreamEvent{Type: "error", Error: msg})
+	}
+
+	if err := as.Send(&pb.ServerMessage{
+		Payload: &pb.ServerMessage_CmdRequest{
+			CmdRequest: &pb.CommandRequest{
+				RequestId:      requestID,
+				Command:        start.Command,
+				Timeout:        int32(start.Timeout),
+				Stream:         true,
+				MaxOutputBytes: start.MaxOutputBytes,
+			},
+		},
+	}); err != nil {
+		fail(err.Error())
+		return
+	}
+	write(&commandStreamEvent{Type: "started", RequestID: requestID})
+
+	cancel := func() {
+		as.Send(&pb.ServerMessage{
+			Payload: &pb.ServerMessage_CmdCancel{CmdCancel: &pb.CommandCancel{RequestId: requestID}},
+		})
+	}
+
+	// 读取浏览器消息：取消指令或连接关闭
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var msg struct {
+				Type string `json:"type"`
+			}
+			if json.Unmarshal(data, &msg) == nil && msg.Type == "cancel" {
+				cancel()
+			}
+		}
+	}()
+
+	timer := time.NewTimer(time.Duration(start.Timeout+30) * time.Second)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case res := <-legacy:
+		if r, ok := res.(*pb.CommandResult); ok {
+			if r.Stdout != "" {
+				record([]byte(r.Stdout))
+				write(&commandStreamEvent{Type: "output", Stream: "stdout", Data: r.Stdout})
+			}
+			if r.Stderr != "" {
+				record([]byte(r.Stderr))
+				write(&commandStreamEvent{Type: "output", Stream: "stderr", Data: r.Stderr})
+			}
+			resultMu.Lock()
+			result.ExitCode, result.Error = r.ExitCode, r.Error
+			resultMu.Unlock()
+			write(&commandStreamEvent{Type: "exit", ExitCode: r.ExitCode, Error: r.Error, DurationMs: time.Since(startedAt).Milliseconds()})
+		}
+	case <-closed:
+		appLogger.Info("流式命令WebSocket关闭，取消命令", zap.String("requestID", requestID))
+		cancel()
+		resultMu.Lock()
+		result.ExitCode, result.Canceled, result.Error = -1, true, "连接已关闭，命令已取消"
+		resultMu.Unlock()
+	case <-timer.C:
+		cancel()
+		fail("等待Agent响应超时")
+	case <-as.DoneCh:
+		fail("Agent连接已断开")
+	}
+	connMu.Lock()
+	conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+	conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	connMu.Unlock()
+}
+
+// splitIncompleteUTF8 拆出末尾不完整的 UTF-8 字符，留待与下一个分片拼接
+func splitIncompleteUTF8(b []byte) ([]byte, []byte) {
+	// UTF-8 字符最长 4 字节，只需检查末尾 3 个字节
+	for i := 1; i <= 3 && i <= len(b); i++ {
+		c := b[len(b)-i]
+		if c < utf8.RuneSelf {
+			break
+		}
+		if utf8.RuneStart(c) {
+			if !utf8.FullRune(b[len(b)-i:]) {
+				return b[:len(b)-i], b[len(b)-i:]
+			}
+			break
+		}
+	}
+	return b, nil
+}
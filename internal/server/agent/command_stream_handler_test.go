@@ -0,0 +1,201 @@
+package agent
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	pb "github.com/ydcloud-dy/opshub/pkg/agentproto"
+	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
+	"go.uber.org/zap"
+)
+
+func TestSplitIncompleteUTF8(t *testing.T) {
+	zh := []byte("中") // 3 字节
+	tests := []struct {
+		in   []byte
+		data string
+		rest int
+	}{
+		{[]byte("abc"), "abc", 0},
+		{append([]byte("a"), zh...), "a中", 0},
+		{append([]byte("a"), zh[:1]...), "a", 1},
+		{append([]byte("a"), zh[:2]...), "a", 2},
+		{[]byte{0xff}, "\xff", 0},
+	}
+	for _, tt := range tests {
+		data, rest := splitIncompleteUTF8(tt.in)
+		if string(data) != tt.data || len(rest) != tt.rest {
+			t.Errorf("splitIncompleteUTF8(%q) = %q,%q", tt.in, data, rest)
+		}
+	}
+}
+
+// fakeAgent 模拟Agent：收到流式命令后按 script 回放输出，收到取消后返回 canceled
+func fakeAgent(t *testing.T, hub *AgentHub, as *AgentStream, script func(requestID string)) {
+	for msg := range as.SendCh {
+		switch p := msg.Payload.(type) {
+		case *pb.ServerMessage_CmdRequest:
+			if !p.CmdRequest.Stream {
+				t.Errorf("command request not streamed")
+			}
+			go script(p.CmdRequest.RequestId)
+		case *pb.ServerMessage_CmdCancel:
+			hub.HandleCommandOutput(&pb.CommandOutputChunk{RequestId: p.CmdCancel.RequestId, Eof: true, ExitCode: -1, Signal: "killed", Canceled: true})
+		}
+	}
+}
+
+func dialCommandStream(t *testing.T, hub *AgentHub) *websocket.Conn {
+	return dialCommandStreamWithHooks(t, hub, CommandStreamHooks{})
+}
+
+func dialCommandStreamWithHooks(t *testing.T, hub *AgentHub, hooks CommandStreamHooks) *websocket.Conn {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/agents/:hostId/execute/stream", func(c *gin.Context) { ServeCommandStream(c, hub, 1, hooks) })
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/agents/1/execute/stream", nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return conn
+}
+
+func readEvents(t *testing.T, conn *websocket.Conn) []commandStreamEvent {
+	var events []commandStreamEvent
+	for {
+		var ev commandStreamEvent
+		if err := conn.ReadJSON(&ev); err != nil {
+			return events
+		}
+		events = append(events, ev)
+		if ev.Type == "exit" || ev.Type == "error" {
+			return events
+		}
+	}
+}
+
+func TestExecuteCommandStream(t *testing.T) {
+	appLogger.Log = zap.NewNop()
+	hub := NewAgentHub()
+	as := hub.Register("agent-1", 1, nil)
+	zh := []byte("中")
+	go fakeAgent(t, hub, as, func(id string) {
+		hub.HandleCommandOutput(&pb.CommandOutputChunk{RequestId: id, Seq: 1, Stream: "stdout", Data: append([]byte("a"), zh[:2]...)})
+		hub.HandleCommandOutput(&pb.CommandOutputChunk{RequestId: id, Seq: 2, Stream: "stderr", Data: []byte("warn")})
+		hub.HandleCommandOutput(&pb.CommandOutputChunk{RequestId: id, Seq: 3, Stream: "stdout", Data: append(zh[2:], 'b')})
+		hub.HandleCommandOutput(&pb.CommandOutputChunk{RequestId: id, Seq: 4, Eof: true, ExitCode: 3, Truncated: true})
+	})
+	defer hub.Unregister("agent-1")
+
+	conn := dialCommandStream(t, hub)
+	conn.WriteJSON(commandStreamStart{Command: "make upgrade"})
+	events := readEvents(t, conn)
+
+	var got []string
+	for _, ev := range events {
+		switch ev.Type {
+		case "output":
+			got = append(got, ev.Stream+":"+ev.Data)
+		case "exit":
+			if ev.ExitCode != 3 || !ev.Truncated {
+				t.Errorf("exit event = %+v", ev)
+			}
+			got = append(got, "exit")
+		default:
+			got = append(got, ev.Type)
+		}
+	}
+	if want := "started stdout:a stderr:warn stdout:中b exit"; strings.Join(got, " ") != want {
+		t.Errorf("events = %v, want %s", got, want)
+	}
+}
+
+func TestExecuteCommandStream_Cancel(t *testing.T) {
+	appLogger.Log = zap.NewNop()
+	hub := NewAgentHub()
+	as := hub.Register("agent-2", 1, nil)
+	go fakeAgent(t, hub, as, func(id string) {
+		hub.HandleCommandOutput(&pb.CommandOutputChunk{RequestId: id, Seq: 1, Stream: "stdout", Data: []byte("running")})
+	})
+	defer hub.Unregister("agent-2")
+
+	conn := dialCommandStream(t, hub)
+	conn.WriteJSON(commandStreamStart{Command: "sleep 100"})
+	var ev commandStreamEvent
+	for ev.Type != "output" {
+		if err := conn.ReadJSON(&ev); err != nil {
+			t.Fatalf("read: %v", err)
+		}
+	}
+	conn.WriteJSON(map[string]string{"type": "cancel"})
+	events := readEvents(t, conn)
+	last := events[len(events)-1]
+	if last.Type != "exit" || !last.Canceled || last.Signal != "killed" {
+		t.Errorf("last event = %+v", last)
+	}
+
+	// 缺少命令时直接返回错误
+	conn = dialCommandStream(t, hub)
+	conn.WriteJSON(commandStreamStart{})
+	if events := readEvents(t, conn); len(events) != 1 || events[0].Type != "error" {
+		t.Errorf("events = %+v", events)
+	}
+}
+
+func TestExecuteCommandStream_Hooks(t *testing.T) {
+	appLogger.Log = zap.NewNop()
+	hub := NewAgentHub()
+	as := hub.Register("agent-3", 1, nil)
+	go fakeAgent(t, hub, as, func(id string) {
+		hub.HandleCommandOutput(&pb.CommandOutputChunk{RequestId: id, Seq: 1, Stream: "stdout", Data: []byte("out ")})
+		hub.HandleCommandOutput(&pb.CommandOutputChunk{RequestId: id, Seq: 2, Stream: "stderr", Data: []byte("err")})
+		hub.HandleCommandOutput(&pb.CommandOutputChunk{RequestId: id, Seq: 3, Eof: true, ExitCode: 2})
+	})
+	defer hub.Unregister("agent-3")
+
+	// 校验拒绝的命令不下发，也不产生执行结果
+	var finished []*CommandStreamResult
+	finishedCh := make(chan struct{}, 1)
+	hooks := CommandStreamHooks{
+		Begin: func(command string) error {
+			if strings.Contains(command, "reboot") {
+				return errors.New("命令被拦截")
+			}
+			return nil
+		},
+		Finish: func(r *CommandStreamResult) {
+			finished = append(finished, r)
+			finishedCh <- struct{}{}
+		},
+	}
+	conn := dialCommandStreamWithHooks(t, hub, hooks)
+	conn.WriteJSON(commandStreamStart{Command: "reboot"})
+	if events := readEvents(t, conn); len(events) != 1 || events[0].Type != "error" || events[0].Error != "命令被拦截" {
+		t.Fatalf("events = %+v", events)
+	}
+
+	conn = dialCommandStreamWithHooks(t, hub, hooks)
+	conn.WriteJSON(commandStreamStart{Command: "make check"})
+	readEvents(t, conn)
+	select {
+	case <-finishedCh:
+	case <-time.After(5 * time.Second):
+		t.Fatal("finish hook not called")
+	}
+	if len(finished) != 1 {
+		t.Fatalf("finish called %d times", len(finished))
+	}
+	if r := finished[0]; r.ExitCode != 2 || r.Output != "out err" {
+		t.Errorf("result = %+v", r)
+	}
+}
@@ -15,11 +15,21 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	agentmodel "github.com/ydcloud-dy/opshub/internal/agent"
+	assetbiz "github.com/ydcloud-dy/opshub/internal/biz/asset"
+	rbacService "github.com/ydcloud-dy/opshub/internal/service/rbac"
 	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
-	sshclient "github.com/ydcloud-dy/opshub/pkg/ssh"
 	"go.uber.org/zap"
 )
 
+// deploySSHContext 记录发起部署的当前用户，CA签发的SSH证书按该用户及其角色授权
+func deploySSHContext(c *gin.Context) context.Context {
+	return assetbiz.WithSSHIdentity(c.Request.Context(), assetbiz.SSHIdentity{
+		UserID:   rbacService.GetUserID(c),
+		Username: rbacService.GetUsername(c),
+		Source:   "agent-deploy",
+	})
+}
+
 // DeployAgent 部署Agent到目标主机
 func (s *HTTPServer) DeployAgent(c *gin.Context) {
 	hostIDStr := c.Param("hostId")
@@ -34,7 +44,7 @@ func (s *HTTPServer) DeployAgent(c *gin.Context) {
 	}
 	c.ShouldBindJSON(&req)
 
-	if err := s.deployToHost(c.Request.Context(), uint(hostID), req.ServerAddr); err != nil {
+	if err := s.deployToHost(deploySSHContext(c), uint(hostID), req.ServerAddr); err != nil {
 		appLogger.Error("部署Agent失败", zap.Uint64("hostID", hostID), zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": fmt.Sprintf("部署失败: %v", err)})
 		return
@@ -56,7 +66,7 @@ func (s *HTTPServer) BatchDeployAgent(c *gin.Context) {
 
 	results := make([]map[string]any, 0, len(req.HostIDs))
 	for _, hostID := range req.HostIDs {
-		err := s.deployToHost(c.Request.Context(), hostID, req.ServerAddr)
+		err := s.deployToHost(deploySSHContext(c), hostID, req.ServerAddr)
 		result := map[string]any{"hostId": hostID, "success": err == nil}
 		if err != nil {
 			result["error"] = err.Error()
@@ -76,18 +86,18 @@ func (s *HTTPServer) deployToHost(ctx context.Context, hostID uint, serverAddr s
 		return fmt.Errorf("获取主机信息失败: %w", err)
 	}
 
-	// 获取解密后的凭证
-	credentialRepo := s.hostUseCase.GetCredentialRepo()
-	credential, err := credentialRepo.GetByIDDecrypted(ctx, hostVO.CredentialID)
+	// SSH连接目标主机，配置了跳板链时经跳板连接
+	client, err := s.hostUseCase.ConnectHost(ctx, hostID)
 	if err != nil {
-		return fmt.Errorf("获取凭证失败: %w", err)
+		return fmt.Errorf("SSH连接失败: %w", err)
 	}
+	defer client.Close()
 
 	// 生成AgentID
 	agentID := uuid.New().String()
 
 	// 签发Agent证书
-	certPEM, keyPEM, err := s.tlsMgr.SignAgentCert(agentID, hostVO.IP)
+	certPEM, keyPEM, err := s.certs.Issue(agentID, hostVO.IP)
 	if err != nil {
 		return fmt.Errorf("签发证书失败: %w", err)
 	}
@@ -97,20 +107,12 @@ func (s *HTTPServer) deployToHost(ctx context.Context, hostID uint, serverAddr s
 		return fmt.Errorf("获取CA证书失败: %w", err)
 	}
 
-	// SSH连接目标主机
-	var privateKey []byte
-	if credential.PrivateKey != "" {
-		privateKey = []byte(credential.PrivateKey)
-	}
-	client, err := sshclient.NewClient(hostVO.IP, hostVO.Port, hostVO.SSHUser, credential.Password, privateKey, credential.Passphrase)
-	if err != nil {
-		return fmt.Errorf("SSH连接失败: %w", err)
-	}
-	defer client.Close()
-
-	// 获取服务端地址：优先使用用户指定的地址，否则自动检测
+	// 获取服务端地址：优先使用用户指定的地址，否则自动检测。
+	// 经跳板连接时 SSH_CLIENT 是最后一跳的地址，不能作为服务端地址
 	if serverAddr == "" {
-		if out, err := client.Execute("echo $SSH_CLIENT | awk '{print $1}'"); err == nil {
+		if client.ViaJump() {
+			appLogger.Warn("主机经跳板连接，无法自动检测服务端地址，请部署时指定", zap.Uint("hostID", hostID))
+		} else if out, err := client.Execute("echo $SSH_CLIENT | awk '{print $1}'"); err == nil {
 			addr := strings.TrimSpace(out)
 			if addr != "" {
 				serverAddr = addr
@@ -130,7 +132,7 @@ func (s *HTTPServer) deployToHost(ctx context.Context, hostID uint, serverAddr s
 	deployPath := s.grpcServer.conf.Agent.DeployPath
 
 	// 查找本地tar.gz安装包
-	tarballPath, err := s.findAgentTarball()
+	tarballPath, err := findAgentTarball(s.grpcServer.conf.Agent.BinaryDir)
 	if err != nil {
 		return fmt.Errorf("查找Agent安装包失败: %w", err)
 	}
@@ -217,8 +219,7 @@ func (s *HTTPServer) deployToHost(ctx context.Context, hostID uint, serverAddr s
 
 // findAgentTarball 查找Agent安装包tar.gz
 // 优先查找多架构安装包（multi-arch），如果没有则查找任意 .tar.gz
-func (s *HTTPServer) findAgentTarball() (string, error) {
-	binaryDir := s.grpcServer.conf.Agent.BinaryDir
+func findAgentTarball(binaryDir string) (string, error) {
 	entries, err := os.ReadDir(binaryDir)
 	if err != nil {
 		return "", fmt.Errorf("读取目录 %s 失败: %w", binaryDir, err)
@@ -274,7 +275,7 @@ func (s *HTTPServer) UpdateAgent(c *gin.Context) {
 	}
 	c.ShouldBindJSON(&req)
 
-	if err := s.updateAgentOnHost(c.Request.Context(), uint(hostID), req.ServerAddr); err != nil {
+	if err := s.updateAgentOnHost(deploySSHContext(c), uint(hostID), req.ServerAddr); err != nil {
 		appLogger.Error("更新Agent失败", zap.Uint64("hostID", hostID), zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": fmt.Sprintf("更新失败: %v", err)})
 		return
@@ -292,7 +293,7 @@ func (s *HTTPServer) UninstallAgent(c *gin.Context) {
 		return
 	}
 
-	if err := s.uninstallAgentOnHost(c.Request.Context(), uint(hostID)); err != nil {
+	if err := s.uninstallAgentOnHost(deploySSHContext(c), uint(hostID)); err != nil {
 		appLogger.Error("卸载Agent失败", zap.Uint64("hostID", hostID), zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": fmt.Sprintf("卸载失败: %v", err)})
 		return
@@ -309,23 +310,13 @@ func (s *HTTPServer) updateAgentOnHost(ctx context.Context, hostID uint, serverA
 		return fmt.Errorf("获取主机信息失败: %w", err)
 	}
 
-	credentialRepo := s.hostUseCase.GetCredentialRepo()
-	credential, err := credentialRepo.GetByIDDecrypted(ctx, hostVO.CredentialID)
-	if err != nil {
-		return fmt.Errorf("获取凭证失败: %w", err)
-	}
-
-	var privateKey []byte
-	if credential.PrivateKey != "" {
-		privateKey = []byte(credential.PrivateKey)
-	}
-	client, err := sshclient.NewClient(hostVO.IP, hostVO.Port, hostVO.SSHUser, credential.Password, privateKey, credential.Passphrase)
+	client, err := s.hostUseCase.ConnectHost(ctx, hostID)
 	if err != nil {
 		return fmt.Errorf("SSH连接失败: %w", err)
 	}
 	defer client.Close()
 
-	tarballPath, err := s.findAgentTarball()
+	tarballPath, err := findAgentTarball(s.grpcServer.conf.Agent.BinaryDir)
 	if err != nil {
 		return fmt.Errorf("查找Agent安装包失败: %w", err)
 	}
@@ -405,6 +396,13 @@ func (s *HTTPServer) updateAgentOnHost(ctx context.Context, hostID uint, serverA
 		}
 	}
 
+	// 写入配置的发布签名公钥（替换旧配置中的公钥），之后可通过Agent通道远程升级
+	if _, err := upgradePublicKey(s.grpcServer.conf); err == nil {
+		keyCmd := fmt.Sprintf("sudo sed -i '/^upgrade_public_key:/d' %s/agent.yaml && echo 'upgrade_public_key: \"%s\"' | sudo tee -a %s/agent.yaml > /dev/null",
+			deployPath, s.grpcServer.conf.Agent.UpgradePublicKey, deployPath)
+		client.Execute(keyCmd)
+	}
+
 	client.Execute("sudo systemctl start srehub-agent")
 
 	// 清理临时目录
@@ -416,22 +414,7 @@ func (s *HTTPServer) updateAgentOnHost(ctx context.Context, hostID uint, serverA
 
 // uninstallAgentOnHost 卸载单台主机上的Agent
 func (s *HTTPServer) uninstallAgentOnHost(ctx context.Context, hostID uint) error {
-	hostVO, err := s.hostUseCase.GetByID(ctx, hostID)
-	if err != nil {
-		return fmt.Errorf("获取主机信息失败: %w", err)
-	}
-
-	credentialRepo := s.hostUseCase.GetCredentialRepo()
-	credential, err := credentialRepo.GetByIDDecrypted(ctx, hostVO.CredentialID)
-	if err != nil {
-		return fmt.Errorf("获取凭证失败: %w", err)
-	}
-
-	var privateKey []byte
-	if credential.PrivateKey != "" {
-		privateKey = []byte(credential.PrivateKey)
-	}
-	client, err := sshclient.NewClient(hostVO.IP, hostVO.Port, hostVO.SSHUser, credential.Password, privateKey, credential.Passphrase)
+	client, err := s.hostUseCase.ConnectHost(ctx, hostID)
 	if err != nil {
 		return fmt.Errorf("SSH连接失败: %w", err)
 	}
@@ -474,16 +457,25 @@ func (s *HTTPServer) renderAgentConfig(agentID, serverAddr string) ([]byte, erro
 server_addr: "{{.ServerAddr}}"
 cert_dir: "{{.DeployPath}}/certs"
 log_file: "/var/log/srehub-agent.log"
+upgrade_public_key: "{{.UpgradePublicKey}}"
 `
 	t, err := template.New("agent").Parse(tmpl)
 	if err != nil {
 		return nil, err
 	}
+	// 写入发布签名公钥，Agent据此校验远程升级包
+	publicKey := ""
+	if _, err := upgradePublicKey(s.grpcServer.conf); err != nil {
+		appLogger.Warn("发布签名公钥不可用，Agent将无法远程升级", zap.Error(err))
+	} else {
+		publicKey = s.grpcServer.conf.Agent.UpgradePublicKey
+	}
 	var buf bytes.Buffer
 	err = t.Execute(&buf, map[string]any{
-		"AgentID":    agentID,
-		"ServerAddr": finalServerAddr,
-		"DeployPath": deployPath,
+		"AgentID":          agentID,
+		"ServerAddr":       finalServerAddr,
+		"DeployPath":       deployPath,
+		"UpgradePublicKey": publicKey,
 	})
 	return buf.Bytes(), err
 }
@@ -0,0 +1,132 @@
+package agent
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	agentmodel "github.com/ydcloud-dy/opshub/internal/agent"
+	"github.com/ydcloud-dy/opshub/internal/biz/asset"
+	pb "github.com/ydcloud-dy/opshub/pkg/agentproto"
+	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// handleServiceDiscovery 保存Agent上报的服务发现结果
+func (s *AgentService) handleServiceDiscovery(as *AgentStream, d *pb.ServiceDiscovery) {
+	if as.HostID == 0 {
+		return
+	}
+	seenAt := time.Now()
+	if d.Timestamp > 0 {
+		seenAt = time.Unix(d.Timestamp, 0)
+	}
+	if err := saveDiscoveredServices(s.db, as.HostID, d.Services, seenAt); err != nil {
+		appLogger.Error("保存服务发现结果失败", zap.Uint("hostID", as.HostID), zap.Error(err))
+		return
+	}
+	appLogger.Debug("服务发现结果已更新", zap.Uint("hostID", as.HostID), zap.Int("services", len(d.Services)))
+}
+
+// saveDiscoveredServices 以上报结果为准更新主机的服务列表：新服务插入，已有服务刷新，
+// 本次未上报的服务标记为 gone（保留记录便于查看服务消失时间）
+func saveDiscoveredServices(db *gorm.DB, hostID uint, services []*pb.DiscoveredService, seenAt time.Time) error {
+	var records []*agentmodel.AgentDiscoveredService
+	incoming := make(map[string]*agentmodel.AgentDiscoveredService, len(services))
+	for _, svc := range services {
+		record := discoveredRecord(hostID, svc, seenAt)
+		if _, dup := incoming[record.ServiceKey]; dup {
+			continue
+		}
+		records = append(records, record)
+		incoming[record.ServiceKey] = record
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var existing []agentmodel.AgentDiscoveredService
+		if err := tx.Where("host_id = ?", hostID).Find(&existing).Error; err != nil {
+			return err
+		}
+		for _, old := range existing {
+			record, ok := incoming[old.ServiceKey]
+			if !ok {
+				if old.Status != agentmodel.DiscoveredServiceGone {
+					if err := tx.Model(&old).Update("status", agentmodel.DiscoveredServiceGone).Error; err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			delete(incoming, old.ServiceKey)
+			err := tx.Model(&old).Updates(map[string]any{
+				"name":         record.Name,
+				"process":      record.Process,
+				"pid":          record.PID,
+				"cmdline":      record.Cmdline,
+				"listen_addrs": record.ListenAddrs,
+				"exporter_for": record.ExporterFor,
+				"status":       agentmodel.DiscoveredServiceActive,
+				"last_seen":    seenAt,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		for _, record := range records {
+			if _, ok := incoming[record.ServiceKey]; !ok {
+				continue // 已更新
+			}
+			if err := tx.Create(record).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// discoveredRecord 转换上报的服务，服务键由类型、名称和主端口组成，进程重启后保持不变
+func discoveredRecord(hostID uint, svc *pb.DiscoveredService, seenAt time.Time) *agentmodel.AgentDiscoveredService {
+	port := primaryPort(svc)
+	addrs := make([]string, 0, len(svc.Listen))
+	for _, l := range svc.Listen {
+		addrs = append(addrs, net.JoinHostPort(l.Ip, strconv.Itoa(int(l.Port))))
+	}
+	sort.Strings(addrs)
+	listenAddrs := strings.Join(addrs, ",")
+	if len(listenAddrs) > 1000 {
+		listenAddrs = listenAddrs[:1000]
+	}
+	return &agentmodel.AgentDiscoveredService{
+		HostID:      hostID,
+		ServiceKey:  fmt.Sprintf("%s/%s:%d", svc.Type, svc.Name, port),
+		Type:        svc.Type,
+		Name:        svc.Name,
+		Process:     svc.Process,
+		PID:         svc.Pid,
+		Cmdline:     svc.Cmdline,
+		Port:        port,
+		ListenAddrs: listenAddrs,
+		ExporterFor: svc.ExporterFor,
+		Status:      agentmodel.DiscoveredServiceActive,
+		FirstSeen:   seenAt,
+		LastSeen:    seenAt,
+	}
+}
+
+// primaryPort 服务的主端口：监听了该类型默认端口时取默认端口，否则取最小端口
+func primaryPort(svc *pb.DiscoveredService) int {
+	port := 0
+	for _, l := range svc.Listen {
+		if def, ok := asset.DefaultPorts[svc.Type]; ok && int(l.Port) == def {
+			return def
+		}
+		if port == 0 || int(l.Port) < port {
+			port = int(l.Port)
+		}
+	}
+	return port
+}
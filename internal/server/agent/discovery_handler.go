@@ -0,0 +1,317 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	agentmodel "github.com/ydcloud-dy/opshub/internal/agent"
+	"github.com/ydcloud-dy/opshub/internal/biz/asset"
+	pb "github.com/ydcloud-dy/opshub/pkg/agentproto"
+	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 导入建议类型
+const (
+	proposalMiddleware   = "middleware"    // 创建中间件资产
+	proposalExporterPort = "exporter_port" // 设置主机 node_exporter 端口
+	proposalLabelPort    = "label_port"    // 设置主机服务标签端口覆盖
+)
+
+// discoveryProposal 根据发现的服务生成的导入建议
+type discoveryProposal struct {
+	ServiceID uint   `json:"serviceId"`
+	Kind      string `json:"kind"`
+	Type      string `json:"type"`
+	Name      string `json:"name"`
+	Host      string `json:"host"`
+	Port      int    `json:"port"`
+	Label     string `json:"label,omitempty"`
+	Imported  bool   `json:"imported"`
+}
+
+// GetDiscoveredServices 主机上发现的服务及可导入的资产建议
+func (s *HTTPServer) GetDiscoveredServices(c *gin.Context) {
+	hostID, err := strconv.ParseUint(c.Param("hostId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "无效的主机ID"})
+		return
+	}
+
+	var host asset.Host
+	if err := s.db.First(&host, hostID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "主机不存在"})
+		return
+	}
+	services, proposals, err := discoveryProposals(s.db, &host)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success", "data": gin.H{"services": services, "proposals": proposals}})
+}
+
+// ImportDiscoveredServices 按建议导入选中的服务：创建中间件或更新主机 Exporter 端口
+func (s *HTTPServer) ImportDiscoveredServices(c *gin.Context) {
+	hostID, err := strconv.ParseUint(c.Param("hostId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "无效的主机ID"})
+		return
+	}
+	var req struct {
+		ServiceIDs []uint `json:"serviceIds" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误: " + err.Error()})
+		return
+	}
+
+	var host asset.Host
+	if err := s.db.First(&host, hostID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "主机不存在"})
+		return
+	}
+	imported, err := importDiscoveredServices(s.db, &host, req.ServiceIDs)
+	if err != nil {
+		appLogger.Error("导入发现的服务失败", zap.Uint64("hostID", hostID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": fmt.Sprintf("导入失败: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "导入成功", "data": gin.H{"imported": imported}})
+}
+
+// RescanServices 要求Agent立即重新扫描并上报服务
+func (s *HTTPServer) RescanServices(c *gin.Context) {
+	hostID, err := strconv.ParseUint(c.Param("hostId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "无效的主机ID"})
+		return
+	}
+	as, ok := s.hub.GetByHostID(uint(hostID))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "Agent不在线"})
+		return
+	}
+	err = as.Send(&pb.ServerMessage{
+		Payload: &pb.ServerMessage_DiscoveryRequest{DiscoveryRequest: &pb.ServiceDiscoveryRequest{}},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "已通知Agent重新扫描"})
+}
+
+// discoveryProposals 查询主机的发现服务，并生成中间件和 Exporter 端口的导入建议
+func discoveryProposals(db *gorm.DB, host *asset.Host) ([]agentmodel.AgentDiscoveredService, []discoveryProposal, error) {
+	var services []agentmodel.AgentDiscoveredService
+	if err := db.Where("host_id = ?", host.ID).Order("status ASC, type ASC, port ASC").Find(&services).Error; err != nil {
+		return nil, nil, err
+	}
+	var middlewares []asset.Middleware
+	if err := db.Where("host_id = ? OR host IN ?", host.ID, []string{host.IP, host.Name}).Find(&middlewares).Error; err != nil {
+		return nil, nil, err
+	}
+	var labels []asset.ServiceLabel
+	if err := db.Where("status = ?", 1).Find(&labels).Error; err != nil {
+		return nil, nil, err
+	}
+	var overrides map[string]int
+	if host.LabelPortOverrides != "" {
+		json.Unmarshal([]byte(host.LabelPortOverrides), &overrides)
+	}
+
+	proposals := make([]discoveryProposal, 0)
+	for _, svc := range services {
+		if svc.Status != agentmodel.DiscoveredServiceActive {
+			continue
+		}
+		if _, ok := asset.DefaultPorts[svc.Type]; ok {
+			addr, ok := serviceAddress(host, &svc)
+			if !ok {
+				continue // 只监听回环地址，平台无法直连
+			}
+			p := discoveryProposal{
+				ServiceID: svc.ID,
+				Kind:      proposalMiddleware,
+				Type:      svc.Type,
+				Name:      fmt.Sprintf("%s-%s-%d", host.Name, svc.Type, svc.Port),
+				Host:      addr,
+				Port:      svc.Port,
+			}
+			for _, m := range middlewares {
+				if m.Type == svc.Type && m.Port == svc.Port {
+					p.Imported = true
+					break
+				}
+			}
+			proposals = append(proposals, p)
+			continue
+		}
+		if svc.Type != "exporter" {
+			continue
+		}
+		if svc.ExporterFor == "node" {
+			proposals = append(proposals, discoveryProposal{
+				ServiceID: svc.ID,
+				Kind:      proposalExporterPort,
+				Type:      svc.Type,
+				Name:      svc.Name,
+				Host:      host.IP,
+				Port:      svc.Port,
+				Imported:  host.ExporterPort == svc.Port,
+			})
+			continue
+		}
+		label := exporterLabel(labels, services, svc.ExporterFor)
+		if label == nil {
+			continue // 没有对应的服务标签，Exporter 端口无处配置
+		}
+		current := label.ExporterPort
+		if port, ok := overrides[label.Name]; ok && port > 0 {
+			current = port
+		}
+		proposals = append(proposals, discoveryProposal{
+			ServiceID: svc.ID,
+			Kind:      proposalLabelPort,
+			Type:      svc.Type,
+			Name:      svc.Name,
+			Host:      host.IP,
+			Port:      svc.Port,
+			Label:     label.Name,
+			Imported:  current == svc.Port && hasTag(host.Tags, label.Name),
+		})
+	}
+	return services, proposals, nil
+}
+
+// importDiscoveredServices 应用选中服务的导入建议，返回实际导入的数量
+func importDiscoveredServices(db *gorm.DB, host *asset.Host, serviceIDs []uint) (int, error) {
+	_, proposals, err := discoveryProposals(db, host)
+	if err != nil {
+		return 0, err
+	}
+	selected := make(map[uint]bool, len(serviceIDs))
+	for _, id := range serviceIDs {
+		selected[id] = true
+	}
+
+	overrides := make(map[string]int)
+	if host.LabelPortOverrides != "" {
+		json.Unmarshal([]byte(host.LabelPortOverrides), &overrides)
+	}
+	hostUpdates := make(map[string]any)
+	tags := host.Tags
+	imported := 0
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, p := range proposals {
+			if !selected[p.ServiceID] || p.Imported {
+				continue
+			}
+			switch p.Kind {
+			case proposalMiddleware:
+				m := &asset.Middleware{
+					Name:        p.Name,
+					Type:        p.Type,
+					GroupID:     host.GroupID,
+					HostID:      host.ID,
+					Host:        p.Host,
+					Port:        p.Port,
+					Description: "由Agent服务发现导入",
+					Status:      -1,
+				}
+				if err := tx.Create(m).Error; err != nil {
+					return err
+				}
+			case proposalExporterPort:
+				hostUpdates["exporter_port"] = p.Port
+			case proposalLabelPort:
+				overrides[p.Label] = p.Port
+				if !hasTag(tags, p.Label) {
+					if tags != "" {
+						tags += ","
+					}
+					tags += p.Label
+				}
+			}
+			imported++
+		}
+		if tags != host.Tags {
+			hostUpdates["tags"] = tags
+		}
+		if len(overrides) > 0 {
+			data, _ := json.Marshal(overrides)
+			if string(data) != host.LabelPortOverrides {
+				hostUpdates["label_port_overrides"] = string(data)
+			}
+		}
+		if len(hostUpdates) == 0 {
+			return nil
+		}
+		return tx.Model(&asset.Host{}).Where("id = ?", host.ID).Updates(hostUpdates).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return imported, nil
+}
+
+// serviceAddress 平台连接服务使用的地址：监听通配地址时使用主机IP，否则使用监听的具体地址
+func serviceAddress(host *asset.Host, svc *agentmodel.AgentDiscoveredService) (string, bool) {
+	addr := ""
+	for _, listen := range strings.Split(svc.ListenAddrs, ",") {
+		ipStr, portStr, err := net.SplitHostPort(listen)
+		if err != nil || portStr != strconv.Itoa(svc.Port) {
+			continue
+		}
+		ip := net.ParseIP(ipStr)
+		switch {
+		case ip == nil || ip.IsLoopback():
+		case ip.IsUnspecified():
+			return host.IP, true
+		case addr == "":
+			addr = ipStr
+		}
+	}
+	return addr, addr != ""
+}
+
+// exporterLabel 查找 Exporter 对应的服务标签：标签名与被监控服务类型一致，
+// 或标签匹配的进程名是该类型服务的进程
+func exporterLabel(labels []asset.ServiceLabel, services []agentmodel.AgentDiscoveredService, target string) *asset.ServiceLabel {
+	processes := make(map[string]bool)
+	for _, svc := range services {
+		if svc.Type == target && svc.Status == agentmodel.DiscoveredServiceActive {
+			processes[svc.Process] = true
+		}
+	}
+	for i := range labels {
+		if strings.EqualFold(labels[i].Name, target) {
+			return &labels[i]
+		}
+	}
+	for i := range labels {
+		for _, proc := range strings.Split(labels[i].MatchProcesses, ",") {
+			if proc = strings.TrimSpace(proc); proc != "" && processes[proc] {
+				return &labels[i]
+			}
+		}
+	}
+	return nil
+}
+
+func hasTag(tags, tag string) bool {
+	for _, t := range strings.Split(tags, ",") {
+		if strings.TrimSpace(t) == tag {
+			return true
+		}
+	}
+	return false
+}
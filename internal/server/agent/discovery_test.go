@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	agentmodel "github.com/ydcloud-dy/opshub/internal/agent"
+	"github.com/ydcloud-dy/opshub/internal/biz/asset"
+	"github.com/ydcloud-dy/opshub/internal/testutil"
+	pb "github.com/ydcloud-dy/opshub/pkg/agentproto"
+	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
+	"go.uber.org/zap"
+)
+
+func TestServiceDiscovery_SaveAndImport(t *testing.T) {
+	appLogger.Log = zap.NewNop()
+	db := testutil.NewDB(t, &agentmodel.AgentDiscoveredService{}, &asset.Host{}, &asset.Middleware{}, &asset.ServiceLabel{})
+	host := &asset.Host{Name: "db-1", IP: "10.0.0.5", GroupID: 3, ExporterPort: 9100}
+	host.ID = 1
+	db.Create(host)
+	db.Create(&asset.ServiceLabel{Name: "mysql", MatchProcesses: "mysqld", ExporterPort: 9104, Status: 1})
+
+	listen := func(ip string, ports ...int32) []*pb.ListenAddr {
+		var addrs []*pb.ListenAddr
+		for _, p := range ports {
+			addrs = append(addrs, &pb.ListenAddr{Ip: ip, Port: p})
+		}
+		return addrs
+	}
+	svc := &AgentService{db: db}
+	svc.handleServiceDiscovery(&AgentStream{HostID: 1}, &pb.ServiceDiscovery{Timestamp: 1700000000, Services: []*pb.DiscoveredService{
+		{Type: "mysql", Name: "mysql", Pid: 10, Process: "mysqld", Listen: listen("0.0.0.0", 33060, 3306)},
+		{Type: "redis", Name: "redis", Pid: 11, Process: "redis-server", Listen: listen("127.0.0.1", 6379)},
+		{Type: "exporter", Name: "node_exporter", Pid: 12, Process: "node_exporter", Listen: listen("::", 9101), ExporterFor: "node"},
+		{Type: "exporter", Name: "mysqld_exporter", Pid: 13, Process: "mysqld_exporter", Listen: listen("::", 9204), ExporterFor: "mysql"},
+		{Type: "nginx", Name: "nginx", Pid: 14, Process: "nginx", Listen: listen("0.0.0.0", 80)},
+	}})
+
+	services, proposals, err := discoveryProposals(db, host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 5 {
+		t.Fatalf("期望保存 5 个服务, 实际 %d", len(services))
+	}
+	// redis 只监听回环地址、nginx 不是中间件，均不生成建议
+	if len(proposals) != 3 {
+		t.Fatalf("期望 3 条导入建议, 实际 %+v", proposals)
+	}
+	byKind := make(map[string]discoveryProposal)
+	var ids []uint
+	for _, p := range proposals {
+		byKind[p.Kind] = p
+		ids = append(ids, p.ServiceID)
+	}
+	if m := byKind[proposalMiddleware]; m.Host != "10.0.0.5" || m.Port != 3306 || m.Type != "mysql" {
+		t.Fatalf("中间件建议错误: %+v", m)
+	}
+	if l := byKind[proposalLabelPort]; l.Label != "mysql" || l.Port != 9204 {
+		t.Fatalf("标签端口建议错误: %+v", l)
+	}
+
+	imported, err := importDiscoveredServices(db, host, ids)
+	if err != nil || imported != 3 {
+		t.Fatalf("导入失败: imported=%d err=%v", imported, err)
+	}
+	var middleware asset.Middleware
+	if err := db.First(&middleware).Error; err != nil || middleware.HostID != 1 || middleware.GroupID != 3 || middleware.Port != 3306 {
+		t.Fatalf("中间件未正确创建: %+v err=%v", middleware, err)
+	}
+	var updated asset.Host
+	db.First(&updated, 1)
+	var overrides map[string]int
+	json.Unmarshal([]byte(updated.LabelPortOverrides), &overrides)
+	if updated.ExporterPort != 9101 || overrides["mysql"] != 9204 || updated.Tags != "mysql" {
+		t.Fatalf("主机 Exporter 配置未更新: %+v", updated)
+	}
+
+	// 再次查看时建议均已导入，重复导入不产生新记录
+	_, proposals, _ = discoveryProposals(db, &updated)
+	for _, p := range proposals {
+		if !p.Imported {
+			t.Fatalf("建议应标记为已导入: %+v", p)
+		}
+	}
+	if imported, _ := importDiscoveredServices(db, &updated, ids); imported != 0 {
+		t.Fatalf("重复导入应被跳过, imported=%d", imported)
+	}
+
+	// mysql 重启换了 PID 仍是同一服务，nginx 消失后标记为 gone
+	err = saveDiscoveredServices(db, 1, []*pb.DiscoveredService{
+		{Type: "mysql", Name: "mysql", Pid: 20, Process: "mysqld", Listen: listen("0.0.0.0", 3306)},
+	}, time.Unix(1700000300, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rows []agentmodel.AgentDiscoveredService
+	db.Order("id").Find(&rows)
+	if len(rows) != 5 || rows[0].PID != 20 || rows[0].Status != agentmodel.DiscoveredServiceActive || !rows[0].FirstSeen.Equal(time.Unix(1700000000, 0)) {
+		t.Fatalf("已有服务应原地更新: %+v", rows[0])
+	}
+	for _, r := range rows[1:] {
+		if r.Status != agentmodel.DiscoveredServiceGone {
+			t.Fatalf("未上报的服务应标记为 gone: %+v", r)
+		}
+	}
+}
@@ -1,15 +1,25 @@
 package agent
 
 import (
+	"archive/tar"
+	"compress/gzip"
 	"fmt"
 	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"os"
+	pathpkg "path"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	pb "github.com/ydcloud-dy/opshub/pkg/agentproto"
+	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
+	"go.uber.org/zap"
 )
 
 // ListFiles Agent文件列表
@@ -53,8 +63,9 @@ func (s *HTTPServer) ListFiles(c *gin.Context) {
 }
 
 // UploadFile Agent文件上传
+// 分片传输并校验 SHA-256；可选 mode(八进制)、owner、group，未指定时覆盖文件沿用原有权限和属主
 func (s *HTTPServer) UploadFile(c *gin.Context) {
-	_, as, err := s.getAgentStream(c)
+	hostID, _, err := s.getAgentStream(c)
 	if err != nil {
 		return
 	}
@@ -67,82 +78,181 @@ func (s *HTTPServer) UploadFile(c *gin.Context) {
 	}
 	defer file.Close()
 
-	data, err := io.ReadAll(file)
+	opts := UploadOptions{Dir: path, Filename: header.Filename, Owner: c.PostForm("owner"), Group: c.PostForm("group")}
+	if m := c.PostForm("mode"); m != "" {
+		mode, err := strconv.ParseUint(m, 8, 32)
+		if err != nil || mode > 0o7777 {
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "权限格式错误，应为八进制如 0644"})
+			return
+		}
+		opts.Mode = uint32(mode)
+	}
+
+	if err := s.hub.UploadFile(c.Request.Context(), hostID, file, header.Size, opts); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "上传成功"})
+}
+
+// UploadDir Agent目录上传
+// 浏览器按目录选择的文件(files + relativePaths)在服务端打包为 tar.gz，分片上传后在目标主机解压
+func (s *HTTPServer) UploadDir(c *gin.Context) {
+	hostID, _, err := s.getAgentStream(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "读取文件失败"})
 		return
 	}
 
-	requestID := uuid.New().String()
-	as.Send(&pb.ServerMessage{
-		Payload: &pb.ServerMessage_FileRequest{
-			FileRequest: &pb.FileRequest{
-				RequestId: requestID,
-				Action:    "upload",
-				Path:      path,
-				Filename:  header.Filename,
-				Data:      data,
-			},
-		},
-	})
+	form, err := c.MultipartForm()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "文件参数错误"})
+		return
+	}
+	files, relPaths := form.File["files"], form.Value["relativePaths"]
+	if len(files) == 0 || len(files) != len(relPaths) {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "文件参数错误"})
+		return
+	}
 
-	result, err := s.hub.WaitResponse(as, requestID, 60*time.Second)
+	archive, err := os.CreateTemp("", "opshub-upload-*.tar.gz")
 	if err != nil {
-		c.JSON(http.StatusGatewayTimeout, gin.H{"code": 504, "message": err.Error()})
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "创建临时文件失败"})
 		return
 	}
+	defer os.Remove(archive.Name())
+	defer archive.Close()
 
-	chunk, ok := result.(*pb.FileChunk)
-	if !ok || (chunk != nil && chunk.Error != "") {
-		msg := "上传失败"
-		if chunk != nil {
-			msg = chunk.Error
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": msg})
+	dirName, err := writeUploadArchive(archive, files, relPaths)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+	info, err := archive.Stat()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "读取临时文件失败"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "上传成功"})
+	opts := UploadOptions{Dir: c.PostForm("path"), Filename: dirName, Extract: true}
+	if err := s.hub.UploadFile(c.Request.Context(), hostID, archive, info.Size(), opts); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "上传成功", "data": gin.H{"dir": dirName, "files": len(files)}})
+}
+
+// writeUploadArchive 将上传的文件写成 tar.gz，条目路径去掉公共的顶层目录，返回该目录名
+func writeUploadArchive(w io.Writer, files []*multipart.FileHeader, relPaths []string) (string, error) {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	dirName := ""
+	now := time.Now()
+	for i, fh := range files {
+		rel := pathpkg.Clean("/" + filepath.ToSlash(relPaths[i]))[1:]
+		root, name, ok := strings.Cut(rel, "/")
+		if !ok || name == "" || (dirName != "" && root != dirName) {
+			return "", fmt.Errorf("无效的相对路径: %s", relPaths[i])
+		}
+		dirName = root
+
+		src, err := fh.Open()
+		if err != nil {
+			return "", err
+		}
+		err = tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: fh.Size, ModTime: now, Typeflag: tar.TypeReg})
+		if err == nil {
+			_, err = io.Copy(tw, src)
+		}
+		src.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return "", err
+	}
+	return dirName, gz.Close()
 }
 
 // DownloadFile Agent文件下载
+// 分片读取并校验 SHA-256，支持 Range 续传；目录打包为 tar.gz 下载
+// 响应头携带 X-File-Sha256、X-File-Mode、X-File-Owner、X-File-Group
 func (s *HTTPServer) DownloadFile(c *gin.Context) {
-	_, as, err := s.getAgentStream(c)
+	hostID, _, err := s.getAgentStream(c)
 	if err != nil {
 		return
 	}
 
+	ctx := c.Request.Context()
 	path := c.Query("path")
-	requestID := uuid.New().String()
-
-	as.Send(&pb.ServerMessage{
-		Payload: &pb.ServerMessage_FileRequest{
-			FileRequest: &pb.FileRequest{
-				RequestId: requestID,
-				Action:    "download",
-				Path:      path,
-			},
-		},
-	})
-
-	result, err := s.hub.WaitResponse(as, requestID, 60*time.Second)
+	info, err := s.hub.StatFile(ctx, hostID, path)
 	if err != nil {
-		c.JSON(http.StatusGatewayTimeout, gin.H{"code": 504, "message": err.Error()})
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": err.Error()})
 		return
 	}
 
-	chunk, ok := result.(*pb.FileChunk)
-	if !ok || (chunk != nil && chunk.Error != "") {
-		msg := "下载失败"
-		if chunk != nil {
-			msg = chunk.Error
+	readPath, name := path, pathpkg.Base(path)
+	var size int64
+	var sum string
+	if info.IsDir {
+		pack, cleanup, err := s.hub.PackDir(ctx, hostID, path)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": err.Error()})
+			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": msg})
-		return
+		defer cleanup()
+		readPath, name, size, sum = pack.Path, name+".tar.gz", pack.Size, pack.Sha256
+	} else {
+		checksum, err := s.hub.ChecksumFile(ctx, hostID, path, info.Size)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": err.Error()})
+			return
+		}
+		size, sum = checksum.Size, checksum.Sha256
 	}
 
+	offset := parseRangeStart(c.GetHeader("Range"), size)
 	c.Header("Content-Type", "application/octet-stream")
-	c.Writer.Write(chunk.Data)
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	c.Header("Accept-Ranges", "bytes")
+	c.Header("Content-Length", strconv.FormatInt(size-offset, 10))
+	c.Header("X-File-Sha256", sum)
+	c.Header("X-File-Mode", fmt.Sprintf("%04o", info.Mode))
+	c.Header("X-File-Owner", info.Owner)
+	c.Header("X-File-Group", info.Group)
+	if info.ModTime > 0 {
+		c.Header("Last-Modified", time.Unix(info.ModTime, 0).UTC().Format(http.TimeFormat))
+	}
+	if offset > 0 {
+		c.Header("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, size-1, size))
+		c.Status(http.StatusPartialContent)
+	} else {
+		c.Status(http.StatusOK)
+	}
+
+	// 响应头已写出，失败时只能中断传输，客户端会因长度不足而识别为下载失败
+	if err := s.hub.ReadFile(ctx, hostID, readPath, offset, sum, c.Writer); err != nil {
+		appLogger.Error("Agent文件下载失败", zap.Uint("host_id", hostID), zap.String("path", path), zap.Error(err))
+	}
+}
+
+// parseRangeStart 解析 "bytes=N-" 形式的续传起点，其他形式按完整下载处理
+func parseRangeStart(header string, size int64) int64 {
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return 0
+	}
+	start, end, _ := strings.Cut(spec, "-")
+	offset, err := strconv.ParseInt(start, 10, 64)
+	if err != nil || offset <= 0 || offset >= size {
+		return 0
+	}
+	if end != "" && end != strconv.FormatInt(size-1, 10) {
+		return 0
+	}
+	return offset
 }
 
 // DeleteFile Agent文件删除
@@ -0,0 +1,225 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	pb "github.com/ydcloud-dy/opshub/pkg/agentproto"
+	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	// FileChunkSize 分片传输的单片大小，需低于 gRPC 默认 4MB 消息限制
+	FileChunkSize = 1 << 20
+	// fileRequestTimeout 单个分片请求的超时
+	fileRequestTimeout = 60 * time.Second
+	// agentReconnectWait 传输中 Agent 断线后等待其重连的时间
+	agentReconnectWait = 60 * time.Second
+	// agentReconnectPoll 等待重连时的检查间隔
+	agentReconnectPoll = time.Second
+	// fileRequestAttempts 单个文件请求的最大尝试次数
+	fileRequestAttempts = 5
+)
+
+// errAgentRetryable Agent 断线或超时，等待重连后可以重试
+var errAgentRetryable = errors.New("agent unavailable")
+
+// UploadOptions 分片上传参数
+type UploadOptions struct {
+	Dir      string // 目标目录
+	Filename string // 目标文件名；Extract 时为解压目录名
+	Mode     uint32 // 权限位，0 表示沿用已有文件权限（新文件为 0644）
+	Owner    string
+	Group    string
+	ModTime  int64
+	Extract  bool // 源为 tar.gz，解压到 Dir/Filename
+}
+
+// FileRequest 发送文件请求并等待响应
+// 超时会重试，Agent 断线则等待其重连后重试；分片请求均为幂等操作
+func (h *AgentHub) FileRequest(ctx context.Context, hostID uint, req *pb.FileRequest, timeout time.Duration) (*pb.FileChunk, error) {
+	deadline := time.Now().Add(agentReconnectWait)
+	var closed *AgentStream
+	for attempt := 1; ; {
+		as, ok := h.GetByHostID(hostID)
+		if ok && as != closed {
+			chunk, err := h.sendFileRequest(as, req, timeout)
+			if !errors.Is(err, errAgentRetryable) || attempt >= fileRequestAttempts {
+				return chunk, err
+			}
+			attempt++
+			appLogger.Warn("文件传输中Agent不可用，稍后重试",
+				zap.Uint("host_id", hostID), zap.String("action", req.Action), zap.Error(err))
+			select {
+			case <-as.DoneCh:
+				closed = as
+			default:
+			}
+			deadline = time.Now().Add(agentReconnectWait)
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("Agent不在线")
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(agentReconnectPoll):
+		}
+	}
+}
+
+func (h *AgentHub) sendFileRequest(as *AgentStream, req *pb.FileRequest, timeout time.Duration) (*pb.FileChunk, error) {
+	req.RequestId = uuid.New().String()
+	if err := as.Send(&pb.ServerMessage{Payload: &pb.ServerMessage_FileRequest{FileRequest: req}}); err != nil {
+		return nil, fmt.Errorf("%w: %v", errAgentRetryable, err)
+	}
+	result, err := h.WaitResponse(as, req.RequestId, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errAgentRetryable, err)
+	}
+	chunk, ok := result.(*pb.FileChunk)
+	if !ok {
+		return nil, fmt.Errorf("响应类型错误")
+	}
+	if chunk.Error != "" {
+		return chunk, errors.New(chunk.Error)
+	}
+	return chunk, nil
+}
+
+// hashTimeout Agent 需要完整读取文件（校验、打包）时按大小放宽超时
+func hashTimeout(size int64) time.Duration {
+	return fileRequestTimeout + time.Duration(size/(20<<20))*time.Second
+}
+
+// UploadFile 以分片方式上传文件并校验 SHA-256
+// 传输标识由内容摘要和目标路径决定，中断后再次上传同一文件会从已写入位置续传
+func (h *AgentHub) UploadFile(ctx context.Context, hostID uint, src io.ReaderAt, size int64, opts UploadOptions) error {
+	sum, err := sectionSHA256(src, size)
+	if err != nil {
+		return fmt.Errorf("读取源文件失败: %w", err)
+	}
+	id := sha256.Sum256([]byte(sum + "\x00" + opts.Dir + "\x00" + opts.Filename))
+	transferID := hex.EncodeToString(id[:16])
+	base := func(action string) *pb.FileRequest {
+		return &pb.FileRequest{Action: action, Path: opts.Dir, Filename: opts.Filename, TransferId: transferID, Size: size}
+	}
+
+	started, err := h.FileRequest(ctx, hostID, base("upload_init"), fileRequestTimeout)
+	if err != nil {
+		return err
+	}
+	offset := started.Offset
+	if offset > 0 {
+		// 续传前确认已写入部分与源文件一致，否则从头开始
+		prefix, err := sectionSHA256(src, offset)
+		if err != nil || prefix != started.Sha256 {
+			offset = 0
+		} else {
+			appLogger.Info("断点续传", zap.Uint("host_id", hostID), zap.String("file", opts.Filename), zap.Int64("offset", offset))
+		}
+	}
+
+	// 至少发送一片，空文件或已完整写入时也会校准临时文件长度
+	buf := make([]byte, FileChunkSize)
+	for first := true; first || offset < size; first = false {
+		n, err := src.ReadAt(buf[:min(int64(len(buf)), size-offset)], offset)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("读取源文件失败: %w", err)
+		}
+		req := base("upload_chunk")
+		req.Offset, req.Data = offset, buf[:n]
+		resp, err := h.FileRequest(ctx, hostID, req, fileRequestTimeout)
+		if err != nil {
+			return err
+		}
+		offset = resp.Offset
+	}
+
+	commit := base("upload_commit")
+	commit.Sha256, commit.Mode, commit.Owner, commit.Group, commit.ModTime, commit.Extract =
+		sum, opts.Mode, opts.Owner, opts.Group, opts.ModTime, opts.Extract
+	if _, err := h.FileRequest(ctx, hostID, commit, hashTimeout(size)); err != nil {
+		// 提交成功但响应丢失时临时文件已被重命名，按目标文件摘要确认
+		if !opts.Extract && strings.Contains(err.Error(), "上传会话不存在") {
+			if target, cerr := h.ChecksumFile(ctx, hostID, strings.TrimSuffix(opts.Dir, "/")+"/"+opts.Filename, size); cerr == nil && target.Sha256 == sum {
+				return nil
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// StatFile 获取文件元数据
+func (h *AgentHub) StatFile(ctx context.Context, hostID uint, path string) (*pb.FileChunk, error) {
+	return h.FileRequest(ctx, hostID, &pb.FileRequest{Action: "stat", Path: path}, fileRequestTimeout)
+}
+
+// ChecksumFile 计算文件 SHA-256，size 用于估算超时
+func (h *AgentHub) ChecksumFile(ctx context.Context, hostID uint, path string, size int64) (*pb.FileChunk, error) {
+	return h.FileRequest(ctx, hostID, &pb.FileRequest{Action: "checksum", Path: path}, hashTimeout(size))
+}
+
+// PackDir 将目录打包为 tar.gz，返回打包文件信息和清理函数
+func (h *AgentHub) PackDir(ctx context.Context, hostID uint, path string) (*pb.FileChunk, func(), error) {
+	transferID := strings.ReplaceAll(uuid.New().String(), "-", "")
+	chunk, err := h.FileRequest(ctx, hostID, &pb.FileRequest{Action: "pack", Path: path, TransferId: transferID}, 30*time.Minute)
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() {
+		h.FileRequest(context.Background(), hostID, &pb.FileRequest{Action: "pack_cleanup", TransferId: transferID}, fileRequestTimeout)
+	}
+	return chunk, cleanup, nil
+}
+
+// ReadFile 从 offset 开始分片读取文件并写入 w，直到文件末尾
+// expectSHA256 非空且从头读取时校验摘要，最后一片在校验通过后才写出，校验失败时数据不完整
+func (h *AgentHub) ReadFile(ctx context.Context, hostID uint, path string, offset int64, expectSHA256 string, w io.Writer) error {
+	hash := sha256.New()
+	verify := expectSHA256 != "" && offset == 0
+	for {
+		chunk, err := h.FileRequest(ctx, hostID, &pb.FileRequest{Action: "read", Path: path, Offset: offset, Size: FileChunkSize}, fileRequestTimeout)
+		if err != nil {
+			return err
+		}
+		if chunk.Offset != offset {
+			return fmt.Errorf("分片位置不一致: 期望 %d, 实际 %d", offset, chunk.Offset)
+		}
+		if verify {
+			hash.Write(chunk.Data)
+			if chunk.Eof {
+				if got := hex.EncodeToString(hash.Sum(nil)); got != expectSHA256 {
+					return fmt.Errorf("SHA-256 校验失败: 期望 %s, 实际 %s", expectSHA256, got)
+				}
+			}
+		}
+		if _, err := w.Write(chunk.Data); err != nil {
+			return err
+		}
+		offset += int64(len(chunk.Data))
+		if chunk.Eof {
+			return nil
+		}
+		if len(chunk.Data) == 0 {
+			return fmt.Errorf("读取中断: offset=%d", offset)
+		}
+	}
+}
+
+func sectionSHA256(src io.ReaderAt, size int64) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, io.NewSectionReader(src, 0, size)); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
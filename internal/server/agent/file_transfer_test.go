@@ -0,0 +1,239 @@
+package agent
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"math/rand"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+
+	pb "github.com/ydcloud-dy/opshub/pkg/agentproto"
+	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// memFileAgent 内存中的文件Agent，按 Agent 端协议处理分片请求
+type memFileAgent struct {
+	mu    sync.Mutex
+	files map[string][]byte
+	parts map[string][]byte
+	// drop 返回 true 时丢弃该请求且不响应，模拟传输中断线
+	drop func(req *pb.FileRequest) bool
+}
+
+func (a *memFileAgent) serve(as *AgentStream) {
+	for {
+		select {
+		case msg := <-as.SendCh:
+			req := msg.GetFileRequest()
+			if req == nil {
+				continue
+			}
+			if a.drop != nil && a.drop(req) {
+				continue
+			}
+			chunk := a.handle(req)
+			chunk.RequestId = req.RequestId
+			as.ResolvePending(req.RequestId, chunk)
+		case <-as.DoneCh:
+			return
+		}
+	}
+}
+
+func (a *memFileAgent) handle(req *pb.FileRequest) *pb.FileChunk {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	key := req.Path + "/" + req.Filename + "#" + req.TransferId
+	switch req.Action {
+	case "upload_init":
+		part := a.parts[key]
+		sum := sha256.Sum256(part)
+		return &pb.FileChunk{Offset: int64(len(part)), Sha256: hex.EncodeToString(sum[:])}
+	case "upload_chunk":
+		part := a.parts[key]
+		if req.Offset > int64(len(part)) {
+			return &pb.FileChunk{Error: "分片不连续"}
+		}
+		a.parts[key] = append(part[:req.Offset:req.Offset], req.Data...)
+		return &pb.FileChunk{Offset: int64(len(a.parts[key]))}
+	case "upload_commit":
+		part, ok := a.parts[key]
+		if !ok {
+			return &pb.FileChunk{Error: "上传会话不存在"}
+		}
+		if sum := sha256.Sum256(part); hex.EncodeToString(sum[:]) != req.Sha256 || int64(len(part)) != req.Size {
+			return &pb.FileChunk{Error: "SHA-256 校验失败"}
+		}
+		delete(a.parts, key)
+		a.files[req.Path+"/"+req.Filename] = part
+		return &pb.FileChunk{Eof: true}
+	case "read":
+		data := a.files[req.Path]
+		end := min(int64(len(data)), req.Offset+req.Size)
+		return &pb.FileChunk{Offset: req.Offset, Data: data[req.Offset:end], Size: int64(len(data)), Eof: end == int64(len(data))}
+	}
+	return &pb.FileChunk{Error: "未知操作"}
+}
+
+func TestUploadFile_ResumeAfterReconnect(t *testing.T) {
+	appLogger.Log = zap.NewNop()
+	hub := NewAgentHub()
+	agent := &memFileAgent{files: map[string][]byte{}, parts: map[string][]byte{}}
+
+	data := make([]byte, 2*FileChunkSize+FileChunkSize/2)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	// 第二个分片发出后 Agent 断线，稍后以新连接重新注册
+	var chunks, inits int
+	reconnected := make(chan struct{})
+	agent.drop = func(req *pb.FileRequest) bool {
+		switch req.Action {
+		case "upload_init":
+			inits++
+		case "upload_chunk":
+			chunks++
+			if chunks == 2 {
+				go func() {
+					hub.Unregister("agent-1")
+					go agent.serve(hub.Register("agent-1", 7, nil))
+					close(reconnected)
+				}()
+				return true
+			}
+		}
+		return false
+	}
+	go agent.serve(hub.Register("agent-1", 7, nil))
+	defer hub.Unregister("agent-1")
+
+	err := hub.UploadFile(context.Background(), 7, bytes.NewReader(data), int64(len(data)), UploadOptions{Dir: "/opt/app", Filename: "app.bin"})
+	if err != nil {
+		t.Fatalf("UploadFile() error: %v", err)
+	}
+	<-reconnected
+	if !bytes.Equal(agent.files["/opt/app/app.bin"], data) {
+		t.Fatal("uploaded content mismatch")
+	}
+	// 3 个分片 + 断线重发 1 次
+	if chunks != 4 || inits != 1 {
+		t.Errorf("chunks = %d, inits = %d", chunks, inits)
+	}
+
+	// 再次上传相同文件：已有的临时文件前缀不一致时从头上传
+	agent.drop = nil
+	id := sha256.Sum256(data)
+	sum := hex.EncodeToString(id[:])
+	tid := sha256.Sum256([]byte(sum + "\x00/opt/app\x00app.bin"))
+	agent.parts["/opt/app/app.bin#"+hex.EncodeToString(tid[:16])] = []byte("garbage")
+	if err := hub.UploadFile(context.Background(), 7, bytes.NewReader(data), int64(len(data)), UploadOptions{Dir: "/opt/app", Filename: "app.bin"}); err != nil {
+		t.Fatalf("UploadFile() with stale part error: %v", err)
+	}
+	if !bytes.Equal(agent.files["/opt/app/app.bin"], data) {
+		t.Fatal("re-uploaded content mismatch")
+	}
+}
+
+func TestReadFile_VerifyChecksum(t *testing.T) {
+	appLogger.Log = zap.NewNop()
+	hub := NewAgentHub()
+	data := bytes.Repeat([]byte("0123456789"), FileChunkSize/5)
+	agent := &memFileAgent{files: map[string][]byte{"/var/log/app.log": data}}
+	go agent.serve(hub.Register("agent-2", 8, nil))
+	defer hub.Unregister("agent-2")
+
+	sum := sha256.Sum256(data)
+	var out bytes.Buffer
+	if err := hub.ReadFile(context.Background(), 8, "/var/log/app.log", 0, hex.EncodeToString(sum[:]), &out); err != nil || !bytes.Equal(out.Bytes(), data) {
+		t.Fatalf("ReadFile() = %d bytes, %v", out.Len(), err)
+	}
+
+	// 校验失败时不写出最后一片
+	out.Reset()
+	if err := hub.ReadFile(context.Background(), 8, "/var/log/app.log", 0, strings.Repeat("0", 64), &out); err == nil {
+		t.Fatal("checksum mismatch not detected")
+	}
+	if out.Len() != FileChunkSize {
+		t.Errorf("written = %d, want %d", out.Len(), FileChunkSize)
+	}
+
+	// 续传从 offset 开始
+	out.Reset()
+	if err := hub.ReadFile(context.Background(), 8, "/var/log/app.log", 5, "", &out); err != nil || !bytes.Equal(out.Bytes(), data[5:]) {
+		t.Fatalf("ReadFile(offset) = %d bytes, %v", out.Len(), err)
+	}
+}
+
+func TestParseRangeStart(t *testing.T) {
+	tests := []struct {
+		header string
+		want   int64
+	}{
+		{"", 0},
+		{"bytes=100-", 100},
+		{"bytes=100-999", 100},
+		{"bytes=100-200", 0},
+		{"bytes=1000-", 0},
+		{"bytes=0-10,20-30", 0},
+		{"items=5-", 0},
+	}
+	for _, tt := range tests {
+		if got := parseRangeStart(tt.header, 1000); got != tt.want {
+			t.Errorf("parseRangeStart(%q) = %d, want %d", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestWriteUploadArchive(t *testing.T) {
+	files := multipartFiles(t, map[string]string{"a.txt": "A", "b.txt": "BB"})
+	var buf bytes.Buffer
+	dir, err := writeUploadArchive(&buf, files, []string{"conf/a.txt", "conf/sub/b.txt"})
+	if err != nil || dir != "conf" {
+		t.Fatalf("writeUploadArchive() = %q, %v", dir, err)
+	}
+	gz, _ := gzip.NewReader(&buf)
+	tr := tar.NewReader(gz)
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+		content, _ := io.ReadAll(tr)
+		names = append(names, hdr.Name+"="+string(content))
+	}
+	if strings.Join(names, " ") != "a.txt=A sub/b.txt=BB" {
+		t.Errorf("entries = %v", names)
+	}
+
+	for _, paths := range [][]string{{"conf/a.txt", "other/b.txt"}, {"a.txt", "conf/b.txt"}, {"../a.txt", "conf/b.txt"}} {
+		if _, err := writeUploadArchive(io.Discard, files, paths); err == nil {
+			t.Errorf("paths %v: expected error", paths)
+		}
+	}
+}
+
+func multipartFiles(t *testing.T, contents map[string]string) []*multipart.FileHeader {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, name := range []string{"a.txt", "b.txt"} {
+		h := textproto.MIMEHeader{}
+		h.Set("Content-Disposition", `form-data; name="files"; filename="`+name+`"`)
+		w, _ := mw.CreatePart(h)
+		w.Write([]byte(contents[name]))
+	}
+	mw.Close()
+	form, err := multipart.NewReader(&body, mw.Boundary()).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatalf("ReadForm: %v", err)
+	}
+	return form.File["files"]
+}
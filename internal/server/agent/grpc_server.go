@@ -5,6 +5,7 @@ import (
 	"net"
 	"time"
 
+	"github.com/redis/go-redis/v9"
 	"github.com/ydcloud-dy/opshub/internal/biz/asset"
 	"github.com/ydcloud-dy/opshub/internal/conf"
 	agentrepo "github.com/ydcloud-dy/opshub/internal/data/agent"
@@ -25,6 +26,8 @@ type GRPCServer struct {
 	conf       *conf.Config
 	db         *gorm.DB
 	agentRepo  *agentrepo.Repository
+	cluster    *Cluster
+	certs      *CertManager
 }
 
 // NewGRPCServer 创建gRPC服务器
@@ -32,12 +35,15 @@ func NewGRPCServer(cfg *conf.Config, db *gorm.DB) *GRPCServer {
 	hub := NewAgentHub()
 	repo := agentrepo.NewRepository(db)
 	tlsMgr := NewTLSManager(cfg.Agent.CertDir)
+	tlsMgr.SetAgentCertTTL(time.Duration(cfg.Agent.CertValidDays) * 24 * time.Hour)
+	certs := NewCertManager(tlsMgr, hub, db, time.Duration(cfg.Agent.CertRenewDays)*24*time.Hour)
 
 	svc := &AgentService{
 		hub:       hub,
 		agentRepo: repo,
 		db:        db,
 		cfg:       cfg,
+		certs:     certs,
 	}
 
 	return &GRPCServer{
@@ -47,6 +53,7 @@ func NewGRPCServer(cfg *conf.Config, db *gorm.DB) *GRPCServer {
 		conf:      cfg,
 		db:        db,
 		agentRepo: repo,
+		certs:     certs,
 	}
 }
 
@@ -60,6 +67,11 @@ func (s *GRPCServer) TLSManager() *TLSManager {
 	return s.tlsMgr
 }
 
+// Certs 返回Agent证书管理器
+func (s *GRPCServer) Certs() *CertManager {
+	return s.certs
+}
+
 // AgentRepo 返回Agent仓库
 func (s *GRPCServer) AgentRepo() *agentrepo.Repository {
 	return s.agentRepo
@@ -75,6 +87,19 @@ func (s *GRPCServer) SetHostRepo(hostUseCase interface{ GetHostRepo() asset.Host
 	s.service.hostRepo = hostUseCase.GetHostRepo()
 }
 
+// EnableCluster 多副本部署时经 Redis 广播证书吊销并启用副本间转发，未配置 relay_addr 时不启用转发
+func (s *GRPCServer) EnableCluster(rdb *redis.Client) {
+	if rdb == nil {
+		return
+	}
+	s.certs.EnableBroadcast(rdb)
+	if s.conf.Agent.RelayAddr == "" {
+		return
+	}
+	s.cluster = NewCluster(s.hub, rdb, s.conf.Agent.RelayAddr)
+	s.cluster.dispatch = s.service.dispatchResponse
+}
+
 // Start 启动gRPC服务器
 func (s *GRPCServer) Start() error {
 	if !s.conf.Agent.Enabled {
@@ -86,9 +111,10 @@ func (s *GRPCServer) Start() error {
 	if err := s.tlsMgr.InitCA(); err != nil {
 		return fmt.Errorf("初始化CA失败: %w", err)
 	}
+	s.certs.Start()
 
-	// 加载TLS配置（传入配置的额外地址）
-	tlsConfig, err := s.tlsMgr.LoadServerTLSConfig(s.conf.Agent.ServerAddresses)
+	// 加载TLS配置（传入配置的额外地址和副本转发地址）
+	tlsConfig, err := s.tlsMgr.LoadServerTLSConfig(serverCertAddresses(s.conf.Agent.ServerAddresses, s.conf.Agent.RelayAddr))
 	if err != nil {
 		return fmt.Errorf("加载TLS配置失败: %w", err)
 	}
@@ -96,6 +122,14 @@ func (s *GRPCServer) Start() error {
 	// 创建gRPC服务器
 	s.server = grpc.NewServer(grpc.Creds(credentials.NewTLS(tlsConfig)))
 	pb.RegisterAgentHubServer(s.server, s.service)
+	if s.cluster != nil {
+		clientTLS, err := s.tlsMgr.LoadRelayClientTLSConfig(s.cluster.addr)
+		if err != nil {
+			return fmt.Errorf("加载副本转发证书失败: %w", err)
+		}
+		pb.RegisterAgentRelayServer(s.server, s.cluster)
+		s.cluster.Start(clientTLS)
+	}
 
 	addr := fmt.Sprintf(":%d", s.conf.Server.RPCPort)
 	lis, err := net.Listen("tcp", addr)
@@ -112,8 +146,12 @@ func (s *GRPCServer) Stop() {
 	if s.server != nil {
 		appLogger.Info("正在停止Agent gRPC服务器...")
 
-		// 先关闭所有Agent连接
+		// 先注销副本登记，再关闭所有Agent连接
+		if s.cluster != nil {
+			s.cluster.Stop()
+		}
 		s.hub.CloseAll()
+		s.certs.Stop()
 
 		// 使用带超时的优雅关闭
 		done := make(chan struct{})
@@ -3,6 +3,7 @@ package agent
 import (
 	"github.com/gin-gonic/gin"
 	assetbiz "github.com/ydcloud-dy/opshub/internal/biz/asset"
+	rbacbiz "github.com/ydcloud-dy/opshub/internal/biz/rbac"
 	rbacService "github.com/ydcloud-dy/opshub/internal/service/rbac"
 	"gorm.io/gorm"
 )
@@ -15,6 +16,9 @@ type HTTPServer struct {
 	authMiddleware *rbacService.AuthMiddleware
 	tlsMgr         *TLSManager
 	grpcServer     *GRPCServer
+	upgrades       *UpgradeController
+	tunnels        *TunnelManager
+	certs          *CertManager
 }
 
 // NewHTTPServer 创建Agent HTTP服务
@@ -24,6 +28,8 @@ func NewHTTPServer(
 	db *gorm.DB,
 	authMiddleware *rbacService.AuthMiddleware,
 ) *HTTPServer {
+	upgrades := NewUpgradeController(grpcServer)
+	upgrades.RecoverRollouts()
 	return &HTTPServer{
 		hub:            grpcServer.Hub(),
 		hostUseCase:    hostUseCase,
@@ -31,6 +37,9 @@ func NewHTTPServer(
 		authMiddleware: authMiddleware,
 		tlsMgr:         grpcServer.TLSManager(),
 		grpcServer:     grpcServer,
+		upgrades:       upgrades,
+		tunnels:        NewTunnelManager(grpcServer),
+		certs:          grpcServer.Certs(),
 	}
 }
 
@@ -42,11 +51,28 @@ func (s *HTTPServer) RegisterRoutes(r *gin.RouterGroup) {
 		agents.GET("/:hostId/status", s.GetAgentStatus)
 		agents.POST("/:hostId/deploy", s.DeployAgent)
 		agents.PUT("/:hostId/update", s.UpdateAgent)
+		agents.POST("/:hostId/upgrade", s.authMiddleware.RequireAdmin(), s.UpgradeAgentViaChannel)
+		agents.GET("/upgrade/package", s.authMiddleware.RequireAdmin(), s.GetUpgradePackage)
+		agents.GET("/upgrade/rollouts", s.authMiddleware.RequireAdmin(), s.ListUpgradeRollouts)
+		agents.POST("/upgrade/rollouts", s.authMiddleware.RequireAdmin(), s.CreateUpgradeRollout)
+		agents.GET("/upgrade/rollouts/:id", s.authMiddleware.RequireAdmin(), s.GetUpgradeRollout)
+		agents.POST("/upgrade/rollouts/:id/resume", s.authMiddleware.RequireAdmin(), s.ResumeUpgradeRollout)
+		agents.POST("/upgrade/rollouts/:id/cancel", s.authMiddleware.RequireAdmin(), s.CancelUpgradeRollout)
+		agents.GET("/policy-violations", s.ListPolicyViolations)
+		agents.GET("/:hostId/discovery", s.GetDiscoveredServices)
+		agents.POST("/:hostId/discovery/import", s.authMiddleware.RequireHostPermission(rbacbiz.PermissionEdit), s.ImportDiscoveredServices)
+		agents.POST("/:hostId/discovery/rescan", s.RescanServices)
+		agents.GET("/tunnels", s.ListTunnels)
+		agents.POST("/:hostId/tunnels", s.authMiddleware.RequireHostPermission(rbacbiz.PermissionTunnel), s.OpenTunnel)
+		agents.DELETE("/:hostId/tunnels/:sessionId", s.authMiddleware.RequireHostPermission(rbacbiz.PermissionTunnel), s.CloseTunnel)
+		agents.GET("/:hostId/certs", s.authMiddleware.RequireAdmin(), s.ListAgentCerts)
+		agents.POST("/:hostId/certs/revoke", s.authMiddleware.RequireAdmin(), s.RevokeAgentCert)
 		agents.DELETE("/:hostId/uninstall", s.UninstallAgent)
 		agents.POST("/batch-deploy", s.BatchDeployAgent)
 		agents.GET("/:hostId/terminal", s.HandleTerminal)
 		agents.GET("/:hostId/files", s.ListFiles)
 		agents.POST("/:hostId/files/upload", s.UploadFile)
+		agents.POST("/:hostId/files/upload-dir", s.UploadDir)
 		agents.GET("/:hostId/files/download", s.DownloadFile)
 		agents.DELETE("/:hostId/files", s.DeleteFile)
 		agents.POST("/:hostId/execute", s.ExecuteCommand)
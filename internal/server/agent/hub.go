@@ -8,6 +8,7 @@ import (
 
 	pb "github.com/ydcloud-dy/opshub/pkg/agentproto"
 	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
+	"github.com/ydcloud-dy/opshub/pkg/tunnel"
 	"go.uber.org/zap"
 )
 
@@ -22,6 +23,47 @@ type AgentStream struct {
 	// 请求响应回调
 	pending  map[string]interface{}
 	pendMu   sync.Mutex
+	// 连接使用的客户端证书，由 mu 保护；经副本转发的代理流为空
+	certSerial   string
+	certNotAfter time.Time
+	// 被服务端主动断开（如证书吊销）时关闭
+	kickCh     chan struct{}
+	kickOnce   sync.Once
+	kickReason string
+}
+
+// setCert 记录连接使用的客户端证书
+func (s *AgentStream) setCert(serial string, notAfter time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.certSerial, s.certNotAfter = serial, notAfter
+}
+
+// cert 返回连接使用的客户端证书序列号和过期时间
+func (s *AgentStream) cert() (string, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.certSerial, s.certNotAfter
+}
+
+// kick 要求接收协程断开连接
+func (s *AgentStream) kick(reason string) {
+	if s.kickCh == nil {
+		return
+	}
+	s.kickOnce.Do(func() {
+		s.mu.Lock()
+		s.kickReason = reason
+		s.mu.Unlock()
+		close(s.kickCh)
+	})
+}
+
+// KickReason 被主动断开的原因
+func (s *AgentStream) KickReason() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.kickReason
 }
 
 // Send 线程安全地发送消息
@@ -66,14 +108,28 @@ type AgentHub struct {
 	// 终端输出回调: sessionID -> callback
 	termCallbacks map[string]func(data []byte)
 	termMu        sync.RWMutex
+	// 流式命令输出回调: requestID -> callback
+	cmdCallbacks map[string]func(chunk *pb.CommandOutputChunk)
+	cmdMu        sync.RWMutex
+	// 升级后重新注册的等待者: upgradeID -> channel
+	upgradeWaiters map[string]chan *pb.RegisterRequest
+	upgradeMu      sync.Mutex
+	// 端口转发连接: connID -> endpoint
+	tunnels  map[string]*tunnel.Endpoint
+	tunnelMu sync.RWMutex
+	// 多副本转发，单副本部署时为 nil
+	cluster *Cluster
 }
 
 // NewAgentHub 创建AgentHub
 func NewAgentHub() *AgentHub {
 	return &AgentHub{
-		byAgentID:     make(map[string]*AgentStream),
-		byHostID:      make(map[uint]*AgentStream),
-		termCallbacks: make(map[string]func(data []byte)),
+		byAgentID:      make(map[string]*AgentStream),
+		byHostID:       make(map[uint]*AgentStream),
+		termCallbacks:  make(map[string]func(data []byte)),
+		cmdCallbacks:   make(map[string]func(chunk *pb.CommandOutputChunk)),
+		upgradeWaiters: make(map[string]chan *pb.RegisterRequest),
+		tunnels:        make(map[string]*tunnel.Endpoint),
 	}
 }
 
@@ -86,6 +142,7 @@ func (h *AgentHub) Register(agentID string, hostID uint, stream pb.AgentHub_Conn
 		SendCh:  make(chan *pb.ServerMessage, 64),
 		DoneCh:  make(chan struct{}),
 		pending: make(map[string]interface{}),
+		kickCh:  make(chan struct{}),
 	}
 
 	h.mu.Lock()
@@ -104,6 +161,10 @@ func (h *AgentHub) Register(agentID string, hostID uint, stream pb.AgentHub_Conn
 	totalAgents := len(h.byHostID)
 	h.mu.Unlock()
 
+	if h.cluster != nil {
+		h.cluster.claim(hostID, agentID)
+	}
+
 	appLogger.Info("Agent已注册",
 		zap.String("agent_id", agentID),
 		zap.Uint("host_id", hostID),
@@ -116,7 +177,8 @@ func (h *AgentHub) Register(agentID string, hostID uint, stream pb.AgentHub_Conn
 func (h *AgentHub) Unregister(agentID string) {
 	h.mu.Lock()
 	var hostID uint
-	if as, ok := h.byAgentID[agentID]; ok {
+	as, found := h.byAgentID[agentID]
+	if found {
 		hostID = as.HostID
 		delete(h.byAgentID, agentID)
 		delete(h.byHostID, as.HostID)
@@ -129,6 +191,10 @@ func (h *AgentHub) Unregister(agentID string) {
 	totalAgents := len(h.byHostID)
 	h.mu.Unlock()
 
+	if found && h.cluster != nil {
+		h.cluster.unclaim(hostID)
+	}
+
 	appLogger.Info("Agent已注销",
 		zap.String("agent_id", agentID),
 		zap.Uint("host_id", hostID),
@@ -136,14 +202,54 @@ func (h *AgentHub) Unregister(agentID string) {
 	)
 }
 
-// GetByHostID 根据HostID获取Agent流
+// GetByHostID 根据HostID获取Agent流，Agent连接在其他副本上时返回转发代理流
 func (h *AgentHub) GetByHostID(hostID uint) (*AgentStream, bool) {
+	as, ok := h.getLocal(hostID)
+	if !ok && h.cluster != nil {
+		return h.cluster.remoteStream(hostID)
+	}
+	return as, ok
+}
+
+// getLocal 获取连接在本副本上的Agent流
+func (h *AgentHub) getLocal(hostID uint) (*AgentStream, bool) {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 	as, ok := h.byHostID[hostID]
 	return as, ok
 }
 
+// localAgents 本副本持有的Agent连接: hostID → agentID
+func (h *AgentHub) localAgents() map[uint]string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	agents := make(map[uint]string, len(h.byHostID))
+	for hostID, as := range h.byHostID {
+		agents[hostID] = as.AgentID
+	}
+	return agents
+}
+
+// localStreams 本副本持有的Agent连接
+func (h *AgentHub) localStreams() []*AgentStream {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	streams := make([]*AgentStream, 0, len(h.byAgentID))
+	for _, as := range h.byAgentID {
+		streams = append(streams, as)
+	}
+	return streams
+}
+
+// Disconnect 主动断开连接在本副本上的Agent，返回是否找到连接
+func (h *AgentHub) Disconnect(agentID, reason string) bool {
+	as, ok := h.GetByAgentID(agentID)
+	if ok {
+		as.kick(reason)
+	}
+	return ok
+}
+
 // GetByAgentID 根据AgentID获取Agent流
 func (h *AgentHub) GetByAgentID(agentID string) (*AgentStream, bool) {
 	h.mu.RLock()
@@ -155,15 +261,20 @@ func (h *AgentHub) GetByAgentID(agentID string) (*AgentStream, bool) {
 // IsOnline 检查Agent是否在线
 func (h *AgentHub) IsOnline(hostID uint) bool {
 	h.mu.RLock()
-	defer h.mu.RUnlock()
 	_, ok := h.byHostID[hostID]
+	totalAgents := len(h.byHostID)
+	h.mu.RUnlock()
 
 	appLogger.Info("Checking agent online status",
 		zap.Uint("host_id", hostID),
 		zap.Bool("is_online", ok),
-		zap.Int("total_agents", len(h.byHostID)),
+		zap.Int("total_agents", totalAgents),
 	)
 
+	// 不在本副本时查询是否连接在其他副本上
+	if !ok && h.cluster != nil {
+		return h.cluster.isOnline(hostID)
+	}
 	return ok
 }
 
@@ -191,6 +302,98 @@ func (h *AgentHub) HandleTerminalOutput(sessionID string, data []byte) {
 	}
 }
 
+// RegisterCommandCallback 注册流式命令输出回调
+func (h *AgentHub) RegisterCommandCallback(requestID string, cb func(chunk *pb.CommandOutputChunk)) {
+	h.cmdMu.Lock()
+	defer h.cmdMu.Unlock()
+	h.cmdCallbacks[requestID] = cb
+}
+
+// UnregisterCommandCallback 注销流式命令输出回调
+func (h *AgentHub) UnregisterCommandCallback(requestID string) {
+	h.cmdMu.Lock()
+	defer h.cmdMu.Unlock()
+	delete(h.cmdCallbacks, requestID)
+}
+
+// HandleCommandOutput 处理流式命令输出
+func (h *AgentHub) HandleCommandOutput(chunk *pb.CommandOutputChunk) {
+	h.cmdMu.RLock()
+	cb, ok := h.cmdCallbacks[chunk.RequestId]
+	h.cmdMu.RUnlock()
+	if ok {
+		cb(chunk)
+	}
+}
+
+// RegisterUpgradeWaiter 等待Agent升级后携带 upgradeID 重新注册
+func (h *AgentHub) RegisterUpgradeWaiter(upgradeID string) <-chan *pb.RegisterRequest {
+	ch := make(chan *pb.RegisterRequest, 1)
+	h.upgradeMu.Lock()
+	h.upgradeWaiters[upgradeID] = ch
+	h.upgradeMu.Unlock()
+	return ch
+}
+
+// UnregisterUpgradeWaiter 注销升级等待者
+func (h *AgentHub) UnregisterUpgradeWaiter(upgradeID string) {
+	h.upgradeMu.Lock()
+	delete(h.upgradeWaiters, upgradeID)
+	h.upgradeMu.Unlock()
+}
+
+// HandleUpgradeRegister 处理携带升级结果的注册
+func (h *AgentHub) HandleUpgradeRegister(req *pb.RegisterRequest) {
+	h.upgradeMu.Lock()
+	ch, ok := h.upgradeWaiters[req.UpgradeId]
+	delete(h.upgradeWaiters, req.UpgradeId)
+	h.upgradeMu.Unlock()
+	if ok {
+		ch <- req
+	}
+}
+
+// RegisterTunnel 注册端口转发连接
+func (h *AgentHub) RegisterTunnel(e *tunnel.Endpoint) {
+	h.tunnelMu.Lock()
+	h.tunnels[e.ID()] = e
+	h.tunnelMu.Unlock()
+}
+
+// UnregisterTunnel 注销端口转发连接
+func (h *AgentHub) UnregisterTunnel(connID string) {
+	h.tunnelMu.Lock()
+	delete(h.tunnels, connID)
+	h.tunnelMu.Unlock()
+}
+
+func (h *AgentHub) getTunnel(connID string) *tunnel.Endpoint {
+	h.tunnelMu.RLock()
+	defer h.tunnelMu.RUnlock()
+	return h.tunnels[connID]
+}
+
+// HandleTunnelData 分发Agent发来的隧道数据
+func (h *AgentHub) HandleTunnelData(data *pb.TunnelData) {
+	if e := h.getTunnel(data.ConnId); e != nil {
+		e.Deliver(data.Data)
+	}
+}
+
+// HandleTunnelAck 分发Agent的隧道确认
+func (h *AgentHub) HandleTunnelAck(ack *pb.TunnelAck) {
+	if e := h.getTunnel(ack.ConnId); e != nil {
+		e.Ack(int(ack.Bytes))
+	}
+}
+
+// HandleTunnelClose 分发Agent关闭隧道连接
+func (h *AgentHub) HandleTunnelClose(c *pb.TunnelClose) {
+	if e := h.getTunnel(c.ConnId); e != nil {
+		e.Shutdown(c.Error)
+	}
+}
+
 // WaitResponse 等待Agent响应，带超时
 func (h *AgentHub) WaitResponse(as *AgentStream, requestID string, timeout time.Duration) (any, error) {
 	ch := as.RegisterPending(requestID)
@@ -47,7 +47,7 @@ func (s *HTTPServer) GenerateInstallPackage(c *gin.Context) {
 	agentID := uuid.New().String()
 
 	// 签发Agent证书
-	certPEM, keyPEM, err := s.tlsMgr.SignAgentCert(agentID, "")
+	certPEM, keyPEM, err := s.certs.Issue(agentID, "")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": fmt.Sprintf("签发证书失败: %v", err)})
 		return
@@ -66,7 +66,7 @@ func (s *HTTPServer) GenerateInstallPackage(c *gin.Context) {
 	}
 
 	// 查找原始安装包
-	srcTarball, err := s.findAgentTarball()
+	srcTarball, err := findAgentTarball(s.grpcServer.conf.Agent.BinaryDir)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": fmt.Sprintf("查找安装包失败: %v", err)})
 		return
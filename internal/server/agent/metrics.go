@@ -0,0 +1,248 @@
+package agent
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/ydcloud-dy/opshub/internal/biz/asset"
+	"github.com/ydcloud-dy/opshub/internal/cache"
+	pb "github.com/ydcloud-dy/opshub/pkg/agentproto"
+	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// hostLabels 所有主机指标共有的标签
+var hostLabels = []string{"host_id", "host", "ip", "group"}
+
+func hostDesc(name, help string, extra ...string) *prometheus.Desc {
+	return prometheus.NewDesc("srehub_host_"+name, help, append(append([]string{}, hostLabels...), extra...), nil)
+}
+
+var (
+	cpuUsageDesc        = hostDesc("cpu_usage_percent", "CPU usage reported by the agent")
+	memoryUsageDesc     = hostDesc("memory_usage_percent", "Memory usage reported by the agent")
+	memoryTotalDesc     = hostDesc("memory_total_bytes", "Total memory")
+	memoryUsedDesc      = hostDesc("memory_used_bytes", "Used memory (total minus available)")
+	loadDesc            = hostDesc("load", "Load average", "period")
+	uptimeDesc          = hostDesc("uptime_seconds", "Host uptime")
+	collectedDesc       = hostDesc("metrics_collected_timestamp_seconds", "Unix time of the last heartbeat carrying metrics")
+	diskUsageDesc       = hostDesc("disk_usage_percent", "Filesystem usage per mountpoint", "mountpoint", "device", "fstype")
+	diskTotalDesc       = hostDesc("disk_total_bytes", "Filesystem size per mountpoint", "mountpoint", "device", "fstype")
+	diskUsedDesc        = hostDesc("disk_used_bytes", "Filesystem used bytes per mountpoint", "mountpoint", "device", "fstype")
+	diskInodesDesc      = hostDesc("disk_inodes_usage_percent", "Inode usage per mountpoint", "mountpoint", "device", "fstype")
+	netRxDesc           = hostDesc("network_receive_bytes_total", "Bytes received per interface", "interface")
+	netTxDesc           = hostDesc("network_transmit_bytes_total", "Bytes transmitted per interface", "interface")
+	netRxRateDesc       = hostDesc("network_receive_bytes_per_second", "Receive throughput per interface between two heartbeats", "interface")
+	netTxRateDesc       = hostDesc("network_transmit_bytes_per_second", "Transmit throughput per interface between two heartbeats", "interface")
+	netRxErrDesc        = hostDesc("network_receive_errors_total", "Receive errors per interface", "interface")
+	netTxErrDesc        = hostDesc("network_transmit_errors_total", "Transmit errors per interface", "interface")
+	tcpDesc             = hostDesc("tcp_connections", "TCP connections by state", "state")
+	processCPUDesc      = hostDesc("process_cpu_usage_percent", "CPU usage of the top processes, summed by name (100 = one core)", "process")
+	processRSSDesc      = hostDesc("process_memory_rss_bytes", "Resident memory of the top processes, summed by name", "process")
+	processMemUsageDesc = hostDesc("process_memory_usage_percent", "Memory usage of the top processes, summed by name", "process")
+)
+
+// heartbeatMetrics 将心跳中的指标转换为缓存结构
+func heartbeatMetrics(hostID uint, req *pb.HeartbeatRequest, now time.Time) *cache.HostMetricsCache {
+	m := &cache.HostMetricsCache{
+		HostID:        hostID,
+		CPUUsage:      req.CpuUsage,
+		MemoryUsage:   req.MemoryUsage,
+		DiskUsage:     req.DiskUsage,
+		MemoryTotal:   req.MemoryTotal,
+		MemoryUsed:    req.MemoryUsed,
+		Load1:         req.Load1,
+		Load5:         req.Load5,
+		Load15:        req.Load15,
+		UptimeSeconds: req.UptimeSeconds,
+		CollectedAt:   now,
+	}
+	for _, d := range req.Disks {
+		m.Disks = append(m.Disks, cache.DiskMetrics{
+			Mountpoint:  d.Mountpoint,
+			Device:      d.Device,
+			FSType:      d.Fstype,
+			TotalBytes:  d.TotalBytes,
+			UsedBytes:   d.UsedBytes,
+			Usage:       d.Usage,
+			InodesUsage: d.InodesUsage,
+		})
+	}
+	var rx, tx float64
+	for _, n := range req.Networks {
+		m.Networks = append(m.Networks, cache.NetworkMetrics{
+			Name:          n.Name,
+			RxBytes:       n.RxBytes,
+			TxBytes:       n.TxBytes,
+			RxBytesPerSec: n.RxBytesPerSec,
+			TxBytesPerSec: n.TxBytesPerSec,
+			RxErrors:      n.RxErrors,
+			TxErrors:      n.TxErrors,
+		})
+		rx += n.RxBytesPerSec
+		tx += n.TxBytesPerSec
+	}
+	m.NetworkIn, m.NetworkOut = uint64(rx), uint64(tx)
+	if len(req.TcpStates) > 0 {
+		m.TCPStates = make(map[string]int, len(req.TcpStates))
+		for state, count := range req.TcpStates {
+			m.TCPStates[state] = int(count)
+		}
+	}
+	for _, p := range req.TopProcesses {
+		m.TopProcesses = append(m.TopProcesses, cache.ProcessMetrics{
+			PID:         p.Pid,
+			Name:        p.Name,
+			CPUUsage:    p.CpuUsage,
+			MemoryRSS:   p.MemoryRss,
+			MemoryUsage: p.MemoryUsage,
+		})
+	}
+	return m
+}
+
+// HostMetricsCollector 将 Agent 心跳上报的主机指标导出为 Prometheus 指标
+// 数据取自 Redis，多副本部署时任一副本都能导出全部主机
+type HostMetricsCollector struct {
+	db     *gorm.DB
+	source func(ctx context.Context) ([]*cache.HostMetricsCache, error)
+}
+
+// NewHostMetricsCollector 创建主机指标导出器
+func NewHostMetricsCollector(db *gorm.DB, agentCache *cache.AgentCache) *HostMetricsCollector {
+	return &HostMetricsCollector{db: db, source: agentCache.ListHostMetrics}
+}
+
+// Describe 指标随主机动态变化，作为 unchecked collector 注册
+func (c *HostMetricsCollector) Describe(chan<- *prometheus.Desc) {}
+
+// Collect 实现 prometheus.Collector
+func (c *HostMetricsCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	list, err := c.source(ctx)
+	if err != nil {
+		appLogger.Warn("读取主机指标失败", zap.Error(err))
+		return
+	}
+	if len(list) == 0 {
+		return
+	}
+	labels := c.hostLabels(ctx, list)
+
+	gauge := func(desc *prometheus.Desc, v float64, lv ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, lv...)
+	}
+	counter := func(desc *prometheus.Desc, v float64, lv ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, v, lv...)
+	}
+
+	for _, m := range list {
+		host, ok := labels[m.HostID]
+		if !ok {
+			continue // 主机已删除
+		}
+		with := func(extra ...string) []string {
+			return append(append([]string{}, host...), extra...)
+		}
+
+		gauge(cpuUsageDesc, m.CPUUsage, host...)
+		gauge(memoryUsageDesc, m.MemoryUsage, host...)
+		gauge(collectedDesc, float64(m.CollectedAt.Unix()), host...)
+		// 旧版本 Agent 心跳只有使用率，其余指标不导出以免产生误导性的 0 值
+		if m.MemoryTotal > 0 {
+			gauge(memoryTotalDesc, float64(m.MemoryTotal), host...)
+			gauge(memoryUsedDesc, float64(m.MemoryUsed), host...)
+		}
+		if m.UptimeSeconds > 0 {
+			gauge(loadDesc, m.Load1, with("1m")...)
+			gauge(loadDesc, m.Load5, with("5m")...)
+			gauge(loadDesc, m.Load15, with("15m")...)
+			gauge(uptimeDesc, float64(m.UptimeSeconds), host...)
+		}
+		for _, d := range m.Disks {
+			lv := with(d.Mountpoint, d.Device, d.FSType)
+			gauge(diskUsageDesc, d.Usage, lv...)
+			gauge(diskTotalDesc, float64(d.TotalBytes), lv...)
+			gauge(diskUsedDesc, float64(d.UsedBytes), lv...)
+			gauge(diskInodesDesc, d.InodesUsage, lv...)
+		}
+		for _, n := range m.Networks {
+			lv := with(n.Name)
+			counter(netRxDesc, float64(n.RxBytes), lv...)
+			counter(netTxDesc, float64(n.TxBytes), lv...)
+			gauge(netRxRateDesc, n.RxBytesPerSec, lv...)
+			gauge(netTxRateDesc, n.TxBytesPerSec, lv...)
+			counter(netRxErrDesc, float64(n.RxErrors), lv...)
+			counter(netTxErrDesc, float64(n.TxErrors), lv...)
+		}
+		for state, count := range m.TCPStates {
+			gauge(tcpDesc, float64(count), with(state)...)
+		}
+		// 不使用 pid 作为标签：进程重启即产生新的时间序列；同名进程（如多个 worker）合并
+		for _, p := range processesByName(m.TopProcesses) {
+			lv := with(p.Name)
+			gauge(processCPUDesc, p.CPUUsage, lv...)
+			gauge(processRSSDesc, float64(p.MemoryRSS), lv...)
+			gauge(processMemUsageDesc, p.MemoryUsage, lv...)
+		}
+	}
+}
+
+// processesByName 按进程名汇总资源占用，保持首次出现的顺序
+func processesByName(list []cache.ProcessMetrics) []cache.ProcessMetrics {
+	index := make(map[string]int, len(list))
+	var merged []cache.ProcessMetrics
+	for _, p := range list {
+		i, ok := index[p.Name]
+		if !ok {
+			index[p.Name] = len(merged)
+			merged = append(merged, cache.ProcessMetrics{Name: p.Name})
+			i = len(merged) - 1
+		}
+		merged[i].CPUUsage += p.CPUUsage
+		merged[i].MemoryRSS += p.MemoryRSS
+		merged[i].MemoryUsage += p.MemoryUsage
+	}
+	return merged
+}
+
+// hostLabels 查询主机名称、IP 和所属分组，返回 hostID -> 公共标签值
+func (c *HostMetricsCollector) hostLabels(ctx context.Context, list []*cache.HostMetricsCache) map[uint][]string {
+	ids := make([]uint, 0, len(list))
+	for _, m := range list {
+		ids = append(ids, m.HostID)
+	}
+
+	var hosts []asset.Host
+	if err := c.db.WithContext(ctx).Select("id", "name", "ip", "group_id").Where("id IN ?", ids).Find(&hosts).Error; err != nil {
+		appLogger.Warn("查询主机标签失败", zap.Error(err))
+		return nil
+	}
+	groupIDs := make([]uint, 0, len(hosts))
+	for _, h := range hosts {
+		if h.GroupID > 0 {
+			groupIDs = append(groupIDs, h.GroupID)
+		}
+	}
+	groupNames := make(map[uint]string)
+	if len(groupIDs) > 0 {
+		var groups []asset.AssetGroup
+		if err := c.db.WithContext(ctx).Select("id", "name").Where("id IN ?", groupIDs).Find(&groups).Error; err != nil {
+			appLogger.Warn("查询主机分组失败", zap.Error(err))
+		}
+		for _, g := range groups {
+			groupNames[g.ID] = g.Name
+		}
+	}
+
+	labels := make(map[uint][]string, len(hosts))
+	for _, h := range hosts {
+		labels[h.ID] = []string{strconv.FormatUint(uint64(h.ID), 10), h.Name, h.IP, groupNames[h.GroupID]}
+	}
+	return labels
+}
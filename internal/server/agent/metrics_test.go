@@ -0,0 +1,126 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+	assetbiz "github.com/ydcloud-dy/opshub/internal/biz/asset"
+	"github.com/ydcloud-dy/opshub/internal/cache"
+	"github.com/ydcloud-dy/opshub/internal/testutil"
+	pb "github.com/ydcloud-dy/opshub/pkg/agentproto"
+	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
+	"go.uber.org/zap"
+)
+
+func TestHeartbeatMetrics(t *testing.T) {
+	now := time.Now()
+	m := heartbeatMetrics(7, &pb.HeartbeatRequest{
+		CpuUsage: 12.5,
+		Networks: []*pb.NetworkStats{
+			{Name: "eth0", RxBytesPerSec: 100, TxBytesPerSec: 10},
+			{Name: "eth1", RxBytesPerSec: 50.5, TxBytesPerSec: 5},
+		},
+		TcpStates: map[string]int32{"ESTABLISHED": 3},
+		TopProcesses: []*pb.ProcessStats{
+			{Pid: 1, Name: "systemd", CpuUsage: 0.5, MemoryRss: 4096},
+		},
+	}, now)
+
+	if m.HostID != 7 || m.CPUUsage != 12.5 || !m.CollectedAt.Equal(now) {
+		t.Fatalf("基础字段转换错误: %+v", m)
+	}
+	if m.NetworkIn != 150 || m.NetworkOut != 15 {
+		t.Fatalf("网络速率汇总错误: in=%d out=%d", m.NetworkIn, m.NetworkOut)
+	}
+	if m.TCPStates["ESTABLISHED"] != 3 || len(m.TopProcesses) != 1 || m.TopProcesses[0].Name != "systemd" {
+		t.Fatalf("TCP/进程转换错误: %+v", m)
+	}
+}
+
+func TestHostMetricsCollector(t *testing.T) {
+	appLogger.Log = zap.NewNop()
+	mr := miniredis.RunT(t)
+	agentCache := cache.NewAgentCache(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+
+	db := testutil.NewDB(t, &assetbiz.Host{}, &assetbiz.AssetGroup{})
+	group := &assetbiz.AssetGroup{Name: "prod", Code: "prod"}
+	group.ID = 1
+	db.Create(group)
+	for _, h := range []struct {
+		id       uint
+		name, ip string
+	}{{1, "web-1", "10.0.0.1"}, {2, "legacy-1", "10.0.0.2"}} {
+		host := &assetbiz.Host{Name: h.name, IP: h.ip, GroupID: 1}
+		host.ID = h.id
+		db.Create(host)
+	}
+
+	ctx := context.Background()
+	now := time.Now()
+	agentCache.SetHostMetrics(ctx, 1, heartbeatMetrics(1, &pb.HeartbeatRequest{
+		CpuUsage:      20,
+		MemoryUsage:   40,
+		MemoryTotal:   1000,
+		MemoryUsed:    400,
+		Load1:         1.5,
+		Load5:         1,
+		Load15:        0.5,
+		UptimeSeconds: 3600,
+		Disks:         []*pb.DiskStats{{Mountpoint: "/", Device: "/dev/vda1", Fstype: "ext4", TotalBytes: 100, UsedBytes: 90, Usage: 90}},
+		Networks:      []*pb.NetworkStats{{Name: "eth0", RxBytes: 1024, RxBytesPerSec: 8}},
+		TcpStates:     map[string]int32{"ESTABLISHED": 3},
+		TopProcesses:  []*pb.ProcessStats{{Pid: 42, Name: "java", CpuUsage: 150, MemoryRss: 512}, {Pid: 43, Name: "java", CpuUsage: 30}, {Pid: 7, Name: "nginx", CpuUsage: 2}},
+	}, now))
+	// 旧版本 Agent 只上报使用率
+	agentCache.SetHostMetrics(ctx, 2, heartbeatMetrics(2, &pb.HeartbeatRequest{CpuUsage: 5}, now))
+	// 已删除主机的指标不导出
+	agentCache.SetHostMetrics(ctx, 3, heartbeatMetrics(3, &pb.HeartbeatRequest{CpuUsage: 99}, now))
+
+	collector := NewHostMetricsCollector(db, agentCache)
+	expected := `
+# HELP srehub_host_cpu_usage_percent CPU usage reported by the agent
+# TYPE srehub_host_cpu_usage_percent gauge
+srehub_host_cpu_usage_percent{group="prod",host="legacy-1",host_id="2",ip="10.0.0.2"} 5
+srehub_host_cpu_usage_percent{group="prod",host="web-1",host_id="1",ip="10.0.0.1"} 20
+# HELP srehub_host_disk_usage_percent Filesystem usage per mountpoint
+# TYPE srehub_host_disk_usage_percent gauge
+srehub_host_disk_usage_percent{device="/dev/vda1",fstype="ext4",group="prod",host="web-1",host_id="1",ip="10.0.0.1",mountpoint="/"} 90
+# HELP srehub_host_load Load average
+# TYPE srehub_host_load gauge
+srehub_host_load{group="prod",host="web-1",host_id="1",ip="10.0.0.1",period="15m"} 0.5
+srehub_host_load{group="prod",host="web-1",host_id="1",ip="10.0.0.1",period="1m"} 1.5
+srehub_host_load{group="prod",host="web-1",host_id="1",ip="10.0.0.1",period="5m"} 1
+# HELP srehub_host_network_receive_bytes_total Bytes received per interface
+# TYPE srehub_host_network_receive_bytes_total counter
+srehub_host_network_receive_bytes_total{group="prod",host="web-1",host_id="1",interface="eth0",ip="10.0.0.1"} 1024
+# HELP srehub_host_process_cpu_usage_percent CPU usage of the top processes, summed by name (100 = one core)
+# TYPE srehub_host_process_cpu_usage_percent gauge
+srehub_host_process_cpu_usage_percent{group="prod",host="web-1",host_id="1",ip="10.0.0.1",process="java"} 180
+srehub_host_process_cpu_usage_percent{group="prod",host="web-1",host_id="1",ip="10.0.0.1",process="nginx"} 2
+# HELP srehub_host_tcp_connections TCP connections by state
+# TYPE srehub_host_tcp_connections gauge
+srehub_host_tcp_connections{group="prod",host="web-1",host_id="1",ip="10.0.0.1",state="ESTABLISHED"} 3
+`
+	err := promtestutil.CollectAndCompare(collector, strings.NewReader(expected),
+		"srehub_host_cpu_usage_percent", "srehub_host_disk_usage_percent", "srehub_host_load",
+		"srehub_host_network_receive_bytes_total", "srehub_host_process_cpu_usage_percent", "srehub_host_tcp_connections")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 指标过期后不再返回，索引中超过 TTL 的主机被清理
+	mr.FastForward(cache.HostMetricsTTL + time.Second)
+	mr.ZAdd(cache.HostMetricsIndex, float64(now.Add(-cache.HostMetricsTTL-time.Minute).Unix()), "9")
+	list, err := agentCache.ListHostMetrics(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if members, _ := mr.ZMembers(cache.HostMetricsIndex); len(list) != 0 || len(members) != 3 {
+		t.Fatalf("期望过期数据不再返回且索引只清理超时成员: list=%d members=%v", len(list), members)
+	}
+}
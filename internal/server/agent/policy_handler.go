@@ -0,0 +1,38 @@
+package agent
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	agentmodel "github.com/ydcloud-dy/opshub/internal/agent"
+)
+
+// ListPolicyViolations Agent本地策略拒绝记录，支持按主机和类型过滤
+func (s *HTTPServer) ListPolicyViolations(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	query := s.db.Model(&agentmodel.AgentPolicyViolation{})
+	if hostID, _ := strconv.ParseUint(c.Query("hostId"), 10, 64); hostID > 0 {
+		query = query.Where("host_id = ?", hostID)
+	}
+	if kind := c.Query("kind"); kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+
+	var total int64
+	var list []agentmodel.AgentPolicyViolation
+	query.Count(&total)
+	if err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success", "data": gin.H{"list": list, "total": total}})
+}
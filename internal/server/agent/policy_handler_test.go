@@ -0,0 +1,62 @@
+package agent
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	agentmodel "github.com/ydcloud-dy/opshub/internal/agent"
+	"github.com/ydcloud-dy/opshub/internal/testutil"
+	pb "github.com/ydcloud-dy/opshub/pkg/agentproto"
+	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
+	"go.uber.org/zap"
+)
+
+func TestPolicyViolation_RecordAndList(t *testing.T) {
+	appLogger.Log = zap.NewNop()
+	db := testutil.NewDB(t, &agentmodel.AgentPolicyViolation{})
+
+	svc := &AgentService{db: db}
+	svc.handlePolicyViolation(&AgentStream{AgentID: "a1", HostID: 1}, &pb.PolicyViolation{
+		RequestId: "r1", Kind: agentmodel.PolicyViolationCommand, Target: "rm -rf /", Reason: `命令 "rm -rf /" 不在允许列表中`, Timestamp: 1700000000,
+	})
+	svc.handlePolicyViolation(&AgentStream{AgentID: "a2", HostID: 2}, &pb.PolicyViolation{
+		RequestId: "r2", Kind: agentmodel.PolicyViolationFile, Action: "download", Target: "/etc/shadow", Reason: "禁止访问 /etc/shadow",
+	})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	s := &HTTPServer{db: db}
+	r.GET("/agents/policy-violations", s.ListPolicyViolations)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/agents/policy-violations?hostId=1", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status=%d body=%s", w.Code, w.Body)
+	}
+	var resp struct {
+		Data struct {
+			List  []agentmodel.AgentPolicyViolation `json:"list"`
+			Total int64                             `json:"total"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data.Total != 1 || len(resp.Data.List) != 1 {
+		t.Fatalf("期望按主机过滤出 1 条记录, 实际 %+v", resp.Data)
+	}
+	got := resp.Data.List[0]
+	if got.AgentID != "a1" || got.RequestID != "r1" || got.Target != "rm -rf /" || got.OccurredAt.Unix() != 1700000000 {
+		t.Fatalf("记录内容错误: %+v", got)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/agents/policy-violations?kind=file", nil))
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Data.Total != 1 || resp.Data.List[0].Target != "/etc/shadow" || resp.Data.List[0].OccurredAt.IsZero() {
+		t.Fatalf("按类型过滤错误: %+v", resp.Data)
+	}
+}
@@ -0,0 +1,500 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	agentmodel "github.com/ydcloud-dy/opshub/internal/agent"
+	assetbiz "github.com/ydcloud-dy/opshub/internal/biz/asset"
+	rbacService "github.com/ydcloud-dy/opshub/internal/service/rbac"
+	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var defaultRolloutStages = []int{10, 50, 100}
+
+// RolloutRequest 创建升级批次请求
+type RolloutRequest struct {
+	GroupIDs []uint `json:"groupIds"` // 目标资产分组（含子分组），为空表示全部
+	HostIDs  []uint `json:"hostIds"`  // 指定主机，与分组取并集
+	// Stages 各阶段累计升级比例，如 [10, 50, 100]；最后一个阶段小于 100 时只升级该比例的主机
+	Stages          []int    `json:"stages"`
+	MaxFailureRate  *float64 `json:"maxFailureRate"` // 0-1，默认 0.1
+	Concurrency     int      `json:"concurrency"`
+	RollbackTimeout int      `json:"rollbackTimeout"`
+	StageInterval   int      `json:"stageInterval"`
+}
+
+// rolloutStages 解析阶段配置
+func rolloutStages(stages string) []int {
+	var result []int
+	for _, s := range strings.Split(stages, ",") {
+		if n, err := strconv.Atoi(strings.TrimSpace(s)); err == nil {
+			result = append(result, n)
+		}
+	}
+	return result
+}
+
+// stageSizes 按累计比例计算每个阶段结束时的累计主机数，每个阶段至少推进一台
+func stageSizes(total int, stages []int) []int {
+	sizes := make([]int, len(stages))
+	prev := 0
+	for i, pct := range stages {
+		n := int(math.Ceil(float64(total) * float64(pct) / 100))
+		n = min(max(n, prev+1), total)
+		sizes[i] = n
+		prev = n
+	}
+	return sizes
+}
+
+// CreateRollout 创建升级批次并开始执行
+func (u *UpgradeController) CreateRollout(ctx context.Context, req *RolloutRequest, userID uint) (*agentmodel.AgentUpgradeRollout, error) {
+	version, err := u.PackageVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	stages := req.Stages
+	if len(stages) == 0 {
+		stages = defaultRolloutStages
+	}
+	for i, pct := range stages {
+		if pct <= 0 || pct > 100 || (i > 0 && pct <= stages[i-1]) {
+			return nil, fmt.Errorf("阶段比例需在 1-100 之间且递增")
+		}
+	}
+	maxFailureRate := 0.1
+	if req.MaxFailureRate != nil {
+		maxFailureRate = *req.MaxFailureRate
+	}
+	if maxFailureRate < 0 || maxFailureRate > 1 {
+		return nil, fmt.Errorf("失败率阈值需在 0-1 之间")
+	}
+	concurrency := req.Concurrency
+	if concurrency <= 0 {
+		concurrency = 5
+	}
+	rollbackTimeout := req.RollbackTimeout
+	if rollbackTimeout <= 0 {
+		rollbackTimeout = defaultRollbackTimeout
+	}
+
+	targets, err := u.rolloutTargets(ctx, req.GroupIDs, req.HostIDs, version)
+	if err != nil {
+		return nil, err
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("没有需要升级的Agent")
+	}
+	// 随机打散，使前期阶段覆盖不同分组和平台
+	rand.Shuffle(len(targets), func(i, j int) { targets[i], targets[j] = targets[j], targets[i] })
+	sizes := stageSizes(len(targets), stages)
+	targets = targets[:sizes[len(sizes)-1]]
+
+	stageStrs := make([]string, len(stages))
+	for i, pct := range stages {
+		stageStrs[i] = strconv.Itoa(pct)
+	}
+	groupStrs := make([]string, len(req.GroupIDs))
+	for i, id := range req.GroupIDs {
+		groupStrs[i] = strconv.FormatUint(uint64(id), 10)
+	}
+	rollout := &agentmodel.AgentUpgradeRollout{
+		Version:         version,
+		GroupIDs:        strings.Join(groupStrs, ","),
+		Stages:          strings.Join(stageStrs, ","),
+		MaxFailureRate:  maxFailureRate,
+		Concurrency:     concurrency,
+		RollbackTimeout: rollbackTimeout,
+		StageInterval:   req.StageInterval,
+		Status:          agentmodel.RolloutStatusRunning,
+		Total:           len(targets),
+		CreatedBy:       userID,
+	}
+	err = u.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(rollout).Error; err != nil {
+			return err
+		}
+		tasks := make([]agentmodel.AgentUpgradeTask, len(targets))
+		stage := 0
+		for i, t := range targets {
+			for i >= sizes[stage] {
+				stage++
+			}
+			tasks[i] = agentmodel.AgentUpgradeTask{
+				RolloutID:   rollout.ID,
+				HostID:      t.HostID,
+				Stage:       stage,
+				FromVersion: t.Version,
+				Status:      agentmodel.UpgradeTaskPending,
+			}
+		}
+		return tx.CreateInBatches(tasks, 100).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("创建升级批次失败: %w", err)
+	}
+
+	appLogger.Info("创建Agent升级批次",
+		zap.Uint("rolloutID", rollout.ID), zap.String("version", version),
+		zap.Int("total", rollout.Total), zap.String("stages", rollout.Stages))
+	u.start(rollout.ID)
+	return rollout, nil
+}
+
+// rolloutTarget 待升级的Agent
+type rolloutTarget struct {
+	HostID  uint
+	Version string
+}
+
+// rolloutTargets 查询分组（含子分组）和指定主机中版本不是目标版本的Agent
+func (u *UpgradeController) rolloutTargets(ctx context.Context, groupIDs, hostIDs []uint, version string) ([]rolloutTarget, error) {
+	db := u.db.WithContext(ctx)
+	query := db.Model(&agentmodel.AgentInfo{}).Where("version <> ?", version)
+	if len(groupIDs) > 0 || len(hostIDs) > 0 {
+		var ids []uint
+		if len(groupIDs) > 0 {
+			allGroups := append([]uint{}, groupIDs...)
+			for parents := groupIDs; len(parents) > 0; {
+				var children []uint
+				if err := db.Model(&assetbiz.AssetGroup{}).Where("parent_id IN ?", parents).Pluck("id", &children).Error; err != nil {
+					return nil, err
+				}
+				allGroups = append(allGroups, children...)
+				parents = children
+			}
+			if err := db.Model(&assetbiz.Host{}).Where("group_id IN ?", allGroups).Pluck("id", &ids).Error; err != nil {
+				return nil, err
+			}
+		}
+		query = query.Where("host_id IN ?", append(ids, hostIDs...))
+	}
+
+	var infos []agentmodel.AgentInfo
+	if err := query.Order("host_id").Find(&infos).Error; err != nil {
+		return nil, err
+	}
+	targets := make([]rolloutTarget, 0, len(infos))
+	for _, info := range infos {
+		targets = append(targets, rolloutTarget{HostID: info.HostID, Version: info.Version})
+	}
+	return targets, nil
+}
+
+// rolloutRun 正在执行的升级批次
+type rolloutRun struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// start 后台执行升级批次
+func (u *UpgradeController) start(rolloutID uint) {
+	ctx, cancel := context.WithCancel(context.Background())
+	run := &rolloutRun{cancel: cancel, done: make(chan struct{})}
+	u.runMu.Lock()
+	if _, ok := u.running[rolloutID]; ok {
+		u.runMu.Unlock()
+		cancel()
+		return
+	}
+	u.running[rolloutID] = run
+	u.runMu.Unlock()
+
+	go func() {
+		defer func() {
+			u.runMu.Lock()
+			delete(u.running, rolloutID)
+			u.runMu.Unlock()
+			cancel()
+			close(run.done)
+		}()
+		u.run(ctx, rolloutID)
+	}()
+}
+
+// run 按阶段推进升级，失败率超过阈值时暂停
+func (u *UpgradeController) run(ctx context.Context, rolloutID uint) {
+	var rollout agentmodel.AgentUpgradeRollout
+	if err := u.db.First(&rollout, rolloutID).Error; err != nil {
+		appLogger.Error("加载升级批次失败", zap.Uint("rolloutID", rolloutID), zap.Error(err))
+		return
+	}
+	stages := rolloutStages(rollout.Stages)
+
+	for stage := rollout.CurrentStage; stage < len(stages); stage++ {
+		if stage != rollout.CurrentStage {
+			// 阶段之间留出观察时间
+			if rollout.StageInterval > 0 {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(time.Duration(rollout.StageInterval) * time.Second):
+				}
+			}
+			rollout.CurrentStage = stage
+			u.db.Model(&rollout).Update("current_stage", stage)
+		}
+
+		halted, reason := u.runStage(ctx, &rollout, stage)
+		if ctx.Err() != nil {
+			return
+		}
+		if halted {
+			u.finishRollout(&rollout, agentmodel.RolloutStatusHalted, reason)
+			return
+		}
+	}
+	u.finishRollout(&rollout, agentmodel.RolloutStatusCompleted,
+		fmt.Sprintf("成功 %d，失败 %d，跳过 %d", rollout.Succeeded, rollout.Failed, rollout.Skipped))
+}
+
+// runStage 并发执行一个阶段的待升级主机，返回是否因失败率超限暂停
+// 暂停只停止启动新的升级，进行中的升级继续完成，避免中断正在重启的Agent
+func (u *UpgradeController) runStage(ctx context.Context, rollout *agentmodel.AgentUpgradeRollout, stage int) (bool, string) {
+	var tasks []agentmodel.AgentUpgradeTask
+	u.db.Where("rollout_id = ? AND stage = ? AND status = ?", rollout.ID, stage, agentmodel.UpgradeTaskPending).
+		Order("id").Find(&tasks)
+
+	launchCtx, stopLaunch := context.WithCancel(ctx)
+	defer stopLaunch()
+
+	var (
+		mu     sync.Mutex
+		halted bool
+		reason string
+		wg     sync.WaitGroup
+	)
+	sem := make(chan struct{}, max(rollout.Concurrency, 1))
+	for i := range tasks {
+		select {
+		case sem <- struct{}{}:
+		case <-launchCtx.Done():
+		}
+		if launchCtx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(task *agentmodel.AgentUpgradeTask) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			status := u.runTask(ctx, rollout, task)
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch status {
+			case agentmodel.UpgradeTaskSucceeded:
+				rollout.Succeeded++
+			case agentmodel.UpgradeTaskFailed:
+				rollout.Failed++
+			case agentmodel.UpgradeTaskSkipped:
+				rollout.Skipped++
+			}
+			u.db.Model(rollout).Updates(map[string]any{
+				"succeeded": rollout.Succeeded, "failed": rollout.Failed, "skipped": rollout.Skipped,
+			})
+			if status == agentmodel.UpgradeTaskFailed && !halted {
+				rate := float64(rollout.Failed) / float64(rollout.Succeeded+rollout.Failed)
+				if rate > rollout.MaxFailureRate {
+					halted = true
+					reason = fmt.Sprintf("失败率 %.0f%% 超过阈值 %.0f%%，已暂停", rate*100, rollout.MaxFailureRate*100)
+					stopLaunch()
+				}
+			}
+		}(&tasks[i])
+	}
+	wg.Wait()
+	return halted, reason
+}
+
+// runTask 升级单台主机并记录结果
+func (u *UpgradeController) runTask(ctx context.Context, rollout *agentmodel.AgentUpgradeRollout, task *agentmodel.AgentUpgradeTask) string {
+	now := time.Now()
+	u.db.Model(task).Updates(map[string]any{"status": agentmodel.UpgradeTaskRunning, "started_at": &now})
+
+	// 灰度批次只升级不回退，回退需逐台明确指定
+	_, err := u.upgradeHost(ctx, task.HostID, rollout.RollbackTimeout, false)
+	status, msg := agentmodel.UpgradeTaskSucceeded, ""
+	switch {
+	case err == nil:
+	case errors.Is(err, errAgentUpToDate), errors.Is(err, errAgentOffline), errors.Is(err, errAgentDowngrade):
+		status, msg = agentmodel.UpgradeTaskSkipped, err.Error()
+	case ctx.Err() != nil:
+		// 暂停或取消时中断的升级重新排队，恢复后重试
+		status = agentmodel.UpgradeTaskPending
+	default:
+		status, msg = agentmodel.UpgradeTaskFailed, err.Error()
+		appLogger.Warn("Agent升级失败", zap.Uint("rolloutID", rollout.ID), zap.Uint("hostID", task.HostID), zap.Error(err))
+	}
+
+	finished := time.Now()
+	updates := map[string]any{"status": status, "error": msg, "finished_at": &finished}
+	if status == agentmodel.UpgradeTaskPending {
+		updates["finished_at"] = nil
+	}
+	u.db.Model(task).Updates(updates)
+	return status
+}
+
+func (u *UpgradeController) finishRollout(rollout *agentmodel.AgentUpgradeRollout, status, message string) {
+	now := time.Now()
+	updates := map[string]any{"status": status, "message": message}
+	if status != agentmodel.RolloutStatusHalted {
+		updates["finished_at"] = &now
+	}
+	u.db.Model(rollout).Updates(updates)
+	appLogger.Info("Agent升级批次结束", zap.Uint("rolloutID", rollout.ID), zap.String("status", status), zap.String("message", message))
+}
+
+// ResumeRollout 恢复已暂停的升级批次
+func (u *UpgradeController) ResumeRollout(ctx context.Context, id uint) error {
+	result := u.db.WithContext(ctx).Model(&agentmodel.AgentUpgradeRollout{}).
+		Where("id = ? AND status = ?", id, agentmodel.RolloutStatusHalted).
+		Updates(map[string]any{"status": agentmodel.RolloutStatusRunning, "message": ""})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("只能恢复已暂停的升级批次")
+	}
+	u.start(id)
+	return nil
+}
+
+// CancelRollout 取消升级批次，未开始的主机标记为跳过
+func (u *UpgradeController) CancelRollout(ctx context.Context, id uint) error {
+	u.runMu.Lock()
+	run, ok := u.running[id]
+	u.runMu.Unlock()
+	if ok {
+		// 等待进行中的升级退出后再更新状态
+		run.cancel()
+		<-run.done
+	}
+
+	now := time.Now()
+	result := u.db.WithContext(ctx).Model(&agentmodel.AgentUpgradeRollout{}).
+		Where("id = ? AND status IN ?", id, []string{agentmodel.RolloutStatusRunning, agentmodel.RolloutStatusHalted}).
+		Updates(map[string]any{"status": agentmodel.RolloutStatusCanceled, "message": "已取消", "finished_at": &now})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("升级批次已结束")
+	}
+	return u.db.WithContext(ctx).Model(&agentmodel.AgentUpgradeTask{}).
+		Where("rollout_id = ? AND status IN ?", id, []string{agentmodel.UpgradeTaskPending, agentmodel.UpgradeTaskRunning}).
+		Updates(map[string]any{"status": agentmodel.UpgradeTaskSkipped, "error": "升级批次已取消"}).Error
+}
+
+// RecoverRollouts 服务启动时将中断的升级批次标记为暂停，由用户确认后恢复
+func (u *UpgradeController) RecoverRollouts() {
+	var ids []uint
+	u.db.Model(&agentmodel.AgentUpgradeRollout{}).Where("status = ?", agentmodel.RolloutStatusRunning).Pluck("id", &ids)
+	if len(ids) == 0 {
+		return
+	}
+	u.db.Model(&agentmodel.AgentUpgradeTask{}).
+		Where("rollout_id IN ? AND status = ?", ids, agentmodel.UpgradeTaskRunning).
+		Updates(map[string]any{"status": agentmodel.UpgradeTaskPending, "started_at": nil})
+	u.db.Model(&agentmodel.AgentUpgradeRollout{}).Where("id IN ?", ids).
+		Updates(map[string]any{"status": agentmodel.RolloutStatusHalted, "message": "服务重启，升级已暂停"})
+	appLogger.Info("升级批次因服务重启暂停", zap.Any("rolloutIDs", ids))
+}
+
+// CreateUpgradeRollout 创建Agent升级批次
+func (s *HTTPServer) CreateUpgradeRollout(c *gin.Context) {
+	var req RolloutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+	rollout, err := s.upgrades.CreateRollout(c.Request.Context(), &req, rbacService.GetUserID(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "升级批次已开始", "data": rollout})
+}
+
+// ListUpgradeRollouts 升级批次列表
+func (s *HTTPServer) ListUpgradeRollouts(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	var total int64
+	var list []agentmodel.AgentUpgradeRollout
+	query := s.db.Model(&agentmodel.AgentUpgradeRollout{})
+	query.Count(&total)
+	if err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success", "data": gin.H{"list": list, "total": total}})
+}
+
+// GetUpgradeRollout 升级批次详情
+func (s *HTTPServer) GetUpgradeRollout(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "无效的ID"})
+		return
+	}
+	var rollout agentmodel.AgentUpgradeRollout
+	if err := s.db.First(&rollout, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "升级批次不存在"})
+		return
+	}
+	var tasks []agentmodel.AgentUpgradeTask
+	s.db.Where("rollout_id = ?", id).Find(&tasks)
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if tasks[i].Stage != tasks[j].Stage {
+			return tasks[i].Stage < tasks[j].Stage
+		}
+		return tasks[i].ID < tasks[j].ID
+	})
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success", "data": gin.H{"rollout": rollout, "tasks": tasks}})
+}
+
+// ResumeUpgradeRollout 恢复已暂停的升级批次
+func (s *HTTPServer) ResumeUpgradeRollout(c *gin.Context) {
+	s.rolloutAction(c, s.upgrades.ResumeRollout, "升级批次已恢复")
+}
+
+// CancelUpgradeRollout 取消升级批次
+func (s *HTTPServer) CancelUpgradeRollout(c *gin.Context) {
+	s.rolloutAction(c, s.upgrades.CancelRollout, "升级批次已取消")
+}
+
+func (s *HTTPServer) rolloutAction(c *gin.Context, action func(context.Context, uint) error, message string) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "无效的ID"})
+		return
+	}
+	if err := action(c.Request.Context(), uint(id)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": message})
+}
@@ -0,0 +1,327 @@
+package agent
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	agentmodel "github.com/ydcloud-dy/opshub/internal/agent"
+	assetbiz "github.com/ydcloud-dy/opshub/internal/biz/asset"
+	"github.com/ydcloud-dy/opshub/internal/conf"
+	agentrepo "github.com/ydcloud-dy/opshub/internal/data/agent"
+	"github.com/ydcloud-dy/opshub/internal/testutil"
+	pb "github.com/ydcloud-dy/opshub/pkg/agentproto"
+	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
+	"go.uber.org/zap"
+)
+
+func writeAgentTarball(t *testing.T, dir, name string, files map[string]string) {
+	f, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for path, content := range files {
+		tw.WriteHeader(&tar.Header{Name: path, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+}
+
+// newTestUpgradeController 创建使用内存数据库的升级控制器，hosts 为 hostID -> 分组ID
+func newTestUpgradeController(t *testing.T, hosts map[uint]uint) *UpgradeController {
+	appLogger.Log = zap.NewNop()
+	db := testutil.NewDB(t, &agentmodel.AgentInfo{}, &agentmodel.AgentUpgradeRollout{}, &agentmodel.AgentUpgradeTask{}, &assetbiz.Host{}, &assetbiz.AssetGroup{})
+	for _, g := range []struct {
+		id, parent uint
+		code       string
+	}{{1, 0, "prod"}, {2, 1, "prod-web"}, {3, 0, "test"}} {
+		group := &assetbiz.AssetGroup{Name: g.code, Code: g.code, ParentID: g.parent}
+		group.ID = g.id
+		db.Create(group)
+	}
+	for hostID, groupID := range hosts {
+		host := &assetbiz.Host{Name: "h", GroupID: groupID, IP: "10.0.0.1", SSHUser: "root"}
+		host.ID = hostID
+		db.Create(host)
+		db.Create(&agentmodel.AgentInfo{AgentID: "agent-" + string(rune('a'+hostID)), HostID: hostID, Version: "1.0.0", OS: "linux", Arch: "amd64"})
+	}
+
+	// 模拟离线发布：私钥仅用于对升级清单生成 .sig，服务端只配置公钥
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	sign := func(version, arch, content string) string {
+		sum := sha256.Sum256([]byte(content))
+		return string(ed25519.Sign(priv, pb.UpgradeManifest(version, "linux", arch, hex.EncodeToString(sum[:]))))
+	}
+	binDir := t.TempDir()
+	writeAgentTarball(t, binDir, "srehub-agent-1.1.0-multi-arch.tar.gz", map[string]string{
+		"srehub-agent-1.1.0-multi-arch/bin/srehub-agent-linux-amd64":     "new-binary",
+		"srehub-agent-1.1.0-multi-arch/bin/srehub-agent-linux-amd64.sig": sign("1.1.0", "amd64", "new-binary"),
+		"srehub-agent-1.1.0-multi-arch/bin/srehub-agent-linux-arm64":     "arm-binary",
+		"srehub-agent-1.1.0-multi-arch/bin/srehub-agent-linux-arm64.sig": base64.StdEncoding.EncodeToString([]byte(sign("1.1.0", "arm64", "tampered"))),
+		// 旧版本的已签名二进制重新打包为新版本
+		"srehub-agent-1.1.0-multi-arch/bin/srehub-agent-linux-arm":     "old-binary",
+		"srehub-agent-1.1.0-multi-arch/bin/srehub-agent-linux-arm.sig": sign("1.0.0", "arm", "old-binary"),
+		"srehub-agent-1.1.0-multi-arch/bin/srehub-agent-linux-386":     "unsigned-binary",
+	})
+	cfg := &conf.Config{}
+	cfg.Agent.BinaryDir = binDir
+	cfg.Agent.UpgradePublicKey = base64.StdEncoding.EncodeToString(pub)
+	u := &UpgradeController{
+		hub:       NewAgentHub(),
+		db:        db,
+		tlsMgr:    NewTLSManager(t.TempDir()),
+		conf:      cfg,
+		agentRepo: agentrepo.NewRepository(db),
+		binCache:  make(map[string]*agentBinary),
+		running:   make(map[uint]*rolloutRun),
+	}
+	u.upgradeHost = u.UpgradeHost
+	return u
+}
+
+func waitRollout(t *testing.T, u *UpgradeController, id uint) *agentmodel.AgentUpgradeRollout {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		u.runMu.Lock()
+		_, running := u.running[id]
+		u.runMu.Unlock()
+		if !running {
+			var rollout agentmodel.AgentUpgradeRollout
+			u.db.First(&rollout, id)
+			return &rollout
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("rollout did not finish")
+	return nil
+}
+
+func TestPackageVersion(t *testing.T) {
+	tests := map[string]string{
+		"srehub-agent-1.0.0-multi-arch.tar.gz":     "1.0.0",
+		"/data/srehub-agent-v2.3.1.tar.gz":         "2.3.1",
+		"srehub-agent-1.2.0-rc1-multi-arch.tar.gz": "1.2.0-rc1",
+	}
+	for name, want := range tests {
+		if got, err := packageVersion(name); err != nil || got != want {
+			t.Errorf("packageVersion(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if _, err := packageVersion("srehub-agent-linux-amd64.tar.gz"); err == nil {
+		t.Error("expected error for package without version")
+	}
+}
+
+func TestStageSizes(t *testing.T) {
+	tests := []struct {
+		total  int
+		stages []int
+		want   []int
+	}{
+		{10, []int{10, 50, 100}, []int{1, 5, 10}},
+		{3, []int{10, 50, 100}, []int{1, 2, 3}},
+		{2, []int{10, 20, 100}, []int{1, 2, 2}},
+		{20, []int{25}, []int{5}},
+	}
+	for _, tt := range tests {
+		got := stageSizes(tt.total, tt.stages)
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("stageSizes(%d, %v) = %v, want %v", tt.total, tt.stages, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestLoadAgentBinary_Signed(t *testing.T) {
+	u := newTestUpgradeController(t, nil)
+	bin, err := u.loadAgentBinary("linux", "amd64")
+	if err != nil {
+		t.Fatalf("loadAgentBinary() error: %v", err)
+	}
+	if bin.Version != "1.1.0" || string(bin.Data) != "new-binary" {
+		t.Errorf("bin = %s %q", bin.Version, bin.Data)
+	}
+	pub, err := upgradePublicKey(u.conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ed25519.Verify(pub, pb.UpgradeManifest(bin.Version, "linux", "amd64", bin.SHA256), bin.Signature) {
+		t.Error("signature verification failed")
+	}
+	// 服务端不再生成或保存签名私钥
+	if _, err := os.Stat(filepath.Join(u.tlsMgr.certDir, "upgrade-key.pem")); !os.IsNotExist(err) {
+		t.Error("server should not hold a signing key")
+	}
+
+	if _, err := u.loadAgentBinary("linux", "arm64"); err == nil {
+		t.Error("expected error for signature over other content")
+	}
+	if _, err := u.loadAgentBinary("linux", "arm"); err == nil {
+		t.Error("expected error for signature of another version")
+	}
+	if _, err := u.loadAgentBinary("linux", "386"); err == nil {
+		t.Error("expected error for binary without .sig")
+	}
+	if _, err := u.loadAgentBinary("darwin", "arm64"); err == nil {
+		t.Error("expected error for missing platform")
+	}
+
+	u.conf.Agent.UpgradePublicKey = ""
+	u.binCache = make(map[string]*agentBinary)
+	if _, err := u.loadAgentBinary("linux", "amd64"); err == nil {
+		t.Error("expected error without configured public key")
+	}
+}
+
+func TestUpgradeHost_RefusesDowngrade(t *testing.T) {
+	u := newTestUpgradeController(t, map[uint]uint{1: 1})
+	u.db.Model(&agentmodel.AgentInfo{}).Where("host_id = ?", 1).Update("version", "1.2.0")
+	u.hub.Register("agent-1", 1, nil)
+	if _, err := u.UpgradeHost(context.Background(), 1, 0, false); !errors.Is(err, errAgentDowngrade) {
+		t.Errorf("UpgradeHost() error = %v, want errAgentDowngrade", err)
+	}
+}
+
+func TestRollout_StagesAndGroups(t *testing.T) {
+	// 分组 1 及其子分组 2 共 4 台，分组 3 的主机不参与
+	u := newTestUpgradeController(t, map[uint]uint{1: 1, 2: 1, 3: 2, 4: 2, 5: 3})
+	var mu sync.Mutex
+	var upgraded []uint
+	u.upgradeHost = func(ctx context.Context, hostID uint, rollbackTimeout int, allowDowngrade bool) (string, error) {
+		mu.Lock()
+		upgraded = append(upgraded, hostID)
+		mu.Unlock()
+		if allowDowngrade {
+			t.Error("rollout should never downgrade")
+		}
+		if hostID == 4 {
+			return "", errAgentOffline
+		}
+		return "1.1.0", nil
+	}
+
+	rollout, err := u.CreateRollout(context.Background(), &RolloutRequest{GroupIDs: []uint{1}, Stages: []int{25, 100}, Concurrency: 2}, 1)
+	if err != nil {
+		t.Fatalf("CreateRollout() error: %v", err)
+	}
+	if rollout.Total != 4 || rollout.Version != "1.1.0" {
+		t.Fatalf("rollout = %+v", rollout)
+	}
+	final := waitRollout(t, u, rollout.ID)
+	if final.Status != agentmodel.RolloutStatusCompleted || final.Succeeded != 3 || final.Skipped != 1 || final.CurrentStage != 1 {
+		t.Errorf("final = %+v", final)
+	}
+	for _, id := range upgraded {
+		if id == 5 {
+			t.Error("host outside target groups upgraded")
+		}
+	}
+
+	var stageCounts []int64
+	for stage := 0; stage < 2; stage++ {
+		var n int64
+		u.db.Model(&agentmodel.AgentUpgradeTask{}).Where("rollout_id = ? AND stage = ?", rollout.ID, stage).Count(&n)
+		stageCounts = append(stageCounts, n)
+	}
+	if stageCounts[0] != 1 || stageCounts[1] != 3 {
+		t.Errorf("stage task counts = %v", stageCounts)
+	}
+}
+
+func TestRollout_HaltOnFailureRateAndResume(t *testing.T) {
+	u := newTestUpgradeController(t, map[uint]uint{1: 1, 2: 1, 3: 1, 4: 1, 5: 1, 6: 1})
+	var mu sync.Mutex
+	fail := true
+	u.upgradeHost = func(ctx context.Context, hostID uint, rollbackTimeout int, allowDowngrade bool) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if fail {
+			return "", errors.New("新版本未能正常运行，已回滚")
+		}
+		return "1.1.0", nil
+	}
+
+	rate := 0.3
+	rollout, err := u.CreateRollout(context.Background(), &RolloutRequest{Stages: []int{50, 100}, Concurrency: 1, MaxFailureRate: &rate}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	halted := waitRollout(t, u, rollout.ID)
+	// 首台失败即超过阈值，后续主机不再启动
+	if halted.Status != agentmodel.RolloutStatusHalted || halted.Failed != 1 || halted.CurrentStage != 0 {
+		t.Fatalf("halted = %+v", halted)
+	}
+	var pending int64
+	u.db.Model(&agentmodel.AgentUpgradeTask{}).Where("rollout_id = ? AND status = ?", rollout.ID, agentmodel.UpgradeTaskPending).Count(&pending)
+	if pending != 5 {
+		t.Errorf("pending = %d, want 5", pending)
+	}
+
+	mu.Lock()
+	fail = false
+	mu.Unlock()
+	if err := u.ResumeRollout(context.Background(), rollout.ID); err != nil {
+		t.Fatal(err)
+	}
+	final := waitRollout(t, u, rollout.ID)
+	if final.Status != agentmodel.RolloutStatusCompleted || final.Succeeded != 5 || final.Failed != 1 {
+		t.Errorf("final = %+v", final)
+	}
+	if err := u.ResumeRollout(context.Background(), rollout.ID); err == nil {
+		t.Error("resume of completed rollout should fail")
+	}
+}
+
+func TestRollout_CancelAndRecover(t *testing.T) {
+	u := newTestUpgradeController(t, map[uint]uint{1: 1, 2: 1, 3: 1})
+	started := make(chan struct{}, 3)
+	u.upgradeHost = func(ctx context.Context, hostID uint, rollbackTimeout int, allowDowngrade bool) (string, error) {
+		started <- struct{}{}
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+	rollout, err := u.CreateRollout(context.Background(), &RolloutRequest{Stages: []int{100}, Concurrency: 1}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	if err := u.CancelRollout(context.Background(), rollout.ID); err != nil {
+		t.Fatal(err)
+	}
+	final := waitRollout(t, u, rollout.ID)
+	var skipped int64
+	u.db.Model(&agentmodel.AgentUpgradeTask{}).Where("rollout_id = ? AND status = ?", rollout.ID, agentmodel.UpgradeTaskSkipped).Count(&skipped)
+	if final.Status != agentmodel.RolloutStatusCanceled || skipped != 3 {
+		t.Errorf("final = %+v, skipped = %d", final, skipped)
+	}
+
+	// 服务重启时运行中的批次转为暂停，运行中的主机重新排队
+	interrupted := &agentmodel.AgentUpgradeRollout{Status: agentmodel.RolloutStatusRunning, Stages: "100"}
+	u.db.Create(interrupted)
+	u.db.Create(&agentmodel.AgentUpgradeTask{RolloutID: interrupted.ID, HostID: 1, Status: agentmodel.UpgradeTaskRunning})
+	u.RecoverRollouts()
+	var recovered agentmodel.AgentUpgradeRollout
+	u.db.First(&recovered, interrupted.ID)
+	var task agentmodel.AgentUpgradeTask
+	u.db.Where("rollout_id = ?", interrupted.ID).First(&task)
+	if recovered.Status != agentmodel.RolloutStatusHalted || task.Status != agentmodel.UpgradeTaskPending {
+		t.Errorf("recovered = %s, task = %s", recovered.Status, task.Status)
+	}
+}
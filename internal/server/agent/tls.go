@@ -13,6 +13,7 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -25,11 +26,30 @@ type TLSManager struct {
 	certDir string
 	caCert  *x509.Certificate
 	caKey   *ecdsa.PrivateKey
+	// Agent证书有效期
+	agentCertTTL time.Duration
+	// 握手时对客户端证书的额外校验（吊销检查），为 nil 时不校验
+	verifyPeer func(cert *x509.Certificate) error
 }
 
+// defaultAgentCertTTL 未配置时Agent证书的有效期
+const defaultAgentCertTTL = 365 * 24 * time.Hour
+
 // NewTLSManager 创建TLS管理器
 func NewTLSManager(certDir string) *TLSManager {
-	return &TLSManager{certDir: certDir}
+	return &TLSManager{certDir: certDir, agentCertTTL: defaultAgentCertTTL}
+}
+
+// SetAgentCertTTL 设置Agent证书有效期
+func (m *TLSManager) SetAgentCertTTL(ttl time.Duration) {
+	if ttl > 0 {
+		m.agentCertTTL = ttl
+	}
+}
+
+// SetPeerVerifier 设置握手时对客户端证书的额外校验，需在 LoadServerTLSConfig 之前调用
+func (m *TLSManager) SetPeerVerifier(fn func(cert *x509.Certificate) error) {
+	m.verifyPeer = fn
 }
 
 // InitCA 初始化CA，如果不存在则生成
@@ -88,7 +108,12 @@ func (m *TLSManager) InitCA() error {
 	}
 	os.Chmod(caKeyPath, 0600)
 
-	m.caCert = caTemplate
+	// 使用解析后的证书，模板缺少 RawSubject 等字段，无法用于校验客户端证书
+	caCert, err := x509.ParseCertificate(caCertDER)
+	if err != nil {
+		return fmt.Errorf("解析CA证书失败: %w", err)
+	}
+	m.caCert = caCert
 	m.caKey = caKey
 	appLogger.Info("CA证书生成完成", zap.String("path", caCertPath))
 	return nil
@@ -140,21 +165,7 @@ func (m *TLSManager) SignAgentCert(agentID string, hostIP string) (certPEM, keyP
 		return nil, nil, fmt.Errorf("生成Agent私钥失败: %w", err)
 	}
 
-	serialNumber, _ := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
-	template := &x509.Certificate{
-		SerialNumber: serialNumber,
-		Subject: pkix.Name{
-			Organization: []string{"OpsHub Agent"},
-			CommonName:   agentID,
-		},
-		NotBefore: time.Now(),
-		NotAfter:  time.Now().Add(365 * 24 * time.Hour),
-		KeyUsage:  x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
-		ExtKeyUsage: []x509.ExtKeyUsage{
-			x509.ExtKeyUsageClientAuth,
-		},
-	}
-
+	template := m.agentCertTemplate(agentID)
 	if ip := net.ParseIP(hostIP); ip != nil {
 		template.IPAddresses = []net.IP{ip}
 	}
@@ -181,6 +192,111 @@ func (m *TLSManager) SignAgentCert(agentID string, hostIP string) (certPEM, keyP
 	return certPEM, keyPEM, nil
 }
 
+// SignAgentCSR 按Agent提交的证书签名请求签发证书，CN 固定为 agentID，不采信请求中的主题
+func (m *TLSManager) SignAgentCSR(agentID string, csrPEM []byte) (certPEM []byte, cert *x509.Certificate, err error) {
+	if m.caCert == nil || m.caKey == nil {
+		return nil, nil, fmt.Errorf("CA未初始化")
+	}
+	block, _ := pem.Decode(csrPEM)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, nil, fmt.Errorf("解析证书签名请求PEM失败")
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("解析证书签名请求失败: %w", err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, nil, fmt.Errorf("证书签名请求签名无效: %w", err)
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, m.agentCertTemplate(agentID), m.caCert, csr.PublicKey, m.caKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("签发Agent证书失败: %w", err)
+	}
+	cert, err = x509.ParseCertificate(certDER)
+	if err != nil {
+		return nil, nil, fmt.Errorf("解析Agent证书失败: %w", err)
+	}
+	appLogger.Info("Agent证书续期签发完成", zap.String("agentID", agentID), zap.Time("notAfter", cert.NotAfter))
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}), cert, nil
+}
+
+// agentCertOrganization Agent客户端证书的组织名
+const agentCertOrganization = "OpsHub Agent"
+
+func (m *TLSManager) agentCertTemplate(agentID string) *x509.Certificate {
+	serialNumber, _ := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	return &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			Organization: []string{agentCertOrganization},
+			CommonName:   agentID,
+		},
+		NotBefore: time.Now(),
+		NotAfter:  time.Now().Add(m.agentCertTTL),
+		KeyUsage:  x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{
+			x509.ExtKeyUsageClientAuth,
+		},
+	}
+}
+
+// relayCertOrganization 副本间转发客户端证书的组织名，用于区分Agent证书
+const relayCertOrganization = "OpsHub Relay"
+
+// LoadRelayClientTLSConfig 为副本间转发签发客户端证书（仅保存在内存中）。
+// 多副本共用同一个CA，对端据证书组织名确认调用方是副本而不是Agent
+func (m *TLSManager) LoadRelayClientTLSConfig(replicaAddr string) (*tls.Config, error) {
+	if m.caCert == nil || m.caKey == nil {
+		return nil, fmt.Errorf("CA未初始化")
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("生成转发客户端私钥失败: %w", err)
+	}
+	serialNumber, _ := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			Organization: []string{relayCertOrganization},
+			CommonName:   replicaAddr,
+		},
+		NotBefore:   time.Now().Add(-time.Minute),
+		NotAfter:    time.Now().Add(10 * 365 * 24 * time.Hour),
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, m.caCert, &key.PublicKey, m.caKey)
+	if err != nil {
+		return nil, fmt.Errorf("签发转发客户端证书失败: %w", err)
+	}
+
+	caPool := x509.NewCertPool()
+	caPool.AddCert(m.caCert)
+	return &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{certDER}, PrivateKey: key}},
+		RootCAs:      caPool,
+	}, nil
+}
+
+// serverCertAddresses 服务端证书需要包含的额外地址：配置的 server_addresses 及
+// relay_addr 的主机部分，其他副本按 relay_addr 连接本副本时据此校验证书
+func serverCertAddresses(serverAddresses []string, relayAddr string) []string {
+	addrs := append([]string(nil), serverAddresses...)
+	if relayAddr == "" {
+		return addrs
+	}
+	host, _, err := net.SplitHostPort(relayAddr)
+	if err != nil {
+		host = relayAddr
+	}
+	if host != "" && !slices.Contains(addrs, host) {
+		addrs = append(addrs, host)
+	}
+	return addrs
+}
+
 // LoadServerTLSConfig 加载gRPC server的mTLS配置
 func (m *TLSManager) LoadServerTLSConfig(configAddresses []string) (*tls.Config, error) {
 	if m.caCert == nil || m.caKey == nil {
@@ -277,6 +393,12 @@ func (m *TLSManager) LoadServerTLSConfig(configAddresses []string) (*tls.Config,
 		Certificates: []tls.Certificate{cert},
 		ClientAuth:   tls.RequireAndVerifyClientCert,
 		ClientCAs:    caPool,
+		VerifyPeerCertificate: func(_ [][]byte, verifiedChains [][]*x509.Certificate) error {
+			if m.verifyPeer == nil || len(verifiedChains) == 0 || len(verifiedChains[0]) == 0 {
+				return nil
+			}
+			return m.verifyPeer(verifiedChains[0][0])
+		},
 	}, nil
 }
 
@@ -0,0 +1,345 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	agentmodel "github.com/ydcloud-dy/opshub/internal/agent"
+	pb "github.com/ydcloud-dy/opshub/pkg/agentproto"
+	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
+	"github.com/ydcloud-dy/opshub/pkg/tunnel"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultTunnelTTL 未指定有效期时的端口转发会话时长
+	defaultTunnelTTL = time.Hour
+	// defaultTunnelMaxTTL 未配置时端口转发会话的最长有效期
+	defaultTunnelMaxTTL = 4 * time.Hour
+	// tunnelOpenTimeout 等待Agent连接目标的超时
+	tunnelOpenTimeout = 15 * time.Second
+	// tunnelConnectTimeout Agent连接目标地址的超时（秒）
+	tunnelConnectTimeout = 10
+)
+
+var errTunnelNotFound = errors.New("端口转发会话不存在或已关闭")
+
+// TunnelManager 管理端口转发会话：每个会话独立监听一个端口，
+// 只接受来自会话发起方地址的连接，经Agent的 gRPC 流原样转发到Agent可达的目标地址，
+// mysql、redis-cli、RDP 等客户端直接连接监听地址即可使用，其他来源（包括服务端本机其他进程）的连接被拒绝
+type TunnelManager struct {
+	hub      *AgentHub
+	db       *gorm.DB
+	bindAddr string
+	maxTTL   time.Duration
+
+	mu       sync.Mutex
+	sessions map[string]*tunnelSession
+}
+
+// tunnelSession 一个端口转发会话的运行状态
+type tunnelSession struct {
+	record   *agentmodel.AgentTunnelSession
+	client   netip.Addr // 允许接入的来源地址（会话发起方）
+	listener net.Listener
+	timer    *time.Timer
+	seq      atomic.Int64
+	once     sync.Once
+
+	mu    sync.Mutex // 保护 timer 和 conns
+	conns map[string]*tunnel.Endpoint
+}
+
+// NewTunnelManager 创建端口转发管理器，进程重启前遗留的活动会话标记为已关闭
+func NewTunnelManager(grpcServer *GRPCServer) *TunnelManager {
+	m := &TunnelManager{
+		hub:      grpcServer.Hub(),
+		db:       grpcServer.db,
+		bindAddr: grpcServer.conf.Agent.TunnelBindAddr,
+		maxTTL:   time.Duration(grpcServer.conf.Agent.TunnelMaxTTL) * time.Minute,
+		sessions: make(map[string]*tunnelSession),
+	}
+	if m.bindAddr == "" {
+		m.bindAddr = "0.0.0.0"
+	}
+	if m.maxTTL <= 0 {
+		m.maxTTL = defaultTunnelMaxTTL
+	}
+	now := time.Now()
+	m.db.Model(&agentmodel.AgentTunnelSession{}).
+		Where("status = ?", agentmodel.TunnelSessionActive).
+		Updates(map[string]any{"status": agentmodel.TunnelSessionClosed, "closed_at": now, "close_reason": "服务重启"})
+	return m
+}
+
+// Open 创建端口转发会话并开始监听，clientIP 为发起请求的客户端地址，只有该地址可以接入
+func (m *TunnelManager) Open(ctx context.Context, hostID, userID uint, username, clientIP, target string, ttl time.Duration) (*agentmodel.AgentTunnelSession, error) {
+	target, err := normalizeTunnelTarget(target)
+	if err != nil {
+		return nil, err
+	}
+	client, err := netip.ParseAddr(clientIP)
+	if err != nil {
+		return nil, fmt.Errorf("无法识别发起方地址: %q", clientIP)
+	}
+	if _, ok := m.hub.GetByHostID(hostID); !ok {
+		return nil, fmt.Errorf("Agent不在线")
+	}
+	if ttl <= 0 {
+		ttl = defaultTunnelTTL
+	}
+	if ttl > m.maxTTL {
+		ttl = m.maxTTL
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(m.bindAddr, "0"))
+	if err != nil {
+		return nil, fmt.Errorf("监听本地端口失败: %w", err)
+	}
+	record := &agentmodel.AgentTunnelSession{
+		SessionID:  uuid.New().String(),
+		HostID:     hostID,
+		UserID:     userID,
+		Username:   username,
+		Target:     target,
+		ClientIP:   client.Unmap().String(),
+		ListenAddr: listener.Addr().String(),
+		Status:     agentmodel.TunnelSessionActive,
+		ExpiresAt:  time.Now().Add(ttl),
+	}
+	if err := m.db.WithContext(ctx).Create(record).Error; err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	sess := &tunnelSession{record: record, client: client.Unmap(), listener: listener, conns: make(map[string]*tunnel.Endpoint)}
+	sess.mu.Lock()
+	sess.timer = time.AfterFunc(ttl, func() {
+		m.closeSession(sess, agentmodel.TunnelSessionExpired, "会话已过期")
+	})
+	sess.mu.Unlock()
+	m.mu.Lock()
+	m.sessions[record.SessionID] = sess
+	m.mu.Unlock()
+
+	appLogger.Info("端口转发会话已创建",
+		zap.String("sessionID", record.SessionID),
+		zap.Uint("hostID", hostID),
+		zap.String("username", username),
+		zap.String("target", target),
+		zap.String("client", record.ClientIP),
+		zap.String("listen", record.ListenAddr),
+		zap.Duration("ttl", ttl))
+	go m.acceptLoop(sess)
+	return record, nil
+}
+
+// Close 关闭端口转发会话
+func (m *TunnelManager) Close(sessionID, reason string) error {
+	m.mu.Lock()
+	sess, ok := m.sessions[sessionID]
+	m.mu.Unlock()
+	if !ok {
+		return errTunnelNotFound
+	}
+	m.closeSession(sess, agentmodel.TunnelSessionClosed, reason)
+	return nil
+}
+
+func (m *TunnelManager) acceptLoop(sess *tunnelSession) {
+	for {
+		conn, err := sess.listener.Accept()
+		if err != nil {
+			return // 会话关闭
+		}
+		go m.serveConn(sess, conn)
+	}
+}
+
+// permits 接入连接是否来自会话发起方地址
+func (s *tunnelSession) permits(remote net.Addr) bool {
+	addr, err := netip.ParseAddrPort(remote.String())
+	return err == nil && addr.Addr().Unmap() == s.client
+}
+
+// serveConn 校验来源地址后为接入的连接建立到Agent的隧道
+func (m *TunnelManager) serveConn(sess *tunnelSession, conn net.Conn) {
+	record := sess.record
+	if !sess.permits(conn.RemoteAddr()) {
+		appLogger.Warn("拒绝非会话发起方的端口转发连接",
+			zap.String("sessionID", record.SessionID),
+			zap.String("remote", conn.RemoteAddr().String()),
+			zap.String("client", record.ClientIP))
+		conn.Close()
+		return
+	}
+	as, ok := m.hub.GetByHostID(record.HostID)
+	if !ok {
+		appLogger.Warn("端口转发失败: Agent不在线", zap.String("sessionID", record.SessionID))
+		conn.Close()
+		return
+	}
+
+	connID := record.SessionID + "-" + strconv.FormatInt(sess.seq.Add(1), 10)
+	e := tunnel.NewEndpoint(connID, conn, &tunnelPeer{as: as}, tunnel.DefaultWindow)
+	// 先注册再通知Agent，Agent连接成功后立即发来的数据不会丢失
+	m.hub.RegisterTunnel(e)
+	if !sess.add(e) {
+		m.hub.UnregisterTunnel(connID)
+		conn.Close()
+		return
+	}
+	defer func() {
+		m.hub.UnregisterTunnel(connID)
+		sess.remove(connID)
+	}()
+
+	resultCh := as.RegisterPending(connID)
+	defer func() {
+		as.pendMu.Lock()
+		delete(as.pending, connID)
+		as.pendMu.Unlock()
+	}()
+	err := as.Send(&pb.ServerMessage{Payload: &pb.ServerMessage_TunnelOpen{TunnelOpen: &pb.TunnelOpen{
+		ConnId:         connID,
+		Target:         record.Target,
+		ConnectTimeout: tunnelConnectTimeout,
+		Window:         tunnel.DefaultWindow,
+	}}})
+	var result *pb.TunnelOpenResult
+	if err == nil {
+		select {
+		case resp := <-resultCh:
+			result, _ = resp.(*pb.TunnelOpenResult)
+		case <-time.After(tunnelOpenTimeout):
+			err = errors.New("等待Agent连接目标超时")
+		case <-as.DoneCh:
+			err = errors.New("Agent连接已断开")
+		}
+	}
+	if err == nil && (result == nil || !result.Success) {
+		err = errors.New("Agent连接目标失败")
+		if result != nil && result.Error != "" {
+			err = errors.New(result.Error)
+		}
+	}
+	if err != nil {
+		appLogger.Warn("端口转发连接建立失败", zap.String("connID", connID), zap.String("target", record.Target), zap.Error(err))
+		// 超时后Agent可能仍会连上目标，通知其关闭
+		e.Close(err.Error())
+		return
+	}
+
+	e.Start(int(result.Window))
+	select {
+	case <-e.Done():
+	case <-as.DoneCh:
+		e.Close("Agent连接已断开")
+	}
+
+	// 本端的输入是目标返回的数据，输出是客户端发往目标的数据
+	received, sent := e.Stats()
+	m.db.Model(&agentmodel.AgentTunnelSession{}).Where("id = ?", record.ID).Updates(map[string]any{
+		"connections":    gorm.Expr("connections + 1"),
+		"bytes_sent":     gorm.Expr("bytes_sent + ?", sent),
+		"bytes_received": gorm.Expr("bytes_received + ?", received),
+	})
+	appLogger.Info("端口转发连接已关闭",
+		zap.String("connID", connID),
+		zap.Int64("bytesSent", sent),
+		zap.Int64("bytesReceived", received),
+		zap.String("reason", e.Reason()))
+}
+
+// closeSession 停止监听并关闭会话内所有连接
+func (m *TunnelManager) closeSession(sess *tunnelSession, status, reason string) {
+	sess.once.Do(func() {
+		sess.listener.Close()
+		m.mu.Lock()
+		delete(m.sessions, sess.record.SessionID)
+		m.mu.Unlock()
+
+		sess.mu.Lock()
+		sess.timer.Stop()
+		conns := sess.conns
+		sess.conns = nil
+		sess.mu.Unlock()
+		for _, e := range conns {
+			e.Close(reason)
+		}
+
+		appLogger.Info("端口转发会话已关闭", zap.String("sessionID", sess.record.SessionID), zap.String("reason", reason))
+		now := time.Now()
+		m.db.Model(&agentmodel.AgentTunnelSession{}).Where("id = ?", sess.record.ID).Updates(map[string]any{
+			"status":       status,
+			"closed_at":    now,
+			"close_reason": reason,
+		})
+	})
+}
+
+// add 会话已关闭时返回 false
+func (s *tunnelSession) add(e *tunnel.Endpoint) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conns == nil {
+		return false
+	}
+	s.conns[e.ID()] = e
+	return true
+}
+
+func (s *tunnelSession) remove(connID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conns, connID)
+}
+
+// normalizeTunnelTarget 校验目标地址，省略主机时转发到Agent所在主机的本地端口
+func normalizeTunnelTarget(target string) (string, error) {
+	host, portStr, err := net.SplitHostPort(target)
+	if err != nil {
+		// 只填写了端口
+		host, portStr = "", target
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 1 || port > 65535 {
+		return "", fmt.Errorf("无效的目标地址: %s", target)
+	}
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port)), nil
+}
+
+// tunnelPeer 把隧道数据封装为发往Agent的消息
+type tunnelPeer struct {
+	as *AgentStream
+}
+
+func (p *tunnelPeer) SendData(connID string, data []byte) error {
+	return p.as.Send(&pb.ServerMessage{Payload: &pb.ServerMessage_TunnelData{
+		TunnelData: &pb.TunnelData{ConnId: connID, Data: data},
+	}})
+}
+
+func (p *tunnelPeer) SendAck(connID string, n int) error {
+	return p.as.Send(&pb.ServerMessage{Payload: &pb.ServerMessage_TunnelAck{
+		TunnelAck: &pb.TunnelAck{ConnId: connID, Bytes: int32(n)},
+	}})
+}
+
+func (p *tunnelPeer) SendClose(connID, reason string) error {
+	return p.as.Send(&pb.ServerMessage{Payload: &pb.ServerMessage_TunnelClose{
+		TunnelClose: &pb.TunnelClose{ConnId: connID, Error: reason},
+	}})
+}
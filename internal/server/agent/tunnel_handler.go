@@ -0,0 +1,109 @@
+package agent
+
+import (
+	"errors"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	agentmodel "github.com/ydcloud-dy/opshub/internal/agent"
+	rbacService "github.com/ydcloud-dy/opshub/internal/service/rbac"
+	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// OpenTunnel 创建端口转发会话，返回服务端监听地址；仅发起请求的客户端地址可以连接该监听地址，
+// 客户端地址取自 c.ClientIP()，只采信 server.trusted_proxies 中代理传递的 X-Forwarded-For。
+// 来自服务端本机的请求无法与本机其他进程（探测、网站代理等）区分，拒绝创建
+func (s *HTTPServer) OpenTunnel(c *gin.Context) {
+	hostID, err := strconv.ParseUint(c.Param("hostId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "无效的主机ID"})
+		return
+	}
+	var req struct {
+		Target     string `json:"target" binding:"required"` // host:port 或端口，相对Agent所在主机
+		TTLMinutes int    `json:"ttlMinutes"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误: " + err.Error()})
+		return
+	}
+
+	clientIP := c.ClientIP()
+	if isLoopbackClient(clientIP) {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "请求来自服务端本机，无法确认发起方地址，请直接访问平台或在 server.trusted_proxies 中配置反向代理"})
+		return
+	}
+
+	record, err := s.tunnels.Open(c.Request.Context(), uint(hostID), rbacService.GetUserID(c), rbacService.GetUsername(c),
+		clientIP, req.Target, time.Duration(req.TTLMinutes)*time.Minute)
+	if err != nil {
+		appLogger.Warn("创建端口转发失败", zap.Uint64("hostID", hostID), zap.String("target", req.Target), zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "端口转发已创建", "data": record})
+}
+
+// CloseTunnel 关闭端口转发会话，仅会话发起人或管理员可操作
+func (s *HTTPServer) CloseTunnel(c *gin.Context) {
+	var record agentmodel.AgentTunnelSession
+	if err := s.db.Where("session_id = ? AND host_id = ?", c.Param("sessionId"), c.Param("hostId")).First(&record).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": errTunnelNotFound.Error()})
+		return
+	}
+	if record.UserID != rbacService.GetUserID(c) && !s.authMiddleware.IsAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": "只能关闭自己创建的端口转发会话"})
+		return
+	}
+	err := s.tunnels.Close(record.SessionID, "由 "+rbacService.GetUsername(c)+" 手动关闭")
+	if errors.Is(err, errTunnelNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "端口转发已关闭"})
+}
+
+// ListTunnels 端口转发会话审计记录，支持按主机、用户和状态过滤；非管理员只能查看自己创建的会话
+func (s *HTTPServer) ListTunnels(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	query := s.db.Model(&agentmodel.AgentTunnelSession{})
+	if !s.authMiddleware.IsAdmin(c) {
+		query = query.Where("user_id = ?", rbacService.GetUserID(c))
+	}
+	if hostID, _ := strconv.ParseUint(c.Query("hostId"), 10, 64); hostID > 0 {
+		query = query.Where("host_id = ?", hostID)
+	}
+	if userID, _ := strconv.ParseUint(c.Query("userId"), 10, 64); userID > 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	var list []agentmodel.AgentTunnelSession
+	query.Count(&total)
+	if err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success", "data": gin.H{"list": list, "total": total}})
+}
+
+// isLoopbackClient 客户端地址是否为服务端本机回环地址
+func isLoopbackClient(clientIP string) bool {
+	addr, err := netip.ParseAddr(clientIP)
+	return err == nil && addr.Unmap().IsLoopback()
+}
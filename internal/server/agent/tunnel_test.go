@@ -0,0 +1,384 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	agentmodel "github.com/ydcloud-dy/opshub/internal/agent"
+	"github.com/ydcloud-dy/opshub/internal/conf"
+	"github.com/ydcloud-dy/opshub/internal/testutil"
+	pb "github.com/ydcloud-dy/opshub/pkg/agentproto"
+	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
+	"github.com/ydcloud-dy/opshub/pkg/tunnel"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// tunnelAgent 按 Agent 端协议处理隧道消息，Agent 发出的消息直接交给服务端 hub
+type tunnelAgent struct {
+	hub   *AgentHub
+	as    *AgentStream
+	mu    sync.Mutex
+	conns map[string]*tunnel.Endpoint
+}
+
+func (a *tunnelAgent) serve() {
+	for {
+		select {
+		case msg := <-a.as.SendCh:
+			switch p := msg.Payload.(type) {
+			case *pb.ServerMessage_TunnelOpen:
+				a.open(p.TunnelOpen)
+			case *pb.ServerMessage_TunnelData:
+				if e := a.get(p.TunnelData.ConnId); e != nil {
+					e.Deliver(p.TunnelData.Data)
+				}
+			case *pb.ServerMessage_TunnelAck:
+				if e := a.get(p.TunnelAck.ConnId); e != nil {
+					e.Ack(int(p.TunnelAck.Bytes))
+				}
+			case *pb.ServerMessage_TunnelClose:
+				if e := a.get(p.TunnelClose.ConnId); e != nil {
+					e.Shutdown(p.TunnelClose.Error)
+				}
+			}
+		case <-a.as.DoneCh:
+			return
+		}
+	}
+}
+
+func (a *tunnelAgent) open(req *pb.TunnelOpen) {
+	conn, err := net.Dial("tcp", req.Target)
+	if err != nil {
+		a.as.ResolvePending(req.ConnId, &pb.TunnelOpenResult{ConnId: req.ConnId, Error: err.Error()})
+		return
+	}
+	e := tunnel.NewEndpoint(req.ConnId, conn, a, tunnel.DefaultWindow)
+	a.mu.Lock()
+	a.conns[req.ConnId] = e
+	a.mu.Unlock()
+	a.as.ResolvePending(req.ConnId, &pb.TunnelOpenResult{ConnId: req.ConnId, Success: true, Window: tunnel.DefaultWindow})
+	e.Start(int(req.Window))
+}
+
+func (a *tunnelAgent) get(connID string) *tunnel.Endpoint {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.conns[connID]
+}
+
+func (a *tunnelAgent) SendData(connID string, data []byte) error {
+	a.hub.HandleTunnelData(&pb.TunnelData{ConnId: connID, Data: data})
+	return nil
+}
+
+func (a *tunnelAgent) SendAck(connID string, n int) error {
+	a.hub.HandleTunnelAck(&pb.TunnelAck{ConnId: connID, Bytes: int32(n)})
+	return nil
+}
+
+func (a *tunnelAgent) SendClose(connID, reason string) error {
+	a.hub.HandleTunnelClose(&pb.TunnelClose{ConnId: connID, Error: reason})
+	return nil
+}
+
+// echoServer 启动回显服务，模拟 Agent 主机上的目标端口
+func echoServer(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				io.Copy(conn, conn)
+				conn.Close()
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// dialTunnel 连接端口转发监听地址，客户端无需任何额外协议
+func dialTunnel(t *testing.T, record *agentmodel.AgentTunnelSession) net.Conn {
+	return dialTunnelFrom(t, "127.0.0.1", record)
+}
+
+// dialTunnelFrom 从指定的本机地址连接监听地址，模拟不同来源的客户端
+func dialTunnelFrom(t *testing.T, localIP string, record *agentmodel.AgentTunnelSession) net.Conn {
+	d := net.Dialer{LocalAddr: &net.TCPAddr{IP: net.ParseIP(localIP)}}
+	conn, err := d.Dial("tcp", record.ListenAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+// assertRejected 连接应被服务端直接关闭，收不到回显
+func assertRejected(t *testing.T, conn net.Conn) {
+	t.Helper()
+	defer conn.Close()
+	conn.Write([]byte("hello"))
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if n, err := conn.Read(make([]byte, 5)); err == nil {
+		t.Fatalf("非发起方的连接应被拒绝, 实际收到 %d 字节", n)
+	}
+}
+
+func newTestTunnelManager(t *testing.T) (*TunnelManager, *AgentHub, *gorm.DB) {
+	appLogger.Log = zap.NewNop()
+	db := testutil.NewDB(t, &agentmodel.AgentTunnelSession{})
+	hub := NewAgentHub()
+	agent := &tunnelAgent{hub: hub, conns: map[string]*tunnel.Endpoint{}}
+	agent.as = hub.Register("agent-1", 7, nil)
+	go agent.serve()
+	t.Cleanup(func() {
+		// 等待连接协程退出，避免影响后续测试
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			hub.tunnelMu.Lock()
+			n := len(hub.tunnels)
+			hub.tunnelMu.Unlock()
+			if n == 0 {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		hub.Unregister("agent-1")
+	})
+	m := &TunnelManager{hub: hub, db: db, bindAddr: "127.0.0.1", maxTTL: time.Hour, sessions: map[string]*tunnelSession{}}
+	return m, hub, db
+}
+
+func TestTunnel_ForwardAndAudit(t *testing.T) {
+	m, _, db := newTestTunnelManager(t)
+	target := echoServer(t)
+
+	record, err := m.Open(context.Background(), 7, 1, "admin", "127.0.0.1", target, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := make([]byte, 600<<10)
+	rand.Read(payload)
+	for i := 0; i < 2; i++ {
+		conn := dialTunnel(t, record)
+		// 隧道不支持半关闭，读完回显后再关闭连接
+		go conn.Write(payload)
+		conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+		echoed := make([]byte, len(payload))
+		_, err = io.ReadFull(conn, echoed)
+		conn.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(echoed, payload) {
+			t.Fatalf("回显数据不一致: 收到 %d 字节, 期望 %d", len(echoed), len(payload))
+		}
+	}
+
+	// 连接统计在连接关闭后异步写入
+	var saved agentmodel.AgentTunnelSession
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		db.First(&saved, record.ID)
+		if saved.Connections == 2 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	want := int64(2 * len(payload))
+	if saved.Connections != 2 || saved.BytesSent != want || saved.BytesReceived != want {
+		t.Fatalf("审计计数错误: connections=%d sent=%d received=%d", saved.Connections, saved.BytesSent, saved.BytesReceived)
+	}
+
+	if err := m.Close(record.SessionID, "测试关闭"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Close(record.SessionID, "测试关闭"); err != errTunnelNotFound {
+		t.Fatalf("重复关闭应返回 errTunnelNotFound, 实际 %v", err)
+	}
+	db.First(&saved, record.ID)
+	if saved.Status != agentmodel.TunnelSessionClosed || saved.ClosedAt == nil || saved.CloseReason != "测试关闭" {
+		t.Fatalf("会话关闭状态错误: %+v", saved)
+	}
+	if _, err := net.DialTimeout("tcp", record.ListenAddr, time.Second); err == nil {
+		t.Fatal("会话关闭后不应继续监听")
+	}
+}
+
+func TestTunnel_ExpireAndTargetFailure(t *testing.T) {
+	m, _, db := newTestTunnelManager(t)
+
+	if _, err := m.Open(context.Background(), 8, 1, "admin", "127.0.0.1", "22", 0); err == nil {
+		t.Fatal("Agent不在线时应拒绝创建")
+	}
+	if _, err := m.Open(context.Background(), 7, 1, "admin", "127.0.0.1", "127.0.0.1:0", 0); err == nil {
+		t.Fatal("应拒绝无效端口")
+	}
+
+	// 目标端口未监听，接入的连接应被关闭
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	closedTarget := ln.Addr().String()
+	ln.Close()
+	record, err := m.Open(context.Background(), 7, 1, "admin", "127.0.0.1", closedTarget, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := dialTunnel(t, record)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("目标不可达时应关闭连接, 实际 %v", err)
+	}
+	conn.Close()
+	m.Close(record.SessionID, "")
+
+	record, err = m.Open(context.Background(), 7, 1, "admin", "127.0.0.1", echoServer(t), 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved agentmodel.AgentTunnelSession
+	deadline := time.Now().Add(5 * time.Second)
+	for saved.Status != agentmodel.TunnelSessionExpired && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+		db.First(&saved, record.ID)
+	}
+	if saved.Status != agentmodel.TunnelSessionExpired {
+		t.Fatalf("会话应已过期, 实际状态 %q", saved.Status)
+	}
+	if _, ok := m.sessions[record.SessionID]; ok {
+		t.Fatal("过期会话应从内存移除")
+	}
+}
+
+func TestTunnel_OnlyOpenerAddress(t *testing.T) {
+	m, _, db := newTestTunnelManager(t)
+	target := echoServer(t)
+
+	if _, err := m.Open(context.Background(), 7, 1, "admin", "", target, 0); err == nil {
+		t.Fatal("无法识别发起方地址时应拒绝创建")
+	}
+
+	// 监听在非回环地址时只接受发起方地址，IPv4 映射地址按 IPv4 匹配
+	record, err := m.Open(context.Background(), 7, 1, "admin", "::ffff:10.9.9.9", target, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close(record.SessionID, "")
+	var saved agentmodel.AgentTunnelSession
+	db.First(&saved, record.ID)
+	if saved.ClientIP != "10.9.9.9" {
+		t.Fatalf("发起方地址 = %q", saved.ClientIP)
+	}
+	sess := &tunnelSession{client: netip.MustParseAddr("10.9.9.9")}
+	tests := []struct {
+		remote string
+		want   bool
+	}{
+		{"10.9.9.9:50000", true},
+		{"[::ffff:10.9.9.9]:50000", true},
+		{"10.9.9.8:50000", false},
+		{"127.0.0.1:50000", false},
+	}
+	for _, tt := range tests {
+		if got := sess.permits(net.TCPAddrFromAddrPort(netip.MustParseAddrPort(tt.remote))); got != tt.want {
+			t.Errorf("permits(%s) = %v, want %v", tt.remote, got, tt.want)
+		}
+	}
+
+	// 发起方与本机其他进程地址不同：发起方可以接入，本机的第二个客户端被拒绝
+	local, err := m.Open(context.Background(), 7, 1, "admin", "127.0.0.2", target, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close(local.SessionID, "")
+	conn := dialTunnelFrom(t, "127.0.0.2", local)
+	defer conn.Close()
+	conn.Write([]byte("hello"))
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	echoed := make([]byte, 5)
+	if _, err := io.ReadFull(conn, echoed); err != nil || string(echoed) != "hello" {
+		t.Fatalf("发起方回显 = %q, %v", echoed, err)
+	}
+	assertRejected(t, dialTunnelFrom(t, "127.0.0.1", local))
+}
+
+// TestTunnel_DefaultConfig 默认配置：只信任本机代理，伪造的 X-Forwarded-For 不被采信，
+// 服务端本机的请求不能创建会话，本机进程也不能接入其他用户的会话
+func TestTunnel_DefaultConfig(t *testing.T) {
+	m, _, _ := newTestTunnelManager(t)
+	if def := NewTunnelManager(&GRPCServer{hub: m.hub, db: m.db, conf: &conf.Config{}}); def.bindAddr != "0.0.0.0" {
+		t.Fatalf("默认监听地址 = %q", def.bindAddr)
+	}
+	target := echoServer(t)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	if err := r.SetTrustedProxies(conf.ServerConfig{}.TrustedProxyList()); err != nil {
+		t.Fatal(err)
+	}
+	s := &HTTPServer{db: m.db, tunnels: m}
+	r.POST("/agents/:hostId/tunnels", s.OpenTunnel)
+	request := func(remoteAddr, forwardedFor string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/agents/7/tunnels", strings.NewReader(`{"target":"`+target+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	open := func(remoteAddr, forwardedFor string) *agentmodel.AgentTunnelSession {
+		w := request(remoteAddr, forwardedFor)
+		var resp struct {
+			Data agentmodel.AgentTunnelSession `json:"data"`
+		}
+		if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &resp) != nil {
+			t.Fatalf("status=%d body=%s", w.Code, w.Body)
+		}
+		return &resp.Data
+	}
+
+	// 直连的远程客户端伪造 X-Forwarded-For，记录的仍是真实来源
+	record := open("203.0.113.7:51000", "127.0.0.1")
+	defer m.Close(record.SessionID, "")
+	if record.ClientIP != "203.0.113.7" {
+		t.Fatalf("发起方地址 = %q, 伪造的 X-Forwarded-For 不应被采信", record.ClientIP)
+	}
+	// 经本机反向代理的请求采信代理传递的地址
+	proxied := open("127.0.0.1:52000", "198.51.100.2")
+	defer m.Close(proxied.SessionID, "")
+	if proxied.ClientIP != "198.51.100.2" {
+		t.Fatalf("经本机代理的发起方地址 = %q", proxied.ClientIP)
+	}
+
+	// 本机进程（包括经 ssh -L 转发的连接）不能接入远程用户的会话
+	assertRejected(t, dialTunnel(t, record))
+
+	// 本机直接发起（未经可信代理）的请求无法确认发起方，拒绝创建
+	for _, remote := range []string{"127.0.0.1:53000", "[::1]:53000"} {
+		if w := request(remote, ""); w.Code != http.StatusBadRequest {
+			t.Fatalf("本机请求 %s 应被拒绝, status=%d body=%s", remote, w.Code, w.Body)
+		}
+	}
+}
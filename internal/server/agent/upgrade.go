@@ -0,0 +1,362 @@
+package agent
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ydcloud-dy/opshub/internal/conf"
+	agentrepo "github.com/ydcloud-dy/opshub/internal/data/agent"
+	pb "github.com/ydcloud-dy/opshub/pkg/agentproto"
+	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultRollbackTimeout 新版本重新注册的默认期限（秒）
+	defaultRollbackTimeout = 120
+	// upgradeStageDir 新二进制在Agent主机上的暂存目录，校验后由Agent移动到安装目录
+	upgradeStageDir = "/tmp"
+)
+
+var (
+	// errAgentUpToDate Agent已是目标版本
+	errAgentUpToDate = errors.New("Agent已是目标版本")
+	// errAgentOffline Agent不在线
+	errAgentOffline = errors.New("Agent不在线")
+	// errAgentDowngrade 安装包版本低于Agent当前版本且未明确要求回退
+	errAgentDowngrade = errors.New("安装包版本低于Agent当前版本，如需回退请明确指定")
+
+	packageVersionRe = regexp.MustCompile(`^srehub-agent-(v?\d[\w.+]*(?:-[\w.+]+)*?)(?:-multi-arch)?$`)
+)
+
+// upgradePublicKey 解析配置的发布签名公钥；签名私钥仅保存在离线发布环境，服务端只负责校验和分发
+func upgradePublicKey(cfg *conf.Config) (ed25519.PublicKey, error) {
+	if cfg.Agent.UpgradePublicKey == "" {
+		return nil, fmt.Errorf("未配置 agent.upgrade_public_key，无法远程升级")
+	}
+	pub, err := base64.StdEncoding.DecodeString(strings.TrimSpace(cfg.Agent.UpgradePublicKey))
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("agent.upgrade_public_key 不是有效的 ed25519 公钥")
+	}
+	return ed25519.PublicKey(pub), nil
+}
+
+// decodeSignature 解析 .sig 文件，支持原始 64 字节签名（openssl pkeyutl -sign -rawin 输出）或其 base64 文本
+func decodeSignature(data []byte) ([]byte, error) {
+	if len(data) == ed25519.SignatureSize {
+		return data, nil
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("签名文件格式错误")
+	}
+	return sig, nil
+}
+
+// agentBinary 安装包中某个平台的Agent二进制
+type agentBinary struct {
+	Version   string
+	Data      []byte
+	SHA256    string
+	Signature []byte // 对 pb.UpgradeManifest 的发布签名
+}
+
+// UpgradeController 通过Agent通道远程升级，并按阶段灰度推进升级批次
+type UpgradeController struct {
+	hub       *AgentHub
+	db        *gorm.DB
+	tlsMgr    *TLSManager
+	conf      *conf.Config
+	agentRepo *agentrepo.Repository
+
+	binMu    sync.Mutex
+	binCache map[string]*agentBinary
+
+	runMu   sync.Mutex
+	running map[uint]*rolloutRun
+
+	// upgradeHost 升级单台主机，测试时可替换
+	upgradeHost func(ctx context.Context, hostID uint, rollbackTimeout int, allowDowngrade bool) (string, error)
+}
+
+// NewUpgradeController 创建升级控制器
+func NewUpgradeController(grpcServer *GRPCServer) *UpgradeController {
+	u := &UpgradeController{
+		hub:       grpcServer.Hub(),
+		db:        grpcServer.db,
+		tlsMgr:    grpcServer.TLSManager(),
+		conf:      grpcServer.conf,
+		agentRepo: grpcServer.AgentRepo(),
+		binCache:  make(map[string]*agentBinary),
+		running:   make(map[uint]*rolloutRun),
+	}
+	u.upgradeHost = u.UpgradeHost
+	return u
+}
+
+// PackageVersion 当前安装包的Agent版本
+func (u *UpgradeController) PackageVersion() (string, error) {
+	tarball, err := findAgentTarball(u.conf.Agent.BinaryDir)
+	if err != nil {
+		return "", err
+	}
+	return packageVersion(tarball)
+}
+
+// packageVersion 从安装包文件名解析版本号，如 srehub-agent-1.2.0-multi-arch.tar.gz
+func packageVersion(tarball string) (string, error) {
+	m := packageVersionRe.FindStringSubmatch(strings.TrimSuffix(filepath.Base(tarball), ".tar.gz"))
+	if m == nil {
+		return "", fmt.Errorf("无法从安装包名称 %s 识别版本号", filepath.Base(tarball))
+	}
+	return strings.TrimPrefix(m[1], "v"), nil
+}
+
+// loadAgentBinary 从安装包读取指定平台的二进制及其离线签名（同目录下的 .sig 文件，签名对象为升级清单），
+// 用配置的发布公钥校验通过后按安装包路径和修改时间缓存
+func (u *UpgradeController) loadAgentBinary(osName, arch string) (*agentBinary, error) {
+	tarball, err := findAgentTarball(u.conf.Agent.BinaryDir)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(tarball)
+	if err != nil {
+		return nil, err
+	}
+	cacheKey := fmt.Sprintf("%s:%d:%s/%s", tarball, info.ModTime().UnixNano(), osName, arch)
+
+	u.binMu.Lock()
+	defer u.binMu.Unlock()
+	if bin, ok := u.binCache[cacheKey]; ok {
+		return bin, nil
+	}
+
+	version, err := packageVersion(tarball)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := upgradePublicKey(u.conf)
+	if err != nil {
+		return nil, err
+	}
+	entry := fmt.Sprintf("bin/srehub-agent-%s-%s", osName, arch)
+	data, err := readTarballEntry(tarball, entry)
+	if err != nil {
+		return nil, err
+	}
+	sigData, err := readTarballEntry(tarball, entry+".sig")
+	if err != nil {
+		return nil, fmt.Errorf("安装包缺少发布签名: %w", err)
+	}
+	sig, err := decodeSignature(sigData)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+	// 提前校验，避免向Agent下发必然被拒绝的升级包
+	if !ed25519.Verify(pub, pb.UpgradeManifest(version, osName, arch, digest), sig) {
+		return nil, fmt.Errorf("%s 发布签名校验失败", entry)
+	}
+	bin := &agentBinary{
+		Version:   version,
+		Data:      data,
+		SHA256:    digest,
+		Signature: sig,
+	}
+	// 安装包更新后旧缓存失效
+	for k := range u.binCache {
+		if !strings.HasPrefix(k, fmt.Sprintf("%s:%d:", tarball, info.ModTime().UnixNano())) {
+			delete(u.binCache, k)
+		}
+	}
+	u.binCache[cacheKey] = bin
+	return bin, nil
+}
+
+// readTarballEntry 读取安装包中以 suffix 结尾的文件（忽略顶层目录）
+func readTarballEntry(tarball, suffix string) ([]byte, error) {
+	f, err := os.Open(tarball)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("安装包中未找到 %s", suffix)
+		}
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimPrefix(hdr.Name, "./")
+		if hdr.Typeflag == tar.TypeReg && (name == suffix || strings.HasSuffix(name, "/"+suffix)) {
+			return io.ReadAll(tr)
+		}
+	}
+}
+
+// UpgradeHost 通过Agent通道升级单台主机：分片上传新二进制，下发升级指令并等待新版本重新注册
+// 安装包版本低于当前版本时仅在 allowDowngrade 时下发，防止重放旧的已签名版本
+func (u *UpgradeController) UpgradeHost(ctx context.Context, hostID uint, rollbackTimeout int, allowDowngrade bool) (string, error) {
+	if !u.hub.IsOnline(hostID) {
+		return "", errAgentOffline
+	}
+	info, err := u.agentRepo.GetByHostID(ctx, hostID)
+	if err != nil {
+		return "", fmt.Errorf("获取Agent信息失败: %w", err)
+	}
+	bin, err := u.loadAgentBinary(info.OS, info.Arch)
+	if err != nil {
+		return "", err
+	}
+	if info.Version == bin.Version {
+		return bin.Version, errAgentUpToDate
+	}
+	if !allowDowngrade && pb.IsDowngrade(info.Version, bin.Version) {
+		return "", errAgentDowngrade
+	}
+	if rollbackTimeout <= 0 {
+		rollbackTimeout = defaultRollbackTimeout
+	}
+
+	filename := fmt.Sprintf("srehub-agent-%s.upgrade", bin.Version)
+	if err := u.hub.UploadFile(ctx, hostID, bytes.NewReader(bin.Data), int64(len(bin.Data)), UploadOptions{
+		Dir: upgradeStageDir, Filename: filename, Mode: 0700,
+	}); err != nil {
+		return "", fmt.Errorf("上传新版本失败: %w", err)
+	}
+
+	as, ok := u.hub.GetByHostID(hostID)
+	if !ok {
+		return "", errAgentOffline
+	}
+	requestID := uuid.New().String()
+	registered := u.hub.RegisterUpgradeWaiter(requestID)
+	defer u.hub.UnregisterUpgradeWaiter(requestID)
+	resultCh := as.RegisterPending(requestID)
+	defer func() {
+		as.pendMu.Lock()
+		delete(as.pending, requestID)
+		as.pendMu.Unlock()
+	}()
+
+	if err := as.Send(&pb.ServerMessage{
+		Payload: &pb.ServerMessage_AgentUpgrade{AgentUpgrade: &pb.AgentUpgrade{
+			RequestId:       requestID,
+			Version:         bin.Version,
+			Path:            upgradeStageDir + "/" + filename,
+			Sha256:          bin.SHA256,
+			Signature:       bin.Signature,
+			RollbackTimeout: int32(rollbackTimeout),
+			AllowDowngrade:  allowDowngrade,
+		}},
+	}); err != nil {
+		return "", err
+	}
+
+	appLogger.Info("已下发Agent升级指令",
+		zap.Uint("hostID", hostID), zap.String("from", info.Version), zap.String("to", bin.Version))
+
+	// Agent校验、预检通过后返回结果，随即重启
+	select {
+	case result := <-resultCh:
+		r, ok := result.(*pb.AgentUpgradeResult)
+		if !ok {
+			return "", fmt.Errorf("响应类型错误")
+		}
+		if !r.Success {
+			return "", fmt.Errorf("Agent拒绝升级: %s", r.Error)
+		}
+	case <-as.DoneCh:
+		return "", fmt.Errorf("Agent连接已断开")
+	case <-time.After(2 * time.Minute):
+		return "", fmt.Errorf("等待Agent升级结果超时")
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	// 新版本重新注册后确认；超时未注册时Agent会自行回滚并以旧版本上报
+	select {
+	case req := <-registered:
+		if req.RolledBack {
+			return "", fmt.Errorf("新版本未能正常运行，已回滚: %s", req.UpgradeError)
+		}
+		if req.Version != bin.Version {
+			return "", fmt.Errorf("升级后版本不一致: 期望 %s, 实际 %s", bin.Version, req.Version)
+		}
+	case <-time.After(time.Duration(rollbackTimeout)*time.Second + 2*time.Minute):
+		return "", fmt.Errorf("等待Agent重新注册超时")
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	appLogger.Info("Agent升级完成", zap.Uint("hostID", hostID), zap.String("version", bin.Version))
+	return bin.Version, nil
+}
+
+// UpgradeAgentViaChannel 通过Agent通道升级（无需SSH）
+func (s *HTTPServer) UpgradeAgentViaChannel(c *gin.Context) {
+	hostID, err := strconv.ParseUint(c.Param("hostId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "无效的主机ID"})
+		return
+	}
+	var req struct {
+		RollbackTimeout int  `json:"rollbackTimeout"`
+		AllowDowngrade  bool `json:"allowDowngrade"` // 明确回退到安装包中的较低版本
+	}
+	c.ShouldBindJSON(&req)
+
+	version, err := s.upgrades.UpgradeHost(c.Request.Context(), uint(hostID), req.RollbackTimeout, req.AllowDowngrade)
+	if errors.Is(err, errAgentUpToDate) {
+		c.JSON(http.StatusOK, gin.H{"code": 0, "message": "Agent已是最新版本", "data": gin.H{"version": version}})
+		return
+	}
+	if errors.Is(err, errAgentDowngrade) {
+		c.JSON(http.StatusConflict, gin.H{"code": 409, "message": err.Error()})
+		return
+	}
+	if err != nil {
+		appLogger.Error("Agent远程升级失败", zap.Uint64("hostID", hostID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": fmt.Sprintf("升级失败: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "Agent升级成功", "data": gin.H{"version": version}})
+}
+
+// GetUpgradePackage 获取当前安装包版本
+func (s *HTTPServer) GetUpgradePackage(c *gin.Context) {
+	version, err := s.upgrades.PackageVersion()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success", "data": gin.H{"version": version}})
+}
@@ -3,9 +3,11 @@ package alert
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	biz "github.com/ydcloud-dy/opshub/internal/biz/alert"
+	alertsvc "github.com/ydcloud-dy/opshub/internal/service/alert"
 	"github.com/ydcloud-dy/opshub/pkg/response"
 )
 
@@ -84,8 +86,13 @@ func (s *HTTPServer) testChannel(c *gin.Context) {
 		Value:    99.9,
 		Labels:   `{"env":"test"}`,
 	}
-	s.notifySvc.Send(c.Request.Context(), ch, testEvent, false, []string{}, []uint{})
-	response.Success(c, gin.H{"message": "测试通知已发送"})
+	// 短信/电话通道可通过 phones 参数指定测试号码（逗号分隔）
+	phones := []string{}
+	if p := strings.TrimSpace(c.Query("phones")); p != "" {
+		phones = strings.Split(p, ",")
+	}
+	result := s.notifySvc.Send(c.Request.Context(), ch, testEvent, false, phones, []uint{})
+	response.Success(c, gin.H{"message": "测试通知已发送", "result": result})
 }
 
 func defaultAlertTemplate(channelType string) string {
@@ -94,11 +101,17 @@ func defaultAlertTemplate(channelType string) string {
 		return "## 🔴 SreHub 告警通知\n> **规则**: <font color=\"warning\">{{.RuleName}}</font>\n> **级别**: {{.Severity}}\n> **当前值**: {{.Value}}\n> **触发时间**: {{.FiredAt}}\n> **标签**: {{.Labels}}"
 	case "dingtalk":
 		return "## 🔴 SreHub 告警通知\n- **规则**: {{.RuleName}}\n- **级别**: {{.Severity}}\n- **当前值**: {{.Value}}\n- **触发时间**: {{.FiredAt}}"
+	case "feishu", "slack", "teams", "webhook":
+		return alertsvc.DefaultTemplate(channelType, false)
 	default:
 		return "【OpsHub告警】规则: {{.RuleName}} | 级别: {{.Severity}} | 值: {{.Value}} | 时间: {{.FiredAt}}"
 	}
 }
 
 func defaultResolveTemplate(channelType string) string {
+	switch channelType {
+	case "feishu", "slack", "teams", "webhook":
+		return alertsvc.DefaultTemplate(channelType, true)
+	}
 	return "## ✅ 告警已恢复\n**规则**: {{.RuleName}}\n**级别**: {{.Severity}}\n**触发值**: {{.Value}}\n**恢复时间**: {{.ResolvedAt}}\n**触发时间**: {{.FiredAt}}"
 }
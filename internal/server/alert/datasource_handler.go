@@ -312,6 +312,10 @@ func (s *HTTPServer) testDataSource(c *gin.Context) {
 			testPath = "/api/v1/query?query=up"
 		case "influxdb":
 			testPath = "/query?q=SHOW+DATABASES"
+		case "loki":
+			testPath = "/loki/api/v1/labels"
+		case "clickhouse":
+			testPath = "/?query=SELECT+1"
 		default:
 			testPath = "/"
 		}
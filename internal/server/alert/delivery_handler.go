@@ -0,0 +1,65 @@
+package alert
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	rbacService "github.com/ydcloud-dy/opshub/internal/service/rbac"
+	"github.com/ydcloud-dy/opshub/pkg/response"
+)
+
+// getEvent 告警事件详情（含通知投递记录、升级进度和处理时间线）
+func (s *HTTPServer) getEvent(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	event, err := s.eventRepo.GetByID(c.Request.Context(), uint(id))
+	if err != nil {
+		response.ErrorCode(c, http.StatusNotFound, "告警事件不存在")
+		return
+	}
+	deliveries, err := s.deliveryRepo.ListByEventID(c.Request.Context(), event.ID)
+	if err != nil {
+		response.ErrorCode(c, http.StatusInternalServerError, "查询投递记录失败")
+		return
+	}
+	event.Deliveries = deliveries
+	event.Escalations, _ = s.eventEscalationRepo.ListByEventID(c.Request.Context(), event.ID)
+	event.Timeline, _ = s.eventActionSvc.Timeline(c.Request.Context(), event.ID)
+	response.Success(c, event)
+}
+
+// listDeadLetters 通知死信列表
+func (s *HTTPServer) listDeadLetters(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	eventID, _ := strconv.ParseUint(c.Query("eventId"), 10, 64)
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	var resent *bool
+	if v := c.Query("resent"); v != "" {
+		b := v == "true"
+		resent = &b
+	}
+
+	list, total, err := s.deliveryRepo.ListDeadLetters(c.Request.Context(), page, pageSize, uint(eventID), resent)
+	if err != nil {
+		response.ErrorCode(c, http.StatusInternalServerError, "查询失败")
+		return
+	}
+	response.Pagination(c, total, page, pageSize, list)
+}
+
+// resendDeadLetter 手动重发死信通知
+func (s *HTTPServer) resendDeadLetter(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	delivery, err := s.evalEngine.GetNotifyQueue().Resend(c.Request.Context(), uint(id), rbacService.GetUserID(c))
+	if err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	response.Success(c, delivery)
+}
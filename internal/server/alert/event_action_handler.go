@@ -0,0 +1,288 @@
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"html/template"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	biz "github.com/ydcloud-dy/opshub/internal/biz/alert"
+	alertsvc "github.com/ydcloud-dy/opshub/internal/service/alert"
+	rbacService "github.com/ydcloud-dy/opshub/internal/service/rbac"
+	"github.com/ydcloud-dy/opshub/pkg/logger"
+	"github.com/ydcloud-dy/opshub/pkg/response"
+)
+
+// maxCallbackBodySize IM 回调请求体大小上限
+const maxCallbackBodySize = 1 << 20
+
+// eventActionLabels 操作中文名（用于链接确认页和 IM 回复）
+var eventActionLabels = map[string]string{
+	biz.EventActionAck:     "确认",
+	biz.EventActionUnack:   "取消确认",
+	biz.EventActionSilence: "屏蔽 1 小时",
+	biz.EventActionResolve: "解决",
+}
+
+// --- 控制台操作 ---
+
+func (s *HTTPServer) ackEvent(c *gin.Context)     { s.applyEventAction(c, biz.EventActionAck) }
+func (s *HTTPServer) unackEvent(c *gin.Context)   { s.applyEventAction(c, biz.EventActionUnack) }
+func (s *HTTPServer) claimEvent(c *gin.Context)   { s.applyEventAction(c, biz.EventActionClaim) }
+func (s *HTTPServer) assignEvent(c *gin.Context)  { s.applyEventAction(c, biz.EventActionAssign) }
+func (s *HTTPServer) resolveEvent(c *gin.Context) { s.applyEventAction(c, biz.EventActionResolve) }
+
+func (s *HTTPServer) applyEventAction(c *gin.Context, action string) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	var req struct {
+		AssigneeID uint   `json:"assigneeId"`
+		Note       string `json:"note"`
+	}
+	// 请求体可选
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.ErrorCode(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	event, err := s.eventActionSvc.Apply(c.Request.Context(), uint(id), action, alertsvc.EventActionRequest{
+		Source:     biz.EventActionSourceWeb,
+		OperatorID: rbacService.GetUserID(c),
+		Operator:   rbacService.GetUsername(c),
+		AssigneeID: req.AssigneeID,
+		Note:       req.Note,
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.ErrorCode(c, http.StatusNotFound, "告警事件不存在")
+			return
+		}
+		response.ErrorCode(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	response.Success(c, event)
+}
+
+// getEventTimeline 告警事件处理时间线
+func (s *HTTPServer) getEventTimeline(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	list, err := s.eventActionSvc.Timeline(c.Request.Context(), uint(id))
+	if err != nil {
+		response.ErrorCode(c, http.StatusInternalServerError, "查询失败")
+		return
+	}
+	response.Success(c, list)
+}
+
+// --- 一键处理链接 ---
+
+var eventActionPage = template.Must(template.New("event-action").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><meta name="viewport" content="width=device-width,initial-scale=1">
+<title>告警处理</title>
+<style>body{font-family:sans-serif;max-width:480px;margin:40px auto;padding:0 16px;color:#1d2129}
+.card{border:1px solid #e5e6eb;border-radius:8px;padding:20px}.muted{color:#86909c;font-size:14px}
+button{width:100%;padding:12px;margin-top:16px;border:0;border-radius:6px;background:#165dff;color:#fff;font-size:16px}</style>
+</head><body><div class="card">
+{{if .Event}}<h3>{{.Event.RuleName}}</h3>
+<p class="muted">事件 #{{.Event.ID}} · {{.Event.Severity}} · {{.Event.Status}}{{if .Event.Acknowledged}} · 已确认{{end}}</p>{{end}}
+<p>{{.Message}}</p>
+{{if .Confirm}}<form method="post">
+<input type="hidden" name="event" value="{{.Query.event}}"><input type="hidden" name="action" value="{{.Query.action}}">
+<input type="hidden" name="expires" value="{{.Query.expires}}"><input type="hidden" name="sig" value="{{.Query.sig}}">
+<button type="submit">{{.Label}}</button></form>{{end}}
+</div></body></html>`))
+
+func renderEventActionPage(c *gin.Context, status int, data gin.H) {
+	var buf bytes.Buffer
+	if err := eventActionPage.Execute(&buf, data); err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.Data(status, "text/html; charset=utf-8", buf.Bytes())
+}
+
+// eventActionLink 通知中的一键处理链接
+// GET 只展示确认页（避免 IM 客户端预览链接时误触发），POST 执行操作
+func (s *HTTPServer) eventActionLink(c *gin.Context) {
+	query := map[string]string{
+		"event":   c.Request.FormValue("event"),
+		"action":  c.Request.FormValue("action"),
+		"expires": c.Request.FormValue("expires"),
+		"sig":     c.Request.FormValue("sig"),
+	}
+	eventID, _ := strconv.ParseUint(query["event"], 10, 64)
+	expires, _ := strconv.ParseInt(query["expires"], 10, 64)
+	action := query["action"]
+	if err := alertsvc.VerifyEventAction(uint(eventID), action, expires, query["sig"], time.Now()); err != nil {
+		renderEventActionPage(c, http.StatusForbidden, gin.H{"Message": err.Error()})
+		return
+	}
+	ctx := c.Request.Context()
+	label := eventActionLabels[action]
+
+	if c.Request.Method != http.MethodPost {
+		event, err := s.eventRepo.GetByID(ctx, uint(eventID))
+		if err != nil {
+			renderEventActionPage(c, http.StatusNotFound, gin.H{"Message": "告警事件不存在"})
+			return
+		}
+		renderEventActionPage(c, http.StatusOK, gin.H{
+			"Event": event, "Message": "确认对该告警执行「" + label + "」？",
+			"Confirm": event.Status != "resolved", "Query": query, "Label": label,
+		})
+		return
+	}
+
+	event, err := s.eventActionSvc.Apply(ctx, uint(eventID), action, alertsvc.EventActionRequest{
+		Source:   biz.EventActionSourceLink,
+		Operator: c.ClientIP(),
+	})
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			status = http.StatusNotFound
+		}
+		renderEventActionPage(c, status, gin.H{"Message": err.Error()})
+		return
+	}
+	renderEventActionPage(c, http.StatusOK, gin.H{"Event": event, "Message": "已" + label})
+}
+
+// --- IM 回调 ---
+
+// applyCallbackCommand 执行 IM 回调中的处理命令，返回回复文本
+// imUserID 为 IM 平台用户ID，需映射到拥有告警处理权限的平台用户；display 为回复与时间线中展示的 IM 身份
+func (s *HTTPServer) applyCallbackCommand(c *gin.Context, source, imUserID, display string, mapping map[string]string, command string) string {
+	action, eventID, ok := alertsvc.ParseEventActionCommand(command)
+	if !ok {
+		return "无法识别的命令，格式：ack|unack|silence|resolve <事件ID>，如 ack 123"
+	}
+	return s.applyCallbackAction(c, source, imUserID, display, mapping, action, eventID)
+}
+
+// applyCallbackAction 以映射后的平台用户身份执行 IM 回调中的处理操作，返回回复文本
+func (s *HTTPServer) applyCallbackAction(c *gin.Context, source, imUserID, display string, mapping map[string]string, action string, eventID uint) string {
+	ctx := c.Request.Context()
+	userID, username, err := s.eventActionSvc.IMOperator(ctx, imUserID, mapping)
+	if err != nil {
+		logger.Warn("IM 回调用户无权处理告警", zap.String("source", source), zap.String("imUser", display), zap.Error(err))
+		return err.Error()
+	}
+	event, err := s.eventActionSvc.Apply(ctx, eventID, action, alertsvc.EventActionRequest{
+		Source:     source,
+		OperatorID: userID,
+		Operator:   username + "(" + display + ")",
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "告警事件 #" + strconv.FormatUint(uint64(eventID), 10) + " 不存在"
+		}
+		return err.Error()
+	}
+	logger.Info("IM 回调处理告警", zap.String("source", source), zap.String("operator", username), zap.String("imUser", display),
+		zap.String("action", action), zap.Uint("eventID", eventID))
+	return "已" + eventActionLabels[action] + "告警 #" + strconv.FormatUint(uint64(event.ID), 10) + " " + event.RuleName
+}
+
+// callbackChannel 查询回调对应的通知通道
+func (s *HTTPServer) callbackChannel(c *gin.Context, channelType string) *biz.AlertNotifyChannel {
+	id, _ := strconv.ParseUint(c.Param("channelId"), 10, 64)
+	ch, err := s.channelRepo.GetByID(c.Request.Context(), uint(id))
+	if err != nil || ch.Type != channelType {
+		c.String(http.StatusNotFound, "channel not found")
+		return nil
+	}
+	return ch
+}
+
+// dingTalkCallback 钉钉机器人回调：群内 @机器人 发送 "ack 123" 等命令
+func (s *HTTPServer) dingTalkCallback(c *gin.Context) {
+	ch := s.callbackChannel(c, "dingtalk")
+	if ch == nil {
+		return
+	}
+	cfg, err := alertsvc.ParseDingTalkCallbackConfig(ch.Config)
+	if err != nil {
+		c.String(http.StatusForbidden, err.Error())
+		return
+	}
+	if err := alertsvc.VerifyDingTalkCallback(cfg.AppSecret, c.GetHeader("timestamp"), c.GetHeader("sign"), time.Now()); err != nil {
+		logger.Warn("钉钉回调校验失败", zap.Uint("channelID", ch.ID), zap.Error(err))
+		c.String(http.StatusForbidden, err.Error())
+		return
+	}
+	var msg alertsvc.DingTalkCallbackMessage
+	if err := json.NewDecoder(io.LimitReader(c.Request.Body, maxCallbackBodySize)).Decode(&msg); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	reply := s.applyCallbackCommand(c, biz.EventActionSourceDingTalk, msg.SenderStaffID, msg.Operator(), cfg.UserMapping, msg.Text.Content)
+	c.JSON(http.StatusOK, gin.H{"msgtype": "text", "text": gin.H{"content": reply}})
+}
+
+// wechatWorkCallback 企业微信应用回调
+// GET 为配置回调地址时的 URL 验证；POST 接收文本消息命令与模板卡片按钮事件
+func (s *HTTPServer) wechatWorkCallback(c *gin.Context) {
+	ch := s.callbackChannel(c, "wechat_work")
+	if ch == nil {
+		return
+	}
+	cfg, crypto, err := alertsvc.ParseWecomCallbackConfig(ch.Config)
+	if err != nil {
+		c.String(http.StatusForbidden, err.Error())
+		return
+	}
+	signature, timestamp, nonce := c.Query("msg_signature"), c.Query("timestamp"), c.Query("nonce")
+
+	if c.Request.Method == http.MethodGet {
+		echo, err := crypto.Decrypt(signature, timestamp, nonce, c.Query("echostr"))
+		if err != nil {
+			c.String(http.StatusForbidden, err.Error())
+			return
+		}
+		c.String(http.StatusOK, string(echo))
+		return
+	}
+
+	var envelope alertsvc.WecomEnvelope
+	if err := xml.NewDecoder(io.LimitReader(c.Request.Body, maxCallbackBodySize)).Decode(&envelope); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	plain, err := crypto.Decrypt(signature, timestamp, nonce, envelope.Encrypt)
+	if err != nil {
+		logger.Warn("企业微信回调校验失败", zap.Uint("channelID", ch.ID), zap.Error(err))
+		c.String(http.StatusForbidden, err.Error())
+		return
+	}
+	var msg alertsvc.WecomCallbackMessage
+	if err := xml.Unmarshal(plain, &msg); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if msg.IsCardEvent() {
+		// 按钮 key 带签名与有效期，校验通过后仍需映射为有处理权限的平台用户
+		action, eventID, err := alertsvc.VerifyEventActionCardKey(msg.EventKey, time.Now())
+		if err != nil {
+			logger.Warn("企业微信卡片按钮校验失败", zap.Uint("channelID", ch.ID), zap.String("user", msg.FromUserName), zap.Error(err))
+			c.String(http.StatusOK, "")
+			return
+		}
+		reply := s.applyCallbackAction(c, biz.EventActionSourceWechatWork, msg.FromUserName, msg.FromUserName, cfg.UserMapping, action, eventID)
+		logger.Info("企业微信回调处理结果", zap.String("user", msg.FromUserName), zap.String("reply", reply))
+	} else if command := msg.Command(); command != "" {
+		reply := s.applyCallbackCommand(c, biz.EventActionSourceWechatWork, msg.FromUserName, msg.FromUserName, cfg.UserMapping, command)
+		logger.Info("企业微信回调处理结果", zap.String("user", msg.FromUserName), zap.String("reply", reply))
+	}
+	// 不做被动回复，返回空串表示已接收
+	c.String(http.StatusOK, "")
+}
@@ -7,6 +7,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 	biz "github.com/ydcloud-dy/opshub/internal/biz/alert"
+	alertsvc "github.com/ydcloud-dy/opshub/internal/service/alert"
+	rbacService "github.com/ydcloud-dy/opshub/internal/service/rbac"
 	"github.com/ydcloud-dy/opshub/pkg/response"
 )
 
@@ -77,15 +79,22 @@ func (s *HTTPServer) silenceEvent(c *gin.Context) {
 		response.ErrorCode(c, http.StatusBadRequest, "屏蔽时长格式错误")
 		return
 	}
-	until := time.Now().Add(dur)
-	event.Silenced = true
-	event.SilenceUntil = &until
-	event.SilenceReason = req.Reason
-	if err := s.eventRepo.Update(c.Request.Context(), event); err != nil {
-		response.ErrorCode(c, http.StatusInternalServerError, "操作失败")
+	if dur <= 0 {
+		response.ErrorCode(c, http.StatusBadRequest, "屏蔽时长必须大于 0")
+		return
+	}
+	event, err = s.eventActionSvc.Apply(c.Request.Context(), event.ID, biz.EventActionSilence, alertsvc.EventActionRequest{
+		Source:     biz.EventActionSourceWeb,
+		OperatorID: rbacService.GetUserID(c),
+		Operator:   rbacService.GetUsername(c),
+		Duration:   dur,
+		Note:       req.Reason,
+	})
+	if err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, err.Error())
 		return
 	}
-	response.Success(c, gin.H{"silenceUntil": until})
+	response.Success(c, gin.H{"silenceUntil": event.SilenceUntil})
 }
 
 func (s *HTTPServer) handleEvent(c *gin.Context) {
@@ -114,6 +123,12 @@ func (s *HTTPServer) handleEvent(c *gin.Context) {
 		response.ErrorCode(c, http.StatusInternalServerError, "操作失败")
 		return
 	}
+	s.eventActionSvc.Record(c.Request.Context(), event.ID, biz.EventActionHandle, alertsvc.EventActionRequest{
+		Source:     biz.EventActionSourceWeb,
+		OperatorID: rbacService.GetUserID(c),
+		Operator:   rbacService.GetUsername(c),
+		Note:       req.Note,
+	})
 	response.Success(c, event)
 }
 
@@ -30,6 +30,13 @@ type HTTPServer struct {
 	subUserRepo             *alertdata.SubscriptionUserRepo
 	subLogRepo              *alertdata.SubscriptionLogRepo
 	silenceRuleRepo         *alertdata.SilenceRuleRepo
+	receiverRepo            *alertdata.ReceiverRepo
+	deliveryRepo            *alertdata.NotifyDeliveryRepo
+	scheduleRepo            *alertdata.OnCallScheduleRepo
+	escalationRepo          *alertdata.EscalationPolicyRepo
+	eventEscalationRepo     *alertdata.EventEscalationRepo
+	oncallSvc               *alertsvc.OnCallService
+	eventActionSvc          *alertsvc.EventActionService
 	notifySvc               *alertsvc.NotifyService
 	evalEngine              *alertsvc.EvalEngine
 	patrolService           *alertsvc.PatrolService
@@ -52,9 +59,13 @@ func NewAlertServices(db *gorm.DB, rdb *redis.Client) *HTTPServer {
 	subUserRepo := alertdata.NewSubscriptionUserRepo(db)
 	subLogRepo := alertdata.NewSubscriptionLogRepo(db)
 	silenceRuleRepo := alertdata.NewSilenceRuleRepo(db)
+	receiverRepo := alertdata.NewReceiverRepo(db)
+	deliveryRepo := alertdata.NewNotifyDeliveryRepo(db)
 	notifySvc := alertsvc.NewNotifyService(channelRepo, db)
 	evalEngine := alertsvc.NewEvalEngine(db, rdb)
 	patrolService := alertsvc.NewPatrolService(db, notifySvc)
+	eventActionSvc := alertsvc.NewEventActionService(db)
+	eventActionSvc.SetResolveHook(evalEngine.ClearEventState)
 
 	return &HTTPServer{
 		db:                  db,
@@ -72,6 +83,13 @@ func NewAlertServices(db *gorm.DB, rdb *redis.Client) *HTTPServer {
 		subUserRepo:         subUserRepo,
 		subLogRepo:          subLogRepo,
 		silenceRuleRepo:     silenceRuleRepo,
+		receiverRepo:        receiverRepo,
+		deliveryRepo:        deliveryRepo,
+		scheduleRepo:        alertdata.NewOnCallScheduleRepo(db),
+		escalationRepo:      alertdata.NewEscalationPolicyRepo(db),
+		eventEscalationRepo: alertdata.NewEventEscalationRepo(db),
+		oncallSvc:           alertsvc.NewOnCallService(db),
+		eventActionSvc:      eventActionSvc,
 		notifySvc:           notifySvc,
 		evalEngine:          evalEngine,
 		patrolService:       patrolService,
@@ -123,6 +141,10 @@ func (s *HTTPServer) RegisterRoutes(rg *gin.RouterGroup) {
 		rg2.GET("/:id", s.getRuleGroup)
 		rg2.PUT("/:id", s.updateRuleGroup)
 		rg2.DELETE("/:id", s.deleteRuleGroup)
+		// Prometheus 规则文件导入导出
+		rg2.POST("/:id/prometheus/preview", s.previewPromRules)
+		rg2.POST("/:id/prometheus/import", s.importPromRules)
+		rg2.GET("/:id/prometheus/export", s.exportPromRules)
 	}
 
 	// 告警规则
@@ -139,6 +161,7 @@ func (s *HTTPServer) RegisterRoutes(rg *gin.RouterGroup) {
 		rules.POST("/import", s.importRules)
 		rules.GET("/export", s.exportRules)
 		rules.POST("/adhoc-test", s.adhocTestRule)
+		rules.POST("/unit-test", s.unitTestRule)
 	}
 
 	// 告警事件
@@ -148,11 +171,25 @@ func (s *HTTPServer) RegisterRoutes(rg *gin.RouterGroup) {
 		events.GET("/history", s.listHistoryEvents)
 		events.POST("/:id/silence", s.silenceEvent)
 		events.POST("/:id/handle", s.handleEvent)
+		events.POST("/:id/ack", s.ackEvent)
+		events.POST("/:id/unack", s.unackEvent)
+		events.POST("/:id/claim", s.claimEvent)
+		events.POST("/:id/assign", s.assignEvent)
+		events.POST("/:id/resolve", s.resolveEvent)
+		events.GET("/:id/timeline", s.getEventTimeline)
 		events.GET("/stats", s.getEventStats)
 		events.GET("/trend", s.getEventTrend)
 		events.POST("/batch-silence", s.batchSilenceEvents)
 		events.POST("/batch-unsilence", s.batchUnsilenceEvents)
 		events.GET("/silenced", s.listSilencedEvents)
+		events.GET("/:id", s.getEvent)
+	}
+
+	// 通知死信
+	deadLetters := alert.Group("/dead-letters")
+	{
+		deadLetters.GET("", s.listDeadLetters)
+		deadLetters.POST("/:id/resend", s.resendDeadLetter)
 	}
 
 	// 通知通道
@@ -179,6 +216,31 @@ func (s *HTTPServer) RegisterRoutes(rg *gin.RouterGroup) {
 		subs.GET("/:id/logs", s.getSubscriptionLogs)
 	}
 
+	// 值班表
+	schedules := alert.Group("/oncall-schedules")
+	{
+		schedules.GET("", s.listOnCallSchedules)
+		schedules.POST("", s.createOnCallSchedule)
+		schedules.GET("/:id", s.getOnCallSchedule)
+		schedules.PUT("/:id", s.updateOnCallSchedule)
+		schedules.DELETE("/:id", s.deleteOnCallSchedule)
+		schedules.GET("/:id/current", s.getOnCallCurrent)
+		schedules.GET("/:id/shifts", s.listOnCallShifts)
+		schedules.GET("/:id/overrides", s.listOnCallOverrides)
+		schedules.POST("/:id/overrides", s.createOnCallOverride)
+		schedules.DELETE("/overrides/:id", s.deleteOnCallOverride)
+	}
+
+	// 升级策略
+	escalations := alert.Group("/escalation-policies")
+	{
+		escalations.GET("", s.listEscalationPolicies)
+		escalations.POST("", s.createEscalationPolicy)
+		escalations.GET("/:id", s.getEscalationPolicy)
+		escalations.PUT("/:id", s.updateEscalationPolicy)
+		escalations.DELETE("/:id", s.deleteEscalationPolicy)
+	}
+
 	// 屏蔽规则
 	silenceRules := alert.Group("/silence-rules")
 	{
@@ -190,6 +252,17 @@ func (s *HTTPServer) RegisterRoutes(rg *gin.RouterGroup) {
 		silenceRules.PUT("/:id/toggle", s.toggleSilenceRule)
 	}
 
+	// 外部告警接收器
+	receivers := alert.Group("/receivers")
+	{
+		receivers.GET("", s.listReceivers)
+		receivers.POST("", s.createReceiver)
+		receivers.GET("/:id", s.getReceiver)
+		receivers.PUT("/:id", s.updateReceiver)
+		receivers.DELETE("/:id", s.deleteReceiver)
+		receivers.PUT("/:id/token", s.resetReceiverToken)
+	}
+
 	// 去重规则
 	dedupHandler := NewDedupHandler(s.db)
 	dedupRules := alert.Group("/dedup-rules")
@@ -223,6 +296,9 @@ func (s *HTTPServer) RegisterRoutes(rg *gin.RouterGroup) {
 		inhibitRules.DELETE("/:id", inhibitHandler.Delete)
 	}
 
+	// 标签匹配器预览（屏蔽、抑制、订阅规则共用）
+	alert.POST("/label-matchers/preview", s.previewLabelMatchers)
+
 	// 告警巡检
 	patrol := alert.Group("/patrol/rule")
 	{
@@ -241,4 +317,19 @@ func (s *HTTPServer) RegisterPublicRoutes(router *gin.Engine) {
 	{
 		proxy.Any("/:token/*path", s.proxyDataSourceRequest)
 	}
+
+	// 外部告警接收（Alertmanager webhook / 通用 JSON，通过 Token 验证）
+	router.POST("/api/v1/alert/receivers/:token", s.receiveAlerts)
+
+	// 通知中的一键处理链接（通过签名验证）
+	router.GET("/api/v1/alert/event-actions", s.eventActionLink)
+	router.POST("/api/v1/alert/event-actions", s.eventActionLink)
+
+	// IM 回调（钉钉机器人签名 / 企业微信消息加密验证）
+	callbacks := router.Group("/api/v1/alert/callbacks")
+	{
+		callbacks.POST("/dingtalk/:channelId", s.dingTalkCallback)
+		callbacks.GET("/wechat-work/:channelId", s.wechatWorkCallback)
+		callbacks.POST("/wechat-work/:channelId", s.wechatWorkCallback)
+	}
 }
@@ -1,12 +1,14 @@
 package alert
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	biz "github.com/ydcloud-dy/opshub/internal/biz/alert"
 	alertdata "github.com/ydcloud-dy/opshub/internal/data/alert"
+	alertsvc "github.com/ydcloud-dy/opshub/internal/service/alert"
 	"github.com/ydcloud-dy/opshub/pkg/response"
 	"gorm.io/gorm"
 )
@@ -64,6 +66,10 @@ func (h *InhibitHandler) Create(c *gin.Context) {
 		response.ErrorCode(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	if err := validateInhibitMatchers(&rule); err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	if err := h.repo.Create(c.Request.Context(), &rule); err != nil {
 		response.ErrorCode(c, http.StatusInternalServerError, "创建失败")
@@ -82,6 +88,10 @@ func (h *InhibitHandler) Update(c *gin.Context) {
 		response.ErrorCode(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	if err := validateInhibitMatchers(&rule); err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	rule.ID = uint(id)
 	if err := h.repo.Update(c.Request.Context(), &rule); err != nil {
@@ -103,3 +113,14 @@ func (h *InhibitHandler) Delete(c *gin.Context) {
 
 	response.Success(c, nil)
 }
+
+// validateInhibitMatchers 校验源 / 目标匹配器语法
+func validateInhibitMatchers(rule *biz.AlertInhibitRule) error {
+	if _, err := alertsvc.ParseLabelMatchers(rule.SourceMatchers); err != nil {
+		return fmt.Errorf("源告警条件: %w", err)
+	}
+	if _, err := alertsvc.ParseLabelMatchers(rule.TargetMatchers); err != nil {
+		return fmt.Errorf("目标告警条件: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package alert
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+
+	biz "github.com/ydcloud-dy/opshub/internal/biz/alert"
+	alertsvc "github.com/ydcloud-dy/opshub/internal/service/alert"
+	"github.com/ydcloud-dy/opshub/pkg/response"
+)
+
+// maxMatcherPreviewEvents 预览返回的告警条数上限
+const maxMatcherPreviewEvents = 100
+
+// previewLabelMatchers 预览匹配器命中的当前告警
+// matchers 可以是选择器字符串、匹配器数组或旧格式标签对象；severity、ruleName 按事件字段匹配
+func (s *HTTPServer) previewLabelMatchers(c *gin.Context) {
+	var req struct {
+		Matchers json.RawMessage `json:"matchers"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	matchers, err := alertsvc.ParseLabelMatchers(string(req.Matchers))
+	if err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	events, err := s.eventRepo.ListAllFiring(c.Request.Context())
+	if err != nil {
+		response.ErrorCode(c, http.StatusInternalServerError, "查询失败")
+		return
+	}
+
+	matched := make([]*biz.AlertEvent, 0)
+	for _, event := range events {
+		if matchers.Matches(alertsvc.EventMatchLabels(event)) {
+			matched = append(matched, event)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].FiredAt.After(matched[j].FiredAt) })
+	total := len(matched)
+	if total > maxMatcherPreviewEvents {
+		matched = matched[:maxMatcherPreviewEvents]
+	}
+	response.Success(c, gin.H{
+		"matchers":    matchers,
+		"selector":    matchers.String(),
+		"total":       total,
+		"firingTotal": len(events),
+		"events":      matched,
+	})
+}
@@ -0,0 +1,321 @@
+package alert
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	biz "github.com/ydcloud-dy/opshub/internal/biz/alert"
+	alertsvc "github.com/ydcloud-dy/opshub/internal/service/alert"
+	rbacService "github.com/ydcloud-dy/opshub/internal/service/rbac"
+	"github.com/ydcloud-dy/opshub/pkg/response"
+)
+
+// --- OnCall Schedules ---
+
+func (s *HTTPServer) listOnCallSchedules(c *gin.Context) {
+	list, err := s.scheduleRepo.List(c.Request.Context())
+	if err != nil {
+		response.ErrorCode(c, http.StatusInternalServerError, "查询失败")
+		return
+	}
+	response.Success(c, list)
+}
+
+func (s *HTTPServer) createOnCallSchedule(c *gin.Context) {
+	var req biz.AlertOnCallSchedule
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validateOnCallSchedule(&req); err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	req.ID = 0
+	if err := s.scheduleRepo.Create(c.Request.Context(), &req); err != nil {
+		response.ErrorCode(c, http.StatusInternalServerError, "创建失败")
+		return
+	}
+	response.Success(c, req)
+}
+
+func (s *HTTPServer) getOnCallSchedule(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	schedule, err := s.scheduleRepo.GetByID(c.Request.Context(), uint(id))
+	if err != nil {
+		response.ErrorCode(c, http.StatusNotFound, "值班表不存在")
+		return
+	}
+	response.Success(c, schedule)
+}
+
+func (s *HTTPServer) updateOnCallSchedule(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	existing, err := s.scheduleRepo.GetByID(c.Request.Context(), uint(id))
+	if err != nil {
+		response.ErrorCode(c, http.StatusNotFound, "值班表不存在")
+		return
+	}
+	var req biz.AlertOnCallSchedule
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validateOnCallSchedule(&req); err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	req.ID = existing.ID
+	req.CreatedAt = existing.CreatedAt
+	if err := s.scheduleRepo.Update(c.Request.Context(), &req); err != nil {
+		response.ErrorCode(c, http.StatusInternalServerError, "更新失败")
+		return
+	}
+	response.Success(c, req)
+}
+
+func (s *HTTPServer) deleteOnCallSchedule(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	var count int64
+	s.db.Model(&biz.AlertSubscription{}).Where("schedule_id = ?", id).Count(&count)
+	if count > 0 {
+		response.ErrorCode(c, http.StatusBadRequest, "该值班表仍被订阅使用，无法删除")
+		return
+	}
+	if err := s.scheduleRepo.Delete(c.Request.Context(), uint(id)); err != nil {
+		response.ErrorCode(c, http.StatusInternalServerError, "删除失败")
+		return
+	}
+	response.Success(c, nil)
+}
+
+// getOnCallCurrent 查询当前（或 at 指定时刻）的值班人
+func (s *HTTPServer) getOnCallCurrent(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	at := time.Now()
+	if v := c.Query("at"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			response.ErrorCode(c, http.StatusBadRequest, "at 时间格式错误，应为 RFC3339")
+			return
+		}
+		at = t
+	}
+	shift, err := s.oncallSvc.CurrentOnCall(c.Request.Context(), uint(id), at)
+	if err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	response.Success(c, shift)
+}
+
+// listOnCallShifts 预览时间段内的值班班次（默认从现在起 14 天）
+func (s *HTTPServer) listOnCallShifts(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	schedule, err := s.scheduleRepo.GetByID(c.Request.Context(), uint(id))
+	if err != nil {
+		response.ErrorCode(c, http.StatusNotFound, "值班表不存在")
+		return
+	}
+	from := time.Now()
+	if v := c.Query("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			response.ErrorCode(c, http.StatusBadRequest, "from 时间格式错误，应为 RFC3339")
+			return
+		}
+	}
+	to := from.AddDate(0, 0, 14)
+	if v := c.Query("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			response.ErrorCode(c, http.StatusBadRequest, "to 时间格式错误，应为 RFC3339")
+			return
+		}
+	}
+	shifts, err := s.oncallSvc.Shifts(c.Request.Context(), schedule, from, to)
+	if err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	response.Success(c, shifts)
+}
+
+// --- OnCall Overrides ---
+
+func (s *HTTPServer) listOnCallOverrides(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	// 默认只返回尚未结束的替换
+	list, err := s.scheduleRepo.ListOverrides(c.Request.Context(), uint(id), time.Now(), time.Time{})
+	if err != nil {
+		response.ErrorCode(c, http.StatusInternalServerError, "查询失败")
+		return
+	}
+	response.Success(c, list)
+}
+
+func (s *HTTPServer) createOnCallOverride(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	if _, err := s.scheduleRepo.GetByID(c.Request.Context(), uint(id)); err != nil {
+		response.ErrorCode(c, http.StatusNotFound, "值班表不存在")
+		return
+	}
+	var req biz.AlertOnCallOverride
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.UserID == 0 {
+		response.ErrorCode(c, http.StatusBadRequest, "请选择代班人员")
+		return
+	}
+	if !req.EndAt.After(req.StartAt) {
+		response.ErrorCode(c, http.StatusBadRequest, "结束时间必须晚于开始时间")
+		return
+	}
+	req.ID = 0
+	req.ScheduleID = uint(id)
+	req.CreatedBy = rbacService.GetUserID(c)
+	if err := s.scheduleRepo.CreateOverride(c.Request.Context(), &req); err != nil {
+		response.ErrorCode(c, http.StatusInternalServerError, "创建失败")
+		return
+	}
+	response.Success(c, req)
+}
+
+func (s *HTTPServer) deleteOnCallOverride(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err := s.scheduleRepo.DeleteOverride(c.Request.Context(), uint(id)); err != nil {
+		response.ErrorCode(c, http.StatusInternalServerError, "删除失败")
+		return
+	}
+	response.Success(c, nil)
+}
+
+// --- Escalation Policies ---
+
+func (s *HTTPServer) listEscalationPolicies(c *gin.Context) {
+	list, err := s.escalationRepo.List(c.Request.Context())
+	if err != nil {
+		response.ErrorCode(c, http.StatusInternalServerError, "查询失败")
+		return
+	}
+	response.Success(c, list)
+}
+
+func (s *HTTPServer) createEscalationPolicy(c *gin.Context) {
+	var req biz.AlertEscalationPolicy
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := s.validateEscalationPolicy(c, &req); err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	req.ID = 0
+	if err := s.escalationRepo.Create(c.Request.Context(), &req); err != nil {
+		response.ErrorCode(c, http.StatusInternalServerError, "创建失败")
+		return
+	}
+	response.Success(c, req)
+}
+
+func (s *HTTPServer) getEscalationPolicy(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	policy, err := s.escalationRepo.GetByID(c.Request.Context(), uint(id))
+	if err != nil {
+		response.ErrorCode(c, http.StatusNotFound, "升级策略不存在")
+		return
+	}
+	response.Success(c, policy)
+}
+
+func (s *HTTPServer) updateEscalationPolicy(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	existing, err := s.escalationRepo.GetByID(c.Request.Context(), uint(id))
+	if err != nil {
+		response.ErrorCode(c, http.StatusNotFound, "升级策略不存在")
+		return
+	}
+	var req biz.AlertEscalationPolicy
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := s.validateEscalationPolicy(c, &req); err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	req.ID = existing.ID
+	req.CreatedAt = existing.CreatedAt
+	if err := s.escalationRepo.Update(c.Request.Context(), &req); err != nil {
+		response.ErrorCode(c, http.StatusInternalServerError, "更新失败")
+		return
+	}
+	response.Success(c, req)
+}
+
+func (s *HTTPServer) deleteEscalationPolicy(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	var count int64
+	s.db.Model(&biz.AlertSubscription{}).Where("escalation_policy_id = ?", id).Count(&count)
+	if count > 0 {
+		response.ErrorCode(c, http.StatusBadRequest, "该升级策略仍被订阅使用，无法删除")
+		return
+	}
+	if err := s.escalationRepo.Delete(c.Request.Context(), uint(id)); err != nil {
+		response.ErrorCode(c, http.StatusInternalServerError, "删除失败")
+		return
+	}
+	response.Success(c, nil)
+}
+
+// validateOnCallSchedule 校验值班表配置
+func validateOnCallSchedule(req *biz.AlertOnCallSchedule) error {
+	if req.Name == "" {
+		return fmt.Errorf("值班表名称不能为空")
+	}
+	if req.Timezone != "" {
+		if _, err := time.LoadLocation(req.Timezone); err != nil {
+			return fmt.Errorf("时区无效: %s", req.Timezone)
+		}
+	}
+	layers, err := alertsvc.ParseOnCallLayers(req.Layers)
+	if err != nil {
+		return err
+	}
+	if len(layers) == 0 {
+		return fmt.Errorf("至少需要配置一个值班层")
+	}
+	return nil
+}
+
+// validateEscalationPolicy 校验升级策略，步骤引用的值班表和通道必须存在
+func (s *HTTPServer) validateEscalationPolicy(c *gin.Context, req *biz.AlertEscalationPolicy) error {
+	if req.Name == "" {
+		return fmt.Errorf("升级策略名称不能为空")
+	}
+	steps, err := alertsvc.ParseEscalationSteps(req.Steps)
+	if err != nil {
+		return err
+	}
+	if len(steps) == 0 {
+		return fmt.Errorf("至少需要配置一个升级步骤")
+	}
+	for _, st := range steps {
+		if st.ScheduleID > 0 {
+			if _, err := s.scheduleRepo.GetByID(c.Request.Context(), st.ScheduleID); err != nil {
+				return fmt.Errorf("值班表 ID %d 无效", st.ScheduleID)
+			}
+		}
+		if len(st.ChannelIDs) > 0 {
+			channels, err := s.channelRepo.ListByIDs(c.Request.Context(), st.ChannelIDs)
+			if err != nil || len(channels) != len(st.ChannelIDs) {
+				return fmt.Errorf("部分通道 ID 无效")
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	biz "github.com/ydcloud-dy/opshub/internal/biz/alert"
+	alertsvc "github.com/ydcloud-dy/opshub/internal/service/alert"
+	"github.com/ydcloud-dy/opshub/pkg/logger"
+	"github.com/ydcloud-dy/opshub/pkg/response"
+)
+
+// maxRuleFileSize Prometheus 规则文件大小上限
+const maxRuleFileSize = 10 << 20
+
+// previewPromRules 预览 Prometheus 规则文件导入结果（按规则名检查冲突，不写库）
+func (s *HTTPServer) previewPromRules(c *gin.Context) {
+	group, items, ok := s.parsePromRuleUpload(c)
+	if !ok {
+		return
+	}
+	if err := s.markPromRuleConflicts(c.Request.Context(), group.ID, items); err != nil {
+		response.ErrorCode(c, http.StatusInternalServerError, "查询已有规则失败")
+		return
+	}
+	response.Success(c, gin.H{"ruleGroup": group, "items": items, "summary": summarizePromImport(items)})
+}
+
+// importPromRules 导入 Prometheus 规则文件到规则分类
+// onConflict=skip（默认）跳过同名规则，overwrite 覆盖本分类中的同名规则；与其他分类中的规则同名时始终跳过
+// 覆盖时只更新文件中定义的字段，启用状态和数据源仅在显式传入 enabled / dataSourceIds 时修改
+func (s *HTTPServer) importPromRules(c *gin.Context) {
+	group, items, ok := s.parsePromRuleUpload(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	if err := s.markPromRuleConflicts(ctx, group.ID, items); err != nil {
+		response.ErrorCode(c, http.StatusInternalServerError, "查询已有规则失败")
+		return
+	}
+
+	onConflict := c.DefaultQuery("onConflict", "skip")
+	if onConflict != "skip" && onConflict != "overwrite" {
+		response.ErrorCode(c, http.StatusBadRequest, "onConflict 仅支持 skip / overwrite")
+		return
+	}
+	enabledParam, setEnabled := c.GetQuery("enabled")
+	enabled := enabledParam == "true"
+	dsIDs, err := parseDataSourceIDsParam(c.Query("dataSourceIds"))
+	if err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	dsIDsJSON := ""
+	if len(dsIDs) > 0 {
+		b, _ := json.Marshal(dsIDs)
+		dsIDsJSON = string(b)
+	}
+
+	created, updated, skipped := 0, 0, 0
+	var failed []string
+	for _, item := range items {
+		if item.Rule == nil || item.Action == alertsvc.PromImportDuplicate || item.Action == alertsvc.PromImportInvalid ||
+			item.Action == alertsvc.PromImportCrossGroup {
+			skipped++
+			continue
+		}
+		rule := item.Rule
+		if item.Action == alertsvc.PromImportConflict {
+			if onConflict == "skip" {
+				skipped++
+				continue
+			}
+			existing, err := s.ruleRepo.GetByID(ctx, item.ExistingRuleID)
+			if err != nil {
+				failed = append(failed, fmt.Sprintf("%s: 查询已有规则失败 - %v", rule.Name, err))
+				continue
+			}
+			rule = item.MergeInto(existing)
+			if setEnabled {
+				rule.Enabled = enabled
+			}
+			if len(dsIDs) > 0 {
+				rule.DataSourceID = dsIDs[0]
+				rule.DataSourceIDs = dsIDsJSON
+			}
+			if err := s.ruleRepo.Update(ctx, rule); err != nil {
+				failed = append(failed, fmt.Sprintf("%s: 更新失败 - %v", rule.Name, err))
+				continue
+			}
+			updated++
+			continue
+		}
+
+		rule.AssetGroupID = group.AssetGroupID
+		rule.RuleGroupID = group.ID
+		rule.Enabled = enabled
+		if len(dsIDs) > 0 {
+			rule.DataSourceID = dsIDs[0]
+			rule.DataSourceIDs = dsIDsJSON
+		}
+		if err := s.ruleRepo.Create(ctx, rule); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: 创建失败 - %v", rule.Name, err))
+			continue
+		}
+		created++
+	}
+
+	if s.evalEngine != nil && (created > 0 || updated > 0) {
+		if err := s.evalEngine.GetRuleCache().PublishReloadEvent(ctx); err != nil {
+			logger.Error("发布规则重载事件失败", zap.Error(err))
+			c.Error(err)
+		}
+	}
+
+	logger.Info("导入 Prometheus 规则完成", zap.Uint("ruleGroupID", group.ID), zap.Int("新增", created), zap.Int("更新", updated), zap.Int("跳过", skipped), zap.Int("失败", len(failed)))
+
+	result := gin.H{"imported": created, "updated": updated, "skipped": skipped, "total": len(items)}
+	if len(failed) > 0 {
+		result["failed"] = failed
+	}
+	response.Success(c, result)
+}
+
+// exportPromRules 导出规则分类为 Prometheus 规则文件
+func (s *HTTPServer) exportPromRules(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	group, err := s.ruleGroupRepo.GetByID(c.Request.Context(), uint(id))
+	if err != nil {
+		response.ErrorCode(c, http.StatusNotFound, "规则分类不存在")
+		return
+	}
+	rules, _, err := s.ruleRepo.List(c.Request.Context(), 1, 10000, 0, group.ID, "", nil)
+	if err != nil {
+		response.ErrorCode(c, http.StatusInternalServerError, "查询失败")
+		return
+	}
+	data, err := alertsvc.AlertRulesToPromRuleFile(group.Name, rules)
+	if err != nil {
+		response.ErrorCode(c, http.StatusInternalServerError, "导出失败: "+err.Error())
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=rule_group_%d.rules.yaml", group.ID))
+	c.Data(http.StatusOK, "application/x-yaml", data)
+}
+
+// parsePromRuleUpload 读取上传的规则文件（multipart file 字段或原始请求体）并解析
+func (s *HTTPServer) parsePromRuleUpload(c *gin.Context) (*biz.AlertRuleGroup, []*alertsvc.PromImportItem, bool) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	group, err := s.ruleGroupRepo.GetByID(c.Request.Context(), uint(id))
+	if err != nil {
+		response.ErrorCode(c, http.StatusNotFound, "规则分类不存在")
+		return nil, nil, false
+	}
+
+	var reader io.Reader = c.Request.Body
+	if file, err := c.FormFile("file"); err == nil {
+		f, err := file.Open()
+		if err != nil {
+			response.ErrorCode(c, http.StatusInternalServerError, "文件读取失败")
+			return nil, nil, false
+		}
+		defer f.Close()
+		reader = f
+	}
+	data, err := io.ReadAll(io.LimitReader(reader, maxRuleFileSize))
+	if err != nil || len(data) == 0 {
+		response.ErrorCode(c, http.StatusBadRequest, "请上传规则文件")
+		return nil, nil, false
+	}
+
+	items, err := alertsvc.ParsePromRuleFile(data)
+	if err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, err.Error())
+		return nil, nil, false
+	}
+	return group, items, true
+}
+
+// markPromRuleConflicts 标记与已有规则同名的导入项：本分类中的同名规则可覆盖，其他分类中的只报告
+func (s *HTTPServer) markPromRuleConflicts(ctx context.Context, groupID uint, items []*alertsvc.PromImportItem) error {
+	rules, _, err := s.ruleRepo.List(ctx, 1, 10000, 0, groupID, "", nil)
+	if err != nil {
+		return err
+	}
+	inGroup := make(map[string]*biz.AlertRule, len(rules))
+	for _, rule := range rules {
+		if _, ok := inGroup[rule.Name]; !ok {
+			inGroup[rule.Name] = rule
+		}
+	}
+	for _, item := range items {
+		if item.Action != alertsvc.PromImportCreate {
+			continue
+		}
+		if existing, ok := inGroup[item.Name]; ok {
+			item.Action = alertsvc.PromImportConflict
+			item.ExistingRuleID = existing.ID
+			item.ExistingRuleGroupID = existing.RuleGroupID
+			continue
+		}
+		existing, err := s.ruleRepo.GetByName(ctx, item.Name)
+		if err == nil && existing != nil && existing.ID > 0 {
+			item.Action = alertsvc.PromImportCrossGroup
+			item.ExistingRuleID = existing.ID
+			item.ExistingRuleGroupID = existing.RuleGroupID
+		}
+	}
+	return nil
+}
+
+// summarizePromImport 统计各导入动作数量
+func summarizePromImport(items []*alertsvc.PromImportItem) map[string]int {
+	summary := map[string]int{
+		alertsvc.PromImportCreate:     0,
+		alertsvc.PromImportConflict:   0,
+		alertsvc.PromImportCrossGroup: 0,
+		alertsvc.PromImportDuplicate:  0,
+		alertsvc.PromImportInvalid:    0,
+	}
+	for _, item := range items {
+		summary[item.Action]++
+	}
+	return summary
+}
+
+// parseDataSourceIDsParam 解析数据源 ID 参数，支持 "[1,2]" 和 "1,2"
+func parseDataSourceIDsParam(v string) ([]uint, error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return nil, nil
+	}
+	var ids []uint
+	if strings.HasPrefix(v, "[") {
+		if err := json.Unmarshal([]byte(v), &ids); err != nil {
+			return nil, fmt.Errorf("dataSourceIds 格式错误")
+		}
+		return ids, nil
+	}
+	for _, part := range strings.Split(v, ",") {
+		id, err := strconv.ParseUint(strings.TrimSpace(part), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("dataSourceIds 格式错误")
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, nil
+}
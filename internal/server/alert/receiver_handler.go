@@ -0,0 +1,162 @@
+package alert
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	biz "github.com/ydcloud-dy/opshub/internal/biz/alert"
+	alertsvc "github.com/ydcloud-dy/opshub/internal/service/alert"
+	"github.com/ydcloud-dy/opshub/pkg/logger"
+	"github.com/ydcloud-dy/opshub/pkg/response"
+)
+
+// maxReceiverBodySize 外部告警请求体大小上限
+const maxReceiverBodySize = 5 << 20
+
+// --- Receivers ---
+
+func (s *HTTPServer) listReceivers(c *gin.Context) {
+	list, err := s.receiverRepo.List(c.Request.Context())
+	if err != nil {
+		response.ErrorCode(c, http.StatusInternalServerError, "查询失败")
+		return
+	}
+	response.Success(c, list)
+}
+
+func (s *HTTPServer) createReceiver(c *gin.Context) {
+	var req biz.AlertReceiver
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Type == "" {
+		req.Type = biz.ReceiverTypeAlertmanager
+	}
+	if req.Type != biz.ReceiverTypeAlertmanager && req.Type != biz.ReceiverTypeGeneric {
+		response.ErrorCode(c, http.StatusBadRequest, "不支持的接收器类型: "+req.Type)
+		return
+	}
+	req.ID = 0
+	req.Token = uuid.New().String()
+	if err := s.receiverRepo.Create(c.Request.Context(), &req); err != nil {
+		response.ErrorCode(c, http.StatusInternalServerError, "创建失败")
+		return
+	}
+	response.Success(c, req)
+}
+
+func (s *HTTPServer) getReceiver(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	rc, err := s.receiverRepo.GetByID(c.Request.Context(), uint(id))
+	if err != nil {
+		response.ErrorCode(c, http.StatusNotFound, "接收器不存在")
+		return
+	}
+	response.Success(c, rc)
+}
+
+func (s *HTTPServer) updateReceiver(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	existing, err := s.receiverRepo.GetByID(c.Request.Context(), uint(id))
+	if err != nil {
+		response.ErrorCode(c, http.StatusNotFound, "接收器不存在")
+		return
+	}
+	var req biz.AlertReceiver
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Type != biz.ReceiverTypeAlertmanager && req.Type != biz.ReceiverTypeGeneric {
+		response.ErrorCode(c, http.StatusBadRequest, "不支持的接收器类型: "+req.Type)
+		return
+	}
+	// Token 和创建时间不允许通过编辑修改
+	req.ID = existing.ID
+	req.Token = existing.Token
+	req.CreatedAt = existing.CreatedAt
+	req.LastReceivedAt = existing.LastReceivedAt
+	if err := s.receiverRepo.Update(c.Request.Context(), &req); err != nil {
+		response.ErrorCode(c, http.StatusInternalServerError, "更新失败")
+		return
+	}
+	response.Success(c, req)
+}
+
+func (s *HTTPServer) deleteReceiver(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err := s.receiverRepo.Delete(c.Request.Context(), uint(id)); err != nil {
+		response.ErrorCode(c, http.StatusInternalServerError, "删除失败")
+		return
+	}
+	response.Success(c, nil)
+}
+
+// resetReceiverToken 重新生成接收器 Token（旧地址立即失效）
+func (s *HTTPServer) resetReceiverToken(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	rc, err := s.receiverRepo.GetByID(c.Request.Context(), uint(id))
+	if err != nil {
+		response.ErrorCode(c, http.StatusNotFound, "接收器不存在")
+		return
+	}
+	rc.Token = uuid.New().String()
+	if err := s.receiverRepo.Update(c.Request.Context(), rc); err != nil {
+		response.ErrorCode(c, http.StatusInternalServerError, "更新失败")
+		return
+	}
+	response.Success(c, rc)
+}
+
+// receiveAlerts 接收外部告警（公开路由，通过 Token 验证）
+func (s *HTTPServer) receiveAlerts(c *gin.Context) {
+	ctx := c.Request.Context()
+	rc, err := s.receiverRepo.GetByToken(ctx, c.Param("token"))
+	if err != nil {
+		response.ErrorCode(c, http.StatusNotFound, "接收器不存在")
+		return
+	}
+	if !rc.Enabled {
+		response.ErrorCode(c, http.StatusForbidden, "接收器已禁用")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxReceiverBodySize))
+	if err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, "读取请求体失败")
+		return
+	}
+
+	var alerts []alertsvc.ExternalAlert
+	switch rc.Type {
+	case biz.ReceiverTypeGeneric:
+		alerts, err = alertsvc.ParseGenericPayload(body, rc.FieldMapping)
+	default:
+		alerts, err = alertsvc.ParseAlertmanagerPayload(body)
+	}
+	if err != nil {
+		logger.Warn("解析外部告警失败", zap.String("receiver", rc.Name), zap.Error(err))
+		response.ErrorCode(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result := s.evalEngine.IngestExternalAlerts(ctx, rc, alerts)
+	if err := s.receiverRepo.TouchReceived(ctx, rc.ID, time.Now()); err != nil {
+		c.Error(err)
+	}
+	if result.Busy > 0 {
+		// 部分告警因并发锁未写入，返回 503 让 Alertmanager 等发送方重试（已写入的告警重试时按指纹幂等更新）
+		c.Header("Retry-After", "5")
+		response.ErrorCode(c, http.StatusServiceUnavailable, fmt.Sprintf("%d 条告警正在处理中，请稍后重试", result.Busy))
+		return
+	}
+	response.Success(c, result)
+}
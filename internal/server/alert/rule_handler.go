@@ -11,6 +11,7 @@ import (
 	"gopkg.in/yaml.v3"
 
 	biz "github.com/ydcloud-dy/opshub/internal/biz/alert"
+	alertsvc "github.com/ydcloud-dy/opshub/internal/service/alert"
 	"github.com/ydcloud-dy/opshub/pkg/logger"
 	"github.com/ydcloud-dy/opshub/pkg/response"
 )
@@ -115,6 +116,10 @@ func (s *HTTPServer) createRule(c *gin.Context) {
 	if req.EvalInterval <= 0 {
 		req.EvalInterval = 15
 	}
+	if err := alertsvc.ValidateKeepFiringFor(req.KeepFiringFor); err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err := s.ruleRepo.Create(c.Request.Context(), &req); err != nil {
 		response.ErrorCode(c, http.StatusInternalServerError, "创建失败")
 		return
@@ -156,6 +161,17 @@ func (s *HTTPServer) updateRule(c *gin.Context) {
 		response.ErrorCode(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	if v, ok := updates["keepFiringFor"]; ok && v != nil {
+		keepFor, isStr := v.(string)
+		if !isStr {
+			response.ErrorCode(c, http.StatusBadRequest, "keepFiringFor 必须为字符串，如 5m")
+			return
+		}
+		if err := alertsvc.ValidateKeepFiringFor(keepFor); err != nil {
+			response.ErrorCode(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
 
 	// 使用 GORM 的 Updates 方法只更新传递的字段
 	if err := s.ruleRepo.UpdateFields(c.Request.Context(), uint(id), updates); err != nil {
@@ -342,6 +358,10 @@ func (s *HTTPServer) importRules(c *gin.Context) {
 		if rules[i].EvalInterval <= 0 {
 			rules[i].EvalInterval = 15
 		}
+		if err := alertsvc.ValidateKeepFiringFor(rules[i].KeepFiringFor); err != nil {
+			failedRules = append(failedRules, fmt.Sprintf("%s: %v", rules[i].Name, err))
+			continue
+		}
 
 		// 检查是否存在同名规则
 		existingRule, err := s.ruleRepo.GetByName(c.Request.Context(), rules[i].Name)
@@ -0,0 +1,47 @@
+package alert
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	biz "github.com/ydcloud-dy/opshub/internal/biz/alert"
+	alertsvc "github.com/ydcloud-dy/opshub/internal/service/alert"
+	"github.com/ydcloud-dy/opshub/pkg/response"
+)
+
+// unitTestRule 规则单元测试：按测试文件中的合成序列回放评估，返回每个用例的通过情况
+// 指定 ruleId 时测试已保存的规则（忽略文件中的 rule），否则测试文件内定义的规则
+func (s *HTTPServer) unitTestRule(c *gin.Context) {
+	var req struct {
+		RuleID  uint   `json:"ruleId"`
+		Content string `json:"content" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, "请输入测试文件内容")
+		return
+	}
+	if len(req.Content) > maxRuleFileSize {
+		response.ErrorCode(c, http.StatusBadRequest, "测试文件过大")
+		return
+	}
+	f, err := alertsvc.ParseRuleTestFile([]byte(req.Content))
+	if err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	var rule *biz.AlertRule
+	if req.RuleID > 0 {
+		rule, err = s.ruleRepo.GetByID(c.Request.Context(), req.RuleID)
+		if err != nil {
+			response.ErrorCode(c, http.StatusNotFound, "规则不存在")
+			return
+		}
+	}
+	result, err := alertsvc.RunRuleTests(f, rule)
+	if err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	response.Success(c, result)
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	biz "github.com/ydcloud-dy/opshub/internal/biz/alert"
+	alertsvc "github.com/ydcloud-dy/opshub/internal/service/alert"
 	"github.com/ydcloud-dy/opshub/pkg/response"
 )
 
@@ -30,6 +31,10 @@ func (s *HTTPServer) createSilenceRule(c *gin.Context) {
 		response.ErrorCode(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	if _, err := alertsvc.ParseLabelMatchers(req.Labels); err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	// 计算屏蔽截止时间
 	if req.Type == "fixed" && req.Duration != "" {
@@ -81,6 +86,10 @@ func (s *HTTPServer) updateSilenceRule(c *gin.Context) {
 		response.ErrorCode(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	if _, err := alertsvc.ParseLabelMatchers(rule.Labels); err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	// 重新计算屏蔽截止时间
 	if rule.Type == "fixed" && rule.Duration != "" {
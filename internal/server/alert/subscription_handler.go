@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"regexp"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -215,6 +214,18 @@ func (s *HTTPServer) saveSubRelations(c *gin.Context, subID uint, rules []subscr
 func (s *HTTPServer) validateSubscriptionRequest(c *gin.Context, req *subscriptionRequest) error {
 	ctx := c.Request.Context()
 
+	// 校验值班表与升级策略
+	if req.ScheduleID > 0 {
+		if _, err := s.scheduleRepo.GetByID(ctx, req.ScheduleID); err != nil {
+			return fmt.Errorf("值班表 ID %d 无效", req.ScheduleID)
+		}
+	}
+	if req.EscalationPolicyID > 0 {
+		if _, err := s.escalationRepo.GetByID(ctx, req.EscalationPolicyID); err != nil {
+			return fmt.Errorf("升级策略 ID %d 无效", req.EscalationPolicyID)
+		}
+	}
+
 	// 如果订阅已禁用，跳过通道校验（允许用户关闭订阅）
 	// 但仍然校验数据源和标签匹配器的语法
 	if !req.Enabled {
@@ -232,23 +243,8 @@ func (s *HTTPServer) validateSubscriptionRequest(c *gin.Context, req *subscripti
 
 		// 校验标签匹配器语法
 		for _, rule := range req.Rules {
-			var matchers []map[string]string
-			if err := json.Unmarshal(rule.LabelMatchers, &matchers); err == nil {
-				for _, m := range matchers {
-					op, ok := m["op"]
-					if !ok {
-						return fmt.Errorf("标签匹配器缺少 op 字段")
-					}
-					if op != "=" && op != "!=" && op != "=~" && op != "!~" {
-						return fmt.Errorf("标签匹配器操作符无效: %s", op)
-					}
-					// 校验正则表达式语法
-					if (op == "=~" || op == "!~") && m["value"] != "" {
-						if _, err := regexp.Compile(m["value"]); err != nil {
-							return fmt.Errorf("标签匹配器正则表达式无效: %s", m["value"])
-						}
-					}
-				}
+			if _, err := alertsvc.ParseLabelMatchers(string(rule.LabelMatchers)); err != nil {
+				return err
 			}
 		}
 
@@ -303,23 +299,8 @@ func (s *HTTPServer) validateSubscriptionRequest(c *gin.Context, req *subscripti
 
 	// 4. 校验标签匹配器语法
 	for _, rule := range req.Rules {
-		var matchers []map[string]string
-		if err := json.Unmarshal(rule.LabelMatchers, &matchers); err == nil {
-			for _, m := range matchers {
-				op, ok := m["op"]
-				if !ok {
-					return fmt.Errorf("标签匹配器缺少 op 字段")
-				}
-				if op != "=" && op != "!=" && op != "=~" && op != "!~" {
-					return fmt.Errorf("标签匹配器操作符无效: %s", op)
-				}
-				// 校验正则表达式语法
-				if (op == "=~" || op == "!~") && m["value"] != "" {
-					if _, err := regexp.Compile(m["value"]); err != nil {
-						return fmt.Errorf("标签匹配器正则表达式无效: %s", m["value"])
-					}
-				}
-			}
+		if _, err := alertsvc.ParseLabelMatchers(string(rule.LabelMatchers)); err != nil {
+			return err
 		}
 	}
 
@@ -465,6 +446,21 @@ func (s *HTTPServer) getSubscriptionLogs(c *gin.Context) {
 		return
 	}
 
+	// 回填各通道投递状态
+	logIDs := make([]uint, 0, len(logs))
+	byID := make(map[uint]*biz.AlertSubscriptionLog, len(logs))
+	for _, l := range logs {
+		logIDs = append(logIDs, l.ID)
+		byID[l.ID] = l
+	}
+	if deliveries, err := s.deliveryRepo.ListBySubscriptionLogIDs(c.Request.Context(), logIDs); err == nil {
+		for _, d := range deliveries {
+			if l := byID[d.SubscriptionLogID]; l != nil {
+				l.Deliveries = append(l.Deliveries, d)
+			}
+		}
+	}
+
 	response.Pagination(c, total, page, pageSize, logs)
 }
 
@@ -28,6 +28,7 @@ import (
 	rbacdata "github.com/ydcloud-dy/opshub/internal/data/rbac"
 	assetService "github.com/ydcloud-dy/opshub/internal/service/asset"
 	rbacService "github.com/ydcloud-dy/opshub/internal/service/rbac"
+	sshclient "github.com/ydcloud-dy/opshub/pkg/ssh"
 	"gorm.io/gorm"
 )
 
@@ -89,6 +90,9 @@ func (s *HTTPServer) RegisterRoutes(r *gin.RouterGroup) {
 		groups.GET("/:id", s.assetGroupService.GetGroup)
 		groups.PUT("/:id", s.assetGroupService.UpdateGroup)
 		groups.DELETE("/:id", s.assetGroupService.DeleteGroup)
+		groups.GET("/:id/jump-chain", s.assetGroupService.GetGroupJumpChain)
+		// 分组跳板链作用于分组及子分组下的所有主机，仅管理员可修改
+		groups.PUT("/:id/jump-chain", s.authMiddleware.RequireAdmin(), s.assetGroupService.UpdateGroupJumpChain)
 	}
 
 	// 主机管理
@@ -100,6 +104,9 @@ func (s *HTTPServer) RegisterRoutes(r *gin.RouterGroup) {
 		hosts.POST("/import", s.hostService.ImportFromExcel)
 		hosts.POST("/batch-collect", s.hostService.BatchCollectHostInfo)
 		hosts.POST("/batch-delete", s.hostService.BatchDeleteHosts)
+		hosts.GET("/host-key-alerts", s.hostService.ListHostKeyAlerts)
+		hosts.POST("/host-key-alerts/:id/retrust", s.authMiddleware.RequireAdmin(), s.hostService.RetrustHostKey)
+		hosts.POST("/host-keys/trust-existing", s.authMiddleware.RequireAdmin(), s.hostService.TrustExistingHostKeys)
 
 		// 查看权限 - 查看主机详情
 		hosts.GET("/:id",
@@ -124,6 +131,15 @@ func (s *HTTPServer) RegisterRoutes(r *gin.RouterGroup) {
 			s.authMiddleware.RequireHostPermission(rbacbiz.PermissionCollect),
 			s.hostService.CollectHostInfo)
 		hosts.POST("/:id/test", s.hostService.TestHostConnection)
+		hosts.GET("/:id/host-key",
+			s.authMiddleware.RequireHostPermission(rbacbiz.PermissionView),
+			s.hostService.GetHostKey)
+		hosts.GET("/:id/jump-chain",
+			s.authMiddleware.RequireHostPermission(rbacbiz.PermissionView),
+			s.hostService.GetHostJumpChain)
+		hosts.PUT("/:id/jump-chain",
+			s.authMiddleware.RequireHostPermission(rbacbiz.PermissionEdit),
+			s.hostService.UpdateHostJumpChain)
 
 		// 文件管理权限 - 文件上传、下载、删除
 		hosts.GET("/:id/files",
@@ -151,6 +167,16 @@ func (s *HTTPServer) RegisterRoutes(r *gin.RouterGroup) {
 		credentials.DELETE("/:id", s.hostService.DeleteCredential)
 	}
 
+	// SSH CA：签发短期用户证书，CA公钥安装到主机，仅管理员可操作
+	sshCA := r.Group("/ssh-ca", s.authMiddleware.RequireAdmin())
+	{
+		sshCA.GET("", s.hostService.GetSSHCA)
+		sshCA.PUT("", s.hostService.UpdateSSHCA)
+		sshCA.POST("/generate", s.hostService.GenerateSSHCA)
+		sshCA.POST("/install-script", s.hostService.DownloadSSHCAInstallScript)
+		sshCA.POST("/install", s.hostService.InstallSSHCA)
+	}
+
 	// 云平台账号管理
 	cloudAccounts := r.Group("/cloud-accounts")
 	{
@@ -163,6 +189,7 @@ func (s *HTTPServer) RegisterRoutes(r *gin.RouterGroup) {
 		cloudAccounts.PUT("/:id", s.hostService.UpdateCloudAccount)
 		cloudAccounts.DELETE("/:id", s.hostService.DeleteCloudAccount)
 		cloudAccounts.POST("/import", s.hostService.ImportFromCloud)
+		cloudAccounts.POST("/:id/sync", s.hostService.SyncCloudAccount)
 	}
 
 	// SSH终端 - 终端权限
@@ -485,6 +512,9 @@ func NewAssetServices(db *gorm.DB, rdb *redis.Client) (
 	serviceLabelRepo := assetdata.NewServiceLabelRepo(db)
 	websiteRepo := assetdata.NewWebsiteRepo(db)
 	aiModelProxyRepo := assetdata.NewAIModelProxyRepo(db)
+	hostKeyRepo := assetdata.NewHostKeyRepo(db)
+	jumpHopRepo := assetdata.NewJumpHopRepo(db)
+	sshCARepo := assetdata.NewSSHCARepo(db)
 
 	// 初始化UseCase
 	assetGroupUseCase := assetbiz.NewAssetGroupUseCase(assetGroupRepo)
@@ -497,6 +527,15 @@ func NewAssetServices(db *gorm.DB, rdb *redis.Client) (
 	serviceLabelUseCase := assetbiz.NewServiceLabelUseCase(serviceLabelRepo)
 	websiteUseCase := assetbiz.NewWebsiteUseCase(websiteRepo, assetGroupRepo, hostRepo)
 	aiModelProxyUseCase := assetbiz.NewAIModelProxyUseCase(aiModelProxyRepo, assetGroupRepo, hostRepo)
+	hostKeyUseCase := assetbiz.NewHostKeyUseCase(hostKeyRepo)
+	jumpChainUseCase := assetbiz.NewJumpChainUseCase(jumpHopRepo, hostRepo, assetGroupRepo, credentialRepo)
+	hostUseCase.SetJumpChainUseCase(jumpChainUseCase)
+	sshCAUseCase := assetbiz.NewSSHCAUseCase(sshCARepo, hostUseCase)
+	sshCAUseCase.SetHostPermissionChecker(assetPermissionUseCase)
+	// CA签发的凭证在所有SSH连接处使用同一个CA
+	assetbiz.SetSSHCA(sshCAUseCase)
+	// 所有SSH连接共用同一份已知主机密钥
+	sshclient.SetKnownHosts(hostKeyUseCase)
 
 	// 初始化访问管理器（如果 Redis 可用）
 	var accessManager *assetbiz.WebsiteAccessManager
@@ -508,6 +547,10 @@ func NewAssetServices(db *gorm.DB, rdb *redis.Client) (
 	// 初始化Service
 	assetGroupService := assetService.NewAssetGroupService(assetGroupUseCase)
 	hostService := assetService.NewHostService(hostUseCase, credentialUseCase, cloudAccountUseCase, assetPermissionUseCase)
+	hostService.SetHostKeyUseCase(hostKeyUseCase)
+	hostService.SetJumpChainUseCase(jumpChainUseCase)
+	hostService.SetSSHCAUseCase(sshCAUseCase)
+	assetGroupService.SetJumpChainUseCase(jumpChainUseCase)
 	middlewareService := assetService.NewMiddlewareService(middlewareUseCase, mwPermissionUseCase, db)
 	mwPermissionService := rbacService.NewMiddlewarePermissionService(mwPermissionUseCase)
 	serviceLabelService := assetService.NewServiceLabelService(serviceLabelUseCase)
@@ -34,6 +34,7 @@ import (
 	"golang.org/x/crypto/ssh"
 	assetbiz "github.com/ydcloud-dy/opshub/internal/biz/asset"
 	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
+	sshclient "github.com/ydcloud-dy/opshub/pkg/ssh"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
@@ -72,7 +73,7 @@ type TerminalSession struct {
 	HostIP      string
 	UserID      uint
 	Username    string
-	SSHClient   *ssh.Client
+	SSHClient   *sshclient.Client
 	SSHSession  *ssh.Session
 	StdinPipe   io.WriteCloser
 	StdoutPipe  io.Reader
@@ -106,51 +107,11 @@ func (tm *TerminalManager) CreateSession(ctx context.Context, hostID uint, userI
 		return nil, fmt.Errorf("获取主机信息失败: %w", err)
 	}
 
-	// 获取凭证（需要解密后的凭证）
-	var credential *assetbiz.Credential
-	if hostVO.CredentialID > 0 {
-		credentialRepo := tm.hostUseCase.GetCredentialRepo()
-		credential, err = credentialRepo.GetByIDDecrypted(ctx, hostVO.CredentialID)
-		if err != nil {
-			return nil, fmt.Errorf("获取凭证信息失败: %w", err)
-		}
-	} else {
-		return nil, fmt.Errorf("主机未配置凭证")
-	}
-
-	// 解析私钥
-	var signer ssh.Signer
-	var authMethod ssh.AuthMethod
-
-	if credential.Type == "key" {
-		// 使用私钥认证
-		if credential.Password != "" {
-			signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(credential.PrivateKey), []byte(credential.Password))
-		} else {
-			signer, err = ssh.ParsePrivateKey([]byte(credential.PrivateKey))
-		}
-		if err != nil {
-			return nil, fmt.Errorf("解析私钥失败: %w", err)
-		}
-		authMethod = ssh.PublicKeys(signer)
-	} else {
-		// 使用密码认证
-		authMethod = ssh.Password(credential.Password)
-	}
-
-	// SSH配置
-	config := &ssh.ClientConfig{
-		User:            hostVO.SSHUser,
-		Auth:            []ssh.AuthMethod{authMethod},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-		Timeout:         10 * time.Second,
-	}
-
-	// 连接SSH
-	address := fmt.Sprintf("%s:%d", hostVO.IP, hostVO.Port)
-	client, err := ssh.Dial("tcp", address, config)
+	// 按主机凭证和跳板链建立SSH连接，CA签发的凭证按当前用户签发证书
+	ctx = assetbiz.WithSSHIdentity(ctx, assetbiz.SSHIdentity{UserID: userID, Username: username, Source: "terminal"})
+	client, err := tm.hostUseCase.ConnectHost(ctx, hostID)
 	if err != nil {
-		return nil, fmt.Errorf("SSH连接失败: %w", err)
+		return nil, err
 	}
 
 	// 创建会话
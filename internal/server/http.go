@@ -21,11 +21,14 @@ package server
 
 import (
 	"context"
+	"crypto/subtle"
 	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/redis/go-redis/v9"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -40,6 +43,7 @@ import (
 	auditserver "github.com/ydcloud-dy/opshub/internal/server/audit"
 	identityserver "github.com/ydcloud-dy/opshub/internal/server/identity"
 	alertserver "github.com/ydcloud-dy/opshub/internal/server/alert"
+	alertsvc "github.com/ydcloud-dy/opshub/internal/service/alert"
 	inspectionserver "github.com/ydcloud-dy/opshub/internal/server/inspection"
 	"github.com/ydcloud-dy/opshub/internal/server/rbac"
 	systemserver "github.com/ydcloud-dy/opshub/internal/server/system"
@@ -81,6 +85,11 @@ func NewHTTPServer(conf *conf.Config, svc *service.Service, db *gorm.DB, redisCl
 
 	// 创建路由
 	router := gin.New()
+	// 客户端地址用于审计和端口转发来源校验，只采信可信代理传递的 X-Forwarded-For，避免伪造
+	if err := router.SetTrustedProxies(conf.Server.TrustedProxyList()); err != nil {
+		appLogger.Error("可信代理配置无效，不采信任何代理头", zap.Strings("trustedProxies", conf.Server.TrustedProxies), zap.Error(err))
+		router.SetTrustedProxies(nil)
+	}
 
 	// 使用中间件
 	router.Use(middleware.Logger())
@@ -206,6 +215,9 @@ func (s *HTTPServer) registerRoutes(router *gin.Engine, jwtSecret string) {
 	// 健康检查
 	router.GET("/health", s.svc.Health)
 
+	// Prometheus 指标（含 Agent 心跳上报的主机指标）
+	router.GET("/metrics", s.metricsHandler())
+
 	// 静态文件服务 - 上传的文件
 	router.Static("/uploads", "./web/public/uploads")
 
@@ -363,6 +375,7 @@ func (s *HTTPServer) registerRoutes(router *gin.Engine, jwtSecret string) {
 
 		// 注册 Alert（告警管理）路由
 		s.alertServer = alertserver.NewAlertServices(s.db, s.redisClient)
+		alertsvc.ConfigureEventActionLinks(s.conf.Server.ExternalURL, s.conf.Server.JWTSecret)
 		if s.grpcServer != nil {
 			s.alertServer.SetAgentHub(s.grpcServer.Hub())
 		}
@@ -398,6 +411,34 @@ func (s *HTTPServer) registerRoutes(router *gin.Engine, jwtSecret string) {
 	// })
 }
 
+// metricsHandler 导出 Prometheus 指标，要求 Bearer 认证；未配置 metrics_token 时拒绝访问，
+// 避免主机清单和资源指标被匿名抓取
+func (s *HTTPServer) metricsHandler() gin.HandlerFunc {
+	token := s.conf.Server.MetricsToken
+	if token == "" {
+		appLogger.Warn("未配置 server.metrics_token，/metrics 已禁用")
+		return func(c *gin.Context) {
+			c.AbortWithStatus(http.StatusForbidden)
+		}
+	}
+	if s.cacheManager != nil {
+		collector := agentserver.NewHostMetricsCollector(s.db, s.cacheManager.GetAgentCache())
+		if err := prometheus.Register(collector); err != nil {
+			appLogger.Warn("注册主机指标导出器失败", zap.Error(err))
+		}
+	}
+	// 单台主机数据异常时仍返回其余指标
+	handler := promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError})
+	return func(c *gin.Context) {
+		auth := c.GetHeader("Authorization")
+		if subtle.ConstantTimeCompare([]byte(auth), []byte("Bearer "+token)) != 1 {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(c.Writer, c.Request)
+	}
+}
+
 // enablePlugins 启用所有已注册的插件
 func (s *HTTPServer) enablePlugins() {
 	for _, p := range s.pluginMgr.GetAllPlugins() {
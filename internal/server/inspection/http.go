@@ -268,6 +268,16 @@ func (p *taskProvider) GetEnabledTasks(ctx context.Context) ([]scheduler.Task, e
 		Enabled:  true,
 	})
 
+	// 内置系统任务：云主机清单同步（每30分钟）
+	result = append(result, scheduler.Task{
+		ID:       999998,
+		Name:     "cloud_inventory_sync",
+		Type:     "cloud_inventory_sync",
+		CronExpr: "0 0/30 * * * ?",
+		Payload:  "{}",
+		Enabled:  true,
+	})
+
 	return result, nil
 }
 
@@ -342,8 +352,13 @@ func NewInspectionServices(db *gorm.DB, redisClient *redis.Client, hostRepo asse
 
 	// Host health check executor
 	healthExecutor := assetbiz.NewHostHealthExecutor(hostRepo, credentialRepo)
+	healthExecutor.SetJumpChainUseCase(assetbiz.NewJumpChainUseCase(assetdata.NewJumpHopRepo(db), hostRepo, assetdata.NewAssetGroupRepo(db), credentialRepo))
 	sched.RegisterExecutor(healthExecutor)
 
+	// Cloud inventory sync executor
+	cloudUseCase := assetbiz.NewCloudAccountUseCase(assetdata.NewCloudAccountRepo(db))
+	sched.RegisterExecutor(assetbiz.NewCloudSyncExecutor(cloudUseCase, hostRepo))
+
 	// 初始化巡检管理服务
 	sysConfigUseCase, ok := configUseCase.(*systembiz.ConfigUseCase)
 	if !ok {
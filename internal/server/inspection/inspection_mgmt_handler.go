@@ -10,7 +10,9 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	assetbiz "github.com/ydcloud-dy/opshub/internal/biz/asset"
 	"github.com/ydcloud-dy/opshub/internal/service/inspection_mgmt"
+	rbacService "github.com/ydcloud-dy/opshub/internal/service/rbac"
 	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
 	"github.com/ydcloud-dy/opshub/pkg/response"
 	"github.com/ydcloud-dy/opshub/pkg/scheduler"
@@ -488,7 +490,7 @@ func (s *HTTPServer) testRunInspectionItems(c *gin.Context) {
 		return
 	}
 
-	results, err := s.inspectionItemService.TestRun(c.Request.Context(), &req)
+	results, err := s.inspectionItemService.TestRun(inspectionSSHContext(c, c.Request.Context()), &req)
 	if err != nil {
 		response.ErrorCode(c, http.StatusInternalServerError, err.Error())
 		return
@@ -510,7 +512,7 @@ func (s *HTTPServer) testRunInspectionItemsWithoutSave(c *gin.Context) {
 		return
 	}
 
-	results, err := s.inspectionItemService.TestRunWithoutSave(c.Request.Context(), req.GroupID, req.Items)
+	results, err := s.inspectionItemService.TestRunWithoutSave(inspectionSSHContext(c, c.Request.Context()), req.GroupID, req.Items)
 	if err != nil {
 		response.ErrorCode(c, http.StatusInternalServerError, err.Error())
 		return
@@ -522,6 +524,16 @@ func (s *HTTPServer) testRunInspectionItemsWithoutSave(c *gin.Context) {
 	})
 }
 
+// inspectionSSHContext 记录发起巡检的当前用户，CA签发的SSH证书按该用户及其角色授权，
+// 主机登录日志中可追溯到执行人
+func inspectionSSHContext(c *gin.Context, ctx context.Context) context.Context {
+	return assetbiz.WithSSHIdentity(ctx, assetbiz.SSHIdentity{
+		UserID:   rbacService.GetUserID(c),
+		Username: rbacService.GetUsername(c),
+		Source:   "inspection",
+	})
+}
+
 // ==================== 巡检执行记录 Handlers ====================
 
 func (s *HTTPServer) listExecutionRecords(c *gin.Context) {
@@ -794,7 +806,7 @@ func (s *HTTPServer) runInspectionTask(c *gin.Context) {
 		response.ErrorCode(c, http.StatusConflict, "任务已在运行中，请先停止")
 		return
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 30*60*time.Second)
+	ctx, cancel := context.WithTimeout(inspectionSSHContext(c, context.Background()), 30*60*time.Second)
 	s.runningTasks[taskID] = cancel
 	s.runningTasksMu.Unlock()
 
@@ -878,7 +890,7 @@ func (s *HTTPServer) runInspectionTaskSync(c *gin.Context) {
 		response.ErrorCode(c, http.StatusConflict, "任务已在运行中，请先停止")
 		return
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 30*60*time.Second)
+	ctx, cancel := context.WithTimeout(inspectionSSHContext(c, context.Background()), 30*60*time.Second)
 	s.runningTasks[taskID] = cancel
 	s.runningTasksMu.Unlock()
 
@@ -8,6 +8,7 @@ import (
 	inspectionmgmtbiz "github.com/ydcloud-dy/opshub/internal/biz/inspection_mgmt"
 	systembiz "github.com/ydcloud-dy/opshub/internal/biz/system"
 	alertdata "github.com/ydcloud-dy/opshub/internal/data/alert"
+	assetdata "github.com/ydcloud-dy/opshub/internal/data/asset"
 	inspectiondata "github.com/ydcloud-dy/opshub/internal/data/inspection"
 	inspectionmgmtdata "github.com/ydcloud-dy/opshub/internal/data/inspection_mgmt"
 	"github.com/ydcloud-dy/opshub/internal/server/agent"
@@ -58,6 +59,7 @@ func InitInspectionMgmtServices(
 
 	// 初始化执行器
 	cmdExecutor := inspectionmgmtbiz.NewCommandExecutor(agentHub, credentialRepo)
+	cmdExecutor.SetJumpChainUseCase(assetbiz.NewJumpChainUseCase(assetdata.NewJumpHopRepo(db), hostRepo, assetGroupRepo, credentialRepo))
 
 	// 初始化拨测配置仓储和变量仓储
 	probeConfigRepo := inspectiondata.NewProbeConfigRepo(db)
@@ -0,0 +1,96 @@
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	biz "github.com/ydcloud-dy/opshub/internal/biz/alert"
+)
+
+// queryClickHouse 通过 HTTP 接口执行 ClickHouse SQL，每行对应一条时间序列：
+// 名为 value 的列（没有则取最后一列）为值，其余列作为标签，如
+//
+//	SELECT service, count() AS value FROM logs.errors WHERE ts > now() - INTERVAL 5 MINUTE GROUP BY service
+func queryClickHouse(ds *biz.AlertDataSource, expr string) ([]QueryResult, error) {
+	params := url.Values{}
+	params.Set("default_format", "JSON")
+	params.Set("output_format_json_quote_64bit_integers", "0")
+	// 告警查询只读，防止规则表达式中的 INSERT/ALTER/DROP 等语句改动数据
+	params.Set("readonly", "1")
+
+	body, err := doDataSourceRequest(ds, http.MethodPost, "/", params, strings.NewReader(expr), "text/plain; charset=utf-8", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return parseClickHouseResponse(body)
+}
+
+// parseClickHouseResponse 解析 FORMAT JSON 响应
+func parseClickHouseResponse(body []byte) ([]QueryResult, error) {
+	var resp struct {
+		Meta []struct {
+			Name string `json:"name"`
+			Type string `json:"type"`
+		} `json:"meta"`
+		Data []map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("clickhouse 响应需为 JSON 格式: %w", err)
+	}
+	if len(resp.Meta) == 0 {
+		return nil, fmt.Errorf("clickhouse query error: 查询结果没有列")
+	}
+
+	valueCol := resp.Meta[len(resp.Meta)-1].Name
+	for _, m := range resp.Meta {
+		if m.Name == "value" {
+			valueCol = m.Name
+			break
+		}
+	}
+
+	results := make([]QueryResult, 0, len(resp.Data))
+	for _, row := range resp.Data {
+		val, err := clickHouseFloat(row[valueCol])
+		if err != nil {
+			return nil, fmt.Errorf("clickhouse query error: 值列 %s 不是数值: %s", valueCol, row[valueCol])
+		}
+		labels := make(map[string]string, len(resp.Meta)-1)
+		for _, m := range resp.Meta {
+			if m.Name == valueCol {
+				continue
+			}
+			raw := row[m.Name]
+			if raw == nil || bytes.Equal(raw, []byte("null")) {
+				continue
+			}
+			var s string
+			if err := json.Unmarshal(raw, &s); err == nil {
+				labels[m.Name] = s
+			} else {
+				labels[m.Name] = string(raw)
+			}
+		}
+		results = append(results, QueryResult{Labels: labels, Value: val})
+	}
+	return results, nil
+}
+
+// clickHouseFloat 解析数值列（64 位整数、Decimal、nan/inf 可能以字符串返回）
+func clickHouseFloat(raw json.RawMessage) (float64, error) {
+	var f float64
+	if err := json.Unmarshal(raw, &f); err == nil {
+		return f, nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return 0, fmt.Errorf("invalid number")
+	}
+	return strconv.ParseFloat(s, 64)
+}
@@ -0,0 +1,203 @@
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	biz "github.com/ydcloud-dy/opshub/internal/biz/alert"
+)
+
+// queryElasticsearch 执行 Elasticsearch 聚合查询
+// expr 为 _search 请求体 JSON，额外的 index 字段指定索引（支持通配符和逗号分隔），时间范围需在 query 中自行限定，如：
+//
+//	{"index": "app-logs-*",
+//	 "query": {"bool": {"filter": [{"term": {"level": "error"}}, {"range": {"@timestamp": {"gte": "now-5m"}}}]}},
+//	 "aggs": {"service": {"terms": {"field": "service.keyword"}}}}
+//
+// 桶聚合的 key 作为标签（标签名为聚合名），叶子指标聚合的 value 作为值，桶内没有指标聚合时取 doc_count；
+// 没有 aggs 时返回命中总数
+func queryElasticsearch(ds *biz.AlertDataSource, expr string) ([]QueryResult, error) {
+	var search map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(expr), &search); err != nil {
+		return nil, fmt.Errorf("elasticsearch 查询需为 JSON（index + query/aggs）: %w", err)
+	}
+	var index string
+	_ = json.Unmarshal(search["index"], &index)
+	index = strings.TrimSpace(index)
+	if index == "" || strings.ContainsAny(index, "/?# ") {
+		return nil, fmt.Errorf("elasticsearch 查询缺少有效的 index")
+	}
+	delete(search, "index")
+	// 只需要聚合结果，不返回文档
+	if _, ok := search["size"]; !ok {
+		search["size"] = json.RawMessage("0")
+	}
+	if _, ok := search["track_total_hits"]; !ok {
+		search["track_total_hits"] = json.RawMessage("true")
+	}
+	payload, _ := json.Marshal(search)
+
+	body, err := doDataSourceRequest(ds, http.MethodPost, "/"+index+"/_search", nil, bytes.NewReader(payload), "application/json", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return parseElasticsearchResponse(body)
+}
+
+// parseElasticsearchResponse 将 _search 响应展开为时间序列
+func parseElasticsearchResponse(body []byte) ([]QueryResult, error) {
+	var resp struct {
+		Hits struct {
+			Total json.RawMessage `json:"total"`
+		} `json:"hits"`
+		Aggregations map[string]json.RawMessage `json:"aggregations"`
+		TimedOut     bool                       `json:"timed_out"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	if resp.TimedOut {
+		return nil, fmt.Errorf("elasticsearch query error: timed out")
+	}
+
+	// hits.total：7.x 起为 {"value": n}，6.x 为数字
+	var total float64
+	var totalObj struct {
+		Value float64 `json:"value"`
+	}
+	if err := json.Unmarshal(resp.Hits.Total, &totalObj); err == nil {
+		total = totalObj.Value
+	} else {
+		_ = json.Unmarshal(resp.Hits.Total, &total)
+	}
+
+	if len(resp.Aggregations) == 0 {
+		return []QueryResult{{Labels: map[string]string{}, Value: total}}, nil
+	}
+	var results []QueryResult
+	collectESAggs(resp.Aggregations, map[string]string{}, total, &results)
+	return results, nil
+}
+
+// collectESAggs 递归展开一层聚合结果；同一桶内有多个指标聚合时以 __name__ 区分
+func collectESAggs(aggs map[string]json.RawMessage, labels map[string]string, docCount float64, results *[]QueryResult) {
+	names := make([]string, 0, len(aggs))
+	for name := range aggs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	type metric struct {
+		name  string
+		value float64
+	}
+	var metrics []metric
+	nested := false
+	for _, name := range names {
+		// 桶中的 key、doc_count 等非对象字段直接跳过
+		var agg map[string]json.RawMessage
+		if err := json.Unmarshal(aggs[name], &agg); err != nil || agg == nil {
+			continue
+		}
+		if raw, ok := agg["buckets"]; ok {
+			nested = true
+			for _, b := range esBuckets(raw) {
+				var count float64
+				_ = json.Unmarshal(b.fields["doc_count"], &count)
+				sub := copyLabels(labels)
+				sub[name] = b.key
+				collectESAggs(b.fields, sub, count, results)
+			}
+			continue
+		}
+		if raw, ok := agg["value"]; ok {
+			// 无数据时指标聚合的 value 为 null
+			var v *float64
+			if err := json.Unmarshal(raw, &v); err == nil && v != nil {
+				metrics = append(metrics, metric{name: name, value: *v})
+			}
+			continue
+		}
+		if raw, ok := agg["doc_count"]; ok {
+			// 单桶聚合（filter、nested 等）
+			nested = true
+			var count float64
+			_ = json.Unmarshal(raw, &count)
+			collectESAggs(agg, copyLabels(labels), count, results)
+		}
+	}
+
+	switch {
+	case len(metrics) == 1:
+		*results = append(*results, QueryResult{Labels: labels, Value: metrics[0].value})
+	case len(metrics) > 1:
+		for _, m := range metrics {
+			l := copyLabels(labels)
+			l["__name__"] = m.name
+			*results = append(*results, QueryResult{Labels: l, Value: m.value})
+		}
+	case !nested:
+		*results = append(*results, QueryResult{Labels: labels, Value: docCount})
+	}
+}
+
+type esBucket struct {
+	key    string
+	fields map[string]json.RawMessage
+}
+
+// esBuckets 解析 buckets（数组或 keyed 对象两种形式）
+func esBuckets(raw json.RawMessage) []esBucket {
+	var list []map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &list); err == nil {
+		buckets := make([]esBucket, 0, len(list))
+		for _, fields := range list {
+			key := fields["key_as_string"]
+			if key == nil {
+				key = fields["key"]
+			}
+			buckets = append(buckets, esBucket{key: esKeyString(key), fields: fields})
+		}
+		return buckets
+	}
+	var keyed map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &keyed); err != nil {
+		return nil
+	}
+	keys := make([]string, 0, len(keyed))
+	for k := range keyed {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	buckets := make([]esBucket, 0, len(keys))
+	for _, k := range keys {
+		buckets = append(buckets, esBucket{key: k, fields: keyed[k]})
+	}
+	return buckets
+}
+
+func esKeyString(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var f float64
+	if err := json.Unmarshal(raw, &f); err == nil {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return string(raw)
+}
+
+func copyLabels(labels map[string]string) map[string]string {
+	out := make(map[string]string, len(labels)+1)
+	for k, v := range labels {
+		out[k] = v
+	}
+	return out
+}
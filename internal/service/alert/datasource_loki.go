@@ -0,0 +1,95 @@
+package alert
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	biz "github.com/ydcloud-dy/opshub/internal/biz/alert"
+)
+
+// queryLoki 执行 LogQL 指标查询，如 sum by (app) (count_over_time({level="error"}[5m]))
+// Loki 的 instant query 响应与 Prometheus 一致，标签即流标签
+func queryLoki(ds *biz.AlertDataSource, expr string) ([]QueryResult, error) {
+	params := url.Values{}
+	params.Set("query", expr)
+	params.Set("time", strconv.FormatInt(time.Now().UnixNano(), 10))
+
+	body, err := doDataSourceRequest(ds, http.MethodGet, "/loki/api/v1/query", params, nil, "", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return parseLokiResponse(body)
+}
+
+// queryLokiRange 执行 LogQL 指标 range query（用于启动时回溯 pending 状态）
+func queryLokiRange(ds *biz.AlertDataSource, expr string, start, end time.Time, step time.Duration) ([]RangeResult, error) {
+	if step <= 0 {
+		step = 15 * time.Second
+	}
+	params := url.Values{}
+	params.Set("query", expr)
+	params.Set("start", strconv.FormatInt(start.UnixNano(), 10))
+	params.Set("end", strconv.FormatInt(end.UnixNano(), 10))
+	params.Set("step", fmt.Sprintf("%ds", int(step.Seconds())))
+
+	body, err := doDataSourceRequest(ds, http.MethodGet, "/loki/api/v1/query_range", params, nil, "", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkLokiResultType(body, "matrix"); err != nil {
+		return nil, err
+	}
+	return parsePrometheusRangeResponse(body)
+}
+
+// parseLokiResponse 解析 Loki instant query 响应，日志流查询（streams）无法作为告警数值
+func parseLokiResponse(body []byte) ([]QueryResult, error) {
+	var resp struct {
+		Data struct {
+			ResultType string          `json:"resultType"`
+			Result     json.RawMessage `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Data.ResultType == "scalar" {
+		var pair []interface{}
+		if err := json.Unmarshal(resp.Data.Result, &pair); err != nil || len(pair) < 2 {
+			return nil, fmt.Errorf("loki query error: invalid scalar result")
+		}
+		valStr, _ := pair[1].(string)
+		val, err := strconv.ParseFloat(valStr, 64)
+		if err != nil {
+			return nil, fmt.Errorf("loki query error: invalid scalar value %q", valStr)
+		}
+		return []QueryResult{{Labels: map[string]string{}, Value: val}}, nil
+	}
+	if err := checkLokiResultType(body, "vector"); err != nil {
+		return nil, err
+	}
+	return parsePrometheusResponse(body)
+}
+
+func checkLokiResultType(body []byte, want string) error {
+	var resp struct {
+		Data struct {
+			ResultType string `json:"resultType"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return err
+	}
+	switch resp.Data.ResultType {
+	case want:
+		return nil
+	case "streams":
+		return fmt.Errorf("loki query error: LogQL 需为指标查询（如 count_over_time、rate），日志流查询无法用于告警")
+	default:
+		return fmt.Errorf("loki query error: unexpected result type %q", resp.Data.ResultType)
+	}
+}
@@ -20,6 +20,18 @@ type QueryResult struct {
 	Value  float64
 }
 
+// RangeSample range query 中的单个采样点
+type RangeSample struct {
+	Time  time.Time
+	Value float64
+}
+
+// RangeResult 数据源 range query 结果（单条时间序列）
+type RangeResult struct {
+	Labels  map[string]string
+	Samples []RangeSample
+}
+
 // QueryDataSource 根据数据源类型执行 instant query
 func QueryDataSource(ds *biz.AlertDataSource, expr string) ([]QueryResult, error) {
 	switch ds.Type {
@@ -27,23 +39,90 @@ func QueryDataSource(ds *biz.AlertDataSource, expr string) ([]QueryResult, error
 		return queryPrometheus(ds, expr)
 	case "influxdb":
 		return queryInfluxDB(ds, expr)
+	case "loki":
+		return queryLoki(ds, expr)
+	case "elasticsearch":
+		return queryElasticsearch(ds, expr)
+	case "clickhouse":
+		return queryClickHouse(ds, expr)
 	default:
 		return nil, fmt.Errorf("unsupported datasource type: %s", ds.Type)
 	}
 }
 
-func queryPrometheus(ds *biz.AlertDataSource, expr string) ([]QueryResult, error) {
-	// 根据接入方式构建 URL
-	var baseURL string
+// QueryDataSourceRange 根据数据源类型执行 range query（用于启动时回溯 pending 状态）
+func QueryDataSourceRange(ds *biz.AlertDataSource, expr string, start, end time.Time, step time.Duration) ([]RangeResult, error) {
+	switch ds.Type {
+	case "prometheus", "victoriametrics":
+		return queryPrometheusRange(ds, expr, start, end, step)
+	case "loki":
+		return queryLokiRange(ds, expr, start, end, step)
+	default:
+		return nil, fmt.Errorf("datasource type %s does not support range query", ds.Type)
+	}
+}
+
+// dataSourceBaseURL 根据接入方式构建数据源基础 URL
+func dataSourceBaseURL(ds *biz.AlertDataSource) string {
 	if ds.AccessMode == "agent" {
 		// Agent 代理模式：使用代理转发 URL
 		// 格式：http://localhost:9876/api/v1/alert/proxy/datasource/{token}
-		baseURL = "http://localhost:9876" + ds.ProxyURL
-	} else {
-		// 直连模式：直接使用数据源 URL
-		baseURL = strings.TrimRight(ds.URL, "/")
+		return "http://localhost:9876" + ds.ProxyURL
+	}
+	// 直连模式：直接使用数据源 URL
+	return strings.TrimRight(ds.URL, "/")
+}
+
+// maxDataSourceResponseSize 数据源响应体大小上限
+const maxDataSourceResponseSize = 32 << 20
+
+// doDataSourceRequest 向数据源发送 HTTP 请求（Agent 模式经代理转发），非 2xx 响应返回错误
+func doDataSourceRequest(ds *biz.AlertDataSource, method, path string, params url.Values, body io.Reader, contentType string, timeout time.Duration) ([]byte, error) {
+	reqURL := dataSourceBaseURL(ds) + path
+	if len(params) > 0 {
+		reqURL += "?" + params.Encode()
+	}
+	req, err := http.NewRequest(method, reqURL, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	// 直连模式才需要添加认证（Agent 模式由代理处理器添加）
+	if ds.AccessMode == "direct" {
+		if ds.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+ds.Token)
+		} else if ds.Username != "" {
+			req.SetBasicAuth(ds.Username, ds.Password)
+		}
 	}
 
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDataSourceResponseSize))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg := strings.TrimSpace(string(data))
+		if len(msg) > 300 {
+			msg = msg[:300]
+		}
+		return nil, fmt.Errorf("%s query error: HTTP %d %s", ds.Type, resp.StatusCode, msg)
+	}
+	return data, nil
+}
+
+func queryPrometheus(ds *biz.AlertDataSource, expr string) ([]QueryResult, error) {
+	baseURL := dataSourceBaseURL(ds)
+
 	params := url.Values{}
 	params.Set("query", expr)
 	params.Set("time", fmt.Sprintf("%d", time.Now().Unix()))
@@ -79,6 +158,47 @@ func queryPrometheus(ds *biz.AlertDataSource, expr string) ([]QueryResult, error
 	return parsePrometheusResponse(body)
 }
 
+func queryPrometheusRange(ds *biz.AlertDataSource, expr string, start, end time.Time, step time.Duration) ([]RangeResult, error) {
+	if step <= 0 {
+		step = 15 * time.Second
+	}
+	params := url.Values{}
+	params.Set("query", expr)
+	params.Set("start", fmt.Sprintf("%d", start.Unix()))
+	params.Set("end", fmt.Sprintf("%d", end.Unix()))
+	params.Set("step", fmt.Sprintf("%ds", int(step.Seconds())))
+
+	reqURL := fmt.Sprintf("%s/api/v1/query_range?%s", dataSourceBaseURL(ds), params.Encode())
+
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// 直连模式才需要添加认证（Agent 模式由代理处理器添加）
+	if ds.AccessMode == "direct" {
+		if ds.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+ds.Token)
+		} else if ds.Username != "" {
+			req.SetBasicAuth(ds.Username, ds.Password)
+		}
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return parsePrometheusRangeResponse(body)
+}
+
 func queryInfluxDB(ds *biz.AlertDataSource, expr string) ([]QueryResult, error) {
 	// 根据接入方式构建 URL
 	var baseURL string
@@ -169,6 +289,14 @@ func TestDataSource(ds *biz.AlertDataSource) error {
 		testExpr = "up"
 	case "influxdb":
 		testExpr = "SHOW MEASUREMENTS LIMIT 1"
+	case "loki":
+		_, err := doDataSourceRequest(ds, http.MethodGet, "/loki/api/v1/labels", nil, nil, "", 10*time.Second)
+		return err
+	case "elasticsearch":
+		_, err := doDataSourceRequest(ds, http.MethodGet, "/", nil, nil, "", 10*time.Second)
+		return err
+	case "clickhouse":
+		testExpr = "SELECT 1 AS value"
 	default:
 		return fmt.Errorf("unsupported datasource type: %s", ds.Type)
 	}
@@ -276,3 +404,46 @@ func parsePrometheusResponse(body []byte) ([]QueryResult, error) {
 	}
 	return results, nil
 }
+
+// parsePrometheusRangeResponse 解析 Prometheus query_range 响应（resultType=matrix）
+func parsePrometheusRangeResponse(body []byte) ([]RangeResult, error) {
+	var promResp struct {
+		Status string `json:"status"`
+		Data   struct {
+			ResultType string `json:"resultType"`
+			Result     []struct {
+				Metric map[string]string `json:"metric"`
+				Values [][]interface{}   `json:"values"`
+			} `json:"result"`
+		} `json:"data"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &promResp); err != nil {
+		return nil, err
+	}
+	if promResp.Status != "success" {
+		return nil, fmt.Errorf("prometheus query error: %s", promResp.Error)
+	}
+
+	var results []RangeResult
+	for _, item := range promResp.Data.Result {
+		series := RangeResult{Labels: item.Metric}
+		for _, pair := range item.Values {
+			if len(pair) < 2 {
+				continue
+			}
+			ts, ok := pair[0].(float64)
+			if !ok {
+				continue
+			}
+			valStr, _ := pair[1].(string)
+			var val float64
+			fmt.Sscanf(valStr, "%f", &val)
+			sec := int64(ts)
+			nsec := int64((ts - float64(sec)) * 1e9)
+			series.Samples = append(series.Samples, RangeSample{Time: time.Unix(sec, nsec), Value: val})
+		}
+		results = append(results, series)
+	}
+	return results, nil
+}
@@ -0,0 +1,185 @@
+package alert
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	biz "github.com/ydcloud-dy/opshub/internal/biz/alert"
+)
+
+// formatQueryResults 按标签排序格式化，便于断言
+func formatQueryResults(results []QueryResult) string {
+	var out []string
+	for _, r := range results {
+		b, _ := json.Marshal(r.Labels)
+		out = append(out, string(b)+"="+jsonNumber(r.Value))
+	}
+	sort.Strings(out)
+	return strings.Join(out, " ")
+}
+
+func jsonNumber(v float64) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func TestQueryDataSource_Loki(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/loki/api/v1/query" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		if user, pass, _ := r.BasicAuth(); user != "u" || pass != "p" {
+			t.Errorf("basic auth = %s:%s", user, pass)
+		}
+		switch r.URL.Query().Get("query") {
+		case `{app="api"}`:
+			w.Write([]byte(`{"status":"success","data":{"resultType":"streams","result":[]}}`))
+		case "bad(":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("parse error at line 1"))
+		default:
+			w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[
+				{"metric":{"app":"api"},"value":[1700000000.1,"12"]},
+				{"metric":{"app":"web"},"value":[1700000000.1,"3"]}]}}`))
+		}
+	}))
+	defer srv.Close()
+	ds := &biz.AlertDataSource{Type: "loki", URL: srv.URL + "/", AccessMode: "direct", Username: "u", Password: "p"}
+
+	results, err := QueryDataSource(ds, `sum by (app) (count_over_time({level="error"}[5m]))`)
+	if err != nil {
+		t.Fatalf("QueryDataSource() error: %v", err)
+	}
+	if got := formatQueryResults(results); got != `{"app":"api"}=12 {"app":"web"}=3` {
+		t.Errorf("results = %s", got)
+	}
+	if _, err := QueryDataSource(ds, `{app="api"}`); err == nil || !strings.Contains(err.Error(), "指标查询") {
+		t.Errorf("stream query error = %v", err)
+	}
+	if _, err := QueryDataSource(ds, "bad("); err == nil || !strings.Contains(err.Error(), "parse error") {
+		t.Errorf("bad query error = %v", err)
+	}
+}
+
+func TestQueryDataSource_Elasticsearch(t *testing.T) {
+	var gotBody map[string]interface{}
+	var respBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/app-logs-*/_search" {
+			t.Errorf("request = %s %s", r.Method, r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer tk" {
+			t.Errorf("authorization = %q", r.Header.Get("Authorization"))
+		}
+		gotBody = nil
+		json.NewDecoder(r.Body).Decode(&gotBody)
+		w.Write([]byte(respBody))
+	}))
+	defer srv.Close()
+	ds := &biz.AlertDataSource{Type: "elasticsearch", URL: srv.URL, AccessMode: "direct", Token: "tk"}
+
+	tests := []struct {
+		name string
+		resp string
+		want string
+	}{
+		{
+			name: "无聚合取命中数",
+			resp: `{"hits":{"total":{"value":42,"relation":"eq"}}}`,
+			want: `{}=42`,
+		},
+		{
+			name: "terms 桶取 doc_count",
+			resp: `{"hits":{"total":{"value":5}},"aggregations":{"service":{"buckets":[
+				{"key":"api","doc_count":4},{"key":"web","doc_count":1}]}}}`,
+			want: `{"service":"api"}=4 {"service":"web"}=1`,
+		},
+		{
+			name: "嵌套桶 + 单个指标",
+			resp: `{"hits":{"total":7},"aggregations":{"host":{"buckets":[
+				{"key":"h1","doc_count":3,"status":{"buckets":[{"key":500,"doc_count":3,"p":{"value":1.5}}]}}]}}}`,
+			want: `{"host":"h1","status":"500"}=1.5`,
+		},
+		{
+			name: "多个指标以 __name__ 区分，null 值跳过",
+			resp: `{"hits":{"total":{"value":2}},"aggregations":{"avg_latency":{"value":120.5},"max_latency":{"value":300},"empty":{"value":null}}}`,
+			want: `{"__name__":"avg_latency"}=120.5 {"__name__":"max_latency"}=300`,
+		},
+		{
+			name: "keyed 桶与单桶聚合",
+			resp: `{"hits":{"total":{"value":9}},"aggregations":{"errors":{"doc_count":6,"by":{"buckets":{"a":{"doc_count":2},"b":{"doc_count":4}}}}}}`,
+			want: `{"by":"a"}=2 {"by":"b"}=4`,
+		},
+	}
+	expr := `{"index":"app-logs-*","query":{"term":{"level":"error"}},"aggs":{}}`
+	for _, tt := range tests {
+		respBody = tt.resp
+		results, err := QueryDataSource(ds, expr)
+		if err != nil {
+			t.Fatalf("%s: error %v", tt.name, err)
+		}
+		if got := formatQueryResults(results); got != tt.want {
+			t.Errorf("%s: results = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+	if _, ok := gotBody["index"]; ok || gotBody["size"] != float64(0) {
+		t.Errorf("search body = %v", gotBody)
+	}
+
+	for _, bad := range []string{`not json`, `{"query":{}}`, `{"index":"a/b"}`} {
+		if _, err := QueryDataSource(ds, bad); err == nil {
+			t.Errorf("expr %q: expected error", bad)
+		}
+	}
+}
+
+func TestQueryDataSource_ClickHouse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sql, _ := io.ReadAll(r.Body)
+		if r.URL.Query().Get("default_format") != "JSON" || r.URL.Query().Get("readonly") != "1" {
+			t.Errorf("query params = %s", r.URL.RawQuery)
+		}
+		switch {
+		case strings.Contains(string(sql), "broken"):
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Code: 62. DB::Exception: Syntax error"))
+		case strings.Contains(string(sql), "AS value"):
+			w.Write([]byte(`{"meta":[{"name":"service","type":"String"},{"name":"value","type":"UInt64"},{"name":"region","type":"Nullable(String)"}],
+				"data":[{"service":"api","value":"12","region":"cn"},{"service":"web","value":3,"region":null}],"rows":2}`))
+		default:
+			w.Write([]byte(`{"meta":[{"name":"code","type":"UInt16"},{"name":"ratio","type":"Float64"}],"data":[{"code":500,"ratio":0.25}],"rows":1}`))
+		}
+	}))
+	defer srv.Close()
+	ds := &biz.AlertDataSource{Type: "clickhouse", URL: srv.URL, AccessMode: "direct"}
+
+	results, err := QueryDataSource(ds, "SELECT service, count() AS value, region FROM logs GROUP BY service, region")
+	if err != nil {
+		t.Fatalf("QueryDataSource() error: %v", err)
+	}
+	if got := formatQueryResults(results); got != `{"region":"cn","service":"api"}=12 {"service":"web"}=3` {
+		t.Errorf("results = %s", got)
+	}
+	results, err = QueryDataSource(ds, "SELECT code, avg(x) AS ratio FROM t GROUP BY code")
+	if err != nil {
+		t.Fatalf("QueryDataSource() error: %v", err)
+	}
+	if got := formatQueryResults(results); got != `{"code":"500"}=0.25` {
+		t.Errorf("last-column results = %s", got)
+	}
+	if _, err := QueryDataSource(ds, "SELECT broken"); err == nil || !strings.Contains(err.Error(), "Syntax error") {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestQueryDataSource_AgentModeUsesProxyURL(t *testing.T) {
+	ds := &biz.AlertDataSource{Type: "clickhouse", URL: "http://ch:8123", AccessMode: "agent", ProxyURL: "/api/v1/alert/proxy/datasource/tk"}
+	if got := dataSourceBaseURL(ds); got != "http://localhost:9876/api/v1/alert/proxy/datasource/tk" {
+		t.Errorf("dataSourceBaseURL() = %s", got)
+	}
+}
@@ -0,0 +1,211 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	biz "github.com/ydcloud-dy/opshub/internal/biz/alert"
+	alertdata "github.com/ydcloud-dy/opshub/internal/data/alert"
+)
+
+const (
+	escalationTickInterval = 30 * time.Second
+	escalationBatch        = 100
+)
+
+// EscalationStep 升级步骤
+type EscalationStep struct {
+	DelayMinutes int    `json:"delayMinutes"` // 距上一次通知的等待分钟数
+	ScheduleID   uint   `json:"scheduleId"`   // 通知该值班表当前值班人
+	UserIDs      []uint `json:"userIds"`      // 通知指定用户
+	ChannelIDs   []uint `json:"channelIds"`   // 为空时沿用首次通知的通道
+}
+
+// ParseEscalationSteps 解析并校验升级步骤
+func ParseEscalationSteps(stepsJSON string) ([]EscalationStep, error) {
+	if stepsJSON == "" || stepsJSON == "null" {
+		return nil, nil
+	}
+	var steps []EscalationStep
+	if err := json.Unmarshal([]byte(stepsJSON), &steps); err != nil {
+		return nil, fmt.Errorf("升级步骤格式错误: %v", err)
+	}
+	for i, st := range steps {
+		if st.DelayMinutes <= 0 {
+			return nil, fmt.Errorf("第 %d 步等待时间必须大于 0 分钟", i+1)
+		}
+		if st.ScheduleID == 0 && len(st.UserIDs) == 0 {
+			return nil, fmt.Errorf("第 %d 步需要指定值班表或接收用户", i+1)
+		}
+	}
+	return steps, nil
+}
+
+// EscalationService 告警升级服务
+// 订阅配置了升级策略时，首次通知后开始计时；告警在每一步的等待时间内未被确认或人工介入
+// 则通知该步接收人，直到告警恢复、被确认/介入或所有步骤执行完毕。
+type EscalationService struct {
+	db           *gorm.DB
+	policyRepo   *alertdata.EscalationPolicyRepo
+	escRepo      *alertdata.EventEscalationRepo
+	eventRepo    *alertdata.EventRepo
+	channelRepo  *alertdata.ChannelRepo
+	timelineRepo *alertdata.EventTimelineRepo
+	notifyQueue  *NotifyQueue
+	oncall       *OnCallService
+}
+
+// NewEscalationService 创建升级服务
+func NewEscalationService(db *gorm.DB, notifyQueue *NotifyQueue, oncall *OnCallService) *EscalationService {
+	return &EscalationService{
+		db:           db,
+		policyRepo:   alertdata.NewEscalationPolicyRepo(db),
+		escRepo:      alertdata.NewEventEscalationRepo(db),
+		eventRepo:    alertdata.NewEventRepo(db),
+		channelRepo:  alertdata.NewChannelRepo(db),
+		timelineRepo: alertdata.NewEventTimelineRepo(db),
+		notifyQueue:  notifyQueue,
+		oncall:       oncall,
+	}
+}
+
+// Begin 为事件开始升级计时，channelIDs 为首次通知使用的通道
+func (s *EscalationService) Begin(ctx context.Context, event *biz.AlertEvent, sub *biz.AlertSubscription, channelIDs []uint, now time.Time) error {
+	policy, err := s.policyRepo.GetByID(ctx, sub.EscalationPolicyID)
+	if err != nil {
+		return fmt.Errorf("升级策略不存在: %w", err)
+	}
+	if !policy.Enabled {
+		return nil
+	}
+	steps, err := ParseEscalationSteps(policy.Steps)
+	if err != nil || len(steps) == 0 {
+		return err
+	}
+	channelsJSON, _ := json.Marshal(channelIDs)
+	next := now.Add(time.Duration(steps[0].DelayMinutes) * time.Minute)
+	return s.escRepo.Create(ctx, &biz.AlertEventEscalation{
+		EventID:        event.ID,
+		SubscriptionID: sub.ID,
+		PolicyID:       policy.ID,
+		ChannelIDs:     string(channelsJSON),
+		Status:         biz.EscalationActive,
+		NextAt:         &next,
+		LastNotifiedAt: &now,
+	})
+}
+
+// Start 启动升级检查（阻塞，在 goroutine 中调用）
+func (s *EscalationService) Start(ctx context.Context) {
+	appLogger.Info("告警升级服务启动")
+	ticker := time.NewTicker(escalationTickInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			appLogger.Info("告警升级服务停止")
+			return
+		case <-ticker.C:
+			s.tick(ctx, time.Now())
+		}
+	}
+}
+
+// tick 处理所有到期的升级
+func (s *EscalationService) tick(ctx context.Context, now time.Time) {
+	list, err := s.escRepo.ListDue(ctx, now, escalationBatch)
+	if err != nil {
+		appLogger.Error("查询待升级告警失败", zap.Error(err))
+		return
+	}
+	for _, esc := range list {
+		s.escalate(ctx, esc, now)
+	}
+}
+
+func (s *EscalationService) escalate(ctx context.Context, esc *biz.AlertEventEscalation, now time.Time) {
+	event, err := s.eventRepo.GetByID(ctx, esc.EventID)
+	if err != nil || event.Status == "resolved" {
+		s.stop(ctx, esc, biz.EscalationResolved)
+		return
+	}
+	if event.ManualHandled || event.Acknowledged {
+		s.stop(ctx, esc, biz.EscalationAcknowledged)
+		return
+	}
+	policy, err := s.policyRepo.GetByID(ctx, esc.PolicyID)
+	if err != nil || !policy.Enabled {
+		s.stop(ctx, esc, biz.EscalationCompleted)
+		return
+	}
+	steps, err := ParseEscalationSteps(policy.Steps)
+	if err != nil || esc.Step >= len(steps) {
+		s.stop(ctx, esc, biz.EscalationCompleted)
+		return
+	}
+
+	step := steps[esc.Step]
+	updates := map[string]interface{}{
+		"step":             esc.Step + 1,
+		"last_notified_at": now,
+	}
+	if esc.Step+1 < len(steps) {
+		updates["next_at"] = now.Add(time.Duration(steps[esc.Step+1].DelayMinutes) * time.Minute)
+	} else {
+		updates["status"] = biz.EscalationCompleted
+		updates["next_at"] = nil
+	}
+	ok, err := s.escRepo.Advance(ctx, esc.ID, esc.Step, updates)
+	if err != nil || !ok {
+		return // 已被其他实例处理
+	}
+
+	appLogger.Info("告警升级", zap.Uint("eventID", event.ID), zap.Uint("subscriptionID", esc.SubscriptionID), zap.Int("step", esc.Step+1))
+	s.timelineRepo.Create(ctx, &biz.AlertEventTimeline{
+		EventID: event.ID,
+		Action:  biz.EventActionEscalate,
+		Source:  biz.EventActionSourceSystem,
+		Note:    fmt.Sprintf("升级策略 %s 第 %d 步", policy.Name, esc.Step+1),
+	})
+	s.notifyStep(ctx, event, esc, step, now)
+}
+
+// stop 结束升级
+func (s *EscalationService) stop(ctx context.Context, esc *biz.AlertEventEscalation, status string) {
+	if _, err := s.escRepo.Advance(ctx, esc.ID, esc.Step, map[string]interface{}{"status": status, "next_at": nil}); err != nil {
+		appLogger.Error("结束告警升级失败", zap.Uint("escalationID", esc.ID), zap.Error(err))
+	}
+}
+
+// notifyStep 通知升级步骤的接收人
+func (s *EscalationService) notifyStep(ctx context.Context, event *biz.AlertEvent, esc *biz.AlertEventEscalation, step EscalationStep, now time.Time) {
+	userIDs := append([]uint{}, step.UserIDs...)
+	if step.ScheduleID > 0 {
+		userIDs = append(userIDs, s.oncall.OnCallUserIDs(ctx, step.ScheduleID, now)...)
+	}
+	if len(userIDs) == 0 {
+		appLogger.Warn("升级步骤无接收人", zap.Uint("eventID", event.ID), zap.Uint("scheduleID", step.ScheduleID))
+		return
+	}
+	phones := queryUserPhones(ctx, s.db, userIDs)
+
+	channelIDs := step.ChannelIDs
+	if len(channelIDs) == 0 {
+		channelIDs = parseUintList(esc.ChannelIDs)
+	}
+	channels, _ := s.channelRepo.ListByIDs(ctx, channelIDs)
+	for _, ch := range channels {
+		if !ch.Enabled {
+			continue
+		}
+		if _, err := s.notifyQueue.Enqueue(ctx, event, ch, esc.SubscriptionID, 0, false, phones, userIDs); err != nil {
+			appLogger.Error("升级通知入队失败", zap.String("channel", ch.Name), zap.Uint("eventID", event.ID), zap.Error(err))
+		}
+	}
+}
@@ -33,6 +33,7 @@ type alertState struct {
 	LastEvalAt  time.Time `json:"lastEvalAt"`
 	Value       float64   `json:"value"`
 	PendingSince time.Time `json:"pendingSince"` // 首次命中时间（用于 duration 判断）
+	KeepFiringSince time.Time `json:"keepFiringSince"` // 条件不再满足的起始时间（用于 keep_firing_for 判断）
 }
 
 // EvalEngine 告警评估引擎
@@ -49,12 +50,16 @@ type EvalEngine struct {
 	subLogRepo      *alertdata.SubscriptionLogRepo
 	channelRepo     *alertdata.ChannelRepo
 	silenceRuleRepo *alertdata.SilenceRuleRepo
+	fingerprintRepo *alertdata.FingerprintRepo // 评估状态持久化
 	notifySvc       *NotifyService
+	notifyQueue     *NotifyQueue     // 通知发送队列（重试 + 死信）
 	evalCache       *EvalCache       // 评估时间缓存
 	ruleCache       *RuleCache       // 规则列表缓存
 	silenceCache    *SilenceRuleCache // 屏蔽规则缓存
 	dedupService    *DedupService    // 去重服务
 	groupService    *GroupService    // 分组服务
+	oncallService   *OnCallService    // 值班服务
+	escalationService *EscalationService // 升级服务
 	inhibitService  *InhibitService  // 抑制服务
 	promTplAdapter  *promtemplate.Adapter // Prometheus 模板适配器
 }
@@ -83,7 +88,10 @@ func NewEvalEngine(db *gorm.DB, rdb *redis.Client) *EvalEngine {
 
 	// 创建治理服务
 	dedupService := NewDedupService(db)
-	groupService := NewGroupService(db, notifySvc)
+	notifyQueue := NewNotifyQueue(db, rdb, notifySvc)
+	oncallService := NewOnCallService(db)
+	escalationService := NewEscalationService(db, notifyQueue, oncallService)
+	groupService := NewGroupService(db, notifyQueue, escalationService)
 	inhibitService := NewInhibitService(db)
 
 	return &EvalEngine{
@@ -99,17 +107,26 @@ func NewEvalEngine(db *gorm.DB, rdb *redis.Client) *EvalEngine {
 		subLogRepo:      subLogRepo,
 		channelRepo:     channelRepo,
 		silenceRuleRepo: silenceRuleRepo,
+		fingerprintRepo: alertdata.NewFingerprintRepo(db),
 		notifySvc:       notifySvc,
+		notifyQueue:     notifyQueue,
 		evalCache:       evalCache,
 		ruleCache:       ruleCache,
 		silenceCache:    silenceCache,
 		dedupService:    dedupService,
 		groupService:    groupService,
+		oncallService:   oncallService,
+		escalationService: escalationService,
 		inhibitService:  inhibitService,
 		promTplAdapter:  promtemplate.NewAdapter(),
 	}
 }
 
+// GetNotifyQueue 返回通知发送队列（供死信重发接口使用）
+func (e *EvalEngine) GetNotifyQueue() *NotifyQueue {
+	return e.notifyQueue
+}
+
 // Start 启动告警评估引擎（阻塞，在 goroutine 中调用）
 func (e *EvalEngine) Start(ctx context.Context) {
 	appLogger.Info("告警评估引擎启动")
@@ -125,12 +142,21 @@ func (e *EvalEngine) Start(ctx context.Context) {
 	// 启动批量同步 Worker
 	go e.startSyncWorker(ctx)
 
+	// 启动通知发送队列
+	go e.notifyQueue.Start(ctx)
+
+	// 启动告警升级检查
+	go e.escalationService.Start(ctx)
+
 	// 启动规则缓存管理器（订阅 Pub/Sub）
 	go e.ruleCache.Start(ctx)
 
 	// 启动屏蔽规则缓存管理器（订阅 Pub/Sub）
 	go e.silenceCache.Start(ctx)
 
+	// 恢复持久化的 pending/firing 状态，避免重启后 duration 重新计时
+	e.restoreStates(ctx)
+
 	// 记录每条规则上次入队时间
 	lastQueued := make(map[uint]time.Time)
 
@@ -218,20 +244,7 @@ func (e *EvalEngine) evalRule(ctx context.Context, rule *biz.AlertRule) {
 	_ = e.evalCache.UpdateEvalTime(ctx, rule.ID, time.Now())
 
 	// 支持多数据源：优先使用 DataSourceIDs JSON 数组，降级到单个 DataSourceID
-	var dsIDs []uint
-	if rule.DataSourceIDs != "" {
-		var ids []uint
-		if err := json.Unmarshal([]byte(rule.DataSourceIDs), &ids); err == nil {
-			for _, id := range ids {
-				if id > 0 {
-					dsIDs = append(dsIDs, id)
-				}
-			}
-		}
-	}
-	if len(dsIDs) == 0 && rule.DataSourceID > 0 {
-		dsIDs = []uint{rule.DataSourceID}
-	}
+	dsIDs := ruleDataSourceIDs(rule)
 	if len(dsIDs) == 0 {
 		appLogger.Warn("规则无有效数据源，跳过评估", zap.Uint("ruleID", rule.ID))
 		return
@@ -260,34 +273,14 @@ func (e *EvalEngine) evalRule(ctx context.Context, rule *biz.AlertRule) {
 			continue
 		}
 
-		// 查询数据源关联的业务分组
-		var groupNames []string
-		e.db.Table("alert_datasource_group_relations AS r").
-			Select("g.name").
-			Joins("LEFT JOIN asset_group AS g ON r.asset_group_id = g.id").
-			Where("r.data_source_id = ?", dsID).
-			Pluck("name", &groupNames)
-
-		// 确定业务分组标签值
-		var dsAssetGroup string
-		if len(groupNames) == 0 {
-			dsAssetGroup = ""
-		} else if len(groupNames) == 1 {
-			dsAssetGroup = groupNames[0]
-		} else {
-			dsAssetGroup = "公共"
-		}
-
 		// 为每个结果添加数据源标签，用于区分不同数据源的告警
+		dsLabels := e.dataSourceLabels(ds)
 		for i := range r {
 			if r[i].Labels == nil {
 				r[i].Labels = make(map[string]string)
 			}
-			r[i].Labels["datasource_id"] = fmt.Sprintf("%d", dsID)
-			r[i].Labels["datasource"] = ds.Name
-			r[i].Labels["datasource_type"] = ds.Type
-			if dsAssetGroup != "" {
-				r[i].Labels["ds_asset_group"] = dsAssetGroup
+			for k, v := range dsLabels {
+				r[i].Labels[k] = v
 			}
 		}
 		results = append(results, r...)
@@ -297,32 +290,8 @@ func (e *EvalEngine) evalRule(ctx context.Context, rule *biz.AlertRule) {
 		return
 	}
 
-	// 确定使用新规则还是旧规则
-	useNewRule := rule.QueryExpr != "" && rule.Conditions != ""
-
 	// 收集本次命中的所有 fingerprints（同时保留 metric 标签和值）
-	type hitInfo struct {
-		Value  float64
-		Labels map[string]string
-	}
-	hitFingerprints := make(map[string]hitInfo)
-	resolveFingerprints := make(map[string]float64)
-
-	for _, res := range results {
-		fp := calcFingerprint(rule.ID, res.Labels)
-
-		if useNewRule {
-			// 新规则：使用条件评估
-			if EvaluateConditions(res.Value, rule.Conditions) {
-				hitFingerprints[fp] = hitInfo{Value: res.Value, Labels: res.Labels}
-			} else {
-				resolveFingerprints[fp] = res.Value
-			}
-		} else {
-			// 旧规则：expr 已包含阈值判断，结果非空即触发
-			hitFingerprints[fp] = hitInfo{Value: res.Value, Labels: res.Labels}
-		}
-	}
+	hitFingerprints, resolveFingerprints := classifyRuleResults(rule, results)
 
 	// 处理命中的
 	for fp, info := range hitFingerprints {
@@ -383,41 +352,39 @@ func (e *EvalEngine) handleFiring(ctx context.Context, rule *biz.AlertRule, fing
 	redisKey := fmt.Sprintf("%s%d:%s", redisAlertStatePrefix, rule.ID, fingerprint)
 	now := time.Now()
 
-	var state alertState
-	val, err := e.rdb.Get(ctx, redisKey).Result()
-	if err == redis.Nil {
-		// 首次命中
-		state = alertState{PendingSince: now, LastEvalAt: now, Value: value}
-		data, _ := json.Marshal(state)
-		e.rdb.Set(ctx, redisKey, data, redisAlertStateTTL)
-		// duration=0s 则立即触发，否则等待
-		if rule.Duration == "" || rule.Duration == "0s" || rule.Duration == "0" {
-			e.createFiringEvent(ctx, rule, fingerprint, value, now, metricLabels)
-		}
-		return
-	} else if err != nil {
+	// Bug 5 修复：检查 duration，解析失败时记录错误并跳过评估
+	dur, err := ruleDuration(rule.Duration)
+	if err != nil {
+		appLogger.Error("解析 duration 失败，跳过本次评估", zap.Uint("ruleID", rule.ID), zap.String("duration", rule.Duration), zap.Error(err))
 		return
 	}
 
-	if err := json.Unmarshal([]byte(val), &state); err != nil {
+	state, found, err := e.loadState(ctx, rule, fingerprint)
+	if err != nil {
 		return
 	}
-	state.LastEvalAt = now
-	state.Value = value
-
-	// Bug 5 修复：检查 duration，解析失败时记录错误并跳过评估
-	if rule.Duration != "" && rule.Duration != "0s" && rule.Duration != "0" {
-		dur, err := parseDuration(rule.Duration)
-		if err != nil {
-			appLogger.Error("解析 duration 失败，跳过本次评估", zap.Uint("ruleID", rule.ID), zap.String("duration", rule.Duration), zap.Error(err))
-			return
-		}
-		if now.Sub(state.PendingSince) < dur {
-			// 未满持续时长，更新状态
-			data, _ := json.Marshal(state)
-			e.rdb.Set(ctx, redisKey, data, redisAlertStateTTL)
+	keepingFiring := found && !state.KeepFiringSince.IsZero()
+	state, ready := firingTransition(state, found, value, now, dur)
+	if !found {
+		// 首次命中：duration=0s 则立即触发，否则等待
+		if ready {
+			state.FiredAt = now
+			e.saveState(ctx, rule, fingerprint, &state, metricLabels)
+			e.createFiringEvent(ctx, rule, fingerprint, value, now, metricLabels)
 			return
 		}
+		e.saveState(ctx, rule, fingerprint, &state, metricLabels)
+		return
+	}
+
+	// 条件重新满足，取消 keep_firing_for 计时
+	if keepingFiring {
+		e.saveState(ctx, rule, fingerprint, &state, metricLabels)
+	}
+	if !ready {
+		// 未满持续时长，更新状态
+		e.setRedisState(ctx, redisKey, &state)
+		return
 	}
 
 	// Bug 3 修复：使用分布式锁避免重复创建事件
@@ -425,8 +392,7 @@ func (e *EvalEngine) handleFiring(ctx context.Context, rule *biz.AlertRule, fing
 	acquired, err := e.rdb.SetNX(ctx, lockKey, "1", 5*time.Second).Result()
 	if !acquired || err != nil {
 		// 其他 worker 正在处理，跳过
-		data, _ := json.Marshal(state)
-		e.rdb.Set(ctx, redisKey, data, redisAlertStateTTL)
+		e.setRedisState(ctx, redisKey, &state)
 		return
 	}
 	defer e.rdb.Del(ctx, lockKey)
@@ -438,39 +404,130 @@ func (e *EvalEngine) handleFiring(ctx context.Context, rule *biz.AlertRule, fing
 		if state.FiredAt.IsZero() {
 			state.FiredAt = now
 		}
+		e.saveState(ctx, rule, fingerprint, &state, metricLabels)
 		e.createFiringEvent(ctx, rule, fingerprint, value, state.FiredAt, metricLabels)
-	} else {
-		// 已存在，更新 value 字段
-		existing.Value = value
-		if err := e.eventRepo.Update(ctx, existing); err != nil {
-			appLogger.Warn("更新告警值失败", zap.Uint("eventID", existing.ID), zap.Error(err))
+		return
+	}
+
+	// 已存在，更新 value 字段
+	existing.Value = value
+	if err := e.eventRepo.Update(ctx, existing); err != nil {
+		appLogger.Warn("更新告警值失败", zap.Uint("eventID", existing.ID), zap.Error(err))
+	}
+	if state.FiredAt.IsZero() {
+		// 状态丢失但事件仍在 firing（如 Redis 被清空），补齐触发时间
+		state.FiredAt = existing.FiredAt
+		e.saveState(ctx, rule, fingerprint, &state, metricLabels)
+		return
+	}
+	e.setRedisState(ctx, redisKey, &state)
+}
+
+// ruleHit 本次评估命中的序列
+type ruleHit struct {
+	Value  float64
+	Labels map[string]string
+}
+
+// classifyRuleResults 按规则划分查询结果：命中的序列，以及未命中序列的当前值（作为恢复值）
+func classifyRuleResults(rule *biz.AlertRule, results []QueryResult) (map[string]ruleHit, map[string]float64) {
+	// 确定使用新规则还是旧规则
+	useNewRule := rule.QueryExpr != "" && rule.Conditions != ""
+
+	hits := make(map[string]ruleHit)
+	resolves := make(map[string]float64)
+	for _, res := range results {
+		fp := calcFingerprint(rule.ID, res.Labels)
+		if useNewRule {
+			// 新规则：使用条件评估
+			if EvaluateConditions(res.Value, rule.Conditions) {
+				hits[fp] = ruleHit{Value: res.Value, Labels: res.Labels}
+			} else {
+				resolves[fp] = res.Value
+			}
+		} else {
+			// 旧规则：expr 已包含阈值判断，结果非空即触发
+			hits[fp] = ruleHit{Value: res.Value, Labels: res.Labels}
 		}
 	}
+	return hits, resolves
+}
 
-	data, _ := json.Marshal(state)
-	e.rdb.Set(ctx, redisKey, data, redisAlertStateTTL)
+// firingTransition 序列命中时推进告警状态，返回新状态及是否已满足持续时长（可触发）
+// found=false 表示首次命中；命中会取消 keep_firing_for 计时
+func firingTransition(state alertState, found bool, value float64, now time.Time, dur time.Duration) (alertState, bool) {
+	if !found {
+		state = alertState{PendingSince: now}
+	}
+	state.LastEvalAt = now
+	state.Value = value
+	state.KeepFiringSince = time.Time{}
+	return state, dur <= 0 || now.Sub(state.PendingSince) >= dur
+}
+
+// resolveTransition 已触发的序列不再命中时推进 keep_firing_for 计时，返回新状态及是否应恢复
+func resolveTransition(state alertState, now time.Time, keepFor time.Duration) (alertState, bool) {
+	if keepFor <= 0 {
+		return state, true
+	}
+	state.LastEvalAt = now
+	if state.KeepFiringSince.IsZero() {
+		state.KeepFiringSince = now
+	}
+	return state, now.Sub(state.KeepFiringSince) >= keepFor
 }
 
 // mergeLabels 合并规则自定义标签和 Prometheus metric 标签（metric 标签优先）
-func mergeLabels(ruleLabelsJSON string, metricLabels map[string]string) string {
+func mergeLabels(ruleLabelsJSON string, metricLabels map[string]string) map[string]string {
 	merged := make(map[string]string)
 	// 先放规则自定义标签
-	if ruleLabelsJSON != "" {
+	if ruleLabelsJSON != "" && ruleLabelsJSON != "{}" {
 		_ = json.Unmarshal([]byte(ruleLabelsJSON), &merged)
 	}
 	// metric 标签覆盖（优先级更高，包含 instance/job 等关键字段）
 	for k, v := range metricLabels {
 		merged[k] = v
 	}
-	if len(merged) == 0 {
-		return "{}"
+	return merged
+}
+
+// newFiringEvent 构建触发事件：合并标签、补充预设标签并渲染 Annotations（不落库）
+// assetGroupName 为规则所属业务分组名称，为空时不添加 asset_group 标签
+func newFiringEvent(adapter *promtemplate.Adapter, rule *biz.AlertRule, fingerprint string, value float64, firedAt time.Time, metricLabels map[string]string, assetGroupName string) *biz.AlertEvent {
+	// 1. 先合并规则标签和指标标签
+	enrichedLabels := mergeLabels(rule.Labels, metricLabels)
+
+	// 2. 补充预设标签（如果不存在）
+	if _, exists := enrichedLabels["severity"]; !exists {
+		enrichedLabels["severity"] = rule.Severity
+	}
+	if _, exists := enrichedLabels["ruleName"]; !exists {
+		enrichedLabels["ruleName"] = rule.Name
+	}
+
+	// 3. 补充业务分组标签
+	if assetGroupName != "" {
+		enrichedLabels["asset_group"] = assetGroupName
+	}
+
+	labelsJSON, _ := json.Marshal(enrichedLabels)
+
+	return &biz.AlertEvent{
+		AlertRuleID:  rule.ID,
+		RuleName:     rule.Name,
+		AssetGroupID: rule.AssetGroupID,
+		Fingerprint:  fingerprint,
+		Severity:     rule.Severity,
+		Status:       "firing",
+		Labels:       string(labelsJSON),
+		Annotations:  renderAnnotations(adapter, rule.Annotations, rule, value, firedAt, enrichedLabels),
+		Value:        value,
+		FiredAt:      firedAt,
 	}
-	b, _ := json.Marshal(merged)
-	return string(b)
 }
 
 // renderAnnotations 渲染 Annotations 模板（支持 Prometheus 和 Go template 语法）
-func (e *EvalEngine) renderAnnotations(annotationsJSON string, rule *biz.AlertRule, value float64, firedAt time.Time, labels map[string]string) string {
+func renderAnnotations(adapter *promtemplate.Adapter, annotationsJSON string, rule *biz.AlertRule, value float64, firedAt time.Time, labels map[string]string) string {
 	if annotationsJSON == "" || annotationsJSON == "{}" {
 		return annotationsJSON
 	}
@@ -499,7 +556,7 @@ func (e *EvalEngine) renderAnnotations(annotationsJSON string, rule *biz.AlertRu
 	rendered := make(map[string]string)
 	for k, v := range annotations {
 		// 使用 Prometheus 模板适配器渲染（自动转换 $labels.xxx 和 $value）
-		result, err := e.promTplAdapter.Render(v, data)
+		result, err := adapter.Render(v, data)
 		if err != nil {
 			appLogger.Warn("模板渲染失败，使用原始值",
 				zap.String("key", k),
@@ -518,77 +575,15 @@ func (e *EvalEngine) renderAnnotations(annotationsJSON string, rule *biz.AlertRu
 func (e *EvalEngine) createFiringEvent(ctx context.Context, rule *biz.AlertRule, fingerprint string, value float64, firedAt time.Time, metricLabels map[string]string) {
 	appLogger.Info("创建告警事件", zap.Uint("ruleID", rule.ID), zap.String("fingerprint", fingerprint), zap.Float64("value", value))
 
-	// 补充标签：severity、ruleName、datasource、assetGroup
-	enrichedLabels := make(map[string]string)
-
-	// 1. 先合并规则标签和指标标签
-	if rule.Labels != "" && rule.Labels != "{}" {
-		var ruleLabels map[string]string
-		if err := json.Unmarshal([]byte(rule.Labels), &ruleLabels); err == nil {
-			for k, v := range ruleLabels {
-				enrichedLabels[k] = v
-			}
-		}
-	}
-	for k, v := range metricLabels {
-		enrichedLabels[k] = v
-	}
-
-	// 2. 补充预设标签（如果不存在）
-	if _, exists := enrichedLabels["severity"]; !exists {
-		enrichedLabels["severity"] = rule.Severity
-	}
-	if _, exists := enrichedLabels["ruleName"]; !exists {
-		enrichedLabels["ruleName"] = rule.Name
-	}
-
-	// 3. 补充业务分组标签
+	// 补充标签：severity、ruleName、assetGroup，并渲染 Annotations 模板
+	var groupName string
 	if rule.AssetGroupID > 0 {
-		var groupName string
 		e.db.Table("asset_group").Select("name").Where("id = ?", rule.AssetGroupID).Scan(&groupName)
-		if groupName != "" {
-			enrichedLabels["asset_group"] = groupName
-		}
-	}
-
-	labelsJSON, _ := json.Marshal(enrichedLabels)
-
-	// 渲染 Annotations 模板
-	renderedAnnotations := e.renderAnnotations(rule.Annotations, rule, value, firedAt, enrichedLabels)
-
-	event := &biz.AlertEvent{
-		AlertRuleID:  rule.ID,
-		RuleName:     rule.Name,
-		AssetGroupID: rule.AssetGroupID,
-		Fingerprint:  fingerprint,
-		Severity:     rule.Severity,
-		Status:       "firing",
-		Labels:       string(labelsJSON),
-		Annotations:  renderedAnnotations,
-		Value:        value,
-		FiredAt:      firedAt,
 	}
+	event := newFiringEvent(e.promTplAdapter, rule, fingerprint, value, firedAt, metricLabels, groupName)
 
 	// 检查是否匹配屏蔽规则（修复：告警恢复后再次触发时应继承屏蔽状态）
-	if matchedRule := e.findMatchingSilenceRule(ctx, event); matchedRule != nil {
-		event.Silenced = true
-		event.SilenceType = matchedRule.Type
-		event.SilenceReason = matchedRule.Reason
-		now := time.Now()
-		event.SilencedAt = &now
-
-		if matchedRule.Type == "fixed" {
-			event.SilenceUntil = matchedRule.SilenceUntil
-		} else if matchedRule.Type == "periodic" {
-			event.SilenceTimeRanges = matchedRule.TimeRanges
-		}
-
-		appLogger.Info("新告警匹配到屏蔽规则，自动设置为屏蔽状态",
-			zap.Uint("ruleID", rule.ID),
-			zap.String("fingerprint", fingerprint),
-			zap.Uint("silenceRuleID", matchedRule.ID),
-			zap.String("silenceType", matchedRule.Type))
-	}
+	e.applySilenceRule(ctx, event)
 
 	if err := e.eventRepo.Create(ctx, event); err != nil {
 		appLogger.Error("创建告警事件失败", zap.Error(err))
@@ -601,6 +596,31 @@ func (e *EvalEngine) createFiringEvent(ctx context.Context, rule *biz.AlertRule,
 	}
 }
 
+// applySilenceRule 新告警匹配到屏蔽规则时，自动设置为屏蔽状态
+func (e *EvalEngine) applySilenceRule(ctx context.Context, event *biz.AlertEvent) {
+	matchedRule := e.findMatchingSilenceRule(ctx, event)
+	if matchedRule == nil {
+		return
+	}
+	event.Silenced = true
+	event.SilenceType = matchedRule.Type
+	event.SilenceReason = matchedRule.Reason
+	now := time.Now()
+	event.SilencedAt = &now
+
+	if matchedRule.Type == "fixed" {
+		event.SilenceUntil = matchedRule.SilenceUntil
+	} else if matchedRule.Type == "periodic" {
+		event.SilenceTimeRanges = matchedRule.TimeRanges
+	}
+
+	appLogger.Info("新告警匹配到屏蔽规则，自动设置为屏蔽状态",
+		zap.Uint("ruleID", event.AlertRuleID),
+		zap.String("fingerprint", event.Fingerprint),
+		zap.Uint("silenceRuleID", matchedRule.ID),
+		zap.String("silenceType", matchedRule.Type))
+}
+
 func (e *EvalEngine) handleResolvedWithValue(ctx context.Context, rule *biz.AlertRule, fingerprint string, resolveValue float64) {
 	redisKey := fmt.Sprintf("%s%d:%s", redisAlertStatePrefix, rule.ID, fingerprint)
 
@@ -610,12 +630,34 @@ func (e *EvalEngine) handleResolvedWithValue(ctx context.Context, rule *biz.Aler
 	event, err := e.eventRepo.GetFiringByFingerprint(ctx, fingerprint)
 	if err != nil || event == nil {
 		appLogger.Debug("未找到firing事件", zap.String("fingerprint", fingerprint), zap.Error(err))
-		// 数据库中没有 firing 事件，清理 Redis（如果存在）
-		e.rdb.Del(ctx, redisKey)
+		// 数据库中没有 firing 事件（含仍在 pending 的状态），清理 Redis 和持久化状态
+		e.deleteState(ctx, rule.ID, fingerprint)
 		return
 	}
 
 	now := time.Now()
+
+	// keep_firing_for：条件不再满足后继续保持 firing 一段时间，避免抖动造成恢复/触发风暴
+	if keepFor, err := ruleDuration(rule.KeepFiringFor); err == nil && keepFor > 0 {
+		state, found, err := e.loadState(ctx, rule, fingerprint)
+		if err != nil {
+			return
+		}
+		if !found {
+			state = alertState{PendingSince: event.FiredAt, FiredAt: event.FiredAt, Value: event.Value}
+		}
+		started := state.KeepFiringSince.IsZero()
+		state, resolve := resolveTransition(state, now, keepFor)
+		if !resolve {
+			if started {
+				e.saveState(ctx, rule, fingerprint, &state, nil)
+				appLogger.Debug("条件不再满足，进入 keep_firing_for 保持期", zap.Uint("ruleID", rule.ID), zap.String("fingerprint", fingerprint))
+			} else {
+				e.setRedisState(ctx, redisKey, &state)
+			}
+			return
+		}
+	}
 	event.Status = "resolved"
 	// 若人工介入过则标记为 manual_then_auto，否则 auto
 	if event.ManualHandled {
@@ -631,8 +673,8 @@ func (e *EvalEngine) handleResolvedWithValue(ctx context.Context, rule *biz.Aler
 		return // 更新失败，保留 Redis 状态
 	}
 
-	// 更新成功后才删除 Redis key
-	e.rdb.Del(ctx, redisKey)
+	// 更新成功后才删除 Redis key 和持久化状态
+	e.deleteState(ctx, rule.ID, fingerprint)
 
 	appLogger.Info("准备发送恢复通知", zap.Uint("ruleID", rule.ID), zap.Bool("notifyOnResolve", rule.NotifyOnResolve))
 
@@ -799,30 +841,31 @@ func (e *EvalEngine) sendNotifications(ctx context.Context, rule *biz.AlertRule,
 			}
 		}
 
-		// 确定接收用户
+		// 确定接收用户（订阅关联值班表时以当前值班人为准）
 		var phones []string
 		var userIDs []uint
-		if perRuleUsers := parseUintList(sr.UserIDs); len(perRuleUsers) > 0 {
+		if perRuleUsers := parseUintList(sr.UserIDs); len(perRuleUsers) > 0 && sub.ScheduleID == 0 {
 			userIDs = perRuleUsers
 			phones = e.getUserPhonesByIDs(ctx, perRuleUsers)
 		} else {
-			phones = e.getSubUserPhones(ctx, sr.SubscriptionID)
+			phones, userIDs = e.getSubUserPhones(ctx, sub, now)
 		}
 
 		// 发送通知
 		channels, _ := e.channelRepo.ListByIDs(ctx, channelIDs)
-		var channelResults []map[string]interface{}
+		var enabledChannels []*biz.AlertNotifyChannel
+		var channelResults []*ChannelDelivery
 		for _, ch := range channels {
 			if !ch.Enabled {
 				continue
 			}
-			channelResults = append(channelResults, map[string]interface{}{
-				"id":     ch.ID,
-				"name":   ch.Name,
-				"type":   ch.Type,
-				"status": "sent",
+			enabledChannels = append(enabledChannels, ch)
+			channelResults = append(channelResults, &ChannelDelivery{
+				ChannelID: ch.ID,
+				Name:      ch.Name,
+				Type:      ch.Type,
+				Status:    biz.NotifyDeliveryPending,
 			})
-			go e.notifySvc.Send(ctx, ch, event, isResolve, phones, userIDs)
 		}
 
 		notifyResult["channels"] = channelResults
@@ -831,13 +874,28 @@ func (e *EvalEngine) sendNotifications(ctx context.Context, rule *biz.AlertRule,
 		// 记录指纹（用于去重）
 		e.dedupService.RecordFingerprint(ctx, event, sr.SubscriptionID)
 
-		// 保存日志
-		e.saveSubscriptionLog(ctx, sr.SubscriptionID, event.ID, rule.ID, matched, matchResult, denoiseResult, notifyResult)
+		// 保存日志，各通道投递记录关联到该日志，由通知队列异步发送并重试
+		var subLogID uint
+		if subLog := e.saveSubscriptionLog(ctx, sr.SubscriptionID, event.ID, rule.ID, matched, matchResult, denoiseResult, notifyResult); subLog != nil {
+			subLogID = subLog.ID
+		}
+		for _, ch := range enabledChannels {
+			if _, err := e.notifyQueue.Enqueue(ctx, event, ch, sr.SubscriptionID, subLogID, isResolve, phones, userIDs); err != nil {
+				appLogger.Error("通知入队失败", zap.String("channel", ch.Name), zap.Uint("eventID", event.ID), zap.Error(err))
+			}
+		}
+
+		// 开始升级计时（未人工介入时按策略逐级通知）
+		if !isResolve && sub.EscalationPolicyID > 0 {
+			if err := e.escalationService.Begin(ctx, event, sub, channelIDs, now); err != nil {
+				appLogger.Error("启动告警升级失败", zap.Uint("eventID", event.ID), zap.Uint("subscriptionID", sub.ID), zap.Error(err))
+			}
+		}
 	}
 }
 
 // saveSubscriptionLog 保存订阅执行日志
-func (e *EvalEngine) saveSubscriptionLog(ctx context.Context, subscriptionID, eventID, ruleID uint, matched bool, matchResult, denoiseResult, notifyResult map[string]interface{}) {
+func (e *EvalEngine) saveSubscriptionLog(ctx context.Context, subscriptionID, eventID, ruleID uint, matched bool, matchResult, denoiseResult, notifyResult map[string]interface{}) *biz.AlertSubscriptionLog {
 	matchJSON, _ := json.Marshal(matchResult)
 	denoiseJSON, _ := json.Marshal(denoiseResult)
 	notifyJSON, _ := json.Marshal(notifyResult)
@@ -854,7 +912,9 @@ func (e *EvalEngine) saveSubscriptionLog(ctx context.Context, subscriptionID, ev
 
 	if err := e.subLogRepo.Create(ctx, log); err != nil {
 		appLogger.Error("保存订阅日志失败", zap.Error(err))
+		return nil
 	}
+	return log
 }
 
 // getUserPhonesByIDs 通过用户 ID 列表直接查手机号（支持 ID=0 表示 @all）
@@ -866,49 +926,29 @@ func (e *EvalEngine) getUserPhonesByIDs(ctx context.Context, userIDs []uint) []s
 			return nil // nil=@all
 		}
 	}
-	// 查询指定用户的手机号
-	type phoneRow struct{ Phone string }
-	var rows []phoneRow
-	e.db.WithContext(ctx).Table("sys_user").Select("phone").Where("id IN ? AND phone != ''", userIDs).Scan(&rows)
-	var phones []string
-	for _, r := range rows {
-		if r.Phone != "" {
-			phones = append(phones, r.Phone)
-		}
-	}
-	return phones
+	return queryUserPhones(ctx, e.db, userIDs)
 }
 
-// getSubUserPhones 获取订阅的接收用户手机号列表
+// getSubUserPhones 获取订阅的接收用户手机号及用户 ID 列表
+// 订阅关联值班表时解析 now 时刻的值班人，否则使用订阅的固定接收用户
 // 返回 nil 表示 @all，返回空切片表示不 @任何人，返回非空切片表示 @指定用户
-func (e *EvalEngine) getSubUserPhones(ctx context.Context, subscriptionID uint) []string {
-	subUsers, err := e.subUserRepo.ListBySubscription(ctx, subscriptionID)
-	if err != nil || len(subUsers) == 0 {
-		return []string{} // 空切片=不@任何人
-	}
-	// 检查是否有 userID=0（所有人）
-	for _, su := range subUsers {
-		if su.UserID == 0 {
-			return nil // nil=@all
+func (e *EvalEngine) getSubUserPhones(ctx context.Context, sub *biz.AlertSubscription, now time.Time) ([]string, []uint) {
+	if sub.ScheduleID > 0 {
+		userIDs := e.oncallService.OnCallUserIDs(ctx, sub.ScheduleID, now)
+		if len(userIDs) == 0 {
+			appLogger.Warn("值班表当前无值班人员", zap.Uint("subscriptionID", sub.ID), zap.Uint("scheduleID", sub.ScheduleID))
 		}
+		return queryUserPhones(ctx, e.db, userIDs), userIDs
+	}
+	subUsers, err := e.subUserRepo.ListBySubscription(ctx, sub.ID)
+	if err != nil || len(subUsers) == 0 {
+		return []string{}, nil // 空切片=不@任何人
 	}
-	// 批量查手机号
 	var userIDs []uint
 	for _, su := range subUsers {
 		userIDs = append(userIDs, su.UserID)
 	}
-	type phoneRow struct {
-		Phone string
-	}
-	var rows []phoneRow
-	e.db.WithContext(ctx).Table("sys_user").Select("phone").Where("id IN ? AND phone != ''", userIDs).Scan(&rows)
-	var phones []string
-	for _, r := range rows {
-		if r.Phone != "" {
-			phones = append(phones, r.Phone)
-		}
-	}
-	return phones
+	return e.getUserPhonesByIDs(ctx, userIDs), userIDs
 }
 
 // findMatchingSilenceRule 查找匹配的屏蔽规则（返回第一个匹配的规则）
@@ -1002,6 +1042,69 @@ func calcFingerprint(ruleID uint, labels map[string]string) string {
 	return fmt.Sprintf("%x", h[:8])
 }
 
+// ruleDuration 解析规则中的时长配置，空值或 "0"/"0s" 返回 0
+func ruleDuration(s string) (time.Duration, error) {
+	if s == "" || s == "0s" || s == "0" {
+		return 0, nil
+	}
+	return parseDuration(s)
+}
+
+// ValidateKeepFiringFor 校验规则的 keep_firing_for 配置，非法或为负时返回错误（空值表示不启用）
+func ValidateKeepFiringFor(s string) error {
+	d, err := ruleDuration(s)
+	if err != nil {
+		return fmt.Errorf("keepFiringFor 格式错误: %s，支持 30s/5m/1h", s)
+	}
+	if d < 0 {
+		return fmt.Errorf("keepFiringFor 不能为负数: %s", s)
+	}
+	return nil
+}
+
+// ruleDataSourceIDs 解析规则关联的数据源（优先使用 DataSourceIDs JSON 数组，降级到单个 DataSourceID）
+func ruleDataSourceIDs(rule *biz.AlertRule) []uint {
+	var dsIDs []uint
+	if rule.DataSourceIDs != "" {
+		var ids []uint
+		if err := json.Unmarshal([]byte(rule.DataSourceIDs), &ids); err == nil {
+			for _, id := range ids {
+				if id > 0 {
+					dsIDs = append(dsIDs, id)
+				}
+			}
+		}
+	}
+	if len(dsIDs) == 0 && rule.DataSourceID > 0 {
+		dsIDs = []uint{rule.DataSourceID}
+	}
+	return dsIDs
+}
+
+// dataSourceLabels 构建数据源附加标签（datasource_id/datasource/datasource_type/ds_asset_group）
+func (e *EvalEngine) dataSourceLabels(ds *biz.AlertDataSource) map[string]string {
+	// 查询数据源关联的业务分组
+	var groupNames []string
+	e.db.Table("alert_datasource_group_relations AS r").
+		Select("g.name").
+		Joins("LEFT JOIN asset_group AS g ON r.asset_group_id = g.id").
+		Where("r.data_source_id = ?", ds.ID).
+		Pluck("name", &groupNames)
+
+	labels := map[string]string{
+		"datasource_id":   fmt.Sprintf("%d", ds.ID),
+		"datasource":      ds.Name,
+		"datasource_type": ds.Type,
+	}
+	// 确定业务分组标签值
+	if len(groupNames) == 1 && groupNames[0] != "" {
+		labels["ds_asset_group"] = groupNames[0]
+	} else if len(groupNames) > 1 {
+		labels["ds_asset_group"] = "公共"
+	}
+	return labels
+}
+
 // parseDuration 解析 "5m", "1h", "30s" 等格式
 func parseDuration(s string) (time.Duration, error) {
 	if strings.HasSuffix(s, "m") {
@@ -1130,5 +1233,10 @@ func (e *EvalEngine) ClearRuleStates(ctx context.Context, ruleID uint) {
 		e.rdb.Del(ctx, keys...)
 		appLogger.Info("清理规则 Redis 状态", zap.Uint("ruleID", ruleID), zap.Int("count", len(keys)))
 	}
+
+	// 同时清理持久化的评估状态
+	if err := e.fingerprintRepo.DeleteEvalStatesByRule(ctx, ruleID); err != nil {
+		appLogger.Warn("清理规则持久化评估状态失败", zap.Uint("ruleID", ruleID), zap.Error(err))
+	}
 }
 
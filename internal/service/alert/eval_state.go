@@ -0,0 +1,297 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	biz "github.com/ydcloud-dy/opshub/internal/biz/alert"
+)
+
+// restoreWorkers 启动恢复状态时的并发数
+const restoreWorkers = 10
+
+// loadState 读取告警状态：优先 Redis，未命中时从 MySQL 持久化状态恢复并回写 Redis
+func (e *EvalEngine) loadState(ctx context.Context, rule *biz.AlertRule, fingerprint string) (alertState, bool, error) {
+	redisKey := fmt.Sprintf("%s%d:%s", redisAlertStatePrefix, rule.ID, fingerprint)
+
+	var state alertState
+	val, err := e.rdb.Get(ctx, redisKey).Result()
+	if err == nil {
+		if err := json.Unmarshal([]byte(val), &state); err != nil {
+			return state, false, err
+		}
+		return state, true, nil
+	}
+	if err != redis.Nil {
+		return state, false, err
+	}
+
+	// Redis 中没有状态（重启、Redis 清空或切换实例），尝试从 MySQL 恢复
+	fp, err := e.fingerprintRepo.GetEvalState(ctx, rule.ID, fingerprint)
+	if err == gorm.ErrRecordNotFound {
+		return state, false, nil
+	}
+	if err != nil {
+		return state, false, err
+	}
+	state = stateFromFingerprint(fp)
+	e.setRedisState(ctx, redisKey, &state)
+	appLogger.Debug("从持久化状态恢复告警状态", zap.Uint("ruleID", rule.ID), zap.String("fingerprint", fingerprint), zap.String("state", fp.EvalState))
+	return state, true, nil
+}
+
+// setRedisState 仅写入 Redis（每次评估都会调用）
+func (e *EvalEngine) setRedisState(ctx context.Context, redisKey string, state *alertState) {
+	data, _ := json.Marshal(state)
+	e.rdb.Set(ctx, redisKey, data, redisAlertStateTTL)
+}
+
+// saveState 写入 Redis 并持久化到 MySQL（仅在状态变化时调用，避免每次评估写库）
+func (e *EvalEngine) saveState(ctx context.Context, rule *biz.AlertRule, fingerprint string, state *alertState, metricLabels map[string]string) {
+	redisKey := fmt.Sprintf("%s%d:%s", redisAlertStatePrefix, rule.ID, fingerprint)
+	e.setRedisState(ctx, redisKey, state)
+
+	labels := "{}"
+	if len(metricLabels) > 0 {
+		b, _ := json.Marshal(metricLabels)
+		labels = string(b)
+	}
+	fp := &biz.AlertFingerprint{
+		AlertRuleID:     rule.ID,
+		Fingerprint:     fingerprint,
+		RuleName:        rule.Name,
+		Severity:        rule.Severity,
+		Labels:          labels,
+		FirstSeenAt:     state.PendingSince,
+		LastSeenAt:      time.Now(),
+		OccurrenceCount: 1,
+		EvalState:       biz.EvalStatePending,
+		PendingSince:    timePtr(state.PendingSince),
+		FiredAt:         timePtr(state.FiredAt),
+		KeepFiringSince: timePtr(state.KeepFiringSince),
+	}
+	if !state.FiredAt.IsZero() {
+		fp.EvalState = biz.EvalStateFiring
+	}
+	if err := e.fingerprintRepo.SaveEvalState(ctx, fp); err != nil {
+		appLogger.Warn("持久化告警评估状态失败", zap.Uint("ruleID", rule.ID), zap.String("fingerprint", fingerprint), zap.Error(err))
+	}
+}
+
+// deleteState 删除 Redis 状态和持久化状态
+func (e *EvalEngine) deleteState(ctx context.Context, ruleID uint, fingerprint string) {
+	redisKey := fmt.Sprintf("%s%d:%s", redisAlertStatePrefix, ruleID, fingerprint)
+	e.rdb.Del(ctx, redisKey)
+	if err := e.fingerprintRepo.DeleteEvalState(ctx, ruleID, fingerprint); err != nil {
+		appLogger.Warn("删除持久化评估状态失败", zap.Uint("ruleID", ruleID), zap.String("fingerprint", fingerprint), zap.Error(err))
+	}
+}
+
+// ClearEventState 事件被人工解决后清理对应序列的评估状态（Redis 与持久化记录）
+// 否则引擎仍认为该序列处于 firing，条件持续满足时会绕过 for 计时立即重新触发
+func (e *EvalEngine) ClearEventState(ctx context.Context, event *biz.AlertEvent) {
+	if event == nil || event.AlertRuleID == 0 || event.Fingerprint == "" {
+		return // 外部告警不经过引擎评估
+	}
+	e.deleteState(ctx, event.AlertRuleID, event.Fingerprint)
+}
+
+// restoreStates 启动时恢复评估状态
+// 1. 将 MySQL 中持久化的 pending/firing 状态回填到 Redis
+// 2. 为没有持久化状态的 firing 事件补建状态（兼容升级前产生的事件）
+// 3. 对配置了 duration 的规则，通过 query_range 回溯计算尚未记录的 pending 起点
+func (e *EvalEngine) restoreStates(ctx context.Context) {
+	rules, err := e.ruleCache.GetEnabledRules(ctx)
+	if err != nil {
+		appLogger.Warn("恢复告警状态失败：加载规则失败", zap.Error(err))
+		return
+	}
+
+	start := time.Now()
+	ruleCh := make(chan *biz.AlertRule)
+	var wg sync.WaitGroup
+	for i := 0; i < restoreWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for rule := range ruleCh {
+				e.restoreRuleStates(ctx, rule)
+			}
+		}()
+	}
+	for _, rule := range rules {
+		select {
+		case ruleCh <- rule:
+		case <-ctx.Done():
+		}
+	}
+	close(ruleCh)
+	wg.Wait()
+
+	appLogger.Info("告警评估状态恢复完成", zap.Int("rules", len(rules)), zap.Duration("elapsed", time.Since(start)))
+}
+
+// restoreRuleStates 恢复单条规则的评估状态
+func (e *EvalEngine) restoreRuleStates(ctx context.Context, rule *biz.AlertRule) {
+	defer func() {
+		if r := recover(); r != nil {
+			appLogger.Error("恢复告警状态 panic", zap.Uint("ruleID", rule.ID), zap.Any("recover", r))
+		}
+	}()
+
+	// 1. 持久化状态 -> Redis（Redis 中已有的状态以 Redis 为准）
+	states, err := e.fingerprintRepo.ListEvalStatesByRule(ctx, rule.ID)
+	if err != nil {
+		appLogger.Warn("读取持久化评估状态失败", zap.Uint("ruleID", rule.ID), zap.Error(err))
+	}
+	known := make(map[string]bool, len(states))
+	for _, fp := range states {
+		known[fp.Fingerprint] = true
+		state := stateFromFingerprint(fp)
+		data, _ := json.Marshal(state)
+		redisKey := fmt.Sprintf("%s%d:%s", redisAlertStatePrefix, rule.ID, fp.Fingerprint)
+		e.rdb.SetNX(ctx, redisKey, data, redisAlertStateTTL)
+	}
+
+	// 2. firing 事件 -> 状态
+	events, err := e.eventRepo.ListFiringByRuleID(ctx, rule.ID)
+	if err == nil {
+		for _, event := range events {
+			if known[event.Fingerprint] {
+				continue
+			}
+			known[event.Fingerprint] = true
+			state := alertState{PendingSince: event.FiredAt, FiredAt: event.FiredAt, LastEvalAt: event.FiredAt, Value: event.Value}
+			redisKey := fmt.Sprintf("%s%d:%s", redisAlertStatePrefix, rule.ID, event.Fingerprint)
+			if exists, _ := e.rdb.Exists(ctx, redisKey).Result(); exists > 0 {
+				continue
+			}
+			var labels map[string]string
+			_ = json.Unmarshal([]byte(event.Labels), &labels)
+			e.saveState(ctx, rule, event.Fingerprint, &state, labels)
+		}
+	}
+
+	// 3. query_range 回溯 pending 起点
+	e.backfillPending(ctx, rule, known)
+}
+
+// backfillPending 通过 query_range 回溯规则在 [now-duration, now] 内持续命中的序列，
+// 以最早的连续命中时间作为 pending 起点，使重启后仍按原计划准时触发
+func (e *EvalEngine) backfillPending(ctx context.Context, rule *biz.AlertRule, known map[string]bool) {
+	dur, err := ruleDuration(rule.Duration)
+	if err != nil || dur == 0 {
+		return
+	}
+	step := time.Duration(rule.EvalInterval) * time.Second
+	if step <= 0 {
+		step = 15 * time.Second
+	}
+
+	queryExpr := rule.QueryExpr
+	if queryExpr == "" {
+		queryExpr = rule.Expr
+	}
+	useNewRule := rule.QueryExpr != "" && rule.Conditions != ""
+	hit := func(v float64) bool {
+		if useNewRule {
+			return EvaluateConditions(v, rule.Conditions)
+		}
+		// 旧规则：expr 已包含阈值判断，有采样即命中
+		return true
+	}
+
+	end := time.Now()
+	start := end.Add(-dur - step)
+
+	for _, dsID := range ruleDataSourceIDs(rule) {
+		ds, err := e.dsRepo.GetByID(ctx, dsID)
+		if err != nil {
+			continue
+		}
+		series, err := QueryDataSourceRange(ds, queryExpr, start, end, step)
+		if err != nil {
+			appLogger.Debug("回溯 pending 状态失败", zap.Uint("ruleID", rule.ID), zap.Uint("dsID", dsID), zap.Error(err))
+			continue
+		}
+		dsLabels := e.dataSourceLabels(ds)
+		for _, sr := range series {
+			labels := make(map[string]string, len(sr.Labels)+len(dsLabels))
+			for k, v := range sr.Labels {
+				labels[k] = v
+			}
+			for k, v := range dsLabels {
+				labels[k] = v
+			}
+			fp := calcFingerprint(rule.ID, labels)
+			if known[fp] {
+				continue
+			}
+			since := pendingSinceFromSamples(sr.Samples, hit, step, end)
+			if since.IsZero() {
+				continue
+			}
+			last := sr.Samples[len(sr.Samples)-1]
+			state := alertState{PendingSince: since, LastEvalAt: last.Time, Value: last.Value}
+			redisKey := fmt.Sprintf("%s%d:%s", redisAlertStatePrefix, rule.ID, fp)
+			data, _ := json.Marshal(state)
+			if ok, _ := e.rdb.SetNX(ctx, redisKey, data, redisAlertStateTTL).Result(); !ok {
+				continue
+			}
+			e.saveState(ctx, rule, fp, &state, labels)
+			appLogger.Info("回溯恢复 pending 状态", zap.Uint("ruleID", rule.ID), zap.String("fingerprint", fp), zap.Time("pendingSince", since))
+		}
+	}
+}
+
+// pendingSinceFromSamples 从最新采样向前查找连续命中的最早时间
+// 最新采样必须在 end 前 2 个 step 内（序列仍然存活），相邻采样间隔超过 2 个 step 视为中断
+func pendingSinceFromSamples(samples []RangeSample, hit func(float64) bool, step time.Duration, end time.Time) time.Time {
+	if len(samples) == 0 {
+		return time.Time{}
+	}
+	maxGap := 2 * step
+	last := samples[len(samples)-1]
+	if end.Sub(last.Time) > maxGap || !hit(last.Value) {
+		return time.Time{}
+	}
+	since := last.Time
+	for i := len(samples) - 2; i >= 0; i-- {
+		if samples[i+1].Time.Sub(samples[i].Time) > maxGap || !hit(samples[i].Value) {
+			break
+		}
+		since = samples[i].Time
+	}
+	return since
+}
+
+// stateFromFingerprint 将持久化的评估状态转换为 Redis 状态
+func stateFromFingerprint(fp *biz.AlertFingerprint) alertState {
+	state := alertState{LastEvalAt: fp.LastSeenAt}
+	if fp.PendingSince != nil {
+		state.PendingSince = *fp.PendingSince
+	}
+	if fp.FiredAt != nil {
+		state.FiredAt = *fp.FiredAt
+	}
+	if fp.KeepFiringSince != nil {
+		state.KeepFiringSince = *fp.KeepFiringSince
+	}
+	return state
+}
+
+// timePtr 零值时间返回 nil
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
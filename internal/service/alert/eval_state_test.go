@@ -0,0 +1,165 @@
+package alert
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPendingSinceFromSamples(t *testing.T) {
+	end := time.Date(2026, 5, 5, 10, 10, 0, 0, time.UTC)
+	step := 15 * time.Second
+	above80 := func(v float64) bool { return v > 80 }
+
+	series := func(values ...float64) []RangeSample {
+		var samples []RangeSample
+		for i, v := range values {
+			samples = append(samples, RangeSample{
+				Time:  end.Add(-time.Duration(len(values)-1-i) * step),
+				Value: v,
+			})
+		}
+		return samples
+	}
+
+	tests := []struct {
+		name    string
+		samples []RangeSample
+		want    time.Time
+	}{
+		{
+			name:    "continuous hits start from first sample",
+			samples: series(90, 91, 92, 93),
+			want:    end.Add(-3 * step),
+		},
+		{
+			name:    "dip resets pending start",
+			samples: series(90, 70, 92, 93),
+			want:    end.Add(-1 * step),
+		},
+		{
+			name:    "latest sample not hitting",
+			samples: series(90, 91, 92, 50),
+			want:    time.Time{},
+		},
+		{
+			name:    "empty series",
+			samples: nil,
+			want:    time.Time{},
+		},
+		{
+			name: "stale series is ignored",
+			samples: []RangeSample{
+				{Time: end.Add(-10 * step), Value: 95},
+				{Time: end.Add(-9 * step), Value: 95},
+			},
+			want: time.Time{},
+		},
+		{
+			name: "gap in series breaks continuity",
+			samples: []RangeSample{
+				{Time: end.Add(-10 * step), Value: 95},
+				{Time: end.Add(-1 * step), Value: 95},
+				{Time: end, Value: 95},
+			},
+			want: end.Add(-1 * step),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pendingSinceFromSamples(tt.samples, above80, step, end)
+			if !got.Equal(tt.want) {
+				t.Errorf("pendingSinceFromSamples() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleDuration(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"", 0},
+		{"0", 0},
+		{"0s", 0},
+		{"10m", 10 * time.Minute},
+		{"1h", time.Hour},
+		{"90s", 90 * time.Second},
+	}
+	for _, tt := range tests {
+		got, err := ruleDuration(tt.in)
+		if err != nil {
+			t.Fatalf("ruleDuration(%q) error: %v", tt.in, err)
+		}
+		if got != tt.want {
+			t.Errorf("ruleDuration(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestKeepFiringTransition(t *testing.T) {
+	start := time.Date(2026, 5, 5, 10, 0, 0, 0, time.UTC)
+	dur := time.Minute
+	keepFor := 5 * time.Minute
+
+	// 首次命中进入 pending，持续满 for 后触发
+	state, fire := firingTransition(alertState{}, false, 90, start, dur)
+	if fire {
+		t.Fatal("first hit should stay pending")
+	}
+	state, fire = firingTransition(state, true, 91, start.Add(dur), dur)
+	if !fire {
+		t.Fatal("hit after for duration should fire")
+	}
+	state.FiredAt = start.Add(dur)
+
+	// 条件不再满足：进入 keep_firing_for 保持期，未满时长前不恢复
+	notHit := start.Add(2 * time.Minute)
+	state, resolve := resolveTransition(state, notHit, keepFor)
+	if resolve {
+		t.Fatal("should keep firing right after condition stops matching")
+	}
+	if !state.KeepFiringSince.Equal(notHit) {
+		t.Fatalf("KeepFiringSince = %v, want %v", state.KeepFiringSince, notHit)
+	}
+	state, resolve = resolveTransition(state, notHit.Add(keepFor-time.Second), keepFor)
+	if resolve || !state.KeepFiringSince.Equal(notHit) {
+		t.Fatalf("should still keep firing, resolve=%v keepFiringSince=%v", resolve, state.KeepFiringSince)
+	}
+
+	// 保持期内再次命中：取消计时且保持 firing
+	rehit := notHit.Add(time.Minute)
+	state, fire = firingTransition(state, true, 92, rehit, dur)
+	if !fire || !state.KeepFiringSince.IsZero() {
+		t.Fatalf("re-hit should cancel keep_firing_for, fire=%v keepFiringSince=%v", fire, state.KeepFiringSince)
+	}
+
+	// 再次不满足并超过保持期后恢复
+	stop := rehit.Add(time.Minute)
+	state, resolve = resolveTransition(state, stop, keepFor)
+	if resolve {
+		t.Fatal("keep_firing_for should restart after re-hit")
+	}
+	if _, resolve = resolveTransition(state, stop.Add(keepFor), keepFor); !resolve {
+		t.Fatal("should resolve once keep_firing_for elapsed")
+	}
+
+	// 未配置 keep_firing_for 时立即恢复
+	if _, resolve = resolveTransition(state, stop, 0); !resolve {
+		t.Fatal("should resolve immediately without keep_firing_for")
+	}
+}
+
+func TestValidateKeepFiringFor(t *testing.T) {
+	for _, s := range []string{"", "0", "0s", "30s", "5m", "1h"} {
+		if err := ValidateKeepFiringFor(s); err != nil {
+			t.Errorf("ValidateKeepFiringFor(%q) unexpected error: %v", s, err)
+		}
+	}
+	for _, s := range []string{"5", "abc", "5x", "m", "-5m"} {
+		if err := ValidateKeepFiringFor(s); err == nil {
+			t.Errorf("ValidateKeepFiringFor(%q) expected error", s)
+		}
+	}
+}
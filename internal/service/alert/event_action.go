@@ -0,0 +1,365 @@
+package alert
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	biz "github.com/ydcloud-dy/opshub/internal/biz/alert"
+	alertdata "github.com/ydcloud-dy/opshub/internal/data/alert"
+)
+
+// 一键处理链接
+const (
+	eventActionLinkTTL     = 72 * time.Hour // 链接有效期
+	eventActionLinkPath    = "/api/v1/alert/event-actions"
+	defaultSilenceDuration = time.Hour // 链接/IM 回调屏蔽时长
+)
+
+// eventActionLinkActions 允许通过链接与 IM 回调执行的操作
+var eventActionLinkActions = map[string]bool{
+	biz.EventActionAck:     true,
+	biz.EventActionUnack:   true,
+	biz.EventActionSilence: true,
+	biz.EventActionResolve: true,
+}
+
+// EventHandlePermission 通过 IM 回调处理告警所需的按钮权限（实时告警 - 手动处理）
+const EventHandlePermission = "alert:events:handle"
+
+var (
+	ErrEventAlreadyResolved = errors.New("告警已恢复")
+	ErrEventActionLink      = errors.New("处理链接无效或已过期")
+)
+
+// eventActionLinker 一键处理链接签名配置，未配置外部访问地址时不生成链接
+var eventActionLinker struct {
+	sync.RWMutex
+	baseURL string
+	key     []byte
+}
+
+// ConfigureEventActionLinks 配置一键处理链接（服务启动时调用）
+// baseURL 为平台外部访问地址，secret 用于派生签名密钥
+func ConfigureEventActionLinks(baseURL, secret string) {
+	sum := sha256.Sum256([]byte("alert-event-action:" + secret))
+	eventActionLinker.Lock()
+	defer eventActionLinker.Unlock()
+	eventActionLinker.baseURL = strings.TrimRight(baseURL, "/")
+	eventActionLinker.key = sum[:]
+}
+
+// signEventAction 计算链接签名：HMAC-SHA256(key, "eventID:action:expires")
+func signEventAction(eventID uint, action string, expires int64) string {
+	eventActionLinker.RLock()
+	key := eventActionLinker.key
+	eventActionLinker.RUnlock()
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%d:%s:%d", eventID, action, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// EventActionURL 生成事件一键处理链接，未配置外部地址时返回空
+func EventActionURL(eventID uint, action string, now time.Time) string {
+	eventActionLinker.RLock()
+	baseURL := eventActionLinker.baseURL
+	eventActionLinker.RUnlock()
+	if baseURL == "" || eventID == 0 || !eventActionLinkActions[action] {
+		return ""
+	}
+	expires := now.Add(eventActionLinkTTL).Unix()
+	q := url.Values{}
+	q.Set("event", strconv.FormatUint(uint64(eventID), 10))
+	q.Set("action", action)
+	q.Set("expires", strconv.FormatInt(expires, 10))
+	q.Set("sig", signEventAction(eventID, action, expires))
+	return baseURL + eventActionLinkPath + "?" + q.Encode()
+}
+
+// VerifyEventAction 校验一键处理链接参数
+func VerifyEventAction(eventID uint, action string, expires int64, sig string, now time.Time) error {
+	eventActionLinker.RLock()
+	configured := len(eventActionLinker.key) > 0
+	eventActionLinker.RUnlock()
+	if !configured || eventID == 0 || !eventActionLinkActions[action] || now.Unix() > expires {
+		return ErrEventActionLink
+	}
+	if !hmac.Equal([]byte(sig), []byte(signEventAction(eventID, action, expires))) {
+		return ErrEventActionLink
+	}
+	return nil
+}
+
+// EventActionCardKey 生成企业微信模板卡片按钮 key："action:eventID:expires:sig"
+// 与一键处理链接共用签名，回调时校验，避免伪造或篡改按钮 key 处理任意告警
+func EventActionCardKey(eventID uint, action string, now time.Time) string {
+	eventActionLinker.RLock()
+	configured := len(eventActionLinker.key) > 0
+	eventActionLinker.RUnlock()
+	if !configured || eventID == 0 || !eventActionLinkActions[action] {
+		return ""
+	}
+	expires := now.Add(eventActionLinkTTL).Unix()
+	return fmt.Sprintf("%s:%d:%d:%s", action, eventID, expires, signEventAction(eventID, action, expires))
+}
+
+// VerifyEventActionCardKey 校验模板卡片按钮 key，返回操作与事件ID
+func VerifyEventActionCardKey(key string, now time.Time) (string, uint, error) {
+	parts := strings.Split(key, ":")
+	if len(parts) != 4 {
+		return "", 0, ErrEventActionLink
+	}
+	eventID, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return "", 0, ErrEventActionLink
+	}
+	expires, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return "", 0, ErrEventActionLink
+	}
+	if err := VerifyEventAction(uint(eventID), parts[0], expires, parts[3], now); err != nil {
+		return "", 0, err
+	}
+	return parts[0], uint(eventID), nil
+}
+
+// eventActionAliases IM 命令别名
+var eventActionAliases = map[string]string{
+	"ack": biz.EventActionAck, "确认": biz.EventActionAck,
+	"unack": biz.EventActionUnack, "取消确认": biz.EventActionUnack,
+	"silence": biz.EventActionSilence, "屏蔽": biz.EventActionSilence,
+	"resolve": biz.EventActionResolve, "解决": biz.EventActionResolve, "恢复": biz.EventActionResolve,
+}
+
+// ParseEventActionCommand 解析 IM 回调中的处理命令，如 "@机器人 ack 123"、"确认 #123"
+func ParseEventActionCommand(text string) (string, uint, bool) {
+	var fields []string
+	for _, f := range strings.Fields(text) {
+		if !strings.HasPrefix(f, "@") {
+			fields = append(fields, f)
+		}
+	}
+	if len(fields) != 2 {
+		return "", 0, false
+	}
+	action, ok := eventActionAliases[strings.ToLower(fields[0])]
+	if !ok {
+		return "", 0, false
+	}
+	id, err := strconv.ParseUint(strings.TrimPrefix(fields[1], "#"), 10, 64)
+	if err != nil || id == 0 {
+		return "", 0, false
+	}
+	return action, uint(id), true
+}
+
+// EventActionRequest 事件操作参数
+type EventActionRequest struct {
+	Source     string
+	OperatorID uint   // 平台用户，链接为 0
+	Operator   string // 用户名；链接为客户端 IP，IM 回调为 "用户名(IM 用户标识)"
+	AssigneeID uint   // assign 时的处理人
+	Duration   time.Duration
+	Note       string
+}
+
+// EventActionService 告警确认、认领、指派等状态流转，每次操作记录到事件时间线
+type EventActionService struct {
+	db           *gorm.DB
+	timelineRepo *alertdata.EventTimelineRepo
+	onResolve    func(ctx context.Context, event *biz.AlertEvent)
+}
+
+// NewEventActionService 创建事件操作服务
+func NewEventActionService(db *gorm.DB) *EventActionService {
+	return &EventActionService{
+		db:           db,
+		timelineRepo: alertdata.NewEventTimelineRepo(db),
+	}
+}
+
+// SetResolveHook 设置人工解决事件后的回调（清理评估引擎中该序列的 firing 状态）
+func (s *EventActionService) SetResolveHook(fn func(ctx context.Context, event *biz.AlertEvent)) {
+	s.onResolve = fn
+}
+
+// Apply 对事件执行操作，返回更新后的事件；事件不存在时返回 gorm.ErrRecordNotFound
+func (s *EventActionService) Apply(ctx context.Context, eventID uint, action string, req EventActionRequest) (*biz.AlertEvent, error) {
+	var event *biz.AlertEvent
+	changed := false
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		eventRepo := alertdata.NewEventRepo(tx)
+		var err error
+		if event, err = eventRepo.GetByID(ctx, eventID); err != nil {
+			return err
+		}
+		entry, err := applyEventAction(event, action, &req, time.Now())
+		if err != nil {
+			return err
+		}
+		if entry == nil {
+			return nil // 状态未变化
+		}
+		if err := eventRepo.Update(ctx, event); err != nil {
+			return err
+		}
+		changed = true
+		return alertdata.NewEventTimelineRepo(tx).Create(ctx, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if changed && action == biz.EventActionResolve && s.onResolve != nil {
+		s.onResolve(ctx, event)
+	}
+	return event, nil
+}
+
+// IMOperator 将 IM 回调的用户映射为平台用户，并校验其拥有告警处理权限
+// mapping 为通道配置的 "IM 用户ID -> 平台用户名"，未配置映射的 IM 用户一律拒绝，
+// 避免 IM 侧可自行修改的用户ID 与平台用户名碰撞时被冒用
+func (s *EventActionService) IMOperator(ctx context.Context, imUserID string, mapping map[string]string) (uint, string, error) {
+	if imUserID == "" {
+		return 0, "", fmt.Errorf("无法识别 IM 用户身份")
+	}
+	username := mapping[imUserID]
+	if username == "" {
+		return 0, "", fmt.Errorf("IM 用户 %s 未配置平台用户映射", imUserID)
+	}
+	var user struct {
+		ID       uint
+		Username string
+	}
+	err := s.db.WithContext(ctx).Table("sys_user").Select("id, username").
+		Where("username = ? AND status = 1 AND deleted_at IS NULL", username).Take(&user).Error
+	if err != nil {
+		return 0, "", fmt.Errorf("IM 用户 %s 未绑定平台用户", imUserID)
+	}
+
+	// 超级管理员或角色拥有 alert:events:handle 按钮权限
+	var count int64
+	err = s.db.WithContext(ctx).Raw(`
+		SELECT COUNT(*) FROM sys_user_role sur
+		JOIN sys_role sr ON sr.id = sur.role_id AND sr.deleted_at IS NULL AND sr.status = 1
+		LEFT JOIN sys_role_menu srm ON srm.role_id = sr.id
+		LEFT JOIN sys_menu sm ON sm.id = srm.menu_id AND sm.deleted_at IS NULL AND sm.status = 1
+		WHERE sur.user_id = ? AND (sr.code = 'admin' OR sm.code = ?)
+	`, user.ID, EventHandlePermission).Scan(&count).Error
+	if err != nil {
+		return 0, "", fmt.Errorf("权限检查失败")
+	}
+	if count == 0 {
+		return 0, "", fmt.Errorf("用户 %s 无告警处理权限", user.Username)
+	}
+	return user.ID, user.Username, nil
+}
+
+// Record 仅记录时间线（状态已由调用方更新，如人工介入、升级通知）
+func (s *EventActionService) Record(ctx context.Context, eventID uint, action string, req EventActionRequest) error {
+	return s.timelineRepo.Create(ctx, &biz.AlertEventTimeline{
+		EventID:    eventID,
+		Action:     action,
+		Source:     req.Source,
+		OperatorID: req.OperatorID,
+		Operator:   req.Operator,
+		AssigneeID: req.AssigneeID,
+		Note:       req.Note,
+	})
+}
+
+// Timeline 查询事件时间线
+func (s *EventActionService) Timeline(ctx context.Context, eventID uint) ([]*biz.AlertEventTimeline, error) {
+	return s.timelineRepo.ListByEventID(ctx, eventID)
+}
+
+// applyEventAction 在内存中执行状态流转，返回需要记录的时间线；重复操作（如已确认再确认）返回 nil
+func applyEventAction(event *biz.AlertEvent, action string, req *EventActionRequest, now time.Time) (*biz.AlertEventTimeline, error) {
+	if event.Status == "resolved" {
+		return nil, ErrEventAlreadyResolved
+	}
+	entry := &biz.AlertEventTimeline{
+		EventID:    event.ID,
+		Action:     action,
+		Source:     req.Source,
+		OperatorID: req.OperatorID,
+		Operator:   req.Operator,
+		Note:       req.Note,
+	}
+	var operatorID *uint
+	if req.OperatorID > 0 {
+		id := req.OperatorID
+		operatorID = &id
+	}
+	ack := func() {
+		event.Acknowledged = true
+		event.AckedBy = operatorID
+		event.AckedAt = &now
+	}
+	assign := func(userID uint) {
+		event.AssigneeID = &userID
+		event.AssignedAt = &now
+		entry.AssigneeID = userID
+	}
+
+	switch action {
+	case biz.EventActionAck:
+		if event.Acknowledged {
+			return nil, nil
+		}
+		ack()
+	case biz.EventActionUnack:
+		if !event.Acknowledged {
+			return nil, fmt.Errorf("告警尚未确认")
+		}
+		event.Acknowledged = false
+		event.AckedBy = nil
+		event.AckedAt = nil
+	case biz.EventActionClaim:
+		if operatorID == nil {
+			return nil, fmt.Errorf("认领需要登录平台用户")
+		}
+		if event.AssigneeID != nil && *event.AssigneeID == req.OperatorID && event.Acknowledged {
+			return nil, nil
+		}
+		assign(req.OperatorID)
+		if !event.Acknowledged {
+			ack()
+		}
+	case biz.EventActionAssign:
+		if req.AssigneeID == 0 {
+			return nil, fmt.Errorf("请选择处理人")
+		}
+		assign(req.AssigneeID)
+	case biz.EventActionSilence:
+		dur := req.Duration
+		if dur <= 0 {
+			dur = defaultSilenceDuration
+		}
+		until := now.Add(dur)
+		event.Silenced = true
+		event.SilenceUntil = &until
+		event.SilencedAt = &now
+		event.SilenceReason = req.Note
+		if entry.Note == "" {
+			entry.Note = "屏蔽 " + dur.String()
+		}
+	case biz.EventActionResolve:
+		// 仅关闭事件；若规则条件仍满足，引擎会在下次评估时重新产生事件
+		event.Status = "resolved"
+		event.ResolveType = "manual"
+		event.ResolvedAt = &now
+	default:
+		return nil, fmt.Errorf("不支持的操作: %s", action)
+	}
+	return entry, nil
+}
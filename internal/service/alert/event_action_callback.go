@@ -0,0 +1,118 @@
+package alert
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// dingTalkCallbackMaxSkew 钉钉回调时间戳允许的最大偏差
+const dingTalkCallbackMaxSkew = time.Hour
+
+// DingTalkCallbackConfig 钉钉通道的机器人回调配置
+type DingTalkCallbackConfig struct {
+	AppSecret   string            `json:"appSecret"`   // 企业内部机器人 AppSecret，用于校验回调签名
+	UserMapping map[string]string `json:"userMapping"` // 钉钉 userid -> 平台用户名，未配置映射的用户不能处理告警
+}
+
+// WecomCallbackConfig 企业微信通道的应用回调配置
+type WecomCallbackConfig struct {
+	CorpID         string            `json:"corpId"`
+	CallbackToken  string            `json:"callbackToken"`
+	CallbackAESKey string            `json:"callbackAesKey"`
+	UserMapping    map[string]string `json:"userMapping"` // 企业微信 userid -> 平台用户名，未配置映射的用户不能处理告警
+}
+
+// ParseDingTalkCallbackConfig 解析钉钉通道回调配置
+func ParseDingTalkCallbackConfig(configJSON string) (*DingTalkCallbackConfig, error) {
+	var cfg DingTalkCallbackConfig
+	if err := json.Unmarshal([]byte(configJSON), &cfg); err != nil {
+		return nil, err
+	}
+	if cfg.AppSecret == "" {
+		return nil, fmt.Errorf("通道未配置机器人 AppSecret")
+	}
+	return &cfg, nil
+}
+
+// ParseWecomCallbackConfig 解析企业微信通道回调配置并创建加解密器
+func ParseWecomCallbackConfig(configJSON string) (*WecomCallbackConfig, *WecomCrypto, error) {
+	var cfg WecomCallbackConfig
+	if err := json.Unmarshal([]byte(configJSON), &cfg); err != nil {
+		return nil, nil, err
+	}
+	crypto, err := NewWecomCrypto(cfg.CallbackToken, cfg.CallbackAESKey, cfg.CorpID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &cfg, crypto, nil
+}
+
+// VerifyDingTalkCallback 校验钉钉机器人回调签名
+// 请求头 timestamp 为毫秒时间戳，sign = Base64(HmacSHA256(appSecret, timestamp + "\n" + appSecret))
+func VerifyDingTalkCallback(appSecret, timestamp, sign string, now time.Time) error {
+	ms, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("钉钉回调时间戳无效")
+	}
+	if d := now.Sub(time.UnixMilli(ms)); d > dingTalkCallbackMaxSkew || d < -dingTalkCallbackMaxSkew {
+		return fmt.Errorf("钉钉回调已过期")
+	}
+	if !hmac.Equal([]byte(sign), []byte(dingTalkSign(timestamp, appSecret))) {
+		return fmt.Errorf("钉钉回调签名校验失败")
+	}
+	return nil
+}
+
+// DingTalkCallbackMessage 钉钉机器人回调消息（群内 @机器人）
+type DingTalkCallbackMessage struct {
+	MsgType string `json:"msgtype"`
+	Text    struct {
+		Content string `json:"content"`
+	} `json:"text"`
+	SenderNick    string `json:"senderNick"`
+	SenderStaffID string `json:"senderStaffId"`
+}
+
+// Operator 操作人标识
+func (m *DingTalkCallbackMessage) Operator() string {
+	if m.SenderStaffID != "" {
+		return fmt.Sprintf("%s(%s)", m.SenderNick, m.SenderStaffID)
+	}
+	return m.SenderNick
+}
+
+// WecomEnvelope 企业微信回调外层消息
+type WecomEnvelope struct {
+	XMLName    xml.Name `xml:"xml"`
+	ToUserName string   `xml:"ToUserName"`
+	AgentID    string   `xml:"AgentID"`
+	Encrypt    string   `xml:"Encrypt"`
+}
+
+// WecomCallbackMessage 企业微信解密后的回调消息
+// 支持文本消息（Content 为命令）和模板卡片按钮事件（EventKey 为 EventActionCardKey 生成的签名 key）
+type WecomCallbackMessage struct {
+	XMLName      xml.Name `xml:"xml"`
+	FromUserName string   `xml:"FromUserName"`
+	MsgType      string   `xml:"MsgType"`
+	Content      string   `xml:"Content"`
+	Event        string   `xml:"Event"`
+	EventKey     string   `xml:"EventKey"`
+}
+
+// IsCardEvent 是否为模板卡片按钮点击事件
+func (m *WecomCallbackMessage) IsCardEvent() bool {
+	return m.MsgType == "event" && m.Event == "template_card_event"
+}
+
+// Command 回调中的处理命令文本
+func (m *WecomCallbackMessage) Command() string {
+	if m.MsgType == "text" {
+		return m.Content
+	}
+	return ""
+}
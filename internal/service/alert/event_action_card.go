@@ -0,0 +1,200 @@
+package alert
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	biz "github.com/ydcloud-dy/opshub/internal/biz/alert"
+)
+
+// wecomAPIBase 企业微信服务端 API 地址
+var wecomAPIBase = "https://qyapi.weixin.qq.com"
+
+// eventCardButtons 卡片按钮顺序与文案，与一键处理链接保持一致
+var eventCardButtons = []struct {
+	action string
+	text   string
+}{
+	{biz.EventActionAck, "确认"},
+	{biz.EventActionSilence, "屏蔽1小时"},
+	{biz.EventActionResolve, "解决"},
+}
+
+// WecomAppConfig 企业微信通道的应用消息配置，配置后告警额外以模板卡片发送给映射用户
+// 群机器人 Webhook 不支持按钮回调，卡片按钮点击事件经应用回调地址回传
+type WecomAppConfig struct {
+	CorpID      string            `json:"corpId"`
+	CorpSecret  string            `json:"corpSecret"`
+	AgentID     flexInt           `json:"agentId"`
+	UserMapping map[string]string `json:"userMapping"`
+}
+
+// cardRecipients 卡片接收人：用户映射中的企业微信 userid，未映射的用户无法处理告警
+func (c *WecomAppConfig) cardRecipients() []string {
+	users := make([]string, 0, len(c.UserMapping))
+	for userID := range c.UserMapping {
+		users = append(users, userID)
+	}
+	sort.Strings(users)
+	return users
+}
+
+// eventCardTitle 卡片标题：优先使用注解 title，否则为规则名
+func eventCardTitle(event *biz.AlertEvent) string {
+	var annotations map[string]string
+	_ = json.Unmarshal([]byte(event.Annotations), &annotations)
+	if title := annotations["title"]; title != "" {
+		return title
+	}
+	return event.RuleName
+}
+
+// wecomEventCard 构造企业微信按钮交互型模板卡片，按钮 key 为 EventActionCardKey 签名 key
+// 未配置签名密钥时返回 nil
+func wecomEventCard(event *biz.AlertEvent, now time.Time) map[string]interface{} {
+	buttons := make([]map[string]interface{}, 0, len(eventCardButtons))
+	for i, b := range eventCardButtons {
+		key := EventActionCardKey(event.ID, b.action, now)
+		if key == "" {
+			return nil
+		}
+		style := 2
+		if i == 0 {
+			style = 1
+		}
+		buttons = append(buttons, map[string]interface{}{"text": b.text, "style": style, "key": key})
+	}
+	return map[string]interface{}{
+		"card_type":  "button_interaction",
+		"source":     map[string]string{"desc": "OpsHub 告警"},
+		"main_title": map[string]string{"title": eventCardTitle(event), "desc": fmt.Sprintf("级别：%s", severityLabel(event.Severity))},
+		"horizontal_content_list": []map[string]string{
+			{"keyname": "规则", "value": event.RuleName},
+			{"keyname": "当前值", "value": fmt.Sprintf("%g", event.Value)},
+			{"keyname": "触发时间", "value": event.FiredAt.Format("2006-01-02 15:04:05")},
+		},
+		// task_id 同一应用内需唯一
+		"task_id":     fmt.Sprintf("alert-%d-%d", event.ID, now.UnixNano()),
+		"button_list": buttons,
+	}
+}
+
+// sendWecomEventCard 通过企业微信应用消息发送告警模板卡片
+// 未配置应用凭据、用户映射或签名密钥时跳过
+func sendWecomEventCard(configJSON string, event *biz.AlertEvent) error {
+	var cfg WecomAppConfig
+	if err := json.Unmarshal([]byte(configJSON), &cfg); err != nil {
+		return err
+	}
+	if event == nil || cfg.CorpID == "" || cfg.CorpSecret == "" || cfg.AgentID == 0 {
+		return nil
+	}
+	users := cfg.cardRecipients()
+	if len(users) == 0 {
+		return nil
+	}
+	card := wecomEventCard(event, time.Now())
+	if card == nil {
+		return nil
+	}
+	token, err := wecomAccessToken(cfg.CorpID, cfg.CorpSecret)
+	if err != nil {
+		return err
+	}
+	payload := map[string]interface{}{
+		"touser":        strings.Join(users, "|"),
+		"msgtype":       "template_card",
+		"agentid":       cfg.AgentID,
+		"template_card": card,
+	}
+	body, err := postJSONResponse(wecomAPIBase+"/cgi-bin/message/send?access_token="+url.QueryEscape(token), payload, nil)
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if json.Unmarshal(body, &resp) == nil && resp.ErrCode != 0 {
+		if resp.ErrCode == 40014 || resp.ErrCode == 42001 {
+			wecomTokens.invalidate(cfg.CorpID, cfg.CorpSecret)
+		}
+		return fmt.Errorf("企业微信卡片发送失败 %d: %s", resp.ErrCode, resp.ErrMsg)
+	}
+	return nil
+}
+
+// wecomTokens 企业微信 access_token 缓存，按 corpId+secret 区分
+var wecomTokens = &wecomTokenCache{tokens: map[string]wecomToken{}}
+
+type wecomToken struct {
+	value   string
+	expires time.Time
+}
+
+type wecomTokenCache struct {
+	sync.Mutex
+	tokens map[string]wecomToken
+}
+
+func (c *wecomTokenCache) invalidate(corpID, secret string) {
+	c.Lock()
+	defer c.Unlock()
+	delete(c.tokens, corpID+"\x00"+secret)
+}
+
+// wecomAccessToken 获取企业微信 access_token，过期前 5 分钟刷新
+func wecomAccessToken(corpID, secret string) (string, error) {
+	key := corpID + "\x00" + secret
+	wecomTokens.Lock()
+	defer wecomTokens.Unlock()
+	if t, ok := wecomTokens.tokens[key]; ok && time.Now().Before(t.expires) {
+		return t.value, nil
+	}
+	q := url.Values{}
+	q.Set("corpid", corpID)
+	q.Set("corpsecret", secret)
+	body, err := doWebhookRequest("GET", wecomAPIBase+"/cgi-bin/gettoken?"+q.Encode(), nil, nil)
+	if err != nil {
+		return "", err
+	}
+	var resp struct {
+		ErrCode     int    `json:"errcode"`
+		ErrMsg      string `json:"errmsg"`
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", err
+	}
+	if resp.ErrCode != 0 || resp.AccessToken == "" {
+		return "", fmt.Errorf("获取企业微信 access_token 失败 %d: %s", resp.ErrCode, resp.ErrMsg)
+	}
+	ttl := time.Duration(resp.ExpiresIn)*time.Second - 5*time.Minute
+	wecomTokens.tokens[key] = wecomToken{value: resp.AccessToken, expires: time.Now().Add(ttl)}
+	return resp.AccessToken, nil
+}
+
+// dingTalkEventCard 构造钉钉独立跳转 ActionCard，按钮为签名的一键处理链接
+// 未配置外部访问地址时返回 nil，退回普通 markdown 消息
+func dingTalkEventCard(msg string, event *biz.AlertEvent, now time.Time) map[string]interface{} {
+	btns := make([]map[string]string, 0, len(eventCardButtons))
+	for _, b := range eventCardButtons {
+		link := EventActionURL(event.ID, b.action, now)
+		if link == "" {
+			return nil
+		}
+		btns = append(btns, map[string]string{"title": b.text, "actionURL": link})
+	}
+	return map[string]interface{}{
+		"title":          "SreHub 告警通知",
+		"text":           msg,
+		"btnOrientation": "1",
+		"btns":           btns,
+	}
+}
@@ -0,0 +1,249 @@
+package alert
+
+import (
+	"context"
+	"encoding/xml"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
+	"go.uber.org/zap"
+
+	biz "github.com/ydcloud-dy/opshub/internal/biz/alert"
+	"github.com/ydcloud-dy/opshub/internal/biz/rbac"
+	"github.com/ydcloud-dy/opshub/internal/testutil"
+)
+
+func TestEventActionURL_SignAndVerify(t *testing.T) {
+	ConfigureEventActionLinks("https://ops.example.com/", "secret")
+	defer ConfigureEventActionLinks("", "")
+	now := time.Now()
+
+	link := EventActionURL(42, biz.EventActionAck, now)
+	if !strings.HasPrefix(link, "https://ops.example.com/api/v1/alert/event-actions?") {
+		t.Fatalf("EventActionURL() = %s", link)
+	}
+	u, _ := url.Parse(link)
+	q := u.Query()
+	expires, _ := strconv.ParseInt(q.Get("expires"), 10, 64)
+	if err := VerifyEventAction(42, q.Get("action"), expires, q.Get("sig"), now); err != nil {
+		t.Fatalf("VerifyEventAction() error: %v", err)
+	}
+	if err := VerifyEventAction(43, q.Get("action"), expires, q.Get("sig"), now); err == nil {
+		t.Error("signature accepted for another event")
+	}
+	if err := VerifyEventAction(42, biz.EventActionResolve, expires, q.Get("sig"), now); err == nil {
+		t.Error("signature accepted for another action")
+	}
+	if err := VerifyEventAction(42, q.Get("action"), expires, q.Get("sig"), now.Add(eventActionLinkTTL+time.Minute)); err == nil {
+		t.Error("expired link accepted")
+	}
+	if EventActionURL(42, biz.EventActionClaim, now) != "" {
+		t.Error("claim should not be available as a link")
+	}
+
+	// 链接随模板输出，已恢复的告警不附带链接
+	event := &biz.AlertEvent{ID: 42, Status: "firing", RuleName: "CPU 高"}
+	if got := renderTemplate("{{.ActionLinks}}", event, nil); !strings.Contains(got, "[确认](https://ops.example.com/") {
+		t.Errorf("firing ActionLinks = %q", got)
+	}
+	event.Status = "resolved"
+	if got := renderTemplate("{{.AckURL}}", event, nil); got != "" {
+		t.Errorf("resolved AckURL = %q, want empty", got)
+	}
+
+	ConfigureEventActionLinks("", "secret")
+	if EventActionURL(42, biz.EventActionAck, now) != "" {
+		t.Error("link generated without external url")
+	}
+}
+
+func TestParseEventActionCommand(t *testing.T) {
+	tests := []struct {
+		text   string
+		action string
+		id     uint
+		ok     bool
+	}{
+		{"@告警机器人 ack 12", biz.EventActionAck, 12, true},
+		{" 确认 #7 ", biz.EventActionAck, 7, true},
+		{"silence 3", biz.EventActionSilence, 3, true},
+		{"resolve 3", biz.EventActionResolve, 3, true},
+		{"claim 3", "", 0, false},
+		{"ack", "", 0, false},
+		{"ack abc", "", 0, false},
+		{"ack 1 2", "", 0, false},
+	}
+	for _, tt := range tests {
+		action, id, ok := ParseEventActionCommand(tt.text)
+		if action != tt.action || id != tt.id || ok != tt.ok {
+			t.Errorf("ParseEventActionCommand(%q) = %s,%d,%v want %s,%d,%v", tt.text, action, id, ok, tt.action, tt.id, tt.ok)
+		}
+	}
+}
+
+func TestEventActionCardKey(t *testing.T) {
+	ConfigureEventActionLinks("", "secret")
+	defer ConfigureEventActionLinks("", "")
+	now := time.Now()
+
+	key := EventActionCardKey(15, biz.EventActionAck, now)
+	plain := []byte("<xml><FromUserName>zhangsan</FromUserName><MsgType>event</MsgType><Event>template_card_event</Event><EventKey>" + key + "</EventKey></xml>")
+	var msg WecomCallbackMessage
+	if err := xml.Unmarshal(plain, &msg); err != nil || !msg.IsCardEvent() || msg.Command() != "" {
+		t.Fatalf("card event = %+v, %v", msg, err)
+	}
+	action, eventID, err := VerifyEventActionCardKey(msg.EventKey, now)
+	if err != nil || action != biz.EventActionAck || eventID != 15 {
+		t.Fatalf("VerifyEventActionCardKey() = %s,%d,%v", action, eventID, err)
+	}
+	// 篡改事件ID、未签名的旧格式 key、过期 key 均拒绝
+	if _, _, err := VerifyEventActionCardKey(strings.Replace(key, ":15:", ":16:", 1), now); err == nil {
+		t.Error("tampered card key accepted")
+	}
+	if _, _, err := VerifyEventActionCardKey("ack:15", now); err == nil {
+		t.Error("unsigned card key accepted")
+	}
+	if _, _, err := VerifyEventActionCardKey(key, now.Add(eventActionLinkTTL+time.Minute)); err == nil {
+		t.Error("expired card key accepted")
+	}
+	if EventActionCardKey(15, biz.EventActionClaim, now) != "" {
+		t.Error("claim should not be available as a card button")
+	}
+}
+
+func TestEventActionService_Apply(t *testing.T) {
+	appLogger.Log = zap.NewNop()
+	db := testutil.NewDB(t, &biz.AlertEvent{}, &biz.AlertEventTimeline{})
+	svc := NewEventActionService(db)
+	var resolved []uint
+	svc.SetResolveHook(func(_ context.Context, e *biz.AlertEvent) { resolved = append(resolved, e.ID) })
+	ctx := context.Background()
+	event := &biz.AlertEvent{RuleName: "磁盘满", Status: "firing", FiredAt: time.Now()}
+	db.Create(event)
+	web := func(userID uint) EventActionRequest {
+		return EventActionRequest{Source: biz.EventActionSourceWeb, OperatorID: userID, Operator: "u" + strconv.Itoa(int(userID))}
+	}
+
+	if _, err := svc.Apply(ctx, event.ID, biz.EventActionUnack, web(1)); err == nil {
+		t.Error("unack before ack should fail")
+	}
+	got, err := svc.Apply(ctx, event.ID, biz.EventActionAck, EventActionRequest{Source: biz.EventActionSourceLink})
+	if err != nil || !got.Acknowledged || got.AckedBy != nil || got.Status != "firing" {
+		t.Fatalf("ack = %+v, %v", got, err)
+	}
+	// 重复确认不产生时间线
+	svc.Apply(ctx, event.ID, biz.EventActionAck, web(1))
+
+	if _, err := svc.Apply(ctx, event.ID, biz.EventActionClaim, EventActionRequest{Source: biz.EventActionSourceDingTalk}); err == nil {
+		t.Error("claim without platform user should fail")
+	}
+	got, err = svc.Apply(ctx, event.ID, biz.EventActionClaim, web(5))
+	if err != nil || got.AssigneeID == nil || *got.AssigneeID != 5 {
+		t.Fatalf("claim = %+v, %v", got, err)
+	}
+	got, err = svc.Apply(ctx, event.ID, biz.EventActionAssign, EventActionRequest{Source: biz.EventActionSourceWeb, OperatorID: 5, AssigneeID: 9, Note: "交接"})
+	if err != nil || *got.AssigneeID != 9 {
+		t.Fatalf("assign = %+v, %v", got, err)
+	}
+	got, err = svc.Apply(ctx, event.ID, biz.EventActionSilence, EventActionRequest{Source: biz.EventActionSourceLink})
+	if err != nil || !got.Silenced || got.SilenceUntil == nil || got.SilenceUntil.Sub(*got.SilencedAt) != time.Hour {
+		t.Fatalf("silence = %+v, %v", got, err)
+	}
+	got, err = svc.Apply(ctx, event.ID, biz.EventActionResolve, web(9))
+	if err != nil || got.Status != "resolved" || got.ResolveType != "manual" || got.ResolvedAt == nil {
+		t.Fatalf("resolve = %+v, %v", got, err)
+	}
+	if _, err := svc.Apply(ctx, event.ID, biz.EventActionAck, web(9)); err != ErrEventAlreadyResolved {
+		t.Errorf("ack after resolve error = %v, want ErrEventAlreadyResolved", err)
+	}
+	// 手动解决后通知评估引擎清理状态，仅触发一次
+	if len(resolved) != 1 || resolved[0] != event.ID {
+		t.Errorf("resolve hook calls = %v, want [%d]", resolved, event.ID)
+	}
+
+	timeline, _ := svc.Timeline(ctx, event.ID)
+	var actions []string
+	for _, e := range timeline {
+		actions = append(actions, e.Action+"/"+e.Source)
+	}
+	want := "ack/link claim/web assign/web silence/link resolve/web"
+	if strings.Join(actions, " ") != want {
+		t.Errorf("timeline = %v, want %s", actions, want)
+	}
+	if timeline[2].AssigneeID != 9 || timeline[2].Note != "交接" {
+		t.Errorf("assign entry = %+v", timeline[2])
+	}
+}
+
+func TestEventActionService_IMOperator(t *testing.T) {
+	appLogger.Log = zap.NewNop()
+	db := testutil.NewDB(t, &rbac.SysUser{}, &rbac.SysRole{}, &rbac.SysMenu{}, &rbac.SysUserRole{}, &rbac.SysRoleMenu{})
+	handleMenu := &rbac.SysMenu{Name: "手动处理", Code: EventHandlePermission, Type: 3, Status: 1}
+	viewMenu := &rbac.SysMenu{Name: "查看", Code: "alert:events:list", Type: 3, Status: 1}
+	db.Create(handleMenu)
+	db.Create(viewMenu)
+	oncall := &rbac.SysRole{Name: "值班", Code: "oncall", Status: 1}
+	viewer := &rbac.SysRole{Name: "只读", Code: "viewer", Status: 1}
+	db.Create(oncall)
+	db.Create(viewer)
+	db.Create(&rbac.SysRoleMenu{RoleID: oncall.ID, MenuID: handleMenu.ID})
+	db.Create(&rbac.SysRoleMenu{RoleID: viewer.ID, MenuID: viewMenu.ID})
+	zhangsan := &rbac.SysUser{Username: "zhangsan", Password: "x", Status: 1}
+	lisi := &rbac.SysUser{Username: "lisi", Password: "x", Status: 1}
+	db.Create(zhangsan)
+	db.Create(lisi)
+	db.Create(&rbac.SysUserRole{UserID: zhangsan.ID, RoleID: oncall.ID})
+	db.Create(&rbac.SysUserRole{UserID: lisi.ID, RoleID: viewer.ID})
+
+	svc := NewEventActionService(db)
+	ctx := context.Background()
+	mapping := map[string]string{"ZS001": "zhangsan", "LS001": "lisi"}
+	if id, name, err := svc.IMOperator(ctx, "ZS001", mapping); err != nil || id != zhangsan.ID || name != "zhangsan" {
+		t.Errorf("mapped operator = %d,%s,%v", id, name, err)
+	}
+	// 未配置映射时不按同名平台用户匹配
+	if _, _, err := svc.IMOperator(ctx, "zhangsan", nil); err == nil {
+		t.Error("unmapped same-name IM user accepted")
+	}
+	if _, _, err := svc.IMOperator(ctx, "LS001", mapping); err == nil {
+		t.Error("user without handle permission accepted")
+	}
+	if _, _, err := svc.IMOperator(ctx, "stranger", nil); err == nil {
+		t.Error("unmapped IM user accepted")
+	}
+	if _, _, err := svc.IMOperator(ctx, "", nil); err == nil {
+		t.Error("empty IM user accepted")
+	}
+}
+
+func TestWecomCrypto_RoundTrip(t *testing.T) {
+	aesKey := "jWmYm7qr5nMoAUwZRjGtBxmz3KA1tkAj3ykkR6q2B2C"
+	c, err := NewWecomCrypto("token", aesKey, "wx5823bf96d3bd56c7")
+	if err != nil {
+		t.Fatalf("NewWecomCrypto() error: %v", err)
+	}
+	msg := []byte("<xml><FromUserName>zhangsan</FromUserName><MsgType>event</MsgType><Event>template_card_event</Event><EventKey>ack:15</EventKey></xml>")
+	encrypt, err := c.Encrypt(msg)
+	if err != nil {
+		t.Fatalf("Encrypt() error: %v", err)
+	}
+	sig := c.Signature("1409659813", "1372623149", encrypt)
+	plain, err := c.Decrypt(sig, "1409659813", "1372623149", encrypt)
+	if err != nil || string(plain) != string(msg) {
+		t.Fatalf("Decrypt() = %s, %v", plain, err)
+	}
+	if _, err := c.Decrypt(sig, "1409659814", "1372623149", encrypt); err == nil {
+		t.Error("tampered timestamp accepted")
+	}
+	other, _ := NewWecomCrypto("token", aesKey, "another-corp")
+	if _, err := other.Decrypt(sig, "1409659813", "1372623149", encrypt); err == nil {
+		t.Error("mismatched corp id accepted")
+	}
+	if _, err := NewWecomCrypto("token", "short", ""); err == nil {
+		t.Error("invalid aes key accepted")
+	}
+}
@@ -20,27 +20,31 @@ type GroupService struct {
 	groupRepo  *alertdata.GroupRuleRepo
 	cacheRepo  *alertdata.GroupCacheRepo
 	eventRepo  *alertdata.EventRepo
-	notifySvc  *NotifyService
+	notifyQueue *NotifyQueue
 	subRepo    *alertdata.SubscriptionRepo
 	subRuleRepo *alertdata.SubscriptionRuleRepo
 	subChannelRepo *alertdata.SubscriptionChannelRepo
 	subUserRepo *alertdata.SubscriptionUserRepo
 	channelRepo *alertdata.ChannelRepo
+	oncall      *OnCallService
+	escalation  *EscalationService
 }
 
 // NewGroupService 创建分组服务
-func NewGroupService(db *gorm.DB, notifySvc *NotifyService) *GroupService {
+func NewGroupService(db *gorm.DB, notifyQueue *NotifyQueue, escalation *EscalationService) *GroupService {
 	return &GroupService{
 		db:         db,
 		groupRepo:  alertdata.NewGroupRuleRepo(db),
 		cacheRepo:  alertdata.NewGroupCacheRepo(db),
 		eventRepo:  alertdata.NewEventRepo(db),
-		notifySvc:  notifySvc,
+		notifyQueue: notifyQueue,
 		subRepo:    alertdata.NewSubscriptionRepo(db),
 		subRuleRepo: alertdata.NewSubscriptionRuleRepo(db),
 		subChannelRepo: alertdata.NewSubscriptionChannelRepo(db),
 		subUserRepo: alertdata.NewSubscriptionUserRepo(db),
 		channelRepo: alertdata.NewChannelRepo(db),
+		oncall:      NewOnCallService(db),
+		escalation:  escalation,
 	}
 }
 
@@ -189,7 +193,12 @@ func (s *GroupService) sendGroupCache(ctx context.Context, cacheID uint) {
 
 	var phones []string
 	var userIDs []uint
-	if perRuleUsers := parseUintList(sr.UserIDs); len(perRuleUsers) > 0 {
+	sub, subErr := s.subRepo.GetByID(ctx, cache.SubscriptionID)
+	if subErr == nil && sub.ScheduleID > 0 {
+		// 订阅关联值班表：通知当前值班人
+		userIDs = s.oncall.OnCallUserIDs(ctx, sub.ScheduleID, time.Now())
+		phones = queryUserPhones(ctx, s.db, userIDs)
+	} else if perRuleUsers := parseUintList(sr.UserIDs); len(perRuleUsers) > 0 {
 		userIDs = perRuleUsers
 		phones = getUserPhones(ctx, s.db, perRuleUsers)
 	} else {
@@ -205,7 +214,16 @@ func (s *GroupService) sendGroupCache(ctx context.Context, cacheID uint) {
 			if !ch.Enabled {
 				continue
 			}
-			go s.notifySvc.Send(ctx, ch, event, isResolve, phones, userIDs)
+			if _, err := s.notifyQueue.Enqueue(ctx, event, ch, cache.SubscriptionID, 0, isResolve, phones, userIDs); err != nil {
+				appLogger.Error("分组通知入队失败", zap.String("channel", ch.Name), zap.Uint("eventID", event.ID), zap.Error(err))
+			}
+		}
+
+		// 与直接发送一致：分组通知发出后开始升级计时
+		if !isResolve && subErr == nil && sub.EscalationPolicyID > 0 && s.escalation != nil {
+			if err := s.escalation.Begin(ctx, event, sub, channelIDs, time.Now()); err != nil {
+				appLogger.Error("启动告警升级失败", zap.Uint("eventID", event.ID), zap.Uint("subscriptionID", sub.ID), zap.Error(err))
+			}
 		}
 	}
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	biz "github.com/ydcloud-dy/opshub/internal/biz/alert"
 	alertdata "github.com/ydcloud-dy/opshub/internal/data/alert"
@@ -68,33 +69,17 @@ func (s *InhibitService) ShouldInhibit(ctx context.Context, event *biz.AlertEven
 }
 
 // matchTarget 检查告警是否匹配目标条件
+// matchersJSON 支持旧格式 {"severity": "warning"} 及匹配器数组 / 选择器，severity、ruleName 取事件字段
 func (s *InhibitService) matchTarget(event *biz.AlertEvent, matchersJSON string) bool {
-	var matchers map[string]string
-	if err := json.Unmarshal([]byte(matchersJSON), &matchers); err != nil {
+	// 未配置条件的规则不生效（与旧版解析失败即不匹配的行为一致）
+	if strings.TrimSpace(matchersJSON) == "" {
 		return false
 	}
-
-	eventLabels := make(map[string]string)
-	json.Unmarshal([]byte(event.Labels), &eventLabels)
-
-	for key, value := range matchers {
-		switch key {
-		case "severity":
-			if event.Severity != value {
-				return false
-			}
-		case "ruleName":
-			if event.RuleName != value {
-				return false
-			}
-		default:
-			if eventLabels[key] != value {
-				return false
-			}
-		}
+	matchers, err := cachedLabelMatchers(matchersJSON)
+	if err != nil {
+		return false
 	}
-
-	return true
+	return matchers.Matches(EventMatchLabels(event))
 }
 
 // matchSource 检查告警是否匹配源条件
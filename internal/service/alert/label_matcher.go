@@ -1,28 +1,30 @@
 package alert
 
 import (
-	"context"
 	"encoding/json"
+	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+
+	biz "github.com/ydcloud-dy/opshub/internal/biz/alert"
 )
 
-// MatchLabels 检查告警标签是否匹配屏蔽规则标签（子集匹配）
-// eventLabelsJSON: 告警的完整标签 {"job":"prometheus","instance":"localhost:9090","pod":"pod-123"}
-// ruleLabelsJSON: 屏蔽规则的标签（用户可能移除了部分标签）{"job":"prometheus","instance":"localhost:9090"}
-// 返回：告警标签是否包含规则标签的所有键值对
+// MatchLabels 检查告警标签是否匹配屏蔽规则标签
+// ruleLabelsJSON 兼容两种存储格式：
+//   - 旧格式 JSON 对象 {"job":"prometheus","instance":"localhost:9090"}：子集精确匹配（用户可能移除了部分标签）
+//   - 匹配器数组 [{"key":"job","op":"=~","value":"prom.*"}] 或 {job=~"prom.*"} 形式的选择器
+//
+// 规则标签无法解析时不匹配（不屏蔽）
 func MatchLabels(eventLabelsJSON string, ruleLabelsJSON string) bool {
-	eventMap := parseLabels(eventLabelsJSON)
-	ruleMap := parseLabels(ruleLabelsJSON)
-
-	// 规则标签必须是告警标签的子集
-	for k, v := range ruleMap {
-		if eventMap[k] != v {
-			return false
-		}
+	matchers, err := cachedLabelMatchers(ruleLabelsJSON)
+	if err != nil {
+		return false
 	}
-	return true
+	return matchers.Matches(parseLabels(eventLabelsJSON))
 }
 
 // parseLabels 解析 JSON 标签为 map
@@ -38,11 +40,16 @@ func parseLabels(labelsJSON string) map[string]string {
 }
 
 // MatchLabelFilter 支持模糊搜索（用于前端搜索功能）
-// filter: "job=prome*" 或 "instance=*:9090"
+// filter: "job=prome*"、"instance=*:9090"，也支持匹配器语法 job=~"prom.*",env!="dev"
 func MatchLabelFilter(eventLabelsJSON string, filter string) bool {
 	if filter == "" {
 		return true
 	}
+	labels := parseLabels(eventLabelsJSON)
+
+	if matchers, err := ParseLabelMatchers(filter); err == nil && !isWildcardFilter(filter) {
+		return matchers.Matches(labels)
+	}
 
 	// 解析 key=value 格式
 	parts := strings.SplitN(filter, "=", 2)
@@ -53,7 +60,6 @@ func MatchLabelFilter(eventLabelsJSON string, filter string) bool {
 	key := strings.TrimSpace(parts[0])
 	pattern := strings.TrimSpace(parts[1])
 
-	labels := parseLabels(eventLabelsJSON)
 	value, exists := labels[key]
 	if !exists {
 		return false
@@ -63,6 +69,16 @@ func MatchLabelFilter(eventLabelsJSON string, filter string) bool {
 	return matchWildcard(value, pattern)
 }
 
+// isWildcardFilter 是否为 key=value* 形式的通配符过滤（未加引号的值中含 *）
+func isWildcardFilter(filter string) bool {
+	parts := strings.SplitN(filter, "=", 2)
+	if len(parts) != 2 || strings.ContainsAny(parts[0], "!~{") {
+		return false
+	}
+	value := strings.TrimSpace(parts[1])
+	return strings.Contains(value, "*") && !strings.HasPrefix(value, "~") && !strings.HasPrefix(value, `"`)
+}
+
 // matchWildcard 通配符匹配
 func matchWildcard(value, pattern string) bool {
 	if pattern == "*" {
@@ -88,66 +104,276 @@ func matchWildcard(value, pattern string) bool {
 	return value == pattern
 }
 
-var _ context.Context // 避免未使用导入错误
+// 标签匹配运算符（与 Prometheus / Alertmanager 一致）
+const (
+	MatchEqual     = "="
+	MatchNotEqual  = "!="
+	MatchRegexp    = "=~"
+	MatchNotRegexp = "!~"
+)
 
-// LabelMatcher 标签匹配器（用于订阅规则）
+// LabelMatcher 标签匹配器（屏蔽、抑制、订阅规则及巡检过滤共用）
+// 不存在的标签按空字符串参与匹配；正则为 RE2 语法并整体锚定（等价于 ^(?:value)$）
 type LabelMatcher struct {
 	Key   string `json:"key"`
 	Op    string `json:"op"` // =, !=, =~, !~
 	Value string `json:"value"`
+
+	re *regexp.Regexp
 }
 
-// MatchSubscriptionLabels 检查告警标签是否匹配订阅规则的标签匹配器
-func MatchSubscriptionLabels(eventLabelsJSON string, matchersJSON string) bool {
-	if matchersJSON == "" {
-		return true
+// NewLabelMatcher 创建并校验标签匹配器
+func NewLabelMatcher(key, op, value string) (*LabelMatcher, error) {
+	m := &LabelMatcher{Key: strings.TrimSpace(key), Op: op, Value: value}
+	if m.Key == "" {
+		return nil, fmt.Errorf("标签匹配器缺少标签名")
 	}
+	switch op {
+	case MatchEqual, MatchNotEqual:
+	case MatchRegexp, MatchNotRegexp:
+		re, err := regexp.Compile("^(?:" + value + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("标签匹配器正则表达式无效: %s", value)
+		}
+		m.re = re
+	default:
+		return nil, fmt.Errorf("标签匹配器操作符无效: %s", op)
+	}
+	return m, nil
+}
 
-	var matchers []LabelMatcher
-	if err := json.Unmarshal([]byte(matchersJSON), &matchers); err != nil {
-		return true
+// Matches 判断标签值是否满足匹配器
+func (m *LabelMatcher) Matches(value string) bool {
+	switch m.Op {
+	case MatchEqual:
+		return value == m.Value
+	case MatchNotEqual:
+		return value != m.Value
+	case MatchRegexp:
+		return m.re != nil && m.re.MatchString(value)
+	case MatchNotRegexp:
+		return m.re != nil && !m.re.MatchString(value)
 	}
+	return false
+}
 
-	if len(matchers) == 0 {
-		return true
+// String 返回 key=~"value" 形式的文本
+func (m *LabelMatcher) String() string {
+	return m.Key + m.Op + strconv.Quote(m.Value)
+}
+
+// LabelMatchers 匹配器集合，全部满足才算匹配；空集合匹配所有告警
+type LabelMatchers []*LabelMatcher
+
+// Matches 判断标签集合是否满足全部匹配器
+func (ms LabelMatchers) Matches(labels map[string]string) bool {
+	for _, m := range ms {
+		if !m.Matches(labels[m.Key]) {
+			return false
+		}
 	}
+	return true
+}
 
-	labels := parseLabels(eventLabelsJSON)
+// String 返回 {k="v", k2=~"v2"} 形式的选择器文本
+func (ms LabelMatchers) String() string {
+	parts := make([]string, 0, len(ms))
+	for _, m := range ms {
+		parts = append(parts, m.String())
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
 
-	for _, m := range matchers {
-		labelValue, exists := labels[m.Key]
+// ParseLabelMatchers 解析匹配器，兼容已存储的各种格式：
+//   - 空串、null、{}、[]：空集合
+//   - JSON 对象 {"k":"v"}：旧格式，逐个精确匹配
+//   - JSON 数组 [{"key":"k","op":"=~","value":"v"}]，op 缺省为 =；
+//     也接受 Alertmanager 格式 [{"name":"k","value":"v","isRegex":true,"isEqual":false}]
+//   - 选择器文本 {k="v", k2!~"v2"}（花括号可省略），可以是 JSON 字符串
+func ParseLabelMatchers(s string) (LabelMatchers, error) {
+	s = strings.TrimSpace(s)
+	switch s {
+	case "", "null", "{}", "[]":
+		return nil, nil
+	}
 
-		switch m.Op {
-		case "=":
-			if !exists || labelValue != m.Value {
-				return false
+	// JSON 字符串包裹的选择器或匹配器 JSON
+	if strings.HasPrefix(s, `"`) {
+		var inner string
+		if err := json.Unmarshal([]byte(s), &inner); err != nil {
+			return nil, fmt.Errorf("标签匹配器格式错误: %w", err)
+		}
+		return ParseLabelMatchers(inner)
+	}
+
+	if strings.HasPrefix(s, "[") {
+		var items []struct {
+			Key     string `json:"key"`
+			Name    string `json:"name"`
+			Op      string `json:"op"`
+			Value   string `json:"value"`
+			IsRegex bool   `json:"isRegex"`
+			IsEqual *bool  `json:"isEqual"`
+		}
+		if err := json.Unmarshal([]byte(s), &items); err != nil {
+			return nil, fmt.Errorf("标签匹配器格式错误: %w", err)
+		}
+		matchers := make(LabelMatchers, 0, len(items))
+		for _, it := range items {
+			key, op := it.Key, it.Op
+			if key == "" {
+				key = it.Name
 			}
-		case "!=":
-			if exists && labelValue == m.Value {
-				return false
+			if op == "" {
+				// Alertmanager 格式：isRegex / isEqual 组合出运算符
+				negative := it.IsEqual != nil && !*it.IsEqual
+				switch {
+				case it.IsRegex && negative:
+					op = MatchNotRegexp
+				case it.IsRegex:
+					op = MatchRegexp
+				case negative:
+					op = MatchNotEqual
+				default:
+					op = MatchEqual
+				}
 			}
-		case "=~":
-			if !exists {
-				return false
+			m, err := NewLabelMatcher(key, op, it.Value)
+			if err != nil {
+				return nil, err
 			}
-			matched, err := regexp.MatchString(m.Value, labelValue)
-			if err != nil || !matched {
-				return false
+			matchers = append(matchers, m)
+		}
+		return matchers, nil
+	}
+
+	if strings.HasPrefix(s, "{") {
+		var legacy map[string]string
+		if err := json.Unmarshal([]byte(s), &legacy); err == nil {
+			keys := make([]string, 0, len(legacy))
+			for k := range legacy {
+				keys = append(keys, k)
 			}
-		case "!~":
-			if !exists {
-				continue
+			sort.Strings(keys)
+			matchers := make(LabelMatchers, 0, len(keys))
+			for _, k := range keys {
+				m, err := NewLabelMatcher(k, MatchEqual, legacy[k])
+				if err != nil {
+					return nil, err
+				}
+				matchers = append(matchers, m)
 			}
-			matched, err := regexp.MatchString(m.Value, labelValue)
-			if err != nil || matched {
-				return false
+			return matchers, nil
+		}
+	}
+	return parseLabelSelector(s)
+}
+
+// parseLabelSelector 解析 {k="v", k2=~"v2"} 形式的选择器，值可用双引号或不加引号（不含逗号）
+func parseLabelSelector(s string) (LabelMatchers, error) {
+	if strings.HasPrefix(s, "{") {
+		if !strings.HasSuffix(s, "}") {
+			return nil, fmt.Errorf("标签匹配器格式错误: 缺少 }")
+		}
+		s = s[1 : len(s)-1]
+	}
+	var matchers LabelMatchers
+	for rest := strings.TrimSpace(s); rest != ""; {
+		i := strings.IndexAny(rest, "=!")
+		if i <= 0 {
+			return nil, fmt.Errorf("标签匹配器格式错误: %s", rest)
+		}
+		key := strings.TrimSpace(rest[:i])
+		rest = rest[i:]
+		var op string
+		for _, candidate := range []string{MatchRegexp, MatchNotRegexp, MatchNotEqual, MatchEqual} {
+			if strings.HasPrefix(rest, candidate) {
+				op = candidate
+				break
 			}
-		default:
-			return false
+		}
+		if op == "" {
+			return nil, fmt.Errorf("标签匹配器操作符无效: %s", rest)
+		}
+		rest = strings.TrimSpace(rest[len(op):])
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			quoted, err := strconv.QuotedPrefix(rest)
+			if err != nil {
+				return nil, fmt.Errorf("标签 %s 的值引号不匹配", key)
+			}
+			value, _ = strconv.Unquote(quoted)
+			rest = strings.TrimSpace(rest[len(quoted):])
+		} else {
+			end := strings.IndexByte(rest, ',')
+			if end < 0 {
+				end = len(rest)
+			}
+			value = strings.TrimSpace(rest[:end])
+			rest = rest[end:]
+		}
+
+		m, err := NewLabelMatcher(key, op, value)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, m)
+
+		if rest != "" {
+			if rest[0] != ',' {
+				return nil, fmt.Errorf("标签匹配器之间需以逗号分隔")
+			}
+			rest = strings.TrimSpace(rest[1:])
 		}
 	}
+	return matchers, nil
+}
 
-	return true
+// labelMatcherCache 已解析匹配器缓存（规则匹配在每次评估中反复执行，避免重复编译正则）
+var labelMatcherCache sync.Map
+
+// labelMatcherCacheSize 缓存条目计数，超过上限后整体清空
+var labelMatcherCacheSize atomic.Int64
+
+const maxLabelMatcherCache = 4096
+
+type cachedMatchers struct {
+	matchers LabelMatchers
+	err      error
+}
+
+// cachedLabelMatchers 带缓存的 ParseLabelMatchers
+func cachedLabelMatchers(s string) (LabelMatchers, error) {
+	if v, ok := labelMatcherCache.Load(s); ok {
+		c := v.(cachedMatchers)
+		return c.matchers, c.err
+	}
+	matchers, err := ParseLabelMatchers(s)
+	if labelMatcherCacheSize.Add(1) > maxLabelMatcherCache {
+		labelMatcherCache.Clear()
+		labelMatcherCacheSize.Store(1)
+	}
+	labelMatcherCache.Store(s, cachedMatchers{matchers: matchers, err: err})
+	return matchers, err
+}
+
+// EventMatchLabels 告警参与匹配的标签：事件标签 + severity、ruleName（以事件字段为准）
+func EventMatchLabels(event *biz.AlertEvent) map[string]string {
+	labels := parseLabels(event.Labels)
+	labels["severity"] = event.Severity
+	labels["ruleName"] = event.RuleName
+	return labels
+}
+
+// MatchSubscriptionLabels 检查告警标签是否匹配订阅规则的标签匹配器
+// 匹配器无法解析时视为不过滤，避免漏发通知
+func MatchSubscriptionLabels(eventLabelsJSON string, matchersJSON string) bool {
+	matchers, err := cachedLabelMatchers(matchersJSON)
+	if err != nil {
+		return true
+	}
+	return matchers.Matches(parseLabels(eventLabelsJSON))
 }
 
 // MatchSubscriptionDataSource 检查告警数据源是否匹配订阅规则
@@ -184,4 +410,3 @@ func MatchSubscriptionDataSource(eventLabelsJSON string, dataSourceIDsJSON strin
 
 	return false
 }
-
@@ -0,0 +1,131 @@
+package alert
+
+import (
+	"testing"
+
+	biz "github.com/ydcloud-dy/opshub/internal/biz/alert"
+)
+
+func TestParseLabelMatchers(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", "{}"},
+		{"{}", "{}"},
+		{`{"job":"node","env":"prod"}`, `{env="prod", job="node"}`},
+		{`[{"key":"job","op":"=~","value":"node|api"},{"key":"env","value":"prod"}]`, `{job=~"node|api", env="prod"}`},
+		{`[{"name":"env","value":"dev","isEqual":false},{"name":"pod","value":"web-.*","isRegex":true}]`, `{env!="dev", pod=~"web-.*"}`},
+		{`{job=~"node.*", env!="dev", instance!~"10\\..*"}`, `{job=~"node.*", env!="dev", instance!~"10\\..*"}`},
+		{`severity=critical, team=~db|sre`, `{severity="critical", team=~"db|sre"}`},
+		{`"{env=\"prod\"}"`, `{env="prod"}`},
+	}
+	for _, tt := range tests {
+		got, err := ParseLabelMatchers(tt.in)
+		if err != nil {
+			t.Errorf("ParseLabelMatchers(%q) error: %v", tt.in, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("ParseLabelMatchers(%q) = %s, want %s", tt.in, got.String(), tt.want)
+		}
+	}
+
+	for _, bad := range []string{
+		`[{"key":"job","op":"==","value":"x"}]`,
+		`[{"key":"job","op":"=~","value":"("}]`,
+		`[{"op":"=","value":"x"}]`,
+		`{job="node"`,
+		`job~"x"`,
+		`job="a" env="b"`,
+	} {
+		if _, err := ParseLabelMatchers(bad); err == nil {
+			t.Errorf("ParseLabelMatchers(%q): expected error", bad)
+		}
+	}
+}
+
+func TestLabelMatchers_Matches(t *testing.T) {
+	labels := map[string]string{"job": "node-exporter", "env": "prod", "instance": "10.0.0.1:9100"}
+	tests := []struct {
+		matchers string
+		want     bool
+	}{
+		{`{job="node-exporter"}`, true},
+		{`{job="node"}`, false},
+		// 正则整体锚定
+		{`{job=~"node"}`, false},
+		{`{job=~"node.*"}`, true},
+		{`{instance!~"10\\.0\\..*"}`, false},
+		{`{env!="dev"}`, true},
+		// 不存在的标签按空字符串处理
+		{`{team=""}`, true},
+		{`{team!="sre"}`, true},
+		{`{team=~"sre|"}`, true},
+		{`{team=~".+"}`, false},
+		{`{job=~"node.*", env="dev"}`, false},
+	}
+	for _, tt := range tests {
+		ms, err := ParseLabelMatchers(tt.matchers)
+		if err != nil {
+			t.Fatalf("ParseLabelMatchers(%q) error: %v", tt.matchers, err)
+		}
+		if got := ms.Matches(labels); got != tt.want {
+			t.Errorf("%s.Matches() = %v, want %v", tt.matchers, got, tt.want)
+		}
+	}
+}
+
+func TestMatchLabels_BackwardCompatible(t *testing.T) {
+	event := `{"job":"prometheus","instance":"localhost:9090","pod":"pod-123"}`
+	if !MatchLabels(event, `{"job":"prometheus","instance":"localhost:9090"}`) {
+		t.Error("legacy subset should match")
+	}
+	if MatchLabels(event, `{"job":"node"}`) {
+		t.Error("legacy mismatch should not match")
+	}
+	if !MatchLabels(event, `[{"key":"pod","op":"=~","value":"pod-\\d+"}]`) {
+		t.Error("regex matcher should match")
+	}
+	if MatchLabels(event, `not json`) {
+		t.Error("unparsable silence labels should not match")
+	}
+
+	if !MatchSubscriptionLabels(event, `[{"key":"job","op":"!=","value":"node"}]`) {
+		t.Error("subscription != should match")
+	}
+	if MatchSubscriptionLabels(event, `[{"key":"job","op":"=~","value":"prom"}]`) {
+		t.Error("subscription regex should be anchored")
+	}
+	if !MatchSubscriptionLabels(event, `[{"key":"job","op":"==","value":"x"}]`) {
+		t.Error("invalid subscription matchers should not filter")
+	}
+
+	if !MatchLabelFilter(event, "instance=*:9090") || MatchLabelFilter(event, "job=node*") {
+		t.Error("wildcard filter mismatch")
+	}
+	if !MatchLabelFilter(event, `job=~"prom.*",pod!="x"`) {
+		t.Error("matcher filter should match")
+	}
+}
+
+func TestInhibitService_MatchTarget(t *testing.T) {
+	s := &InhibitService{}
+	event := &biz.AlertEvent{Severity: "warning", RuleName: "服务不可用", Labels: `{"service":"api","severity":"info"}`}
+	tests := []struct {
+		matchers string
+		want     bool
+	}{
+		{`{"severity":"warning","ruleName":"服务不可用"}`, true},
+		{`{"severity":"info"}`, false},
+		{`{severity=~"warning|critical", service!="web"}`, true},
+		{`[{"key":"ruleName","op":"!~","value":"服务.*"}]`, false},
+		{`{}`, true},
+		{``, false},
+	}
+	for _, tt := range tests {
+		if got := s.matchTarget(event, tt.matchers); got != tt.want {
+			t.Errorf("matchTarget(%q) = %v, want %v", tt.matchers, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package alert
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	aliyunSMSEndpoint = "https://dysmsapi.aliyuncs.com"
+	aliyunVMSEndpoint = "https://dyvmsapi.aliyuncs.com"
+	aliyunAPIVersion  = "2017-05-25"
+)
+
+// aliyunProvider 阿里云短信（SendSms）与语音（SingleCallByTts）
+type aliyunProvider struct {
+	cfg *MessageChannelConfig
+}
+
+func newAliyunProvider(cfg *MessageChannelConfig) (MessageProvider, error) {
+	return &aliyunProvider{cfg: cfg}, nil
+}
+
+type aliyunResponse struct {
+	Code      string `json:"Code"`
+	Message   string `json:"Message"`
+	RequestID string `json:"RequestId"`
+	BizID     string `json:"BizId"`
+	CallID    string `json:"CallId"`
+}
+
+func (p *aliyunProvider) SendSMS(ctx context.Context, phones []string, content string) ([]DeliveryResult, error) {
+	if p.cfg.SignName == "" {
+		return nil, fmt.Errorf("未配置短信签名")
+	}
+	param, _ := json.Marshal(map[string]string{p.paramKey(): p.cfg.templateParam(content)})
+	resp, err := p.call(ctx, p.endpoint(aliyunSMSEndpoint), map[string]string{
+		"Action":        "SendSms",
+		"PhoneNumbers":  strings.Join(phones, ","),
+		"SignName":      p.cfg.SignName,
+		"TemplateCode":  p.cfg.TemplateID,
+		"TemplateParam": string(param),
+	})
+	if err != nil {
+		return nil, err
+	}
+	// SendSms 为批量接口，所有号码共享同一回执
+	results := make([]DeliveryResult, 0, len(phones))
+	for _, phone := range phones {
+		r := DeliveryResult{Phone: phone, Provider: "aliyun", Code: resp.Code, MessageID: resp.BizID, Status: DeliveryStatusSent}
+		if resp.Code != "OK" {
+			r.Status = DeliveryStatusFailed
+			r.Error = resp.Message
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+func (p *aliyunProvider) Call(ctx context.Context, phones []string, content string) ([]DeliveryResult, error) {
+	param, _ := json.Marshal(map[string]string{p.paramKey(): p.cfg.templateParam(content)})
+	playTimes := int(p.cfg.PlayTimes)
+	if playTimes <= 0 {
+		playTimes = 2
+	}
+	// 语音接口单次只能呼叫一个号码
+	results := make([]DeliveryResult, 0, len(phones))
+	for _, phone := range phones {
+		params := map[string]string{
+			"Action":       "SingleCallByTts",
+			"CalledNumber": phone,
+			"TtsCode":      p.cfg.TemplateID,
+			"TtsParam":     string(param),
+			"PlayTimes":    fmt.Sprintf("%d", playTimes),
+		}
+		if p.cfg.CalledShowNumber != "" {
+			params["CalledShowNumber"] = p.cfg.CalledShowNumber
+		}
+		r := DeliveryResult{Phone: phone, Provider: "aliyun"}
+		resp, err := p.call(ctx, p.endpoint(aliyunVMSEndpoint), params)
+		switch {
+		case err != nil:
+			r.Status = DeliveryStatusFailed
+			r.Error = err.Error()
+		case resp.Code != "OK":
+			r.Status = DeliveryStatusFailed
+			r.Code = resp.Code
+			r.Error = resp.Message
+		default:
+			r.Status = DeliveryStatusSent
+			r.Code = resp.Code
+			r.MessageID = resp.CallID
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+func (p *aliyunProvider) paramKey() string {
+	if p.cfg.TemplateParamKey != "" {
+		return p.cfg.TemplateParamKey
+	}
+	return "content"
+}
+
+func (p *aliyunProvider) endpoint(def string) string {
+	if p.cfg.Endpoint != "" {
+		return strings.TrimRight(p.cfg.Endpoint, "/")
+	}
+	return def
+}
+
+// call 以 RPC 风格（签名版本 1.0）调用阿里云 API
+func (p *aliyunProvider) call(ctx context.Context, endpoint string, params map[string]string) (*aliyunResponse, error) {
+	region := p.cfg.Region
+	if region == "" {
+		region = "cn-hangzhou"
+	}
+	all := map[string]string{
+		"AccessKeyId":      p.cfg.AppKey,
+		"Format":           "JSON",
+		"RegionId":         region,
+		"SignatureMethod":  "HMAC-SHA1",
+		"SignatureNonce":   uuid.NewString(),
+		"SignatureVersion": "1.0",
+		"Timestamp":        time.Now().UTC().Format("2006-01-02T15:04:05Z"),
+		"Version":          aliyunAPIVersion,
+	}
+	for k, v := range params {
+		all[k] = v
+	}
+	query := aliyunCanonicalQuery(all)
+	signature := aliyunSign(p.cfg.AppSecret, http.MethodGet, query)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		endpoint+"/?Signature="+aliyunPercentEncode(signature)+"&"+query, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := messageHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+
+	var out aliyunResponse
+	if err := json.Unmarshal(body, &out); err != nil {
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
+	}
+	if out.Code == "" {
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
+	}
+	return &out, nil
+}
+
+// aliyunCanonicalQuery 按参数名排序并编码
+func aliyunCanonicalQuery(params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, aliyunPercentEncode(k)+"="+aliyunPercentEncode(params[k]))
+	}
+	return strings.Join(pairs, "&")
+}
+
+// aliyunSign 计算 HMAC-SHA1 签名：StringToSign = Method&%2F&percentEncode(query)
+func aliyunSign(secret, method, canonicalQuery string) string {
+	stringToSign := method + "&" + aliyunPercentEncode("/") + "&" + aliyunPercentEncode(canonicalQuery)
+	mac := hmac.New(sha1.New, []byte(secret+"&"))
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func aliyunPercentEncode(s string) string {
+	s = url.QueryEscape(s)
+	s = strings.ReplaceAll(s, "+", "%20")
+	s = strings.ReplaceAll(s, "*", "%2A")
+	return strings.ReplaceAll(s, "%7E", "~")
+}
@@ -0,0 +1,237 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// 单个号码投递状态
+const (
+	DeliveryStatusSent        = "sent"
+	DeliveryStatusFailed      = "failed"
+	DeliveryStatusRateLimited = "rate_limited"
+)
+
+// DeliveryResult 单个号码的投递结果
+type DeliveryResult struct {
+	Phone     string `json:"phone"`
+	Status    string `json:"status"` // sent, failed, rate_limited
+	Provider  string `json:"provider"`
+	MessageID string `json:"messageId,omitempty"` // 服务商回执ID（BizId / SerialNo / CallId）
+	Code      string `json:"code,omitempty"`      // 服务商返回码
+	Error     string `json:"error,omitempty"`
+}
+
+// MessageProvider 短信/语音服务商
+// 实现方负责请求签名与响应解析，按号码返回投递结果；返回 error 表示整批请求失败
+type MessageProvider interface {
+	SendSMS(ctx context.Context, phones []string, content string) ([]DeliveryResult, error)
+	Call(ctx context.Context, phones []string, content string) ([]DeliveryResult, error)
+}
+
+// MessageProviderFactory 根据通道配置创建服务商实例
+type MessageProviderFactory func(cfg *MessageChannelConfig) (MessageProvider, error)
+
+var (
+	messageProvidersMu sync.RWMutex
+	messageProviders   = map[string]MessageProviderFactory{
+		"aliyun":  newAliyunProvider,
+		"tencent": newTencentProvider,
+	}
+)
+
+// RegisterMessageProvider 注册短信/语音服务商，name 对应通道配置中的 provider
+func RegisterMessageProvider(name string, factory MessageProviderFactory) {
+	messageProvidersMu.Lock()
+	defer messageProvidersMu.Unlock()
+	messageProviders[name] = factory
+}
+
+func newMessageProvider(cfg *MessageChannelConfig) (MessageProvider, error) {
+	messageProvidersMu.RLock()
+	factory, ok := messageProviders[cfg.Provider]
+	messageProvidersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("不支持的服务商: %s", cfg.Provider)
+	}
+	return factory(cfg)
+}
+
+// MessageChannelConfig sms/phone 通道配置
+type MessageChannelConfig struct {
+	Provider         string  `json:"provider"`         // aliyun, tencent
+	AppKey           string  `json:"appKey"`           // AccessKeyId / SecretId
+	AppSecret        string  `json:"appSecret"`        // AccessKeySecret / SecretKey
+	Endpoint         string  `json:"endpoint"`         // 自定义接入地址，为空使用服务商默认地址
+	Region           string  `json:"region"`           // 地域，阿里云默认 cn-hangzhou，腾讯云默认 ap-guangzhou
+	SignName         string  `json:"signName"`         // 短信签名
+	TemplateID       string  `json:"templateId"`       // 短信/语音模板ID
+	TemplateParamKey string  `json:"templateParamKey"` // 阿里云模板变量名，默认 content
+	SdkAppID         string  `json:"sdkAppId"`         // 腾讯云短信/语音应用ID
+	CalledShowNumber string  `json:"calledShowNumber"` // 阿里云语音主叫显号
+	PlayTimes        flexInt `json:"playTimes"`        // 语音播放次数，默认 2
+	MaxParamLength   flexInt `json:"maxParamLength"`   // 模板变量最大字符数，超出截断，0=不限制
+	RateLimit        flexInt `json:"rateLimit"`        // 每分钟最多发送条数（按号码计），0=不限制
+}
+
+func parseMessageChannelConfig(configJSON string) (*MessageChannelConfig, error) {
+	var cfg MessageChannelConfig
+	if err := json.Unmarshal([]byte(configJSON), &cfg); err != nil {
+		return nil, fmt.Errorf("解析通道配置失败: %w", err)
+	}
+	if cfg.Provider == "" {
+		cfg.Provider = "aliyun"
+	}
+	if cfg.AppKey == "" || cfg.AppSecret == "" {
+		return nil, fmt.Errorf("未配置 AppKey/AppSecret")
+	}
+	if cfg.TemplateID == "" {
+		return nil, fmt.Errorf("未配置模板ID")
+	}
+	return &cfg, nil
+}
+
+// templateParam 返回写入服务商模板变量的内容
+func (c *MessageChannelConfig) templateParam(content string) string {
+	content = strings.TrimSpace(content)
+	if max := int(c.MaxParamLength); max > 0 {
+		if r := []rune(content); len(r) > max {
+			content = string(r[:max])
+		}
+	}
+	return content
+}
+
+// flexInt 兼容前端以字符串或数字提交的整数配置
+type flexInt int
+
+func (n *flexInt) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	if s == "" || s == "null" {
+		*n = 0
+		return nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return err
+	}
+	*n = flexInt(v)
+	return nil
+}
+
+// messageHTTPClient 服务商 API 请求客户端
+var messageHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// channelRateLimiter 按通道的令牌桶限流，容量与每分钟补充量均为 RateLimit
+type channelRateLimiter struct {
+	mu      sync.Mutex
+	buckets map[uint]*tokenBucket
+}
+
+type tokenBucket struct {
+	limit  int
+	tokens float64
+	last   time.Time
+}
+
+var messageRateLimiter = &channelRateLimiter{buckets: make(map[uint]*tokenBucket)}
+
+// take 尝试为通道获取 n 个配额，返回实际获得的数量
+func (l *channelRateLimiter) take(channelID uint, limit, n int, now time.Time) int {
+	if limit <= 0 {
+		return n
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[channelID]
+	if !ok || b.limit != limit {
+		b = &tokenBucket{limit: limit, tokens: float64(limit), last: now}
+		l.buckets[channelID] = b
+	}
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Minutes() * float64(limit)
+		if b.tokens > float64(limit) {
+			b.tokens = float64(limit)
+		}
+		b.last = now
+	}
+	granted := int(b.tokens)
+	if granted > n {
+		granted = n
+	}
+	b.tokens -= float64(granted)
+	return granted
+}
+
+// sendMessage 通过服务商发送短信或语音通知，voice=true 为语音
+func sendMessage(ctx context.Context, channelID uint, configJSON, msg string, phones []string, voice bool) ([]DeliveryResult, error) {
+	// 短信/电话必须指定号码，不支持 @all
+	if len(phones) == 0 {
+		appLogger.Info("短信/电话通道未指定接收号码，跳过发送", zap.Uint("channelId", channelID))
+		return nil, nil
+	}
+	cfg, err := parseMessageChannelConfig(configJSON)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := newMessageProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	phones = uniquePhones(phones)
+	if len(phones) == 0 {
+		return nil, nil
+	}
+	granted := messageRateLimiter.take(channelID, int(cfg.RateLimit), len(phones), time.Now())
+	var results []DeliveryResult
+	for _, p := range phones[granted:] {
+		results = append(results, DeliveryResult{
+			Phone:    p,
+			Status:   DeliveryStatusRateLimited,
+			Provider: cfg.Provider,
+			Error:    fmt.Sprintf("超出通道限流 %d 条/分钟", cfg.RateLimit),
+		})
+	}
+	if granted == 0 {
+		return results, fmt.Errorf("通道已触发限流")
+	}
+
+	var sent []DeliveryResult
+	if voice {
+		sent, err = provider.Call(ctx, phones[:granted], msg)
+	} else {
+		sent, err = provider.SendSMS(ctx, phones[:granted], msg)
+	}
+	if err != nil {
+		sent = nil
+		for _, p := range phones[:granted] {
+			sent = append(sent, DeliveryResult{Phone: p, Status: DeliveryStatusFailed, Provider: cfg.Provider, Error: err.Error()})
+		}
+	}
+	return append(sent, results...), err
+}
+
+func uniquePhones(phones []string) []string {
+	seen := make(map[string]bool, len(phones))
+	var out []string
+	for _, p := range phones {
+		p = strings.TrimSpace(p)
+		if p == "" || seen[p] {
+			continue
+		}
+		seen[p] = true
+		out = append(out, p)
+	}
+	return out
+}
@@ -0,0 +1,199 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAliyunSign(t *testing.T) {
+	// 阿里云短信文档中的签名示例
+	params := map[string]string{
+		"AccessKeyId":      "testId",
+		"Action":           "SendSms",
+		"Format":           "XML",
+		"OutId":            "123",
+		"PhoneNumbers":     "15300000001",
+		"RegionId":         "cn-hangzhou",
+		"SignName":         "阿里云短信测试专用",
+		"SignatureMethod":  "HMAC-SHA1",
+		"SignatureNonce":   "45e25e9b-0a6f-4070-8c85-2956eda1b466",
+		"SignatureVersion": "1.0",
+		"TemplateCode":     "SMS_71390007",
+		"TemplateParam":    `{"customer":"test"}`,
+		"Timestamp":        "2017-07-12T02:42:19Z",
+		"Version":          "2017-05-25",
+	}
+	got := aliyunSign("testSecret", http.MethodGet, aliyunCanonicalQuery(params))
+	if want := "zJDF+Lrzhj/ThnlvIToysFRq6t4="; got != want {
+		t.Errorf("aliyunSign() = %q, want %q", got, want)
+	}
+}
+
+func TestAliyunProvider_SendSMS(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		params := make(map[string]string)
+		for k := range q {
+			if k != "Signature" {
+				params[k] = q.Get(k)
+			}
+		}
+		if sig := aliyunSign("secret", http.MethodGet, aliyunCanonicalQuery(params)); sig != q.Get("Signature") {
+			w.Write([]byte(`{"Code":"SignatureDoesNotMatch","Message":"bad signature"}`))
+			return
+		}
+		if q.Get("PhoneNumbers") != "13800138000,13900139000" {
+			t.Errorf("PhoneNumbers = %q", q.Get("PhoneNumbers"))
+		}
+		if q.Get("TemplateParam") != `{"content":"磁盘告警"}` {
+			t.Errorf("TemplateParam = %q", q.Get("TemplateParam"))
+		}
+		w.Write([]byte(`{"Code":"OK","Message":"OK","BizId":"biz-1","RequestId":"req-1"}`))
+	}))
+	defer srv.Close()
+
+	p, _ := newAliyunProvider(&MessageChannelConfig{
+		AppKey: "key", AppSecret: "secret", Endpoint: srv.URL,
+		SignName: "SreHub", TemplateID: "SMS_1", MaxParamLength: 4,
+	})
+	results, err := p.SendSMS(context.Background(), []string{"13800138000", "13900139000"}, "磁盘告警: / 使用率 95%")
+	if err != nil {
+		t.Fatalf("SendSMS() error: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	for _, r := range results {
+		if r.Status != DeliveryStatusSent || r.MessageID != "biz-1" {
+			t.Errorf("unexpected result %+v", r)
+		}
+	}
+}
+
+func TestAliyunProvider_CallFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("CalledNumber") == "13900139000" {
+			w.Write([]byte(`{"Code":"isv.MOBILE_NUMBER_ILLEGAL","Message":"invalid number"}`))
+			return
+		}
+		w.Write([]byte(`{"Code":"OK","CallId":"call-1"}`))
+	}))
+	defer srv.Close()
+
+	p, _ := newAliyunProvider(&MessageChannelConfig{AppKey: "key", AppSecret: "secret", Endpoint: srv.URL, TemplateID: "TTS_1"})
+	results, err := p.Call(context.Background(), []string{"13800138000", "13900139000"}, "告警")
+	if err != nil {
+		t.Fatalf("Call() error: %v", err)
+	}
+	if results[0].Status != DeliveryStatusSent || results[0].MessageID != "call-1" {
+		t.Errorf("first call = %+v", results[0])
+	}
+	if results[1].Status != DeliveryStatusFailed || results[1].Code != "isv.MOBILE_NUMBER_ILLEGAL" {
+		t.Errorf("second call = %+v", results[1])
+	}
+}
+
+func TestTencentProvider_SendSMS(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ts, _ := strconv.ParseInt(r.Header.Get("X-TC-Timestamp"), 10, 64)
+		want := tencentAuthorization("sid", "skey", "sms", r.Host, body, ts)
+		if r.Header.Get("Authorization") != want {
+			w.Write([]byte(`{"Response":{"Error":{"Code":"AuthFailure.SignatureFailure","Message":"bad signature"}}}`))
+			return
+		}
+		var req struct {
+			PhoneNumberSet   []string
+			TemplateParamSet []string
+		}
+		json.Unmarshal(body, &req)
+		if strings.Join(req.PhoneNumberSet, ",") != "+8613800138000,+8613900139000" {
+			t.Errorf("PhoneNumberSet = %v", req.PhoneNumberSet)
+		}
+		w.Write([]byte(`{"Response":{"SendStatusSet":[
+			{"SerialNo":"s-1","PhoneNumber":"+8613800138000","Code":"Ok","Message":"send success"},
+			{"SerialNo":"","PhoneNumber":"+8613900139000","Code":"LimitExceeded.PhoneNumberDailyLimit","Message":"daily limit"}
+		],"RequestId":"req-1"}}`))
+	}))
+	defer srv.Close()
+
+	p, err := newTencentProvider(&MessageChannelConfig{
+		AppKey: "sid", AppSecret: "skey", Endpoint: srv.URL,
+		SdkAppID: "1400000000", SignName: "SreHub", TemplateID: "100",
+	})
+	if err != nil {
+		t.Fatalf("newTencentProvider() error: %v", err)
+	}
+	results, err := p.SendSMS(context.Background(), []string{"13800138000", "13900139000"}, "告警")
+	if err != nil {
+		t.Fatalf("SendSMS() error: %v", err)
+	}
+	if results[0].Status != DeliveryStatusSent || results[0].MessageID != "s-1" {
+		t.Errorf("first result = %+v", results[0])
+	}
+	if results[1].Status != DeliveryStatusFailed || results[1].Code != "LimitExceeded.PhoneNumberDailyLimit" {
+		t.Errorf("second result = %+v", results[1])
+	}
+}
+
+func TestTencentProvider_ErrorResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Response":{"Error":{"Code":"AuthFailure.SecretIdNotFound","Message":"secret id not found"}}}`))
+	}))
+	defer srv.Close()
+
+	p, _ := newTencentProvider(&MessageChannelConfig{
+		AppKey: "sid", AppSecret: "skey", Endpoint: srv.URL,
+		SdkAppID: "1400000000", SignName: "SreHub", TemplateID: "100",
+	})
+	if _, err := p.SendSMS(context.Background(), []string{"13800138000"}, "告警"); err == nil {
+		t.Error("expected error for API error response")
+	}
+}
+
+func TestChannelRateLimiter(t *testing.T) {
+	l := &channelRateLimiter{buckets: make(map[uint]*tokenBucket)}
+	now := time.Date(2026, 5, 5, 10, 0, 0, 0, time.UTC)
+
+	if got := l.take(1, 2, 3, now); got != 2 {
+		t.Errorf("first take = %d, want 2", got)
+	}
+	if got := l.take(1, 2, 1, now); got != 0 {
+		t.Errorf("take when empty = %d, want 0", got)
+	}
+	if got := l.take(1, 2, 2, now.Add(30*time.Second)); got != 1 {
+		t.Errorf("take after 30s = %d, want 1", got)
+	}
+	if got := l.take(2, 0, 100, now); got != 100 {
+		t.Errorf("unlimited take = %d, want 100", got)
+	}
+}
+
+func TestSendMessage_RateLimited(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Code":"OK","BizId":"biz-1"}`))
+	}))
+	defer srv.Close()
+
+	cfg := `{"provider":"aliyun","appKey":"key","appSecret":"secret","signName":"SreHub","templateId":"SMS_1","rateLimit":"1","endpoint":"` + srv.URL + `"}`
+	results, err := sendMessage(context.Background(), 990001, cfg, "告警", []string{"13800138000", "13900139000", "13800138000"}, false)
+	if err != nil {
+		t.Fatalf("sendMessage() error: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2 (duplicate phone removed)", len(results))
+	}
+	if results[0].Status != DeliveryStatusSent || results[1].Status != DeliveryStatusRateLimited {
+		t.Errorf("unexpected results %+v", results)
+	}
+	if got := deliveryStatus("sms", results, err); got != ChannelStatusPartial {
+		t.Errorf("deliveryStatus() = %q, want %q", got, ChannelStatusPartial)
+	}
+}
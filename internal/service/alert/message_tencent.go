@@ -0,0 +1,237 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	tencentSMSEndpoint = "https://sms.tencentcloudapi.com"
+	tencentVMSEndpoint = "https://vms.tencentcloudapi.com"
+	tencentSMSVersion  = "2021-01-11"
+	tencentVMSVersion  = "2020-09-02"
+)
+
+// tencentProvider 腾讯云短信（SendSms）与语音消息（SendTtsVoice）
+type tencentProvider struct {
+	cfg *MessageChannelConfig
+}
+
+func newTencentProvider(cfg *MessageChannelConfig) (MessageProvider, error) {
+	if cfg.SdkAppID == "" {
+		return nil, fmt.Errorf("未配置腾讯云 SdkAppId")
+	}
+	return &tencentProvider{cfg: cfg}, nil
+}
+
+type tencentError struct {
+	Code    string `json:"Code"`
+	Message string `json:"Message"`
+}
+
+type tencentSendStatus struct {
+	SerialNo    string `json:"SerialNo"`
+	PhoneNumber string `json:"PhoneNumber"`
+	Code        string `json:"Code"`
+	Message     string `json:"Message"`
+}
+
+func (p *tencentProvider) SendSMS(ctx context.Context, phones []string, content string) ([]DeliveryResult, error) {
+	if p.cfg.SignName == "" {
+		return nil, fmt.Errorf("未配置短信签名")
+	}
+	numbers := make([]string, 0, len(phones))
+	for _, phone := range phones {
+		numbers = append(numbers, tencentE164(phone))
+	}
+	var out struct {
+		Response struct {
+			Error         *tencentError       `json:"Error"`
+			SendStatusSet []tencentSendStatus `json:"SendStatusSet"`
+			RequestID     string              `json:"RequestId"`
+		} `json:"Response"`
+	}
+	err := p.call(ctx, p.endpoint(tencentSMSEndpoint), "sms", "SendSms", tencentSMSVersion, map[string]interface{}{
+		"PhoneNumberSet":   numbers,
+		"SmsSdkAppId":      p.cfg.SdkAppID,
+		"SignName":         p.cfg.SignName,
+		"TemplateId":       p.cfg.TemplateID,
+		"TemplateParamSet": []string{p.cfg.templateParam(content)},
+	}, &out)
+	if err != nil {
+		return nil, err
+	}
+	if e := out.Response.Error; e != nil {
+		return nil, fmt.Errorf("%s: %s", e.Code, e.Message)
+	}
+
+	statusByPhone := make(map[string]tencentSendStatus, len(out.Response.SendStatusSet))
+	for _, st := range out.Response.SendStatusSet {
+		statusByPhone[st.PhoneNumber] = st
+	}
+	results := make([]DeliveryResult, 0, len(phones))
+	for i, phone := range phones {
+		r := DeliveryResult{Phone: phone, Provider: "tencent"}
+		st, ok := statusByPhone[numbers[i]]
+		switch {
+		case !ok:
+			r.Status = DeliveryStatusFailed
+			r.Error = "服务商未返回该号码的发送状态"
+		case !strings.EqualFold(st.Code, "Ok"):
+			r.Status = DeliveryStatusFailed
+			r.Code = st.Code
+			r.Error = st.Message
+		default:
+			r.Status = DeliveryStatusSent
+			r.Code = st.Code
+			r.MessageID = st.SerialNo
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+func (p *tencentProvider) Call(ctx context.Context, phones []string, content string) ([]DeliveryResult, error) {
+	playTimes := int(p.cfg.PlayTimes)
+	if playTimes <= 0 {
+		playTimes = 2
+	}
+	results := make([]DeliveryResult, 0, len(phones))
+	for _, phone := range phones {
+		var out struct {
+			Response struct {
+				Error      *tencentError `json:"Error"`
+				SendStatus struct {
+					CallID string `json:"CallId"`
+				} `json:"SendStatus"`
+			} `json:"Response"`
+		}
+		r := DeliveryResult{Phone: phone, Provider: "tencent"}
+		err := p.call(ctx, p.endpoint(tencentVMSEndpoint), "vms", "SendTtsVoice", tencentVMSVersion, map[string]interface{}{
+			"TemplateId":       p.cfg.TemplateID,
+			"TemplateParamSet": []string{p.cfg.templateParam(content)},
+			"CalledNumber":     tencentE164(phone),
+			"VoiceSdkAppid":    p.cfg.SdkAppID,
+			"PlayTimes":        playTimes,
+		}, &out)
+		switch {
+		case err != nil:
+			r.Status = DeliveryStatusFailed
+			r.Error = err.Error()
+		case out.Response.Error != nil:
+			r.Status = DeliveryStatusFailed
+			r.Code = out.Response.Error.Code
+			r.Error = out.Response.Error.Message
+		default:
+			r.Status = DeliveryStatusSent
+			r.MessageID = out.Response.SendStatus.CallID
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+func (p *tencentProvider) endpoint(def string) string {
+	if p.cfg.Endpoint != "" {
+		return strings.TrimRight(p.cfg.Endpoint, "/")
+	}
+	return def
+}
+
+// call 以 TC3-HMAC-SHA256 签名调用腾讯云 API 3.0
+func (p *tencentProvider) call(ctx context.Context, endpoint, service, action, version string, payload interface{}, out interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return err
+	}
+	region := p.cfg.Region
+	if region == "" {
+		region = "ap-guangzhou"
+	}
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Host", u.Host)
+	req.Header.Set("X-TC-Action", action)
+	req.Header.Set("X-TC-Version", version)
+	req.Header.Set("X-TC-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-TC-Region", region)
+	req.Header.Set("Authorization", tencentAuthorization(p.cfg.AppKey, p.cfg.AppSecret, service, u.Host, body, timestamp))
+
+	resp, err := messageHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
+
+// tencentAuthorization 计算 TC3-HMAC-SHA256 Authorization 头
+func tencentAuthorization(secretID, secretKey, service, host string, body []byte, timestamp int64) string {
+	date := time.Unix(timestamp, 0).UTC().Format("2006-01-02")
+	canonicalRequest := strings.Join([]string{
+		http.MethodPost,
+		"/",
+		"",
+		"content-type:application/json; charset=utf-8\nhost:" + host + "\n",
+		"content-type;host",
+		sha256Hex(body),
+	}, "\n")
+	scope := date + "/" + service + "/tc3_request"
+	stringToSign := strings.Join([]string{
+		"TC3-HMAC-SHA256",
+		strconv.FormatInt(timestamp, 10),
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	secretDate := hmacSHA256([]byte("TC3"+secretKey), date)
+	secretService := hmacSHA256(secretDate, service)
+	secretSigning := hmacSHA256(secretService, "tc3_request")
+	signature := hex.EncodeToString(hmacSHA256(secretSigning, stringToSign))
+
+	return fmt.Sprintf("TC3-HMAC-SHA256 Credential=%s/%s, SignedHeaders=content-type;host, Signature=%s",
+		secretID, scope, signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// tencentE164 腾讯云要求 E.164 格式号码，未带国家码时默认 +86
+func tencentE164(phone string) string {
+	if strings.HasPrefix(phone, "+") {
+		return phone
+	}
+	return "+86" + phone
+}
@@ -0,0 +1,412 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	biz "github.com/ydcloud-dy/opshub/internal/biz/alert"
+	alertdata "github.com/ydcloud-dy/opshub/internal/data/alert"
+)
+
+const (
+	// notifyQueueKey 待发送通知 ZSET，member=投递ID，score=下次发送时间（毫秒）
+	notifyQueueKey = "alert:notify:queue"
+
+	notifyMaxAttempts     = 5
+	notifyRetryBase       = 10 * time.Second
+	notifyRetryMax        = 10 * time.Minute
+	notifyQueueBatch      = 50
+	notifyQueueWorkers    = 10
+	notifyRecoverInterval = time.Minute
+	notifyClaimLease      = 2 * time.Minute // 发送租约时长，发送期间每 1/3 租约续期一次
+)
+
+// NotifyQueue 基于 Redis 的通知发送队列
+// 投递记录持久化在 MySQL，Redis ZSET 仅保存到期时间；多副本通过 ZREM 抢占、再以 MySQL 中的发送租约（claimed_until）
+// 保证同一投递只被一个实例处理，补偿任务不会重新拾取仍在发送中的投递；失败后按指数退避重试，超过最大次数进入死信表。
+type NotifyQueue struct {
+	rdb          *redis.Client
+	deliveryRepo *alertdata.NotifyDeliveryRepo
+	channelRepo  *alertdata.ChannelRepo
+	eventRepo    *alertdata.EventRepo
+	notifySvc    *NotifyService
+}
+
+func NewNotifyQueue(db *gorm.DB, rdb *redis.Client, notifySvc *NotifyService) *NotifyQueue {
+	return &NotifyQueue{
+		rdb:          rdb,
+		deliveryRepo: alertdata.NewNotifyDeliveryRepo(db),
+		channelRepo:  alertdata.NewChannelRepo(db),
+		eventRepo:    alertdata.NewEventRepo(db),
+		notifySvc:    notifySvc,
+	}
+}
+
+// Enqueue 创建投递记录并加入发送队列
+func (q *NotifyQueue) Enqueue(ctx context.Context, event *biz.AlertEvent, ch *biz.AlertNotifyChannel, subscriptionID, subscriptionLogID uint,
+	isResolve bool, phones []string, userIDs []uint) (*biz.AlertNotifyDelivery, error) {
+	phonesJSON, _ := json.Marshal(phones) // nil 编码为 null，保留 @all 语义
+	userIDsJSON, _ := json.Marshal(userIDs)
+	now := time.Now()
+	d := &biz.AlertNotifyDelivery{
+		EventID:           event.ID,
+		SubscriptionID:    subscriptionID,
+		SubscriptionLogID: subscriptionLogID,
+		ChannelID:         ch.ID,
+		ChannelName:       ch.Name,
+		ChannelType:       ch.Type,
+		IsResolve:         isResolve,
+		Phones:            string(phonesJSON),
+		UserIDs:           string(userIDsJSON),
+		Status:            biz.NotifyDeliveryPending,
+		MaxAttempts:       notifyMaxAttempts,
+		NextRetryAt:       &now,
+	}
+	if err := q.deliveryRepo.Create(ctx, d); err != nil {
+		return nil, err
+	}
+	if err := q.push(ctx, d.ID, now); err != nil {
+		// 入队失败不影响投递，补偿任务会从 MySQL 重新入队
+		appLogger.Warn("通知入队失败，等待补偿", zap.Uint("deliveryID", d.ID), zap.Error(err))
+	}
+	return d, nil
+}
+
+// Resend 手动重发死信：重置原投递记录并重新入队
+func (q *NotifyQueue) Resend(ctx context.Context, deadLetterID, userID uint) (*biz.AlertNotifyDelivery, error) {
+	dl, err := q.deliveryRepo.GetDeadLetter(ctx, deadLetterID)
+	if err != nil {
+		return nil, fmt.Errorf("死信不存在")
+	}
+	if dl.Resent {
+		return nil, fmt.Errorf("该死信已重发")
+	}
+	d, err := q.deliveryRepo.GetByID(ctx, dl.DeliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("投递记录不存在")
+	}
+
+	now := time.Now()
+	d.Status = biz.NotifyDeliveryPending
+	d.Attempts = 0
+	d.LastError = ""
+	d.NextRetryAt = &now
+	if err := q.deliveryRepo.Update(ctx, d); err != nil {
+		return nil, err
+	}
+	dl.Resent = true
+	dl.ResentAt = &now
+	dl.ResentBy = userID
+	if err := q.deliveryRepo.UpdateDeadLetter(ctx, dl); err != nil {
+		return nil, err
+	}
+	if err := q.push(ctx, d.ID, now); err != nil {
+		appLogger.Warn("重发通知入队失败，等待补偿", zap.Uint("deliveryID", d.ID), zap.Error(err))
+	}
+	return d, nil
+}
+
+// Start 启动队列消费（阻塞，在 goroutine 中调用）
+func (q *NotifyQueue) Start(ctx context.Context) {
+	appLogger.Info("通知发送队列启动")
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	recoverTicker := time.NewTicker(notifyRecoverInterval)
+	defer recoverTicker.Stop()
+
+	sem := make(chan struct{}, notifyQueueWorkers)
+	var wg sync.WaitGroup
+	q.recoverDue(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			appLogger.Info("通知发送队列停止")
+			return
+		case <-recoverTicker.C:
+			q.recoverDue(ctx)
+		case <-ticker.C:
+			ids, err := q.claimDue(ctx, time.Now())
+			if err != nil {
+				appLogger.Error("拉取待发送通知失败", zap.Error(err))
+				continue
+			}
+			for _, id := range ids {
+				sem <- struct{}{}
+				wg.Add(1)
+				go func(id uint) {
+					defer func() { <-sem; wg.Done() }()
+					q.process(ctx, id)
+				}(id)
+			}
+		}
+	}
+}
+
+func (q *NotifyQueue) push(ctx context.Context, id uint, at time.Time) error {
+	return q.rdb.ZAdd(ctx, notifyQueueKey, redis.Z{Score: float64(at.UnixMilli()), Member: strconv.FormatUint(uint64(id), 10)}).Err()
+}
+
+// claimDue 取出已到期的投递ID；ZREM 成功才视为抢占成功，避免多副本重复发送
+func (q *NotifyQueue) claimDue(ctx context.Context, now time.Time) ([]uint, error) {
+	members, err := q.rdb.ZRangeByScore(ctx, notifyQueueKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(now.UnixMilli(), 10),
+		Count: notifyQueueBatch,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	var ids []uint
+	for _, m := range members {
+		removed, err := q.rdb.ZRem(ctx, notifyQueueKey, m).Result()
+		if err != nil || removed == 0 {
+			continue
+		}
+		id, err := strconv.ParseUint(m, 10, 64)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, nil
+}
+
+// recoverDue 将 MySQL 中已到期但不在队列中的投递重新入队（Redis 数据丢失或实例在处理中退出）
+func (q *NotifyQueue) recoverDue(ctx context.Context) {
+	// 留出处理窗口，避免与正在发送的任务竞争
+	list, err := q.deliveryRepo.ListDue(ctx, time.Now().Add(-notifyRecoverInterval), 500)
+	if err != nil {
+		appLogger.Error("查询待补偿通知失败", zap.Error(err))
+		return
+	}
+	for _, d := range list {
+		q.rdb.ZAddNX(ctx, notifyQueueKey, redis.Z{Score: float64(time.Now().UnixMilli()), Member: strconv.FormatUint(uint64(d.ID), 10)})
+	}
+	if len(list) > 0 {
+		appLogger.Info("补偿入队通知", zap.Int("count", len(list)))
+	}
+}
+
+// process 发送一次投递并更新状态
+func (q *NotifyQueue) process(ctx context.Context, id uint) {
+	defer func() {
+		if r := recover(); r != nil {
+			appLogger.Error("通知发送 panic", zap.Uint("deliveryID", id), zap.Any("recover", r))
+		}
+	}()
+
+	// 抢占发送租约：已完成或正被其他实例发送的投递直接跳过
+	now := time.Now()
+	claimed, err := q.deliveryRepo.Claim(ctx, id, now, now.Add(notifyClaimLease))
+	if err != nil {
+		appLogger.Warn("抢占通知投递失败", zap.Uint("deliveryID", id), zap.Error(err))
+		return
+	}
+	if !claimed {
+		return
+	}
+	stopRenew := q.keepClaim(ctx, id)
+	defer stopRenew()
+
+	d, err := q.deliveryRepo.GetByID(ctx, id)
+	if err != nil {
+		appLogger.Warn("投递记录不存在", zap.Uint("deliveryID", id), zap.Error(err))
+		return
+	}
+	// 之后每次保存投递状态都会释放租约
+	d.ClaimedUntil = nil
+
+	d.Attempts++
+	ch, err := q.channelRepo.GetByID(ctx, d.ChannelID)
+	if err != nil {
+		q.markDead(ctx, d, "通知通道不存在")
+		return
+	}
+	if !ch.Enabled {
+		q.markDead(ctx, d, "通知通道已禁用")
+		return
+	}
+	event, err := q.eventRepo.GetByID(ctx, d.EventID)
+	if err != nil {
+		q.markDead(ctx, d, "告警事件不存在")
+		return
+	}
+
+	var phones []string
+	var userIDs []uint
+	json.Unmarshal([]byte(d.Phones), &phones)
+	json.Unmarshal([]byte(d.UserIDs), &userIDs)
+
+	result := q.notifySvc.Send(ctx, ch, event, d.IsResolve, phones, userIDs)
+	result.Recipients = mergeSentRecipients(d.Result, result.Recipients)
+	resultJSON, _ := json.Marshal(result)
+	d.Result = string(resultJSON)
+
+	var retryErr string
+	switch result.Status {
+	case ChannelStatusFailed:
+		retryErr = result.Error
+	case ChannelStatusPartial:
+		// 部分号码失败：仅保留失败号码，重试时不再重复发送已成功的号码
+		failed := failedRecipientPhones(result.Recipients)
+		failedJSON, _ := json.Marshal(failed)
+		d.Phones = string(failedJSON)
+		retryErr = fmt.Sprintf("%d 个号码发送失败: %s", len(failed), strings.Join(failed, ","))
+		if result.Error != "" {
+			retryErr += ": " + result.Error
+		}
+	default:
+		now := time.Now()
+		d.Status = biz.NotifyDeliverySent
+		d.LastError = ""
+		d.SentAt = &now
+		d.NextRetryAt = nil
+		if err := q.deliveryRepo.Update(ctx, d); err != nil {
+			appLogger.Error("更新投递状态失败", zap.Uint("deliveryID", d.ID), zap.Error(err))
+		}
+		return
+	}
+
+	if d.Attempts >= d.MaxAttempts {
+		q.markDead(ctx, d, retryErr)
+		return
+	}
+	next := time.Now().Add(notifyBackoff(d.Attempts))
+	d.Status = biz.NotifyDeliveryFailed
+	d.LastError = truncateString(retryErr, 1000)
+	d.NextRetryAt = &next
+	if err := q.deliveryRepo.Update(ctx, d); err != nil {
+		appLogger.Error("更新投递状态失败", zap.Uint("deliveryID", d.ID), zap.Error(err))
+	}
+	if err := q.push(ctx, d.ID, next); err != nil {
+		appLogger.Warn("通知重试入队失败，等待补偿", zap.Uint("deliveryID", d.ID), zap.Error(err))
+	}
+	appLogger.Warn("通知发送失败，等待重试",
+		zap.Uint("deliveryID", d.ID),
+		zap.String("channel", d.ChannelName),
+		zap.Int("attempts", d.Attempts),
+		zap.Time("nextRetryAt", next))
+}
+
+// keepClaim 发送期间定期续期租约，避免慢速发送超过租约后被补偿任务重复拾取；返回停止函数
+func (q *NotifyQueue) keepClaim(ctx context.Context, id uint) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(notifyClaimLease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := q.deliveryRepo.RenewClaim(ctx, id, time.Now().Add(notifyClaimLease)); err != nil {
+					appLogger.Warn("续期通知发送租约失败", zap.Uint("deliveryID", id), zap.Error(err))
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// mergeSentRecipients 合并此前已发送成功的号码结果，保留完整的按号码投递记录
+func mergeSentRecipients(prevResult string, current []DeliveryResult) []DeliveryResult {
+	if prevResult == "" {
+		return current
+	}
+	var prev ChannelDelivery
+	if json.Unmarshal([]byte(prevResult), &prev) != nil {
+		return current
+	}
+	seen := make(map[string]bool, len(current))
+	for _, r := range current {
+		seen[r.Phone] = true
+	}
+	var merged []DeliveryResult
+	for _, r := range prev.Recipients {
+		if r.Status == DeliveryStatusSent && !seen[r.Phone] {
+			merged = append(merged, r)
+		}
+	}
+	return append(merged, current...)
+}
+
+// failedRecipientPhones 返回未发送成功（失败或被限流）的号码
+func failedRecipientPhones(recipients []DeliveryResult) []string {
+	var phones []string
+	for _, r := range recipients {
+		if r.Status != DeliveryStatusSent {
+			phones = append(phones, r.Phone)
+		}
+	}
+	return phones
+}
+
+// markDead 投递进入死信
+func (q *NotifyQueue) markDead(ctx context.Context, d *biz.AlertNotifyDelivery, reason string) {
+	d.Status = biz.NotifyDeliveryDead
+	d.LastError = truncateString(reason, 1000)
+	d.NextRetryAt = nil
+	if err := q.deliveryRepo.Update(ctx, d); err != nil {
+		appLogger.Error("更新投递状态失败", zap.Uint("deliveryID", d.ID), zap.Error(err))
+	}
+
+	var ruleName string
+	if event, err := q.eventRepo.GetByID(ctx, d.EventID); err == nil {
+		ruleName = event.RuleName
+	}
+	dl := &biz.AlertNotifyDeadLetter{
+		DeliveryID:  d.ID,
+		EventID:     d.EventID,
+		RuleName:    ruleName,
+		ChannelID:   d.ChannelID,
+		ChannelName: d.ChannelName,
+		ChannelType: d.ChannelType,
+		IsResolve:   d.IsResolve,
+		Attempts:    d.Attempts,
+		LastError:   d.LastError,
+	}
+	if err := q.deliveryRepo.CreateDeadLetter(ctx, dl); err != nil {
+		appLogger.Error("写入通知死信失败", zap.Uint("deliveryID", d.ID), zap.Error(err))
+	}
+	appLogger.Error("通知重试耗尽，进入死信",
+		zap.Uint("deliveryID", d.ID),
+		zap.String("channel", d.ChannelName),
+		zap.Int("attempts", d.Attempts),
+		zap.String("error", reason))
+}
+
+// notifyBackoff 第 n 次失败后的重试间隔：10s、20s、40s ... 最长 10 分钟
+func notifyBackoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	d := notifyRetryBase
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= notifyRetryMax {
+			return notifyRetryMax
+		}
+	}
+	return d
+}
+
+func truncateString(s string, max int) string {
+	if r := []rune(s); len(r) > max {
+		return string(r[:max])
+	}
+	return s
+}
@@ -0,0 +1,257 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
+	"go.uber.org/zap"
+
+	biz "github.com/ydcloud-dy/opshub/internal/biz/alert"
+	alertdata "github.com/ydcloud-dy/opshub/internal/data/alert"
+	"github.com/ydcloud-dy/opshub/internal/testutil"
+)
+
+// setupNotifyQueue 使用 sqlite 内存库与 miniredis 构建队列，webhook 通道指向 handler
+func setupNotifyQueue(t *testing.T, handler http.HandlerFunc) (*NotifyQueue, *biz.AlertEvent, *biz.AlertNotifyChannel) {
+	t.Helper()
+	appLogger.Log = zap.NewNop()
+
+	db := testutil.NewDB(t, &biz.AlertEvent{}, &biz.AlertNotifyChannel{}, &biz.AlertNotifyDelivery{}, &biz.AlertNotifyDeadLetter{})
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	ch := &biz.AlertNotifyChannel{Name: "hook", Type: "webhook", Enabled: true, Config: `{"url":"` + srv.URL + `"}`}
+	db.Create(ch)
+	event := &biz.AlertEvent{RuleName: "CPU 高", Severity: "critical", Status: "firing", Labels: `{}`, Annotations: `{}`, FiredAt: time.Now()}
+	db.Create(event)
+
+	notifySvc := NewNotifyService(alertdata.NewChannelRepo(db), db)
+	return NewNotifyQueue(db, rdb, notifySvc), event, ch
+}
+
+func TestNotifyQueue_RetryThenSent(t *testing.T) {
+	var calls int32
+	q, event, ch := setupNotifyQueue(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	ctx := context.Background()
+
+	d, err := q.Enqueue(ctx, event, ch, 1, 0, false, []string{}, []uint{})
+	if err != nil {
+		t.Fatalf("Enqueue() error: %v", err)
+	}
+
+	ids, _ := q.claimDue(ctx, time.Now())
+	if len(ids) != 1 || ids[0] != d.ID {
+		t.Fatalf("claimDue() = %v, want [%d]", ids, d.ID)
+	}
+	q.process(ctx, d.ID)
+
+	d, _ = q.deliveryRepo.GetByID(ctx, d.ID)
+	if d.Status != biz.NotifyDeliveryFailed || d.Attempts != 1 || d.NextRetryAt == nil {
+		t.Fatalf("after first attempt: status=%s attempts=%d", d.Status, d.Attempts)
+	}
+
+	// 未到重试时间不应被取出
+	if ids, _ := q.claimDue(ctx, time.Now()); len(ids) != 0 {
+		t.Fatalf("claimDue() before backoff = %v, want none", ids)
+	}
+
+	ids, _ = q.claimDue(ctx, time.Now().Add(notifyRetryBase+time.Second))
+	if len(ids) != 1 {
+		t.Fatalf("claimDue() after backoff = %v", ids)
+	}
+	q.process(ctx, ids[0])
+
+	d, _ = q.deliveryRepo.GetByID(ctx, d.ID)
+	if d.Status != biz.NotifyDeliverySent || d.Attempts != 2 || d.SentAt == nil {
+		t.Errorf("after retry: status=%s attempts=%d", d.Status, d.Attempts)
+	}
+}
+
+func TestNotifyQueue_DeadLetterAndResend(t *testing.T) {
+	q, event, ch := setupNotifyQueue(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	ctx := context.Background()
+
+	d, _ := q.Enqueue(ctx, event, ch, 1, 0, true, nil, nil)
+	future := time.Now().Add(time.Hour)
+	for i := 0; i < notifyMaxAttempts; i++ {
+		ids, _ := q.claimDue(ctx, future)
+		if len(ids) != 1 {
+			t.Fatalf("attempt %d: claimDue() = %v", i+1, ids)
+		}
+		q.process(ctx, ids[0])
+	}
+
+	d, _ = q.deliveryRepo.GetByID(ctx, d.ID)
+	if d.Status != biz.NotifyDeliveryDead || d.Attempts != notifyMaxAttempts {
+		t.Fatalf("status=%s attempts=%d, want dead after %d attempts", d.Status, d.Attempts, notifyMaxAttempts)
+	}
+	if ids, _ := q.claimDue(ctx, future); len(ids) != 0 {
+		t.Fatalf("dead delivery still queued: %v", ids)
+	}
+
+	letters, total, _ := q.deliveryRepo.ListDeadLetters(ctx, 1, 10, event.ID, nil)
+	if total != 1 || letters[0].DeliveryID != d.ID || letters[0].RuleName != "CPU 高" {
+		t.Fatalf("dead letters = %+v", letters)
+	}
+
+	resent, err := q.Resend(ctx, letters[0].ID, 9)
+	if err != nil {
+		t.Fatalf("Resend() error: %v", err)
+	}
+	if resent.Status != biz.NotifyDeliveryPending || resent.Attempts != 0 {
+		t.Errorf("resent delivery status=%s attempts=%d", resent.Status, resent.Attempts)
+	}
+	if ids, _ := q.claimDue(ctx, time.Now().Add(time.Second)); len(ids) != 1 {
+		t.Errorf("resent delivery not queued: %v", ids)
+	}
+	if _, err := q.Resend(ctx, letters[0].ID, 9); err == nil {
+		t.Error("expected error when resending the same dead letter twice")
+	}
+}
+
+func TestNotifyQueue_ClaimLeasePreventsDoubleSend(t *testing.T) {
+	var calls int32
+	q, event, ch := setupNotifyQueue(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusOK)
+	})
+	ctx := context.Background()
+
+	d, _ := q.Enqueue(ctx, event, ch, 1, 0, false, []string{}, nil)
+
+	// 模拟其他实例正在发送（租约有效）
+	now := time.Now()
+	if ok, err := q.deliveryRepo.Claim(ctx, d.ID, now, now.Add(notifyClaimLease)); !ok || err != nil {
+		t.Fatalf("Claim() = %v, %v", ok, err)
+	}
+	if ok, _ := q.deliveryRepo.Claim(ctx, d.ID, now, now.Add(notifyClaimLease)); ok {
+		t.Fatal("second Claim() should fail while lease is held")
+	}
+	if list, _ := q.deliveryRepo.ListDue(ctx, now.Add(time.Hour), 10); len(list) != 0 {
+		t.Fatalf("ListDue() returned leased delivery: %d", len(list))
+	}
+	q.process(ctx, d.ID)
+	if atomic.LoadInt32(&calls) != 0 {
+		t.Fatal("leased delivery should not be sent again")
+	}
+
+	// 续期后租约仍有效；租约过期后可被补偿任务重新拾取并发送
+	if err := q.deliveryRepo.RenewClaim(ctx, d.ID, now.Add(-time.Second)); err != nil {
+		t.Fatalf("RenewClaim() error: %v", err)
+	}
+	if list, _ := q.deliveryRepo.ListDue(ctx, now.Add(time.Hour), 10); len(list) != 1 {
+		t.Fatalf("expired lease should be recoverable, got %d", len(list))
+	}
+	q.process(ctx, d.ID)
+	d, _ = q.deliveryRepo.GetByID(ctx, d.ID)
+	if atomic.LoadInt32(&calls) != 1 || d.Status != biz.NotifyDeliverySent || d.ClaimedUntil != nil {
+		t.Fatalf("calls=%d status=%s claimedUntil=%v", calls, d.Status, d.ClaimedUntil)
+	}
+
+	// 已完成的投递不能再被抢占
+	if ok, _ := q.deliveryRepo.Claim(ctx, d.ID, time.Now(), time.Now().Add(notifyClaimLease)); ok {
+		t.Fatal("sent delivery should not be claimable")
+	}
+}
+
+// partialSMSProvider 首次发送时指定号码失败，记录每次发送的号码
+type partialSMSProvider struct {
+	failOnce map[string]bool
+	sends    [][]string
+}
+
+func (p *partialSMSProvider) SendSMS(ctx context.Context, phones []string, content string) ([]DeliveryResult, error) {
+	p.sends = append(p.sends, append([]string(nil), phones...))
+	var results []DeliveryResult
+	for _, ph := range phones {
+		status := DeliveryStatusSent
+		if p.failOnce[ph] {
+			status = DeliveryStatusFailed
+			delete(p.failOnce, ph)
+		}
+		results = append(results, DeliveryResult{Phone: ph, Status: status, Provider: "partial-test"})
+	}
+	return results, nil
+}
+
+func (p *partialSMSProvider) Call(ctx context.Context, phones []string, content string) ([]DeliveryResult, error) {
+	return p.SendSMS(ctx, phones, content)
+}
+
+func TestNotifyQueue_PartialSMSRetriesFailedRecipients(t *testing.T) {
+	provider := &partialSMSProvider{failOnce: map[string]bool{"13900139000": true}}
+	RegisterMessageProvider("partial-test", func(cfg *MessageChannelConfig) (MessageProvider, error) {
+		return provider, nil
+	})
+	q, event, _ := setupNotifyQueue(t, func(w http.ResponseWriter, r *http.Request) {})
+	ctx := context.Background()
+
+	ch := &biz.AlertNotifyChannel{Name: "sms", Type: "sms", Enabled: true,
+		Config: `{"provider":"partial-test","appKey":"k","appSecret":"s","templateId":"t"}`}
+	if err := q.channelRepo.Create(ctx, ch); err != nil {
+		t.Fatal(err)
+	}
+
+	d, _ := q.Enqueue(ctx, event, ch, 1, 0, false, []string{"13800138000", "13900139000"}, nil)
+	q.process(ctx, d.ID)
+
+	d, _ = q.deliveryRepo.GetByID(ctx, d.ID)
+	if d.Status != biz.NotifyDeliveryFailed || d.NextRetryAt == nil {
+		t.Fatalf("partial send should be retried, status=%s", d.Status)
+	}
+	if d.Phones != `["13900139000"]` {
+		t.Fatalf("pending phones = %s, want only the failed number", d.Phones)
+	}
+
+	q.process(ctx, d.ID)
+	d, _ = q.deliveryRepo.GetByID(ctx, d.ID)
+	if d.Status != biz.NotifyDeliverySent {
+		t.Fatalf("status after retry = %s, want sent", d.Status)
+	}
+	if len(provider.sends) != 2 || len(provider.sends[1]) != 1 || provider.sends[1][0] != "13900139000" {
+		t.Fatalf("sends = %v, retry should only target the failed number", provider.sends)
+	}
+	var result ChannelDelivery
+	json.Unmarshal([]byte(d.Result), &result)
+	if len(result.Recipients) != 2 {
+		t.Errorf("result recipients = %+v, want both numbers recorded", result.Recipients)
+	}
+}
+
+func TestNotifyBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, 10 * time.Second},
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{4, 80 * time.Second},
+		{10, 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := notifyBackoff(tt.attempts); got != tt.want {
+			t.Errorf("notifyBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -3,13 +3,17 @@ package alert
 import (
 	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
 	"crypto/tls"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
 	"net/smtp"
+	"net/url"
 	"strings"
 	"text/template"
 	"time"
@@ -53,10 +57,28 @@ type notifyTplData struct {
 	Mentions          string // @用户列表（手机号或userid）
 }
 
-// Send 发送通知（firing 或 resolved）
+// 通道投递状态
+const (
+	ChannelStatusSent    = "sent"
+	ChannelStatusPartial = "partial" // 部分号码发送成功
+	ChannelStatusFailed  = "failed"
+	ChannelStatusSkipped = "skipped" // 无可发送对象或通道未启用该能力
+)
+
+// ChannelDelivery 单个通道的投递结果，记录到订阅日志 NotifyResult.channels
+type ChannelDelivery struct {
+	ChannelID  uint             `json:"id"`
+	Name       string           `json:"name"`
+	Type       string           `json:"type"`
+	Status     string           `json:"status"`
+	Error      string           `json:"error,omitempty"`
+	Recipients []DeliveryResult `json:"recipients,omitempty"` // 短信/电话按号码的投递结果
+}
+
+// Send 发送通知（firing 或 resolved），返回通道投递结果
 // phones: 接收用户手机号列表，nil=@all，空切片=不@任何人，非空切片=@指定用户
 // userIDs: 接收用户ID列表，用于邮件通道直接查询邮箱
-func (s *NotifyService) Send(ctx context.Context, ch *biz.AlertNotifyChannel, event *biz.AlertEvent, isResolve bool, phones []string, userIDs []uint) {
+func (s *NotifyService) Send(ctx context.Context, ch *biz.AlertNotifyChannel, event *biz.AlertEvent, isResolve bool, phones []string, userIDs []uint) *ChannelDelivery {
 	appLogger.Info("发送通知",
 		zap.String("channel", ch.Name),
 		zap.Bool("isResolve", isResolve),
@@ -78,29 +100,85 @@ func (s *NotifyService) Send(ctx context.Context, ch *biz.AlertNotifyChannel, ev
 
 	msg := renderTemplate(tplStr, event, phones)
 
+	delivery := &ChannelDelivery{ChannelID: ch.ID, Name: ch.Name, Type: ch.Type, Status: ChannelStatusSent}
 	var err error
 	switch ch.Type {
 	case "email":
 		err = s.sendEmail(ctx, ch.Config, msg, userIDs)
 	case "wechat_work":
 		err = sendWechatWork(ch.Config, msg, phones, isResolve)
+		if err == nil && !isResolve {
+			// 模板卡片为附加投递，失败只记录日志，不影响群消息的投递状态
+			if cardErr := sendWecomEventCard(ch.Config, event); cardErr != nil {
+				appLogger.Warn("企业微信告警卡片发送失败", zap.String("channel", ch.Name), zap.Error(cardErr))
+			}
+		}
 	case "dingtalk":
-		err = sendDingTalk(ch.Config, msg, phones)
+		var card map[string]interface{}
+		if !isResolve {
+			card = dingTalkEventCard(msg, event, time.Now())
+		}
+		err = sendDingTalk(ch.Config, msg, phones, card)
+	case "feishu":
+		err = sendFeishu(ch.Config, msg, phones, isResolve)
+	case "slack":
+		err = sendSlack(ch.Config, msg, phones)
+	case "teams":
+		err = sendTeams(ch.Config, msg, isResolve)
+	case "webhook":
+		err = sendWebhook(ch.Config, msg)
 	case "sms":
-		err = sendSMS(ch.Config, msg, phones)
+		delivery.Recipients, err = sendMessage(ctx, ch.ID, ch.Config, msg, phones, false)
 	case "phone":
-		err = sendPhone(ch.Config, msg, phones)
+		delivery.Recipients, err = sendMessage(ctx, ch.ID, ch.Config, msg, phones, true)
 	case "ai_agent":
-		if ch.AIHookEnabled {
-			err = sendAIAgent(ch.Config, msg, event)
+		if !ch.AIHookEnabled {
+			delivery.Status = ChannelStatusSkipped
+			return delivery
 		}
+		err = sendAIAgent(ch.Config, msg, event)
 	default:
 		appLogger.Warn("未知通知通道类型", zap.String("type", ch.Type))
-		return
+		delivery.Status = ChannelStatusFailed
+		delivery.Error = "未知通知通道类型: " + ch.Type
+		return delivery
 	}
+	delivery.Status = deliveryStatus(ch.Type, delivery.Recipients, err)
 	if err != nil {
+		delivery.Error = err.Error()
 		appLogger.Error("告警通知发送失败", zap.String("channel", ch.Name), zap.Error(err))
 	}
+	return delivery
+}
+
+// deliveryStatus 汇总通道投递状态；短信/电话按号码结果统计
+func deliveryStatus(channelType string, recipients []DeliveryResult, err error) string {
+	if channelType != "sms" && channelType != "phone" {
+		if err != nil {
+			return ChannelStatusFailed
+		}
+		return ChannelStatusSent
+	}
+	if len(recipients) == 0 {
+		if err != nil {
+			return ChannelStatusFailed
+		}
+		return ChannelStatusSkipped
+	}
+	sent := 0
+	for _, r := range recipients {
+		if r.Status == DeliveryStatusSent {
+			sent++
+		}
+	}
+	switch {
+	case sent == len(recipients):
+		return ChannelStatusSent
+	case sent > 0:
+		return ChannelStatusPartial
+	default:
+		return ChannelStatusFailed
+	}
 }
 
 func severityLabel(s string) string {
@@ -133,8 +211,19 @@ func formatKVDetail(jsonStr string) string {
 	return strings.TrimRight(sb.String(), "\n")
 }
 
+// notifyTplFuncs 模板函数；json 用于在 webhook JSON 模板中安全输出字符串或对象
+var notifyTplFuncs = template.FuncMap{
+	"json": func(v interface{}) string {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "null"
+		}
+		return string(b)
+	},
+}
+
 func renderTemplate(tplStr string, event *biz.AlertEvent, phones []string) string {
-	tpl, err := template.New("").Parse(tplStr)
+	tpl, err := template.New("").Funcs(notifyTplFuncs).Parse(tplStr)
 	if err != nil {
 		return tplStr
 	}
@@ -175,8 +264,20 @@ func renderTemplate(tplStr string, event *biz.AlertEvent, phones []string) strin
 		resolveValueFormatted = fmt.Sprintf("%.2f", *event.ResolveValue)
 	}
 
+	annotationsMap := make(map[string]string)
+	if event.Annotations != "" {
+		json.Unmarshal([]byte(event.Annotations), &annotationsMap)
+	}
+	status := event.Status
+	if status == "" {
+		status = "firing"
+	}
+
 	// 构建模板数据（使用 map 支持动态字段）
 	data := map[string]interface{}{
+		"EventID":           event.ID,
+		"Fingerprint":       event.Fingerprint,
+		"Status":            status,
 		"RuleName":          event.RuleName,
 		"Severity":          event.Severity,
 		"SeverityLabel":     severityLabel(event.Severity),
@@ -192,11 +293,30 @@ func renderTemplate(tplStr string, event *biz.AlertEvent, phones []string) strin
 		"Title":             annTitle,
 		"Description":       annDesc,
 		"Mentions":          mentions,
+		"LabelsMap":         labelsMap,      // 标签 map，配合 json 函数输出 JSON 对象
+		"AnnotationsMap":    annotationsMap, // 注解 map
 	}
+	data["ResolvedAt"] = ""
 	if event.ResolvedAt != nil {
 		data["ResolvedAt"] = event.ResolvedAt.Format("2006-01-02 15:04:05")
 	}
 
+	// 一键处理链接：未配置外部访问地址或告警已恢复时为空
+	now := time.Now()
+	ackURL, silenceURL, resolveURL := "", "", ""
+	if status == "firing" {
+		ackURL = EventActionURL(event.ID, biz.EventActionAck, now)
+		silenceURL = EventActionURL(event.ID, biz.EventActionSilence, now)
+		resolveURL = EventActionURL(event.ID, biz.EventActionResolve, now)
+	}
+	data["AckURL"] = ackURL
+	data["SilenceURL"] = silenceURL
+	data["ResolveURL"] = resolveURL
+	data["ActionLinks"] = ""
+	if ackURL != "" {
+		data["ActionLinks"] = fmt.Sprintf("[确认](%s) | [屏蔽1小时](%s) | [解决](%s)", ackURL, silenceURL, resolveURL)
+	}
+
 	// 将 Labels 中的字段添加到模板数据中，支持 {{.instance}}、{{.job}} 等
 	for k, v := range labelsMap {
 		data[k] = v
@@ -486,7 +606,9 @@ func sendEmailWithSTARTTLS(addr, host, user, password, from string, to []string,
 	return client.Quit()
 }
 
-func sendDingTalk(configJSON, msg string, phones []string) error {
+// sendDingTalk 发送钉钉群消息；card 非空时以 ActionCard 发送处理按钮
+// ActionCard 不支持 @，需要 @ 时先发送一条提醒文本
+func sendDingTalk(configJSON, msg string, phones []string, card map[string]interface{}) error {
 	var cfg struct {
 		WebhookURL string `json:"webhookUrl"`
 		Secret     string `json:"secret"`
@@ -503,32 +625,57 @@ func sendDingTalk(configJSON, msg string, phones []string) error {
 	} else {
 		atObj = map[string]interface{}{"isAtAll": false}
 	}
-	payload := map[string]interface{}{
-		"msgtype": "markdown",
-		"markdown": map[string]string{
-			"title": "SreHub 告警通知",
-			"text":  msg,
-		},
-		"at": atObj,
-	}
-	webhookURL := cfg.WebhookURL
-	if cfg.Secret != "" {
-		timestamp := fmt.Sprintf("%d", time.Now().UnixMilli())
-		webhookURL = fmt.Sprintf("%s&timestamp=%s", webhookURL, timestamp)
+	if card == nil {
+		return postDingTalk(cfg.WebhookURL, cfg.Secret, map[string]interface{}{
+			"msgtype": "markdown",
+			"markdown": map[string]string{
+				"title": "SreHub 告警通知",
+				"text":  msg,
+			},
+			"at": atObj,
+		})
+	}
+	if phones == nil || len(phones) > 0 {
+		if err := postDingTalk(cfg.WebhookURL, cfg.Secret, map[string]interface{}{
+			"msgtype": "text",
+			"text":    map[string]string{"content": "告警通知，请相关人员及时处理！"},
+			"at":      atObj,
+		}); err != nil {
+			return err
+		}
 	}
-	return postJSON(webhookURL, payload)
+	return postDingTalk(cfg.WebhookURL, cfg.Secret, map[string]interface{}{
+		"msgtype":    "actionCard",
+		"actionCard": card,
+	})
 }
 
-func sendSMS(configJSON, msg string, phones []string) error {
-	// 预留：调用阿里云/腾讯云短信 SDK，当前仅记录日志
-	appLogger.Info("[SMS通知预留]", zap.String("msg", msg), zap.Strings("phones", phones))
+// postDingTalk 发送钉钉机器人消息并检查业务错误码
+func postDingTalk(webhookURL, secret string, payload map[string]interface{}) error {
+	if secret != "" {
+		// 加签模式：sign = Base64(HmacSHA256(secret, timestamp + "\n" + secret))
+		timestamp := fmt.Sprintf("%d", time.Now().UnixMilli())
+		webhookURL = fmt.Sprintf("%s&timestamp=%s&sign=%s", webhookURL, timestamp,
+			url.QueryEscape(dingTalkSign(timestamp, secret)))
+	}
+	body, err := postJSONResponse(webhookURL, payload, nil)
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if json.Unmarshal(body, &resp) == nil && resp.ErrCode != 0 {
+		return fmt.Errorf("钉钉返回错误 %d: %s", resp.ErrCode, resp.ErrMsg)
+	}
 	return nil
 }
 
-func sendPhone(configJSON, msg string, phones []string) error {
-	// 预留：调用语音通话 SDK
-	appLogger.Info("[电话通知预留]", zap.String("msg", msg), zap.Strings("phones", phones))
-	return nil
+func dingTalkSign(timestamp, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + secret))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
 }
 
 func sendAIAgent(configJSON, msg string, event *biz.AlertEvent) error {
@@ -588,6 +735,41 @@ func postJSON(url string, payload interface{}) error {
 	return nil
 }
 
+// postJSONResponse 与 postJSON 相同，但返回响应体供调用方检查业务错误码
+func postJSONResponse(url string, payload interface{}, headers map[string]string) ([]byte, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return doWebhookRequest(http.MethodPost, url, body, headers)
+}
+
+func doWebhookRequest(method, url string, body []byte, headers map[string]string) ([]byte, error) {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := webhookHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode >= 300 {
+		return b, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(b))
+	}
+	return b, nil
+}
+
+// DefaultTemplate 返回通道类型的默认通知模板
+func DefaultTemplate(channelType string, isResolve bool) string {
+	return defaultTemplate(channelType, isResolve)
+}
+
 func defaultTemplate(channelType string, isResolve bool) string {
 	if isResolve {
 		switch channelType {
@@ -621,6 +803,46 @@ func defaultTemplate(channelType string, isResolve bool) string {
 **注解详情**:
 {{.AnnotationsDetail}}
 `)
+		case "feishu":
+			return strings.TrimSpace(`
+**规则**: {{.RuleName}}
+**级别**: {{.SeverityLabel}}
+**恢复值**: {{if .ResolveValue}}{{.ResolveValue}}{{else}}{{.Value}}{{end}}
+**恢复时间**: {{.ResolvedAt}}
+**触发时间**: {{.FiredAt}}
+
+**标签详情**:
+{{.LabelsDetail}}
+
+**注解详情**:
+{{.AnnotationsDetail}}
+`)
+		case "slack":
+			return strings.TrimSpace(`
+:white_check_mark: *SreHub 恢复通知*
+*规则*: {{.RuleName}}
+*级别*: {{.SeverityLabel}}
+*恢复值*: {{if .ResolveValue}}{{.ResolveValue}}{{else}}{{.Value}}{{end}}
+*恢复时间*: {{.ResolvedAt}}
+*触发时间*: {{.FiredAt}}
+
+*标签详情*:
+{{.LabelsDetail}}
+`)
+		case "teams":
+			return strings.TrimSpace(`
+- **规则**: {{.RuleName}}
+- **级别**: {{.SeverityLabel}}
+- **恢复值**: {{if .ResolveValue}}{{.ResolveValue}}{{else}}{{.Value}}{{end}}
+- **恢复时间**: {{.ResolvedAt}}
+- **触发时间**: {{.FiredAt}}
+
+**标签详情**:
+
+{{.LabelsDetail}}
+`)
+		case "webhook":
+			return defaultWebhookTemplate
 		default:
 			return strings.TrimSpace(`【SreHub恢复】规则: {{.RuleName}} | 级别: {{.SeverityLabel}} | 值: {{if .ResolveValue}}{{.ResolveValue}}{{else}}{{.Value}}{{end}} | 恢复时间: {{.ResolvedAt}}`)
 		}
@@ -639,6 +861,8 @@ func defaultTemplate(channelType string, isResolve bool) string {
 
 **注解详情**:
 {{.AnnotationsDetail}}
+{{if .ActionLinks}}
+{{.ActionLinks}}{{end}}
 `)
 	case "dingtalk":
 		return strings.TrimSpace(`
@@ -653,7 +877,59 @@ func defaultTemplate(channelType string, isResolve bool) string {
 
 **注解详情**:
 {{.AnnotationsDetail}}
+{{if .ActionLinks}}
+{{.ActionLinks}}{{end}}
+`)
+	case "feishu":
+		return strings.TrimSpace(`
+**规则**: {{.RuleName}}
+**级别**: {{.SeverityLabel}}
+**当前值**: {{.Value}}
+**触发时间**: {{.FiredAt}}
+
+**标签详情**:
+{{.LabelsDetail}}
+
+**注解详情**:
+{{.AnnotationsDetail}}
+{{if .ActionLinks}}
+{{.ActionLinks}}{{end}}
+`)
+	case "slack":
+		return strings.TrimSpace(`
+:red_circle: *SreHub 告警通知*
+*规则*: {{.RuleName}}
+*级别*: {{.SeverityLabel}}
+*当前值*: {{.Value}}
+*触发时间*: {{.FiredAt}}
+
+*标签详情*:
+{{.LabelsDetail}}
+
+*注解详情*:
+{{.AnnotationsDetail}}
+{{if .AckURL}}
+<{{.AckURL}}|确认> | <{{.SilenceURL}}|屏蔽1小时> | <{{.ResolveURL}}|解决>{{end}}
+`)
+	case "teams":
+		return strings.TrimSpace(`
+- **规则**: {{.RuleName}}
+- **级别**: {{.SeverityLabel}}
+- **当前值**: {{.Value}}
+- **触发时间**: {{.FiredAt}}
+
+**标签详情**:
+
+{{.LabelsDetail}}
+
+**注解详情**:
+
+{{.AnnotationsDetail}}
+{{if .ActionLinks}}
+{{.ActionLinks}}{{end}}
 `)
+	case "webhook":
+		return defaultWebhookTemplate
 	default:
 		return strings.TrimSpace(`【SreHub告警】规则: {{.RuleName}} | 级别: {{.SeverityLabel}} | 值: {{.Value}} | 时间: {{.FiredAt}}`)
 	}
@@ -672,11 +948,17 @@ func (s *NotifyService) SendPatrolReport(ctx context.Context, ch *biz.AlertNotif
 	case "wechat_work":
 		err = sendWechatWork(ch.Config, content, phones, false)
 	case "dingtalk":
-		err = sendDingTalk(ch.Config, content, phones)
+		err = sendDingTalk(ch.Config, content, phones, nil)
+	case "feishu":
+		err = sendFeishu(ch.Config, content, phones, false)
+	case "slack":
+		err = sendSlack(ch.Config, content, phones)
+	case "teams":
+		err = sendTeams(ch.Config, content, false)
 	case "sms":
-		err = sendSMS(ch.Config, content, phones)
+		_, err = sendMessage(ctx, ch.ID, ch.Config, content, phones, false)
 	case "phone":
-		err = sendPhone(ch.Config, content, phones)
+		_, err = sendMessage(ctx, ch.ID, ch.Config, content, phones, true)
 	case "ai_agent":
 		if ch.AIHookEnabled {
 			// AI Agent 通道需要构造一个临时的 AlertEvent 对象
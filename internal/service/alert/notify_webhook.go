@@ -0,0 +1,196 @@
+package alert
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// webhookHTTPClient IM / 通用 Webhook 请求客户端
+var webhookHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// defaultWebhookTemplate 通用 Webhook 默认请求体模板（渲染结果必须为合法 JSON）
+const defaultWebhookTemplate = `{
+  "eventId": {{json .EventID}},
+  "status": {{json .Status}},
+  "ruleName": {{json .RuleName}},
+  "severity": {{json .Severity}},
+  "value": {{json .ValueRaw}},
+  "resolveValue": {{json .ResolveValueRaw}},
+  "firedAt": {{json .FiredAt}},
+  "resolvedAt": {{json .ResolvedAt}},
+  "fingerprint": {{json .Fingerprint}},
+  "labels": {{json .LabelsMap}},
+  "annotations": {{json .AnnotationsMap}}
+}`
+
+// sendFeishu 飞书/Lark 自定义机器人，使用消息卡片发送
+func sendFeishu(configJSON, msg string, phones []string, isResolve bool) error {
+	var cfg struct {
+		WebhookURL string `json:"webhookUrl"`
+		Secret     string `json:"secret"`
+	}
+	if err := json.Unmarshal([]byte(configJSON), &cfg); err != nil {
+		return err
+	}
+
+	// 自定义机器人只能按 open_id @成员，手机号无法直接使用；phones 为 nil 时 @所有人
+	if phones == nil {
+		msg += "\n<at id=all></at>"
+	}
+	title, color := "🔴 SreHub 告警通知", "red"
+	if isResolve {
+		title, color = "✅ SreHub 恢复通知", "green"
+	}
+	payload := map[string]interface{}{
+		"msg_type": "interactive",
+		"card": map[string]interface{}{
+			"config": map[string]interface{}{"wide_screen_mode": true},
+			"header": map[string]interface{}{
+				"title":    map[string]string{"tag": "plain_text", "content": title},
+				"template": color,
+			},
+			"elements": []interface{}{
+				map[string]interface{}{
+					"tag":  "div",
+					"text": map[string]string{"tag": "lark_md", "content": msg},
+				},
+			},
+		},
+	}
+	if cfg.Secret != "" {
+		timestamp := fmt.Sprintf("%d", time.Now().Unix())
+		payload["timestamp"] = timestamp
+		payload["sign"] = feishuSign(timestamp, cfg.Secret)
+	}
+
+	body, err := postJSONResponse(cfg.WebhookURL, payload, nil)
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if json.Unmarshal(body, &resp) == nil && resp.Code != 0 {
+		return fmt.Errorf("飞书返回错误 %d: %s", resp.Code, resp.Msg)
+	}
+	return nil
+}
+
+// feishuSign 飞书签名校验：以 timestamp + "\n" + secret 为密钥对空串做 HmacSHA256
+func feishuSign(timestamp, secret string) string {
+	mac := hmac.New(sha256.New, []byte(timestamp+"\n"+secret))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// sendSlack Slack Incoming Webhook，消息使用 mrkdwn 格式
+func sendSlack(configJSON, msg string, phones []string) error {
+	var cfg struct {
+		WebhookURL string `json:"webhookUrl"`
+	}
+	if err := json.Unmarshal([]byte(configJSON), &cfg); err != nil {
+		return err
+	}
+	if phones == nil {
+		msg = "<!channel>\n" + msg
+	}
+	payload := map[string]interface{}{
+		"text": msg,
+		"blocks": []interface{}{
+			map[string]interface{}{
+				"type": "section",
+				"text": map[string]string{"type": "mrkdwn", "text": msg},
+			},
+		},
+	}
+	_, err := postJSONResponse(cfg.WebhookURL, payload, nil)
+	return err
+}
+
+// sendTeams Microsoft Teams Webhook（Workflows / Incoming Webhook），使用 Adaptive Card
+func sendTeams(configJSON, msg string, isResolve bool) error {
+	var cfg struct {
+		WebhookURL string `json:"webhookUrl"`
+	}
+	if err := json.Unmarshal([]byte(configJSON), &cfg); err != nil {
+		return err
+	}
+	title, color := "🔴 SreHub 告警通知", "Attention"
+	if isResolve {
+		title, color = "✅ SreHub 恢复通知", "Good"
+	}
+	payload := map[string]interface{}{
+		"type": "message",
+		"attachments": []interface{}{
+			map[string]interface{}{
+				"contentType": "application/vnd.microsoft.card.adaptive",
+				"content": map[string]interface{}{
+					"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+					"type":    "AdaptiveCard",
+					"version": "1.4",
+					"body": []interface{}{
+						map[string]interface{}{"type": "TextBlock", "text": title, "weight": "Bolder", "size": "Medium", "color": color},
+						map[string]interface{}{"type": "TextBlock", "text": msg, "wrap": true},
+					},
+				},
+			},
+		},
+	}
+	_, err := postJSONResponse(cfg.WebhookURL, payload, nil)
+	return err
+}
+
+// webhookChannelConfig 通用 Webhook 配置，请求体为渲染后的通知模板
+type webhookChannelConfig struct {
+	URL             string            `json:"url"`
+	Method          string            `json:"method"`          // POST（默认）、PUT
+	Headers         map[string]string `json:"headers"`         // 自定义请求头
+	Secret          string            `json:"secret"`          // 非空时对请求体做 HMAC-SHA256 签名
+	SignatureHeader string            `json:"signatureHeader"` // 签名请求头，默认 X-SreHub-Signature
+}
+
+// sendWebhook 通用 Webhook：body 为 Go 模板渲染出的 JSON，
+// 配置 secret 时附加签名头 "sha256=" + hex(HmacSHA256(secret, body))
+func sendWebhook(configJSON, body string) error {
+	var cfg webhookChannelConfig
+	if err := json.Unmarshal([]byte(configJSON), &cfg); err != nil {
+		return err
+	}
+	if cfg.URL == "" {
+		return fmt.Errorf("未配置 Webhook URL")
+	}
+	if !json.Valid([]byte(body)) {
+		return fmt.Errorf("通知模板渲染结果不是合法 JSON，字符串字段请使用 {{json .Field}} 输出")
+	}
+	method := strings.ToUpper(cfg.Method)
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	headers := make(map[string]string, len(cfg.Headers)+1)
+	for k, v := range cfg.Headers {
+		headers[k] = v
+	}
+	if cfg.Secret != "" {
+		header := cfg.SignatureHeader
+		if header == "" {
+			header = "X-SreHub-Signature"
+		}
+		headers[header] = "sha256=" + webhookSignature(cfg.Secret, []byte(body))
+	}
+	_, err := doWebhookRequest(method, cfg.URL, []byte(body), headers)
+	return err
+}
+
+func webhookSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
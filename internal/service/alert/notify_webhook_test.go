@@ -0,0 +1,235 @@
+package alert
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	biz "github.com/ydcloud-dy/opshub/internal/biz/alert"
+)
+
+func TestDefaultWebhookTemplate_RendersValidJSON(t *testing.T) {
+	event := &biz.AlertEvent{
+		ID:          7,
+		RuleName:    `CPU "高" 告警`,
+		Severity:    "critical",
+		Status:      "firing",
+		Value:       92.5,
+		FiredAt:     time.Date(2026, 5, 5, 10, 0, 0, 0, time.UTC),
+		Labels:      `{"instance":"10.0.0.1:9100","job":"node"}`,
+		Annotations: `{"description":"line1\nline2"}`,
+	}
+	for _, isResolve := range []bool{false, true} {
+		out := renderTemplate(defaultTemplate("webhook", isResolve), event, nil)
+		var got struct {
+			EventID  uint              `json:"eventId"`
+			RuleName string            `json:"ruleName"`
+			Value    float64           `json:"value"`
+			Labels   map[string]string `json:"labels"`
+		}
+		if err := json.Unmarshal([]byte(out), &got); err != nil {
+			t.Fatalf("rendered body is not valid JSON: %v\n%s", err, out)
+		}
+		if got.EventID != 7 || got.RuleName != event.RuleName || got.Value != 92.5 || got.Labels["job"] != "node" {
+			t.Errorf("unexpected payload %+v", got)
+		}
+	}
+}
+
+func TestDefaultTemplate_NewChannelTypes(t *testing.T) {
+	event := &biz.AlertEvent{RuleName: "磁盘告警", Severity: "major", Labels: `{}`, Annotations: `{}`}
+	for _, typ := range []string{"feishu", "slack", "teams"} {
+		for _, isResolve := range []bool{false, true} {
+			out := renderTemplate(defaultTemplate(typ, isResolve), event, nil)
+			if !strings.Contains(out, "磁盘告警") || strings.Contains(out, "<no value>") {
+				t.Errorf("%s (resolve=%v) rendered %q", typ, isResolve, out)
+			}
+		}
+	}
+}
+
+func TestSendWebhook_SignatureAndHeaders(t *testing.T) {
+	body := `{"ruleName":"test"}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ := io.ReadAll(r.Body)
+		if r.Method != http.MethodPut {
+			t.Errorf("method = %s, want PUT", r.Method)
+		}
+		if string(got) != body {
+			t.Errorf("body = %s", got)
+		}
+		if r.Header.Get("X-Token") != "abc" {
+			t.Errorf("custom header missing")
+		}
+		if sig := r.Header.Get("X-Sign"); sig != "sha256="+webhookSignature("s3cret", got) {
+			t.Errorf("signature = %q", sig)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := `{"url":"` + srv.URL + `","method":"put","headers":{"X-Token":"abc"},"secret":"s3cret","signatureHeader":"X-Sign"}`
+	if err := sendWebhook(cfg, body); err != nil {
+		t.Fatalf("sendWebhook() error: %v", err)
+	}
+	if err := sendWebhook(cfg, `{"broken": "a`); err == nil {
+		t.Error("expected error for invalid JSON body")
+	}
+}
+
+func TestSendFeishu_Signed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Timestamp string `json:"timestamp"`
+			Sign      string `json:"sign"`
+			MsgType   string `json:"msg_type"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		if payload.MsgType != "interactive" || payload.Sign != feishuSign(payload.Timestamp, "fs-secret") {
+			w.Write([]byte(`{"code":19021,"msg":"sign match fail or timestamp is not within one hour from current time"}`))
+			return
+		}
+		w.Write([]byte(`{"code":0,"msg":"success"}`))
+	}))
+	defer srv.Close()
+
+	if err := sendFeishu(`{"webhookUrl":"`+srv.URL+`","secret":"fs-secret"}`, "msg", nil, false); err != nil {
+		t.Errorf("signed request rejected: %v", err)
+	}
+	if err := sendFeishu(`{"webhookUrl":"`+srv.URL+`","secret":"wrong"}`, "msg", nil, false); err == nil {
+		t.Error("expected error when feishu returns non-zero code")
+	}
+}
+
+func TestSendDingTalk_Signed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("sign") != dingTalkSign(q.Get("timestamp"), "ding-secret") {
+			w.Write([]byte(`{"errcode":310000,"errmsg":"sign not match"}`))
+			return
+		}
+		w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+	}))
+	defer srv.Close()
+
+	if err := sendDingTalk(`{"webhookUrl":"`+srv.URL+`/robot/send?access_token=t","secret":"ding-secret"}`, "msg", nil, nil); err != nil {
+		t.Errorf("signed request rejected: %v", err)
+	}
+	if err := sendDingTalk(`{"webhookUrl":"`+srv.URL+`/robot/send?access_token=t","secret":"wrong"}`, "msg", nil, nil); err == nil {
+		t.Error("expected error when dingtalk returns non-zero errcode")
+	}
+}
+
+func TestSendDingTalk_ActionCard(t *testing.T) {
+	ConfigureEventActionLinks("https://ops.example.com", "secret")
+	defer ConfigureEventActionLinks("", "")
+
+	var msgTypes []string
+	var btns []map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			MsgType    string `json:"msgtype"`
+			ActionCard struct {
+				Btns []map[string]string `json:"btns"`
+			} `json:"actionCard"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		msgTypes = append(msgTypes, payload.MsgType)
+		if payload.MsgType == "actionCard" {
+			btns = payload.ActionCard.Btns
+		}
+		w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+	}))
+	defer srv.Close()
+
+	event := &biz.AlertEvent{ID: 9, RuleName: "cpu", Status: "firing"}
+	card := dingTalkEventCard("msg", event, time.Now())
+	if err := sendDingTalk(`{"webhookUrl":"`+srv.URL+`/robot/send?access_token=t"}`, "msg", []string{"13800000000"}, card); err != nil {
+		t.Fatalf("sendDingTalk() error = %v", err)
+	}
+	// 需要 @ 时先发提醒文本，再发带处理按钮的 ActionCard
+	if strings.Join(msgTypes, ",") != "text,actionCard" || len(btns) != 3 {
+		t.Fatalf("msgtypes = %v, btns = %v", msgTypes, btns)
+	}
+	if !strings.HasPrefix(btns[0]["actionURL"], "https://ops.example.com/api/v1/alert/event-actions?") || !strings.Contains(btns[0]["actionURL"], "action=ack") {
+		t.Errorf("ack button = %v", btns[0])
+	}
+
+	ConfigureEventActionLinks("", "secret")
+	if dingTalkEventCard("msg", event, time.Now()) != nil {
+		t.Error("card built without external base URL")
+	}
+}
+
+func TestSendWecomEventCard(t *testing.T) {
+	ConfigureEventActionLinks("", "secret")
+	defer ConfigureEventActionLinks("", "")
+
+	var sent struct {
+		ToUser       string `json:"touser"`
+		MsgType      string `json:"msgtype"`
+		AgentID      int64  `json:"agentid"`
+		TemplateCard struct {
+			CardType   string `json:"card_type"`
+			ButtonList []struct {
+				Key string `json:"key"`
+			} `json:"button_list"`
+		} `json:"template_card"`
+	}
+	tokenCalls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cgi-bin/gettoken":
+			tokenCalls++
+			if r.URL.Query().Get("corpsecret") != "corp-secret" {
+				w.Write([]byte(`{"errcode":40001,"errmsg":"invalid secret"}`))
+				return
+			}
+			w.Write([]byte(`{"errcode":0,"access_token":"tk","expires_in":7200}`))
+		case "/cgi-bin/message/send":
+			if r.URL.Query().Get("access_token") != "tk" {
+				w.Write([]byte(`{"errcode":42001,"errmsg":"expired"}`))
+				return
+			}
+			json.NewDecoder(r.Body).Decode(&sent)
+			w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+		}
+	}))
+	defer srv.Close()
+	defer func(base string) { wecomAPIBase = base }(wecomAPIBase)
+	wecomAPIBase = srv.URL
+
+	event := &biz.AlertEvent{ID: 21, RuleName: "disk", Status: "firing", FiredAt: time.Now()}
+	cfg := `{"corpId":"ww1","corpSecret":"corp-secret","agentId":1000002,"userMapping":{"lisi":"lisi","zhangsan":"admin"}}`
+	for i := 0; i < 2; i++ {
+		if err := sendWecomEventCard(cfg, event); err != nil {
+			t.Fatalf("sendWecomEventCard() error = %v", err)
+		}
+	}
+	if tokenCalls != 1 {
+		t.Errorf("gettoken called %d times, want cached token", tokenCalls)
+	}
+	if sent.ToUser != "lisi|zhangsan" || sent.MsgType != "template_card" || sent.AgentID != 1000002 ||
+		sent.TemplateCard.CardType != "button_interaction" || len(sent.TemplateCard.ButtonList) != 3 {
+		t.Fatalf("sent = %+v", sent)
+	}
+	// 按钮 key 可被应用回调校验，对应确认/屏蔽/解决
+	wantActions := []string{biz.EventActionAck, biz.EventActionSilence, biz.EventActionResolve}
+	for i, b := range sent.TemplateCard.ButtonList {
+		action, eventID, err := VerifyEventActionCardKey(b.Key, time.Now())
+		if err != nil || action != wantActions[i] || eventID != 21 {
+			t.Errorf("button %d key = %q: %s,%d,%v", i, b.Key, action, eventID, err)
+		}
+	}
+
+	// 未配置应用凭据时不发送卡片
+	if err := sendWecomEventCard(`{"corpId":"ww1","userMapping":{"lisi":"lisi"}}`, event); err != nil {
+		t.Errorf("card without app credentials = %v, want skipped", err)
+	}
+	if err := sendWecomEventCard(`{"corpId":"ww2","corpSecret":"bad","agentId":1,"userMapping":{"lisi":"lisi"}}`, event); err == nil {
+		t.Error("expected gettoken error")
+	}
+}
@@ -0,0 +1,290 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+
+	biz "github.com/ydcloud-dy/opshub/internal/biz/alert"
+	alertdata "github.com/ydcloud-dy/opshub/internal/data/alert"
+)
+
+// onCallTimeLayout 值班层起点时间格式（按值班表时区解析）
+const onCallTimeLayout = "2006-01-02 15:04"
+
+// maxOnCallShiftRange 班次预览的最大时间跨度
+const maxOnCallShiftRange = 90 * 24 * time.Hour
+
+// OnCallLayer 值班轮换层
+type OnCallLayer struct {
+	Name         string `json:"name"`
+	RotationType string `json:"rotationType"` // daily, weekly
+	Interval     int    `json:"interval"`     // 每班持续的天数/周数，默认 1
+	Start        string `json:"start"`        // 轮换起点，同时决定交接时刻，如 "2026-01-05 09:00"
+	UserIDs      []uint `json:"userIds"`      // 轮换顺序
+}
+
+// OnCallShift 值班班次
+type OnCallShift struct {
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	UserID   uint      `json:"userId"`
+	Layer    string    `json:"layer"`    // 所属层名称，替换班次为空
+	Override bool      `json:"override"` // 是否为临时替换
+}
+
+// onCallPlan 解析后的值班表
+type onCallPlan struct {
+	layers    []onCallLayerPlan
+	overrides []*biz.AlertOnCallOverride
+}
+
+type onCallLayerPlan struct {
+	OnCallLayer
+	start time.Time
+}
+
+// ParseOnCallLayers 解析并校验值班层配置
+func ParseOnCallLayers(layersJSON string) ([]OnCallLayer, error) {
+	if layersJSON == "" || layersJSON == "null" {
+		return nil, nil
+	}
+	var layers []OnCallLayer
+	if err := json.Unmarshal([]byte(layersJSON), &layers); err != nil {
+		return nil, fmt.Errorf("值班层配置格式错误: %v", err)
+	}
+	for i, l := range layers {
+		if l.RotationType != biz.OnCallRotationDaily && l.RotationType != biz.OnCallRotationWeekly {
+			return nil, fmt.Errorf("第 %d 层轮换类型无效: %s", i+1, l.RotationType)
+		}
+		if l.Interval < 0 {
+			return nil, fmt.Errorf("第 %d 层轮换间隔无效: %d", i+1, l.Interval)
+		}
+		if _, err := time.Parse(onCallTimeLayout, l.Start); err != nil {
+			return nil, fmt.Errorf("第 %d 层起点时间格式错误，应为 %s", i+1, onCallTimeLayout)
+		}
+		if len(l.UserIDs) == 0 {
+			return nil, fmt.Errorf("第 %d 层至少需要一名值班人员", i+1)
+		}
+	}
+	return layers, nil
+}
+
+// onCallLocation 值班表时区，空值使用服务器时区
+func onCallLocation(tz string) (*time.Location, error) {
+	if tz == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("时区无效: %s", tz)
+	}
+	return loc, nil
+}
+
+func newOnCallPlan(schedule *biz.AlertOnCallSchedule, overrides []*biz.AlertOnCallOverride) (*onCallPlan, error) {
+	loc, err := onCallLocation(schedule.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	layers, err := ParseOnCallLayers(schedule.Layers)
+	if err != nil {
+		return nil, err
+	}
+	plan := &onCallPlan{overrides: overrides}
+	for _, l := range layers {
+		start, _ := time.ParseInLocation(onCallTimeLayout, l.Start, loc)
+		if l.Interval == 0 {
+			l.Interval = 1
+		}
+		plan.layers = append(plan.layers, onCallLayerPlan{OnCallLayer: l, start: start})
+	}
+	return plan, nil
+}
+
+// shiftDays 每班天数
+func (l onCallLayerPlan) shiftDays() int {
+	if l.RotationType == biz.OnCallRotationWeekly {
+		return 7 * l.Interval
+	}
+	return l.Interval
+}
+
+// shiftAt 返回 t 所在班次的序号及起止时间（按日历天推进，夏令时下交接时刻保持不变）
+func (l onCallLayerPlan) shiftAt(t time.Time) (int, time.Time, time.Time) {
+	days := l.shiftDays()
+	period := time.Duration(days) * 24 * time.Hour
+	k := int(t.Sub(l.start) / period)
+	for k > 0 && l.start.AddDate(0, 0, k*days).After(t) {
+		k--
+	}
+	for !l.start.AddDate(0, 0, (k+1)*days).After(t) {
+		k++
+	}
+	return k, l.start.AddDate(0, 0, k*days), l.start.AddDate(0, 0, (k+1)*days)
+}
+
+// userAt 返回该层 t 时刻的值班人，t 早于轮换起点时返回 false
+func (l onCallLayerPlan) userAt(t time.Time) (uint, bool) {
+	if t.Before(l.start) || len(l.UserIDs) == 0 {
+		return 0, false
+	}
+	k, _, _ := l.shiftAt(t)
+	return l.UserIDs[k%len(l.UserIDs)], true
+}
+
+// at 返回 t 时刻的值班班次：临时替换优先（后创建的优先），其次靠后的层覆盖靠前的层
+func (p *onCallPlan) at(t time.Time) (OnCallShift, bool) {
+	var override *biz.AlertOnCallOverride
+	for _, o := range p.overrides {
+		if !t.Before(o.StartAt) && t.Before(o.EndAt) && (override == nil || o.ID > override.ID) {
+			override = o
+		}
+	}
+	if override != nil {
+		return OnCallShift{UserID: override.UserID, Override: true}, true
+	}
+	for i := len(p.layers) - 1; i >= 0; i-- {
+		if uid, ok := p.layers[i].userAt(t); ok {
+			return OnCallShift{UserID: uid, Layer: p.layers[i].Name}, true
+		}
+	}
+	return OnCallShift{}, false
+}
+
+// shifts 计算 [from, to) 内的值班班次，相邻的相同班次会合并
+func (p *onCallPlan) shifts(from, to time.Time) []OnCallShift {
+	points := []time.Time{from}
+	addPoint := func(t time.Time) {
+		if t.After(from) && t.Before(to) {
+			points = append(points, t)
+		}
+	}
+	for _, l := range p.layers {
+		addPoint(l.start)
+		cursor := from
+		if cursor.Before(l.start) {
+			cursor = l.start
+		}
+		if !cursor.Before(to) {
+			continue
+		}
+		_, _, end := l.shiftAt(cursor)
+		for end.Before(to) {
+			addPoint(end)
+			_, _, end = l.shiftAt(end)
+		}
+	}
+	for _, o := range p.overrides {
+		addPoint(o.StartAt)
+		addPoint(o.EndAt)
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Before(points[j]) })
+
+	var result []OnCallShift
+	for i, start := range points {
+		if i > 0 && start.Equal(points[i-1]) {
+			continue
+		}
+		end := to
+		for _, next := range points[i+1:] {
+			if next.After(start) {
+				end = next
+				break
+			}
+		}
+		shift, ok := p.at(start)
+		if !ok {
+			continue
+		}
+		if n := len(result); n > 0 {
+			last := &result[n-1]
+			if last.End.Equal(start) && last.UserID == shift.UserID && last.Layer == shift.Layer && last.Override == shift.Override {
+				last.End = end
+				continue
+			}
+		}
+		shift.Start, shift.End = start, end
+		result = append(result, shift)
+	}
+	return result
+}
+
+// OnCallService 值班服务
+type OnCallService struct {
+	db           *gorm.DB
+	scheduleRepo *alertdata.OnCallScheduleRepo
+}
+
+// NewOnCallService 创建值班服务
+func NewOnCallService(db *gorm.DB) *OnCallService {
+	return &OnCallService{
+		db:           db,
+		scheduleRepo: alertdata.NewOnCallScheduleRepo(db),
+	}
+}
+
+// CurrentOnCall 返回 at 时刻的值班班次（Start 为 at，End 为下一次交接时间），无人值班时返回 nil
+func (s *OnCallService) CurrentOnCall(ctx context.Context, scheduleID uint, at time.Time) (*OnCallShift, error) {
+	schedule, err := s.scheduleRepo.GetByID(ctx, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	shifts, err := s.Shifts(ctx, schedule, at, at.Add(maxOnCallShiftRange))
+	if err != nil {
+		return nil, err
+	}
+	if len(shifts) == 0 || !shifts[0].Start.Equal(at) {
+		return nil, nil
+	}
+	return &shifts[0], nil
+}
+
+// OnCallUserIDs 返回 at 时刻的值班人员 ID，值班表不存在或无人值班时返回空
+func (s *OnCallService) OnCallUserIDs(ctx context.Context, scheduleID uint, at time.Time) []uint {
+	shift, err := s.CurrentOnCall(ctx, scheduleID, at)
+	if err != nil || shift == nil {
+		return nil
+	}
+	return []uint{shift.UserID}
+}
+
+// Shifts 计算值班表在 [from, to) 内的班次
+func (s *OnCallService) Shifts(ctx context.Context, schedule *biz.AlertOnCallSchedule, from, to time.Time) ([]OnCallShift, error) {
+	if !to.After(from) {
+		return nil, fmt.Errorf("结束时间必须晚于开始时间")
+	}
+	if to.Sub(from) > maxOnCallShiftRange {
+		return nil, fmt.Errorf("时间跨度不能超过 %d 天", int(maxOnCallShiftRange.Hours()/24))
+	}
+	overrides, err := s.scheduleRepo.ListOverrides(ctx, schedule.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := newOnCallPlan(schedule, overrides)
+	if err != nil {
+		return nil, err
+	}
+	return plan.shifts(from, to), nil
+}
+
+// queryUserPhones 批量查询用户手机号（忽略未填写手机号的用户）
+func queryUserPhones(ctx context.Context, db *gorm.DB, userIDs []uint) []string {
+	phones := []string{}
+	if len(userIDs) == 0 {
+		return phones
+	}
+	type phoneRow struct{ Phone string }
+	var rows []phoneRow
+	db.WithContext(ctx).Table("sys_user").Select("phone").Where("id IN ? AND phone != ''", userIDs).Scan(&rows)
+	for _, r := range rows {
+		if r.Phone != "" {
+			phones = append(phones, r.Phone)
+		}
+	}
+	return phones
+}
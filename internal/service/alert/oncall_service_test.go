@@ -0,0 +1,225 @@
+package alert
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
+	"go.uber.org/zap"
+
+	biz "github.com/ydcloud-dy/opshub/internal/biz/alert"
+	alertdata "github.com/ydcloud-dy/opshub/internal/data/alert"
+	"github.com/ydcloud-dy/opshub/internal/testutil"
+)
+
+func mustOnCallPlan(t *testing.T, layers string, overrides ...*biz.AlertOnCallOverride) *onCallPlan {
+	t.Helper()
+	plan, err := newOnCallPlan(&biz.AlertOnCallSchedule{Timezone: "Asia/Shanghai", Layers: layers}, overrides)
+	if err != nil {
+		t.Fatalf("newOnCallPlan() error: %v", err)
+	}
+	return plan
+}
+
+func TestOnCallPlan_At(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Shanghai")
+	at := func(s string) time.Time {
+		ts, _ := time.ParseInLocation(onCallTimeLayout, s, loc)
+		return ts
+	}
+	layers := `[
+		{"name":"weekly","rotationType":"weekly","start":"2026-01-05 09:00","userIds":[1,2,3]},
+		{"name":"weekend","rotationType":"daily","interval":2,"start":"2026-01-10 00:00","userIds":[7]}
+	]`
+	override := &biz.AlertOnCallOverride{ID: 1, UserID: 9, StartAt: at("2026-01-13 12:00"), EndAt: at("2026-01-13 18:00")}
+	plan := mustOnCallPlan(t, layers, override)
+
+	tests := []struct {
+		at       string
+		wantUser uint
+		wantOK   bool
+	}{
+		{"2026-01-05 08:59", 0, false}, // 早于起点
+		{"2026-01-05 09:00", 1, true},
+		{"2026-01-09 23:59", 1, true},
+		{"2026-01-10 00:00", 7, true}, // 第二层覆盖第一层
+		{"2026-01-12 08:59", 7, true}, // weekend 层每 2 天一班，仍由 7 值班
+		{"2026-01-12 09:00", 7, true},
+		{"2026-01-13 12:30", 9, true}, // 临时替换优先
+		{"2026-01-13 18:00", 7, true}, // 替换结束（不含结束时刻）
+	}
+	for _, tt := range tests {
+		got, ok := plan.at(at(tt.at))
+		if ok != tt.wantOK || got.UserID != tt.wantUser {
+			t.Errorf("at(%s) = %d,%v want %d,%v", tt.at, got.UserID, ok, tt.wantUser, tt.wantOK)
+		}
+	}
+
+	// 单层按周轮换
+	single := mustOnCallPlan(t, `[{"name":"w","rotationType":"weekly","start":"2026-01-05 09:00","userIds":[1,2,3]}]`)
+	for s, want := range map[string]uint{
+		"2026-01-12 08:59": 1,
+		"2026-01-12 09:00": 2,
+		"2026-01-19 09:00": 3,
+		"2026-01-26 09:00": 1,
+	} {
+		if got, _ := single.at(at(s)); got.UserID != want {
+			t.Errorf("weekly at(%s) = %d, want %d", s, got.UserID, want)
+		}
+	}
+}
+
+func TestOnCallPlan_Shifts(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Shanghai")
+	at := func(s string) time.Time {
+		ts, _ := time.ParseInLocation(onCallTimeLayout, s, loc)
+		return ts
+	}
+	override := &biz.AlertOnCallOverride{ID: 1, UserID: 9, StartAt: at("2026-01-06 12:00"), EndAt: at("2026-01-06 18:00")}
+	plan := mustOnCallPlan(t, `[{"name":"d","rotationType":"daily","start":"2026-01-05 09:00","userIds":[1,2]}]`, override)
+
+	shifts := plan.shifts(at("2026-01-05 00:00"), at("2026-01-07 12:00"))
+	want := []struct {
+		start, end string
+		user       uint
+	}{
+		{"2026-01-05 09:00", "2026-01-06 09:00", 1},
+		{"2026-01-06 09:00", "2026-01-06 12:00", 2},
+		{"2026-01-06 12:00", "2026-01-06 18:00", 9},
+		{"2026-01-06 18:00", "2026-01-07 09:00", 2},
+		{"2026-01-07 09:00", "2026-01-07 12:00", 1},
+	}
+	if len(shifts) != len(want) {
+		t.Fatalf("shifts = %+v, want %d entries", shifts, len(want))
+	}
+	for i, w := range want {
+		s := shifts[i]
+		if !s.Start.Equal(at(w.start)) || !s.End.Equal(at(w.end)) || s.UserID != w.user {
+			t.Errorf("shift[%d] = %s~%s user %d, want %s~%s user %d", i,
+				s.Start.In(loc).Format(onCallTimeLayout), s.End.In(loc).Format(onCallTimeLayout), s.UserID, w.start, w.end, w.user)
+		}
+	}
+	if !shifts[2].Override {
+		t.Error("override shift not flagged")
+	}
+}
+
+func TestParseOnCallLayers_Invalid(t *testing.T) {
+	for _, layers := range []string{
+		`[{"rotationType":"monthly","start":"2026-01-05 09:00","userIds":[1]}]`,
+		`[{"rotationType":"daily","start":"2026/01/05","userIds":[1]}]`,
+		`[{"rotationType":"daily","start":"2026-01-05 09:00","userIds":[]}]`,
+		`{}`,
+	} {
+		if _, err := ParseOnCallLayers(layers); err == nil {
+			t.Errorf("ParseOnCallLayers(%s) expected error", layers)
+		}
+	}
+}
+
+func TestEscalationService_EscalatesUntilAcknowledged(t *testing.T) {
+	appLogger.Log = zap.NewNop()
+	db := testutil.NewDB(t, &biz.AlertEvent{}, &biz.AlertNotifyChannel{}, &biz.AlertNotifyDelivery{}, &biz.AlertNotifyDeadLetter{},
+		&biz.AlertOnCallSchedule{}, &biz.AlertOnCallOverride{}, &biz.AlertEscalationPolicy{}, &biz.AlertEventEscalation{}, &biz.AlertEventTimeline{})
+	rdb := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	queue := NewNotifyQueue(db, rdb, NewNotifyService(alertdata.NewChannelRepo(db), db))
+	svc := NewEscalationService(db, queue, NewOnCallService(db))
+	ctx := context.Background()
+
+	ch := &biz.AlertNotifyChannel{Name: "hook", Type: "webhook", Enabled: true, Config: `{"url":"http://127.0.0.1"}`}
+	db.Create(ch)
+	schedule := &biz.AlertOnCallSchedule{Name: "二线", Layers: `[{"name":"l","rotationType":"daily","start":"2020-01-01 00:00","userIds":[5]}]`}
+	db.Create(schedule)
+	policy := &biz.AlertEscalationPolicy{Name: "p", Enabled: true,
+		Steps: `[{"delayMinutes":10,"scheduleId":` + strconv.FormatUint(uint64(schedule.ID), 10) + `},{"delayMinutes":20,"userIds":[8]}]`}
+	db.Create(policy)
+	event := &biz.AlertEvent{RuleName: "CPU 高", Severity: "critical", Status: "firing", Labels: `{}`, Annotations: `{}`, FiredAt: time.Now()}
+	db.Create(event)
+	sub := &biz.AlertSubscription{ID: 3, EscalationPolicyID: policy.ID}
+
+	now := time.Now()
+	if err := svc.Begin(ctx, event, sub, []uint{ch.ID}, now); err != nil {
+		t.Fatalf("Begin() error: %v", err)
+	}
+
+	deliveryUsers := func() []string {
+		var list []biz.AlertNotifyDelivery
+		db.Order("id").Find(&list)
+		var users []string
+		for _, d := range list {
+			users = append(users, d.UserIDs)
+		}
+		return users
+	}
+
+	svc.tick(ctx, now.Add(9*time.Minute))
+	if got := deliveryUsers(); len(got) != 0 {
+		t.Fatalf("escalated before delay: %v", got)
+	}
+
+	svc.tick(ctx, now.Add(10*time.Minute))
+	if got := deliveryUsers(); len(got) != 1 || got[0] != "[5]" {
+		t.Fatalf("step 1 deliveries = %v, want on-call user 5", got)
+	}
+
+	// 人工介入后停止升级
+	db.Model(event).Update("manual_handled", true)
+	svc.tick(ctx, now.Add(31*time.Minute))
+	if got := deliveryUsers(); len(got) != 1 {
+		t.Fatalf("escalated after acknowledge: %v", got)
+	}
+	var esc biz.AlertEventEscalation
+	db.First(&esc)
+	if esc.Status != biz.EscalationAcknowledged || esc.Step != 1 {
+		t.Errorf("escalation status=%s step=%d, want acknowledged after 1 step", esc.Status, esc.Step)
+	}
+}
+
+func TestGroupService_GroupedSendBeginsEscalation(t *testing.T) {
+	appLogger.Log = zap.NewNop()
+	db := testutil.NewDB(t, &biz.AlertEvent{}, &biz.AlertNotifyChannel{}, &biz.AlertNotifyDelivery{}, &biz.AlertNotifyDeadLetter{},
+		&biz.AlertOnCallSchedule{}, &biz.AlertOnCallOverride{}, &biz.AlertEscalationPolicy{}, &biz.AlertEventEscalation{}, &biz.AlertEventTimeline{},
+		&biz.AlertGroupCache{}, &biz.AlertSubscription{}, &biz.AlertSubscriptionRule{}, &biz.AlertSubscriptionChannel{}, &biz.AlertSubscriptionUser{})
+	rdb := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	queue := NewNotifyQueue(db, rdb, NewNotifyService(alertdata.NewChannelRepo(db), db))
+	escalation := NewEscalationService(db, queue, NewOnCallService(db))
+	svc := NewGroupService(db, queue, escalation)
+	ctx := context.Background()
+
+	ch := &biz.AlertNotifyChannel{Name: "hook", Type: "webhook", Enabled: true, Config: `{"url":"http://127.0.0.1"}`}
+	db.Create(ch)
+	policy := &biz.AlertEscalationPolicy{Name: "p", Enabled: true, Steps: `[{"delayMinutes":10,"userIds":[8]}]`}
+	db.Create(policy)
+	sub := &biz.AlertSubscription{Name: "s", EscalationPolicyID: policy.ID}
+	db.Create(sub)
+	db.Create(&biz.AlertSubscriptionRule{SubscriptionID: sub.ID, RuleID: 1, ChannelIDs: "[" + strconv.FormatUint(uint64(ch.ID), 10) + "]"})
+
+	firing := &biz.AlertEvent{RuleName: "CPU 高", Severity: "critical", Status: "firing", Labels: `{}`, Annotations: `{}`, FiredAt: time.Now()}
+	db.Create(firing)
+	resolved := &biz.AlertEvent{RuleName: "CPU 高", Severity: "critical", Status: "resolved", Labels: `{}`, Annotations: `{}`, FiredAt: time.Now()}
+	db.Create(resolved)
+	cache := &biz.AlertGroupCache{GroupRuleID: 1, SubscriptionID: sub.ID, GroupKey: "k",
+		Alerts:       "[" + strconv.FormatUint(uint64(firing.ID), 10) + "," + strconv.FormatUint(uint64(resolved.ID), 10) + "]",
+		FirstAlertAt: time.Now(), LastAlertAt: time.Now(), AlertCount: 2}
+	db.Create(cache)
+
+	svc.sendGroupCache(ctx, cache.ID)
+
+	var deliveries int64
+	db.Model(&biz.AlertNotifyDelivery{}).Count(&deliveries)
+	if deliveries != 2 {
+		t.Fatalf("deliveries = %d, want 2", deliveries)
+	}
+	var escs []biz.AlertEventEscalation
+	db.Find(&escs)
+	if len(escs) != 1 || escs[0].EventID != firing.ID || escs[0].SubscriptionID != sub.ID || escs[0].Status != biz.EscalationActive {
+		t.Fatalf("escalations = %+v, want one active escalation for the firing event", escs)
+	}
+	if escs[0].ChannelIDs != "["+strconv.FormatUint(uint64(ch.ID), 10)+"]" {
+		t.Errorf("escalation channels = %s", escs[0].ChannelIDs)
+	}
+}
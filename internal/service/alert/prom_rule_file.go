@@ -0,0 +1,458 @@
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	biz "github.com/ydcloud-dy/opshub/internal/biz/alert"
+)
+
+// PromRuleFile Prometheus 规则文件（groups: - name: rules: ...）
+type PromRuleFile struct {
+	Groups []PromRuleGroup `yaml:"groups"`
+}
+
+// PromRuleGroup Prometheus 规则分组
+type PromRuleGroup struct {
+	Name     string     `yaml:"name"`
+	Interval string     `yaml:"interval,omitempty"`
+	Rules    []PromRule `yaml:"rules"`
+}
+
+// PromRule Prometheus 告警规则
+type PromRule struct {
+	Record        string            `yaml:"record,omitempty"`
+	Alert         string            `yaml:"alert,omitempty"`
+	Expr          string            `yaml:"expr"`
+	For           string            `yaml:"for,omitempty"`
+	KeepFiringFor string            `yaml:"keep_firing_for,omitempty"`
+	Labels        map[string]string `yaml:"labels,omitempty"`
+	Annotations   map[string]string `yaml:"annotations,omitempty"`
+}
+
+// 导入预览动作
+const (
+	PromImportCreate     = "create"      // 新建
+	PromImportConflict   = "conflict"    // 与本分类中已有规则同名，可覆盖
+	PromImportCrossGroup = "cross_group" // 与其他分类中的规则同名，不覆盖，避免把规则移到本分类
+	PromImportDuplicate  = "duplicate"   // 文件内同名
+	PromImportInvalid    = "invalid"     // 无法导入
+)
+
+// PromImportItem Prometheus 规则导入预览项
+type PromImportItem struct {
+	Group               string         `json:"group"` // 规则文件中的分组名
+	Name                string         `json:"name"`
+	Expr                string         `json:"expr"`
+	QueryExpr           string         `json:"queryExpr"`
+	Conditions          string         `json:"conditions"`
+	Duration            string         `json:"duration"`
+	Severity            string         `json:"severity"`
+	Action              string         `json:"action"`
+	ExistingRuleID      uint           `json:"existingRuleId,omitempty"`
+	ExistingRuleGroupID uint           `json:"existingRuleGroupId,omitempty"`
+	Error               string         `json:"error,omitempty"`
+	Rule                *biz.AlertRule `json:"-"`
+
+	source       PromRule // 文件中的原始规则，覆盖已有规则时只应用文件中定义的字段
+	evalInterval int      // 分组 interval（秒），未定义时为 0
+}
+
+// ParsePromRuleFile 解析 Prometheus 规则文件并转换为告警规则（不含数据源、分组等归属信息）
+func ParsePromRuleFile(data []byte) ([]*PromImportItem, error) {
+	var file PromRuleFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("YAML 解析失败: %w", err)
+	}
+	if len(file.Groups) == 0 {
+		return nil, fmt.Errorf("规则文件中没有 groups")
+	}
+
+	var items []*PromImportItem
+	seen := make(map[string]bool)
+	for _, g := range file.Groups {
+		evalInterval := 0
+		if g.Interval != "" {
+			d, err := parsePromDuration(g.Interval)
+			if err != nil {
+				return nil, fmt.Errorf("分组 %s 的 interval 无效: %w", g.Name, err)
+			}
+			evalInterval = int(d.Seconds())
+		}
+		for _, r := range g.Rules {
+			item := &PromImportItem{Group: g.Name, Name: r.Alert, Expr: r.Expr, Action: PromImportCreate}
+			items = append(items, item)
+
+			if r.Record != "" {
+				item.Name = r.Record
+				item.Action = PromImportInvalid
+				item.Error = "不支持 recording rule"
+				continue
+			}
+			rule, err := PromRuleToAlertRule(r, evalInterval)
+			if err != nil {
+				item.Action = PromImportInvalid
+				item.Error = err.Error()
+				continue
+			}
+			item.Rule = rule
+			item.source, item.evalInterval = r, evalInterval
+			item.QueryExpr = rule.QueryExpr
+			item.Conditions = rule.Conditions
+			item.Duration = rule.Duration
+			item.Severity = rule.Severity
+			if seen[rule.Name] {
+				item.Action = PromImportDuplicate
+				item.Error = "文件内规则名重复"
+				continue
+			}
+			seen[rule.Name] = true
+		}
+	}
+	return items, nil
+}
+
+// MergeInto 覆盖导入时将文件中定义的字段应用到已有规则的副本上，
+// 文件未定义的字段（for、keep_firing_for、interval、severity、labels、annotations 以及描述、启用状态等）保持原值
+func (item *PromImportItem) MergeInto(existing *biz.AlertRule) *biz.AlertRule {
+	merged := *existing
+	r := item.Rule
+	merged.Name = r.Name
+	merged.Expr, merged.QueryExpr, merged.Conditions = r.Expr, r.QueryExpr, r.Conditions
+	if item.source.For != "" {
+		merged.Duration = r.Duration
+	}
+	if item.source.KeepFiringFor != "" {
+		merged.KeepFiringFor = r.KeepFiringFor
+	}
+	if item.evalInterval > 0 {
+		merged.EvalInterval = r.EvalInterval
+	}
+	if _, ok := item.source.Labels["severity"]; ok {
+		merged.Severity = r.Severity
+	}
+	if item.source.Labels != nil {
+		merged.Labels = r.Labels
+	}
+	if item.source.Annotations != nil {
+		merged.Annotations = r.Annotations
+	}
+	return &merged
+}
+
+// PromRuleToAlertRule 将 Prometheus 告警规则转换为 AlertRule
+// for -> Duration；expr 顶层为 "查询 比较符 数值" 时拆分为 QueryExpr + Conditions，否则保留为旧格式 Expr
+func PromRuleToAlertRule(r PromRule, evalInterval int) (*biz.AlertRule, error) {
+	if r.Alert == "" {
+		return nil, fmt.Errorf("缺少 alert 名称")
+	}
+	if strings.TrimSpace(r.Expr) == "" {
+		return nil, fmt.Errorf("缺少 expr")
+	}
+
+	rule := &biz.AlertRule{
+		Name:            r.Alert,
+		Expr:            strings.TrimSpace(r.Expr),
+		Duration:        "0s",
+		Severity:        "warning",
+		EvalInterval:    evalInterval,
+		NotifyOnResolve: true,
+	}
+	if rule.EvalInterval <= 0 {
+		rule.EvalInterval = 15
+	}
+	if r.For != "" {
+		d, err := parsePromDuration(r.For)
+		if err != nil {
+			return nil, fmt.Errorf("for 无效: %w", err)
+		}
+		rule.Duration = formatRuleDuration(d)
+	}
+	if r.KeepFiringFor != "" {
+		d, err := parsePromDuration(r.KeepFiringFor)
+		if err != nil {
+			return nil, fmt.Errorf("keep_firing_for 无效: %w", err)
+		}
+		rule.KeepFiringFor = formatRuleDuration(d)
+	}
+
+	// severity 标签映射到规则级别，其余标签保留
+	labels := make(map[string]string, len(r.Labels))
+	for k, v := range r.Labels {
+		if k == "severity" {
+			rule.Severity = normalizeSeverity(v)
+			continue
+		}
+		labels[k] = v
+	}
+	if len(labels) > 0 {
+		b, _ := json.Marshal(labels)
+		rule.Labels = string(b)
+	}
+	if len(r.Annotations) > 0 {
+		b, _ := json.Marshal(r.Annotations)
+		rule.Annotations = string(b)
+	}
+
+	if query, cond, ok := splitThresholdExpr(rule.Expr); ok {
+		rule.QueryExpr = query
+		// 不转义 < > 等字符，与前端 JSON.stringify 结果一致
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		_ = enc.Encode([]biz.ThresholdCondition{cond})
+		rule.Conditions = strings.TrimSpace(buf.String())
+	}
+	return rule, nil
+}
+
+// AlertRulesToPromRuleFile 将规则分类下的规则导出为 Prometheus 规则文件
+func AlertRulesToPromRuleFile(groupName string, rules []*biz.AlertRule) ([]byte, error) {
+	group := PromRuleGroup{Name: groupName, Rules: make([]PromRule, 0, len(rules))}
+
+	// 所有规则采集频率一致时导出为分组 interval
+	interval := 0
+	for i, r := range rules {
+		if i == 0 {
+			interval = r.EvalInterval
+		} else if r.EvalInterval != interval {
+			interval = 0
+			break
+		}
+	}
+	if interval > 0 {
+		group.Interval = fmt.Sprintf("%ds", interval)
+	}
+
+	for _, r := range rules {
+		group.Rules = append(group.Rules, AlertRuleToPromRule(r))
+	}
+	return yaml.Marshal(PromRuleFile{Groups: []PromRuleGroup{group}})
+}
+
+// AlertRuleToPromRule 将 AlertRule 转换为 Prometheus 告警规则
+func AlertRuleToPromRule(r *biz.AlertRule) PromRule {
+	pr := PromRule{Alert: r.Name, Expr: r.Expr}
+
+	if r.QueryExpr != "" && r.Conditions != "" {
+		var conds []biz.ThresholdCondition
+		if err := json.Unmarshal([]byte(r.Conditions), &conds); err == nil && len(conds) > 0 {
+			pr.Expr = buildThresholdExpr(r.QueryExpr, conds)
+		}
+	}
+	if d, _ := ruleDuration(r.Duration); d > 0 {
+		pr.For = r.Duration
+	}
+	if d, _ := ruleDuration(r.KeepFiringFor); d > 0 {
+		pr.KeepFiringFor = r.KeepFiringFor
+	}
+
+	pr.Labels = map[string]string{}
+	if r.Labels != "" {
+		_ = json.Unmarshal([]byte(r.Labels), &pr.Labels)
+	}
+	if r.Severity != "" {
+		pr.Labels["severity"] = r.Severity
+	}
+	if r.Annotations != "" && r.Annotations != "{}" {
+		_ = json.Unmarshal([]byte(r.Annotations), &pr.Annotations)
+	}
+	return pr
+}
+
+// buildThresholdExpr 由查询表达式和阈值条件拼接 PromQL（条件按从左到右的顺序组合，与 EvaluateConditions 一致）
+func buildThresholdExpr(query string, conds []biz.ThresholdCondition) string {
+	q := strings.TrimSpace(query)
+	if hasTopLevelOperator(q) {
+		q = "(" + q + ")"
+	}
+	part := func(c biz.ThresholdCondition) string {
+		return fmt.Sprintf("%s %s %s", q, c.Operator, strconv.FormatFloat(c.Value, 'f', -1, 64))
+	}
+
+	expr := part(conds[0])
+	for i := 1; i < len(conds); i++ {
+		logic := "and"
+		if strings.EqualFold(conds[i-1].Logic, "OR") {
+			logic = "or"
+		}
+		if i > 1 {
+			expr = "(" + expr + ")"
+		}
+		expr = fmt.Sprintf("%s %s %s", expr, logic, part(conds[i]))
+	}
+	return expr
+}
+
+// comparisonOps PromQL 比较运算符（长的在前，避免 >= 被识别为 >）
+var comparisonOps = []string{"==", "!=", ">=", "<=", ">", "<"}
+
+// flipOps 数值在左侧时翻转比较方向
+var flipOps = map[string]string{">": "<", "<": ">", ">=": "<=", "<=": ">=", "==": "==", "!=": "!="}
+
+// splitThresholdExpr 拆分 "查询 比较符 数值"（或 "数值 比较符 查询"）形式的表达式
+func splitThresholdExpr(expr string) (string, biz.ThresholdCondition, bool) {
+	var cond biz.ThresholdCondition
+	ops := topLevelOperators(expr)
+	if len(ops) != 1 || ops[0].setOp {
+		return "", cond, false
+	}
+	op := ops[0]
+	left := strings.TrimSpace(expr[:op.pos])
+	right := strings.TrimSpace(expr[op.pos+len(op.op):])
+	if left == "" || right == "" {
+		return "", cond, false
+	}
+	// "> bool" 返回 0/1，语义不同，不拆分
+	if strings.HasPrefix(right, "bool ") || strings.HasPrefix(right, "bool(") {
+		return "", cond, false
+	}
+
+	if v, err := strconv.ParseFloat(right, 64); err == nil {
+		return left, biz.ThresholdCondition{Operator: op.op, Value: v}, true
+	}
+	if v, err := strconv.ParseFloat(left, 64); err == nil {
+		return right, biz.ThresholdCondition{Operator: flipOps[op.op], Value: v}, true
+	}
+	return "", cond, false
+}
+
+type exprOperator struct {
+	pos   int
+	op    string
+	setOp bool // and / or / unless
+}
+
+// topLevelOperators 查找不在括号、方括号、花括号和字符串内的比较运算符及集合运算符
+func topLevelOperators(expr string) []exprOperator {
+	var ops []exprOperator
+	depth := 0
+	var quote byte
+	for i := 0; i < len(expr); i++ {
+		ch := expr[i]
+		if quote != 0 {
+			if ch == '\\' {
+				i++
+			} else if ch == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch ch {
+		case '"', '\'', '`':
+			quote = ch
+			continue
+		case '(', '[', '{':
+			depth++
+			continue
+		case ')', ']', '}':
+			depth--
+			continue
+		}
+		if depth != 0 {
+			continue
+		}
+		matched := false
+		for _, op := range comparisonOps {
+			if strings.HasPrefix(expr[i:], op) {
+				ops = append(ops, exprOperator{pos: i, op: op})
+				i += len(op) - 1
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+		for _, word := range []string{"and", "or", "unless"} {
+			if strings.HasPrefix(expr[i:], word) && isWordBoundary(expr, i-1) && isWordBoundary(expr, i+len(word)) {
+				ops = append(ops, exprOperator{pos: i, op: word, setOp: true})
+				i += len(word) - 1
+				break
+			}
+		}
+	}
+	return ops
+}
+
+// hasTopLevelOperator 表达式顶层是否包含比较或集合运算符
+func hasTopLevelOperator(expr string) bool {
+	return len(topLevelOperators(expr)) > 0
+}
+
+func isWordBoundary(s string, i int) bool {
+	if i < 0 || i >= len(s) {
+		return true
+	}
+	c := s[i]
+	return !(c == '_' || c == ':' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9'))
+}
+
+// normalizeSeverity 将外部级别归一为 critical/warning/info
+func normalizeSeverity(s string) string {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "critical", "crit", "fatal", "emergency", "disaster", "high", "p0", "p1":
+		return "critical"
+	case "info", "information", "notice", "low", "none", "p4":
+		return "info"
+	default:
+		return "warning"
+	}
+}
+
+// formatRuleDuration 转换为规则使用的单一单位时长（"90s"、"5m"、"2h"），与 parseDuration 兼容
+func formatRuleDuration(d time.Duration) string {
+	switch {
+	case d <= 0:
+		return "0s"
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	default:
+		return fmt.Sprintf("%ds", d/time.Second)
+	}
+}
+
+// parsePromDuration 解析 Prometheus 时长格式（支持 1h30m、1d、1w 等组合）
+func parsePromDuration(s string) (time.Duration, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return d, nil
+	}
+	units := map[string]time.Duration{
+		"ms": time.Millisecond, "s": time.Second, "m": time.Minute,
+		"h": time.Hour, "d": 24 * time.Hour, "w": 7 * 24 * time.Hour, "y": 365 * 24 * time.Hour,
+	}
+	var total time.Duration
+	rest := s
+	for rest != "" {
+		i := 0
+		for i < len(rest) && rest[i] >= '0' && rest[i] <= '9' {
+			i++
+		}
+		if i == 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		n, _ := strconv.Atoi(rest[:i])
+		rest = rest[i:]
+		unit := ""
+		if strings.HasPrefix(rest, "ms") {
+			unit = "ms"
+		} else if rest != "" {
+			unit = rest[:1]
+		}
+		mul, ok := units[unit]
+		if !ok {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		total += time.Duration(n) * mul
+		rest = rest[len(unit):]
+	}
+	return total, nil
+}
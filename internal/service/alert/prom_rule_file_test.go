@@ -0,0 +1,138 @@
+package alert
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	biz "github.com/ydcloud-dy/opshub/internal/biz/alert"
+)
+
+const samplePromRules = `
+groups:
+  - name: node
+    interval: 30s
+    rules:
+      - alert: HighCPU
+        expr: 100 - avg by (instance) (rate(node_cpu_seconds_total{mode="idle"}[5m])) * 100 > 80
+        for: 10m
+        labels:
+          severity: critical
+          team: sre
+        annotations:
+          summary: "CPU high on {{ $labels.instance }}"
+      - alert: LowDisk
+        expr: 10 >= node_filesystem_avail_bytes / node_filesystem_size_bytes * 100
+        for: 1h30m
+      - alert: TargetDown
+        expr: up == 0 and on(instance) node_boot_time_seconds > 0
+      - record: job:up:sum
+        expr: sum by (job) (up)
+      - alert: HighCPU
+        expr: foo > 1
+`
+
+func TestParsePromRuleFile(t *testing.T) {
+	items, err := ParsePromRuleFile([]byte(samplePromRules))
+	if err != nil {
+		t.Fatalf("ParsePromRuleFile error: %v", err)
+	}
+	if len(items) != 5 {
+		t.Fatalf("got %d items, want 5", len(items))
+	}
+
+	cpu := items[0].Rule
+	if cpu.QueryExpr != `100 - avg by (instance) (rate(node_cpu_seconds_total{mode="idle"}[5m])) * 100` {
+		t.Errorf("QueryExpr = %q", cpu.QueryExpr)
+	}
+	if cpu.Conditions != `[{"operator":">","value":80,"logic":""}]` {
+		t.Errorf("Conditions = %s", cpu.Conditions)
+	}
+	if cpu.Duration != "10m" || cpu.Severity != "critical" || cpu.EvalInterval != 30 {
+		t.Errorf("rule = %+v", cpu)
+	}
+	if cpu.Labels != `{"team":"sre"}` {
+		t.Errorf("Labels = %s", cpu.Labels)
+	}
+
+	disk := items[1].Rule
+	if disk.QueryExpr != "node_filesystem_avail_bytes / node_filesystem_size_bytes * 100" || !strings.Contains(disk.Conditions, `"operator":"<="`) {
+		t.Errorf("flipped split failed: %q %s", disk.QueryExpr, disk.Conditions)
+	}
+	if disk.Duration != "90m" {
+		t.Errorf("Duration = %s, want 90m", disk.Duration)
+	}
+
+	if down := items[2].Rule; down.QueryExpr != "" || down.Conditions != "" {
+		t.Errorf("expression with set operator should not be split: %+v", down)
+	}
+	if items[3].Action != PromImportInvalid {
+		t.Errorf("recording rule action = %s", items[3].Action)
+	}
+	if items[4].Action != PromImportDuplicate {
+		t.Errorf("duplicate rule action = %s", items[4].Action)
+	}
+}
+
+func TestPromRuleFileRoundTrip(t *testing.T) {
+	items, err := ParsePromRuleFile([]byte(samplePromRules))
+	if err != nil {
+		t.Fatalf("ParsePromRuleFile error: %v", err)
+	}
+	out, err := AlertRulesToPromRuleFile("node", []*biz.AlertRule{items[0].Rule, items[1].Rule, items[2].Rule})
+	if err != nil {
+		t.Fatalf("AlertRulesToPromRuleFile error: %v", err)
+	}
+
+	var file PromRuleFile
+	if err := yaml.Unmarshal(out, &file); err != nil {
+		t.Fatalf("exported YAML invalid: %v\n%s", err, out)
+	}
+	g := file.Groups[0]
+	if g.Name != "node" || g.Interval != "30s" || len(g.Rules) != 3 {
+		t.Fatalf("group = %+v", g)
+	}
+	if g.Rules[0].Expr != `100 - avg by (instance) (rate(node_cpu_seconds_total{mode="idle"}[5m])) * 100 > 80` {
+		t.Errorf("expr = %q", g.Rules[0].Expr)
+	}
+	if g.Rules[0].For != "10m" || g.Rules[0].Labels["severity"] != "critical" || g.Rules[0].Labels["team"] != "sre" {
+		t.Errorf("rule = %+v", g.Rules[0])
+	}
+	if g.Rules[2].Expr != "up == 0 and on(instance) node_boot_time_seconds > 0" || g.Rules[2].For != "" {
+		t.Errorf("legacy rule = %+v", g.Rules[2])
+	}
+}
+
+func TestPromImportItem_MergeInto(t *testing.T) {
+	items, err := ParsePromRuleFile([]byte(samplePromRules))
+	if err != nil {
+		t.Fatalf("ParsePromRuleFile error: %v", err)
+	}
+	existing := &biz.AlertRule{
+		ID: 9, Name: "TargetDown", Description: "人工维护的描述", RuleGroupID: 3, DataSourceID: 2, DataSourceIDs: "[2]",
+		Expr: "up == 0", Duration: "5m", KeepFiringFor: "2m", EvalInterval: 60, Severity: "info",
+		Labels: `{"team":"dba"}`, Annotations: `{"title":"down"}`, Enabled: true, NotifyOnResolve: false,
+	}
+
+	// 文件只定义了 alert/expr（分组定义了 interval），其余字段保持原值
+	merged := items[2].MergeInto(existing)
+	if merged.ID != 9 || merged.Expr != "up == 0 and on(instance) node_boot_time_seconds > 0" || merged.EvalInterval != 30 {
+		t.Fatalf("merged = %+v", merged)
+	}
+	if !merged.Enabled || merged.NotifyOnResolve || merged.Description != existing.Description || merged.Duration != "5m" ||
+		merged.KeepFiringFor != "2m" || merged.Severity != "info" || merged.Labels != existing.Labels ||
+		merged.Annotations != existing.Annotations || merged.DataSourceIDs != "[2]" || merged.RuleGroupID != 3 {
+		t.Errorf("fields not defined in file were changed: %+v", merged)
+	}
+	if existing.Expr != "up == 0" {
+		t.Error("MergeInto modified the existing rule")
+	}
+
+	// 文件定义的 for、severity、labels、annotations 覆盖原值
+	merged = items[0].MergeInto(existing)
+	if merged.Duration != "10m" || merged.Severity != "critical" || merged.Labels != `{"team":"sre"}` ||
+		!strings.Contains(merged.Annotations, "summary") || !merged.Enabled {
+		t.Errorf("merged = %+v", merged)
+	}
+}
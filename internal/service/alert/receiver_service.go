@@ -0,0 +1,428 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
+	"go.uber.org/zap"
+
+	biz "github.com/ydcloud-dy/opshub/internal/biz/alert"
+)
+
+// ExternalAlert 外部告警（Alertmanager / 通用 JSON 解析后的统一格式）
+type ExternalAlert struct {
+	Status       string            `json:"status"` // firing, resolved
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	Value        float64           `json:"value"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+}
+
+// IngestResult 外部告警接收结果
+type IngestResult struct {
+	Received int `json:"received"`
+	Fired    int `json:"fired"`    // 新建 firing 事件
+	Updated  int `json:"updated"`  // 已有 firing 事件，仅更新
+	Resolved int `json:"resolved"` // 恢复
+	Ignored  int `json:"ignored"`  // 恢复但无对应 firing 事件
+	Busy     int `json:"busy"`     // 同一告警正被其他请求处理，未写入（调用方需重试）
+}
+
+// alertmanagerPayload Alertmanager webhook v4 请求体
+type alertmanagerPayload struct {
+	Version           string            `json:"version"`
+	GroupKey          string            `json:"groupKey"`
+	Status            string            `json:"status"`
+	Receiver          string            `json:"receiver"`
+	GroupLabels       map[string]string `json:"groupLabels"`
+	CommonLabels      map[string]string `json:"commonLabels"`
+	CommonAnnotations map[string]string `json:"commonAnnotations"`
+	ExternalURL       string            `json:"externalURL"`
+	Alerts            []struct {
+		Status       string            `json:"status"`
+		Labels       map[string]string `json:"labels"`
+		Annotations  map[string]string `json:"annotations"`
+		StartsAt     time.Time         `json:"startsAt"`
+		EndsAt       time.Time         `json:"endsAt"`
+		GeneratorURL string            `json:"generatorURL"`
+		Fingerprint  string            `json:"fingerprint"`
+	} `json:"alerts"`
+}
+
+// ParseAlertmanagerPayload 解析 Alertmanager webhook v4 请求体
+func ParseAlertmanagerPayload(body []byte) ([]ExternalAlert, error) {
+	var payload alertmanagerPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("解析 Alertmanager 请求体失败: %w", err)
+	}
+	if payload.Version != "" && payload.Version != "4" {
+		return nil, fmt.Errorf("不支持的 Alertmanager webhook 版本: %s", payload.Version)
+	}
+
+	alerts := make([]ExternalAlert, 0, len(payload.Alerts))
+	for _, a := range payload.Alerts {
+		status := a.Status
+		if status == "" {
+			status = payload.Status
+		}
+		// commonAnnotations 作为兜底，单条告警的注解优先
+		annotations := make(map[string]string, len(a.Annotations)+len(payload.CommonAnnotations))
+		for k, v := range payload.CommonAnnotations {
+			annotations[k] = v
+		}
+		for k, v := range a.Annotations {
+			annotations[k] = v
+		}
+		alerts = append(alerts, ExternalAlert{
+			Status:       normalizeExternalStatus(status, nil),
+			Labels:       a.Labels,
+			Annotations:  annotations,
+			Value:        parseAnnotationValue(annotations),
+			StartsAt:     a.StartsAt,
+			EndsAt:       a.EndsAt,
+			GeneratorURL: a.GeneratorURL,
+		})
+	}
+	return alerts, nil
+}
+
+// ParseGenericPayload 按字段映射解析通用 JSON 请求体
+func ParseGenericPayload(body []byte, mappingJSON string) ([]ExternalAlert, error) {
+	var mapping biz.GenericFieldMapping
+	if mappingJSON != "" {
+		if err := json.Unmarshal([]byte(mappingJSON), &mapping); err != nil {
+			return nil, fmt.Errorf("字段映射配置错误: %w", err)
+		}
+	}
+
+	var root interface{}
+	if err := json.Unmarshal(body, &root); err != nil {
+		return nil, fmt.Errorf("解析请求体失败: %w", err)
+	}
+
+	items := root
+	if mapping.AlertsPath != "" {
+		items = lookupJSONPath(root, mapping.AlertsPath)
+		if items == nil {
+			return nil, fmt.Errorf("请求体中未找到告警数组: %s", mapping.AlertsPath)
+		}
+	}
+	var list []interface{}
+	switch v := items.(type) {
+	case []interface{}:
+		list = v
+	case map[string]interface{}:
+		list = []interface{}{v}
+	default:
+		return nil, fmt.Errorf("告警数据格式错误")
+	}
+
+	alerts := make([]ExternalAlert, 0, len(list))
+	for _, item := range list {
+		a := ExternalAlert{
+			Labels:      toStringMap(lookupJSONPath(item, mapping.LabelsPath)),
+			Annotations: toStringMap(lookupJSONPath(item, mapping.AnnotationsPath)),
+		}
+		if a.Labels == nil {
+			a.Labels = make(map[string]string)
+		}
+		if a.Annotations == nil {
+			a.Annotations = make(map[string]string)
+		}
+		if mapping.NamePath != "" {
+			if name := toString(lookupJSONPath(item, mapping.NamePath)); name != "" {
+				a.Labels["alertname"] = name
+			}
+		}
+		if mapping.SeverityPath != "" {
+			if sev := toString(lookupJSONPath(item, mapping.SeverityPath)); sev != "" {
+				if mapped, ok := mapping.SeverityMap[sev]; ok {
+					sev = mapped
+				}
+				a.Labels["severity"] = sev
+			}
+		}
+		a.Status = normalizeExternalStatus(toString(lookupJSONPath(item, mapping.StatusPath)), mapping.ResolvedValues)
+		if mapping.ValuePath != "" {
+			a.Value, _ = strconv.ParseFloat(toString(lookupJSONPath(item, mapping.ValuePath)), 64)
+		} else {
+			a.Value = parseAnnotationValue(a.Annotations)
+		}
+		a.StartsAt = toTime(lookupJSONPath(item, mapping.StartsAtPath))
+		a.EndsAt = toTime(lookupJSONPath(item, mapping.EndsAtPath))
+		alerts = append(alerts, a)
+	}
+	return alerts, nil
+}
+
+// IngestExternalAlerts 将外部告警写入告警事件，并走与内部评估相同的屏蔽/抑制/分组/去重/订阅通知流程
+func (e *EvalEngine) IngestExternalAlerts(ctx context.Context, receiver *biz.AlertReceiver, alerts []ExternalAlert) *IngestResult {
+	result := &IngestResult{Received: len(alerts)}
+
+	var extraLabels map[string]string
+	if receiver.Labels != "" && receiver.Labels != "{}" {
+		_ = json.Unmarshal([]byte(receiver.Labels), &extraLabels)
+	}
+
+	for i := range alerts {
+		switch e.ingestExternalAlert(ctx, receiver, extraLabels, &alerts[i]) {
+		case "fired":
+			result.Fired++
+		case "updated":
+			result.Updated++
+		case "resolved":
+			result.Resolved++
+		case "busy":
+			result.Busy++
+		default:
+			result.Ignored++
+		}
+	}
+	return result
+}
+
+func (e *EvalEngine) ingestExternalAlert(ctx context.Context, receiver *biz.AlertReceiver, extraLabels map[string]string, alert *ExternalAlert) string {
+	// 标签：接收器附加标签 < 告警标签，并补充接收器信息
+	labels := make(map[string]string, len(alert.Labels)+len(extraLabels)+4)
+	for k, v := range extraLabels {
+		labels[k] = v
+	}
+	for k, v := range alert.Labels {
+		labels[k] = v
+	}
+	labels["receiver"] = receiver.Name
+	labels["source"] = receiver.Type
+
+	ruleName := labels["alertname"]
+	nameFallback := false
+	if ruleName == "" {
+		ruleName = receiver.Name
+		_, hasRuleName := labels["ruleName"]
+		nameFallback = !hasRuleName
+	}
+	severity := labels["severity"]
+	if severity == "" {
+		severity = receiver.DefaultSeverity
+		if severity == "" {
+			severity = "warning"
+		}
+		labels["severity"] = severity
+	}
+	if _, ok := labels["ruleName"]; !ok {
+		labels["ruleName"] = ruleName
+	}
+
+	fingerprint := externalFingerprint(receiver, labels, nameFallback)
+
+	// 外部告警使用虚拟规则走通知流程
+	rule := &biz.AlertRule{
+		Name:            ruleName,
+		Severity:        severity,
+		AssetGroupID:    receiver.AssetGroupID,
+		NotifyOnResolve: receiver.NotifyOnResolve,
+	}
+
+	lockKey := fmt.Sprintf("alert:lock:%s", fingerprint)
+	acquired, err := e.rdb.SetNX(ctx, lockKey, "1", 5*time.Second).Result()
+	if err != nil || !acquired {
+		// 同一告警正在被其他请求处理（或 Redis 不可用），不能确认本次状态已写入，交由调用方重试
+		return "busy"
+	}
+	defer e.rdb.Del(ctx, lockKey)
+
+	existing, _ := e.eventRepo.GetFiringByFingerprint(ctx, fingerprint)
+
+	if alert.Status == "resolved" {
+		if existing == nil || existing.ID == 0 {
+			return "ignored"
+		}
+		resolvedAt := alert.EndsAt
+		if resolvedAt.IsZero() {
+			resolvedAt = time.Now()
+		}
+		resolveValue := alert.Value
+		existing.Status = "resolved"
+		if existing.ManualHandled {
+			existing.ResolveType = "manual_then_auto"
+		} else {
+			existing.ResolveType = "auto"
+		}
+		existing.ResolvedAt = &resolvedAt
+		existing.ResolveValue = &resolveValue
+		if err := e.eventRepo.Update(ctx, existing); err != nil {
+			appLogger.Error("更新外部告警恢复状态失败", zap.Uint("eventID", existing.ID), zap.Error(err))
+			return "ignored"
+		}
+		if rule.NotifyOnResolve {
+			go e.sendNotifications(context.Background(), rule, existing, true)
+		}
+		return "resolved"
+	}
+
+	annotationsJSON := "{}"
+	if len(alert.Annotations) > 0 {
+		b, _ := json.Marshal(alert.Annotations)
+		annotationsJSON = string(b)
+	}
+
+	if existing != nil && existing.ID > 0 {
+		existing.Value = alert.Value
+		existing.Annotations = annotationsJSON
+		if err := e.eventRepo.Update(ctx, existing); err != nil {
+			appLogger.Warn("更新外部告警失败", zap.Uint("eventID", existing.ID), zap.Error(err))
+		}
+		return "updated"
+	}
+
+	firedAt := alert.StartsAt
+	if firedAt.IsZero() {
+		firedAt = time.Now()
+	}
+	labelsJSON, _ := json.Marshal(labels)
+	event := &biz.AlertEvent{
+		RuleName:     ruleName,
+		AssetGroupID: receiver.AssetGroupID,
+		Fingerprint:  fingerprint,
+		Severity:     severity,
+		Status:       "firing",
+		Labels:       string(labelsJSON),
+		Annotations:  annotationsJSON,
+		Value:        alert.Value,
+		FiredAt:      firedAt,
+	}
+	e.applySilenceRule(ctx, event)
+
+	if err := e.eventRepo.Create(ctx, event); err != nil {
+		appLogger.Error("创建外部告警事件失败", zap.String("receiver", receiver.Name), zap.Error(err))
+		return "ignored"
+	}
+	appLogger.Info("接收外部告警", zap.String("receiver", receiver.Name), zap.String("ruleName", ruleName), zap.String("fingerprint", fingerprint))
+
+	if !event.Silenced {
+		e.sendNotifications(ctx, rule, event, false)
+	}
+	return "fired"
+}
+
+// externalFingerprint 计算外部告警指纹：使用接收器 ID 而非名称，接收器改名后仍能匹配到原有事件
+// 外部告警不对应内部规则，ruleID 固定为 0（订阅中 rule_id=0 的"全部规则"可匹配）
+// nameFallback 表示 ruleName 取自接收器名称，此时 ruleName 同样不参与指纹计算
+func externalFingerprint(receiver *biz.AlertReceiver, labels map[string]string, nameFallback bool) string {
+	fpLabels := make(map[string]string, len(labels)+1)
+	for k, v := range labels {
+		fpLabels[k] = v
+	}
+	delete(fpLabels, "receiver")
+	if nameFallback {
+		delete(fpLabels, "ruleName")
+	}
+	fpLabels["receiver_id"] = strconv.FormatUint(uint64(receiver.ID), 10)
+	return calcFingerprint(0, fpLabels)
+}
+
+// normalizeExternalStatus 统一外部状态为 firing/resolved
+func normalizeExternalStatus(status string, resolvedValues []string) string {
+	if len(resolvedValues) == 0 {
+		resolvedValues = []string{"resolved", "ok", "recovered", "normal", "0"}
+	}
+	for _, v := range resolvedValues {
+		if strings.EqualFold(status, v) {
+			return "resolved"
+		}
+	}
+	return "firing"
+}
+
+// parseAnnotationValue 从注解中提取当前值（Prometheus 规则常用 value 注解）
+func parseAnnotationValue(annotations map[string]string) float64 {
+	for _, key := range []string{"value", "current_value", "currentValue"} {
+		if v, ok := annotations[key]; ok {
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				return f
+			}
+		}
+	}
+	return 0
+}
+
+// lookupJSONPath 按点分路径读取 JSON 字段，支持数组下标（如 "alerts.0.labels"）
+func lookupJSONPath(data interface{}, path string) interface{} {
+	if path == "" {
+		return nil
+	}
+	cur := data
+	for _, part := range strings.Split(path, ".") {
+		switch v := cur.(type) {
+		case map[string]interface{}:
+			cur = v[part]
+		case []interface{}:
+			idx, err := strconv.Atoi(part)
+			if err != nil || idx < 0 || idx >= len(v) {
+				return nil
+			}
+			cur = v[idx]
+		default:
+			return nil
+		}
+	}
+	return cur
+}
+
+func toString(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(val)
+	default:
+		b, _ := json.Marshal(val)
+		return string(b)
+	}
+}
+
+func toStringMap(v interface{}) map[string]string {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	out := make(map[string]string, len(m))
+	for k, val := range m {
+		out[k] = toString(val)
+	}
+	return out
+}
+
+// toTime 解析 RFC3339 字符串或 Unix 时间戳（秒/毫秒）
+func toTime(v interface{}) time.Time {
+	switch val := v.(type) {
+	case string:
+		if t, err := time.Parse(time.RFC3339, val); err == nil {
+			return t
+		}
+		if t, err := time.ParseInLocation("2006-01-02 15:04:05", val, time.Local); err == nil {
+			return t
+		}
+		if n, err := strconv.ParseInt(val, 10, 64); err == nil {
+			return toTime(float64(n))
+		}
+	case float64:
+		if val > 1e12 {
+			return time.UnixMilli(int64(val))
+		}
+		if val > 0 {
+			return time.Unix(int64(val), 0)
+		}
+	}
+	return time.Time{}
+}
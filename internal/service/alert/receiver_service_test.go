@@ -0,0 +1,120 @@
+package alert
+
+import (
+	"testing"
+	"time"
+
+	biz "github.com/ydcloud-dy/opshub/internal/biz/alert"
+)
+
+func TestParseAlertmanagerPayload(t *testing.T) {
+	body := []byte(`{
+		"version": "4",
+		"status": "firing",
+		"receiver": "opshub",
+		"commonAnnotations": {"summary": "common"},
+		"alerts": [
+			{
+				"status": "firing",
+				"labels": {"alertname": "HighCPU", "instance": "10.0.0.1:9100", "severity": "critical"},
+				"annotations": {"description": "cpu high", "value": "92.5"},
+				"startsAt": "2026-05-05T10:00:00Z",
+				"endsAt": "0001-01-01T00:00:00Z"
+			},
+			{
+				"status": "resolved",
+				"labels": {"alertname": "HighCPU", "instance": "10.0.0.2:9100"},
+				"annotations": {},
+				"startsAt": "2026-05-05T09:00:00Z",
+				"endsAt": "2026-05-05T10:05:00Z"
+			}
+		]
+	}`)
+
+	alerts, err := ParseAlertmanagerPayload(body)
+	if err != nil {
+		t.Fatalf("ParseAlertmanagerPayload error: %v", err)
+	}
+	if len(alerts) != 2 {
+		t.Fatalf("got %d alerts, want 2", len(alerts))
+	}
+	if alerts[0].Status != "firing" || alerts[0].Value != 92.5 {
+		t.Errorf("first alert = %+v", alerts[0])
+	}
+	if alerts[0].Annotations["summary"] != "common" {
+		t.Errorf("commonAnnotations not merged: %v", alerts[0].Annotations)
+	}
+	if alerts[1].Status != "resolved" || !alerts[1].EndsAt.Equal(time.Date(2026, 5, 5, 10, 5, 0, 0, time.UTC)) {
+		t.Errorf("second alert = %+v", alerts[1])
+	}
+
+	if _, err := ParseAlertmanagerPayload([]byte(`{"version":"3","alerts":[]}`)); err == nil {
+		t.Error("expected error for unsupported version")
+	}
+}
+
+func TestParseGenericPayload(t *testing.T) {
+	body := []byte(`{"data": {"events": [
+		{"trigger": "Disk full", "state": "PROBLEM", "level": "High", "host": {"name": "db01"}, "metric": 97, "clock": 1777975200},
+		{"trigger": "Disk full", "state": "OK", "level": "High", "host": {"name": "db02"}, "metric": 40}
+	]}}`)
+	mapping := `{
+		"alertsPath": "data.events",
+		"namePath": "trigger",
+		"statusPath": "state",
+		"resolvedValues": ["OK"],
+		"severityPath": "level",
+		"severityMap": {"High": "critical"},
+		"labelsPath": "host",
+		"valuePath": "metric",
+		"startsAtPath": "clock"
+	}`
+
+	alerts, err := ParseGenericPayload(body, mapping)
+	if err != nil {
+		t.Fatalf("ParseGenericPayload error: %v", err)
+	}
+	if len(alerts) != 2 {
+		t.Fatalf("got %d alerts, want 2", len(alerts))
+	}
+	first := alerts[0]
+	if first.Status != "firing" || first.Labels["alertname"] != "Disk full" || first.Labels["severity"] != "critical" {
+		t.Errorf("first alert = %+v", first)
+	}
+	if first.Labels["name"] != "db01" || first.Value != 97 {
+		t.Errorf("first alert labels/value = %v / %v", first.Labels, first.Value)
+	}
+	if !first.StartsAt.Equal(time.Unix(1777975200, 0)) {
+		t.Errorf("startsAt = %v", first.StartsAt)
+	}
+	if alerts[1].Status != "resolved" {
+		t.Errorf("second alert status = %s, want resolved", alerts[1].Status)
+	}
+}
+
+func TestExternalFingerprint_StableAcrossReceiverRename(t *testing.T) {
+	rc := &biz.AlertReceiver{ID: 3, Name: "prom-prod", Type: biz.ReceiverTypeAlertmanager}
+	labels := func(name string) map[string]string {
+		return map[string]string{"alertname": "HighCPU", "instance": "10.0.0.1", "receiver": name, "source": rc.Type, "ruleName": "HighCPU"}
+	}
+
+	before := externalFingerprint(rc, labels("prom-prod"), false)
+	rc.Name = "prom-production"
+	if after := externalFingerprint(rc, labels("prom-production"), false); after != before {
+		t.Fatalf("fingerprint changed after rename: %s -> %s", before, after)
+	}
+
+	// ruleName 取自接收器名称时同样不影响指纹
+	noName := func(name string) map[string]string {
+		return map[string]string{"instance": "10.0.0.1", "receiver": name, "ruleName": name}
+	}
+	if externalFingerprint(rc, noName("a"), true) != externalFingerprint(rc, noName("b"), true) {
+		t.Fatal("fallback ruleName should not affect fingerprint")
+	}
+
+	// 不同接收器的相同告警互不合并
+	other := &biz.AlertReceiver{ID: 4, Name: rc.Name, Type: rc.Type}
+	if externalFingerprint(other, labels(rc.Name), false) == externalFingerprint(rc, labels(rc.Name), false) {
+		t.Fatal("different receivers should have different fingerprints")
+	}
+}
@@ -0,0 +1,505 @@
+package alert
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	biz "github.com/ydcloud-dy/opshub/internal/biz/alert"
+	"github.com/ydcloud-dy/opshub/pkg/promtemplate"
+)
+
+// 规则单元测试默认参数（与 promtool test rules 一致）
+const (
+	defaultRuleTestInterval = time.Minute     // 默认采样间隔与评估间隔
+	ruleTestLookbackDelta   = 5 * time.Minute // 序列回溯窗口，超过则视为序列消失
+)
+
+// RuleTestFile 规则单元测试文件（promtool test rules 风格）
+//
+//	evaluation_interval: 1m
+//	rule:
+//	  alert: HighCPU
+//	  expr: cpu_usage > 90
+//	  for: 5m
+//	  labels: {severity: critical}
+//	  annotations: {summary: "{{ $labels.instance }} CPU {{ $value }}%"}
+//	tests:
+//	  - name: 持续 5 分钟后触发
+//	    interval: 1m
+//	    input_series:
+//	      - series: 'cpu_usage{instance="a"}'
+//	        values: '50 95x10'
+//	    alert_rule_test:
+//	      - eval_time: 7m
+//	        exp_alerts:
+//	          - exp_labels: {instance: a}
+//	            exp_annotations: {summary: "a CPU 95%"}
+//
+// input_series 模拟规则查询（QueryExpr，旧格式为 Expr）在各时刻返回的序列，表达式本身不执行；
+// 阈值条件、持续时长、keep_firing_for、标签合并与注解渲染均走告警引擎的同一套逻辑
+type RuleTestFile struct {
+	EvaluationInterval string         `yaml:"evaluation_interval,omitempty" json:"evaluationInterval,omitempty"`
+	Rule               *PromRule      `yaml:"rule,omitempty" json:"rule,omitempty"`
+	Tests              []RuleTestCase `yaml:"tests" json:"tests"`
+}
+
+// RuleTestCase 单个测试用例
+type RuleTestCase struct {
+	Name          string               `yaml:"name" json:"name"`
+	Interval      string               `yaml:"interval,omitempty" json:"interval,omitempty"` // 输入序列采样间隔
+	InputSeries   []RuleTestSeries     `yaml:"input_series" json:"inputSeries"`
+	AlertRuleTest []RuleTestEvaluation `yaml:"alert_rule_test" json:"alertRuleTest"`
+}
+
+// RuleTestSeries 输入序列
+// series 为 'metric{k="v"}' 形式；values 支持展开写法：a+bxn、a-bxn、axn、_（缺失）、_xn、stale
+type RuleTestSeries struct {
+	Series string `yaml:"series" json:"series"`
+	Values string `yaml:"values" json:"values"`
+}
+
+// RuleTestEvaluation 在指定时刻断言正在触发的告警
+type RuleTestEvaluation struct {
+	EvalTime  string             `yaml:"eval_time" json:"evalTime"`
+	ExpAlerts []RuleTestExpAlert `yaml:"exp_alerts" json:"expAlerts"`
+}
+
+// RuleTestExpAlert 期望的告警
+// exp_labels 与告警标签精确比对（忽略 __name__，未写 ruleName、severity 时按规则补齐）；
+// exp_annotations 非空时与渲染后的注解精确比对
+type RuleTestExpAlert struct {
+	ExpLabels      map[string]string `yaml:"exp_labels" json:"expLabels"`
+	ExpAnnotations map[string]string `yaml:"exp_annotations,omitempty" json:"expAnnotations,omitempty"`
+}
+
+// RuleTestResult 规则单元测试结果
+type RuleTestResult struct {
+	Passed bool                 `json:"passed"`
+	Cases  []RuleTestCaseResult `json:"cases"`
+}
+
+// RuleTestCaseResult 单个用例结果
+type RuleTestCaseResult struct {
+	Name     string   `json:"name"`
+	Passed   bool     `json:"passed"`
+	Failures []string `json:"failures,omitempty"`
+}
+
+// ParseRuleTestFile 解析规则单元测试文件
+func ParseRuleTestFile(data []byte) (*RuleTestFile, error) {
+	var f RuleTestFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("YAML 解析失败: %w", err)
+	}
+	if len(f.Tests) == 0 {
+		return nil, fmt.Errorf("测试文件中没有 tests")
+	}
+	return &f, nil
+}
+
+// RunRuleTests 执行规则单元测试
+// rule 为空时使用文件中的 rule 定义；用例本身格式错误时返回 error
+func RunRuleTests(f *RuleTestFile, rule *biz.AlertRule) (*RuleTestResult, error) {
+	if rule == nil {
+		if f.Rule == nil {
+			return nil, fmt.Errorf("测试文件未定义 rule")
+		}
+		r, err := PromRuleToAlertRule(*f.Rule, 0)
+		if err != nil {
+			return nil, fmt.Errorf("rule 无效: %w", err)
+		}
+		rule = r
+	}
+	evalInterval, err := parseRuleTestDuration(f.EvaluationInterval, defaultRuleTestInterval)
+	if err != nil || evalInterval <= 0 {
+		return nil, fmt.Errorf("evaluation_interval 无效: %s", f.EvaluationInterval)
+	}
+	dur, err := ruleDuration(rule.Duration)
+	if err != nil {
+		return nil, fmt.Errorf("规则持续时长无效: %w", err)
+	}
+	keepFor, err := ruleDuration(rule.KeepFiringFor)
+	if err != nil {
+		return nil, fmt.Errorf("规则 keep_firing_for 无效: %w", err)
+	}
+
+	sim := &ruleTestSimulator{
+		adapter:      promtemplate.NewAdapter(),
+		rule:         rule,
+		evalInterval: evalInterval,
+		dur:          dur,
+		keepFor:      keepFor,
+	}
+	result := &RuleTestResult{Passed: true}
+	for i, tc := range f.Tests {
+		name := tc.Name
+		if name == "" {
+			name = fmt.Sprintf("test #%d", i+1)
+		}
+		cr, err := sim.run(tc)
+		if err != nil {
+			return nil, fmt.Errorf("用例 %s: %w", name, err)
+		}
+		cr.Name = name
+		result.Passed = result.Passed && cr.Passed
+		result.Cases = append(result.Cases, *cr)
+	}
+	return result, nil
+}
+
+// ruleTestSeries 解析后的输入序列，samples[i] 对应 i*interval 时刻，nil 表示无采样
+type ruleTestSeries struct {
+	labels  map[string]string
+	samples []*float64
+	stale   []bool
+}
+
+// ruleTestDataSource 按时刻返回输入序列的伪数据源，取值规则与 Prometheus instant query 一致：
+// 取不晚于查询时刻的最近采样，超过回溯窗口或遇到 stale 标记则序列消失
+type ruleTestDataSource struct {
+	interval time.Duration
+	series   []ruleTestSeries
+}
+
+func (ds *ruleTestDataSource) query(offset time.Duration) []QueryResult {
+	var results []QueryResult
+	for _, s := range ds.series {
+		idx := int(offset / ds.interval)
+		if idx >= len(s.samples) {
+			idx = len(s.samples) - 1
+		}
+		for ; idx >= 0; idx-- {
+			if offset-time.Duration(idx)*ds.interval > ruleTestLookbackDelta || s.stale[idx] {
+				break
+			}
+			if v := s.samples[idx]; v != nil {
+				results = append(results, QueryResult{Labels: s.labels, Value: *v})
+				break
+			}
+		}
+	}
+	return results
+}
+
+// ruleTestSimulator 在内存中按评估间隔回放告警引擎的状态流转
+type ruleTestSimulator struct {
+	adapter      *promtemplate.Adapter
+	rule         *biz.AlertRule
+	evalInterval time.Duration
+	dur          time.Duration
+	keepFor      time.Duration
+}
+
+// ruleTestBaseTime 模拟时钟起点，保证 FiredAt 等注解渲染结果稳定
+var ruleTestBaseTime = time.Unix(0, 0).UTC()
+
+func (sim *ruleTestSimulator) run(tc RuleTestCase) (*RuleTestCaseResult, error) {
+	interval, err := parseRuleTestDuration(tc.Interval, defaultRuleTestInterval)
+	if err != nil || interval <= 0 {
+		return nil, fmt.Errorf("interval 无效: %s", tc.Interval)
+	}
+	ds := &ruleTestDataSource{interval: interval}
+	for _, in := range tc.InputSeries {
+		labels, err := parseRuleTestSeries(in.Series)
+		if err != nil {
+			return nil, err
+		}
+		samples, stale, err := expandRuleTestValues(in.Values)
+		if err != nil {
+			return nil, fmt.Errorf("序列 %s: %w", in.Series, err)
+		}
+		ds.series = append(ds.series, ruleTestSeries{labels: labels, samples: samples, stale: stale})
+	}
+
+	evals := tc.AlertRuleTest
+	evalTimes := make([]time.Duration, len(evals))
+	for i, ev := range evals {
+		d, err := parsePromDuration(ev.EvalTime)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("eval_time 无效: %s", ev.EvalTime)
+		}
+		evalTimes[i] = d
+	}
+	order := make([]int, len(evals))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return evalTimes[order[a]] < evalTimes[order[b]] })
+
+	res := &RuleTestCaseResult{}
+	states := make(map[string]alertState)
+	firing := make(map[string]*biz.AlertEvent)
+	var clock time.Duration
+	evaluated := false
+	for _, i := range order {
+		// 推进到不晚于 eval_time 的最后一个评估时刻
+		for !evaluated || clock+sim.evalInterval <= evalTimes[i] {
+			if evaluated {
+				clock += sim.evalInterval
+			}
+			evaluated = true
+			sim.step(ds.query(clock), ruleTestBaseTime.Add(clock), states, firing)
+		}
+		for _, msg := range sim.compare(evals[i], firing) {
+			res.Failures = append(res.Failures, fmt.Sprintf("eval_time %s: %s", evals[i].EvalTime, msg))
+		}
+	}
+	res.Passed = len(res.Failures) == 0
+	return res, nil
+}
+
+// step 单次评估，状态流转与 EvalEngine.evalRule 一致
+func (sim *ruleTestSimulator) step(results []QueryResult, now time.Time, states map[string]alertState, firing map[string]*biz.AlertEvent) {
+	hits, _ := classifyRuleResults(sim.rule, results)
+	for fp, hit := range hits {
+		state, found := states[fp]
+		state, ready := firingTransition(state, found, hit.Value, now, sim.dur)
+		if ready {
+			if event := firing[fp]; event != nil {
+				event.Value = hit.Value
+			} else {
+				if state.FiredAt.IsZero() {
+					state.FiredAt = now
+				}
+				firing[fp] = newFiringEvent(sim.adapter, sim.rule, fp, hit.Value, state.FiredAt, hit.Labels, "")
+			}
+		}
+		states[fp] = state
+	}
+
+	// 未命中的序列：pending 直接清除，已触发的按 keep_firing_for 判断是否恢复
+	pending := make(map[string]bool, len(states)+len(firing))
+	for fp := range states {
+		pending[fp] = true
+	}
+	for fp := range firing {
+		pending[fp] = true
+	}
+	for fp := range pending {
+		if _, hit := hits[fp]; hit {
+			continue
+		}
+		event := firing[fp]
+		if event == nil {
+			delete(states, fp)
+			continue
+		}
+		state, found := states[fp]
+		if !found {
+			state = alertState{PendingSince: event.FiredAt, FiredAt: event.FiredAt, Value: event.Value}
+		}
+		state, resolve := resolveTransition(state, now, sim.keepFor)
+		if !resolve {
+			states[fp] = state
+			continue
+		}
+		delete(firing, fp)
+		delete(states, fp)
+	}
+}
+
+// compare 比对当前触发中的告警与期望告警，返回差异描述
+func (sim *ruleTestSimulator) compare(ev RuleTestEvaluation, firing map[string]*biz.AlertEvent) []string {
+	type gotAlert struct {
+		labels      map[string]string
+		annotations map[string]string
+	}
+	got := make(map[string]gotAlert, len(firing))
+	for _, event := range firing {
+		labels := parseLabels(event.Labels)
+		delete(labels, "__name__")
+		annotations := map[string]string{}
+		if event.Annotations != "" {
+			_ = json.Unmarshal([]byte(event.Annotations), &annotations)
+		}
+		got[formatRuleTestLabels(labels)] = gotAlert{labels: labels, annotations: annotations}
+	}
+
+	var failures []string
+	matched := make(map[string]bool, len(ev.ExpAlerts))
+	for _, exp := range ev.ExpAlerts {
+		labels := make(map[string]string, len(exp.ExpLabels)+2)
+		for k, v := range exp.ExpLabels {
+			labels[k] = v
+		}
+		if _, ok := labels["severity"]; !ok {
+			labels["severity"] = sim.rule.Severity
+		}
+		if _, ok := labels["ruleName"]; !ok {
+			labels["ruleName"] = sim.rule.Name
+		}
+		key := formatRuleTestLabels(labels)
+		alert, ok := got[key]
+		if !ok || matched[key] {
+			failures = append(failures, "未触发期望的告警 "+key)
+			continue
+		}
+		matched[key] = true
+		if len(exp.ExpAnnotations) > 0 && formatRuleTestLabels(exp.ExpAnnotations) != formatRuleTestLabels(alert.annotations) {
+			failures = append(failures, fmt.Sprintf("告警 %s 注解不符，期望 %s，实际 %s",
+				key, formatRuleTestLabels(exp.ExpAnnotations), formatRuleTestLabels(alert.annotations)))
+		}
+	}
+
+	var unexpected []string
+	for key := range got {
+		if !matched[key] {
+			unexpected = append(unexpected, key)
+		}
+	}
+	sort.Strings(unexpected)
+	for _, key := range unexpected {
+		failures = append(failures, "出现非期望的告警 "+key)
+	}
+	return failures
+}
+
+// formatRuleTestLabels 按 key 排序格式化为 {k="v", ...}
+func formatRuleTestLabels(m map[string]string) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+"="+strconv.Quote(m[k]))
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// parseRuleTestDuration 解析 Prometheus 时长，空值返回默认值
+func parseRuleTestDuration(s string, def time.Duration) (time.Duration, error) {
+	if s == "" {
+		return def, nil
+	}
+	return parsePromDuration(s)
+}
+
+// parseRuleTestSeries 解析 'metric{k="v", ...}'，指标名作为 __name__ 标签
+func parseRuleTestSeries(s string) (map[string]string, error) {
+	s = strings.TrimSpace(s)
+	labels := make(map[string]string)
+	name, rest := s, ""
+	if i := strings.IndexByte(s, '{'); i >= 0 {
+		if !strings.HasSuffix(s, "}") {
+			return nil, fmt.Errorf("序列 %s 格式错误：缺少 }", s)
+		}
+		name, rest = strings.TrimSpace(s[:i]), s[i+1:len(s)-1]
+	}
+	if name != "" {
+		labels["__name__"] = name
+	}
+	for rest = strings.TrimSpace(rest); rest != ""; {
+		eq := strings.IndexByte(rest, '=')
+		if eq <= 0 {
+			return nil, fmt.Errorf("序列 %s 格式错误：标签缺少 =", s)
+		}
+		key := strings.TrimSpace(rest[:eq])
+		rest = strings.TrimSpace(rest[eq+1:])
+		quoted, err := strconv.QuotedPrefix(rest)
+		if err != nil {
+			return nil, fmt.Errorf("序列 %s 格式错误：标签 %s 的值需加引号", s, key)
+		}
+		value, _ := strconv.Unquote(quoted)
+		labels[key] = value
+		rest = strings.TrimSpace(rest[len(quoted):])
+		if rest != "" {
+			if rest[0] != ',' {
+				return nil, fmt.Errorf("序列 %s 格式错误：标签间需以逗号分隔", s)
+			}
+			rest = strings.TrimSpace(rest[1:])
+		}
+	}
+	if len(labels) == 0 {
+		return nil, fmt.Errorf("序列不能为空")
+	}
+	return labels, nil
+}
+
+// expandRuleTestValues 展开 promtool 值写法
+// "1 2 3"、"1+2x3"（1 3 5 7）、"5-1x2"（5 4 3）、"7x2"（7 7 7）、"_"（缺失）、"_x3"、"stale"
+func expandRuleTestValues(s string) ([]*float64, []bool, error) {
+	var samples []*float64
+	var stale []bool
+	for _, tok := range strings.Fields(s) {
+		switch {
+		case tok == "stale":
+			samples = append(samples, nil)
+			stale = append(stale, true)
+			continue
+		case tok == "_":
+			samples = append(samples, nil)
+			stale = append(stale, false)
+			continue
+		case strings.HasPrefix(tok, "_x"):
+			n, err := strconv.Atoi(tok[2:])
+			if err != nil || n < 0 {
+				return nil, nil, fmt.Errorf("无效的值 %q", tok)
+			}
+			for i := 0; i < n; i++ {
+				samples = append(samples, nil)
+				stale = append(stale, false)
+			}
+			continue
+		}
+
+		x := strings.LastIndexByte(tok, 'x')
+		if x < 0 {
+			v, err := parseRuleTestValue(tok)
+			if err != nil {
+				return nil, nil, fmt.Errorf("无效的值 %q", tok)
+			}
+			samples = append(samples, &v)
+			stale = append(stale, false)
+			continue
+		}
+		n, err := strconv.Atoi(tok[x+1:])
+		if err != nil || n < 0 {
+			return nil, nil, fmt.Errorf("无效的值 %q", tok)
+		}
+		head := tok[:x]
+		start, step := head, "0"
+		// 从第二个字符开始找步长运算符，跳过负号和科学计数法中的符号
+		for i := 1; i < len(head); i++ {
+			if (head[i] == '+' || head[i] == '-') && head[i-1] != 'e' && head[i-1] != 'E' {
+				start, step = head[:i], head[i:]
+				break
+			}
+		}
+		a, err := parseRuleTestValue(start)
+		if err != nil {
+			return nil, nil, fmt.Errorf("无效的值 %q", tok)
+		}
+		b, err := strconv.ParseFloat(step, 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("无效的值 %q", tok)
+		}
+		// axn 展开为 n+1 个值
+		for i := 0; i <= n; i++ {
+			v := a + b*float64(i)
+			samples = append(samples, &v)
+			stale = append(stale, false)
+		}
+	}
+	return samples, stale, nil
+}
+
+func parseRuleTestValue(s string) (float64, error) {
+	switch s {
+	case "Inf", "+Inf":
+		return math.Inf(1), nil
+	case "-Inf":
+		return math.Inf(-1), nil
+	case "NaN":
+		return math.NaN(), nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
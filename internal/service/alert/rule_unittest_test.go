@@ -0,0 +1,130 @@
+package alert
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const sampleRuleTest = `
+evaluation_interval: 1m
+rule:
+  alert: HighCPU
+  expr: cpu_usage > 90
+  for: 3m
+  keep_firing_for: 2m
+  labels:
+    severity: critical
+    team: sre
+  annotations:
+    summary: "{{ $labels.instance }} CPU {{ $value }}"
+tests:
+  - name: 持续超过 for 后触发，恢复后保持 keep_firing_for
+    input_series:
+      - series: 'cpu_usage{instance="a", team="db"}'
+        values: '50 95x4 60x5'
+      - series: 'cpu_usage{instance="b"}'
+        values: '95 95 50x5'
+    alert_rule_test:
+      - eval_time: 2m
+        exp_alerts: []
+      - eval_time: 4m
+        exp_alerts:
+          - exp_labels: {instance: a, team: db}
+            exp_annotations: {summary: "a CPU 95"}
+      - eval_time: 7m
+        exp_alerts:
+          - exp_labels: {instance: a, team: db}
+      - eval_time: 8m
+        exp_alerts: []
+  - name: 期望不符
+    input_series:
+      - series: 'cpu_usage{instance="a"}'
+        values: '95x5'
+    alert_rule_test:
+      - eval_time: 3m
+        exp_alerts:
+          - exp_labels: {instance: b}
+            exp_annotations: {summary: wrong}
+`
+
+func TestRunRuleTests(t *testing.T) {
+	appLogger.Log = zap.NewNop()
+	f, err := ParseRuleTestFile([]byte(sampleRuleTest))
+	if err != nil {
+		t.Fatalf("ParseRuleTestFile error: %v", err)
+	}
+	result, err := RunRuleTests(f, nil)
+	if err != nil {
+		t.Fatalf("RunRuleTests error: %v", err)
+	}
+	if result.Passed || len(result.Cases) != 2 {
+		t.Fatalf("result = %+v", result)
+	}
+	if c := result.Cases[0]; !c.Passed {
+		t.Errorf("case 1 failures: %v", c.Failures)
+	}
+	c := result.Cases[1]
+	if c.Passed || len(c.Failures) != 2 {
+		t.Fatalf("case 2 = %+v", c)
+	}
+	if !strings.Contains(c.Failures[0], `instance="b"`) || !strings.Contains(c.Failures[1], `出现非期望的告警 {instance="a", ruleName="HighCPU", severity="critical", team="sre"}`) {
+		t.Errorf("case 2 failures = %v", c.Failures)
+	}
+}
+
+func TestRunRuleTests_Invalid(t *testing.T) {
+	for _, content := range []string{
+		"tests: []",
+		"tests: [{name: x}]",
+		"rule: {alert: A, expr: up == 0}\ntests: [{input_series: [{series: 'up{a=b}', values: '1'}]}]",
+		"rule: {alert: A, expr: up == 0}\ntests: [{input_series: [{series: 'up', values: '1y2'}]}]",
+		"rule: {alert: A, expr: up == 0}\ntests: [{alert_rule_test: [{eval_time: abc}]}]",
+	} {
+		f, err := ParseRuleTestFile([]byte(content))
+		if err == nil {
+			_, err = RunRuleTests(f, nil)
+		}
+		if err == nil {
+			t.Errorf("content %q: expected error", content)
+		}
+	}
+}
+
+func TestExpandRuleTestValues(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"1 2 3", "1 2 3"},
+		{"1+2x3", "1 3 5 7"},
+		{"5-1x2", "5 4 3"},
+		{"-1+1x2", "-1 0 1"},
+		{"7x2", "7 7 7"},
+		{"1e1+1x1", "10 11"},
+		{"_ 1 _x2 stale", "_ 1 _ _ stale"},
+	}
+	for _, tt := range tests {
+		samples, stale, err := expandRuleTestValues(tt.in)
+		if err != nil {
+			t.Fatalf("expandRuleTestValues(%q) error: %v", tt.in, err)
+		}
+		var got []string
+		for i, v := range samples {
+			switch {
+			case stale[i]:
+				got = append(got, "stale")
+			case v == nil:
+				got = append(got, "_")
+			default:
+				got = append(got, strconv.FormatFloat(*v, 'g', -1, 64))
+			}
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("expandRuleTestValues(%q) = %v, want %s", tt.in, got, tt.want)
+		}
+	}
+}
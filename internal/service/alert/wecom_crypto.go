@@ -0,0 +1,105 @@
+package alert
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// WecomCrypto 企业微信应用回调消息加解密（WXBizMsgCrypt）
+// 签名 sha1(sort(token, timestamp, nonce, encrypt))；消息体 AES-256-CBC，IV 取密钥前 16 字节，
+// 明文格式为 16 字节随机串 + 4 字节网络序长度 + 消息 + ReceiveId(CorpID)
+type WecomCrypto struct {
+	token      string
+	receiverID string
+	key        []byte
+}
+
+var errWecomSignature = errors.New("企业微信回调签名校验失败")
+
+// NewWecomCrypto 创建加解密器，encodingAESKey 为 43 位 Base64 字符串
+func NewWecomCrypto(token, encodingAESKey, receiverID string) (*WecomCrypto, error) {
+	if token == "" || len(encodingAESKey) != 43 {
+		return nil, fmt.Errorf("企业微信回调 Token 或 EncodingAESKey 配置无效")
+	}
+	key, err := base64.StdEncoding.DecodeString(encodingAESKey + "=")
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("企业微信 EncodingAESKey 格式错误")
+	}
+	return &WecomCrypto{token: token, receiverID: receiverID, key: key}, nil
+}
+
+// Signature 计算消息签名
+func (c *WecomCrypto) Signature(timestamp, nonce, encrypt string) string {
+	parts := []string{c.token, timestamp, nonce, encrypt}
+	sort.Strings(parts)
+	sum := sha1.Sum([]byte(strings.Join(parts, "")))
+	return hex.EncodeToString(sum[:])
+}
+
+// Decrypt 校验签名并解密消息
+func (c *WecomCrypto) Decrypt(msgSignature, timestamp, nonce, encrypt string) ([]byte, error) {
+	if c.Signature(timestamp, nonce, encrypt) != msgSignature {
+		return nil, errWecomSignature
+	}
+	data, err := base64.StdEncoding.DecodeString(encrypt)
+	if err != nil || len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("企业微信回调消息格式错误")
+	}
+	block, err := aes.NewCipher(c.key)
+	if err != nil {
+		return nil, err
+	}
+	plain := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, c.key[:aes.BlockSize]).CryptBlocks(plain, data)
+
+	pad := int(plain[len(plain)-1])
+	if pad < 1 || pad > 32 || pad > len(plain) {
+		return nil, fmt.Errorf("企业微信回调消息填充错误")
+	}
+	plain = plain[:len(plain)-pad]
+	if len(plain) < 20 {
+		return nil, fmt.Errorf("企业微信回调消息长度错误")
+	}
+	msgLen := int(binary.BigEndian.Uint32(plain[16:20]))
+	if msgLen > len(plain)-20 {
+		return nil, fmt.Errorf("企业微信回调消息长度错误")
+	}
+	msg := plain[20 : 20+msgLen]
+	if c.receiverID != "" && string(plain[20+msgLen:]) != c.receiverID {
+		return nil, fmt.Errorf("企业微信回调 CorpID 不匹配")
+	}
+	return msg, nil
+}
+
+// Encrypt 加密消息（用于被动回复）
+func (c *WecomCrypto) Encrypt(msg []byte) (string, error) {
+	var buf bytes.Buffer
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	buf.Write(random)
+	binary.Write(&buf, binary.BigEndian, uint32(len(msg)))
+	buf.Write(msg)
+	buf.WriteString(c.receiverID)
+	pad := 32 - buf.Len()%32
+	buf.Write(bytes.Repeat([]byte{byte(pad)}, pad))
+
+	block, err := aes.NewCipher(c.key)
+	if err != nil {
+		return "", err
+	}
+	out := make([]byte, buf.Len())
+	cipher.NewCBCEncrypter(block, c.key[:aes.BlockSize]).CryptBlocks(out, buf.Bytes())
+	return base64.StdEncoding.EncodeToString(out), nil
+}
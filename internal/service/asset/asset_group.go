@@ -29,7 +29,8 @@ import (
 )
 
 type AssetGroupService struct {
-	groupUseCase *asset.AssetGroupUseCase
+	groupUseCase     *asset.AssetGroupUseCase
+	jumpChainUseCase *asset.JumpChainUseCase
 }
 
 func NewAssetGroupService(groupUseCase *asset.AssetGroupUseCase) *AssetGroupService {
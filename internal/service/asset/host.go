@@ -39,6 +39,9 @@ type HostService struct {
 	credentialUseCase        *asset.CredentialUseCase
 	cloudUseCase             *asset.CloudAccountUseCase
 	assetPermissionUseCase   *rbac.AssetPermissionUseCase
+	hostKeyUseCase           *asset.HostKeyUseCase
+	jumpChainUseCase         *asset.JumpChainUseCase
+	sshCAUseCase             *asset.SSHCAUseCase
 }
 
 func NewHostService(hostUseCase *asset.HostUseCase, credentialUseCase *asset.CredentialUseCase, cloudUseCase *asset.CloudAccountUseCase, assetPermissionUseCase *rbac.AssetPermissionUseCase) *HostService {
@@ -345,7 +348,7 @@ func (s *HostService) GetCredential(c *gin.Context) {
 	}
 
 	// 获取解密后的凭证详情（用于编辑时回显私钥）
-	credential, err := s.credentialUseCase.GetByIDDecrypted(c.Request.Context(), uint(id))
+	credential, err := s.credentialUseCase.GetByIDDecrypted(sshSessionContext(c, "credential"), uint(id))
 	if err != nil {
 		response.ErrorCode(c, http.StatusNotFound, "凭证不存在")
 		return
@@ -659,6 +662,34 @@ func (s *HostService) ImportFromCloud(c *gin.Context) {
 	response.SuccessWithMessage(c, "导入成功", nil)
 }
 
+// SyncCloudAccount 同步云账号的主机清单
+// @Summary 同步云主机
+// @Description 同步云账号下所有区域的实例：新实例创建主机，已有主机更新IP、规格和状态，已释放的实例标记为已下线
+// @Tags 资产管理-云账号
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "云账号ID"
+// @Success 200 {object} response.Response "同步成功"
+// @Failure 400 {object} response.Response "参数错误"
+// @Router /api/v1/cloud-accounts/{id}/sync [post]
+func (s *HostService) SyncCloudAccount(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, "无效的账号ID")
+		return
+	}
+
+	result, err := s.cloudUseCase.Sync(c.Request.Context(), uint(id), s.hostUseCase.GetHostRepo())
+	if err != nil {
+		response.ErrorCode(c, http.StatusInternalServerError, "同步失败: "+err.Error())
+		return
+	}
+
+	response.Success(c, result)
+}
+
 // CollectHostInfo 采集主机信息
 // @Summary 采集主机信息
 // @Description 采集指定主机的系统信息
@@ -678,7 +709,7 @@ func (s *HostService) CollectHostInfo(c *gin.Context) {
 		return
 	}
 
-	if err := s.hostUseCase.CollectHostInfo(c.Request.Context(), uint(id)); err != nil {
+	if err := s.hostUseCase.CollectHostInfo(sshSessionContext(c, "collect"), uint(id)); err != nil {
 		response.ErrorCode(c, http.StatusInternalServerError, "采集失败: "+err.Error())
 		return
 	}
@@ -705,7 +736,7 @@ func (s *HostService) TestHostConnection(c *gin.Context) {
 		return
 	}
 
-	if err := s.hostUseCase.TestConnection(c.Request.Context(), uint(id)); err != nil {
+	if err := s.hostUseCase.TestConnection(sshSessionContext(c, "test"), uint(id)); err != nil {
 		response.ErrorCode(c, http.StatusInternalServerError, "连接失败: "+err.Error())
 		return
 	}
@@ -733,7 +764,7 @@ func (s *HostService) BatchCollectHostInfo(c *gin.Context) {
 		return
 	}
 
-	if err := s.hostUseCase.BatchCollectHostInfo(c.Request.Context(), req.HostIDs); err != nil {
+	if err := s.hostUseCase.BatchCollectHostInfo(sshSessionContext(c, "collect"), req.HostIDs); err != nil {
 		response.ErrorCode(c, http.StatusInternalServerError, "批量采集失败: "+err.Error())
 		return
 	}
@@ -939,7 +970,7 @@ func (s *HostService) ListHostFiles(c *gin.Context) {
 	// 获取目录路径参数，默认为用户主目录
 	remotePath := c.DefaultQuery("path", "~")
 
-	files, err := s.hostUseCase.ListFiles(c.Request.Context(), uint(id), remotePath)
+	files, err := s.hostUseCase.ListFiles(sshSessionContext(c, "sftp"), uint(id), remotePath)
 	if err != nil {
 		response.ErrorCode(c, http.StatusInternalServerError, "获取文件列表失败: "+err.Error())
 		return
@@ -991,7 +1022,7 @@ func (s *HostService) UploadHostFile(c *gin.Context) {
 	defer src.Close()
 
 	// 上传文件
-	if err := s.hostUseCase.UploadFile(c.Request.Context(), uint(id), src, remotePath, file.Filename); err != nil {
+	if err := s.hostUseCase.UploadFile(sshSessionContext(c, "sftp"), uint(id), src, remotePath, file.Filename); err != nil {
 		response.ErrorCode(c, http.StatusInternalServerError, "上传文件失败: "+err.Error())
 		return
 	}
@@ -1038,7 +1069,7 @@ func (s *HostService) DownloadHostFile(c *gin.Context) {
 	c.Header("Content-Transfer-Encoding", "binary")
 
 	// 下载文件
-	if err := s.hostUseCase.DownloadFile(c.Request.Context(), uint(id), remotePath, c.Writer); err != nil {
+	if err := s.hostUseCase.DownloadFile(sshSessionContext(c, "sftp"), uint(id), remotePath, c.Writer); err != nil {
 		response.ErrorCode(c, http.StatusInternalServerError, "下载文件失败: "+err.Error())
 		return
 	}
@@ -1072,7 +1103,7 @@ func (s *HostService) DeleteHostFile(c *gin.Context) {
 		return
 	}
 
-	if err := s.hostUseCase.DeleteFile(c.Request.Context(), uint(id), req.Path); err != nil {
+	if err := s.hostUseCase.DeleteFile(sshSessionContext(c, "sftp"), uint(id), req.Path); err != nil {
 		response.ErrorCode(c, http.StatusInternalServerError, "删除文件失败: "+err.Error())
 		return
 	}
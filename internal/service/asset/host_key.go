@@ -0,0 +1,152 @@
+// Copyright (c) 2026 DYCloud J.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package asset
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ydcloud-dy/opshub/internal/biz/asset"
+	rbacService "github.com/ydcloud-dy/opshub/internal/service/rbac"
+	"github.com/ydcloud-dy/opshub/pkg/response"
+	"gorm.io/gorm"
+)
+
+// SetHostKeyUseCase 注入主机密钥用例
+func (s *HostService) SetHostKeyUseCase(uc *asset.HostKeyUseCase) {
+	s.hostKeyUseCase = uc
+}
+
+// GetHostKey 获取主机已信任的SSH主机密钥
+// @Summary 获取主机密钥
+// @Description 获取主机首次连接时信任或管理员重新信任的SSH主机密钥
+// @Tags 资产管理-主机
+// @Produce json
+// @Security Bearer
+// @Param id path int true "主机ID"
+// @Success 200 {object} response.Response{data=asset.HostKey} "获取成功"
+// @Failure 404 {object} response.Response "尚未连接过"
+// @Router /api/v1/hosts/{id}/host-key [get]
+func (s *HostService) GetHostKey(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, "无效的主机ID")
+		return
+	}
+	key, err := s.hostKeyUseCase.GetByHostID(c.Request.Context(), uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		response.ErrorCode(c, http.StatusNotFound, "尚未通过SSH连接过该主机")
+		return
+	}
+	if err != nil {
+		response.ErrorCode(c, http.StatusInternalServerError, "查询失败: "+err.Error())
+		return
+	}
+	response.Success(c, key)
+}
+
+// ListHostKeyAlerts 主机密钥变更告警列表
+// @Summary 主机密钥变更告警
+// @Description 主机密钥与已信任的不一致时拒绝连接并记录告警
+// @Tags 资产管理-主机
+// @Produce json
+// @Security Bearer
+// @Param hostId query int false "主机ID"
+// @Param status query string false "状态 open/retrusted"
+// @Param page query int false "页码"
+// @Param pageSize query int false "每页数量"
+// @Success 200 {object} response.Response "获取成功"
+// @Router /api/v1/hosts/host-key-alerts [get]
+func (s *HostService) ListHostKeyAlerts(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	hostID, _ := strconv.ParseUint(c.Query("hostId"), 10, 32)
+
+	alerts, total, err := s.hostKeyUseCase.ListAlerts(c.Request.Context(), page, pageSize, uint(hostID), c.Query("status"))
+	if err != nil {
+		response.ErrorCode(c, http.StatusInternalServerError, "查询失败: "+err.Error())
+		return
+	}
+	response.Success(c, gin.H{
+		"list":     alerts,
+		"total":    total,
+		"page":     page,
+		"pageSize": pageSize,
+	})
+}
+
+// RetrustHostKey 核实后信任告警中的新主机密钥
+// @Summary 重新信任主机密钥
+// @Description 管理员核实主机密钥变更（如重装系统）后，信任告警中的新密钥
+// @Tags 资产管理-主机
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "告警ID"
+// @Param body body object true "新密钥指纹 {fingerprint: string}"
+// @Success 200 {object} response.Response{data=asset.HostKey} "已重新信任"
+// @Failure 400 {object} response.Response "指纹不一致或告警已处理"
+// @Router /api/v1/hosts/host-key-alerts/{id}/retrust [post]
+func (s *HostService) RetrustHostKey(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, "无效的告警ID")
+		return
+	}
+	var req struct {
+		Fingerprint string `json:"fingerprint" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+
+	key, err := s.hostKeyUseCase.Retrust(c.Request.Context(), uint(id), req.Fingerprint, rbacService.GetUsername(c))
+	if err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	response.SuccessWithMessage(c, "已重新信任主机密钥", key)
+}
+
+// TrustExistingHostKeys 批量信任尚未记录密钥的主机的当前密钥
+// @Summary 批量信任现有主机密钥
+// @Description 启用主机密钥校验前已存在的主机没有记录密钥，终端、任务和文件传输会拒绝连接。管理员确认网络可信后，对这些主机测试连接并首次信任当前密钥
+// @Tags 资产管理-主机
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} response.Response{data=[]asset.HostKeyTrustResult} "各主机的信任结果"
+// @Router /api/v1/hosts/host-keys/trust-existing [post]
+func (s *HostService) TrustExistingHostKeys(c *gin.Context) {
+	results, err := s.hostKeyUseCase.TrustExisting(sshSessionContext(c, "test"), s.hostUseCase)
+	if err != nil {
+		response.ErrorCode(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	response.SuccessWithMessage(c, "已信任现有主机密钥", results)
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2026 DYCloud J.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package asset
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ydcloud-dy/opshub/internal/biz/asset"
+	"github.com/ydcloud-dy/opshub/pkg/response"
+)
+
+// jumpChainRequest 跳板链配置，按连接顺序排列，为空表示清除
+type jumpChainRequest struct {
+	Hops []asset.JumpHopRequest `json:"hops" binding:"dive"`
+}
+
+// SetJumpChainUseCase 注入跳板链用例
+func (s *HostService) SetJumpChainUseCase(uc *asset.JumpChainUseCase) {
+	s.jumpChainUseCase = uc
+}
+
+// SetJumpChainUseCase 注入跳板链用例
+func (s *AssetGroupService) SetJumpChainUseCase(uc *asset.JumpChainUseCase) {
+	s.jumpChainUseCase = uc
+}
+
+// GetHostJumpChain 获取主机生效的跳板链
+// @Summary 获取主机跳板链
+// @Description 主机单独配置的跳板链优先，未配置时返回所属分组或上级分组的跳板链
+// @Tags 资产管理-主机
+// @Produce json
+// @Security Bearer
+// @Param id path int true "主机ID"
+// @Success 200 {object} response.Response{data=asset.JumpChainVO} "获取成功"
+// @Router /api/v1/hosts/{id}/jump-chain [get]
+func (s *HostService) GetHostJumpChain(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, "无效的主机ID")
+		return
+	}
+	chain, err := s.jumpChainUseCase.GetHostChain(c.Request.Context(), uint(id))
+	if err != nil {
+		response.ErrorCode(c, http.StatusInternalServerError, "查询失败: "+err.Error())
+		return
+	}
+	response.Success(c, chain)
+}
+
+// UpdateHostJumpChain 设置主机的跳板链
+// @Summary 设置主机跳板链
+// @Description 设置连接主机时依次经过的跳板，每跳使用自己的凭证；为空时沿用分组的跳板链
+// @Tags 资产管理-主机
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "主机ID"
+// @Param body body jumpChainRequest true "跳板链"
+// @Success 200 {object} response.Response "设置成功"
+// @Router /api/v1/hosts/{id}/jump-chain [put]
+func (s *HostService) UpdateHostJumpChain(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, "无效的主机ID")
+		return
+	}
+	var req jumpChainRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+	if err := s.jumpChainUseCase.SetHostChain(c.Request.Context(), uint(id), req.Hops); err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	response.SuccessWithMessage(c, "跳板链已更新", nil)
+}
+
+// GetGroupJumpChain 获取分组配置的跳板链
+// @Summary 获取分组跳板链
+// @Description 分组的跳板链对分组及下级分组中未单独配置跳板链的主机生效
+// @Tags 资产管理-分组
+// @Produce json
+// @Security Bearer
+// @Param id path int true "分组ID"
+// @Success 200 {object} response.Response{data=asset.JumpChainVO} "获取成功"
+// @Router /api/v1/asset-groups/{id}/jump-chain [get]
+func (s *AssetGroupService) GetGroupJumpChain(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, "无效的分组ID")
+		return
+	}
+	chain, err := s.jumpChainUseCase.GetGroupChain(c.Request.Context(), uint(id))
+	if err != nil {
+		response.ErrorCode(c, http.StatusInternalServerError, "查询失败: "+err.Error())
+		return
+	}
+	response.Success(c, chain)
+}
+
+// UpdateGroupJumpChain 设置分组的跳板链
+// @Summary 设置分组跳板链
+// @Description 设置分组内主机默认经过的跳板，每跳使用自己的凭证；为空时沿用上级分组的跳板链
+// @Tags 资产管理-分组
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "分组ID"
+// @Param body body jumpChainRequest true "跳板链"
+// @Success 200 {object} response.Response "设置成功"
+// @Router /api/v1/asset-groups/{id}/jump-chain [put]
+func (s *AssetGroupService) UpdateGroupJumpChain(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, "无效的分组ID")
+		return
+	}
+	var req jumpChainRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+	if err := s.jumpChainUseCase.SetGroupChain(c.Request.Context(), uint(id), req.Hops); err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	response.SuccessWithMessage(c, "跳板链已更新", nil)
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2026 DYCloud J.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package asset
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ydcloud-dy/opshub/internal/biz/asset"
+	rbacService "github.com/ydcloud-dy/opshub/internal/service/rbac"
+	"github.com/ydcloud-dy/opshub/pkg/response"
+)
+
+// SetSSHCAUseCase 注入 SSH CA 用例
+func (s *HostService) SetSSHCAUseCase(uc *asset.SSHCAUseCase) {
+	s.sshCAUseCase = uc
+}
+
+// sshSessionContext 记录发起SSH会话的当前用户，CA签发的证书按该用户及其角色授权
+func sshSessionContext(c *gin.Context, source string) context.Context {
+	return asset.WithSSHIdentity(c.Request.Context(), asset.SSHIdentity{
+		UserID:   rbacService.GetUserID(c),
+		Username: rbacService.GetUsername(c),
+		Source:   source,
+	})
+}
+
+// GetSSHCA 获取 SSH CA 信息
+// @Summary 获取SSH CA
+// @Description 获取 OpsHub SSH CA 公钥、指纹和签发证书的有效期
+// @Tags 资产管理-SSH CA
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} response.Response{data=asset.SSHCAVO} "获取成功"
+// @Router /api/v1/ssh-ca [get]
+func (s *HostService) GetSSHCA(c *gin.Context) {
+	vo, err := s.sshCAUseCase.Get(c.Request.Context())
+	if err != nil {
+		response.ErrorCode(c, http.StatusInternalServerError, "查询失败: "+err.Error())
+		return
+	}
+	response.Success(c, vo)
+}
+
+// GenerateSSHCA 生成或轮换 SSH CA
+// @Summary 生成SSH CA
+// @Description 生成 CA 密钥，已存在时轮换；轮换后需在主机上重新安装公钥
+// @Tags 资产管理-SSH CA
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} response.Response{data=asset.SSHCAVO} "生成成功"
+// @Router /api/v1/ssh-ca/generate [post]
+func (s *HostService) GenerateSSHCA(c *gin.Context) {
+	vo, err := s.sshCAUseCase.Generate(c.Request.Context())
+	if err != nil {
+		response.ErrorCode(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	response.SuccessWithMessage(c, "SSH CA 已生成", vo)
+}
+
+// UpdateSSHCA 修改签发证书的有效期
+// @Summary 修改SSH CA设置
+// @Tags 资产管理-SSH CA
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param body body object true "证书有效期（分钟） {certTtl: int}"
+// @Success 200 {object} response.Response "修改成功"
+// @Router /api/v1/ssh-ca [put]
+func (s *HostService) UpdateSSHCA(c *gin.Context) {
+	var req struct {
+		CertTTL int `json:"certTtl" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+	if err := s.sshCAUseCase.UpdateCertTTL(c.Request.Context(), req.CertTTL); err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	response.SuccessWithMessage(c, "修改成功", nil)
+}
+
+// DownloadSSHCAInstallScript 下载 CA 公钥安装脚本
+// @Summary 下载SSH CA安装脚本
+// @Description 脚本写入 CA 公钥和各登录账号允许的证书主体，配置 TrustedUserCAKeys、AuthorizedPrincipalsFile 后重新加载 sshd
+// @Tags 资产管理-SSH CA
+// @Accept json
+// @Produce plain
+// @Security Bearer
+// @Param body body asset.SSHCAInstallRequest false "登录账号及允许的证书主体"
+// @Success 200 {string} string "安装脚本"
+// @Router /api/v1/ssh-ca/install-script [post]
+func (s *HostService) DownloadSSHCAInstallScript(c *gin.Context) {
+	var req asset.SSHCAInstallRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.ErrorCode(c, http.StatusBadRequest, "参数错误: "+err.Error())
+			return
+		}
+	}
+	script, err := s.sshCAUseCase.InstallScript(c.Request.Context(), req.Accounts)
+	if err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	c.Header("Content-Disposition", "attachment; filename=install-opshub-ssh-ca.sh")
+	c.Data(http.StatusOK, "text/x-shellscript; charset=utf-8", []byte(script))
+}
+
+// InstallSSHCA 在主机上安装 CA 公钥
+// @Summary 安装SSH CA公钥
+// @Description Agent在线时经Agent执行安装脚本，否则使用主机凭证经SSH执行
+// @Tags 资产管理-SSH CA
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param body body asset.SSHCAInstallRequest true "主机及登录账号"
+// @Success 200 {object} response.Response{data=[]asset.SSHCAInstallResult} "执行结果"
+// @Router /api/v1/ssh-ca/install [post]
+func (s *HostService) InstallSSHCA(c *gin.Context) {
+	var req asset.SSHCAInstallRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+	results, err := s.sshCAUseCase.Install(sshSessionContext(c, "ssh-ca-install"), &req)
+	if err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	response.Success(c, results)
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/robfig/cron/v3"
+	assetbiz "github.com/ydcloud-dy/opshub/internal/biz/asset"
 	inspectionmgmtdata "github.com/ydcloud-dy/opshub/internal/data/inspection_mgmt"
 	appLogger "github.com/ydcloud-dy/opshub/pkg/logger"
 	"github.com/ydcloud-dy/opshub/pkg/metrics"
@@ -93,6 +94,8 @@ func (e *InspectionExecutor) Execute(ctx context.Context, task scheduler.Task) e
 	triggerType := payload.TriggerType
 	if triggerType == "" {
 		triggerType = "scheduled"
+		// 定时触发没有发起用户，CA签发的凭证使用自动化任务主体
+		ctx = assetbiz.WithSSHSystem(ctx, "inspection")
 	}
 
 	// 获取任务配置
@@ -204,8 +204,11 @@ func (m *AuthMiddleware) RequireHostPermission(operation uint) gin.HandlerFunc {
 			return
 		}
 
-		// 获取主机ID
+		// 获取主机ID（Agent相关路由使用 :hostId）
 		hostIDStr := c.Param("id")
+		if hostIDStr == "" {
+			hostIDStr = c.Param("hostId")
+		}
 		if hostIDStr == "" {
 			// 对于创建操作，暂不检查具体权限（创建权限通过分组权限检查）
 			c.Next()
@@ -414,3 +417,12 @@ func (m *AuthMiddleware) checkIsAdmin(ctx context.Context, userID uint) bool {
 	}
 	return false
 }
+
+// IsAdmin 当前请求用户是否为管理员（API Key 视同管理员），用于处理器内按资源归属放行
+func (m *AuthMiddleware) IsAdmin(c *gin.Context) bool {
+	if isAPIKey, exists := c.Get("is_api_key"); exists && isAPIKey.(bool) {
+		return true
+	}
+	userID := GetUserID(c)
+	return userID != 0 && m.checkIsAdmin(c.Request.Context(), userID)
+}
@@ -0,0 +1,31 @@
+// Package testutil 单元测试共用的辅助函数，只应被 _test.go 引用。
+// 数据库辅助依赖 gorm.io/driver/sqlite（mattn/go-sqlite3），运行相关测试需开启 cgo
+package testutil
+
+import (
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// NewDB 创建测试用的内存 sqlite 数据库并建表，每个测试独立一个库，测试结束时关闭
+// 共享缓存模式下限制为单连接，避免并发写入时 database is locked
+func NewDB(t testing.TB, models ...any) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("打开 sqlite 失败: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("建表失败: %v", err)
+	}
+	return db
+}
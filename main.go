@@ -24,10 +24,11 @@ import (
 	"os"
 
 	"github.com/ydcloud-dy/opshub/cmd/root"
-	_ "github.com/ydcloud-dy/opshub/cmd/config"  // 注册配置命令
-	_ "github.com/ydcloud-dy/opshub/cmd/server"  // 注册服务命令
-	_ "github.com/ydcloud-dy/opshub/cmd/version" // 注册版本命令
-	_ "github.com/ydcloud-dy/opshub/docs"        // 导入 Swagger 生成的文档
+	_ "github.com/ydcloud-dy/opshub/cmd/config"   // 注册配置命令
+	_ "github.com/ydcloud-dy/opshub/cmd/ruletest" // 注册规则单元测试命令
+	_ "github.com/ydcloud-dy/opshub/cmd/server"   // 注册服务命令
+	_ "github.com/ydcloud-dy/opshub/cmd/version"  // 注册版本命令
+	_ "github.com/ydcloud-dy/opshub/docs"         // 导入 Swagger 生成的文档
 )
 
 // @title           OpsHub API
@@ -1409,6 +1409,7 @@ VALUES
   (220, '/api/v1/hosts', 'POST', NOW(), NOW()),
   (220, '/api/v1/hosts/import', 'POST', NOW(), NOW()),
   (220, '/api/v1/cloud-accounts/import', 'POST', NOW(), NOW()),
+  (220, '/api/v1/cloud-accounts/:id/sync', 'POST', NOW(), NOW()),
   (221, '/api/v1/hosts/:id', 'PUT', NOW(), NOW()),
   (222, '/api/v1/hosts/:id', 'DELETE', NOW(), NOW()),
   (223, '/api/v1/hosts/batch-delete', 'POST', NOW(), NOW()),
@@ -1422,6 +1423,7 @@ VALUES
   (231, '/api/v1/credentials/:id', 'DELETE', NOW(), NOW()),
   (232, '/api/v1/cloud-accounts', 'POST', NOW(), NOW()),
   (233, '/api/v1/cloud-accounts/import', 'POST', NOW(), NOW()),
+  (233, '/api/v1/cloud-accounts/:id/sync', 'POST', NOW(), NOW()),
   (234, '/api/v1/cloud-accounts/:id', 'PUT', NOW(), NOW()),
   (235, '/api/v1/cloud-accounts/:id', 'DELETE', NOW(), NOW()),
   (236, '/api/v1/terminal-sessions/:id', 'DELETE', NOW(), NOW()),
@@ -1505,6 +1507,7 @@ VALUES
   (313, '/api/v1/plugins/task/templates/:id', 'PUT', NOW(), NOW()),
   (314, '/api/v1/plugins/task/templates/:id', 'DELETE', NOW(), NOW()),
   (315, '/api/v1/plugins/task/execute', 'POST', NOW(), NOW()),
+  (315, '/api/v1/plugins/task/execute/stream', 'GET', NOW(), NOW()),
   (316, '/api/v1/plugins/task/distribute', 'POST', NOW(), NOW()),
   (317, '/api/v1/identity/sources', 'POST', NOW(), NOW()),
   (318, '/api/v1/identity/sources/:id', 'PUT', NOW(), NOW()),
@@ -2060,6 +2063,9 @@ VALUES
   (416, '/api/v1/alert/rules/:id/clone', 'POST', NOW(), NOW()),
   (417, '/api/v1/alert/rules/import', 'POST', NOW(), NOW()),
   (417, '/api/v1/alert/rules/export', 'GET', NOW(), NOW()),
+  (417, '/api/v1/alert/rule-groups/:id/prometheus/preview', 'POST', NOW(), NOW()),
+  (417, '/api/v1/alert/rule-groups/:id/prometheus/import', 'POST', NOW(), NOW()),
+  (417, '/api/v1/alert/rule-groups/:id/prometheus/export', 'GET', NOW(), NOW()),
   -- 数据源页面（读取类）
   (406, '/api/v1/alert/datasources', 'GET', NOW(), NOW()),
   (406, '/api/v1/alert/datasources/:id/test', 'POST', NOW(), NOW()),
@@ -2071,9 +2077,15 @@ VALUES
   (407, '/api/v1/alert/events/active', 'GET', NOW(), NOW()),
   (407, '/api/v1/alert/events/stats', 'GET', NOW(), NOW()),
   (407, '/api/v1/alert/events/trend', 'GET', NOW(), NOW()),
+  (407, '/api/v1/alert/events/:id/timeline', 'GET', NOW(), NOW()),
   -- 实时告警按钮
   (421, '/api/v1/alert/events/:id/silence', 'POST', NOW(), NOW()),
   (422, '/api/v1/alert/events/:id/handle', 'POST', NOW(), NOW()),
+  (422, '/api/v1/alert/events/:id/ack', 'POST', NOW(), NOW()),
+  (422, '/api/v1/alert/events/:id/unack', 'POST', NOW(), NOW()),
+  (422, '/api/v1/alert/events/:id/claim', 'POST', NOW(), NOW()),
+  (422, '/api/v1/alert/events/:id/assign', 'POST', NOW(), NOW()),
+  (422, '/api/v1/alert/events/:id/resolve', 'POST', NOW(), NOW()),
   -- 历史告警页面（读取类）
   (408, '/api/v1/alert/events/history', 'GET', NOW(), NOW()),
   -- 告警通道页面（读取类）
@@ -2227,4 +2239,195 @@ VALUES
   (1, 431),  -- 查看巡检报告
   (1, 432);  -- 手动执行巡检
 
+-- ============================================================
+-- 20. 主机与Agent安全管理按钮权限（接口同时要求管理员角色）
+-- ============================================================
+
+-- 20.1 Agent证书管理 (parent_id=16 主机管理)
+INSERT INTO `sys_menu` (`id`, `name`, `code`, `type`, `parent_id`, `path`, `component`, `icon`, `sort`, `visible`, `status`, `api_path`, `api_method`, `created_at`, `updated_at`)
+VALUES
+  (433, 'Agent证书', 'hosts:agent-certs', 3, 16, '', '', '', 27, 1, 1, '/api/v1/agents/:hostId/certs', 'GET', NOW(), NOW()),
+  (434, '吊销Agent证书', 'hosts:agent-certs-revoke', 3, 16, '', '', '', 28, 1, 1, '/api/v1/agents/:hostId/certs/revoke', 'POST', NOW(), NOW());
+
+INSERT INTO `sys_menu_api` (`menu_id`, `api_path`, `api_method`, `created_at`, `updated_at`)
+VALUES
+  (433, '/api/v1/agents/:hostId/certs', 'GET', NOW(), NOW()),
+  (434, '/api/v1/agents/:hostId/certs/revoke', 'POST', NOW(), NOW());
+
+INSERT INTO `sys_role_menu` (`role_id`, `menu_id`)
+VALUES
+  (1, 433),  -- Agent证书
+  (1, 434);  -- 吊销Agent证书
+
+-- 20.2 主机密钥重新信任 (parent_id=16 主机管理)
+INSERT INTO `sys_menu` (`id`, `name`, `code`, `type`, `parent_id`, `path`, `component`, `icon`, `sort`, `visible`, `status`, `api_path`, `api_method`, `created_at`, `updated_at`)
+VALUES
+  (435, '重新信任主机密钥', 'hosts:host-key-retrust', 3, 16, '', '', '', 29, 1, 1, '/api/v1/hosts/host-key-alerts/:id/retrust', 'POST', NOW(), NOW());
+
+INSERT INTO `sys_menu_api` (`menu_id`, `api_path`, `api_method`, `created_at`, `updated_at`)
+VALUES
+  (435, '/api/v1/hosts/host-key-alerts/:id/retrust', 'POST', NOW(), NOW());
+
+INSERT INTO `sys_role_menu` (`role_id`, `menu_id`)
+VALUES
+  (1, 435);  -- 重新信任主机密钥
+
+-- 20.3 跳板链配置接口归入主机、分组的编辑按钮
+INSERT INTO `sys_menu_api` (`menu_id`, `api_path`, `api_method`, `created_at`, `updated_at`)
+VALUES
+  (221, '/api/v1/hosts/:id/jump-chain', 'PUT', NOW(), NOW()),
+  (227, '/api/v1/asset-groups/:id/jump-chain', 'PUT', NOW(), NOW());
+
+-- 20.4 SSH CA管理 (parent_id=16 主机管理)
+INSERT INTO `sys_menu` (`id`, `name`, `code`, `type`, `parent_id`, `path`, `component`, `icon`, `sort`, `visible`, `status`, `api_path`, `api_method`, `created_at`, `updated_at`)
+VALUES
+  (436, 'SSH CA', 'hosts:ssh-ca', 3, 16, '', '', '', 30, 1, 1, '/api/v1/ssh-ca', 'GET', NOW(), NOW()),
+  (437, '生成SSH CA', 'hosts:ssh-ca-generate', 3, 16, '', '', '', 31, 1, 1, '/api/v1/ssh-ca/generate', 'POST', NOW(), NOW()),
+  (438, '下载CA安装脚本', 'hosts:ssh-ca-install-script', 3, 16, '', '', '', 32, 1, 1, '/api/v1/ssh-ca/install-script', 'POST', NOW(), NOW()),
+  (439, '安装CA公钥', 'hosts:ssh-ca-install', 3, 16, '', '', '', 33, 1, 1, '/api/v1/ssh-ca/install', 'POST', NOW(), NOW());
+
+INSERT INTO `sys_menu_api` (`menu_id`, `api_path`, `api_method`, `created_at`, `updated_at`)
+VALUES
+  (436, '/api/v1/ssh-ca', 'GET', NOW(), NOW()),
+  (437, '/api/v1/ssh-ca/generate', 'POST', NOW(), NOW()),
+  (437, '/api/v1/ssh-ca', 'PUT', NOW(), NOW()),
+  (438, '/api/v1/ssh-ca/install-script', 'POST', NOW(), NOW()),
+  (439, '/api/v1/ssh-ca/install', 'POST', NOW(), NOW());
+
+INSERT INTO `sys_role_menu` (`role_id`, `menu_id`)
+VALUES
+  (1, 436),  -- SSH CA
+  (1, 437),  -- 生成SSH CA
+  (1, 438),  -- 下载CA安装脚本
+  (1, 439);  -- 安装CA公钥
+
+-- 20.5 Agent远程升级 (parent_id=16 主机管理)
+INSERT INTO `sys_menu` (`id`, `name`, `code`, `type`, `parent_id`, `path`, `component`, `icon`, `sort`, `visible`, `status`, `api_path`, `api_method`, `created_at`, `updated_at`)
+VALUES
+  (450, '远程升级Agent', 'hosts:agent-upgrade', 3, 16, '', '', '', 34, 1, 1, '/api/v1/agents/:hostId/upgrade', 'POST', NOW(), NOW()),
+  (451, 'Agent升级批次', 'hosts:agent-rollouts', 3, 16, '', '', '', 35, 1, 1, '/api/v1/agents/upgrade/rollouts', 'GET', NOW(), NOW()),
+  (452, '管理Agent升级批次', 'hosts:agent-rollouts-manage', 3, 16, '', '', '', 36, 1, 1, '/api/v1/agents/upgrade/rollouts', 'POST', NOW(), NOW());
+
+INSERT INTO `sys_menu_api` (`menu_id`, `api_path`, `api_method`, `created_at`, `updated_at`)
+VALUES
+  (450, '/api/v1/agents/:hostId/upgrade', 'POST', NOW(), NOW()),
+  (450, '/api/v1/agents/upgrade/package', 'GET', NOW(), NOW()),
+  (451, '/api/v1/agents/upgrade/rollouts', 'GET', NOW(), NOW()),
+  (451, '/api/v1/agents/upgrade/rollouts/:id', 'GET', NOW(), NOW()),
+  (451, '/api/v1/agents/upgrade/package', 'GET', NOW(), NOW()),
+  (452, '/api/v1/agents/upgrade/rollouts', 'POST', NOW(), NOW()),
+  (452, '/api/v1/agents/upgrade/rollouts/:id/resume', 'POST', NOW(), NOW()),
+  (452, '/api/v1/agents/upgrade/rollouts/:id/cancel', 'POST', NOW(), NOW());
+
+INSERT INTO `sys_role_menu` (`role_id`, `menu_id`)
+VALUES
+  (1, 450),  -- 远程升级Agent
+  (1, 451),  -- Agent升级批次
+  (1, 452);  -- 管理Agent升级批次
+
+-- 20.6 导入发现的服务会创建中间件，归入新增中间件按钮
+INSERT INTO `sys_menu_api` (`menu_id`, `api_path`, `api_method`, `created_at`, `updated_at`)
+VALUES
+  (335, '/api/v1/agents/:hostId/discovery/import', 'POST', NOW(), NOW());
+
+-- 20.7 Agent端口转发记录 (parent_id=16 主机管理)，非管理员只返回本人创建的会话
+INSERT INTO `sys_menu` (`id`, `name`, `code`, `type`, `parent_id`, `path`, `component`, `icon`, `sort`, `visible`, `status`, `api_path`, `api_method`, `created_at`, `updated_at`)
+VALUES
+  (453, '端口转发记录', 'hosts:agent-tunnels', 3, 16, '', '', '', 37, 1, 1, '/api/v1/agents/tunnels', 'GET', NOW(), NOW());
+
+INSERT INTO `sys_menu_api` (`menu_id`, `api_path`, `api_method`, `created_at`, `updated_at`)
+VALUES
+  (453, '/api/v1/agents/tunnels', 'GET', NOW(), NOW());
+
+INSERT INTO `sys_role_menu` (`role_id`, `menu_id`)
+VALUES
+  (1, 453);  -- 端口转发记录
+
+-- ============================================================
+-- 21. 告警扩展功能按钮权限
+-- ============================================================
+
+-- 21.1 外部告警接收器 (parent_id=409 告警通道)
+INSERT INTO `sys_menu` (`id`, `name`, `code`, `type`, `parent_id`, `path`, `component`, `icon`, `sort`, `visible`, `status`, `api_path`, `api_method`, `created_at`, `updated_at`)
+VALUES
+  (440, '新增接收器', 'alert:receivers:create', 3, 409, '', '', '', 4, 1, 1, '/api/v1/alert/receivers', 'POST', NOW(), NOW()),
+  (441, '编辑接收器', 'alert:receivers:edit', 3, 409, '', '', '', 5, 1, 1, '/api/v1/alert/receivers/:id', 'PUT', NOW(), NOW()),
+  (442, '删除接收器', 'alert:receivers:delete', 3, 409, '', '', '', 6, 1, 1, '/api/v1/alert/receivers/:id', 'DELETE', NOW(), NOW());
+
+INSERT INTO `sys_menu_api` (`menu_id`, `api_path`, `api_method`, `created_at`, `updated_at`)
+VALUES
+  (440, '/api/v1/alert/receivers', 'POST', NOW(), NOW()),
+  (441, '/api/v1/alert/receivers/:id', 'PUT', NOW(), NOW()),
+  (441, '/api/v1/alert/receivers/:id/token', 'PUT', NOW(), NOW()),
+  (442, '/api/v1/alert/receivers/:id', 'DELETE', NOW(), NOW());
+
+INSERT INTO `sys_role_menu` (`role_id`, `menu_id`)
+VALUES
+  (1, 440),  -- 新增接收器
+  (1, 441),  -- 编辑接收器
+  (1, 442);  -- 删除接收器
+
+-- 21.2 通知死信重发 (parent_id=409 告警通道)
+INSERT INTO `sys_menu` (`id`, `name`, `code`, `type`, `parent_id`, `path`, `component`, `icon`, `sort`, `visible`, `status`, `api_path`, `api_method`, `created_at`, `updated_at`)
+VALUES
+  (443, '重发死信', 'alert:dead-letters:resend', 3, 409, '', '', '', 7, 1, 1, '/api/v1/alert/dead-letters/:id/resend', 'POST', NOW(), NOW());
+
+INSERT INTO `sys_menu_api` (`menu_id`, `api_path`, `api_method`, `created_at`, `updated_at`)
+VALUES
+  (443, '/api/v1/alert/dead-letters/:id/resend', 'POST', NOW(), NOW());
+
+INSERT INTO `sys_role_menu` (`role_id`, `menu_id`)
+VALUES
+  (1, 443);  -- 重发死信
+
+-- 21.3 值班与升级菜单 (parent_id=404 告警管理)
+INSERT INTO `sys_menu` (`id`, `name`, `code`, `type`, `parent_id`, `path`, `component`, `icon`, `sort`, `visible`, `status`, `api_path`, `api_method`, `created_at`, `updated_at`)
+VALUES
+  (454, '值班与升级', 'alert-oncall', 2, 404, '/alert/oncall', 'alert/OnCall', 'Schedule', 7, 1, 1, '', '', NOW(), NOW());
+
+-- 值班表与升级策略按钮 (parent_id=454 值班与升级)
+INSERT INTO `sys_menu` (`id`, `name`, `code`, `type`, `parent_id`, `path`, `component`, `icon`, `sort`, `visible`, `status`, `api_path`, `api_method`, `created_at`, `updated_at`)
+VALUES
+  (444, '新增值班表', 'alert:oncall:create', 3, 454, '', '', '', 1, 1, 1, '/api/v1/alert/oncall-schedules', 'POST', NOW(), NOW()),
+  (445, '编辑值班表', 'alert:oncall:edit', 3, 454, '', '', '', 2, 1, 1, '/api/v1/alert/oncall-schedules/:id', 'PUT', NOW(), NOW()),
+  (446, '删除值班表', 'alert:oncall:delete', 3, 454, '', '', '', 3, 1, 1, '/api/v1/alert/oncall-schedules/:id', 'DELETE', NOW(), NOW()),
+  (447, '新增升级策略', 'alert:escalations:create', 3, 454, '', '', '', 4, 1, 1, '/api/v1/alert/escalation-policies', 'POST', NOW(), NOW()),
+  (448, '编辑升级策略', 'alert:escalations:edit', 3, 454, '', '', '', 5, 1, 1, '/api/v1/alert/escalation-policies/:id', 'PUT', NOW(), NOW()),
+  (449, '删除升级策略', 'alert:escalations:delete', 3, 454, '', '', '', 6, 1, 1, '/api/v1/alert/escalation-policies/:id', 'DELETE', NOW(), NOW());
+
+INSERT INTO `sys_menu_api` (`menu_id`, `api_path`, `api_method`, `created_at`, `updated_at`)
+VALUES
+  (444, '/api/v1/alert/oncall-schedules', 'POST', NOW(), NOW()),
+  (445, '/api/v1/alert/oncall-schedules/:id', 'PUT', NOW(), NOW()),
+  -- 临时换班属于编辑值班表
+  (445, '/api/v1/alert/oncall-schedules/:id/overrides', 'POST', NOW(), NOW()),
+  (445, '/api/v1/alert/oncall-schedules/overrides/:id', 'DELETE', NOW(), NOW()),
+  (446, '/api/v1/alert/oncall-schedules/:id', 'DELETE', NOW(), NOW()),
+  (447, '/api/v1/alert/escalation-policies', 'POST', NOW(), NOW()),
+  (448, '/api/v1/alert/escalation-policies/:id', 'PUT', NOW(), NOW()),
+  (449, '/api/v1/alert/escalation-policies/:id', 'DELETE', NOW(), NOW());
+
+INSERT INTO `sys_role_menu` (`role_id`, `menu_id`)
+VALUES
+  (1, 454),  -- 值班与升级
+  (1, 444),  -- 新增值班表
+  (1, 445),  -- 编辑值班表
+  (1, 446),  -- 删除值班表
+  (1, 447),  -- 新增升级策略
+  (1, 448),  -- 编辑升级策略
+  (1, 449);  -- 删除升级策略
+
+-- 21.4 告警规则单元测试 (parent_id=405 告警规则)
+INSERT INTO `sys_menu` (`id`, `name`, `code`, `type`, `parent_id`, `path`, `component`, `icon`, `sort`, `visible`, `status`, `api_path`, `api_method`, `created_at`, `updated_at`)
+VALUES
+  (455, '单元测试', 'alert:rules:unit-test', 3, 405, '', '', '', 8, 1, 1, '/api/v1/alert/rules/unit-test', 'POST', NOW(), NOW());
+
+INSERT INTO `sys_menu_api` (`menu_id`, `api_path`, `api_method`, `created_at`, `updated_at`)
+VALUES
+  (455, '/api/v1/alert/rules/unit-test', 'POST', NOW(), NOW());
+
+INSERT INTO `sys_role_menu` (`role_id`, `menu_id`)
+VALUES
+  (1, 455);  -- 单元测试
+
 SET FOREIGN_KEY_CHECKS = 1;
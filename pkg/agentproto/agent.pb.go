@@ -36,6 +36,15 @@ type AgentMessage struct {
 	//	*AgentMessage_HttpProxyResponse
 	//	*AgentMessage_WsSessionResult
 	//	*AgentMessage_StreamProxyChunk
+	//	*AgentMessage_CmdOutput
+	//	*AgentMessage_UpgradeResult
+	//	*AgentMessage_PolicyViolation
+	//	*AgentMessage_ServiceDiscovery
+	//	*AgentMessage_TunnelOpenResult
+	//	*AgentMessage_TunnelData
+	//	*AgentMessage_TunnelAck
+	//	*AgentMessage_TunnelClose
+	//	*AgentMessage_CertCsr
 	Payload       isAgentMessage_Payload `protobuf_oneof:"payload"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
@@ -168,6 +177,87 @@ func (x *AgentMessage) GetStreamProxyChunk() *StreamProxyChunk {
 	return nil
 }
 
+func (x *AgentMessage) GetCmdOutput() *CommandOutputChunk {
+	if x != nil {
+		if x, ok := x.Payload.(*AgentMessage_CmdOutput); ok {
+			return x.CmdOutput
+		}
+	}
+	return nil
+}
+
+func (x *AgentMessage) GetUpgradeResult() *AgentUpgradeResult {
+	if x != nil {
+		if x, ok := x.Payload.(*AgentMessage_UpgradeResult); ok {
+			return x.UpgradeResult
+		}
+	}
+	return nil
+}
+
+func (x *AgentMessage) GetPolicyViolation() *PolicyViolation {
+	if x != nil {
+		if x, ok := x.Payload.(*AgentMessage_PolicyViolation); ok {
+			return x.PolicyViolation
+		}
+	}
+	return nil
+}
+
+func (x *AgentMessage) GetServiceDiscovery() *ServiceDiscovery {
+	if x != nil {
+		if x, ok := x.Payload.(*AgentMessage_ServiceDiscovery); ok {
+			return x.ServiceDiscovery
+		}
+	}
+	return nil
+}
+
+func (x *AgentMessage) GetTunnelOpenResult() *TunnelOpenResult {
+	if x != nil {
+		if x, ok := x.Payload.(*AgentMessage_TunnelOpenResult); ok {
+			return x.TunnelOpenResult
+		}
+	}
+	return nil
+}
+
+func (x *AgentMessage) GetTunnelData() *TunnelData {
+	if x != nil {
+		if x, ok := x.Payload.(*AgentMessage_TunnelData); ok {
+			return x.TunnelData
+		}
+	}
+	return nil
+}
+
+func (x *AgentMessage) GetTunnelAck() *TunnelAck {
+	if x != nil {
+		if x, ok := x.Payload.(*AgentMessage_TunnelAck); ok {
+			return x.TunnelAck
+		}
+	}
+	return nil
+}
+
+func (x *AgentMessage) GetTunnelClose() *TunnelClose {
+	if x != nil {
+		if x, ok := x.Payload.(*AgentMessage_TunnelClose); ok {
+			return x.TunnelClose
+		}
+	}
+	return nil
+}
+
+func (x *AgentMessage) GetCertCsr() *CertSigningRequest {
+	if x != nil {
+		if x, ok := x.Payload.(*AgentMessage_CertCsr); ok {
+			return x.CertCsr
+		}
+	}
+	return nil
+}
+
 type isAgentMessage_Payload interface {
 	isAgentMessage_Payload()
 }
@@ -212,6 +302,42 @@ type AgentMessage_StreamProxyChunk struct {
 	StreamProxyChunk *StreamProxyChunk `protobuf:"bytes,10,opt,name=stream_proxy_chunk,json=streamProxyChunk,proto3,oneof"`
 }
 
+type AgentMessage_CmdOutput struct {
+	CmdOutput *CommandOutputChunk `protobuf:"bytes,11,opt,name=cmd_output,json=cmdOutput,proto3,oneof"`
+}
+
+type AgentMessage_UpgradeResult struct {
+	UpgradeResult *AgentUpgradeResult `protobuf:"bytes,12,opt,name=upgrade_result,json=upgradeResult,proto3,oneof"`
+}
+
+type AgentMessage_PolicyViolation struct {
+	PolicyViolation *PolicyViolation `protobuf:"bytes,13,opt,name=policy_violation,json=policyViolation,proto3,oneof"`
+}
+
+type AgentMessage_ServiceDiscovery struct {
+	ServiceDiscovery *ServiceDiscovery `protobuf:"bytes,14,opt,name=service_discovery,json=serviceDiscovery,proto3,oneof"`
+}
+
+type AgentMessage_TunnelOpenResult struct {
+	TunnelOpenResult *TunnelOpenResult `protobuf:"bytes,15,opt,name=tunnel_open_result,json=tunnelOpenResult,proto3,oneof"`
+}
+
+type AgentMessage_TunnelData struct {
+	TunnelData *TunnelData `protobuf:"bytes,16,opt,name=tunnel_data,json=tunnelData,proto3,oneof"`
+}
+
+type AgentMessage_TunnelAck struct {
+	TunnelAck *TunnelAck `protobuf:"bytes,17,opt,name=tunnel_ack,json=tunnelAck,proto3,oneof"`
+}
+
+type AgentMessage_TunnelClose struct {
+	TunnelClose *TunnelClose `protobuf:"bytes,18,opt,name=tunnel_close,json=tunnelClose,proto3,oneof"`
+}
+
+type AgentMessage_CertCsr struct {
+	CertCsr *CertSigningRequest `protobuf:"bytes,19,opt,name=cert_csr,json=certCsr,proto3,oneof"`
+}
+
 func (*AgentMessage_Register) isAgentMessage_Payload() {}
 
 func (*AgentMessage_Heartbeat) isAgentMessage_Payload() {}
@@ -232,6 +358,24 @@ func (*AgentMessage_WsSessionResult) isAgentMessage_Payload() {}
 
 func (*AgentMessage_StreamProxyChunk) isAgentMessage_Payload() {}
 
+func (*AgentMessage_CmdOutput) isAgentMessage_Payload() {}
+
+func (*AgentMessage_UpgradeResult) isAgentMessage_Payload() {}
+
+func (*AgentMessage_PolicyViolation) isAgentMessage_Payload() {}
+
+func (*AgentMessage_ServiceDiscovery) isAgentMessage_Payload() {}
+
+func (*AgentMessage_TunnelOpenResult) isAgentMessage_Payload() {}
+
+func (*AgentMessage_TunnelData) isAgentMessage_Payload() {}
+
+func (*AgentMessage_TunnelAck) isAgentMessage_Payload() {}
+
+func (*AgentMessage_TunnelClose) isAgentMessage_Payload() {}
+
+func (*AgentMessage_CertCsr) isAgentMessage_Payload() {}
+
 // Server → Agent
 type ServerMessage struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
@@ -251,6 +395,15 @@ type ServerMessage struct {
 	//	*ServerMessage_WsSessionAction
 	//	*ServerMessage_WsSessionClose
 	//	*ServerMessage_StreamProxyRequest
+	//	*ServerMessage_CmdCancel
+	//	*ServerMessage_AgentUpgrade
+	//	*ServerMessage_DiscoveryRequest
+	//	*ServerMessage_TunnelOpen
+	//	*ServerMessage_TunnelData
+	//	*ServerMessage_TunnelAck
+	//	*ServerMessage_TunnelClose
+	//	*ServerMessage_CertRenewRequest
+	//	*ServerMessage_CertRenewResponse
 	Payload       isServerMessage_Payload `protobuf_oneof:"payload"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
@@ -419,6 +572,87 @@ func (x *ServerMessage) GetStreamProxyRequest() *StreamProxyRequest {
 	return nil
 }
 
+func (x *ServerMessage) GetCmdCancel() *CommandCancel {
+	if x != nil {
+		if x, ok := x.Payload.(*ServerMessage_CmdCancel); ok {
+			return x.CmdCancel
+		}
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetAgentUpgrade() *AgentUpgrade {
+	if x != nil {
+		if x, ok := x.Payload.(*ServerMessage_AgentUpgrade); ok {
+			return x.AgentUpgrade
+		}
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetDiscoveryRequest() *ServiceDiscoveryRequest {
+	if x != nil {
+		if x, ok := x.Payload.(*ServerMessage_DiscoveryRequest); ok {
+			return x.DiscoveryRequest
+		}
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetTunnelOpen() *TunnelOpen {
+	if x != nil {
+		if x, ok := x.Payload.(*ServerMessage_TunnelOpen); ok {
+			return x.TunnelOpen
+		}
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetTunnelData() *TunnelData {
+	if x != nil {
+		if x, ok := x.Payload.(*ServerMessage_TunnelData); ok {
+			return x.TunnelData
+		}
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetTunnelAck() *TunnelAck {
+	if x != nil {
+		if x, ok := x.Payload.(*ServerMessage_TunnelAck); ok {
+			return x.TunnelAck
+		}
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetTunnelClose() *TunnelClose {
+	if x != nil {
+		if x, ok := x.Payload.(*ServerMessage_TunnelClose); ok {
+			return x.TunnelClose
+		}
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetCertRenewRequest() *CertRenewRequest {
+	if x != nil {
+		if x, ok := x.Payload.(*ServerMessage_CertRenewRequest); ok {
+			return x.CertRenewRequest
+		}
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetCertRenewResponse() *CertRenewResponse {
+	if x != nil {
+		if x, ok := x.Payload.(*ServerMessage_CertRenewResponse); ok {
+			return x.CertRenewResponse
+		}
+	}
+	return nil
+}
+
 type isServerMessage_Payload interface {
 	isServerMessage_Payload()
 }
@@ -479,6 +713,42 @@ type ServerMessage_StreamProxyRequest struct {
 	StreamProxyRequest *StreamProxyRequest `protobuf:"bytes,14,opt,name=stream_proxy_request,json=streamProxyRequest,proto3,oneof"`
 }
 
+type ServerMessage_CmdCancel struct {
+	CmdCancel *CommandCancel `protobuf:"bytes,15,opt,name=cmd_cancel,json=cmdCancel,proto3,oneof"`
+}
+
+type ServerMessage_AgentUpgrade struct {
+	AgentUpgrade *AgentUpgrade `protobuf:"bytes,16,opt,name=agent_upgrade,json=agentUpgrade,proto3,oneof"`
+}
+
+type ServerMessage_DiscoveryRequest struct {
+	DiscoveryRequest *ServiceDiscoveryRequest `protobuf:"bytes,17,opt,name=discovery_request,json=discoveryRequest,proto3,oneof"`
+}
+
+type ServerMessage_TunnelOpen struct {
+	TunnelOpen *TunnelOpen `protobuf:"bytes,18,opt,name=tunnel_open,json=tunnelOpen,proto3,oneof"`
+}
+
+type ServerMessage_TunnelData struct {
+	TunnelData *TunnelData `protobuf:"bytes,19,opt,name=tunnel_data,json=tunnelData,proto3,oneof"`
+}
+
+type ServerMessage_TunnelAck struct {
+	TunnelAck *TunnelAck `protobuf:"bytes,20,opt,name=tunnel_ack,json=tunnelAck,proto3,oneof"`
+}
+
+type ServerMessage_TunnelClose struct {
+	TunnelClose *TunnelClose `protobuf:"bytes,21,opt,name=tunnel_close,json=tunnelClose,proto3,oneof"`
+}
+
+type ServerMessage_CertRenewRequest struct {
+	CertRenewRequest *CertRenewRequest `protobuf:"bytes,22,opt,name=cert_renew_request,json=certRenewRequest,proto3,oneof"`
+}
+
+type ServerMessage_CertRenewResponse struct {
+	CertRenewResponse *CertRenewResponse `protobuf:"bytes,23,opt,name=cert_renew_response,json=certRenewResponse,proto3,oneof"`
+}
+
 func (*ServerMessage_RegisterAck) isServerMessage_Payload() {}
 
 func (*ServerMessage_HeartbeatAck) isServerMessage_Payload() {}
@@ -507,6 +777,24 @@ func (*ServerMessage_WsSessionClose) isServerMessage_Payload() {}
 
 func (*ServerMessage_StreamProxyRequest) isServerMessage_Payload() {}
 
+func (*ServerMessage_CmdCancel) isServerMessage_Payload() {}
+
+func (*ServerMessage_AgentUpgrade) isServerMessage_Payload() {}
+
+func (*ServerMessage_DiscoveryRequest) isServerMessage_Payload() {}
+
+func (*ServerMessage_TunnelOpen) isServerMessage_Payload() {}
+
+func (*ServerMessage_TunnelData) isServerMessage_Payload() {}
+
+func (*ServerMessage_TunnelAck) isServerMessage_Payload() {}
+
+func (*ServerMessage_TunnelClose) isServerMessage_Payload() {}
+
+func (*ServerMessage_CertRenewRequest) isServerMessage_Payload() {}
+
+func (*ServerMessage_CertRenewResponse) isServerMessage_Payload() {}
+
 // ========== 注册 ==========
 type RegisterRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -516,6 +804,9 @@ type RegisterRequest struct {
 	Arch          string                 `protobuf:"bytes,4,opt,name=arch,proto3" json:"arch,omitempty"`
 	Version       string                 `protobuf:"bytes,5,opt,name=version,proto3" json:"version,omitempty"`
 	Ips           []string               `protobuf:"bytes,6,rep,name=ips,proto3" json:"ips,omitempty"`
+	UpgradeId     string                 `protobuf:"bytes,7,opt,name=upgrade_id,json=upgradeId,proto3" json:"upgrade_id,omitempty"`          // 升级后首次注册时携带，用于确认升级结果
+	RolledBack    bool                   `protobuf:"varint,8,opt,name=rolled_back,json=rolledBack,proto3" json:"rolled_back,omitempty"`      // 升级失败已回滚到旧版本
+	UpgradeError  string                 `protobuf:"bytes,9,opt,name=upgrade_error,json=upgradeError,proto3" json:"upgrade_error,omitempty"` // 回滚原因
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -592,6 +883,27 @@ func (x *RegisterRequest) GetIps() []string {
 	return nil
 }
 
+func (x *RegisterRequest) GetUpgradeId() string {
+	if x != nil {
+		return x.UpgradeId
+	}
+	return ""
+}
+
+func (x *RegisterRequest) GetRolledBack() bool {
+	if x != nil {
+		return x.RolledBack
+	}
+	return false
+}
+
+func (x *RegisterRequest) GetUpgradeError() string {
+	if x != nil {
+		return x.UpgradeError
+	}
+	return ""
+}
+
 type RegisterResponse struct {
 	state             protoimpl.MessageState `protogen:"open.v1"`
 	Success           bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
@@ -660,6 +972,15 @@ type HeartbeatRequest struct {
 	MemoryUsage   float64                `protobuf:"fixed64,3,opt,name=memory_usage,json=memoryUsage,proto3" json:"memory_usage,omitempty"`
 	DiskUsage     float64                `protobuf:"fixed64,4,opt,name=disk_usage,json=diskUsage,proto3" json:"disk_usage,omitempty"`
 	UptimeSeconds int64                  `protobuf:"varint,5,opt,name=uptime_seconds,json=uptimeSeconds,proto3" json:"uptime_seconds,omitempty"`
+	Load1         float64                `protobuf:"fixed64,6,opt,name=load1,proto3" json:"load1,omitempty"`
+	Load5         float64                `protobuf:"fixed64,7,opt,name=load5,proto3" json:"load5,omitempty"`
+	Load15        float64                `protobuf:"fixed64,8,opt,name=load15,proto3" json:"load15,omitempty"`
+	MemoryTotal   uint64                 `protobuf:"varint,9,opt,name=memory_total,json=memoryTotal,proto3" json:"memory_total,omitempty"`
+	MemoryUsed    uint64                 `protobuf:"varint,10,opt,name=memory_used,json=memoryUsed,proto3" json:"memory_used,omitempty"`
+	Disks         []*DiskStats           `protobuf:"bytes,11,rep,name=disks,proto3" json:"disks,omitempty"`                                                                                                     // 按挂载点
+	Networks      []*NetworkStats        `protobuf:"bytes,12,rep,name=networks,proto3" json:"networks,omitempty"`                                                                                               // 按网卡（不含 lo）
+	TcpStates     map[string]int32       `protobuf:"bytes,13,rep,name=tcp_states,json=tcpStates,proto3" json:"tcp_states,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"` // TCP 连接状态 -> 数量，如 ESTABLISHED
+	TopProcesses  []*ProcessStats        `protobuf:"bytes,14,rep,name=top_processes,json=topProcesses,proto3" json:"top_processes,omitempty"`                                                                   // CPU 占用最高的 N 个进程
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -729,75 +1050,97 @@ func (x *HeartbeatRequest) GetUptimeSeconds() int64 {
 	return 0
 }
 
-type HeartbeatResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+func (x *HeartbeatRequest) GetLoad1() float64 {
+	if x != nil {
+		return x.Load1
+	}
+	return 0
 }
 
-func (x *HeartbeatResponse) Reset() {
-	*x = HeartbeatResponse{}
-	mi := &file_api_proto_agent_proto_msgTypes[5]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
+func (x *HeartbeatRequest) GetLoad5() float64 {
+	if x != nil {
+		return x.Load5
+	}
+	return 0
 }
 
-func (x *HeartbeatResponse) String() string {
-	return protoimpl.X.MessageStringOf(x)
+func (x *HeartbeatRequest) GetLoad15() float64 {
+	if x != nil {
+		return x.Load15
+	}
+	return 0
 }
 
-func (*HeartbeatResponse) ProtoMessage() {}
+func (x *HeartbeatRequest) GetMemoryTotal() uint64 {
+	if x != nil {
+		return x.MemoryTotal
+	}
+	return 0
+}
 
-func (x *HeartbeatResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_proto_msgTypes[5]
+func (x *HeartbeatRequest) GetMemoryUsed() uint64 {
 	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
+		return x.MemoryUsed
 	}
-	return mi.MessageOf(x)
+	return 0
 }
 
-// Deprecated: Use HeartbeatResponse.ProtoReflect.Descriptor instead.
-func (*HeartbeatResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_proto_rawDescGZIP(), []int{5}
+func (x *HeartbeatRequest) GetDisks() []*DiskStats {
+	if x != nil {
+		return x.Disks
+	}
+	return nil
 }
 
-func (x *HeartbeatResponse) GetSuccess() bool {
+func (x *HeartbeatRequest) GetNetworks() []*NetworkStats {
 	if x != nil {
-		return x.Success
+		return x.Networks
 	}
-	return false
+	return nil
 }
 
-// ========== 终端 ==========
-type TerminalOpen struct {
+func (x *HeartbeatRequest) GetTcpStates() map[string]int32 {
+	if x != nil {
+		return x.TcpStates
+	}
+	return nil
+}
+
+func (x *HeartbeatRequest) GetTopProcesses() []*ProcessStats {
+	if x != nil {
+		return x.TopProcesses
+	}
+	return nil
+}
+
+type DiskStats struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
-	Cols          uint32                 `protobuf:"varint,2,opt,name=cols,proto3" json:"cols,omitempty"`
-	Rows          uint32                 `protobuf:"varint,3,opt,name=rows,proto3" json:"rows,omitempty"`
+	Mountpoint    string                 `protobuf:"bytes,1,opt,name=mountpoint,proto3" json:"mountpoint,omitempty"`
+	Device        string                 `protobuf:"bytes,2,opt,name=device,proto3" json:"device,omitempty"`
+	Fstype        string                 `protobuf:"bytes,3,opt,name=fstype,proto3" json:"fstype,omitempty"`
+	TotalBytes    uint64                 `protobuf:"varint,4,opt,name=total_bytes,json=totalBytes,proto3" json:"total_bytes,omitempty"`
+	UsedBytes     uint64                 `protobuf:"varint,5,opt,name=used_bytes,json=usedBytes,proto3" json:"used_bytes,omitempty"`
+	Usage         float64                `protobuf:"fixed64,6,opt,name=usage,proto3" json:"usage,omitempty"`                                // 百分比
+	InodesUsage   float64                `protobuf:"fixed64,7,opt,name=inodes_usage,json=inodesUsage,proto3" json:"inodes_usage,omitempty"` // 百分比
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *TerminalOpen) Reset() {
-	*x = TerminalOpen{}
-	mi := &file_api_proto_agent_proto_msgTypes[6]
+func (x *DiskStats) Reset() {
+	*x = DiskStats{}
+	mi := &file_api_proto_agent_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *TerminalOpen) String() string {
+func (x *DiskStats) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*TerminalOpen) ProtoMessage() {}
+func (*DiskStats) ProtoMessage() {}
 
-func (x *TerminalOpen) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_proto_msgTypes[6]
+func (x *DiskStats) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -808,55 +1151,88 @@ func (x *TerminalOpen) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use TerminalOpen.ProtoReflect.Descriptor instead.
-func (*TerminalOpen) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_proto_rawDescGZIP(), []int{6}
+// Deprecated: Use DiskStats.ProtoReflect.Descriptor instead.
+func (*DiskStats) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{5}
 }
 
-func (x *TerminalOpen) GetSessionId() string {
+func (x *DiskStats) GetMountpoint() string {
 	if x != nil {
-		return x.SessionId
+		return x.Mountpoint
 	}
 	return ""
 }
 
-func (x *TerminalOpen) GetCols() uint32 {
+func (x *DiskStats) GetDevice() string {
 	if x != nil {
-		return x.Cols
+		return x.Device
 	}
-	return 0
+	return ""
 }
 
-func (x *TerminalOpen) GetRows() uint32 {
+func (x *DiskStats) GetFstype() string {
 	if x != nil {
-		return x.Rows
+		return x.Fstype
+	}
+	return ""
+}
+
+func (x *DiskStats) GetTotalBytes() uint64 {
+	if x != nil {
+		return x.TotalBytes
 	}
 	return 0
 }
 
-type TerminalInput struct {
+func (x *DiskStats) GetUsedBytes() uint64 {
+	if x != nil {
+		return x.UsedBytes
+	}
+	return 0
+}
+
+func (x *DiskStats) GetUsage() float64 {
+	if x != nil {
+		return x.Usage
+	}
+	return 0
+}
+
+func (x *DiskStats) GetInodesUsage() float64 {
+	if x != nil {
+		return x.InodesUsage
+	}
+	return 0
+}
+
+type NetworkStats struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
-	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	RxBytes       uint64                 `protobuf:"varint,2,opt,name=rx_bytes,json=rxBytes,proto3" json:"rx_bytes,omitempty"` // 累计值
+	TxBytes       uint64                 `protobuf:"varint,3,opt,name=tx_bytes,json=txBytes,proto3" json:"tx_bytes,omitempty"`
+	RxBytesPerSec float64                `protobuf:"fixed64,4,opt,name=rx_bytes_per_sec,json=rxBytesPerSec,proto3" json:"rx_bytes_per_sec,omitempty"`
+	TxBytesPerSec float64                `protobuf:"fixed64,5,opt,name=tx_bytes_per_sec,json=txBytesPerSec,proto3" json:"tx_bytes_per_sec,omitempty"`
+	RxErrors      uint64                 `protobuf:"varint,6,opt,name=rx_errors,json=rxErrors,proto3" json:"rx_errors,omitempty"`
+	TxErrors      uint64                 `protobuf:"varint,7,opt,name=tx_errors,json=txErrors,proto3" json:"tx_errors,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *TerminalInput) Reset() {
-	*x = TerminalInput{}
-	mi := &file_api_proto_agent_proto_msgTypes[7]
+func (x *NetworkStats) Reset() {
+	*x = NetworkStats{}
+	mi := &file_api_proto_agent_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *TerminalInput) String() string {
+func (x *NetworkStats) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*TerminalInput) ProtoMessage() {}
+func (*NetworkStats) ProtoMessage() {}
 
-func (x *TerminalInput) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_proto_msgTypes[7]
+func (x *NetworkStats) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -867,101 +1243,86 @@ func (x *TerminalInput) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use TerminalInput.ProtoReflect.Descriptor instead.
-func (*TerminalInput) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_proto_rawDescGZIP(), []int{7}
+// Deprecated: Use NetworkStats.ProtoReflect.Descriptor instead.
+func (*NetworkStats) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{6}
 }
 
-func (x *TerminalInput) GetSessionId() string {
+func (x *NetworkStats) GetName() string {
 	if x != nil {
-		return x.SessionId
+		return x.Name
 	}
 	return ""
 }
 
-func (x *TerminalInput) GetData() []byte {
+func (x *NetworkStats) GetRxBytes() uint64 {
 	if x != nil {
-		return x.Data
+		return x.RxBytes
 	}
-	return nil
-}
-
-type TerminalOutput struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
-	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
-}
-
-func (x *TerminalOutput) Reset() {
-	*x = TerminalOutput{}
-	mi := &file_api_proto_agent_proto_msgTypes[8]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
+	return 0
 }
 
-func (x *TerminalOutput) String() string {
-	return protoimpl.X.MessageStringOf(x)
+func (x *NetworkStats) GetTxBytes() uint64 {
+	if x != nil {
+		return x.TxBytes
+	}
+	return 0
 }
 
-func (*TerminalOutput) ProtoMessage() {}
-
-func (x *TerminalOutput) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_proto_msgTypes[8]
+func (x *NetworkStats) GetRxBytesPerSec() float64 {
 	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
+		return x.RxBytesPerSec
 	}
-	return mi.MessageOf(x)
+	return 0
 }
 
-// Deprecated: Use TerminalOutput.ProtoReflect.Descriptor instead.
-func (*TerminalOutput) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_proto_rawDescGZIP(), []int{8}
+func (x *NetworkStats) GetTxBytesPerSec() float64 {
+	if x != nil {
+		return x.TxBytesPerSec
+	}
+	return 0
 }
 
-func (x *TerminalOutput) GetSessionId() string {
+func (x *NetworkStats) GetRxErrors() uint64 {
 	if x != nil {
-		return x.SessionId
+		return x.RxErrors
 	}
-	return ""
+	return 0
 }
 
-func (x *TerminalOutput) GetData() []byte {
+func (x *NetworkStats) GetTxErrors() uint64 {
 	if x != nil {
-		return x.Data
+		return x.TxErrors
 	}
-	return nil
+	return 0
 }
 
-type TerminalResize struct {
+type ProcessStats struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
-	Cols          uint32                 `protobuf:"varint,2,opt,name=cols,proto3" json:"cols,omitempty"`
-	Rows          uint32                 `protobuf:"varint,3,opt,name=rows,proto3" json:"rows,omitempty"`
+	Pid           int32                  `protobuf:"varint,1,opt,name=pid,proto3" json:"pid,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	CpuUsage      float64                `protobuf:"fixed64,3,opt,name=cpu_usage,json=cpuUsage,proto3" json:"cpu_usage,omitempty"` // 百分比，相对单核
+	MemoryRss     uint64                 `protobuf:"varint,4,opt,name=memory_rss,json=memoryRss,proto3" json:"memory_rss,omitempty"`
+	MemoryUsage   float64                `protobuf:"fixed64,5,opt,name=memory_usage,json=memoryUsage,proto3" json:"memory_usage,omitempty"` // 百分比
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *TerminalResize) Reset() {
-	*x = TerminalResize{}
-	mi := &file_api_proto_agent_proto_msgTypes[9]
+func (x *ProcessStats) Reset() {
+	*x = ProcessStats{}
+	mi := &file_api_proto_agent_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *TerminalResize) String() string {
+func (x *ProcessStats) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*TerminalResize) ProtoMessage() {}
+func (*ProcessStats) ProtoMessage() {}
 
-func (x *TerminalResize) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_proto_msgTypes[9]
+func (x *ProcessStats) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -972,54 +1333,68 @@ func (x *TerminalResize) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use TerminalResize.ProtoReflect.Descriptor instead.
-func (*TerminalResize) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_proto_rawDescGZIP(), []int{9}
+// Deprecated: Use ProcessStats.ProtoReflect.Descriptor instead.
+func (*ProcessStats) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{7}
 }
 
-func (x *TerminalResize) GetSessionId() string {
+func (x *ProcessStats) GetPid() int32 {
 	if x != nil {
-		return x.SessionId
+		return x.Pid
+	}
+	return 0
+}
+
+func (x *ProcessStats) GetName() string {
+	if x != nil {
+		return x.Name
 	}
 	return ""
 }
 
-func (x *TerminalResize) GetCols() uint32 {
+func (x *ProcessStats) GetCpuUsage() float64 {
 	if x != nil {
-		return x.Cols
+		return x.CpuUsage
 	}
 	return 0
 }
 
-func (x *TerminalResize) GetRows() uint32 {
+func (x *ProcessStats) GetMemoryRss() uint64 {
 	if x != nil {
-		return x.Rows
+		return x.MemoryRss
 	}
 	return 0
 }
 
-type TerminalClose struct {
+func (x *ProcessStats) GetMemoryUsage() float64 {
+	if x != nil {
+		return x.MemoryUsage
+	}
+	return 0
+}
+
+type HeartbeatResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *TerminalClose) Reset() {
-	*x = TerminalClose{}
-	mi := &file_api_proto_agent_proto_msgTypes[10]
+func (x *HeartbeatResponse) Reset() {
+	*x = HeartbeatResponse{}
+	mi := &file_api_proto_agent_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *TerminalClose) String() string {
+func (x *HeartbeatResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*TerminalClose) ProtoMessage() {}
+func (*HeartbeatResponse) ProtoMessage() {}
 
-func (x *TerminalClose) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_proto_msgTypes[10]
+func (x *HeartbeatResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1030,45 +1405,43 @@ func (x *TerminalClose) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use TerminalClose.ProtoReflect.Descriptor instead.
-func (*TerminalClose) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_proto_rawDescGZIP(), []int{10}
+// Deprecated: Use HeartbeatResponse.ProtoReflect.Descriptor instead.
+func (*HeartbeatResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{8}
 }
 
-func (x *TerminalClose) GetSessionId() string {
+func (x *HeartbeatResponse) GetSuccess() bool {
 	if x != nil {
-		return x.SessionId
+		return x.Success
 	}
-	return ""
+	return false
 }
 
-// ========== 文件操作 ==========
-type FileRequest struct {
+// ========== 终端 ==========
+type TerminalOpen struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
-	Action        string                 `protobuf:"bytes,2,opt,name=action,proto3" json:"action,omitempty"` // list, upload, download, delete
-	Path          string                 `protobuf:"bytes,3,opt,name=path,proto3" json:"path,omitempty"`
-	Data          []byte                 `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
-	Filename      string                 `protobuf:"bytes,5,opt,name=filename,proto3" json:"filename,omitempty"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Cols          uint32                 `protobuf:"varint,2,opt,name=cols,proto3" json:"cols,omitempty"`
+	Rows          uint32                 `protobuf:"varint,3,opt,name=rows,proto3" json:"rows,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *FileRequest) Reset() {
-	*x = FileRequest{}
-	mi := &file_api_proto_agent_proto_msgTypes[11]
+func (x *TerminalOpen) Reset() {
+	*x = TerminalOpen{}
+	mi := &file_api_proto_agent_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *FileRequest) String() string {
+func (x *TerminalOpen) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*FileRequest) ProtoMessage() {}
+func (*TerminalOpen) ProtoMessage() {}
 
-func (x *FileRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_proto_msgTypes[11]
+func (x *TerminalOpen) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1079,71 +1452,55 @@ func (x *FileRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use FileRequest.ProtoReflect.Descriptor instead.
-func (*FileRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_proto_rawDescGZIP(), []int{11}
-}
-
-func (x *FileRequest) GetRequestId() string {
-	if x != nil {
-		return x.RequestId
-	}
-	return ""
-}
-
-func (x *FileRequest) GetAction() string {
-	if x != nil {
-		return x.Action
-	}
-	return ""
+// Deprecated: Use TerminalOpen.ProtoReflect.Descriptor instead.
+func (*TerminalOpen) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{9}
 }
 
-func (x *FileRequest) GetPath() string {
+func (x *TerminalOpen) GetSessionId() string {
 	if x != nil {
-		return x.Path
+		return x.SessionId
 	}
 	return ""
 }
 
-func (x *FileRequest) GetData() []byte {
+func (x *TerminalOpen) GetCols() uint32 {
 	if x != nil {
-		return x.Data
+		return x.Cols
 	}
-	return nil
+	return 0
 }
 
-func (x *FileRequest) GetFilename() string {
+func (x *TerminalOpen) GetRows() uint32 {
 	if x != nil {
-		return x.Filename
+		return x.Rows
 	}
-	return ""
+	return 0
 }
 
-type FileChunk struct {
+type TerminalInput struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
 	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
-	Eof           bool                   `protobuf:"varint,3,opt,name=eof,proto3" json:"eof,omitempty"`
-	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *FileChunk) Reset() {
-	*x = FileChunk{}
-	mi := &file_api_proto_agent_proto_msgTypes[12]
+func (x *TerminalInput) Reset() {
+	*x = TerminalInput{}
+	mi := &file_api_proto_agent_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *FileChunk) String() string {
+func (x *TerminalInput) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*FileChunk) ProtoMessage() {}
+func (*TerminalInput) ProtoMessage() {}
 
-func (x *FileChunk) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_proto_msgTypes[12]
+func (x *TerminalInput) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1154,63 +1511,48 @@ func (x *FileChunk) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use FileChunk.ProtoReflect.Descriptor instead.
-func (*FileChunk) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_proto_rawDescGZIP(), []int{12}
+// Deprecated: Use TerminalInput.ProtoReflect.Descriptor instead.
+func (*TerminalInput) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{10}
 }
 
-func (x *FileChunk) GetRequestId() string {
+func (x *TerminalInput) GetSessionId() string {
 	if x != nil {
-		return x.RequestId
+		return x.SessionId
 	}
 	return ""
 }
 
-func (x *FileChunk) GetData() []byte {
+func (x *TerminalInput) GetData() []byte {
 	if x != nil {
 		return x.Data
 	}
 	return nil
 }
 
-func (x *FileChunk) GetEof() bool {
-	if x != nil {
-		return x.Eof
-	}
-	return false
-}
-
-func (x *FileChunk) GetError() string {
-	if x != nil {
-		return x.Error
-	}
-	return ""
-}
-
-type FileListResult struct {
+type TerminalOutput struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
-	Files         []*FileInfo            `protobuf:"bytes,2,rep,name=files,proto3" json:"files,omitempty"`
-	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *FileListResult) Reset() {
-	*x = FileListResult{}
-	mi := &file_api_proto_agent_proto_msgTypes[13]
+func (x *TerminalOutput) Reset() {
+	*x = TerminalOutput{}
+	mi := &file_api_proto_agent_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *FileListResult) String() string {
+func (x *TerminalOutput) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*FileListResult) ProtoMessage() {}
+func (*TerminalOutput) ProtoMessage() {}
 
-func (x *FileListResult) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_proto_msgTypes[13]
+func (x *TerminalOutput) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1221,58 +1563,49 @@ func (x *FileListResult) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use FileListResult.ProtoReflect.Descriptor instead.
-func (*FileListResult) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_proto_rawDescGZIP(), []int{13}
+// Deprecated: Use TerminalOutput.ProtoReflect.Descriptor instead.
+func (*TerminalOutput) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{11}
 }
 
-func (x *FileListResult) GetRequestId() string {
+func (x *TerminalOutput) GetSessionId() string {
 	if x != nil {
-		return x.RequestId
+		return x.SessionId
 	}
 	return ""
 }
 
-func (x *FileListResult) GetFiles() []*FileInfo {
+func (x *TerminalOutput) GetData() []byte {
 	if x != nil {
-		return x.Files
+		return x.Data
 	}
 	return nil
 }
 
-func (x *FileListResult) GetError() string {
-	if x != nil {
-		return x.Error
-	}
-	return ""
-}
-
-type FileInfo struct {
+type TerminalResize struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	Size          int64                  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
-	Mode          string                 `protobuf:"bytes,3,opt,name=mode,proto3" json:"mode,omitempty"`
-	ModTime       int64                  `protobuf:"varint,4,opt,name=mod_time,json=modTime,proto3" json:"mod_time,omitempty"`
-	IsDir         bool                   `protobuf:"varint,5,opt,name=is_dir,json=isDir,proto3" json:"is_dir,omitempty"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Cols          uint32                 `protobuf:"varint,2,opt,name=cols,proto3" json:"cols,omitempty"`
+	Rows          uint32                 `protobuf:"varint,3,opt,name=rows,proto3" json:"rows,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *FileInfo) Reset() {
-	*x = FileInfo{}
-	mi := &file_api_proto_agent_proto_msgTypes[14]
+func (x *TerminalResize) Reset() {
+	*x = TerminalResize{}
+	mi := &file_api_proto_agent_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *FileInfo) String() string {
+func (x *TerminalResize) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*FileInfo) ProtoMessage() {}
+func (*TerminalResize) ProtoMessage() {}
 
-func (x *FileInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_proto_msgTypes[14]
+func (x *TerminalResize) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1283,71 +1616,54 @@ func (x *FileInfo) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use FileInfo.ProtoReflect.Descriptor instead.
-func (*FileInfo) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_proto_rawDescGZIP(), []int{14}
+// Deprecated: Use TerminalResize.ProtoReflect.Descriptor instead.
+func (*TerminalResize) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{12}
 }
 
-func (x *FileInfo) GetName() string {
+func (x *TerminalResize) GetSessionId() string {
 	if x != nil {
-		return x.Name
+		return x.SessionId
 	}
 	return ""
 }
 
-func (x *FileInfo) GetSize() int64 {
+func (x *TerminalResize) GetCols() uint32 {
 	if x != nil {
-		return x.Size
+		return x.Cols
 	}
 	return 0
 }
 
-func (x *FileInfo) GetMode() string {
-	if x != nil {
-		return x.Mode
-	}
-	return ""
-}
-
-func (x *FileInfo) GetModTime() int64 {
+func (x *TerminalResize) GetRows() uint32 {
 	if x != nil {
-		return x.ModTime
+		return x.Rows
 	}
 	return 0
 }
 
-func (x *FileInfo) GetIsDir() bool {
-	if x != nil {
-		return x.IsDir
-	}
-	return false
-}
-
-// ========== 命令执行 ==========
-type CommandRequest struct {
+type TerminalClose struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
-	Command       string                 `protobuf:"bytes,2,opt,name=command,proto3" json:"command,omitempty"`
-	Timeout       int32                  `protobuf:"varint,3,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *CommandRequest) Reset() {
-	*x = CommandRequest{}
-	mi := &file_api_proto_agent_proto_msgTypes[15]
+func (x *TerminalClose) Reset() {
+	*x = TerminalClose{}
+	mi := &file_api_proto_agent_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *CommandRequest) String() string {
+func (x *TerminalClose) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*CommandRequest) ProtoMessage() {}
+func (*TerminalClose) ProtoMessage() {}
 
-func (x *CommandRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_proto_msgTypes[15]
+func (x *TerminalClose) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1358,58 +1674,57 @@ func (x *CommandRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use CommandRequest.ProtoReflect.Descriptor instead.
-func (*CommandRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_proto_rawDescGZIP(), []int{15}
-}
-
-func (x *CommandRequest) GetRequestId() string {
-	if x != nil {
-		return x.RequestId
-	}
-	return ""
+// Deprecated: Use TerminalClose.ProtoReflect.Descriptor instead.
+func (*TerminalClose) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{13}
 }
 
-func (x *CommandRequest) GetCommand() string {
+func (x *TerminalClose) GetSessionId() string {
 	if x != nil {
-		return x.Command
+		return x.SessionId
 	}
 	return ""
 }
 
-func (x *CommandRequest) GetTimeout() int32 {
-	if x != nil {
-		return x.Timeout
-	}
-	return 0
-}
-
-type CommandResult struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
-	ExitCode      int32                  `protobuf:"varint,2,opt,name=exit_code,json=exitCode,proto3" json:"exit_code,omitempty"`
-	Stdout        string                 `protobuf:"bytes,3,opt,name=stdout,proto3" json:"stdout,omitempty"`
-	Stderr        string                 `protobuf:"bytes,4,opt,name=stderr,proto3" json:"stderr,omitempty"`
-	Error         string                 `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+// ========== 文件操作 ==========
+type FileRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	RequestId string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	// list, upload, download, delete, stat,
+	// upload_init, upload_chunk, upload_commit, read, checksum, pack, pack_cleanup
+	Action   string `protobuf:"bytes,2,opt,name=action,proto3" json:"action,omitempty"`
+	Path     string `protobuf:"bytes,3,opt,name=path,proto3" json:"path,omitempty"`
+	Data     []byte `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+	Filename string `protobuf:"bytes,5,opt,name=filename,proto3" json:"filename,omitempty"`
+	// 分片传输
+	Offset        int64  `protobuf:"varint,6,opt,name=offset,proto3" json:"offset,omitempty"`
+	Size          int64  `protobuf:"varint,7,opt,name=size,proto3" json:"size,omitempty"` // upload_init: 文件总大小; read: 读取长度
+	Sha256        string `protobuf:"bytes,8,opt,name=sha256,proto3" json:"sha256,omitempty"`
+	Mode          uint32 `protobuf:"varint,9,opt,name=mode,proto3" json:"mode,omitempty"` // 权限位，0 表示保持原有权限
+	Owner         string `protobuf:"bytes,10,opt,name=owner,proto3" json:"owner,omitempty"`
+	Group         string `protobuf:"bytes,11,opt,name=group,proto3" json:"group,omitempty"`
+	ModTime       int64  `protobuf:"varint,12,opt,name=mod_time,json=modTime,proto3" json:"mod_time,omitempty"`
+	TransferId    string `protobuf:"bytes,13,opt,name=transfer_id,json=transferId,proto3" json:"transfer_id,omitempty"` // 相同标识的传输可断点续传
+	Extract       bool   `protobuf:"varint,14,opt,name=extract,proto3" json:"extract,omitempty"`                        // upload_commit: 按 tar.gz 流解压到 path/filename 目录
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *CommandResult) Reset() {
-	*x = CommandResult{}
-	mi := &file_api_proto_agent_proto_msgTypes[16]
+func (x *FileRequest) Reset() {
+	*x = FileRequest{}
+	mi := &file_api_proto_agent_proto_msgTypes[14]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *CommandResult) String() string {
+func (x *FileRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*CommandResult) ProtoMessage() {}
+func (*FileRequest) ProtoMessage() {}
 
-func (x *CommandResult) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_proto_msgTypes[16]
+func (x *FileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[14]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1420,272 +1735,274 @@ func (x *CommandResult) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use CommandResult.ProtoReflect.Descriptor instead.
-func (*CommandResult) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_proto_rawDescGZIP(), []int{16}
+// Deprecated: Use FileRequest.ProtoReflect.Descriptor instead.
+func (*FileRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{14}
 }
 
-func (x *CommandResult) GetRequestId() string {
+func (x *FileRequest) GetRequestId() string {
 	if x != nil {
 		return x.RequestId
 	}
 	return ""
 }
 
-func (x *CommandResult) GetExitCode() int32 {
+func (x *FileRequest) GetAction() string {
 	if x != nil {
-		return x.ExitCode
+		return x.Action
 	}
-	return 0
+	return ""
 }
 
-func (x *CommandResult) GetStdout() string {
+func (x *FileRequest) GetPath() string {
 	if x != nil {
-		return x.Stdout
+		return x.Path
 	}
 	return ""
 }
 
-func (x *CommandResult) GetStderr() string {
+func (x *FileRequest) GetData() []byte {
 	if x != nil {
-		return x.Stderr
+		return x.Data
 	}
-	return ""
+	return nil
 }
 
-func (x *CommandResult) GetError() string {
+func (x *FileRequest) GetFilename() string {
 	if x != nil {
-		return x.Error
+		return x.Filename
 	}
 	return ""
 }
 
-// ========== 拨测 ==========
-type ProbeRequest struct {
-	state     protoimpl.MessageState `protogen:"open.v1"`
-	RequestId string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
-	ProbeType string                 `protobuf:"bytes,2,opt,name=probe_type,json=probeType,proto3" json:"probe_type,omitempty"` // http, https, websocket, ping, tcp, udp
-	Target    string                 `protobuf:"bytes,3,opt,name=target,proto3" json:"target,omitempty"`
-	Port      int32                  `protobuf:"varint,4,opt,name=port,proto3" json:"port,omitempty"`
-	Timeout   int32                  `protobuf:"varint,5,opt,name=timeout,proto3" json:"timeout,omitempty"`
-	// HTTP/HTTPS/WebSocket specific
-	Method              string            `protobuf:"bytes,10,opt,name=method,proto3" json:"method,omitempty"`
-	Url                 string            `protobuf:"bytes,11,opt,name=url,proto3" json:"url,omitempty"`
-	Headers             map[string]string `protobuf:"bytes,12,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
-	Params              map[string]string `protobuf:"bytes,13,rep,name=params,proto3" json:"params,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
-	Body                string            `protobuf:"bytes,14,opt,name=body,proto3" json:"body,omitempty"`
-	ContentType         string            `protobuf:"bytes,15,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
-	SkipVerify          bool              `protobuf:"varint,16,opt,name=skip_verify,json=skipVerify,proto3" json:"skip_verify,omitempty"`
-	ProxyUrl            string            `protobuf:"bytes,17,opt,name=proxy_url,json=proxyUrl,proto3" json:"proxy_url,omitempty"`
-	Assertions          []*ProbeAssertion `protobuf:"bytes,18,rep,name=assertions,proto3" json:"assertions,omitempty"`
-	MaxResponseBodySize int32             `protobuf:"varint,19,opt,name=max_response_body_size,json=maxResponseBodySize,proto3" json:"max_response_body_size,omitempty"` // 最大响应体大小(字节)，0表示不限制，默认4KB
-	// WebSocket specific
-	WsMessage     string `protobuf:"bytes,20,opt,name=ws_message,json=wsMessage,proto3" json:"ws_message,omitempty"`
-	WsMessageType int32  `protobuf:"varint,21,opt,name=ws_message_type,json=wsMessageType,proto3" json:"ws_message_type,omitempty"` // 1=text, 2=binary
-	WsReadTimeout int32  `protobuf:"varint,22,opt,name=ws_read_timeout,json=wsReadTimeout,proto3" json:"ws_read_timeout,omitempty"`
-	// Ping specific
-	Count         int32 `protobuf:"varint,30,opt,name=count,proto3" json:"count,omitempty"`
-	PacketSize    int32 `protobuf:"varint,31,opt,name=packet_size,json=packetSize,proto3" json:"packet_size,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
-}
-
-func (x *ProbeRequest) Reset() {
-	*x = ProbeRequest{}
-	mi := &file_api_proto_agent_proto_msgTypes[17]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
+func (x *FileRequest) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
 }
 
-func (x *ProbeRequest) String() string {
-	return protoimpl.X.MessageStringOf(x)
+func (x *FileRequest) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
 }
 
-func (*ProbeRequest) ProtoMessage() {}
-
-func (x *ProbeRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_proto_msgTypes[17]
+func (x *FileRequest) GetSha256() string {
 	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
+		return x.Sha256
 	}
-	return mi.MessageOf(x)
+	return ""
 }
 
-// Deprecated: Use ProbeRequest.ProtoReflect.Descriptor instead.
-func (*ProbeRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_proto_rawDescGZIP(), []int{17}
-}
-
-func (x *ProbeRequest) GetRequestId() string {
+func (x *FileRequest) GetMode() uint32 {
 	if x != nil {
-		return x.RequestId
+		return x.Mode
 	}
-	return ""
+	return 0
 }
 
-func (x *ProbeRequest) GetProbeType() string {
+func (x *FileRequest) GetOwner() string {
 	if x != nil {
-		return x.ProbeType
+		return x.Owner
 	}
 	return ""
 }
 
-func (x *ProbeRequest) GetTarget() string {
+func (x *FileRequest) GetGroup() string {
 	if x != nil {
-		return x.Target
+		return x.Group
 	}
 	return ""
 }
 
-func (x *ProbeRequest) GetPort() int32 {
+func (x *FileRequest) GetModTime() int64 {
 	if x != nil {
-		return x.Port
+		return x.ModTime
 	}
 	return 0
 }
 
-func (x *ProbeRequest) GetTimeout() int32 {
+func (x *FileRequest) GetTransferId() string {
 	if x != nil {
-		return x.Timeout
+		return x.TransferId
 	}
-	return 0
+	return ""
 }
 
-func (x *ProbeRequest) GetMethod() string {
+func (x *FileRequest) GetExtract() bool {
 	if x != nil {
-		return x.Method
+		return x.Extract
 	}
-	return ""
+	return false
 }
 
-func (x *ProbeRequest) GetUrl() string {
+type FileChunk struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	RequestId string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	Data      []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	Eof       bool                   `protobuf:"varint,3,opt,name=eof,proto3" json:"eof,omitempty"`
+	Error     string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	// 分片传输
+	Offset        int64  `protobuf:"varint,5,opt,name=offset,proto3" json:"offset,omitempty"` // 已写入的字节数或本次读取的起始位置
+	Size          int64  `protobuf:"varint,6,opt,name=size,proto3" json:"size,omitempty"`
+	Sha256        string `protobuf:"bytes,7,opt,name=sha256,proto3" json:"sha256,omitempty"`
+	Mode          uint32 `protobuf:"varint,8,opt,name=mode,proto3" json:"mode,omitempty"`
+	Owner         string `protobuf:"bytes,9,opt,name=owner,proto3" json:"owner,omitempty"`
+	Group         string `protobuf:"bytes,10,opt,name=group,proto3" json:"group,omitempty"`
+	ModTime       int64  `protobuf:"varint,11,opt,name=mod_time,json=modTime,proto3" json:"mod_time,omitempty"`
+	IsDir         bool   `protobuf:"varint,12,opt,name=is_dir,json=isDir,proto3" json:"is_dir,omitempty"`
+	Path          string `protobuf:"bytes,13,opt,name=path,proto3" json:"path,omitempty"` // pack: 打包文件路径
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FileChunk) Reset() {
+	*x = FileChunk{}
+	mi := &file_api_proto_agent_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FileChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileChunk) ProtoMessage() {}
+
+func (x *FileChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[15]
 	if x != nil {
-		return x.Url
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return ""
+	return mi.MessageOf(x)
 }
 
-func (x *ProbeRequest) GetHeaders() map[string]string {
+// Deprecated: Use FileChunk.ProtoReflect.Descriptor instead.
+func (*FileChunk) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *FileChunk) GetRequestId() string {
 	if x != nil {
-		return x.Headers
+		return x.RequestId
 	}
-	return nil
+	return ""
 }
 
-func (x *ProbeRequest) GetParams() map[string]string {
+func (x *FileChunk) GetData() []byte {
 	if x != nil {
-		return x.Params
+		return x.Data
 	}
 	return nil
 }
 
-func (x *ProbeRequest) GetBody() string {
+func (x *FileChunk) GetEof() bool {
 	if x != nil {
-		return x.Body
+		return x.Eof
 	}
-	return ""
+	return false
 }
 
-func (x *ProbeRequest) GetContentType() string {
+func (x *FileChunk) GetError() string {
 	if x != nil {
-		return x.ContentType
+		return x.Error
 	}
 	return ""
 }
 
-func (x *ProbeRequest) GetSkipVerify() bool {
+func (x *FileChunk) GetOffset() int64 {
 	if x != nil {
-		return x.SkipVerify
+		return x.Offset
 	}
-	return false
+	return 0
 }
 
-func (x *ProbeRequest) GetProxyUrl() string {
+func (x *FileChunk) GetSize() int64 {
 	if x != nil {
-		return x.ProxyUrl
+		return x.Size
 	}
-	return ""
+	return 0
 }
 
-func (x *ProbeRequest) GetAssertions() []*ProbeAssertion {
+func (x *FileChunk) GetSha256() string {
 	if x != nil {
-		return x.Assertions
+		return x.Sha256
 	}
-	return nil
+	return ""
 }
 
-func (x *ProbeRequest) GetMaxResponseBodySize() int32 {
+func (x *FileChunk) GetMode() uint32 {
 	if x != nil {
-		return x.MaxResponseBodySize
+		return x.Mode
 	}
 	return 0
 }
 
-func (x *ProbeRequest) GetWsMessage() string {
+func (x *FileChunk) GetOwner() string {
 	if x != nil {
-		return x.WsMessage
+		return x.Owner
 	}
 	return ""
 }
 
-func (x *ProbeRequest) GetWsMessageType() int32 {
+func (x *FileChunk) GetGroup() string {
 	if x != nil {
-		return x.WsMessageType
+		return x.Group
 	}
-	return 0
+	return ""
 }
 
-func (x *ProbeRequest) GetWsReadTimeout() int32 {
+func (x *FileChunk) GetModTime() int64 {
 	if x != nil {
-		return x.WsReadTimeout
+		return x.ModTime
 	}
 	return 0
 }
 
-func (x *ProbeRequest) GetCount() int32 {
+func (x *FileChunk) GetIsDir() bool {
 	if x != nil {
-		return x.Count
+		return x.IsDir
 	}
-	return 0
+	return false
 }
 
-func (x *ProbeRequest) GetPacketSize() int32 {
+func (x *FileChunk) GetPath() string {
 	if x != nil {
-		return x.PacketSize
+		return x.Path
 	}
-	return 0
+	return ""
 }
 
-type ProbeAssertion struct {
+type FileListResult struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	Source        string                 `protobuf:"bytes,2,opt,name=source,proto3" json:"source,omitempty"` // body, header, status
-	Path          string                 `protobuf:"bytes,3,opt,name=path,proto3" json:"path,omitempty"`
-	Condition     string                 `protobuf:"bytes,4,opt,name=condition,proto3" json:"condition,omitempty"` // ==, >, >=, <, <=, contains, notcontains, regexp, notregexp
-	Value         string                 `protobuf:"bytes,5,opt,name=value,proto3" json:"value,omitempty"`
+	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	Files         []*FileInfo            `protobuf:"bytes,2,rep,name=files,proto3" json:"files,omitempty"`
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ProbeAssertion) Reset() {
-	*x = ProbeAssertion{}
-	mi := &file_api_proto_agent_proto_msgTypes[18]
+func (x *FileListResult) Reset() {
+	*x = FileListResult{}
+	mi := &file_api_proto_agent_proto_msgTypes[16]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ProbeAssertion) String() string {
+func (x *FileListResult) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ProbeAssertion) ProtoMessage() {}
+func (*FileListResult) ProtoMessage() {}
 
-func (x *ProbeAssertion) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_proto_msgTypes[18]
+func (x *FileListResult) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[16]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1696,110 +2013,58 @@ func (x *ProbeAssertion) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ProbeAssertion.ProtoReflect.Descriptor instead.
-func (*ProbeAssertion) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_proto_rawDescGZIP(), []int{18}
-}
-
-func (x *ProbeAssertion) GetName() string {
-	if x != nil {
-		return x.Name
-	}
-	return ""
-}
-
-func (x *ProbeAssertion) GetSource() string {
-	if x != nil {
-		return x.Source
-	}
-	return ""
+// Deprecated: Use FileListResult.ProtoReflect.Descriptor instead.
+func (*FileListResult) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{16}
 }
 
-func (x *ProbeAssertion) GetPath() string {
+func (x *FileListResult) GetRequestId() string {
 	if x != nil {
-		return x.Path
+		return x.RequestId
 	}
 	return ""
 }
 
-func (x *ProbeAssertion) GetCondition() string {
+func (x *FileListResult) GetFiles() []*FileInfo {
 	if x != nil {
-		return x.Condition
+		return x.Files
 	}
-	return ""
+	return nil
 }
 
-func (x *ProbeAssertion) GetValue() string {
+func (x *FileListResult) GetError() string {
 	if x != nil {
-		return x.Value
+		return x.Error
 	}
 	return ""
 }
 
-type ProbeResult struct {
-	state     protoimpl.MessageState `protogen:"open.v1"`
-	RequestId string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
-	Success   bool                   `protobuf:"varint,2,opt,name=success,proto3" json:"success,omitempty"`
-	Error     string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
-	Latency   float64                `protobuf:"fixed64,4,opt,name=latency,proto3" json:"latency,omitempty"` // ms
-	// Network probe results
-	PacketLoss      float64 `protobuf:"fixed64,10,opt,name=packet_loss,json=packetLoss,proto3" json:"packet_loss,omitempty"`
-	PingRttAvg      float64 `protobuf:"fixed64,11,opt,name=ping_rtt_avg,json=pingRttAvg,proto3" json:"ping_rtt_avg,omitempty"`
-	PingRttMin      float64 `protobuf:"fixed64,12,opt,name=ping_rtt_min,json=pingRttMin,proto3" json:"ping_rtt_min,omitempty"`
-	PingRttMax      float64 `protobuf:"fixed64,13,opt,name=ping_rtt_max,json=pingRttMax,proto3" json:"ping_rtt_max,omitempty"`
-	PingStddev      float64 `protobuf:"fixed64,14,opt,name=ping_stddev,json=pingStddev,proto3" json:"ping_stddev,omitempty"`
-	PingPacketsSent int32   `protobuf:"varint,15,opt,name=ping_packets_sent,json=pingPacketsSent,proto3" json:"ping_packets_sent,omitempty"`
-	PingPacketsRecv int32   `protobuf:"varint,16,opt,name=ping_packets_recv,json=pingPacketsRecv,proto3" json:"ping_packets_recv,omitempty"`
-	TcpConnectTime  float64 `protobuf:"fixed64,17,opt,name=tcp_connect_time,json=tcpConnectTime,proto3" json:"tcp_connect_time,omitempty"`
-	UdpWriteTime    float64 `protobuf:"fixed64,18,opt,name=udp_write_time,json=udpWriteTime,proto3" json:"udp_write_time,omitempty"`
-	UdpReadTime     float64 `protobuf:"fixed64,19,opt,name=udp_read_time,json=udpReadTime,proto3" json:"udp_read_time,omitempty"`
-	// HTTP/HTTPS/WebSocket results
-	HttpStatusCode    int32             `protobuf:"varint,20,opt,name=http_status_code,json=httpStatusCode,proto3" json:"http_status_code,omitempty"`
-	HttpResponseTime  float64           `protobuf:"fixed64,21,opt,name=http_response_time,json=httpResponseTime,proto3" json:"http_response_time,omitempty"`
-	HttpContentLength int64             `protobuf:"varint,22,opt,name=http_content_length,json=httpContentLength,proto3" json:"http_content_length,omitempty"`
-	ResponseBody      []byte            `protobuf:"bytes,23,opt,name=response_body,json=responseBody,proto3" json:"response_body,omitempty"` // 支持二进制内容（图片、CSS、JS等）
-	ResponseHeaders   map[string]string `protobuf:"bytes,24,rep,name=response_headers,json=responseHeaders,proto3" json:"response_headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
-	// Performance breakdown
-	DnsLookupTime       float64 `protobuf:"fixed64,30,opt,name=dns_lookup_time,json=dnsLookupTime,proto3" json:"dns_lookup_time,omitempty"`
-	TcpConnectTimeHttp  float64 `protobuf:"fixed64,31,opt,name=tcp_connect_time_http,json=tcpConnectTimeHttp,proto3" json:"tcp_connect_time_http,omitempty"`
-	TlsHandshakeTime    float64 `protobuf:"fixed64,32,opt,name=tls_handshake_time,json=tlsHandshakeTime,proto3" json:"tls_handshake_time,omitempty"`
-	Ttfb                float64 `protobuf:"fixed64,33,opt,name=ttfb,proto3" json:"ttfb,omitempty"` // time to first byte
-	ContentTransferTime float64 `protobuf:"fixed64,34,opt,name=content_transfer_time,json=contentTransferTime,proto3" json:"content_transfer_time,omitempty"`
-	// TLS/Certificate info
-	TlsVersion      string `protobuf:"bytes,40,opt,name=tls_version,json=tlsVersion,proto3" json:"tls_version,omitempty"`
-	TlsCipherSuite  string `protobuf:"bytes,41,opt,name=tls_cipher_suite,json=tlsCipherSuite,proto3" json:"tls_cipher_suite,omitempty"`
-	SslCertNotAfter int64  `protobuf:"varint,42,opt,name=ssl_cert_not_after,json=sslCertNotAfter,proto3" json:"ssl_cert_not_after,omitempty"`
-	// HTTP details
-	RedirectCount       int32   `protobuf:"varint,50,opt,name=redirect_count,json=redirectCount,proto3" json:"redirect_count,omitempty"`
-	RedirectTime        float64 `protobuf:"fixed64,51,opt,name=redirect_time,json=redirectTime,proto3" json:"redirect_time,omitempty"`
-	FinalUrl            string  `protobuf:"bytes,52,opt,name=final_url,json=finalUrl,proto3" json:"final_url,omitempty"`
-	ResponseHeaderBytes int32   `protobuf:"varint,53,opt,name=response_header_bytes,json=responseHeaderBytes,proto3" json:"response_header_bytes,omitempty"`
-	ResponseBodyBytes   int32   `protobuf:"varint,54,opt,name=response_body_bytes,json=responseBodyBytes,proto3" json:"response_body_bytes,omitempty"`
-	// Assertion results
-	AssertionSuccess   bool                    `protobuf:"varint,60,opt,name=assertion_success,json=assertionSuccess,proto3" json:"assertion_success,omitempty"`
-	AssertionResults   []*ProbeAssertionResult `protobuf:"bytes,61,rep,name=assertion_results,json=assertionResults,proto3" json:"assertion_results,omitempty"`
-	AssertionPassCount int32                   `protobuf:"varint,62,opt,name=assertion_pass_count,json=assertionPassCount,proto3" json:"assertion_pass_count,omitempty"`
-	AssertionFailCount int32                   `protobuf:"varint,63,opt,name=assertion_fail_count,json=assertionFailCount,proto3" json:"assertion_fail_count,omitempty"`
-	AssertionEvalTime  float64                 `protobuf:"fixed64,64,opt,name=assertion_eval_time,json=assertionEvalTime,proto3" json:"assertion_eval_time,omitempty"`
-	unknownFields      protoimpl.UnknownFields
-	sizeCache          protoimpl.SizeCache
+type FileInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Size          int64                  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	Mode          string                 `protobuf:"bytes,3,opt,name=mode,proto3" json:"mode,omitempty"`
+	ModTime       int64                  `protobuf:"varint,4,opt,name=mod_time,json=modTime,proto3" json:"mod_time,omitempty"`
+	IsDir         bool                   `protobuf:"varint,5,opt,name=is_dir,json=isDir,proto3" json:"is_dir,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ProbeResult) Reset() {
-	*x = ProbeResult{}
-	mi := &file_api_proto_agent_proto_msgTypes[19]
+func (x *FileInfo) Reset() {
+	*x = FileInfo{}
+	mi := &file_api_proto_agent_proto_msgTypes[17]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ProbeResult) String() string {
+func (x *FileInfo) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ProbeResult) ProtoMessage() {}
+func (*FileInfo) ProtoMessage() {}
 
-func (x *ProbeResult) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_proto_msgTypes[19]
+func (x *FileInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[17]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1810,295 +2075,1982 @@ func (x *ProbeResult) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ProbeResult.ProtoReflect.Descriptor instead.
-func (*ProbeResult) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_proto_rawDescGZIP(), []int{19}
+// Deprecated: Use FileInfo.ProtoReflect.Descriptor instead.
+func (*FileInfo) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{17}
 }
 
-func (x *ProbeResult) GetRequestId() string {
+func (x *FileInfo) GetName() string {
 	if x != nil {
-		return x.RequestId
+		return x.Name
 	}
 	return ""
 }
 
-func (x *ProbeResult) GetSuccess() bool {
+func (x *FileInfo) GetSize() int64 {
 	if x != nil {
-		return x.Success
+		return x.Size
 	}
-	return false
+	return 0
 }
 
-func (x *ProbeResult) GetError() string {
+func (x *FileInfo) GetMode() string {
 	if x != nil {
-		return x.Error
+		return x.Mode
 	}
 	return ""
 }
 
-func (x *ProbeResult) GetLatency() float64 {
+func (x *FileInfo) GetModTime() int64 {
 	if x != nil {
-		return x.Latency
+		return x.ModTime
 	}
 	return 0
 }
 
-func (x *ProbeResult) GetPacketLoss() float64 {
+func (x *FileInfo) GetIsDir() bool {
 	if x != nil {
-		return x.PacketLoss
+		return x.IsDir
 	}
-	return 0
+	return false
 }
 
-func (x *ProbeResult) GetPingRttAvg() float64 {
+// ========== 命令执行 ==========
+type CommandRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	RequestId      string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	Command        string                 `protobuf:"bytes,2,opt,name=command,proto3" json:"command,omitempty"`
+	Timeout        int32                  `protobuf:"varint,3,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	Stream         bool                   `protobuf:"varint,4,opt,name=stream,proto3" json:"stream,omitempty"`                                         // true 时以 CommandOutputChunk 增量返回输出
+	MaxOutputBytes int64                  `protobuf:"varint,5,opt,name=max_output_bytes,json=maxOutputBytes,proto3" json:"max_output_bytes,omitempty"` // 流式输出上限(字节)，0 表示使用 Agent 默认值
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *CommandRequest) Reset() {
+	*x = CommandRequest{}
+	mi := &file_api_proto_agent_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommandRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommandRequest) ProtoMessage() {}
+
+func (x *CommandRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[18]
 	if x != nil {
-		return x.PingRttAvg
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return 0
+	return mi.MessageOf(x)
 }
 
-func (x *ProbeResult) GetPingRttMin() float64 {
+// Deprecated: Use CommandRequest.ProtoReflect.Descriptor instead.
+func (*CommandRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *CommandRequest) GetRequestId() string {
 	if x != nil {
-		return x.PingRttMin
+		return x.RequestId
 	}
-	return 0
+	return ""
 }
 
-func (x *ProbeResult) GetPingRttMax() float64 {
+func (x *CommandRequest) GetCommand() string {
 	if x != nil {
-		return x.PingRttMax
+		return x.Command
 	}
-	return 0
+	return ""
 }
 
-func (x *ProbeResult) GetPingStddev() float64 {
+func (x *CommandRequest) GetTimeout() int32 {
 	if x != nil {
-		return x.PingStddev
+		return x.Timeout
 	}
 	return 0
 }
 
-func (x *ProbeResult) GetPingPacketsSent() int32 {
+func (x *CommandRequest) GetStream() bool {
 	if x != nil {
-		return x.PingPacketsSent
+		return x.Stream
 	}
-	return 0
+	return false
 }
 
-func (x *ProbeResult) GetPingPacketsRecv() int32 {
+func (x *CommandRequest) GetMaxOutputBytes() int64 {
 	if x != nil {
-		return x.PingPacketsRecv
+		return x.MaxOutputBytes
 	}
 	return 0
 }
 
-func (x *ProbeResult) GetTcpConnectTime() float64 {
+type CommandResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	ExitCode      int32                  `protobuf:"varint,2,opt,name=exit_code,json=exitCode,proto3" json:"exit_code,omitempty"`
+	Stdout        string                 `protobuf:"bytes,3,opt,name=stdout,proto3" json:"stdout,omitempty"`
+	Stderr        string                 `protobuf:"bytes,4,opt,name=stderr,proto3" json:"stderr,omitempty"`
+	Error         string                 `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CommandResult) Reset() {
+	*x = CommandResult{}
+	mi := &file_api_proto_agent_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommandResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommandResult) ProtoMessage() {}
+
+func (x *CommandResult) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[19]
 	if x != nil {
-		return x.TcpConnectTime
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return 0
+	return mi.MessageOf(x)
 }
 
-func (x *ProbeResult) GetUdpWriteTime() float64 {
+// Deprecated: Use CommandResult.ProtoReflect.Descriptor instead.
+func (*CommandResult) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *CommandResult) GetRequestId() string {
 	if x != nil {
-		return x.UdpWriteTime
+		return x.RequestId
 	}
-	return 0
+	return ""
 }
 
-func (x *ProbeResult) GetUdpReadTime() float64 {
+func (x *CommandResult) GetExitCode() int32 {
 	if x != nil {
-		return x.UdpReadTime
+		return x.ExitCode
 	}
 	return 0
 }
 
-func (x *ProbeResult) GetHttpStatusCode() int32 {
+func (x *CommandResult) GetStdout() string {
 	if x != nil {
-		return x.HttpStatusCode
+		return x.Stdout
 	}
-	return 0
+	return ""
 }
 
-func (x *ProbeResult) GetHttpResponseTime() float64 {
+func (x *CommandResult) GetStderr() string {
 	if x != nil {
-		return x.HttpResponseTime
+		return x.Stderr
 	}
-	return 0
+	return ""
 }
 
-func (x *ProbeResult) GetHttpContentLength() int64 {
+func (x *CommandResult) GetError() string {
 	if x != nil {
-		return x.HttpContentLength
+		return x.Error
 	}
-	return 0
+	return ""
 }
 
-func (x *ProbeResult) GetResponseBody() []byte {
+// 取消流式命令，Agent 会结束整个进程组
+type CommandCancel struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CommandCancel) Reset() {
+	*x = CommandCancel{}
+	mi := &file_api_proto_agent_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommandCancel) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommandCancel) ProtoMessage() {}
+
+func (x *CommandCancel) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[20]
 	if x != nil {
-		return x.ResponseBody
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return nil
+	return mi.MessageOf(x)
 }
 
-func (x *ProbeResult) GetResponseHeaders() map[string]string {
+// Deprecated: Use CommandCancel.ProtoReflect.Descriptor instead.
+func (*CommandCancel) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *CommandCancel) GetRequestId() string {
 	if x != nil {
-		return x.ResponseHeaders
+		return x.RequestId
 	}
-	return nil
+	return ""
 }
 
-func (x *ProbeResult) GetDnsLookupTime() float64 {
+type CommandOutputChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	Seq           int64                  `protobuf:"varint,2,opt,name=seq,proto3" json:"seq,omitempty"`
+	Stream        string                 `protobuf:"bytes,3,opt,name=stream,proto3" json:"stream,omitempty"` // stdout, stderr
+	Data          []byte                 `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+	Eof           bool                   `protobuf:"varint,5,opt,name=eof,proto3" json:"eof,omitempty"` // 最后一条消息，携带退出信息
+	ExitCode      int32                  `protobuf:"varint,6,opt,name=exit_code,json=exitCode,proto3" json:"exit_code,omitempty"`
+	Signal        string                 `protobuf:"bytes,7,opt,name=signal,proto3" json:"signal,omitempty"` // 被信号终止时的信号名
+	Error         string                 `protobuf:"bytes,8,opt,name=error,proto3" json:"error,omitempty"`
+	Truncated     bool                   `protobuf:"varint,9,opt,name=truncated,proto3" json:"truncated,omitempty"` // 输出超过上限被截断
+	Canceled      bool                   `protobuf:"varint,10,opt,name=canceled,proto3" json:"canceled,omitempty"`
+	TimedOut      bool                   `protobuf:"varint,11,opt,name=timed_out,json=timedOut,proto3" json:"timed_out,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CommandOutputChunk) Reset() {
+	*x = CommandOutputChunk{}
+	mi := &file_api_proto_agent_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommandOutputChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommandOutputChunk) ProtoMessage() {}
+
+func (x *CommandOutputChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[21]
 	if x != nil {
-		return x.DnsLookupTime
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return 0
+	return mi.MessageOf(x)
 }
 
-func (x *ProbeResult) GetTcpConnectTimeHttp() float64 {
+// Deprecated: Use CommandOutputChunk.ProtoReflect.Descriptor instead.
+func (*CommandOutputChunk) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *CommandOutputChunk) GetRequestId() string {
 	if x != nil {
-		return x.TcpConnectTimeHttp
+		return x.RequestId
 	}
-	return 0
+	return ""
 }
 
-func (x *ProbeResult) GetTlsHandshakeTime() float64 {
+func (x *CommandOutputChunk) GetSeq() int64 {
 	if x != nil {
-		return x.TlsHandshakeTime
+		return x.Seq
 	}
 	return 0
 }
 
-func (x *ProbeResult) GetTtfb() float64 {
+func (x *CommandOutputChunk) GetStream() string {
 	if x != nil {
-		return x.Ttfb
+		return x.Stream
 	}
-	return 0
+	return ""
 }
 
-func (x *ProbeResult) GetContentTransferTime() float64 {
+func (x *CommandOutputChunk) GetData() []byte {
 	if x != nil {
-		return x.ContentTransferTime
+		return x.Data
 	}
-	return 0
+	return nil
 }
 
-func (x *ProbeResult) GetTlsVersion() string {
+func (x *CommandOutputChunk) GetEof() bool {
 	if x != nil {
-		return x.TlsVersion
+		return x.Eof
 	}
-	return ""
+	return false
 }
 
-func (x *ProbeResult) GetTlsCipherSuite() string {
+func (x *CommandOutputChunk) GetExitCode() int32 {
 	if x != nil {
-		return x.TlsCipherSuite
+		return x.ExitCode
 	}
-	return ""
+	return 0
 }
 
-func (x *ProbeResult) GetSslCertNotAfter() int64 {
+func (x *CommandOutputChunk) GetSignal() string {
 	if x != nil {
-		return x.SslCertNotAfter
+		return x.Signal
 	}
-	return 0
+	return ""
 }
 
-func (x *ProbeResult) GetRedirectCount() int32 {
+func (x *CommandOutputChunk) GetError() string {
 	if x != nil {
-		return x.RedirectCount
+		return x.Error
 	}
-	return 0
+	return ""
 }
 
-func (x *ProbeResult) GetRedirectTime() float64 {
+func (x *CommandOutputChunk) GetTruncated() bool {
 	if x != nil {
-		return x.RedirectTime
+		return x.Truncated
 	}
-	return 0
+	return false
 }
 
-func (x *ProbeResult) GetFinalUrl() string {
+func (x *CommandOutputChunk) GetCanceled() bool {
 	if x != nil {
-		return x.FinalUrl
+		return x.Canceled
 	}
-	return ""
+	return false
 }
 
-func (x *ProbeResult) GetResponseHeaderBytes() int32 {
+func (x *CommandOutputChunk) GetTimedOut() bool {
 	if x != nil {
-		return x.ResponseHeaderBytes
+		return x.TimedOut
 	}
-	return 0
+	return false
+}
+
+// ========== 拨测 ==========
+type ProbeRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	RequestId string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	ProbeType string                 `protobuf:"bytes,2,opt,name=probe_type,json=probeType,proto3" json:"probe_type,omitempty"` // http, https, websocket, ping, tcp, udp
+	Target    string                 `protobuf:"bytes,3,opt,name=target,proto3" json:"target,omitempty"`
+	Port      int32                  `protobuf:"varint,4,opt,name=port,proto3" json:"port,omitempty"`
+	Timeout   int32                  `protobuf:"varint,5,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	// HTTP/HTTPS/WebSocket specific
+	Method              string            `protobuf:"bytes,10,opt,name=method,proto3" json:"method,omitempty"`
+	Url                 string            `protobuf:"bytes,11,opt,name=url,proto3" json:"url,omitempty"`
+	Headers             map[string]string `protobuf:"bytes,12,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Params              map[string]string `protobuf:"bytes,13,rep,name=params,proto3" json:"params,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Body                string            `protobuf:"bytes,14,opt,name=body,proto3" json:"body,omitempty"`
+	ContentType         string            `protobuf:"bytes,15,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	SkipVerify          bool              `protobuf:"varint,16,opt,name=skip_verify,json=skipVerify,proto3" json:"skip_verify,omitempty"`
+	ProxyUrl            string            `protobuf:"bytes,17,opt,name=proxy_url,json=proxyUrl,proto3" json:"proxy_url,omitempty"`
+	Assertions          []*ProbeAssertion `protobuf:"bytes,18,rep,name=assertions,proto3" json:"assertions,omitempty"`
+	MaxResponseBodySize int32             `protobuf:"varint,19,opt,name=max_response_body_size,json=maxResponseBodySize,proto3" json:"max_response_body_size,omitempty"` // 最大响应体大小(字节)，0表示不限制，默认4KB
+	// WebSocket specific
+	WsMessage     string `protobuf:"bytes,20,opt,name=ws_message,json=wsMessage,proto3" json:"ws_message,omitempty"`
+	WsMessageType int32  `protobuf:"varint,21,opt,name=ws_message_type,json=wsMessageType,proto3" json:"ws_message_type,omitempty"` // 1=text, 2=binary
+	WsReadTimeout int32  `protobuf:"varint,22,opt,name=ws_read_timeout,json=wsReadTimeout,proto3" json:"ws_read_timeout,omitempty"`
+	// Ping specific
+	Count         int32 `protobuf:"varint,30,opt,name=count,proto3" json:"count,omitempty"`
+	PacketSize    int32 `protobuf:"varint,31,opt,name=packet_size,json=packetSize,proto3" json:"packet_size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProbeRequest) Reset() {
+	*x = ProbeRequest{}
+	mi := &file_api_proto_agent_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProbeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProbeRequest) ProtoMessage() {}
+
+func (x *ProbeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProbeRequest.ProtoReflect.Descriptor instead.
+func (*ProbeRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *ProbeRequest) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *ProbeRequest) GetProbeType() string {
+	if x != nil {
+		return x.ProbeType
+	}
+	return ""
+}
+
+func (x *ProbeRequest) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *ProbeRequest) GetPort() int32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+func (x *ProbeRequest) GetTimeout() int32 {
+	if x != nil {
+		return x.Timeout
+	}
+	return 0
+}
+
+func (x *ProbeRequest) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *ProbeRequest) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *ProbeRequest) GetHeaders() map[string]string {
+	if x != nil {
+		return x.Headers
+	}
+	return nil
+}
+
+func (x *ProbeRequest) GetParams() map[string]string {
+	if x != nil {
+		return x.Params
+	}
+	return nil
+}
+
+func (x *ProbeRequest) GetBody() string {
+	if x != nil {
+		return x.Body
+	}
+	return ""
+}
+
+func (x *ProbeRequest) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *ProbeRequest) GetSkipVerify() bool {
+	if x != nil {
+		return x.SkipVerify
+	}
+	return false
+}
+
+func (x *ProbeRequest) GetProxyUrl() string {
+	if x != nil {
+		return x.ProxyUrl
+	}
+	return ""
+}
+
+func (x *ProbeRequest) GetAssertions() []*ProbeAssertion {
+	if x != nil {
+		return x.Assertions
+	}
+	return nil
+}
+
+func (x *ProbeRequest) GetMaxResponseBodySize() int32 {
+	if x != nil {
+		return x.MaxResponseBodySize
+	}
+	return 0
+}
+
+func (x *ProbeRequest) GetWsMessage() string {
+	if x != nil {
+		return x.WsMessage
+	}
+	return ""
+}
+
+func (x *ProbeRequest) GetWsMessageType() int32 {
+	if x != nil {
+		return x.WsMessageType
+	}
+	return 0
+}
+
+func (x *ProbeRequest) GetWsReadTimeout() int32 {
+	if x != nil {
+		return x.WsReadTimeout
+	}
+	return 0
+}
+
+func (x *ProbeRequest) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *ProbeRequest) GetPacketSize() int32 {
+	if x != nil {
+		return x.PacketSize
+	}
+	return 0
+}
+
+type ProbeAssertion struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Source        string                 `protobuf:"bytes,2,opt,name=source,proto3" json:"source,omitempty"` // body, header, status
+	Path          string                 `protobuf:"bytes,3,opt,name=path,proto3" json:"path,omitempty"`
+	Condition     string                 `protobuf:"bytes,4,opt,name=condition,proto3" json:"condition,omitempty"` // ==, >, >=, <, <=, contains, notcontains, regexp, notregexp
+	Value         string                 `protobuf:"bytes,5,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProbeAssertion) Reset() {
+	*x = ProbeAssertion{}
+	mi := &file_api_proto_agent_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProbeAssertion) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProbeAssertion) ProtoMessage() {}
+
+func (x *ProbeAssertion) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProbeAssertion.ProtoReflect.Descriptor instead.
+func (*ProbeAssertion) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *ProbeAssertion) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ProbeAssertion) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *ProbeAssertion) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *ProbeAssertion) GetCondition() string {
+	if x != nil {
+		return x.Condition
+	}
+	return ""
+}
+
+func (x *ProbeAssertion) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+type ProbeResult struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	RequestId string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	Success   bool                   `protobuf:"varint,2,opt,name=success,proto3" json:"success,omitempty"`
+	Error     string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	Latency   float64                `protobuf:"fixed64,4,opt,name=latency,proto3" json:"latency,omitempty"` // ms
+	// Network probe results
+	PacketLoss      float64 `protobuf:"fixed64,10,opt,name=packet_loss,json=packetLoss,proto3" json:"packet_loss,omitempty"`
+	PingRttAvg      float64 `protobuf:"fixed64,11,opt,name=ping_rtt_avg,json=pingRttAvg,proto3" json:"ping_rtt_avg,omitempty"`
+	PingRttMin      float64 `protobuf:"fixed64,12,opt,name=ping_rtt_min,json=pingRttMin,proto3" json:"ping_rtt_min,omitempty"`
+	PingRttMax      float64 `protobuf:"fixed64,13,opt,name=ping_rtt_max,json=pingRttMax,proto3" json:"ping_rtt_max,omitempty"`
+	PingStddev      float64 `protobuf:"fixed64,14,opt,name=ping_stddev,json=pingStddev,proto3" json:"ping_stddev,omitempty"`
+	PingPacketsSent int32   `protobuf:"varint,15,opt,name=ping_packets_sent,json=pingPacketsSent,proto3" json:"ping_packets_sent,omitempty"`
+	PingPacketsRecv int32   `protobuf:"varint,16,opt,name=ping_packets_recv,json=pingPacketsRecv,proto3" json:"ping_packets_recv,omitempty"`
+	TcpConnectTime  float64 `protobuf:"fixed64,17,opt,name=tcp_connect_time,json=tcpConnectTime,proto3" json:"tcp_connect_time,omitempty"`
+	UdpWriteTime    float64 `protobuf:"fixed64,18,opt,name=udp_write_time,json=udpWriteTime,proto3" json:"udp_write_time,omitempty"`
+	UdpReadTime     float64 `protobuf:"fixed64,19,opt,name=udp_read_time,json=udpReadTime,proto3" json:"udp_read_time,omitempty"`
+	// HTTP/HTTPS/WebSocket results
+	HttpStatusCode    int32             `protobuf:"varint,20,opt,name=http_status_code,json=httpStatusCode,proto3" json:"http_status_code,omitempty"`
+	HttpResponseTime  float64           `protobuf:"fixed64,21,opt,name=http_response_time,json=httpResponseTime,proto3" json:"http_response_time,omitempty"`
+	HttpContentLength int64             `protobuf:"varint,22,opt,name=http_content_length,json=httpContentLength,proto3" json:"http_content_length,omitempty"`
+	ResponseBody      []byte            `protobuf:"bytes,23,opt,name=response_body,json=responseBody,proto3" json:"response_body,omitempty"` // 支持二进制内容（图片、CSS、JS等）
+	ResponseHeaders   map[string]string `protobuf:"bytes,24,rep,name=response_headers,json=responseHeaders,proto3" json:"response_headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Performance breakdown
+	DnsLookupTime       float64 `protobuf:"fixed64,30,opt,name=dns_lookup_time,json=dnsLookupTime,proto3" json:"dns_lookup_time,omitempty"`
+	TcpConnectTimeHttp  float64 `protobuf:"fixed64,31,opt,name=tcp_connect_time_http,json=tcpConnectTimeHttp,proto3" json:"tcp_connect_time_http,omitempty"`
+	TlsHandshakeTime    float64 `protobuf:"fixed64,32,opt,name=tls_handshake_time,json=tlsHandshakeTime,proto3" json:"tls_handshake_time,omitempty"`
+	Ttfb                float64 `protobuf:"fixed64,33,opt,name=ttfb,proto3" json:"ttfb,omitempty"` // time to first byte
+	ContentTransferTime float64 `protobuf:"fixed64,34,opt,name=content_transfer_time,json=contentTransferTime,proto3" json:"content_transfer_time,omitempty"`
+	// TLS/Certificate info
+	TlsVersion      string `protobuf:"bytes,40,opt,name=tls_version,json=tlsVersion,proto3" json:"tls_version,omitempty"`
+	TlsCipherSuite  string `protobuf:"bytes,41,opt,name=tls_cipher_suite,json=tlsCipherSuite,proto3" json:"tls_cipher_suite,omitempty"`
+	SslCertNotAfter int64  `protobuf:"varint,42,opt,name=ssl_cert_not_after,json=sslCertNotAfter,proto3" json:"ssl_cert_not_after,omitempty"`
+	// HTTP details
+	RedirectCount       int32   `protobuf:"varint,50,opt,name=redirect_count,json=redirectCount,proto3" json:"redirect_count,omitempty"`
+	RedirectTime        float64 `protobuf:"fixed64,51,opt,name=redirect_time,json=redirectTime,proto3" json:"redirect_time,omitempty"`
+	FinalUrl            string  `protobuf:"bytes,52,opt,name=final_url,json=finalUrl,proto3" json:"final_url,omitempty"`
+	ResponseHeaderBytes int32   `protobuf:"varint,53,opt,name=response_header_bytes,json=responseHeaderBytes,proto3" json:"response_header_bytes,omitempty"`
+	ResponseBodyBytes   int32   `protobuf:"varint,54,opt,name=response_body_bytes,json=responseBodyBytes,proto3" json:"response_body_bytes,omitempty"`
+	// Assertion results
+	AssertionSuccess   bool                    `protobuf:"varint,60,opt,name=assertion_success,json=assertionSuccess,proto3" json:"assertion_success,omitempty"`
+	AssertionResults   []*ProbeAssertionResult `protobuf:"bytes,61,rep,name=assertion_results,json=assertionResults,proto3" json:"assertion_results,omitempty"`
+	AssertionPassCount int32                   `protobuf:"varint,62,opt,name=assertion_pass_count,json=assertionPassCount,proto3" json:"assertion_pass_count,omitempty"`
+	AssertionFailCount int32                   `protobuf:"varint,63,opt,name=assertion_fail_count,json=assertionFailCount,proto3" json:"assertion_fail_count,omitempty"`
+	AssertionEvalTime  float64                 `protobuf:"fixed64,64,opt,name=assertion_eval_time,json=assertionEvalTime,proto3" json:"assertion_eval_time,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *ProbeResult) Reset() {
+	*x = ProbeResult{}
+	mi := &file_api_proto_agent_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProbeResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProbeResult) ProtoMessage() {}
+
+func (x *ProbeResult) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProbeResult.ProtoReflect.Descriptor instead.
+func (*ProbeResult) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *ProbeResult) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *ProbeResult) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ProbeResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *ProbeResult) GetLatency() float64 {
+	if x != nil {
+		return x.Latency
+	}
+	return 0
+}
+
+func (x *ProbeResult) GetPacketLoss() float64 {
+	if x != nil {
+		return x.PacketLoss
+	}
+	return 0
+}
+
+func (x *ProbeResult) GetPingRttAvg() float64 {
+	if x != nil {
+		return x.PingRttAvg
+	}
+	return 0
+}
+
+func (x *ProbeResult) GetPingRttMin() float64 {
+	if x != nil {
+		return x.PingRttMin
+	}
+	return 0
+}
+
+func (x *ProbeResult) GetPingRttMax() float64 {
+	if x != nil {
+		return x.PingRttMax
+	}
+	return 0
+}
+
+func (x *ProbeResult) GetPingStddev() float64 {
+	if x != nil {
+		return x.PingStddev
+	}
+	return 0
+}
+
+func (x *ProbeResult) GetPingPacketsSent() int32 {
+	if x != nil {
+		return x.PingPacketsSent
+	}
+	return 0
+}
+
+func (x *ProbeResult) GetPingPacketsRecv() int32 {
+	if x != nil {
+		return x.PingPacketsRecv
+	}
+	return 0
+}
+
+func (x *ProbeResult) GetTcpConnectTime() float64 {
+	if x != nil {
+		return x.TcpConnectTime
+	}
+	return 0
+}
+
+func (x *ProbeResult) GetUdpWriteTime() float64 {
+	if x != nil {
+		return x.UdpWriteTime
+	}
+	return 0
+}
+
+func (x *ProbeResult) GetUdpReadTime() float64 {
+	if x != nil {
+		return x.UdpReadTime
+	}
+	return 0
+}
+
+func (x *ProbeResult) GetHttpStatusCode() int32 {
+	if x != nil {
+		return x.HttpStatusCode
+	}
+	return 0
+}
+
+func (x *ProbeResult) GetHttpResponseTime() float64 {
+	if x != nil {
+		return x.HttpResponseTime
+	}
+	return 0
+}
+
+func (x *ProbeResult) GetHttpContentLength() int64 {
+	if x != nil {
+		return x.HttpContentLength
+	}
+	return 0
+}
+
+func (x *ProbeResult) GetResponseBody() []byte {
+	if x != nil {
+		return x.ResponseBody
+	}
+	return nil
+}
+
+func (x *ProbeResult) GetResponseHeaders() map[string]string {
+	if x != nil {
+		return x.ResponseHeaders
+	}
+	return nil
+}
+
+func (x *ProbeResult) GetDnsLookupTime() float64 {
+	if x != nil {
+		return x.DnsLookupTime
+	}
+	return 0
+}
+
+func (x *ProbeResult) GetTcpConnectTimeHttp() float64 {
+	if x != nil {
+		return x.TcpConnectTimeHttp
+	}
+	return 0
+}
+
+func (x *ProbeResult) GetTlsHandshakeTime() float64 {
+	if x != nil {
+		return x.TlsHandshakeTime
+	}
+	return 0
+}
+
+func (x *ProbeResult) GetTtfb() float64 {
+	if x != nil {
+		return x.Ttfb
+	}
+	return 0
+}
+
+func (x *ProbeResult) GetContentTransferTime() float64 {
+	if x != nil {
+		return x.ContentTransferTime
+	}
+	return 0
+}
+
+func (x *ProbeResult) GetTlsVersion() string {
+	if x != nil {
+		return x.TlsVersion
+	}
+	return ""
+}
+
+func (x *ProbeResult) GetTlsCipherSuite() string {
+	if x != nil {
+		return x.TlsCipherSuite
+	}
+	return ""
+}
+
+func (x *ProbeResult) GetSslCertNotAfter() int64 {
+	if x != nil {
+		return x.SslCertNotAfter
+	}
+	return 0
+}
+
+func (x *ProbeResult) GetRedirectCount() int32 {
+	if x != nil {
+		return x.RedirectCount
+	}
+	return 0
+}
+
+func (x *ProbeResult) GetRedirectTime() float64 {
+	if x != nil {
+		return x.RedirectTime
+	}
+	return 0
+}
+
+func (x *ProbeResult) GetFinalUrl() string {
+	if x != nil {
+		return x.FinalUrl
+	}
+	return ""
+}
+
+func (x *ProbeResult) GetResponseHeaderBytes() int32 {
+	if x != nil {
+		return x.ResponseHeaderBytes
+	}
+	return 0
+}
+
+func (x *ProbeResult) GetResponseBodyBytes() int32 {
+	if x != nil {
+		return x.ResponseBodyBytes
+	}
+	return 0
+}
+
+func (x *ProbeResult) GetAssertionSuccess() bool {
+	if x != nil {
+		return x.AssertionSuccess
+	}
+	return false
+}
+
+func (x *ProbeResult) GetAssertionResults() []*ProbeAssertionResult {
+	if x != nil {
+		return x.AssertionResults
+	}
+	return nil
+}
+
+func (x *ProbeResult) GetAssertionPassCount() int32 {
+	if x != nil {
+		return x.AssertionPassCount
+	}
+	return 0
+}
+
+func (x *ProbeResult) GetAssertionFailCount() int32 {
+	if x != nil {
+		return x.AssertionFailCount
+	}
+	return 0
+}
+
+func (x *ProbeResult) GetAssertionEvalTime() float64 {
+	if x != nil {
+		return x.AssertionEvalTime
+	}
+	return 0
+}
+
+type ProbeAssertionResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Success       bool                   `protobuf:"varint,2,opt,name=success,proto3" json:"success,omitempty"`
+	Actual        string                 `protobuf:"bytes,3,opt,name=actual,proto3" json:"actual,omitempty"`
+	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProbeAssertionResult) Reset() {
+	*x = ProbeAssertionResult{}
+	mi := &file_api_proto_agent_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProbeAssertionResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProbeAssertionResult) ProtoMessage() {}
+
+func (x *ProbeAssertionResult) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProbeAssertionResult.ProtoReflect.Descriptor instead.
+func (*ProbeAssertionResult) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *ProbeAssertionResult) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ProbeAssertionResult) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ProbeAssertionResult) GetActual() string {
+	if x != nil {
+		return x.Actual
+	}
+	return ""
+}
+
+func (x *ProbeAssertionResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// ========== HTTP 代理 ==========
+type HttpProxyRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	Method        string                 `protobuf:"bytes,2,opt,name=method,proto3" json:"method,omitempty"`
+	Url           string                 `protobuf:"bytes,3,opt,name=url,proto3" json:"url,omitempty"`
+	Headers       map[string]string      `protobuf:"bytes,4,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Body          []byte                 `protobuf:"bytes,5,opt,name=body,proto3" json:"body,omitempty"`
+	Timeout       int32                  `protobuf:"varint,6,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HttpProxyRequest) Reset() {
+	*x = HttpProxyRequest{}
+	mi := &file_api_proto_agent_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HttpProxyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HttpProxyRequest) ProtoMessage() {}
+
+func (x *HttpProxyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HttpProxyRequest.ProtoReflect.Descriptor instead.
+func (*HttpProxyRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *HttpProxyRequest) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *HttpProxyRequest) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *HttpProxyRequest) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *HttpProxyRequest) GetHeaders() map[string]string {
+	if x != nil {
+		return x.Headers
+	}
+	return nil
+}
+
+func (x *HttpProxyRequest) GetBody() []byte {
+	if x != nil {
+		return x.Body
+	}
+	return nil
+}
+
+func (x *HttpProxyRequest) GetTimeout() int32 {
+	if x != nil {
+		return x.Timeout
+	}
+	return 0
+}
+
+type HttpProxyResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	StatusCode    int32                  `protobuf:"varint,2,opt,name=status_code,json=statusCode,proto3" json:"status_code,omitempty"`
+	Headers       map[string]string      `protobuf:"bytes,3,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Body          []byte                 `protobuf:"bytes,4,opt,name=body,proto3" json:"body,omitempty"`
+	Error         string                 `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HttpProxyResponse) Reset() {
+	*x = HttpProxyResponse{}
+	mi := &file_api_proto_agent_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HttpProxyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HttpProxyResponse) ProtoMessage() {}
+
+func (x *HttpProxyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HttpProxyResponse.ProtoReflect.Descriptor instead.
+func (*HttpProxyResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *HttpProxyResponse) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *HttpProxyResponse) GetStatusCode() int32 {
+	if x != nil {
+		return x.StatusCode
+	}
+	return 0
+}
+
+func (x *HttpProxyResponse) GetHeaders() map[string]string {
+	if x != nil {
+		return x.Headers
+	}
+	return nil
+}
+
+func (x *HttpProxyResponse) GetBody() []byte {
+	if x != nil {
+		return x.Body
+	}
+	return nil
+}
+
+func (x *HttpProxyResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// ========== WebSocket 会话管理 ==========
+type WsSessionOpen struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Url           string                 `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"`
+	Headers       map[string]string      `protobuf:"bytes,3,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Params        map[string]string      `protobuf:"bytes,4,rep,name=params,proto3" json:"params,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Timeout       int32                  `protobuf:"varint,5,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	SkipVerify    bool                   `protobuf:"varint,6,opt,name=skip_verify,json=skipVerify,proto3" json:"skip_verify,omitempty"`
+	ProxyUrl      string                 `protobuf:"bytes,7,opt,name=proxy_url,json=proxyUrl,proto3" json:"proxy_url,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WsSessionOpen) Reset() {
+	*x = WsSessionOpen{}
+	mi := &file_api_proto_agent_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WsSessionOpen) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WsSessionOpen) ProtoMessage() {}
+
+func (x *WsSessionOpen) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WsSessionOpen.ProtoReflect.Descriptor instead.
+func (*WsSessionOpen) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *WsSessionOpen) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *WsSessionOpen) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *WsSessionOpen) GetHeaders() map[string]string {
+	if x != nil {
+		return x.Headers
+	}
+	return nil
+}
+
+func (x *WsSessionOpen) GetParams() map[string]string {
+	if x != nil {
+		return x.Params
+	}
+	return nil
+}
+
+func (x *WsSessionOpen) GetTimeout() int32 {
+	if x != nil {
+		return x.Timeout
+	}
+	return 0
+}
+
+func (x *WsSessionOpen) GetSkipVerify() bool {
+	if x != nil {
+		return x.SkipVerify
+	}
+	return false
+}
+
+func (x *WsSessionOpen) GetProxyUrl() string {
+	if x != nil {
+		return x.ProxyUrl
+	}
+	return ""
+}
+
+type WsSessionAction struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	ActionId      string                 `protobuf:"bytes,2,opt,name=action_id,json=actionId,proto3" json:"action_id,omitempty"`
+	ActionType    string                 `protobuf:"bytes,3,opt,name=action_type,json=actionType,proto3" json:"action_type,omitempty"`     // "send" or "receive"
+	Message       string                 `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`                             // for send
+	MessageType   int32                  `protobuf:"varint,5,opt,name=message_type,json=messageType,proto3" json:"message_type,omitempty"` // 1=text, 2=binary
+	ReadTimeout   int32                  `protobuf:"varint,6,opt,name=read_timeout,json=readTimeout,proto3" json:"read_timeout,omitempty"` // for receive (seconds)
+	ReceiveMode   string                 `protobuf:"bytes,7,opt,name=receive_mode,json=receiveMode,proto3" json:"receive_mode,omitempty"`  // "" / "single" = one message; "stream" = collect all until timeout
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WsSessionAction) Reset() {
+	*x = WsSessionAction{}
+	mi := &file_api_proto_agent_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WsSessionAction) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WsSessionAction) ProtoMessage() {}
+
+func (x *WsSessionAction) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WsSessionAction.ProtoReflect.Descriptor instead.
+func (*WsSessionAction) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *WsSessionAction) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *WsSessionAction) GetActionId() string {
+	if x != nil {
+		return x.ActionId
+	}
+	return ""
+}
+
+func (x *WsSessionAction) GetActionType() string {
+	if x != nil {
+		return x.ActionType
+	}
+	return ""
+}
+
+func (x *WsSessionAction) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *WsSessionAction) GetMessageType() int32 {
+	if x != nil {
+		return x.MessageType
+	}
+	return 0
+}
+
+func (x *WsSessionAction) GetReadTimeout() int32 {
+	if x != nil {
+		return x.ReadTimeout
+	}
+	return 0
+}
+
+func (x *WsSessionAction) GetReceiveMode() string {
+	if x != nil {
+		return x.ReceiveMode
+	}
+	return ""
+}
+
+type WsSessionClose struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WsSessionClose) Reset() {
+	*x = WsSessionClose{}
+	mi := &file_api_proto_agent_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WsSessionClose) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WsSessionClose) ProtoMessage() {}
+
+func (x *WsSessionClose) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WsSessionClose.ProtoReflect.Descriptor instead.
+func (*WsSessionClose) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *WsSessionClose) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+type WsSessionResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	ActionId      string                 `protobuf:"bytes,2,opt,name=action_id,json=actionId,proto3" json:"action_id,omitempty"`
+	ResultType    string                 `protobuf:"bytes,3,opt,name=result_type,json=resultType,proto3" json:"result_type,omitempty"` // "open", "action", "close"
+	Success       bool                   `protobuf:"varint,4,opt,name=success,proto3" json:"success,omitempty"`
+	Error         string                 `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	Latency       float64                `protobuf:"fixed64,6,opt,name=latency,proto3" json:"latency,omitempty"`                                                                         // ms
+	StatusCode    int32                  `protobuf:"varint,7,opt,name=status_code,json=statusCode,proto3" json:"status_code,omitempty"`                                                  // for open: HTTP upgrade status
+	Headers       map[string]string      `protobuf:"bytes,8,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // for open: upgrade response headers
+	ResponseBody  string                 `protobuf:"bytes,9,opt,name=response_body,json=responseBody,proto3" json:"response_body,omitempty"`                                             // for receive action
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WsSessionResult) Reset() {
+	*x = WsSessionResult{}
+	mi := &file_api_proto_agent_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WsSessionResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WsSessionResult) ProtoMessage() {}
+
+func (x *WsSessionResult) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WsSessionResult.ProtoReflect.Descriptor instead.
+func (*WsSessionResult) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *WsSessionResult) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *WsSessionResult) GetActionId() string {
+	if x != nil {
+		return x.ActionId
+	}
+	return ""
+}
+
+func (x *WsSessionResult) GetResultType() string {
+	if x != nil {
+		return x.ResultType
+	}
+	return ""
+}
+
+func (x *WsSessionResult) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *WsSessionResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *WsSessionResult) GetLatency() float64 {
+	if x != nil {
+		return x.Latency
+	}
+	return 0
+}
+
+func (x *WsSessionResult) GetStatusCode() int32 {
+	if x != nil {
+		return x.StatusCode
+	}
+	return 0
+}
+
+func (x *WsSessionResult) GetHeaders() map[string]string {
+	if x != nil {
+		return x.Headers
+	}
+	return nil
+}
+
+func (x *WsSessionResult) GetResponseBody() string {
+	if x != nil {
+		return x.ResponseBody
+	}
+	return ""
+}
+
+// ========== 流式代理 ==========
+type StreamProxyRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	Method        string                 `protobuf:"bytes,2,opt,name=method,proto3" json:"method,omitempty"`
+	Url           string                 `protobuf:"bytes,3,opt,name=url,proto3" json:"url,omitempty"`
+	Headers       map[string]string      `protobuf:"bytes,4,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Body          []byte                 `protobuf:"bytes,5,opt,name=body,proto3" json:"body,omitempty"`
+	Timeout       int32                  `protobuf:"varint,6,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamProxyRequest) Reset() {
+	*x = StreamProxyRequest{}
+	mi := &file_api_proto_agent_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamProxyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamProxyRequest) ProtoMessage() {}
+
+func (x *StreamProxyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamProxyRequest.ProtoReflect.Descriptor instead.
+func (*StreamProxyRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *StreamProxyRequest) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *StreamProxyRequest) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *StreamProxyRequest) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *StreamProxyRequest) GetHeaders() map[string]string {
+	if x != nil {
+		return x.Headers
+	}
+	return nil
+}
+
+func (x *StreamProxyRequest) GetBody() []byte {
+	if x != nil {
+		return x.Body
+	}
+	return nil
+}
+
+func (x *StreamProxyRequest) GetTimeout() int32 {
+	if x != nil {
+		return x.Timeout
+	}
+	return 0
+}
+
+type StreamProxyChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	IsFinal       bool                   `protobuf:"varint,3,opt,name=is_final,json=isFinal,proto3" json:"is_final,omitempty"`
+	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	StatusCode    int32                  `protobuf:"varint,5,opt,name=status_code,json=statusCode,proto3" json:"status_code,omitempty"`
+	Headers       map[string]string      `protobuf:"bytes,6,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamProxyChunk) Reset() {
+	*x = StreamProxyChunk{}
+	mi := &file_api_proto_agent_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamProxyChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamProxyChunk) ProtoMessage() {}
+
+func (x *StreamProxyChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamProxyChunk.ProtoReflect.Descriptor instead.
+func (*StreamProxyChunk) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *StreamProxyChunk) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *StreamProxyChunk) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *StreamProxyChunk) GetIsFinal() bool {
+	if x != nil {
+		return x.IsFinal
+	}
+	return false
+}
+
+func (x *StreamProxyChunk) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *StreamProxyChunk) GetStatusCode() int32 {
+	if x != nil {
+		return x.StatusCode
+	}
+	return 0
+}
+
+func (x *StreamProxyChunk) GetHeaders() map[string]string {
+	if x != nil {
+		return x.Headers
+	}
+	return nil
+}
+
+// ========== 自升级 ==========
+// 新二进制先通过分片文件传输上传到 path，再下发升级指令
+type AgentUpgrade struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	RequestId       string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	Version         string                 `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	Path            string                 `protobuf:"bytes,3,opt,name=path,proto3" json:"path,omitempty"`
+	Sha256          string                 `protobuf:"bytes,4,opt,name=sha256,proto3" json:"sha256,omitempty"`
+	Signature       []byte                 `protobuf:"bytes,5,opt,name=signature,proto3" json:"signature,omitempty"`                                     // ed25519 签名（对升级清单：版本、平台与 sha256）
+	RollbackTimeout int32                  `protobuf:"varint,6,opt,name=rollback_timeout,json=rollbackTimeout,proto3" json:"rollback_timeout,omitempty"` // 新版本在该时间（秒）内未重新注册则回滚
+	AllowDowngrade  bool                   `protobuf:"varint,7,opt,name=allow_downgrade,json=allowDowngrade,proto3" json:"allow_downgrade,omitempty"`    // 明确回退到较低版本，否则 Agent 拒绝降级
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *AgentUpgrade) Reset() {
+	*x = AgentUpgrade{}
+	mi := &file_api_proto_agent_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AgentUpgrade) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AgentUpgrade) ProtoMessage() {}
+
+func (x *AgentUpgrade) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AgentUpgrade.ProtoReflect.Descriptor instead.
+func (*AgentUpgrade) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *AgentUpgrade) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *AgentUpgrade) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *AgentUpgrade) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *AgentUpgrade) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
+func (x *AgentUpgrade) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+func (x *AgentUpgrade) GetRollbackTimeout() int32 {
+	if x != nil {
+		return x.RollbackTimeout
+	}
+	return 0
+}
+
+func (x *AgentUpgrade) GetAllowDowngrade() bool {
+	if x != nil {
+		return x.AllowDowngrade
+	}
+	return false
+}
+
+type AgentUpgradeResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	Success       bool                   `protobuf:"varint,2,opt,name=success,proto3" json:"success,omitempty"`
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AgentUpgradeResult) Reset() {
+	*x = AgentUpgradeResult{}
+	mi := &file_api_proto_agent_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AgentUpgradeResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AgentUpgradeResult) ProtoMessage() {}
+
+func (x *AgentUpgradeResult) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AgentUpgradeResult.ProtoReflect.Descriptor instead.
+func (*AgentUpgradeResult) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *AgentUpgradeResult) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *AgentUpgradeResult) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *AgentUpgradeResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// ========== 本地策略 ==========
+// Agent 按本地策略拒绝请求后上报的审计事件
+type PolicyViolation struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"` // 被拒绝的请求 ID，终端为 session_id
+	Kind          string                 `protobuf:"bytes,2,opt,name=kind,proto3" json:"kind,omitempty"`                            // command, file, terminal
+	Action        string                 `protobuf:"bytes,3,opt,name=action,proto3" json:"action,omitempty"`                        // 文件操作类型，如 upload、delete
+	Target        string                 `protobuf:"bytes,4,opt,name=target,proto3" json:"target,omitempty"`                        // 命令内容或文件路径
+	Reason        string                 `protobuf:"bytes,5,opt,name=reason,proto3" json:"reason,omitempty"`
+	Timestamp     int64                  `protobuf:"varint,6,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PolicyViolation) Reset() {
+	*x = PolicyViolation{}
+	mi := &file_api_proto_agent_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PolicyViolation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PolicyViolation) ProtoMessage() {}
+
+func (x *PolicyViolation) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PolicyViolation.ProtoReflect.Descriptor instead.
+func (*PolicyViolation) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{36}
 }
 
-func (x *ProbeResult) GetResponseBodyBytes() int32 {
+func (x *PolicyViolation) GetRequestId() string {
 	if x != nil {
-		return x.ResponseBodyBytes
+		return x.RequestId
 	}
-	return 0
+	return ""
 }
 
-func (x *ProbeResult) GetAssertionSuccess() bool {
+func (x *PolicyViolation) GetKind() string {
 	if x != nil {
-		return x.AssertionSuccess
+		return x.Kind
 	}
-	return false
+	return ""
 }
 
-func (x *ProbeResult) GetAssertionResults() []*ProbeAssertionResult {
+func (x *PolicyViolation) GetAction() string {
 	if x != nil {
-		return x.AssertionResults
+		return x.Action
 	}
-	return nil
+	return ""
 }
 
-func (x *ProbeResult) GetAssertionPassCount() int32 {
+func (x *PolicyViolation) GetTarget() string {
 	if x != nil {
-		return x.AssertionPassCount
+		return x.Target
 	}
-	return 0
+	return ""
 }
 
-func (x *ProbeResult) GetAssertionFailCount() int32 {
+func (x *PolicyViolation) GetReason() string {
 	if x != nil {
-		return x.AssertionFailCount
+		return x.Reason
 	}
-	return 0
+	return ""
 }
 
-func (x *ProbeResult) GetAssertionEvalTime() float64 {
+func (x *PolicyViolation) GetTimestamp() int64 {
 	if x != nil {
-		return x.AssertionEvalTime
+		return x.Timestamp
 	}
 	return 0
 }
 
-type ProbeAssertionResult struct {
+// ========== 服务发现 ==========
+// Agent 本机发现的服务全量快照，首次连接、服务变化或服务端请求时上报
+type ServiceDiscovery struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	Success       bool                   `protobuf:"varint,2,opt,name=success,proto3" json:"success,omitempty"`
-	Actual        string                 `protobuf:"bytes,3,opt,name=actual,proto3" json:"actual,omitempty"`
-	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	Services      []*DiscoveredService   `protobuf:"bytes,1,rep,name=services,proto3" json:"services,omitempty"`
+	Timestamp     int64                  `protobuf:"varint,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ProbeAssertionResult) Reset() {
-	*x = ProbeAssertionResult{}
-	mi := &file_api_proto_agent_proto_msgTypes[20]
+func (x *ServiceDiscovery) Reset() {
+	*x = ServiceDiscovery{}
+	mi := &file_api_proto_agent_proto_msgTypes[37]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ProbeAssertionResult) String() string {
+func (x *ServiceDiscovery) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ProbeAssertionResult) ProtoMessage() {}
+func (*ServiceDiscovery) ProtoMessage() {}
 
-func (x *ProbeAssertionResult) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_proto_msgTypes[20]
+func (x *ServiceDiscovery) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[37]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2109,67 +4061,53 @@ func (x *ProbeAssertionResult) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ProbeAssertionResult.ProtoReflect.Descriptor instead.
-func (*ProbeAssertionResult) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_proto_rawDescGZIP(), []int{20}
-}
-
-func (x *ProbeAssertionResult) GetName() string {
-	if x != nil {
-		return x.Name
-	}
-	return ""
-}
-
-func (x *ProbeAssertionResult) GetSuccess() bool {
-	if x != nil {
-		return x.Success
-	}
-	return false
+// Deprecated: Use ServiceDiscovery.ProtoReflect.Descriptor instead.
+func (*ServiceDiscovery) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{37}
 }
 
-func (x *ProbeAssertionResult) GetActual() string {
+func (x *ServiceDiscovery) GetServices() []*DiscoveredService {
 	if x != nil {
-		return x.Actual
+		return x.Services
 	}
-	return ""
+	return nil
 }
 
-func (x *ProbeAssertionResult) GetError() string {
+func (x *ServiceDiscovery) GetTimestamp() int64 {
 	if x != nil {
-		return x.Error
+		return x.Timestamp
 	}
-	return ""
+	return 0
 }
 
-// ========== HTTP 代理 ==========
-type HttpProxyRequest struct {
+type DiscoveredService struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
-	Method        string                 `protobuf:"bytes,2,opt,name=method,proto3" json:"method,omitempty"`
-	Url           string                 `protobuf:"bytes,3,opt,name=url,proto3" json:"url,omitempty"`
-	Headers       map[string]string      `protobuf:"bytes,4,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
-	Body          []byte                 `protobuf:"bytes,5,opt,name=body,proto3" json:"body,omitempty"`
-	Timeout       int32                  `protobuf:"varint,6,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"` // mysql, redis, kafka, nginx, jvm, exporter 等
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"` // 展示名称，如 kafka、node_exporter、JVM 主类
+	Pid           int32                  `protobuf:"varint,3,opt,name=pid,proto3" json:"pid,omitempty"`
+	Process       string                 `protobuf:"bytes,4,opt,name=process,proto3" json:"process,omitempty"` // 进程名
+	Cmdline       string                 `protobuf:"bytes,5,opt,name=cmdline,proto3" json:"cmdline,omitempty"` // 已脱敏并截断的命令行
+	Listen        []*ListenAddr          `protobuf:"bytes,6,rep,name=listen,proto3" json:"listen,omitempty"`
+	ExporterFor   string                 `protobuf:"bytes,7,opt,name=exporter_for,json=exporterFor,proto3" json:"exporter_for,omitempty"` // type=exporter 时被监控的服务类型，如 node、mysql
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *HttpProxyRequest) Reset() {
-	*x = HttpProxyRequest{}
-	mi := &file_api_proto_agent_proto_msgTypes[21]
+func (x *DiscoveredService) Reset() {
+	*x = DiscoveredService{}
+	mi := &file_api_proto_agent_proto_msgTypes[38]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *HttpProxyRequest) String() string {
+func (x *DiscoveredService) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*HttpProxyRequest) ProtoMessage() {}
+func (*DiscoveredService) ProtoMessage() {}
 
-func (x *HttpProxyRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_proto_msgTypes[21]
+func (x *DiscoveredService) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[38]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2180,79 +4118,83 @@ func (x *HttpProxyRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use HttpProxyRequest.ProtoReflect.Descriptor instead.
-func (*HttpProxyRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_proto_rawDescGZIP(), []int{21}
+// Deprecated: Use DiscoveredService.ProtoReflect.Descriptor instead.
+func (*DiscoveredService) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{38}
 }
 
-func (x *HttpProxyRequest) GetRequestId() string {
+func (x *DiscoveredService) GetType() string {
 	if x != nil {
-		return x.RequestId
+		return x.Type
 	}
 	return ""
 }
 
-func (x *HttpProxyRequest) GetMethod() string {
+func (x *DiscoveredService) GetName() string {
 	if x != nil {
-		return x.Method
+		return x.Name
 	}
 	return ""
 }
 
-func (x *HttpProxyRequest) GetUrl() string {
+func (x *DiscoveredService) GetPid() int32 {
 	if x != nil {
-		return x.Url
+		return x.Pid
+	}
+	return 0
+}
+
+func (x *DiscoveredService) GetProcess() string {
+	if x != nil {
+		return x.Process
 	}
 	return ""
 }
 
-func (x *HttpProxyRequest) GetHeaders() map[string]string {
+func (x *DiscoveredService) GetCmdline() string {
 	if x != nil {
-		return x.Headers
+		return x.Cmdline
 	}
-	return nil
+	return ""
 }
 
-func (x *HttpProxyRequest) GetBody() []byte {
+func (x *DiscoveredService) GetListen() []*ListenAddr {
 	if x != nil {
-		return x.Body
+		return x.Listen
 	}
 	return nil
 }
 
-func (x *HttpProxyRequest) GetTimeout() int32 {
+func (x *DiscoveredService) GetExporterFor() string {
 	if x != nil {
-		return x.Timeout
+		return x.ExporterFor
 	}
-	return 0
+	return ""
 }
 
-type HttpProxyResponse struct {
+type ListenAddr struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
-	StatusCode    int32                  `protobuf:"varint,2,opt,name=status_code,json=statusCode,proto3" json:"status_code,omitempty"`
-	Headers       map[string]string      `protobuf:"bytes,3,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
-	Body          []byte                 `protobuf:"bytes,4,opt,name=body,proto3" json:"body,omitempty"`
-	Error         string                 `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	Ip            string                 `protobuf:"bytes,1,opt,name=ip,proto3" json:"ip,omitempty"`
+	Port          int32                  `protobuf:"varint,2,opt,name=port,proto3" json:"port,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *HttpProxyResponse) Reset() {
-	*x = HttpProxyResponse{}
-	mi := &file_api_proto_agent_proto_msgTypes[22]
+func (x *ListenAddr) Reset() {
+	*x = ListenAddr{}
+	mi := &file_api_proto_agent_proto_msgTypes[39]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *HttpProxyResponse) String() string {
+func (x *ListenAddr) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*HttpProxyResponse) ProtoMessage() {}
+func (*ListenAddr) ProtoMessage() {}
 
-func (x *HttpProxyResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_proto_msgTypes[22]
+func (x *ListenAddr) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[39]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2263,75 +4205,47 @@ func (x *HttpProxyResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use HttpProxyResponse.ProtoReflect.Descriptor instead.
-func (*HttpProxyResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_proto_rawDescGZIP(), []int{22}
+// Deprecated: Use ListenAddr.ProtoReflect.Descriptor instead.
+func (*ListenAddr) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{39}
 }
 
-func (x *HttpProxyResponse) GetRequestId() string {
+func (x *ListenAddr) GetIp() string {
 	if x != nil {
-		return x.RequestId
+		return x.Ip
 	}
 	return ""
 }
 
-func (x *HttpProxyResponse) GetStatusCode() int32 {
+func (x *ListenAddr) GetPort() int32 {
 	if x != nil {
-		return x.StatusCode
+		return x.Port
 	}
 	return 0
 }
 
-func (x *HttpProxyResponse) GetHeaders() map[string]string {
-	if x != nil {
-		return x.Headers
-	}
-	return nil
-}
-
-func (x *HttpProxyResponse) GetBody() []byte {
-	if x != nil {
-		return x.Body
-	}
-	return nil
-}
-
-func (x *HttpProxyResponse) GetError() string {
-	if x != nil {
-		return x.Error
-	}
-	return ""
-}
-
-// ========== WebSocket 会话管理 ==========
-type WsSessionOpen struct {
+// 服务端要求立即重新扫描并上报
+type ServiceDiscoveryRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
-	Url           string                 `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"`
-	Headers       map[string]string      `protobuf:"bytes,3,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
-	Params        map[string]string      `protobuf:"bytes,4,rep,name=params,proto3" json:"params,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
-	Timeout       int32                  `protobuf:"varint,5,opt,name=timeout,proto3" json:"timeout,omitempty"`
-	SkipVerify    bool                   `protobuf:"varint,6,opt,name=skip_verify,json=skipVerify,proto3" json:"skip_verify,omitempty"`
-	ProxyUrl      string                 `protobuf:"bytes,7,opt,name=proxy_url,json=proxyUrl,proto3" json:"proxy_url,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *WsSessionOpen) Reset() {
-	*x = WsSessionOpen{}
-	mi := &file_api_proto_agent_proto_msgTypes[23]
+func (x *ServiceDiscoveryRequest) Reset() {
+	*x = ServiceDiscoveryRequest{}
+	mi := &file_api_proto_agent_proto_msgTypes[40]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *WsSessionOpen) String() string {
+func (x *ServiceDiscoveryRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*WsSessionOpen) ProtoMessage() {}
+func (*ServiceDiscoveryRequest) ProtoMessage() {}
 
-func (x *WsSessionOpen) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_proto_msgTypes[23]
+func (x *ServiceDiscoveryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[40]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2342,88 +4256,107 @@ func (x *WsSessionOpen) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use WsSessionOpen.ProtoReflect.Descriptor instead.
-func (*WsSessionOpen) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_proto_rawDescGZIP(), []int{23}
+// Deprecated: Use ServiceDiscoveryRequest.ProtoReflect.Descriptor instead.
+func (*ServiceDiscoveryRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{40}
 }
 
-func (x *WsSessionOpen) GetSessionId() string {
-	if x != nil {
-		return x.SessionId
-	}
-	return ""
+// ========== TCP 隧道 ==========
+// 双向数据均按连接ID复用在 Connect 流上，采用基于信用的流控：
+// 发送方最多发送对端窗口大小的未确认数据，接收方写出后用 TunnelAck 归还额度
+type TunnelOpen struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	ConnId         string                 `protobuf:"bytes,1,opt,name=conn_id,json=connId,proto3" json:"conn_id,omitempty"`
+	Target         string                 `protobuf:"bytes,2,opt,name=target,proto3" json:"target,omitempty"`                                        // host:port，由 Agent 所在主机发起连接
+	ConnectTimeout int32                  `protobuf:"varint,3,opt,name=connect_timeout,json=connectTimeout,proto3" json:"connect_timeout,omitempty"` // 连接超时（秒）
+	Window         int32                  `protobuf:"varint,4,opt,name=window,proto3" json:"window,omitempty"`                                       // 服务端接收窗口（字节）
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
-func (x *WsSessionOpen) GetUrl() string {
-	if x != nil {
-		return x.Url
-	}
-	return ""
+func (x *TunnelOpen) Reset() {
+	*x = TunnelOpen{}
+	mi := &file_api_proto_agent_proto_msgTypes[41]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
-func (x *WsSessionOpen) GetHeaders() map[string]string {
+func (x *TunnelOpen) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TunnelOpen) ProtoMessage() {}
+
+func (x *TunnelOpen) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[41]
 	if x != nil {
-		return x.Headers
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return nil
+	return mi.MessageOf(x)
 }
 
-func (x *WsSessionOpen) GetParams() map[string]string {
+// Deprecated: Use TunnelOpen.ProtoReflect.Descriptor instead.
+func (*TunnelOpen) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{41}
+}
+
+func (x *TunnelOpen) GetConnId() string {
 	if x != nil {
-		return x.Params
+		return x.ConnId
 	}
-	return nil
+	return ""
 }
 
-func (x *WsSessionOpen) GetTimeout() int32 {
+func (x *TunnelOpen) GetTarget() string {
 	if x != nil {
-		return x.Timeout
+		return x.Target
 	}
-	return 0
+	return ""
 }
 
-func (x *WsSessionOpen) GetSkipVerify() bool {
+func (x *TunnelOpen) GetConnectTimeout() int32 {
 	if x != nil {
-		return x.SkipVerify
+		return x.ConnectTimeout
 	}
-	return false
+	return 0
 }
 
-func (x *WsSessionOpen) GetProxyUrl() string {
+func (x *TunnelOpen) GetWindow() int32 {
 	if x != nil {
-		return x.ProxyUrl
+		return x.Window
 	}
-	return ""
+	return 0
 }
 
-type WsSessionAction struct {
+type TunnelOpenResult struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
-	ActionId      string                 `protobuf:"bytes,2,opt,name=action_id,json=actionId,proto3" json:"action_id,omitempty"`
-	ActionType    string                 `protobuf:"bytes,3,opt,name=action_type,json=actionType,proto3" json:"action_type,omitempty"`     // "send" or "receive"
-	Message       string                 `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`                             // for send
-	MessageType   int32                  `protobuf:"varint,5,opt,name=message_type,json=messageType,proto3" json:"message_type,omitempty"` // 1=text, 2=binary
-	ReadTimeout   int32                  `protobuf:"varint,6,opt,name=read_timeout,json=readTimeout,proto3" json:"read_timeout,omitempty"` // for receive (seconds)
-	ReceiveMode   string                 `protobuf:"bytes,7,opt,name=receive_mode,json=receiveMode,proto3" json:"receive_mode,omitempty"`  // "" / "single" = one message; "stream" = collect all until timeout
+	ConnId        string                 `protobuf:"bytes,1,opt,name=conn_id,json=connId,proto3" json:"conn_id,omitempty"`
+	Success       bool                   `protobuf:"varint,2,opt,name=success,proto3" json:"success,omitempty"`
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	Window        int32                  `protobuf:"varint,4,opt,name=window,proto3" json:"window,omitempty"` // Agent 接收窗口（字节）
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *WsSessionAction) Reset() {
-	*x = WsSessionAction{}
-	mi := &file_api_proto_agent_proto_msgTypes[24]
+func (x *TunnelOpenResult) Reset() {
+	*x = TunnelOpenResult{}
+	mi := &file_api_proto_agent_proto_msgTypes[42]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *WsSessionAction) String() string {
+func (x *TunnelOpenResult) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*WsSessionAction) ProtoMessage() {}
+func (*TunnelOpenResult) ProtoMessage() {}
 
-func (x *WsSessionAction) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_proto_msgTypes[24]
+func (x *TunnelOpenResult) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[42]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2434,82 +4367,62 @@ func (x *WsSessionAction) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use WsSessionAction.ProtoReflect.Descriptor instead.
-func (*WsSessionAction) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_proto_rawDescGZIP(), []int{24}
-}
-
-func (x *WsSessionAction) GetSessionId() string {
-	if x != nil {
-		return x.SessionId
-	}
-	return ""
-}
-
-func (x *WsSessionAction) GetActionId() string {
-	if x != nil {
-		return x.ActionId
-	}
-	return ""
-}
-
-func (x *WsSessionAction) GetActionType() string {
-	if x != nil {
-		return x.ActionType
-	}
-	return ""
+// Deprecated: Use TunnelOpenResult.ProtoReflect.Descriptor instead.
+func (*TunnelOpenResult) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{42}
 }
 
-func (x *WsSessionAction) GetMessage() string {
+func (x *TunnelOpenResult) GetConnId() string {
 	if x != nil {
-		return x.Message
+		return x.ConnId
 	}
 	return ""
 }
 
-func (x *WsSessionAction) GetMessageType() int32 {
+func (x *TunnelOpenResult) GetSuccess() bool {
 	if x != nil {
-		return x.MessageType
+		return x.Success
 	}
-	return 0
+	return false
 }
 
-func (x *WsSessionAction) GetReadTimeout() int32 {
+func (x *TunnelOpenResult) GetError() string {
 	if x != nil {
-		return x.ReadTimeout
+		return x.Error
 	}
-	return 0
+	return ""
 }
 
-func (x *WsSessionAction) GetReceiveMode() string {
+func (x *TunnelOpenResult) GetWindow() int32 {
 	if x != nil {
-		return x.ReceiveMode
+		return x.Window
 	}
-	return ""
+	return 0
 }
 
-type WsSessionClose struct {
+type TunnelData struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	ConnId        string                 `protobuf:"bytes,1,opt,name=conn_id,json=connId,proto3" json:"conn_id,omitempty"`
+	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *WsSessionClose) Reset() {
-	*x = WsSessionClose{}
-	mi := &file_api_proto_agent_proto_msgTypes[25]
+func (x *TunnelData) Reset() {
+	*x = TunnelData{}
+	mi := &file_api_proto_agent_proto_msgTypes[43]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *WsSessionClose) String() string {
+func (x *TunnelData) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*WsSessionClose) ProtoMessage() {}
+func (*TunnelData) ProtoMessage() {}
 
-func (x *WsSessionClose) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_proto_msgTypes[25]
+func (x *TunnelData) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[43]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2520,48 +4433,48 @@ func (x *WsSessionClose) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use WsSessionClose.ProtoReflect.Descriptor instead.
-func (*WsSessionClose) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_proto_rawDescGZIP(), []int{25}
+// Deprecated: Use TunnelData.ProtoReflect.Descriptor instead.
+func (*TunnelData) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{43}
 }
 
-func (x *WsSessionClose) GetSessionId() string {
+func (x *TunnelData) GetConnId() string {
 	if x != nil {
-		return x.SessionId
+		return x.ConnId
 	}
 	return ""
 }
 
-type WsSessionResult struct {
+func (x *TunnelData) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type TunnelAck struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
-	ActionId      string                 `protobuf:"bytes,2,opt,name=action_id,json=actionId,proto3" json:"action_id,omitempty"`
-	ResultType    string                 `protobuf:"bytes,3,opt,name=result_type,json=resultType,proto3" json:"result_type,omitempty"` // "open", "action", "close"
-	Success       bool                   `protobuf:"varint,4,opt,name=success,proto3" json:"success,omitempty"`
-	Error         string                 `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
-	Latency       float64                `protobuf:"fixed64,6,opt,name=latency,proto3" json:"latency,omitempty"`                                                                         // ms
-	StatusCode    int32                  `protobuf:"varint,7,opt,name=status_code,json=statusCode,proto3" json:"status_code,omitempty"`                                                  // for open: HTTP upgrade status
-	Headers       map[string]string      `protobuf:"bytes,8,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // for open: upgrade response headers
-	ResponseBody  string                 `protobuf:"bytes,9,opt,name=response_body,json=responseBody,proto3" json:"response_body,omitempty"`                                             // for receive action
+	ConnId        string                 `protobuf:"bytes,1,opt,name=conn_id,json=connId,proto3" json:"conn_id,omitempty"`
+	Bytes         int32                  `protobuf:"varint,2,opt,name=bytes,proto3" json:"bytes,omitempty"` // 接收方已写出的字节数
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *WsSessionResult) Reset() {
-	*x = WsSessionResult{}
-	mi := &file_api_proto_agent_proto_msgTypes[26]
+func (x *TunnelAck) Reset() {
+	*x = TunnelAck{}
+	mi := &file_api_proto_agent_proto_msgTypes[44]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *WsSessionResult) String() string {
+func (x *TunnelAck) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*WsSessionResult) ProtoMessage() {}
+func (*TunnelAck) ProtoMessage() {}
 
-func (x *WsSessionResult) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_proto_msgTypes[26]
+func (x *TunnelAck) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[44]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2572,102 +4485,102 @@ func (x *WsSessionResult) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use WsSessionResult.ProtoReflect.Descriptor instead.
-func (*WsSessionResult) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_proto_rawDescGZIP(), []int{26}
+// Deprecated: Use TunnelAck.ProtoReflect.Descriptor instead.
+func (*TunnelAck) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{44}
 }
 
-func (x *WsSessionResult) GetSessionId() string {
+func (x *TunnelAck) GetConnId() string {
 	if x != nil {
-		return x.SessionId
+		return x.ConnId
 	}
 	return ""
 }
 
-func (x *WsSessionResult) GetActionId() string {
+func (x *TunnelAck) GetBytes() int32 {
 	if x != nil {
-		return x.ActionId
+		return x.Bytes
 	}
-	return ""
+	return 0
 }
 
-func (x *WsSessionResult) GetResultType() string {
-	if x != nil {
-		return x.ResultType
-	}
-	return ""
+type TunnelClose struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ConnId        string                 `protobuf:"bytes,1,opt,name=conn_id,json=connId,proto3" json:"conn_id,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *WsSessionResult) GetSuccess() bool {
-	if x != nil {
-		return x.Success
-	}
-	return false
+func (x *TunnelClose) Reset() {
+	*x = TunnelClose{}
+	mi := &file_api_proto_agent_proto_msgTypes[45]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
-func (x *WsSessionResult) GetError() string {
-	if x != nil {
-		return x.Error
-	}
-	return ""
+func (x *TunnelClose) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-func (x *WsSessionResult) GetLatency() float64 {
+func (*TunnelClose) ProtoMessage() {}
+
+func (x *TunnelClose) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[45]
 	if x != nil {
-		return x.Latency
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return 0
+	return mi.MessageOf(x)
 }
 
-func (x *WsSessionResult) GetStatusCode() int32 {
-	if x != nil {
-		return x.StatusCode
-	}
-	return 0
+// Deprecated: Use TunnelClose.ProtoReflect.Descriptor instead.
+func (*TunnelClose) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{45}
 }
 
-func (x *WsSessionResult) GetHeaders() map[string]string {
+func (x *TunnelClose) GetConnId() string {
 	if x != nil {
-		return x.Headers
+		return x.ConnId
 	}
-	return nil
+	return ""
 }
 
-func (x *WsSessionResult) GetResponseBody() string {
+func (x *TunnelClose) GetError() string {
 	if x != nil {
-		return x.ResponseBody
+		return x.Error
 	}
 	return ""
 }
 
-// ========== 流式代理 ==========
-type StreamProxyRequest struct {
+// ========== 证书续期 ==========
+// 证书临近过期时服务端发起续期，Agent 本地生成新私钥并提交证书签名请求，私钥不离开主机
+type CertRenewRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
-	Method        string                 `protobuf:"bytes,2,opt,name=method,proto3" json:"method,omitempty"`
-	Url           string                 `protobuf:"bytes,3,opt,name=url,proto3" json:"url,omitempty"`
-	Headers       map[string]string      `protobuf:"bytes,4,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
-	Body          []byte                 `protobuf:"bytes,5,opt,name=body,proto3" json:"body,omitempty"`
-	Timeout       int32                  `protobuf:"varint,6,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	NotAfter      int64                  `protobuf:"varint,2,opt,name=not_after,json=notAfter,proto3" json:"not_after,omitempty"` // 当前证书过期时间（Unix 秒）
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *StreamProxyRequest) Reset() {
-	*x = StreamProxyRequest{}
-	mi := &file_api_proto_agent_proto_msgTypes[27]
+func (x *CertRenewRequest) Reset() {
+	*x = CertRenewRequest{}
+	mi := &file_api_proto_agent_proto_msgTypes[46]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *StreamProxyRequest) String() string {
+func (x *CertRenewRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*StreamProxyRequest) ProtoMessage() {}
+func (*CertRenewRequest) ProtoMessage() {}
 
-func (x *StreamProxyRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_proto_msgTypes[27]
+func (x *CertRenewRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[46]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2678,80 +4591,103 @@ func (x *StreamProxyRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use StreamProxyRequest.ProtoReflect.Descriptor instead.
-func (*StreamProxyRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_proto_rawDescGZIP(), []int{27}
+// Deprecated: Use CertRenewRequest.ProtoReflect.Descriptor instead.
+func (*CertRenewRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{46}
 }
 
-func (x *StreamProxyRequest) GetRequestId() string {
+func (x *CertRenewRequest) GetRequestId() string {
 	if x != nil {
 		return x.RequestId
 	}
 	return ""
 }
 
-func (x *StreamProxyRequest) GetMethod() string {
+func (x *CertRenewRequest) GetNotAfter() int64 {
 	if x != nil {
-		return x.Method
+		return x.NotAfter
 	}
-	return ""
+	return 0
 }
 
-func (x *StreamProxyRequest) GetUrl() string {
-	if x != nil {
-		return x.Url
-	}
-	return ""
+type CertSigningRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	CsrPem        []byte                 `protobuf:"bytes,2,opt,name=csr_pem,json=csrPem,proto3" json:"csr_pem,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *StreamProxyRequest) GetHeaders() map[string]string {
+func (x *CertSigningRequest) Reset() {
+	*x = CertSigningRequest{}
+	mi := &file_api_proto_agent_proto_msgTypes[47]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CertSigningRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CertSigningRequest) ProtoMessage() {}
+
+func (x *CertSigningRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[47]
 	if x != nil {
-		return x.Headers
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return nil
+	return mi.MessageOf(x)
 }
 
-func (x *StreamProxyRequest) GetBody() []byte {
+// Deprecated: Use CertSigningRequest.ProtoReflect.Descriptor instead.
+func (*CertSigningRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{47}
+}
+
+func (x *CertSigningRequest) GetRequestId() string {
 	if x != nil {
-		return x.Body
+		return x.RequestId
 	}
-	return nil
+	return ""
 }
 
-func (x *StreamProxyRequest) GetTimeout() int32 {
+func (x *CertSigningRequest) GetCsrPem() []byte {
 	if x != nil {
-		return x.Timeout
+		return x.CsrPem
 	}
-	return 0
+	return nil
 }
 
-type StreamProxyChunk struct {
+type CertRenewResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
-	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
-	IsFinal       bool                   `protobuf:"varint,3,opt,name=is_final,json=isFinal,proto3" json:"is_final,omitempty"`
-	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
-	StatusCode    int32                  `protobuf:"varint,5,opt,name=status_code,json=statusCode,proto3" json:"status_code,omitempty"`
-	Headers       map[string]string      `protobuf:"bytes,6,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Success       bool                   `protobuf:"varint,2,opt,name=success,proto3" json:"success,omitempty"`
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	CertPem       []byte                 `protobuf:"bytes,4,opt,name=cert_pem,json=certPem,proto3" json:"cert_pem,omitempty"`
+	CaPem         []byte                 `protobuf:"bytes,5,opt,name=ca_pem,json=caPem,proto3" json:"ca_pem,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *StreamProxyChunk) Reset() {
-	*x = StreamProxyChunk{}
-	mi := &file_api_proto_agent_proto_msgTypes[28]
+func (x *CertRenewResponse) Reset() {
+	*x = CertRenewResponse{}
+	mi := &file_api_proto_agent_proto_msgTypes[48]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *StreamProxyChunk) String() string {
+func (x *CertRenewResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*StreamProxyChunk) ProtoMessage() {}
+func (*CertRenewResponse) ProtoMessage() {}
 
-func (x *StreamProxyChunk) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_proto_msgTypes[28]
+func (x *CertRenewResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[48]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2762,49 +4698,95 @@ func (x *StreamProxyChunk) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use StreamProxyChunk.ProtoReflect.Descriptor instead.
-func (*StreamProxyChunk) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_proto_rawDescGZIP(), []int{28}
+// Deprecated: Use CertRenewResponse.ProtoReflect.Descriptor instead.
+func (*CertRenewResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{48}
 }
 
-func (x *StreamProxyChunk) GetRequestId() string {
+func (x *CertRenewResponse) GetRequestId() string {
 	if x != nil {
 		return x.RequestId
 	}
 	return ""
 }
 
-func (x *StreamProxyChunk) GetData() []byte {
+func (x *CertRenewResponse) GetSuccess() bool {
 	if x != nil {
-		return x.Data
+		return x.Success
+	}
+	return false
+}
+
+func (x *CertRenewResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *CertRenewResponse) GetCertPem() []byte {
+	if x != nil {
+		return x.CertPem
 	}
 	return nil
 }
 
-func (x *StreamProxyChunk) GetIsFinal() bool {
+func (x *CertRenewResponse) GetCaPem() []byte {
 	if x != nil {
-		return x.IsFinal
+		return x.CaPem
 	}
-	return false
+	return nil
 }
 
-func (x *StreamProxyChunk) GetError() string {
+// ========== 副本间转发 ==========
+type RelayFrame struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	HostId        uint32                 `protobuf:"varint,1,opt,name=host_id,json=hostId,proto3" json:"host_id,omitempty"` // 目标主机，仅首帧有效
+	Message       *ServerMessage         `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`              // 转发给Agent的消息，首帧可为空
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RelayFrame) Reset() {
+	*x = RelayFrame{}
+	mi := &file_api_proto_agent_proto_msgTypes[49]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RelayFrame) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RelayFrame) ProtoMessage() {}
+
+func (x *RelayFrame) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[49]
 	if x != nil {
-		return x.Error
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return ""
+	return mi.MessageOf(x)
 }
 
-func (x *StreamProxyChunk) GetStatusCode() int32 {
+// Deprecated: Use RelayFrame.ProtoReflect.Descriptor instead.
+func (*RelayFrame) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{49}
+}
+
+func (x *RelayFrame) GetHostId() uint32 {
 	if x != nil {
-		return x.StatusCode
+		return x.HostId
 	}
 	return 0
 }
 
-func (x *StreamProxyChunk) GetHeaders() map[string]string {
+func (x *RelayFrame) GetMessage() *ServerMessage {
 	if x != nil {
-		return x.Headers
+		return x.Message
 	}
 	return nil
 }
@@ -2814,7 +4796,8 @@ var File_api_proto_agent_proto protoreflect.FileDescriptor
 const file_api_proto_agent_proto_rawDesc = "" +
 	"\n" +
 	"\x15api/proto/agent.proto\x12\n" +
-	"agentproto\"\xa8\x05\n" +
+	"agentproto\"\x85\n" +
+	"\n" +
 	"\fAgentMessage\x129\n" +
 	"\bregister\x18\x01 \x01(\v2\x1b.agentproto.RegisterRequestH\x00R\bregister\x12<\n" +
 	"\theartbeat\x18\x02 \x01(\v2\x1c.agentproto.HeartbeatRequestH\x00R\theartbeat\x12=\n" +
@@ -2829,8 +4812,20 @@ const file_api_proto_agent_proto_rawDesc = "" +
 	"\x13http_proxy_response\x18\b \x01(\v2\x1d.agentproto.HttpProxyResponseH\x00R\x11httpProxyResponse\x12I\n" +
 	"\x11ws_session_result\x18\t \x01(\v2\x1b.agentproto.WsSessionResultH\x00R\x0fwsSessionResult\x12L\n" +
 	"\x12stream_proxy_chunk\x18\n" +
-	" \x01(\v2\x1c.agentproto.StreamProxyChunkH\x00R\x10streamProxyChunkB\t\n" +
-	"\apayload\"\xcb\a\n" +
+	" \x01(\v2\x1c.agentproto.StreamProxyChunkH\x00R\x10streamProxyChunk\x12?\n" +
+	"\n" +
+	"cmd_output\x18\v \x01(\v2\x1e.agentproto.CommandOutputChunkH\x00R\tcmdOutput\x12G\n" +
+	"\x0eupgrade_result\x18\f \x01(\v2\x1e.agentproto.AgentUpgradeResultH\x00R\rupgradeResult\x12H\n" +
+	"\x10policy_violation\x18\r \x01(\v2\x1b.agentproto.PolicyViolationH\x00R\x0fpolicyViolation\x12K\n" +
+	"\x11service_discovery\x18\x0e \x01(\v2\x1c.agentproto.ServiceDiscoveryH\x00R\x10serviceDiscovery\x12L\n" +
+	"\x12tunnel_open_result\x18\x0f \x01(\v2\x1c.agentproto.TunnelOpenResultH\x00R\x10tunnelOpenResult\x129\n" +
+	"\vtunnel_data\x18\x10 \x01(\v2\x16.agentproto.TunnelDataH\x00R\n" +
+	"tunnelData\x126\n" +
+	"\n" +
+	"tunnel_ack\x18\x11 \x01(\v2\x15.agentproto.TunnelAckH\x00R\ttunnelAck\x12<\n" +
+	"\ftunnel_close\x18\x12 \x01(\v2\x17.agentproto.TunnelCloseH\x00R\vtunnelClose\x12;\n" +
+	"\bcert_csr\x18\x13 \x01(\v2\x1e.agentproto.CertSigningRequestH\x00R\acertCsrB\t\n" +
+	"\apayload\"\xa7\f\n" +
 	"\rServerMessage\x12A\n" +
 	"\fregister_ack\x18\x01 \x01(\v2\x1c.agentproto.RegisterResponseH\x00R\vregisterAck\x12D\n" +
 	"\rheartbeat_ack\x18\x02 \x01(\v2\x1d.agentproto.HeartbeatResponseH\x00R\fheartbeatAck\x127\n" +
@@ -2850,26 +4845,86 @@ const file_api_proto_agent_proto_rawDesc = "" +
 	"\x0fws_session_open\x18\v \x01(\v2\x19.agentproto.WsSessionOpenH\x00R\rwsSessionOpen\x12I\n" +
 	"\x11ws_session_action\x18\f \x01(\v2\x1b.agentproto.WsSessionActionH\x00R\x0fwsSessionAction\x12F\n" +
 	"\x10ws_session_close\x18\r \x01(\v2\x1a.agentproto.WsSessionCloseH\x00R\x0ewsSessionClose\x12R\n" +
-	"\x14stream_proxy_request\x18\x0e \x01(\v2\x1e.agentproto.StreamProxyRequestH\x00R\x12streamProxyRequestB\t\n" +
-	"\apayload\"\x98\x01\n" +
+	"\x14stream_proxy_request\x18\x0e \x01(\v2\x1e.agentproto.StreamProxyRequestH\x00R\x12streamProxyRequest\x12:\n" +
+	"\n" +
+	"cmd_cancel\x18\x0f \x01(\v2\x19.agentproto.CommandCancelH\x00R\tcmdCancel\x12?\n" +
+	"\ragent_upgrade\x18\x10 \x01(\v2\x18.agentproto.AgentUpgradeH\x00R\fagentUpgrade\x12R\n" +
+	"\x11discovery_request\x18\x11 \x01(\v2#.agentproto.ServiceDiscoveryRequestH\x00R\x10discoveryRequest\x129\n" +
+	"\vtunnel_open\x18\x12 \x01(\v2\x16.agentproto.TunnelOpenH\x00R\n" +
+	"tunnelOpen\x129\n" +
+	"\vtunnel_data\x18\x13 \x01(\v2\x16.agentproto.TunnelDataH\x00R\n" +
+	"tunnelData\x126\n" +
+	"\n" +
+	"tunnel_ack\x18\x14 \x01(\v2\x15.agentproto.TunnelAckH\x00R\ttunnelAck\x12<\n" +
+	"\ftunnel_close\x18\x15 \x01(\v2\x17.agentproto.TunnelCloseH\x00R\vtunnelClose\x12L\n" +
+	"\x12cert_renew_request\x18\x16 \x01(\v2\x1c.agentproto.CertRenewRequestH\x00R\x10certRenewRequest\x12O\n" +
+	"\x13cert_renew_response\x18\x17 \x01(\v2\x1d.agentproto.CertRenewResponseH\x00R\x11certRenewResponseB\t\n" +
+	"\apayload\"\xfd\x01\n" +
 	"\x0fRegisterRequest\x12\x19\n" +
 	"\bagent_id\x18\x01 \x01(\tR\aagentId\x12\x1a\n" +
 	"\bhostname\x18\x02 \x01(\tR\bhostname\x12\x0e\n" +
 	"\x02os\x18\x03 \x01(\tR\x02os\x12\x12\n" +
 	"\x04arch\x18\x04 \x01(\tR\x04arch\x12\x18\n" +
 	"\aversion\x18\x05 \x01(\tR\aversion\x12\x10\n" +
-	"\x03ips\x18\x06 \x03(\tR\x03ips\"u\n" +
+	"\x03ips\x18\x06 \x03(\tR\x03ips\x12\x1d\n" +
+	"\n" +
+	"upgrade_id\x18\a \x01(\tR\tupgradeId\x12\x1f\n" +
+	"\vrolled_back\x18\b \x01(\bR\n" +
+	"rolledBack\x12#\n" +
+	"\rupgrade_error\x18\t \x01(\tR\fupgradeError\"u\n" +
 	"\x10RegisterResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
 	"\amessage\x18\x02 \x01(\tR\amessage\x12-\n" +
-	"\x12heartbeat_interval\x18\x03 \x01(\x05R\x11heartbeatInterval\"\xb3\x01\n" +
+	"\x12heartbeat_interval\x18\x03 \x01(\x05R\x11heartbeatInterval\"\xe7\x04\n" +
 	"\x10HeartbeatRequest\x12\x19\n" +
 	"\bagent_id\x18\x01 \x01(\tR\aagentId\x12\x1b\n" +
 	"\tcpu_usage\x18\x02 \x01(\x01R\bcpuUsage\x12!\n" +
 	"\fmemory_usage\x18\x03 \x01(\x01R\vmemoryUsage\x12\x1d\n" +
 	"\n" +
 	"disk_usage\x18\x04 \x01(\x01R\tdiskUsage\x12%\n" +
-	"\x0euptime_seconds\x18\x05 \x01(\x03R\ruptimeSeconds\"-\n" +
+	"\x0euptime_seconds\x18\x05 \x01(\x03R\ruptimeSeconds\x12\x14\n" +
+	"\x05load1\x18\x06 \x01(\x01R\x05load1\x12\x14\n" +
+	"\x05load5\x18\a \x01(\x01R\x05load5\x12\x16\n" +
+	"\x06load15\x18\b \x01(\x01R\x06load15\x12!\n" +
+	"\fmemory_total\x18\t \x01(\x04R\vmemoryTotal\x12\x1f\n" +
+	"\vmemory_used\x18\n" +
+	" \x01(\x04R\n" +
+	"memoryUsed\x12+\n" +
+	"\x05disks\x18\v \x03(\v2\x15.agentproto.DiskStatsR\x05disks\x124\n" +
+	"\bnetworks\x18\f \x03(\v2\x18.agentproto.NetworkStatsR\bnetworks\x12J\n" +
+	"\n" +
+	"tcp_states\x18\r \x03(\v2+.agentproto.HeartbeatRequest.TcpStatesEntryR\ttcpStates\x12=\n" +
+	"\rtop_processes\x18\x0e \x03(\v2\x18.agentproto.ProcessStatsR\ftopProcesses\x1a<\n" +
+	"\x0eTcpStatesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x05R\x05value:\x028\x01\"\xd4\x01\n" +
+	"\tDiskStats\x12\x1e\n" +
+	"\n" +
+	"mountpoint\x18\x01 \x01(\tR\n" +
+	"mountpoint\x12\x16\n" +
+	"\x06device\x18\x02 \x01(\tR\x06device\x12\x16\n" +
+	"\x06fstype\x18\x03 \x01(\tR\x06fstype\x12\x1f\n" +
+	"\vtotal_bytes\x18\x04 \x01(\x04R\n" +
+	"totalBytes\x12\x1d\n" +
+	"\n" +
+	"used_bytes\x18\x05 \x01(\x04R\tusedBytes\x12\x14\n" +
+	"\x05usage\x18\x06 \x01(\x01R\x05usage\x12!\n" +
+	"\finodes_usage\x18\a \x01(\x01R\vinodesUsage\"\xe4\x01\n" +
+	"\fNetworkStats\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x19\n" +
+	"\brx_bytes\x18\x02 \x01(\x04R\arxBytes\x12\x19\n" +
+	"\btx_bytes\x18\x03 \x01(\x04R\atxBytes\x12'\n" +
+	"\x10rx_bytes_per_sec\x18\x04 \x01(\x01R\rrxBytesPerSec\x12'\n" +
+	"\x10tx_bytes_per_sec\x18\x05 \x01(\x01R\rtxBytesPerSec\x12\x1b\n" +
+	"\trx_errors\x18\x06 \x01(\x04R\brxErrors\x12\x1b\n" +
+	"\ttx_errors\x18\a \x01(\x04R\btxErrors\"\x93\x01\n" +
+	"\fProcessStats\x12\x10\n" +
+	"\x03pid\x18\x01 \x01(\x05R\x03pid\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x1b\n" +
+	"\tcpu_usage\x18\x03 \x01(\x01R\bcpuUsage\x12\x1d\n" +
+	"\n" +
+	"memory_rss\x18\x04 \x01(\x04R\tmemoryRss\x12!\n" +
+	"\fmemory_usage\x18\x05 \x01(\x01R\vmemoryUsage\"-\n" +
 	"\x11HeartbeatResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\"U\n" +
 	"\fTerminalOpen\x12\x1d\n" +
@@ -2892,20 +4947,41 @@ const file_api_proto_agent_proto_rawDesc = "" +
 	"\x04rows\x18\x03 \x01(\rR\x04rows\".\n" +
 	"\rTerminalClose\x12\x1d\n" +
 	"\n" +
-	"session_id\x18\x01 \x01(\tR\tsessionId\"\x88\x01\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\"\xe2\x02\n" +
 	"\vFileRequest\x12\x1d\n" +
 	"\n" +
 	"request_id\x18\x01 \x01(\tR\trequestId\x12\x16\n" +
 	"\x06action\x18\x02 \x01(\tR\x06action\x12\x12\n" +
 	"\x04path\x18\x03 \x01(\tR\x04path\x12\x12\n" +
 	"\x04data\x18\x04 \x01(\fR\x04data\x12\x1a\n" +
-	"\bfilename\x18\x05 \x01(\tR\bfilename\"f\n" +
+	"\bfilename\x18\x05 \x01(\tR\bfilename\x12\x16\n" +
+	"\x06offset\x18\x06 \x01(\x03R\x06offset\x12\x12\n" +
+	"\x04size\x18\a \x01(\x03R\x04size\x12\x16\n" +
+	"\x06sha256\x18\b \x01(\tR\x06sha256\x12\x12\n" +
+	"\x04mode\x18\t \x01(\rR\x04mode\x12\x14\n" +
+	"\x05owner\x18\n" +
+	" \x01(\tR\x05owner\x12\x14\n" +
+	"\x05group\x18\v \x01(\tR\x05group\x12\x19\n" +
+	"\bmod_time\x18\f \x01(\x03R\amodTime\x12\x1f\n" +
+	"\vtransfer_id\x18\r \x01(\tR\n" +
+	"transferId\x12\x18\n" +
+	"\aextract\x18\x0e \x01(\bR\aextract\"\xb0\x02\n" +
 	"\tFileChunk\x12\x1d\n" +
 	"\n" +
 	"request_id\x18\x01 \x01(\tR\trequestId\x12\x12\n" +
 	"\x04data\x18\x02 \x01(\fR\x04data\x12\x10\n" +
 	"\x03eof\x18\x03 \x01(\bR\x03eof\x12\x14\n" +
-	"\x05error\x18\x04 \x01(\tR\x05error\"q\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\x12\x16\n" +
+	"\x06offset\x18\x05 \x01(\x03R\x06offset\x12\x12\n" +
+	"\x04size\x18\x06 \x01(\x03R\x04size\x12\x16\n" +
+	"\x06sha256\x18\a \x01(\tR\x06sha256\x12\x12\n" +
+	"\x04mode\x18\b \x01(\rR\x04mode\x12\x14\n" +
+	"\x05owner\x18\t \x01(\tR\x05owner\x12\x14\n" +
+	"\x05group\x18\n" +
+	" \x01(\tR\x05group\x12\x19\n" +
+	"\bmod_time\x18\v \x01(\x03R\amodTime\x12\x15\n" +
+	"\x06is_dir\x18\f \x01(\bR\x05isDir\x12\x12\n" +
+	"\x04path\x18\r \x01(\tR\x04path\"q\n" +
 	"\x0eFileListResult\x12\x1d\n" +
 	"\n" +
 	"request_id\x18\x01 \x01(\tR\trequestId\x12*\n" +
@@ -2916,19 +4992,38 @@ const file_api_proto_agent_proto_rawDesc = "" +
 	"\x04size\x18\x02 \x01(\x03R\x04size\x12\x12\n" +
 	"\x04mode\x18\x03 \x01(\tR\x04mode\x12\x19\n" +
 	"\bmod_time\x18\x04 \x01(\x03R\amodTime\x12\x15\n" +
-	"\x06is_dir\x18\x05 \x01(\bR\x05isDir\"c\n" +
+	"\x06is_dir\x18\x05 \x01(\bR\x05isDir\"\xa5\x01\n" +
 	"\x0eCommandRequest\x12\x1d\n" +
 	"\n" +
 	"request_id\x18\x01 \x01(\tR\trequestId\x12\x18\n" +
 	"\acommand\x18\x02 \x01(\tR\acommand\x12\x18\n" +
-	"\atimeout\x18\x03 \x01(\x05R\atimeout\"\x91\x01\n" +
+	"\atimeout\x18\x03 \x01(\x05R\atimeout\x12\x16\n" +
+	"\x06stream\x18\x04 \x01(\bR\x06stream\x12(\n" +
+	"\x10max_output_bytes\x18\x05 \x01(\x03R\x0emaxOutputBytes\"\x91\x01\n" +
 	"\rCommandResult\x12\x1d\n" +
 	"\n" +
 	"request_id\x18\x01 \x01(\tR\trequestId\x12\x1b\n" +
 	"\texit_code\x18\x02 \x01(\x05R\bexitCode\x12\x16\n" +
 	"\x06stdout\x18\x03 \x01(\tR\x06stdout\x12\x16\n" +
 	"\x06stderr\x18\x04 \x01(\tR\x06stderr\x12\x14\n" +
-	"\x05error\x18\x05 \x01(\tR\x05error\"\xbe\x06\n" +
+	"\x05error\x18\x05 \x01(\tR\x05error\".\n" +
+	"\rCommandCancel\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\"\xa5\x02\n" +
+	"\x12CommandOutputChunk\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\x12\x10\n" +
+	"\x03seq\x18\x02 \x01(\x03R\x03seq\x12\x16\n" +
+	"\x06stream\x18\x03 \x01(\tR\x06stream\x12\x12\n" +
+	"\x04data\x18\x04 \x01(\fR\x04data\x12\x10\n" +
+	"\x03eof\x18\x05 \x01(\bR\x03eof\x12\x1b\n" +
+	"\texit_code\x18\x06 \x01(\x05R\bexitCode\x12\x16\n" +
+	"\x06signal\x18\a \x01(\tR\x06signal\x12\x14\n" +
+	"\x05error\x18\b \x01(\tR\x05error\x12\x1c\n" +
+	"\ttruncated\x18\t \x01(\bR\ttruncated\x12\x1a\n" +
+	"\bcanceled\x18\n" +
+	" \x01(\bR\bcanceled\x12\x1b\n" +
+	"\ttimed_out\x18\v \x01(\bR\btimedOut\"\xbe\x06\n" +
 	"\fProbeRequest\x12\x1d\n" +
 	"\n" +
 	"request_id\x18\x01 \x01(\tR\trequestId\x12\x1d\n" +
@@ -3113,9 +5208,90 @@ const file_api_proto_agent_proto_rawDesc = "" +
 	"\aheaders\x18\x06 \x03(\v2).agentproto.StreamProxyChunk.HeadersEntryR\aheaders\x1a:\n" +
 	"\fHeadersEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x012N\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xe5\x01\n" +
+	"\fAgentUpgrade\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\tR\aversion\x12\x12\n" +
+	"\x04path\x18\x03 \x01(\tR\x04path\x12\x16\n" +
+	"\x06sha256\x18\x04 \x01(\tR\x06sha256\x12\x1c\n" +
+	"\tsignature\x18\x05 \x01(\fR\tsignature\x12)\n" +
+	"\x10rollback_timeout\x18\x06 \x01(\x05R\x0frollbackTimeout\x12'\n" +
+	"\x0fallow_downgrade\x18\a \x01(\bR\x0eallowDowngrade\"c\n" +
+	"\x12AgentUpgradeResult\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\x12\x18\n" +
+	"\asuccess\x18\x02 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\"\xaa\x01\n" +
+	"\x0fPolicyViolation\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\x12\x12\n" +
+	"\x04kind\x18\x02 \x01(\tR\x04kind\x12\x16\n" +
+	"\x06action\x18\x03 \x01(\tR\x06action\x12\x16\n" +
+	"\x06target\x18\x04 \x01(\tR\x06target\x12\x16\n" +
+	"\x06reason\x18\x05 \x01(\tR\x06reason\x12\x1c\n" +
+	"\ttimestamp\x18\x06 \x01(\x03R\ttimestamp\"k\n" +
+	"\x10ServiceDiscovery\x129\n" +
+	"\bservices\x18\x01 \x03(\v2\x1d.agentproto.DiscoveredServiceR\bservices\x12\x1c\n" +
+	"\ttimestamp\x18\x02 \x01(\x03R\ttimestamp\"\xd4\x01\n" +
+	"\x11DiscoveredService\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x10\n" +
+	"\x03pid\x18\x03 \x01(\x05R\x03pid\x12\x18\n" +
+	"\aprocess\x18\x04 \x01(\tR\aprocess\x12\x18\n" +
+	"\acmdline\x18\x05 \x01(\tR\acmdline\x12.\n" +
+	"\x06listen\x18\x06 \x03(\v2\x16.agentproto.ListenAddrR\x06listen\x12!\n" +
+	"\fexporter_for\x18\a \x01(\tR\vexporterFor\"0\n" +
+	"\n" +
+	"ListenAddr\x12\x0e\n" +
+	"\x02ip\x18\x01 \x01(\tR\x02ip\x12\x12\n" +
+	"\x04port\x18\x02 \x01(\x05R\x04port\"\x19\n" +
+	"\x17ServiceDiscoveryRequest\"~\n" +
+	"\n" +
+	"TunnelOpen\x12\x17\n" +
+	"\aconn_id\x18\x01 \x01(\tR\x06connId\x12\x16\n" +
+	"\x06target\x18\x02 \x01(\tR\x06target\x12'\n" +
+	"\x0fconnect_timeout\x18\x03 \x01(\x05R\x0econnectTimeout\x12\x16\n" +
+	"\x06window\x18\x04 \x01(\x05R\x06window\"s\n" +
+	"\x10TunnelOpenResult\x12\x17\n" +
+	"\aconn_id\x18\x01 \x01(\tR\x06connId\x12\x18\n" +
+	"\asuccess\x18\x02 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\x12\x16\n" +
+	"\x06window\x18\x04 \x01(\x05R\x06window\"9\n" +
+	"\n" +
+	"TunnelData\x12\x17\n" +
+	"\aconn_id\x18\x01 \x01(\tR\x06connId\x12\x12\n" +
+	"\x04data\x18\x02 \x01(\fR\x04data\":\n" +
+	"\tTunnelAck\x12\x17\n" +
+	"\aconn_id\x18\x01 \x01(\tR\x06connId\x12\x14\n" +
+	"\x05bytes\x18\x02 \x01(\x05R\x05bytes\"<\n" +
+	"\vTunnelClose\x12\x17\n" +
+	"\aconn_id\x18\x01 \x01(\tR\x06connId\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"N\n" +
+	"\x10CertRenewRequest\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\x12\x1b\n" +
+	"\tnot_after\x18\x02 \x01(\x03R\bnotAfter\"L\n" +
+	"\x12CertSigningRequest\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\x12\x17\n" +
+	"\acsr_pem\x18\x02 \x01(\fR\x06csrPem\"\x94\x01\n" +
+	"\x11CertRenewResponse\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\x12\x18\n" +
+	"\asuccess\x18\x02 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\x12\x19\n" +
+	"\bcert_pem\x18\x04 \x01(\fR\acertPem\x12\x15\n" +
+	"\x06ca_pem\x18\x05 \x01(\fR\x05caPem\"Z\n" +
+	"\n" +
+	"RelayFrame\x12\x17\n" +
+	"\ahost_id\x18\x01 \x01(\rR\x06hostId\x123\n" +
+	"\amessage\x18\x02 \x01(\v2\x19.agentproto.ServerMessageR\amessage2N\n" +
 	"\bAgentHub\x12B\n" +
-	"\aConnect\x12\x18.agentproto.AgentMessage\x1a\x19.agentproto.ServerMessage(\x010\x01B-Z+github.com/ydcloud-dy/opshub/pkg/agentprotob\x06proto3"
+	"\aConnect\x12\x18.agentproto.AgentMessage\x1a\x19.agentproto.ServerMessage(\x010\x012K\n" +
+	"\n" +
+	"AgentRelay\x12=\n" +
+	"\x05Relay\x12\x16.agentproto.RelayFrame\x1a\x18.agentproto.AgentMessage(\x010\x01B-Z+github.com/ydcloud-dy/opshub/pkg/agentprotob\x06proto3"
 
 var (
 	file_api_proto_agent_proto_rawDescOnce sync.Once
@@ -3129,93 +5305,142 @@ func file_api_proto_agent_proto_rawDescGZIP() []byte {
 	return file_api_proto_agent_proto_rawDescData
 }
 
-var file_api_proto_agent_proto_msgTypes = make([]protoimpl.MessageInfo, 39)
+var file_api_proto_agent_proto_msgTypes = make([]protoimpl.MessageInfo, 61)
 var file_api_proto_agent_proto_goTypes = []any{
-	(*AgentMessage)(nil),         // 0: agentproto.AgentMessage
-	(*ServerMessage)(nil),        // 1: agentproto.ServerMessage
-	(*RegisterRequest)(nil),      // 2: agentproto.RegisterRequest
-	(*RegisterResponse)(nil),     // 3: agentproto.RegisterResponse
-	(*HeartbeatRequest)(nil),     // 4: agentproto.HeartbeatRequest
-	(*HeartbeatResponse)(nil),    // 5: agentproto.HeartbeatResponse
-	(*TerminalOpen)(nil),         // 6: agentproto.TerminalOpen
-	(*TerminalInput)(nil),        // 7: agentproto.TerminalInput
-	(*TerminalOutput)(nil),       // 8: agentproto.TerminalOutput
-	(*TerminalResize)(nil),       // 9: agentproto.TerminalResize
-	(*TerminalClose)(nil),        // 10: agentproto.TerminalClose
-	(*FileRequest)(nil),          // 11: agentproto.FileRequest
-	(*FileChunk)(nil),            // 12: agentproto.FileChunk
-	(*FileListResult)(nil),       // 13: agentproto.FileListResult
-	(*FileInfo)(nil),             // 14: agentproto.FileInfo
-	(*CommandRequest)(nil),       // 15: agentproto.CommandRequest
-	(*CommandResult)(nil),        // 16: agentproto.CommandResult
-	(*ProbeRequest)(nil),         // 17: agentproto.ProbeRequest
-	(*ProbeAssertion)(nil),       // 18: agentproto.ProbeAssertion
-	(*ProbeResult)(nil),          // 19: agentproto.ProbeResult
-	(*ProbeAssertionResult)(nil), // 20: agentproto.ProbeAssertionResult
-	(*HttpProxyRequest)(nil),     // 21: agentproto.HttpProxyRequest
-	(*HttpProxyResponse)(nil),    // 22: agentproto.HttpProxyResponse
-	(*WsSessionOpen)(nil),        // 23: agentproto.WsSessionOpen
-	(*WsSessionAction)(nil),      // 24: agentproto.WsSessionAction
-	(*WsSessionClose)(nil),       // 25: agentproto.WsSessionClose
-	(*WsSessionResult)(nil),      // 26: agentproto.WsSessionResult
-	(*StreamProxyRequest)(nil),   // 27: agentproto.StreamProxyRequest
-	(*StreamProxyChunk)(nil),     // 28: agentproto.StreamProxyChunk
-	nil,                          // 29: agentproto.ProbeRequest.HeadersEntry
-	nil,                          // 30: agentproto.ProbeRequest.ParamsEntry
-	nil,                          // 31: agentproto.ProbeResult.ResponseHeadersEntry
-	nil,                          // 32: agentproto.HttpProxyRequest.HeadersEntry
-	nil,                          // 33: agentproto.HttpProxyResponse.HeadersEntry
-	nil,                          // 34: agentproto.WsSessionOpen.HeadersEntry
-	nil,                          // 35: agentproto.WsSessionOpen.ParamsEntry
-	nil,                          // 36: agentproto.WsSessionResult.HeadersEntry
-	nil,                          // 37: agentproto.StreamProxyRequest.HeadersEntry
-	nil,                          // 38: agentproto.StreamProxyChunk.HeadersEntry
+	(*AgentMessage)(nil),            // 0: agentproto.AgentMessage
+	(*ServerMessage)(nil),           // 1: agentproto.ServerMessage
+	(*RegisterRequest)(nil),         // 2: agentproto.RegisterRequest
+	(*RegisterResponse)(nil),        // 3: agentproto.RegisterResponse
+	(*HeartbeatRequest)(nil),        // 4: agentproto.HeartbeatRequest
+	(*DiskStats)(nil),               // 5: agentproto.DiskStats
+	(*NetworkStats)(nil),            // 6: agentproto.NetworkStats
+	(*ProcessStats)(nil),            // 7: agentproto.ProcessStats
+	(*HeartbeatResponse)(nil),       // 8: agentproto.HeartbeatResponse
+	(*TerminalOpen)(nil),            // 9: agentproto.TerminalOpen
+	(*TerminalInput)(nil),           // 10: agentproto.TerminalInput
+	(*TerminalOutput)(nil),          // 11: agentproto.TerminalOutput
+	(*TerminalResize)(nil),          // 12: agentproto.TerminalResize
+	(*TerminalClose)(nil),           // 13: agentproto.TerminalClose
+	(*FileRequest)(nil),             // 14: agentproto.FileRequest
+	(*FileChunk)(nil),               // 15: agentproto.FileChunk
+	(*FileListResult)(nil),          // 16: agentproto.FileListResult
+	(*FileInfo)(nil),                // 17: agentproto.FileInfo
+	(*CommandRequest)(nil),          // 18: agentproto.CommandRequest
+	(*CommandResult)(nil),           // 19: agentproto.CommandResult
+	(*CommandCancel)(nil),           // 20: agentproto.CommandCancel
+	(*CommandOutputChunk)(nil),      // 21: agentproto.CommandOutputChunk
+	(*ProbeRequest)(nil),            // 22: agentproto.ProbeRequest
+	(*ProbeAssertion)(nil),          // 23: agentproto.ProbeAssertion
+	(*ProbeResult)(nil),             // 24: agentproto.ProbeResult
+	(*ProbeAssertionResult)(nil),    // 25: agentproto.ProbeAssertionResult
+	(*HttpProxyRequest)(nil),        // 26: agentproto.HttpProxyRequest
+	(*HttpProxyResponse)(nil),       // 27: agentproto.HttpProxyResponse
+	(*WsSessionOpen)(nil),           // 28: agentproto.WsSessionOpen
+	(*WsSessionAction)(nil),         // 29: agentproto.WsSessionAction
+	(*WsSessionClose)(nil),          // 30: agentproto.WsSessionClose
+	(*WsSessionResult)(nil),         // 31: agentproto.WsSessionResult
+	(*StreamProxyRequest)(nil),      // 32: agentproto.StreamProxyRequest
+	(*StreamProxyChunk)(nil),        // 33: agentproto.StreamProxyChunk
+	(*AgentUpgrade)(nil),            // 34: agentproto.AgentUpgrade
+	(*AgentUpgradeResult)(nil),      // 35: agentproto.AgentUpgradeResult
+	(*PolicyViolation)(nil),         // 36: agentproto.PolicyViolation
+	(*ServiceDiscovery)(nil),        // 37: agentproto.ServiceDiscovery
+	(*DiscoveredService)(nil),       // 38: agentproto.DiscoveredService
+	(*ListenAddr)(nil),              // 39: agentproto.ListenAddr
+	(*ServiceDiscoveryRequest)(nil), // 40: agentproto.ServiceDiscoveryRequest
+	(*TunnelOpen)(nil),              // 41: agentproto.TunnelOpen
+	(*TunnelOpenResult)(nil),        // 42: agentproto.TunnelOpenResult
+	(*TunnelData)(nil),              // 43: agentproto.TunnelData
+	(*TunnelAck)(nil),               // 44: agentproto.TunnelAck
+	(*TunnelClose)(nil),             // 45: agentproto.TunnelClose
+	(*CertRenewRequest)(nil),        // 46: agentproto.CertRenewRequest
+	(*CertSigningRequest)(nil),      // 47: agentproto.CertSigningRequest
+	(*CertRenewResponse)(nil),       // 48: agentproto.CertRenewResponse
+	(*RelayFrame)(nil),              // 49: agentproto.RelayFrame
+	nil,                             // 50: agentproto.HeartbeatRequest.TcpStatesEntry
+	nil,                             // 51: agentproto.ProbeRequest.HeadersEntry
+	nil,                             // 52: agentproto.ProbeRequest.ParamsEntry
+	nil,                             // 53: agentproto.ProbeResult.ResponseHeadersEntry
+	nil,                             // 54: agentproto.HttpProxyRequest.HeadersEntry
+	nil,                             // 55: agentproto.HttpProxyResponse.HeadersEntry
+	nil,                             // 56: agentproto.WsSessionOpen.HeadersEntry
+	nil,                             // 57: agentproto.WsSessionOpen.ParamsEntry
+	nil,                             // 58: agentproto.WsSessionResult.HeadersEntry
+	nil,                             // 59: agentproto.StreamProxyRequest.HeadersEntry
+	nil,                             // 60: agentproto.StreamProxyChunk.HeadersEntry
 }
 var file_api_proto_agent_proto_depIdxs = []int32{
 	2,  // 0: agentproto.AgentMessage.register:type_name -> agentproto.RegisterRequest
 	4,  // 1: agentproto.AgentMessage.heartbeat:type_name -> agentproto.HeartbeatRequest
-	8,  // 2: agentproto.AgentMessage.term_output:type_name -> agentproto.TerminalOutput
-	12, // 3: agentproto.AgentMessage.file_chunk:type_name -> agentproto.FileChunk
-	16, // 4: agentproto.AgentMessage.cmd_result:type_name -> agentproto.CommandResult
-	13, // 5: agentproto.AgentMessage.file_list:type_name -> agentproto.FileListResult
-	19, // 6: agentproto.AgentMessage.probe_result:type_name -> agentproto.ProbeResult
-	22, // 7: agentproto.AgentMessage.http_proxy_response:type_name -> agentproto.HttpProxyResponse
-	26, // 8: agentproto.AgentMessage.ws_session_result:type_name -> agentproto.WsSessionResult
-	28, // 9: agentproto.AgentMessage.stream_proxy_chunk:type_name -> agentproto.StreamProxyChunk
-	3,  // 10: agentproto.ServerMessage.register_ack:type_name -> agentproto.RegisterResponse
-	5,  // 11: agentproto.ServerMessage.heartbeat_ack:type_name -> agentproto.HeartbeatResponse
-	6,  // 12: agentproto.ServerMessage.term_open:type_name -> agentproto.TerminalOpen
-	7,  // 13: agentproto.ServerMessage.term_input:type_name -> agentproto.TerminalInput
-	9,  // 14: agentproto.ServerMessage.term_resize:type_name -> agentproto.TerminalResize
-	10, // 15: agentproto.ServerMessage.term_close:type_name -> agentproto.TerminalClose
-	11, // 16: agentproto.ServerMessage.file_request:type_name -> agentproto.FileRequest
-	15, // 17: agentproto.ServerMessage.cmd_request:type_name -> agentproto.CommandRequest
-	17, // 18: agentproto.ServerMessage.probe_request:type_name -> agentproto.ProbeRequest
-	21, // 19: agentproto.ServerMessage.http_proxy_request:type_name -> agentproto.HttpProxyRequest
-	23, // 20: agentproto.ServerMessage.ws_session_open:type_name -> agentproto.WsSessionOpen
-	24, // 21: agentproto.ServerMessage.ws_session_action:type_name -> agentproto.WsSessionAction
-	25, // 22: agentproto.ServerMessage.ws_session_close:type_name -> agentproto.WsSessionClose
-	27, // 23: agentproto.ServerMessage.stream_proxy_request:type_name -> agentproto.StreamProxyRequest
-	14, // 24: agentproto.FileListResult.files:type_name -> agentproto.FileInfo
-	29, // 25: agentproto.ProbeRequest.headers:type_name -> agentproto.ProbeRequest.HeadersEntry
-	30, // 26: agentproto.ProbeRequest.params:type_name -> agentproto.ProbeRequest.ParamsEntry
-	18, // 27: agentproto.ProbeRequest.assertions:type_name -> agentproto.ProbeAssertion
-	31, // 28: agentproto.ProbeResult.response_headers:type_name -> agentproto.ProbeResult.ResponseHeadersEntry
-	20, // 29: agentproto.ProbeResult.assertion_results:type_name -> agentproto.ProbeAssertionResult
-	32, // 30: agentproto.HttpProxyRequest.headers:type_name -> agentproto.HttpProxyRequest.HeadersEntry
-	33, // 31: agentproto.HttpProxyResponse.headers:type_name -> agentproto.HttpProxyResponse.HeadersEntry
-	34, // 32: agentproto.WsSessionOpen.headers:type_name -> agentproto.WsSessionOpen.HeadersEntry
-	35, // 33: agentproto.WsSessionOpen.params:type_name -> agentproto.WsSessionOpen.ParamsEntry
-	36, // 34: agentproto.WsSessionResult.headers:type_name -> agentproto.WsSessionResult.HeadersEntry
-	37, // 35: agentproto.StreamProxyRequest.headers:type_name -> agentproto.StreamProxyRequest.HeadersEntry
-	38, // 36: agentproto.StreamProxyChunk.headers:type_name -> agentproto.StreamProxyChunk.HeadersEntry
-	0,  // 37: agentproto.AgentHub.Connect:input_type -> agentproto.AgentMessage
-	1,  // 38: agentproto.AgentHub.Connect:output_type -> agentproto.ServerMessage
-	38, // [38:39] is the sub-list for method output_type
-	37, // [37:38] is the sub-list for method input_type
-	37, // [37:37] is the sub-list for extension type_name
-	37, // [37:37] is the sub-list for extension extendee
-	0,  // [0:37] is the sub-list for field type_name
+	11, // 2: agentproto.AgentMessage.term_output:type_name -> agentproto.TerminalOutput
+	15, // 3: agentproto.AgentMessage.file_chunk:type_name -> agentproto.FileChunk
+	19, // 4: agentproto.AgentMessage.cmd_result:type_name -> agentproto.CommandResult
+	16, // 5: agentproto.AgentMessage.file_list:type_name -> agentproto.FileListResult
+	24, // 6: agentproto.AgentMessage.probe_result:type_name -> agentproto.ProbeResult
+	27, // 7: agentproto.AgentMessage.http_proxy_response:type_name -> agentproto.HttpProxyResponse
+	31, // 8: agentproto.AgentMessage.ws_session_result:type_name -> agentproto.WsSessionResult
+	33, // 9: agentproto.AgentMessage.stream_proxy_chunk:type_name -> agentproto.StreamProxyChunk
+	21, // 10: agentproto.AgentMessage.cmd_output:type_name -> agentproto.CommandOutputChunk
+	35, // 11: agentproto.AgentMessage.upgrade_result:type_name -> agentproto.AgentUpgradeResult
+	36, // 12: agentproto.AgentMessage.policy_violation:type_name -> agentproto.PolicyViolation
+	37, // 13: agentproto.AgentMessage.service_discovery:type_name -> agentproto.ServiceDiscovery
+	42, // 14: agentproto.AgentMessage.tunnel_open_result:type_name -> agentproto.TunnelOpenResult
+	43, // 15: agentproto.AgentMessage.tunnel_data:type_name -> agentproto.TunnelData
+	44, // 16: agentproto.AgentMessage.tunnel_ack:type_name -> agentproto.TunnelAck
+	45, // 17: agentproto.AgentMessage.tunnel_close:type_name -> agentproto.TunnelClose
+	47, // 18: agentproto.AgentMessage.cert_csr:type_name -> agentproto.CertSigningRequest
+	3,  // 19: agentproto.ServerMessage.register_ack:type_name -> agentproto.RegisterResponse
+	8,  // 20: agentproto.ServerMessage.heartbeat_ack:type_name -> agentproto.HeartbeatResponse
+	9,  // 21: agentproto.ServerMessage.term_open:type_name -> agentproto.TerminalOpen
+	10, // 22: agentproto.ServerMessage.term_input:type_name -> agentproto.TerminalInput
+	12, // 23: agentproto.ServerMessage.term_resize:type_name -> agentproto.TerminalResize
+	13, // 24: agentproto.ServerMessage.term_close:type_name -> agentproto.TerminalClose
+	14, // 25: agentproto.ServerMessage.file_request:type_name -> agentproto.FileRequest
+	18, // 26: agentproto.ServerMessage.cmd_request:type_name -> agentproto.CommandRequest
+	22, // 27: agentproto.ServerMessage.probe_request:type_name -> agentproto.ProbeRequest
+	26, // 28: agentproto.ServerMessage.http_proxy_request:type_name -> agentproto.HttpProxyRequest
+	28, // 29: agentproto.ServerMessage.ws_session_open:type_name -> agentproto.WsSessionOpen
+	29, // 30: agentproto.ServerMessage.ws_session_action:type_name -> agentproto.WsSessionAction
+	30, // 31: agentproto.ServerMessage.ws_session_close:type_name -> agentproto.WsSessionClose
+	32, // 32: agentproto.ServerMessage.stream_proxy_request:type_name -> agentproto.StreamProxyRequest
+	20, // 33: agentproto.ServerMessage.cmd_cancel:type_name -> agentproto.CommandCancel
+	34, // 34: agentproto.ServerMessage.agent_upgrade:type_name -> agentproto.AgentUpgrade
+	40, // 35: agentproto.ServerMessage.discovery_request:type_name -> agentproto.ServiceDiscoveryRequest
+	41, // 36: agentproto.ServerMessage.tunnel_open:type_name -> agentproto.TunnelOpen
+	43, // 37: agentproto.ServerMessage.tunnel_data:type_name -> agentproto.TunnelData
+	44, // 38: agentproto.ServerMessage.tunnel_ack:type_name -> agentproto.TunnelAck
+	45, // 39: agentproto.ServerMessage.tunnel_close:type_name -> agentproto.TunnelClose
+	46, // 40: agentproto.ServerMessage.cert_renew_request:type_name -> agentproto.CertRenewRequest
+	48, // 41: agentproto.ServerMessage.cert_renew_response:type_name -> agentproto.CertRenewResponse
+	5,  // 42: agentproto.HeartbeatRequest.disks:type_name -> agentproto.DiskStats
+	6,  // 43: agentproto.HeartbeatRequest.networks:type_name -> agentproto.NetworkStats
+	50, // 44: agentproto.HeartbeatRequest.tcp_states:type_name -> agentproto.HeartbeatRequest.TcpStatesEntry
+	7,  // 45: agentproto.HeartbeatRequest.top_processes:type_name -> agentproto.ProcessStats
+	17, // 46: agentproto.FileListResult.files:type_name -> agentproto.FileInfo
+	51, // 47: agentproto.ProbeRequest.headers:type_name -> agentproto.ProbeRequest.HeadersEntry
+	52, // 48: agentproto.ProbeRequest.params:type_name -> agentproto.ProbeRequest.ParamsEntry
+	23, // 49: agentproto.ProbeRequest.assertions:type_name -> agentproto.ProbeAssertion
+	53, // 50: agentproto.ProbeResult.response_headers:type_name -> agentproto.ProbeResult.ResponseHeadersEntry
+	25, // 51: agentproto.ProbeResult.assertion_results:type_name -> agentproto.ProbeAssertionResult
+	54, // 52: agentproto.HttpProxyRequest.headers:type_name -> agentproto.HttpProxyRequest.HeadersEntry
+	55, // 53: agentproto.HttpProxyResponse.headers:type_name -> agentproto.HttpProxyResponse.HeadersEntry
+	56, // 54: agentproto.WsSessionOpen.headers:type_name -> agentproto.WsSessionOpen.HeadersEntry
+	57, // 55: agentproto.WsSessionOpen.params:type_name -> agentproto.WsSessionOpen.ParamsEntry
+	58, // 56: agentproto.WsSessionResult.headers:type_name -> agentproto.WsSessionResult.HeadersEntry
+	59, // 57: agentproto.StreamProxyRequest.headers:type_name -> agentproto.StreamProxyRequest.HeadersEntry
+	60, // 58: agentproto.StreamProxyChunk.headers:type_name -> agentproto.StreamProxyChunk.HeadersEntry
+	38, // 59: agentproto.ServiceDiscovery.services:type_name -> agentproto.DiscoveredService
+	39, // 60: agentproto.DiscoveredService.listen:type_name -> agentproto.ListenAddr
+	1,  // 61: agentproto.RelayFrame.message:type_name -> agentproto.ServerMessage
+	0,  // 62: agentproto.AgentHub.Connect:input_type -> agentproto.AgentMessage
+	49, // 63: agentproto.AgentRelay.Relay:input_type -> agentproto.RelayFrame
+	1,  // 64: agentproto.AgentHub.Connect:output_type -> agentproto.ServerMessage
+	0,  // 65: agentproto.AgentRelay.Relay:output_type -> agentproto.AgentMessage
+	64, // [64:66] is the sub-list for method output_type
+	62, // [62:64] is the sub-list for method input_type
+	62, // [62:62] is the sub-list for extension type_name
+	62, // [62:62] is the sub-list for extension extendee
+	0,  // [0:62] is the sub-list for field type_name
 }
 
 func init() { file_api_proto_agent_proto_init() }
@@ -3234,6 +5459,15 @@ func file_api_proto_agent_proto_init() {
 		(*AgentMessage_HttpProxyResponse)(nil),
 		(*AgentMessage_WsSessionResult)(nil),
 		(*AgentMessage_StreamProxyChunk)(nil),
+		(*AgentMessage_CmdOutput)(nil),
+		(*AgentMessage_UpgradeResult)(nil),
+		(*AgentMessage_PolicyViolation)(nil),
+		(*AgentMessage_ServiceDiscovery)(nil),
+		(*AgentMessage_TunnelOpenResult)(nil),
+		(*AgentMessage_TunnelData)(nil),
+		(*AgentMessage_TunnelAck)(nil),
+		(*AgentMessage_TunnelClose)(nil),
+		(*AgentMessage_CertCsr)(nil),
 	}
 	file_api_proto_agent_proto_msgTypes[1].OneofWrappers = []any{
 		(*ServerMessage_RegisterAck)(nil),
@@ -3250,6 +5484,15 @@ func file_api_proto_agent_proto_init() {
 		(*ServerMessage_WsSessionAction)(nil),
 		(*ServerMessage_WsSessionClose)(nil),
 		(*ServerMessage_StreamProxyRequest)(nil),
+		(*ServerMessage_CmdCancel)(nil),
+		(*ServerMessage_AgentUpgrade)(nil),
+		(*ServerMessage_DiscoveryRequest)(nil),
+		(*ServerMessage_TunnelOpen)(nil),
+		(*ServerMessage_TunnelData)(nil),
+		(*ServerMessage_TunnelAck)(nil),
+		(*ServerMessage_TunnelClose)(nil),
+		(*ServerMessage_CertRenewRequest)(nil),
+		(*ServerMessage_CertRenewResponse)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -3257,9 +5500,9 @@ func file_api_proto_agent_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_proto_agent_proto_rawDesc), len(file_api_proto_agent_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   39,
+			NumMessages:   61,
 			NumExtensions: 0,
-			NumServices:   1,
+			NumServices:   2,
 		},
 		GoTypes:           file_api_proto_agent_proto_goTypes,
 		DependencyIndexes: file_api_proto_agent_proto_depIdxs,
@@ -113,3 +113,105 @@ var AgentHub_ServiceDesc = grpc.ServiceDesc{
 	},
 	Metadata: "api/proto/agent.proto",
 }
+
+const (
+	AgentRelay_Relay_FullMethodName = "/agentproto.AgentRelay/Relay"
+)
+
+// AgentRelayClient is the client API for AgentRelay service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// 多副本部署时副本之间转发Agent消息：请求落在未持有Agent连接的副本上时，
+// 经此服务把消息交给持有连接的副本，Agent的响应沿同一条流返回
+type AgentRelayClient interface {
+	Relay(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[RelayFrame, AgentMessage], error)
+}
+
+type agentRelayClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAgentRelayClient(cc grpc.ClientConnInterface) AgentRelayClient {
+	return &agentRelayClient{cc}
+}
+
+func (c *agentRelayClient) Relay(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[RelayFrame, AgentMessage], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &AgentRelay_ServiceDesc.Streams[0], AgentRelay_Relay_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[RelayFrame, AgentMessage]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type AgentRelay_RelayClient = grpc.BidiStreamingClient[RelayFrame, AgentMessage]
+
+// AgentRelayServer is the server API for AgentRelay service.
+// All implementations must embed UnimplementedAgentRelayServer
+// for forward compatibility.
+//
+// 多副本部署时副本之间转发Agent消息：请求落在未持有Agent连接的副本上时，
+// 经此服务把消息交给持有连接的副本，Agent的响应沿同一条流返回
+type AgentRelayServer interface {
+	Relay(grpc.BidiStreamingServer[RelayFrame, AgentMessage]) error
+	mustEmbedUnimplementedAgentRelayServer()
+}
+
+// UnimplementedAgentRelayServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedAgentRelayServer struct{}
+
+func (UnimplementedAgentRelayServer) Relay(grpc.BidiStreamingServer[RelayFrame, AgentMessage]) error {
+	return status.Error(codes.Unimplemented, "method Relay not implemented")
+}
+func (UnimplementedAgentRelayServer) mustEmbedUnimplementedAgentRelayServer() {}
+func (UnimplementedAgentRelayServer) testEmbeddedByValue()                    {}
+
+// UnsafeAgentRelayServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AgentRelayServer will
+// result in compilation errors.
+type UnsafeAgentRelayServer interface {
+	mustEmbedUnimplementedAgentRelayServer()
+}
+
+func RegisterAgentRelayServer(s grpc.ServiceRegistrar, srv AgentRelayServer) {
+	// If the following call panics, it indicates UnimplementedAgentRelayServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&AgentRelay_ServiceDesc, srv)
+}
+
+func _AgentRelay_Relay_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(AgentRelayServer).Relay(&grpc.GenericServerStream[RelayFrame, AgentMessage]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type AgentRelay_RelayServer = grpc.BidiStreamingServer[RelayFrame, AgentMessage]
+
+// AgentRelay_ServiceDesc is the grpc.ServiceDesc for AgentRelay service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AgentRelay_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "agentproto.AgentRelay",
+	HandlerType: (*AgentRelayServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Relay",
+			Handler:       _AgentRelay_Relay_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "api/proto/agent.proto",
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2026 DYCloud J.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package agentproto
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// UpgradeManifest 远程升级的签名内容：发布签名同时覆盖版本、平台与二进制摘要，
+// 旧版本或其他平台的已签名二进制无法冒充目标版本下发。
+// 与 agent/build.sh 中生成的清单逐字节一致。
+func UpgradeManifest(version, goos, goarch, sha256Hex string) []byte {
+	return fmt.Appendf(nil, "srehub-agent-upgrade\nversion=%s\nos=%s\narch=%s\nsha256=%s\n",
+		strings.TrimPrefix(version, "v"), goos, goarch, strings.ToLower(sha256Hex))
+}
+
+// IsDowngrade 判断从 from 升级到 to 是否为降级，任一版本号无法解析时返回 false
+// 版本号形如 1.2.0、v1.2.0、1.2.0-rc1，预发布版本低于同号正式版本
+func IsDowngrade(from, to string) bool {
+	a, ok1 := parseVersion(from)
+	b, ok2 := parseVersion(to)
+	if !ok1 || !ok2 {
+		return false
+	}
+	return compareVersion(b, a) < 0
+}
+
+type version struct {
+	nums []int
+	pre  string
+}
+
+func parseVersion(s string) (version, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	main, pre, _ := strings.Cut(s, "-")
+	var v version
+	for _, part := range strings.Split(main, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return version{}, false
+		}
+		v.nums = append(v.nums, n)
+	}
+	v.pre = pre
+	return v, true
+}
+
+func compareVersion(a, b version) int {
+	for i := 0; i < max(len(a.nums), len(b.nums)); i++ {
+		var x, y int
+		if i < len(a.nums) {
+			x = a.nums[i]
+		}
+		if i < len(b.nums) {
+			y = b.nums[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case a.pre == b.pre:
+		return 0
+	case a.pre == "":
+		return 1
+	case b.pre == "":
+		return -1
+	}
+	return strings.Compare(a.pre, b.pre)
+}
@@ -0,0 +1,31 @@
+package agentproto
+
+import "testing"
+
+func TestIsDowngrade(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{"1.1.0", "1.0.0", true},
+		{"1.1.0", "1.2.0", false},
+		{"v1.10.0", "1.9.3", true},
+		{"1.2.0", "1.2.0-rc1", true},
+		{"1.2.0-rc1", "1.2.0", false},
+		{"1.2", "1.2.0", false},
+		{"dev", "1.0.0", false},
+	}
+	for _, tt := range tests {
+		if got := IsDowngrade(tt.from, tt.to); got != tt.want {
+			t.Errorf("IsDowngrade(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestUpgradeManifest(t *testing.T) {
+	got := string(UpgradeManifest("v1.2.0", "linux", "amd64", "ABCDEF"))
+	want := "srehub-agent-upgrade\nversion=1.2.0\nos=linux\narch=amd64\nsha256=abcdef\n"
+	if got != want {
+		t.Errorf("UpgradeManifest() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2026 DYCloud J.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package secret
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+)
+
+// defaultKey 内置后端的AES-256密钥，与已有数据兼容，不能修改
+var defaultKey = []byte("opshub-enc-key-32-bytes-long!!!!")
+
+// DBStore 内置后端：AES-GCM加密后随业务数据保存在数据库中
+type DBStore struct {
+	key []byte
+}
+
+// NewDBStore 创建内置后端，key 须为 16/24/32 字节
+func NewDBStore(key []byte) *DBStore {
+	return &DBStore{key: key}
+}
+
+// Put 返回密文，path 不使用
+func (s *DBStore) Put(ctx context.Context, path, plaintext string) (string, error) {
+	return s.Encrypt(plaintext)
+}
+
+// Get 解密
+func (s *DBStore) Get(ctx context.Context, ref string) (string, error) {
+	return s.Decrypt(ref)
+}
+
+// Delete 密文随业务数据删除，无需处理
+func (s *DBStore) Delete(ctx context.Context, path, ref string) error {
+	return nil
+}
+
+// Encrypt 加密，输出 base64(nonce+密文)
+func (s *DBStore) Encrypt(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+
+	gcm, err := s.gcm()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	ciphertext := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// Decrypt 解密 Encrypt 的输出
+func (s *DBStore) Decrypt(ciphertext string) (string, error) {
+	if ciphertext == "" {
+		return "", nil
+	}
+
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", err
+	}
+
+	gcm, err := s.gcm()
+	if err != nil {
+		return "", err
+	}
+
+	nonceSize := gcm.NonceSize()
+	if len(data) < nonceSize {
+		return "", errors.New("ciphertext too short")
+	}
+
+	nonce, cipherData := data[:nonceSize], data[nonceSize:]
+	plaintext, err := gcm.Open(nil, nonce, cipherData, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+func (s *DBStore) gcm() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(s.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+var builtin = NewDBStore(defaultKey)
+
+// Builtin 使用内置密钥的数据库后端，与历史数据兼容
+func Builtin() *DBStore {
+	return builtin
+}
+
+// Encrypt 使用内置密钥加密，供不接入外部后端的配置项使用
+func Encrypt(plaintext string) (string, error) {
+	return builtin.Encrypt(plaintext)
+}
+
+// Decrypt 使用内置密钥解密
+func Decrypt(ciphertext string) (string, error) {
+	return builtin.Decrypt(ciphertext)
+}
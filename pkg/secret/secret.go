@@ -0,0 +1,266 @@
+// Copyright (c) 2026 DYCloud J.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// Package secret 凭证等敏感信息的存储。数据库中保存的是后端返回的引用：
+// 内置后端为密文，外部后端为路径，使用时按引用取回明文。
+package secret
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// 新保存的内容写入的后端
+const (
+	BackendDB    = "db"
+	BackendVault = "vault"
+)
+
+// defaultCacheTTL 外部后端读取结果在内存中的缓存时长
+const defaultCacheTTL = time.Minute
+
+// SecretStore 敏感信息存储后端
+type SecretStore interface {
+	// Put 保存明文，返回写入数据库的引用
+	Put(ctx context.Context, path, plaintext string) (string, error)
+	// Get 按引用取回明文
+	Get(ctx context.Context, ref string) (string, error)
+	// Delete 删除为 path 写入的引用对应的内容，引用的外部内容不删除
+	Delete(ctx context.Context, path, ref string) error
+}
+
+// IsReference 是否为外部后端的引用，引用按原样保存，不加密
+func IsReference(value string) bool {
+	return strings.HasPrefix(value, VaultPrefix) || strings.HasPrefix(value, VaultSSHPrefix)
+}
+
+// IsSSHReference 是否为签发临时SSH证书的引用
+func IsSSHReference(value string) bool {
+	return strings.HasPrefix(value, VaultSSHPrefix)
+}
+
+// Manager 按引用分发到各后端，外部后端读取的内容只缓存在内存中
+type Manager struct {
+	db       *DBStore
+	vault    *VaultStore
+	backend  string
+	cacheTTL time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cachedSecret
+	keys  map[string]SSHKey
+}
+
+type cachedSecret struct {
+	value     string
+	expiresAt time.Time
+}
+
+// SSHKey 临时私钥及签发的证书
+type SSHKey struct {
+	PrivateKey  string    // PEM
+	Certificate string    // authorized_keys 格式
+	ValidBefore time.Time // 证书过期时间
+}
+
+// NewManager 创建存储，vault 为空时只能使用内置后端
+func NewManager(db *DBStore, vault *VaultStore, backend string, cacheTTL time.Duration) *Manager {
+	if backend == "" || vault == nil {
+		backend = BackendDB
+	}
+	if cacheTTL <= 0 {
+		cacheTTL = defaultCacheTTL
+	}
+	return &Manager{
+		db:       db,
+		vault:    vault,
+		backend:  backend,
+		cacheTTL: cacheTTL,
+		cache:    make(map[string]cachedSecret),
+		keys:     make(map[string]SSHKey),
+	}
+}
+
+// Put 允许范围内的引用按原样返回，其他内容写入配置的后端；
+// 本系统写入的引用不能作为新值提交，避免一条记录读取或删除另一条记录的内容
+func (m *Manager) Put(ctx context.Context, path, plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	if IsReference(plaintext) {
+		if m.vault == nil {
+			return "", errors.New("未配置Vault，不能引用Vault中的密钥")
+		}
+		if err := m.vault.CheckRef(plaintext); err != nil {
+			return "", err
+		}
+		return plaintext, nil
+	}
+	if m.backend == BackendVault {
+		return m.vault.Put(ctx, path, plaintext)
+	}
+	return m.db.Put(ctx, path, plaintext)
+}
+
+// Get 按引用取回明文
+func (m *Manager) Get(ctx context.Context, ref string) (string, error) {
+	switch {
+	case ref == "":
+		return "", nil
+	case IsSSHReference(ref):
+		return "", fmt.Errorf("%s 用于签发SSH证书，不能直接读取", ref)
+	case strings.HasPrefix(ref, VaultPrefix):
+		if m.vault == nil {
+			return "", errors.New("未配置Vault，无法读取引用的密钥")
+		}
+		if value, ok := m.cached(ref); ok {
+			return value, nil
+		}
+		value, err := m.vault.Get(ctx, ref)
+		if err != nil {
+			return "", err
+		}
+		m.mu.Lock()
+		m.cache[ref] = cachedSecret{value: value, expiresAt: time.Now().Add(m.cacheTTL)}
+		m.mu.Unlock()
+		return value, nil
+	default:
+		return m.db.Get(ctx, ref)
+	}
+}
+
+// Delete 删除本系统为 path 写入外部后端的内容，用户引用的外部内容不删除
+func (m *Manager) Delete(ctx context.Context, path, ref string) error {
+	if !strings.HasPrefix(ref, VaultPrefix) || m.vault == nil {
+		return nil
+	}
+	m.mu.Lock()
+	delete(m.cache, ref)
+	m.mu.Unlock()
+	return m.vault.Delete(ctx, path, ref)
+}
+
+// Replace 保存字段的新值：与已保存的引用相同时保留，否则写入新值并删除旧内容
+func (m *Manager) Replace(ctx context.Context, path, oldRef, value string) (string, error) {
+	if value == oldRef {
+		return oldRef, nil
+	}
+	ref, err := m.Put(ctx, path, value)
+	if err != nil {
+		return "", err
+	}
+	if oldRef != "" && oldRef != ref {
+		m.Delete(ctx, path, oldRef)
+	}
+	return ref, nil
+}
+
+// SSHKey 使用 vault-ssh 引用生成临时密钥并签发证书，证书过期前复用
+func (m *Manager) SSHKey(ctx context.Context, ref, principal string) (*SSHKey, error) {
+	if m.vault == nil {
+		return nil, errors.New("未配置Vault，无法签发SSH证书")
+	}
+	cacheKey := ref + "|" + principal
+	m.mu.Lock()
+	key, ok := m.keys[cacheKey]
+	m.mu.Unlock()
+	if ok && time.Until(key.ValidBefore) > time.Minute {
+		return &key, nil
+	}
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	signed, err := m.vault.SignSSHKey(ctx, ref, string(ssh.MarshalAuthorizedKey(sshPub)), principal)
+	if err != nil {
+		return nil, err
+	}
+	parsed, _, _, _, err := ssh.ParseAuthorizedKey([]byte(signed))
+	if err != nil {
+		return nil, fmt.Errorf("解析Vault签发的SSH证书失败: %w", err)
+	}
+	cert, ok := parsed.(*ssh.Certificate)
+	if !ok {
+		return nil, errors.New("Vault返回的不是SSH证书")
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		return nil, err
+	}
+
+	key = SSHKey{
+		PrivateKey:  string(pem.EncodeToMemory(block)),
+		Certificate: signed,
+		ValidBefore: time.Unix(int64(cert.ValidBefore), 0),
+	}
+	if cert.ValidBefore == ssh.CertTimeInfinity {
+		key.ValidBefore = time.Now().Add(m.cacheTTL)
+	}
+	m.mu.Lock()
+	m.keys[cacheKey] = key
+	m.mu.Unlock()
+	return &key, nil
+}
+
+func (m *Manager) cached(ref string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.cache[ref]
+	if !ok {
+		return "", false
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(m.cache, ref)
+		return "", false
+	}
+	return entry.value, true
+}
+
+var (
+	defaultMu    sync.RWMutex
+	defaultStore = NewManager(builtin, nil, BackendDB, 0)
+)
+
+// SetDefault 设置全局共用的存储，启动时按配置调用
+func SetDefault(m *Manager) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultStore = m
+}
+
+// Default 全局共用的存储，未设置时只使用内置后端
+func Default() *Manager {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultStore
+}
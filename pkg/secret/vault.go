@@ -0,0 +1,291 @@
+// Copyright (c) 2026 DYCloud J.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package secret
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// VaultPrefix Vault KV v2 引用：vault:<挂载点>/<路径>#<字段>，省略字段时为 value
+	VaultPrefix = "vault:"
+	// VaultSSHPrefix Vault SSH 签名角色引用：vault-ssh:<挂载点>/<角色>，使用时签发临时证书
+	VaultSSHPrefix = "vault-ssh:"
+
+	vaultValueField = "value"
+)
+
+// ErrNotFound 引用的内容不存在
+var ErrNotFound = errors.New("密钥不存在")
+
+// VaultOptions Vault 连接配置
+type VaultOptions struct {
+	Address    string
+	Token      string
+	Namespace  string
+	KVMount    string // 新保存的内容写入的 KV v2 挂载点，默认 secret
+	PathPrefix string // 新保存的内容的路径前缀，默认 opshub
+	// AllowedPaths 允许引用的 KV 路径，格式 <挂载点>[/<路径前缀>]；本系统写入的 KVMount/PathPrefix 下的内容不能引用
+	AllowedPaths []string
+	// AllowedSSHRoles 允许引用的 SSH 签名角色，格式 <挂载点>[/<角色>]，为空时不允许 vault-ssh 引用
+	AllowedSSHRoles []string
+	Timeout         time.Duration
+}
+
+// VaultStore HashiCorp Vault 后端，使用 KV v2 保存内容，使用 SSH 引擎签发临时证书
+type VaultStore struct {
+	opts   VaultOptions
+	client *http.Client
+}
+
+// NewVaultStore 创建 Vault 后端
+func NewVaultStore(opts VaultOptions) *VaultStore {
+	opts.Address = strings.TrimRight(opts.Address, "/")
+	if opts.KVMount == "" {
+		opts.KVMount = "secret"
+	}
+	if opts.PathPrefix == "" {
+		opts.PathPrefix = "opshub"
+	}
+	opts.PathPrefix = strings.Trim(opts.PathPrefix, "/")
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	return &VaultStore{opts: opts, client: &http.Client{Timeout: opts.Timeout}}
+}
+
+// Put 写入 <前缀>/<path>/<随机ID>，返回引用
+func (s *VaultStore) Put(ctx context.Context, path, plaintext string) (string, error) {
+	full := s.opts.PathPrefix + "/" + strings.Trim(path, "/") + "/" + uuid.New().String()
+	body := map[string]any{"data": map[string]string{vaultValueField: plaintext}}
+	if err := s.do(ctx, http.MethodPost, s.opts.KVMount+"/data/"+full, body, nil); err != nil {
+		return "", fmt.Errorf("写入Vault失败: %w", err)
+	}
+	return VaultPrefix + s.opts.KVMount + "/" + full + "#" + vaultValueField, nil
+}
+
+// CheckRef 校验用户提交的引用是否在允许的路径或角色内，不在允许范围内的引用不能保存和使用。
+// 本系统写入的内容属于各自的记录，即使 AllowedPaths 覆盖该路径也不能被其他记录引用
+func (s *VaultStore) CheckRef(ref string) error {
+	if strings.HasPrefix(ref, VaultSSHPrefix) {
+		_, _, err := s.parseSSHRef(ref)
+		return err
+	}
+	mount, path, _, err := parseVaultRef(ref)
+	if err != nil {
+		return err
+	}
+	if s.Owned(ref) {
+		return fmt.Errorf("Vault路径 %s 由本系统管理，不能直接引用", mount+"/"+path)
+	}
+	if !matchVaultPath(s.opts.AllowedPaths, mount, path) {
+		return fmt.Errorf("Vault路径 %s 不在允许引用的范围内", mount+"/"+path)
+	}
+	return nil
+}
+
+// parseRef 解析已保存的 KV 引用并校验路径为本系统写入或在允许范围内
+func (s *VaultStore) parseRef(ref string) (mount, path, field string, err error) {
+	mount, path, field, err = parseVaultRef(ref)
+	if err != nil {
+		return "", "", "", err
+	}
+	if s.Owned(ref) || matchVaultPath(s.opts.AllowedPaths, mount, path) {
+		return mount, path, field, nil
+	}
+	return "", "", "", fmt.Errorf("Vault路径 %s 不在允许引用的范围内", mount+"/"+path)
+}
+
+// parseSSHRef 解析 vault-ssh:<挂载点>/<角色> 并校验角色在允许范围内
+func (s *VaultStore) parseSSHRef(ref string) (mount, role string, err error) {
+	mount, role, ok := strings.Cut(strings.TrimPrefix(ref, VaultSSHPrefix), "/")
+	if !strings.HasPrefix(ref, VaultSSHPrefix) || !ok || mount == "" || role == "" || strings.Contains(role, "/") {
+		return "", "", fmt.Errorf("无效的Vault SSH引用: %s", ref)
+	}
+	if !matchVaultPath(s.opts.AllowedSSHRoles, mount, role) {
+		return "", "", fmt.Errorf("Vault SSH角色 %s 不在允许引用的范围内", mount+"/"+role)
+	}
+	return mount, role, nil
+}
+
+// matchVaultPath path 是否在 allowed 中某一项 <挂载点>[/<前缀>] 之下，前缀按整段匹配
+func matchVaultPath(allowed []string, mount, path string) bool {
+	for _, entry := range allowed {
+		m, prefix, _ := strings.Cut(strings.Trim(entry, "/"), "/")
+		if m != mount {
+			continue
+		}
+		if prefix == "" || path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Get 读取引用的最新版本
+func (s *VaultStore) Get(ctx context.Context, ref string) (string, error) {
+	mount, path, field, err := s.parseRef(ref)
+	if err != nil {
+		return "", err
+	}
+	var resp struct {
+		Data struct {
+			Data map[string]any `json:"data"`
+		} `json:"data"`
+	}
+	if err := s.do(ctx, http.MethodGet, mount+"/data/"+path, nil, &resp); err != nil {
+		return "", fmt.Errorf("读取Vault %s 失败: %w", mount+"/"+path, err)
+	}
+	value, ok := resp.Data.Data[field].(string)
+	if !ok {
+		return "", fmt.Errorf("Vault %s 中没有字段 %s: %w", mount+"/"+path, field, ErrNotFound)
+	}
+	return value, nil
+}
+
+// Delete 删除本系统为 path 写入的内容及其全部版本，引用的外部路径和其他 path 下的内容不删除
+func (s *VaultStore) Delete(ctx context.Context, path, ref string) error {
+	if !s.ownedBy(path, ref) {
+		return nil
+	}
+	mount, path, _, err := parseVaultRef(ref)
+	if err != nil {
+		return err
+	}
+	err = s.do(ctx, http.MethodDelete, mount+"/metadata/"+path, nil, nil)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("删除Vault %s 失败: %w", mount+"/"+path, err)
+	}
+	return nil
+}
+
+// Owned 是否为本系统写入的内容
+func (s *VaultStore) Owned(ref string) bool {
+	mount, path, _, err := parseVaultRef(ref)
+	return err == nil && mount == s.opts.KVMount && strings.HasPrefix(path, s.opts.PathPrefix+"/")
+}
+
+// ownedBy 是否为本系统通过 Put(path) 写入的内容
+func (s *VaultStore) ownedBy(path, ref string) bool {
+	mount, full, _, err := parseVaultRef(ref)
+	return err == nil && mount == s.opts.KVMount && strings.HasPrefix(full, s.opts.PathPrefix+"/"+strings.Trim(path, "/")+"/")
+}
+
+// SignSSHKey 使用 vault-ssh 引用的角色为公钥签发用户证书，返回 authorized_keys 格式的证书
+func (s *VaultStore) SignSSHKey(ctx context.Context, ref, publicKey, principal string) (string, error) {
+	mount, role, err := s.parseSSHRef(ref)
+	if err != nil {
+		return "", err
+	}
+	body := map[string]any{"public_key": publicKey, "cert_type": "user"}
+	if principal != "" {
+		body["valid_principals"] = principal
+	}
+	var resp struct {
+		Data struct {
+			SignedKey string `json:"signed_key"`
+		} `json:"data"`
+	}
+	if err := s.do(ctx, http.MethodPost, mount+"/sign/"+role, body, &resp); err != nil {
+		return "", fmt.Errorf("Vault签发SSH证书失败: %w", err)
+	}
+	if resp.Data.SignedKey == "" {
+		return "", errors.New("Vault未返回SSH证书")
+	}
+	return resp.Data.SignedKey, nil
+}
+
+func (s *VaultStore) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.opts.Address+"/v1/"+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", s.opts.Token)
+	if s.opts.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", s.opts.Namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode >= 300 {
+		var e struct {
+			Errors []string `json:"errors"`
+		}
+		json.Unmarshal(data, &e)
+		if len(e.Errors) > 0 {
+			return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.Join(e.Errors, "; "))
+		}
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	if out != nil && len(data) > 0 {
+		return json.Unmarshal(data, out)
+	}
+	return nil
+}
+
+// parseVaultRef 解析 vault:<挂载点>/<路径>#<字段>
+func parseVaultRef(ref string) (mount, path, field string, err error) {
+	if !strings.HasPrefix(ref, VaultPrefix) {
+		return "", "", "", fmt.Errorf("不是Vault引用: %s", ref)
+	}
+	rest, field, _ := strings.Cut(strings.TrimPrefix(ref, VaultPrefix), "#")
+	if field == "" {
+		field = vaultValueField
+	}
+	mount, path, ok := strings.Cut(strings.Trim(rest, "/"), "/")
+	if !ok || mount == "" || path == "" {
+		return "", "", "", fmt.Errorf("无效的Vault引用: %s", ref)
+	}
+	for _, seg := range strings.Split(path, "/") {
+		if seg == "" || seg == "." || seg == ".." {
+			return "", "", "", fmt.Errorf("无效的Vault引用: %s", ref)
+		}
+	}
+	return mount, path, field, nil
+}
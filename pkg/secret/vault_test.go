@@ -0,0 +1,293 @@
+// Copyright (c) 2026 DYCloud J.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package secret
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// fakeVault 模拟 Vault 的 KV v2 与 SSH 签发接口
+type fakeVault struct {
+	t  *testing.T
+	ca ssh.Signer
+
+	mu    sync.Mutex
+	data  map[string]map[string]any
+	reads int
+	signs int
+}
+
+func newFakeVault(t *testing.T) (*fakeVault, *httptest.Server) {
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	ca, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := &fakeVault{t: t, ca: ca, data: make(map[string]map[string]any)}
+	srv := httptest.NewServer(v)
+	t.Cleanup(srv.Close)
+	return v, srv
+}
+
+func (v *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Vault-Token") != "root" {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]any{"errors": []string{"permission denied"}})
+		return
+	}
+	mount, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/v1/"), "/")
+	kind, path, _ := strings.Cut(rest, "/")
+	key := mount + "/" + path
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	switch {
+	case kind == "data" && r.Method == http.MethodPost:
+		var body struct {
+			Data map[string]any `json:"data"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		v.data[key] = body.Data
+		json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"version": 1}})
+	case kind == "data" && r.Method == http.MethodGet:
+		v.reads++
+		data, ok := v.data[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"data": data}})
+	case kind == "metadata" && r.Method == http.MethodDelete:
+		delete(v.data, key)
+		w.WriteHeader(http.StatusNoContent)
+	case kind == "sign" && r.Method == http.MethodPost:
+		v.signs++
+		var body struct {
+			PublicKey       string `json:"public_key"`
+			ValidPrincipals string `json:"valid_principals"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(body.PublicKey))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		cert := &ssh.Certificate{
+			Key:             pub,
+			CertType:        ssh.UserCert,
+			KeyId:           path,
+			ValidPrincipals: []string{body.ValidPrincipals},
+			ValidAfter:      uint64(time.Now().Add(-time.Minute).Unix()),
+			ValidBefore:     uint64(time.Now().Add(30 * time.Minute).Unix()),
+		}
+		if err := cert.SignCert(rand.Reader, v.ca); err != nil {
+			v.t.Error(err)
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"signed_key": string(ssh.MarshalAuthorizedKey(cert))}})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestManager_VaultBackend(t *testing.T) {
+	fake, srv := newFakeVault(t)
+	// AllowedPaths 覆盖本系统写入的路径时，本系统的内容仍不能被用户引用
+	vault := NewVaultStore(VaultOptions{Address: srv.URL, Token: "root", AllowedPaths: []string{"secret/team", "secret/opshub"}})
+	m := NewManager(Builtin(), vault, BackendVault, time.Minute)
+	ctx := context.Background()
+
+	ref, err := m.Put(ctx, "credentials", "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(ref, "vault:secret/opshub/credentials/") || !vault.Owned(ref) {
+		t.Fatalf("unexpected ref %q", ref)
+	}
+	for i := 0; i < 2; i++ {
+		value, err := m.Get(ctx, ref)
+		if err != nil || value != "s3cret" {
+			t.Fatalf("get = %q, %v", value, err)
+		}
+	}
+	if fake.reads != 1 {
+		t.Fatalf("expected cached read, got %d reads", fake.reads)
+	}
+
+	// 未变化的字段保留原引用，修改后写入新内容并删除旧内容
+	if same, _ := m.Replace(ctx, "credentials", ref, ref); same != ref {
+		t.Fatalf("replace with same ref changed it to %q", same)
+	}
+	newRef, err := m.Replace(ctx, "credentials", ref, "changed")
+	if err != nil || newRef == ref {
+		t.Fatalf("replace = %q, %v", newRef, err)
+	}
+	if _, err := m.Get(ctx, ref); err == nil {
+		t.Fatal("old ref still readable after replace")
+	}
+
+	// 其他记录写入的引用不能作为新值提交，提交失败时不删除当前记录的内容
+	other, err := m.Put(ctx, "cloud-accounts", "other-record")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ref := range []string{other, strings.TrimSuffix(other, "#value") + "#password"} {
+		if _, err := m.Put(ctx, "credentials", ref); err == nil {
+			t.Fatalf("owned ref %q accepted as user input", ref)
+		}
+		if kept, err := m.Replace(ctx, "credentials", newRef, ref); err == nil || kept != "" {
+			t.Fatalf("replace with owned ref = %q, %v", kept, err)
+		}
+	}
+	if value, err := m.Get(ctx, newRef); err != nil || value != "changed" {
+		t.Fatalf("current ref after rejected replace = %q, %v", value, err)
+	}
+	// 只删除为同一 path 写入的内容
+	if err := m.Delete(ctx, "credentials", other); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := m.Get(ctx, other); err != nil || value != "other-record" {
+		t.Fatalf("other path secret deleted: %q, %v", value, err)
+	}
+	if err := m.Delete(ctx, "cloud-accounts", other); err != nil {
+		t.Fatal(err)
+	}
+
+	// 引用的外部路径按原样保存，删除时不动
+	fake.data["secret/team/db"] = map[string]any{"password": "external"}
+	external := "vault:secret/team/db#password"
+	stored, err := m.Put(ctx, "credentials", external)
+	if err != nil || stored != external {
+		t.Fatalf("put external = %q, %v", stored, err)
+	}
+	if value, _ := m.Get(ctx, external); value != "external" {
+		t.Fatalf("external value = %q", value)
+	}
+	if err := m.Delete(ctx, "credentials", external); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fake.data["secret/team/db"]; !ok {
+		t.Fatal("external secret deleted")
+	}
+
+	// 允许范围外的路径不能保存也不能读取，前缀按整段匹配
+	fake.data["secret/teams/db"] = map[string]any{"password": "other"}
+	for _, ref := range []string{"vault:secret/teams/db#password", "vault:kv/team/db", "vault:secret/opshub-other/x"} {
+		if _, err := m.Put(ctx, "credentials", ref); err == nil {
+			t.Fatalf("ref %q outside allowed paths accepted", ref)
+		}
+		if _, err := m.Get(ctx, ref); err == nil {
+			t.Fatalf("ref %q outside allowed paths readable", ref)
+		}
+	}
+	delete(fake.data, "secret/teams/db")
+	if err := m.Delete(ctx, "credentials", newRef); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.data) != 1 {
+		t.Fatalf("owned secrets left behind: %v", fake.data)
+	}
+}
+
+func TestManager_SSHKey(t *testing.T) {
+	fake, srv := newFakeVault(t)
+	m := NewManager(Builtin(), NewVaultStore(VaultOptions{Address: srv.URL, Token: "root", AllowedSSHRoles: []string{"ssh-client-signer/ops"}}), BackendDB, 0)
+	ctx := context.Background()
+
+	// 只允许配置的角色
+	for _, ref := range []string{"vault-ssh:ssh-client-signer/admin", "vault-ssh:ssh/ops"} {
+		if _, err := m.Put(ctx, "credentials", ref); err == nil {
+			t.Fatalf("ssh role %q outside allowed roles accepted", ref)
+		}
+		if _, err := m.SSHKey(ctx, ref, "root"); err == nil {
+			t.Fatalf("ssh role %q outside allowed roles signed", ref)
+		}
+	}
+	if fake.signs != 0 {
+		t.Fatalf("disallowed roles reached vault, signs = %d", fake.signs)
+	}
+	if _, err := m.Put(ctx, "credentials", "vault-ssh:ssh-client-signer/ops"); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := m.SSHKey(ctx, "vault-ssh:ssh-client-signer/ops", "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.ParsePrivateKey([]byte(key.PrivateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(key.Certificate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := pub.(*ssh.Certificate)
+	if string(cert.Key.Marshal()) != string(signer.PublicKey().Marshal()) {
+		t.Fatal("certificate does not match private key")
+	}
+	if len(cert.ValidPrincipals) != 1 || cert.ValidPrincipals[0] != "root" {
+		t.Fatalf("principals = %v", cert.ValidPrincipals)
+	}
+	if time.Until(key.ValidBefore) < 25*time.Minute {
+		t.Fatalf("valid before = %v", key.ValidBefore)
+	}
+
+	again, err := m.SSHKey(ctx, "vault-ssh:ssh-client-signer/ops", "root")
+	if err != nil || again.Certificate != key.Certificate || fake.signs != 1 {
+		t.Fatalf("expected cached certificate, signs = %d, err = %v", fake.signs, err)
+	}
+	if _, err := m.Get(ctx, "vault-ssh:ssh-client-signer/ops"); err == nil {
+		t.Fatal("ssh reference must not be readable")
+	}
+}
+
+func TestManager_DBOnly(t *testing.T) {
+	m := NewManager(Builtin(), nil, BackendVault, 0)
+	ctx := context.Background()
+
+	ref, err := m.Put(ctx, "credentials", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 与历史数据使用同一密钥，旧密文可直接读取
+	legacy, _ := Encrypt("password")
+	for _, r := range []string{ref, legacy} {
+		if value, err := m.Get(ctx, r); err != nil || value != "password" {
+			t.Fatalf("get %q = %q, %v", r, value, err)
+		}
+	}
+	if _, err := m.Put(ctx, "credentials", "vault:secret/x#value"); err == nil {
+		t.Fatal("vault reference accepted without vault")
+	}
+	if _, err := m.Get(ctx, "vault:secret/x#value"); err == nil {
+		t.Fatal("vault reference read without vault")
+	}
+}
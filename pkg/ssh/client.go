@@ -33,62 +33,43 @@ import (
 
 // Client SSH客户端
 type Client struct {
-	client *ssh.Client
+	client  *ssh.Client
+	release func() // 经跳板连接时释放跳板连接的引用
 }
 
 // NewClient 创建SSH客户端
 func NewClient(host string, port int, username, password string, privateKey []byte, passphrase string) (*Client, error) {
-	var authMethods []ssh.AuthMethod
-
-	// 优先使用私钥认证
-	if len(privateKey) > 0 {
-		var signer ssh.Signer
-		var err error
-
-		if passphrase != "" {
-			signer, err = ssh.ParsePrivateKeyWithPassphrase(privateKey, []byte(passphrase))
-		} else {
-			signer, err = ssh.ParsePrivateKey(privateKey)
-		}
-
-		if err != nil {
-			return nil, fmt.Errorf("解析私钥失败: %w", err)
-		}
-
-		authMethods = append(authMethods, ssh.PublicKeys(signer))
-	}
-
-	// 密码认证
-	if password != "" {
-		authMethods = append(authMethods, ssh.Password(password))
-	}
-
-	if len(authMethods) == 0 {
-		return nil, fmt.Errorf("至少需要一种认证方式")
-	}
-
-	config := &ssh.ClientConfig{
-		User:            username,
-		Auth:            authMethods,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(), // 生产环境应该验证主机密钥
-		Timeout:         10 * time.Second,
-	}
-
-	address := fmt.Sprintf("%s:%d", host, port)
-	client, err := ssh.Dial("tcp", address, config)
-	if err != nil {
-		return nil, fmt.Errorf("SSH连接失败: %w", err)
-	}
-
-	return &Client{client: client}, nil
+	return Dial(Endpoint{
+		Host:       host,
+		Port:       port,
+		Username:   username,
+		Password:   password,
+		PrivateKey: privateKey,
+		Passphrase: passphrase,
+	})
 }
 
 // Close 关闭连接
 func (c *Client) Close() error {
+	var err error
 	if c.client != nil {
-		return c.client.Close()
+		err = c.client.Close()
 	}
-	return nil
+	if c.release != nil {
+		c.release()
+		c.release = nil
+	}
+	return err
+}
+
+// ViaJump 是否经跳板连接
+func (c *Client) ViaJump() bool {
+	return c.release != nil
+}
+
+// NewSession 创建会话，用于交互式终端等需要直接操作会话的场景
+func (c *Client) NewSession() (*ssh.Session, error) {
+	return c.client.NewSession()
 }
 
 // Execute 执行命令
@@ -0,0 +1,67 @@
+// Copyright (c) 2026 DYCloud J.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package sshclient
+
+import (
+	"errors"
+	"net"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ErrHostKeyMismatch 主机密钥与已信任的密钥不一致
+var ErrHostKeyMismatch = errors.New("主机密钥已变更，可能存在中间人攻击，请管理员核实后重新信任")
+
+// ErrHostKeyUnknown 主机密钥尚未记录且本次连接不允许首次信任
+var ErrHostKeyUnknown = errors.New("主机密钥尚未信任，请先测试连接或采集主机信息；升级后的现有主机可由管理员批量信任")
+
+// KnownHosts 已知主机密钥存储
+type KnownHosts interface {
+	// Verify 校验主机提供的密钥，资产主机按 hostID，其他地址按 addr（host:port）。
+	// 未记录时 trustOnFirstUse 为 true 才信任并记录，否则返回 ErrHostKeyUnknown；
+	// 与已记录的不一致时返回 ErrHostKeyMismatch
+	Verify(hostID uint, addr string, key ssh.PublicKey, trustOnFirstUse bool) error
+}
+
+var (
+	knownHostsMu sync.RWMutex
+	knownHosts   KnownHosts
+)
+
+// SetKnownHosts 设置所有SSH连接共用的已知主机密钥存储
+func SetKnownHosts(k KnownHosts) {
+	knownHostsMu.Lock()
+	defer knownHostsMu.Unlock()
+	knownHosts = k
+}
+
+// HostKeyCallback 单个连接的主机密钥校验，hostID 为0表示非资产主机，未设置存储时不校验
+func HostKeyCallback(hostID uint, trustOnFirstUse bool) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		knownHostsMu.RLock()
+		k := knownHosts
+		knownHostsMu.RUnlock()
+		if k == nil {
+			return nil
+		}
+		return k.Verify(hostID, hostname, key, trustOnFirstUse)
+	}
+}
@@ -0,0 +1,330 @@
+// Copyright (c) 2026 DYCloud J.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package sshclient
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// dialTimeout 单跳建立连接和握手的超时
+	dialTimeout = 10 * time.Second
+	// jumpIdleTimeout 跳板连接无人使用后保留的时长，期间新连接直接复用
+	jumpIdleTimeout = 2 * time.Minute
+)
+
+// Endpoint SSH连接端点及其认证信息
+type Endpoint struct {
+	Host       string
+	Port       int
+	Username   string
+	Password   string
+	PrivateKey []byte
+	Passphrase string
+	// Certificate 私钥对应的用户证书（authorized_keys 格式），为空时只用私钥认证
+	Certificate []byte
+	// HostID 资产主机ID，主机密钥按ID校验；为0时按地址校验
+	HostID uint
+	// TrustOnFirstUse 主机密钥尚未记录时信任并记录，仅测试连接和采集主机信息时开启
+	TrustOnFirstUse bool
+}
+
+// Address 返回 host:port
+func (e Endpoint) Address() string {
+	port := e.Port
+	if port == 0 {
+		port = 22
+	}
+	return net.JoinHostPort(e.Host, strconv.Itoa(port))
+}
+
+// clientConfig 构建认证配置，优先使用私钥认证
+func (e Endpoint) clientConfig() (*ssh.ClientConfig, error) {
+	var authMethods []ssh.AuthMethod
+
+	if len(e.PrivateKey) > 0 {
+		var signer ssh.Signer
+		var err error
+		if e.Passphrase != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase(e.PrivateKey, []byte(e.Passphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey(e.PrivateKey)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("解析私钥失败: %w", err)
+		}
+		if len(e.Certificate) > 0 {
+			if signer, err = certSigner(e.Certificate, signer); err != nil {
+				return nil, err
+			}
+		}
+		authMethods = append(authMethods, ssh.PublicKeys(signer))
+	}
+
+	if e.Password != "" {
+		authMethods = append(authMethods, ssh.Password(e.Password))
+	}
+
+	if len(authMethods) == 0 {
+		return nil, fmt.Errorf("至少需要一种认证方式")
+	}
+
+	return &ssh.ClientConfig{
+		User:            e.Username,
+		Auth:            authMethods,
+		HostKeyCallback: HostKeyCallback(e.HostID, e.TrustOnFirstUse),
+		Timeout:         dialTimeout,
+	}, nil
+}
+
+// certSigner 使用证书认证的签名器
+func certSigner(certificate []byte, signer ssh.Signer) (ssh.Signer, error) {
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(certificate)
+	if err != nil {
+		return nil, fmt.Errorf("解析SSH证书失败: %w", err)
+	}
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("不是SSH证书")
+	}
+	return ssh.NewCertSigner(cert, signer)
+}
+
+// key 跳板连接复用的标识，认证信息不同的连接不共用
+func (e Endpoint) key() string {
+	h := sha256.New()
+	h.Write([]byte(e.Password))
+	h.Write([]byte{0})
+	h.Write(e.PrivateKey)
+	h.Write([]byte{0})
+	h.Write([]byte(e.Passphrase))
+	h.Write([]byte{0})
+	h.Write(e.Certificate)
+	// 主机密钥的校验范围和是否首次信任不同也不共用
+	h.Write([]byte{0})
+	h.Write([]byte(strconv.FormatUint(uint64(e.HostID), 10) + "/" + strconv.FormatBool(e.TrustOnFirstUse)))
+	return e.Username + "@" + e.Address() + "#" + hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// Dial 依次经过 jumps 中的跳板连接目标主机，没有跳板时直连。
+// 相同的跳板链在多个连接间复用，最后一个使用者关闭后空闲一段时间再断开。
+func Dial(target Endpoint, jumps ...Endpoint) (*Client, error) {
+	config, err := target.clientConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(jumps) == 0 {
+		client, err := ssh.Dial("tcp", target.Address(), config)
+		if err != nil {
+			return nil, fmt.Errorf("SSH连接失败: %w", err)
+		}
+		return &Client{client: client}, nil
+	}
+
+	jump, err := defaultJumpPool.acquire(jumps)
+	if err != nil {
+		return nil, err
+	}
+	client, err := dialVia(jump.client, target.Address(), config)
+	if err != nil {
+		defaultJumpPool.release(jump)
+		return nil, fmt.Errorf("SSH连接失败(经跳板 %s): %w", jumps[len(jumps)-1].Address(), err)
+	}
+	var once sync.Once
+	return &Client{client: client, release: func() {
+		once.Do(func() { defaultJumpPool.release(jump) })
+	}}, nil
+}
+
+// dialVia 通过已建立的SSH连接转发到 addr 并完成握手
+func dialVia(via *ssh.Client, addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	type result struct {
+		client *ssh.Client
+		err    error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		conn, err := via.Dial("tcp", addr)
+		if err != nil {
+			ch <- result{err: err}
+			return
+		}
+		c, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+		if err != nil {
+			conn.Close()
+			ch <- result{err: err}
+			return
+		}
+		ch <- result{client: ssh.NewClient(c, chans, reqs)}
+	}()
+
+	select {
+	case r := <-ch:
+		return r.client, r.err
+	case <-time.After(config.Timeout):
+		// 超时后建立的连接直接关闭
+		go func() {
+			if r := <-ch; r.client != nil {
+				r.client.Close()
+			}
+		}()
+		return nil, fmt.Errorf("连接 %s 超时", addr)
+	}
+}
+
+// jumpConn 跳板链上某一跳的连接，持有上一跳连接的引用
+type jumpConn struct {
+	key    string
+	parent *jumpConn
+	client *ssh.Client
+	err    error
+	ready  chan struct{}
+	refs   int
+	idle   *time.Timer
+	done   bool
+}
+
+// jumpPool 按跳板链复用连接
+type jumpPool struct {
+	mu          sync.Mutex
+	conns       map[string]*jumpConn
+	idleTimeout time.Duration
+}
+
+var defaultJumpPool = &jumpPool{conns: make(map[string]*jumpConn), idleTimeout: jumpIdleTimeout}
+
+// acquire 获取到 hops 最后一跳的连接，不存在时逐跳建立
+func (p *jumpPool) acquire(hops []Endpoint) (*jumpConn, error) {
+	keys := make([]string, len(hops))
+	for i, hop := range hops {
+		keys[i] = hop.key()
+	}
+	key := strings.Join(keys, ">")
+
+	p.mu.Lock()
+	if jc, ok := p.conns[key]; ok {
+		jc.refs++
+		if jc.idle != nil {
+			jc.idle.Stop()
+			jc.idle = nil
+		}
+		p.mu.Unlock()
+		<-jc.ready
+		if jc.err != nil {
+			p.release(jc)
+			return nil, jc.err
+		}
+		return jc, nil
+	}
+	jc := &jumpConn{key: key, refs: 1, ready: make(chan struct{})}
+	p.conns[key] = jc
+	p.mu.Unlock()
+
+	jc.client, jc.parent, jc.err = p.dial(hops)
+	close(jc.ready)
+	if jc.err != nil {
+		p.mu.Lock()
+		if p.conns[key] == jc {
+			delete(p.conns, key)
+		}
+		jc.done = true
+		p.mu.Unlock()
+		return nil, jc.err
+	}
+
+	// 连接断开后不再复用，仍在使用的连接由使用者关闭
+	go func() {
+		jc.client.Wait()
+		p.mu.Lock()
+		if p.conns[key] == jc {
+			delete(p.conns, key)
+		}
+		p.mu.Unlock()
+	}()
+	return jc, nil
+}
+
+// dial 建立到 hops 最后一跳的连接，首跳直连，其余经上一跳转发
+func (p *jumpPool) dial(hops []Endpoint) (*ssh.Client, *jumpConn, error) {
+	last := hops[len(hops)-1]
+	config, err := last.clientConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("跳板 %s: %w", last.Address(), err)
+	}
+
+	if len(hops) == 1 {
+		client, err := ssh.Dial("tcp", last.Address(), config)
+		if err != nil {
+			return nil, nil, fmt.Errorf("连接跳板 %s 失败: %w", last.Address(), err)
+		}
+		return client, nil, nil
+	}
+
+	parent, err := p.acquire(hops[:len(hops)-1])
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := dialVia(parent.client, last.Address(), config)
+	if err != nil {
+		p.release(parent)
+		return nil, nil, fmt.Errorf("连接跳板 %s 失败: %w", last.Address(), err)
+	}
+	return client, parent, nil
+}
+
+// release 释放引用，最后一个引用释放后空闲超时再断开
+func (p *jumpPool) release(jc *jumpConn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	jc.refs--
+	if jc.refs > 0 || jc.done {
+		return
+	}
+	jc.idle = time.AfterFunc(p.idleTimeout, func() { p.close(jc) })
+}
+
+// close 断开空闲的跳板连接并释放上一跳
+func (p *jumpPool) close(jc *jumpConn) {
+	p.mu.Lock()
+	if jc.refs > 0 || jc.done {
+		p.mu.Unlock()
+		return
+	}
+	jc.done = true
+	if p.conns[jc.key] == jc {
+		delete(p.conns, jc.key)
+	}
+	p.mu.Unlock()
+
+	jc.client.Close()
+	if jc.parent != nil {
+		p.release(jc.parent)
+	}
+}
@@ -0,0 +1,183 @@
+package sshclient
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// testServer 只支持密码认证、exec 和 direct-tcpip 转发的SSH服务端
+type testServer struct {
+	name     string
+	addr     string
+	accepted atomic.Int32
+	active   atomic.Int32
+}
+
+func startTestServer(t *testing.T, name, password string) *testServer {
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(c ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			if string(pass) != password {
+				return nil, io.EOF
+			}
+			return nil, nil
+		},
+	}
+	config.AddHostKey(signer)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	s := &testServer{name: name, addr: ln.Addr().String()}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn, config)
+		}
+	}()
+	return s
+}
+
+func (s *testServer) serve(conn net.Conn, config *ssh.ServerConfig) {
+	sconn, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	s.accepted.Add(1)
+	s.active.Add(1)
+	defer s.active.Add(-1)
+	go ssh.DiscardRequests(reqs)
+	for newCh := range chans {
+		switch newCh.ChannelType() {
+		case "session":
+			ch, reqs, _ := newCh.Accept()
+			go func() {
+				for req := range reqs {
+					if req.Type != "exec" {
+						req.Reply(false, nil)
+						continue
+					}
+					req.Reply(true, nil)
+					io.WriteString(ch, s.name)
+					ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{0}))
+					ch.Close()
+				}
+			}()
+		case "direct-tcpip":
+			var payload struct {
+				Host       string
+				Port       uint32
+				OriginHost string
+				OriginPort uint32
+			}
+			if err := ssh.Unmarshal(newCh.ExtraData(), &payload); err != nil {
+				newCh.Reject(ssh.ConnectionFailed, err.Error())
+				continue
+			}
+			target, err := net.Dial("tcp", net.JoinHostPort(payload.Host, strconv.Itoa(int(payload.Port))))
+			if err != nil {
+				newCh.Reject(ssh.ConnectionFailed, err.Error())
+				continue
+			}
+			ch, reqs, _ := newCh.Accept()
+			go ssh.DiscardRequests(reqs)
+			go func() {
+				var wg sync.WaitGroup
+				wg.Add(2)
+				go func() { io.Copy(target, ch); target.Close(); wg.Done() }()
+				go func() { io.Copy(ch, target); ch.CloseWrite(); wg.Done() }()
+				wg.Wait()
+				ch.Close()
+			}()
+		default:
+			newCh.Reject(ssh.UnknownChannelType, "")
+		}
+	}
+	sconn.Wait()
+}
+
+func (s *testServer) endpoint(password string) Endpoint {
+	host, port, _ := net.SplitHostPort(s.addr)
+	p, _ := strconv.Atoi(port)
+	return Endpoint{Host: host, Port: p, Username: "ops", Password: password}
+}
+
+func TestDial_JumpChainReuse(t *testing.T) {
+	defaultJumpPool.idleTimeout = 300 * time.Millisecond
+	defer func() { defaultJumpPool.idleTimeout = jumpIdleTimeout }()
+
+	bastion := startTestServer(t, "bastion", "b-pass")
+	inner := startTestServer(t, "inner", "i-pass")
+	target := startTestServer(t, "target", "t-pass")
+	jumps := []Endpoint{bastion.endpoint("b-pass"), inner.endpoint("i-pass")}
+
+	first, err := Dial(target.endpoint("t-pass"), jumps...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out, err := first.Execute("hostname"); err != nil || out != "target" {
+		t.Fatalf("应经两跳到达目标主机: %q %v", out, err)
+	}
+	if !first.ViaJump() {
+		t.Fatal("应标记为经跳板连接")
+	}
+
+	second, err := Dial(target.endpoint("t-pass"), jumps...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bastion.accepted.Load() != 1 || inner.accepted.Load() != 1 || target.accepted.Load() != 2 {
+		t.Fatalf("跳板连接应复用: bastion=%d inner=%d target=%d",
+			bastion.accepted.Load(), inner.accepted.Load(), target.accepted.Load())
+	}
+
+	// 每跳使用自己的凭证
+	if _, err := Dial(target.endpoint("t-pass"), bastion.endpoint("b-pass"), inner.endpoint("wrong")); err == nil {
+		t.Fatal("跳板凭证错误时应连接失败")
+	}
+
+	// 最后一个使用者关闭后空闲超时再断开
+	first.Close()
+	second.Close()
+	second.Close()
+	time.Sleep(50 * time.Millisecond)
+	if bastion.active.Load() != 1 {
+		t.Fatal("空闲超时前跳板连接应保留")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for bastion.active.Load() != 0 || inner.active.Load() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("空闲超时后跳板连接应断开: bastion=%d inner=%d", bastion.active.Load(), inner.active.Load())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 断开后重新建立
+	third, err := Dial(target.endpoint("t-pass"), jumps...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer third.Close()
+	if bastion.accepted.Load() != 2 {
+		t.Fatalf("断开后应重新连接跳板: %d", bastion.accepted.Load())
+	}
+}
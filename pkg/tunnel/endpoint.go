@@ -0,0 +1,239 @@
+// Package tunnel 实现经 Agent gRPC 流复用的 TCP 隧道两端的数据搬运和流控，
+// Agent 与服务端共用，各自实现 Peer 把数据封装为对应方向的消息
+package tunnel
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// DefaultWindow 默认接收窗口，限制每条连接在对方内存中排队的数据量
+	DefaultWindow = 256 << 10
+	// ChunkSize 单条数据消息的最大负载
+	ChunkSize = 32 << 10
+)
+
+// ErrWindowExceeded 对端发送的未确认数据超出了通告的接收窗口
+var ErrWindowExceeded = errors.New("对端发送数据超出接收窗口")
+
+// Peer 隧道的对端
+type Peer interface {
+	SendData(connID string, data []byte) error
+	SendAck(connID string, n int) error
+	SendClose(connID, reason string) error
+}
+
+// Endpoint 隧道的一端，在本地 TCP 连接和对端之间双向搬运数据。
+// 本地读取的数据受对端窗口限制，对端的数据先进入队列再由独立协程写出，
+// 因此 Deliver/Ack/Shutdown 不会阻塞调用方的消息接收循环
+type Endpoint struct {
+	id   string
+	conn net.Conn
+	peer Peer
+
+	mu         sync.Mutex
+	cond       *sync.Cond
+	credit     int      // 还可以发送给对端的字节数
+	queue      [][]byte // 对端发来、尚未写入本地连接的数据
+	queued     int
+	recvWindow int
+	peerClosed bool // 对端已关闭，写完队列后结束
+	closed     bool
+	reason     string
+
+	bytesIn  atomic.Int64 // 对端 → 本地连接
+	bytesOut atomic.Int64 // 本地连接 → 对端
+	once     sync.Once
+	done     chan struct{}
+}
+
+// NewEndpoint 创建隧道端点，recvWindow 为本端通告给对端的接收窗口。
+// 端点创建后即可接收对端数据，Start 之前收到的数据会排队等待写出
+func NewEndpoint(id string, conn net.Conn, peer Peer, recvWindow int) *Endpoint {
+	if recvWindow <= 0 {
+		recvWindow = DefaultWindow
+	}
+	e := &Endpoint{
+		id:         id,
+		conn:       conn,
+		peer:       peer,
+		recvWindow: recvWindow,
+		done:       make(chan struct{}),
+	}
+	e.cond = sync.NewCond(&e.mu)
+	return e
+}
+
+// ID 连接ID
+func (e *Endpoint) ID() string {
+	return e.id
+}
+
+// Start 启动读写协程，sendWindow 为对端通告的接收窗口
+func (e *Endpoint) Start(sendWindow int) {
+	if sendWindow <= 0 {
+		sendWindow = DefaultWindow
+	}
+	e.Ack(sendWindow)
+	go e.readLoop()
+	go e.writeLoop()
+}
+
+// Deliver 接收对端数据，超出接收窗口时关闭连接
+func (e *Endpoint) Deliver(data []byte) error {
+	e.mu.Lock()
+	if e.closed || e.peerClosed {
+		e.mu.Unlock()
+		return nil
+	}
+	if e.queued+len(data) > e.recvWindow {
+		e.mu.Unlock()
+		e.Close(ErrWindowExceeded.Error())
+		return ErrWindowExceeded
+	}
+	e.queue = append(e.queue, data)
+	e.queued += len(data)
+	e.cond.Broadcast()
+	e.mu.Unlock()
+	return nil
+}
+
+// Ack 对端已写出 n 字节，归还发送额度
+func (e *Endpoint) Ack(n int) {
+	e.mu.Lock()
+	e.credit += n
+	e.cond.Broadcast()
+	e.mu.Unlock()
+}
+
+// Shutdown 对端关闭连接，已收到的数据写完后关闭本地连接
+func (e *Endpoint) Shutdown(reason string) {
+	e.mu.Lock()
+	if !e.closed {
+		e.peerClosed = true
+		e.reason = reason
+		e.cond.Broadcast()
+	}
+	e.mu.Unlock()
+}
+
+// Close 本端主动关闭并通知对端
+func (e *Endpoint) Close(reason string) {
+	e.once.Do(func() {
+		e.mu.Lock()
+		notify := !e.peerClosed
+		e.closed = true
+		if notify || e.reason == "" {
+			e.reason = reason
+		}
+		e.cond.Broadcast()
+		e.mu.Unlock()
+
+		e.conn.Close()
+		if notify {
+			e.peer.SendClose(e.id, reason)
+		}
+		close(e.done)
+	})
+}
+
+// Done 连接关闭后关闭
+func (e *Endpoint) Done() <-chan struct{} {
+	return e.done
+}
+
+// Reason 关闭原因，正常关闭时为空
+func (e *Endpoint) Reason() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.reason
+}
+
+// Stats 返回对端写入本地连接和本地连接发往对端的字节数
+func (e *Endpoint) Stats() (in, out int64) {
+	return e.bytesIn.Load(), e.bytesOut.Load()
+}
+
+func (e *Endpoint) readLoop() {
+	buf := make([]byte, ChunkSize)
+	for {
+		n := e.acquire(len(buf))
+		if n == 0 {
+			return
+		}
+		read, err := e.conn.Read(buf[:n])
+		if read < n {
+			e.Ack(n - read)
+		}
+		if read > 0 {
+			data := make([]byte, read)
+			copy(data, buf[:read])
+			if sendErr := e.peer.SendData(e.id, data); sendErr != nil {
+				e.Close(sendErr.Error())
+				return
+			}
+			e.bytesOut.Add(int64(read))
+		}
+		if err != nil {
+			e.Close(closeReason(err))
+			return
+		}
+	}
+}
+
+// acquire 等待发送额度，连接关闭时返回 0
+func (e *Endpoint) acquire(max int) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for e.credit <= 0 && !e.closed && !e.peerClosed {
+		e.cond.Wait()
+	}
+	if e.closed || e.peerClosed {
+		return 0
+	}
+	n := min(e.credit, max)
+	e.credit -= n
+	return n
+}
+
+func (e *Endpoint) writeLoop() {
+	for {
+		e.mu.Lock()
+		for len(e.queue) == 0 && !e.closed && !e.peerClosed {
+			e.cond.Wait()
+		}
+		if e.closed || len(e.queue) == 0 {
+			reason := e.reason
+			e.mu.Unlock()
+			e.Close(reason)
+			return
+		}
+		data := e.queue[0]
+		e.queue[0] = nil
+		e.queue = e.queue[1:]
+		e.queued -= len(data)
+		e.mu.Unlock()
+
+		if _, err := e.conn.Write(data); err != nil {
+			e.Close(closeReason(err))
+			return
+		}
+		e.bytesIn.Add(int64(len(data)))
+		if err := e.peer.SendAck(e.id, len(data)); err != nil {
+			e.Close(err.Error())
+			return
+		}
+	}
+}
+
+// closeReason 对方正常关闭连接不作为错误上报
+func closeReason(err error) string {
+	if errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) {
+		return ""
+	}
+	return err.Error()
+}
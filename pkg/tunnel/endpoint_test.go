@@ -0,0 +1,117 @@
+package tunnel
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// pipePeer 把消息直接投递给另一端，模拟按序到达的 gRPC 流
+type pipePeer struct {
+	remote *Endpoint
+}
+
+func (p *pipePeer) SendData(_ string, data []byte) error {
+	return p.remote.Deliver(data)
+}
+
+func (p *pipePeer) SendAck(_ string, n int) error {
+	p.remote.Ack(n)
+	return nil
+}
+
+func (p *pipePeer) SendClose(_ string, reason string) error {
+	p.remote.Shutdown(reason)
+	return nil
+}
+
+// newPair 创建一对互联的端点，返回两端各自的本地对端连接
+func newPair(window int) (a, b *Endpoint, clientA, clientB net.Conn) {
+	localA, clientA := net.Pipe()
+	localB, clientB := net.Pipe()
+	peerA, peerB := &pipePeer{}, &pipePeer{}
+	a = NewEndpoint("c1", localA, peerA, window)
+	b = NewEndpoint("c1", localB, peerB, window)
+	peerA.remote, peerB.remote = b, a
+	a.Start(window)
+	b.Start(window)
+	return a, b, clientA, clientB
+}
+
+func TestEndpoint_TransferWithSmallWindow(t *testing.T) {
+	a, b, clientA, clientB := newPair(1024)
+
+	payload := make([]byte, 300<<10)
+	rand.Read(payload)
+
+	received := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(clientB)
+		received <- data
+	}()
+	if _, err := clientA.Write(payload); err != nil {
+		t.Fatal(err)
+	}
+	clientA.Close()
+
+	select {
+	case data := <-received:
+		if !bytes.Equal(data, payload) {
+			t.Fatalf("数据不一致: 收到 %d 字节, 期望 %d", len(data), len(payload))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("传输超时")
+	}
+
+	for _, e := range []*Endpoint{a, b} {
+		select {
+		case <-e.Done():
+		case <-time.After(time.Second):
+			t.Fatal("连接未关闭")
+		}
+		if e.Reason() != "" {
+			t.Fatalf("正常关闭不应有原因: %q", e.Reason())
+		}
+	}
+	if _, out := a.Stats(); out != int64(len(payload)) {
+		t.Fatalf("发送端计数错误: %d", out)
+	}
+	if in, _ := b.Stats(); in != int64(len(payload)) {
+		t.Fatalf("接收端计数错误: %d", in)
+	}
+}
+
+func TestEndpoint_WindowExceeded(t *testing.T) {
+	local, client := net.Pipe()
+	defer client.Close()
+	peer := &closeRecorder{}
+	e := NewEndpoint("c1", local, peer, 16)
+	e.Start(0)
+
+	// 本地连接不读取，写协程最多取走一块，其余数据堆积在队列中
+	var err error
+	for i := 0; i < 3 && err == nil; i++ {
+		err = e.Deliver(make([]byte, 10))
+	}
+	if err != ErrWindowExceeded {
+		t.Fatalf("期望 ErrWindowExceeded, 实际 %v", err)
+	}
+	<-e.Done()
+	if peer.reason != ErrWindowExceeded.Error() {
+		t.Fatalf("应通知对端关闭原因, 实际 %q", peer.reason)
+	}
+}
+
+type closeRecorder struct {
+	reason string
+}
+
+func (r *closeRecorder) SendData(string, []byte) error { return nil }
+func (r *closeRecorder) SendAck(string, int) error     { return nil }
+func (r *closeRecorder) SendClose(_ string, reason string) error {
+	r.reason = reason
+	return nil
+}
@@ -21,9 +21,7 @@ package server
 
 import (
 	"bufio"
-	"crypto/aes"
-	"crypto/cipher"
-	"encoding/base64"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -35,13 +33,11 @@ import (
 	"github.com/gin-gonic/gin"
 	assetbiz "github.com/ydcloud-dy/opshub/internal/biz/asset"
 	"github.com/ydcloud-dy/opshub/pkg/response"
+	"github.com/ydcloud-dy/opshub/pkg/secret"
+	sshclient "github.com/ydcloud-dy/opshub/pkg/ssh"
 	"github.com/ydcloud-dy/opshub/plugins/nginx/model"
-	"golang.org/x/crypto/ssh"
 )
 
-// 加密密钥（与凭证仓库相同）
-var encryptionKey = []byte("opshub-enc-key-32-bytes-long!!!!")
-
 // NginxLogEntry 解析后的日志条目
 type NginxLogEntry struct {
 	Timestamp     time.Time
@@ -174,7 +170,7 @@ func (h *Handler) collectHostLogs(source *model.NginxSource) (int, error) {
 	}
 
 	// 创建SSH连接
-	sshClient, err := createSSHClient(&host, &credential)
+	sshClient, err := h.createSSHClient(context.Background(), &host, &credential)
 	if err != nil {
 		return 0, fmt.Errorf("SSH连接失败: %w", err)
 	}
@@ -854,86 +850,25 @@ func extractHost(uri, referer string) string {
 	return ""
 }
 
-// createSSHClient 创建SSH客户端
-func createSSHClient(host *assetbiz.Host, credential *assetbiz.Credential) (*ssh.Client, error) {
-	var authMethods []ssh.AuthMethod
-
-	switch credential.Type {
-	case "password":
-		authMethods = append(authMethods, ssh.Password(credential.Password))
-	case "key", "private_key":
-		signer, err := ssh.ParsePrivateKey([]byte(credential.PrivateKey))
-		if err != nil {
-			return nil, fmt.Errorf("解析私钥失败: %w", err)
-		}
-		authMethods = append(authMethods, ssh.PublicKeys(signer))
-	default:
-		return nil, fmt.Errorf("不支持的凭证类型: %s", credential.Type)
+// createSSHClient 创建SSH客户端，主机或所属分组配置了跳板链时经跳板连接
+func (h *Handler) createSSHClient(ctx context.Context, host *assetbiz.Host, credential *assetbiz.Credential) (*sshclient.Client, error) {
+	target := *host
+	if credential.Username != "" {
+		target.SSHUser = credential.Username
 	}
-
-	config := &ssh.ClientConfig{
-		User:            credential.Username,
-		Auth:            authMethods,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-		Timeout:         30 * time.Second,
-	}
-
-	addr := fmt.Sprintf("%s:%d", host.IP, host.Port)
-	return ssh.Dial("tcp", addr, config)
+	return h.jumpChains.Connect(ctx, &target, credential)
 }
 
-// decryptCredential 解密凭证
+// decryptCredential 从凭证存储后端取回密码和私钥
 func decryptCredential(credential *assetbiz.Credential) error {
-	if credential.Password != "" {
-		decrypted, err := decrypt(credential.Password)
-		if err != nil {
-			return fmt.Errorf("解密密码失败: %w", err)
-		}
-		credential.Password = decrypted
+	store := secret.Default()
+	ctx := context.Background()
+	var err error
+	if credential.Password, err = store.Get(ctx, credential.Password); err != nil {
+		return fmt.Errorf("解密密码失败: %w", err)
 	}
-
-	if credential.PrivateKey != "" {
-		decrypted, err := decrypt(credential.PrivateKey)
-		if err != nil {
-			return fmt.Errorf("解密私钥失败: %w", err)
-		}
-		credential.PrivateKey = decrypted
+	if credential.PrivateKey, err = store.Get(ctx, credential.PrivateKey); err != nil {
+		return fmt.Errorf("解密私钥失败: %w", err)
 	}
-
 	return nil
 }
-
-// decrypt AES-GCM 解密
-func decrypt(ciphertext string) (string, error) {
-	if ciphertext == "" {
-		return "", nil
-	}
-
-	data, err := base64.StdEncoding.DecodeString(ciphertext)
-	if err != nil {
-		return "", err
-	}
-
-	block, err := aes.NewCipher(encryptionKey)
-	if err != nil {
-		return "", err
-	}
-
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return "", err
-	}
-
-	nonceSize := gcm.NonceSize()
-	if len(data) < nonceSize {
-		return "", fmt.Errorf("ciphertext too short")
-	}
-
-	nonce, cipherData := data[:nonceSize], data[nonceSize:]
-	plaintext, err := gcm.Open(nil, nonce, cipherData, nil)
-	if err != nil {
-		return "", err
-	}
-
-	return string(plaintext), nil
-}
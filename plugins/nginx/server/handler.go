@@ -21,6 +21,7 @@ package server
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -35,6 +36,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	assetbiz "github.com/ydcloud-dy/opshub/internal/biz/asset"
+	assetdata "github.com/ydcloud-dy/opshub/internal/data/asset"
 	"github.com/ydcloud-dy/opshub/pkg/response"
 	"github.com/ydcloud-dy/opshub/plugins/nginx/model"
 	"github.com/ydcloud-dy/opshub/plugins/nginx/repository"
@@ -93,6 +95,8 @@ type Handler struct {
 	geoSvc   *service.GeolocationService
 	uaParser *service.UAParser
 	cache    *overviewCache // 概况数据缓存
+	// jumpChains 按主机生效的跳板链建立SSH连接
+	jumpChains *assetbiz.JumpChainUseCase
 }
 
 func NewHandler(db *gorm.DB) *Handler {
@@ -102,6 +106,8 @@ func NewHandler(db *gorm.DB) *Handler {
 		geoSvc:   service.NewGeolocationService(),
 		uaParser: service.NewUAParser(),
 		cache:    newOverviewCache(100), // 最多缓存100个数据源的概况
+		jumpChains: assetbiz.NewJumpChainUseCase(assetdata.NewJumpHopRepo(db), assetdata.NewHostRepo(db),
+			assetdata.NewAssetGroupRepo(db), assetdata.NewCredentialRepo(db)),
 	}
 }
 
@@ -566,7 +572,7 @@ func (h *Handler) fetchNginxStatsFromHost(source *model.NginxSource, startDate,
 	}
 
 	// 创建SSH连接
-	sshClient, err := createSSHClient(&host, &credential)
+	sshClient, err := h.createSSHClient(context.Background(), &host, &credential)
 	if err != nil {
 		return nil, fmt.Errorf("SSH连接失败: %w", err)
 	}
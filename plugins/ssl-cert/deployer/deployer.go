@@ -61,6 +61,8 @@ type HostInfo struct {
 	Password   string
 	PrivateKey []byte
 	Passphrase string
+	// Certificate 私钥对应的 SSH 用户证书（Vault SSH 签名或 CA 凭证签发）
+	Certificate []byte
 }
 
 // K8sClient K8s客户端接口
@@ -42,14 +42,16 @@ func (d *NginxSSHDeployer) Deploy(ctx context.Context, cert *model.CertBundle, c
 	}
 
 	// 创建SSH客户端
-	client, err := sshclient.NewClient(
-		hostInfo.Host,
-		hostInfo.Port,
-		hostInfo.Username,
-		hostInfo.Password,
-		hostInfo.PrivateKey,
-		hostInfo.Passphrase,
-	)
+	client, err := sshclient.Dial(sshclient.Endpoint{
+		Host:        hostInfo.Host,
+		Port:        hostInfo.Port,
+		Username:    hostInfo.Username,
+		Password:    hostInfo.Password,
+		PrivateKey:  hostInfo.PrivateKey,
+		Passphrase:  hostInfo.Passphrase,
+		Certificate: hostInfo.Certificate,
+		HostID:      nginxConfig.HostID,
+	})
 	if err != nil {
 		return fmt.Errorf("create ssh client failed: %w", err)
 	}
@@ -154,14 +156,16 @@ func (d *NginxSSHDeployer) Test(ctx context.Context, config *model.DeployConfig)
 	}
 
 	// 创建SSH客户端并测试连接
-	client, err := sshclient.NewClient(
-		hostInfo.Host,
-		hostInfo.Port,
-		hostInfo.Username,
-		hostInfo.Password,
-		hostInfo.PrivateKey,
-		hostInfo.Passphrase,
-	)
+	client, err := sshclient.Dial(sshclient.Endpoint{
+		Host:        hostInfo.Host,
+		Port:        hostInfo.Port,
+		Username:    hostInfo.Username,
+		Password:    hostInfo.Password,
+		PrivateKey:  hostInfo.PrivateKey,
+		Passphrase:  hostInfo.Passphrase,
+		Certificate: hostInfo.Certificate,
+		HostID:      nginxConfig.HostID,
+	})
 	if err != nil {
 		return fmt.Errorf("create ssh client failed: %w", err)
 	}
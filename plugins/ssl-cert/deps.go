@@ -9,6 +9,8 @@ import (
 	"fmt"
 	"time"
 
+	assetbiz "github.com/ydcloud-dy/opshub/internal/biz/asset"
+	assetdata "github.com/ydcloud-dy/opshub/internal/data/asset"
 	"gorm.io/gorm"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -18,20 +20,18 @@ import (
 	"github.com/ydcloud-dy/opshub/plugins/ssl-cert/deployer"
 )
 
-// 凭证加密密钥（与 internal/data/asset/host.go 保持一致）
-var encryptionKey = []byte("opshub-enc-key-32-bytes-long!!!!")
-
 // kubeconfig 加密密钥（与 kubernetes 插件保持一致）
 const kubeConfigEncryptionKey = "opshub-k8s-encrypt-key-32bytes!!"
 
 // HostGetter 主机信息获取器
 type HostGetter struct {
-	db *gorm.DB
+	db             *gorm.DB
+	credentialRepo assetbiz.CredentialRepo
 }
 
 // NewHostGetter 创建主机信息获取器
 func NewHostGetter(db *gorm.DB) *HostGetter {
-	return &HostGetter{db: db}
+	return &HostGetter{db: db, credentialRepo: assetdata.NewCredentialRepo(db)}
 }
 
 // Host 主机模型(简化版,与asset.Host对应)
@@ -49,57 +49,6 @@ func (Host) TableName() string {
 	return "hosts"
 }
 
-// Credential 凭证模型
-type Credential struct {
-	ID         uint   `gorm:"primarykey"`
-	Name       string `gorm:"type:varchar(100)"`
-	Type       string `gorm:"type:varchar(20)"` // password/key
-	Username   string `gorm:"type:varchar(100)"`
-	Password   string `gorm:"type:text"`
-	PrivateKey string `gorm:"type:text"`
-	Passphrase string `gorm:"type:varchar(255)"`
-}
-
-// TableName 表名
-func (Credential) TableName() string {
-	return "credentials"
-}
-
-// decrypt 解密凭证数据
-func decrypt(ciphertext string) (string, error) {
-	if ciphertext == "" {
-		return "", nil
-	}
-
-	data, err := base64.StdEncoding.DecodeString(ciphertext)
-	if err != nil {
-		return "", err
-	}
-
-	block, err := aes.NewCipher(encryptionKey)
-	if err != nil {
-		return "", err
-	}
-
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return "", err
-	}
-
-	nonceSize := gcm.NonceSize()
-	if len(data) < nonceSize {
-		return "", fmt.Errorf("ciphertext too short")
-	}
-
-	nonce, ciphertextBytes := data[:nonceSize], data[nonceSize:]
-	plaintext, err := gcm.Open(nil, nonce, ciphertextBytes, nil)
-	if err != nil {
-		return "", err
-	}
-
-	return string(plaintext), nil
-}
-
 // GetHost 获取主机信息
 func (g *HostGetter) GetHost(ctx context.Context, hostID uint) (*deployer.HostInfo, error) {
 	var host Host
@@ -114,33 +63,17 @@ func (g *HostGetter) GetHost(ctx context.Context, hostID uint) (*deployer.HostIn
 		Username: host.SSHUser,
 	}
 
-	// 获取凭证信息
+	// 获取凭证信息：与主机终端共用凭证解密，支持 Vault 引用、Vault SSH 签名和 CA 签发证书
 	if host.CredentialID > 0 {
-		var cred Credential
-		if err := g.db.WithContext(ctx).First(&cred, host.CredentialID).Error; err == nil {
-			if cred.Type == "password" {
-				// 解密密码
-				password, err := decrypt(cred.Password)
-				if err != nil {
-					return nil, fmt.Errorf("decrypt password failed: %w", err)
-				}
-				info.Password = password
-			} else if cred.Type == "key" {
-				// 解密私钥
-				privateKey, err := decrypt(cred.PrivateKey)
-				if err != nil {
-					return nil, fmt.Errorf("decrypt private key failed: %w", err)
-				}
-				info.PrivateKey = []byte(privateKey)
-				// 解密私钥密码
-				if cred.Passphrase != "" {
-					passphrase, err := decrypt(cred.Passphrase)
-					if err != nil {
-						return nil, fmt.Errorf("decrypt passphrase failed: %w", err)
-					}
-					info.Passphrase = passphrase
-				}
-			}
+		cred, err := g.credentialRepo.GetByIDDecrypted(ctx, host.CredentialID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("get credential failed: %w", err)
+		}
+		if cred != nil {
+			info.Password = cred.Password
+			info.PrivateKey = []byte(cred.PrivateKey)
+			info.Passphrase = cred.Passphrase
+			info.Certificate = []byte(cred.Certificate)
 			// 如果凭证中有用户名，优先使用凭证的用户名
 			if cred.Username != "" {
 				info.Username = cred.Username
@@ -1,10 +1,13 @@
 package server
 
 import (
+	"context"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	assetbiz "github.com/ydcloud-dy/opshub/internal/biz/asset"
+	rbacService "github.com/ydcloud-dy/opshub/internal/service/rbac"
 	"github.com/ydcloud-dy/opshub/plugins/ssl-cert/service"
 )
 
@@ -137,6 +140,15 @@ func (h *DeployHandler) Delete(c *gin.Context) {
 	})
 }
 
+// deploySSHContext 记录发起部署的当前用户，主机使用CA签发的凭证时按该用户签发SSH证书
+func deploySSHContext(c *gin.Context) context.Context {
+	return assetbiz.WithSSHIdentity(c.Request.Context(), assetbiz.SSHIdentity{
+		UserID:   rbacService.GetUserID(c),
+		Username: rbacService.GetUsername(c),
+		Source:   "ssl-cert-deploy",
+	})
+}
+
 // Deploy 执行部署
 func (h *DeployHandler) Deploy(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
@@ -145,7 +157,7 @@ func (h *DeployHandler) Deploy(c *gin.Context) {
 		return
 	}
 
-	if err := h.svc.ExecuteDeploy(c.Request.Context(), uint(id)); err != nil {
+	if err := h.svc.ExecuteDeploy(deploySSHContext(c), uint(id)); err != nil {
 		c.JSON(http.StatusOK, gin.H{"code": 500, "message": err.Error()})
 		return
 	}
@@ -164,7 +176,7 @@ func (h *DeployHandler) Test(c *gin.Context) {
 		return
 	}
 
-	if err := h.svc.TestDeployConfig(c.Request.Context(), uint(id)); err != nil {
+	if err := h.svc.TestDeployConfig(deploySSHContext(c), uint(id)); err != nil {
 		c.JSON(http.StatusOK, gin.H{"code": 500, "message": err.Error()})
 		return
 	}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"time"
 
+	assetbiz "github.com/ydcloud-dy/opshub/internal/biz/asset"
 	"github.com/ydcloud-dy/opshub/pkg/logger"
 	"github.com/ydcloud-dy/opshub/plugins/ssl-cert/deployer"
 	"github.com/ydcloud-dy/opshub/plugins/ssl-cert/model"
@@ -782,8 +783,9 @@ func (s *CertificateService) finishTask(ctx context.Context, cert *model.SSLCert
 	s.certRepo.UpdateStatus(ctx, cert.ID, certStatus, errMsg)
 }
 
-// executeAutoDeploy 执行自动部署
+// executeAutoDeploy 执行自动部署，没有发起用户，主机使用CA签发的凭证时以自动化任务主体登录
 func (s *CertificateService) executeAutoDeploy(ctx context.Context, certID uint) {
+	ctx = assetbiz.WithSSHSystem(ctx, "ssl-cert-auto-deploy")
 	configs, err := s.deployRepo.ListAutoDeploy(ctx, certID)
 	if err != nil {
 		return
@@ -5,6 +5,7 @@ import (
 	"sync"
 	"time"
 
+	assetbiz "github.com/ydcloud-dy/opshub/internal/biz/asset"
 	"github.com/ydcloud-dy/opshub/pkg/logger"
 	"github.com/ydcloud-dy/opshub/plugins/ssl-cert/deployer"
 	"github.com/ydcloud-dy/opshub/plugins/ssl-cert/model"
@@ -412,8 +413,9 @@ func (s *Scheduler) finishTask(ctx context.Context, cert *model.SSLCertificate,
 	s.certRepo.UpdateStatus(ctx, cert.ID, certStatus, errMsg)
 }
 
-// executeAutoDeploy 执行自动部署
+// executeAutoDeploy 执行自动部署，没有发起用户，主机使用CA签发的凭证时以自动化任务主体登录
 func (s *Scheduler) executeAutoDeploy(ctx context.Context, certID uint) {
+	ctx = assetbiz.WithSSHSystem(ctx, "ssl-cert-auto-deploy")
 	configs, err := s.deployRepo.ListAutoDeploy(ctx, certID)
 	if err != nil {
 		logger.Error("获取自动部署配置失败", zap.Uint("cert_id", certID), zap.Error(err))
@@ -21,9 +21,6 @@ package server
 
 import (
 	"context"
-	"crypto/aes"
-	"crypto/cipher"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -35,28 +32,29 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/pkg/sftp"
-	"golang.org/x/crypto/ssh"
 	assetbiz "github.com/ydcloud-dy/opshub/internal/biz/asset"
+	assetdata "github.com/ydcloud-dy/opshub/internal/data/asset"
 	agentserver "github.com/ydcloud-dy/opshub/internal/server/agent"
+	rbacService "github.com/ydcloud-dy/opshub/internal/service/rbac"
 	pb "github.com/ydcloud-dy/opshub/pkg/agentproto"
 	"github.com/ydcloud-dy/opshub/pkg/response"
+	"github.com/ydcloud-dy/opshub/pkg/secret"
+	sshclient "github.com/ydcloud-dy/opshub/pkg/ssh"
 	"github.com/ydcloud-dy/opshub/plugins/task/model"
 	"gorm.io/gorm"
 )
 
 type Handler struct {
-	db            *gorm.DB
-	encryptionKey []byte
-	agentHub      *agentserver.AgentHub
+	db         *gorm.DB
+	agentHub   *agentserver.AgentHub
+	jumpChains *assetbiz.JumpChainUseCase
 }
 
 func NewHandler(db *gorm.DB, agentHub *agentserver.AgentHub) *Handler {
-	encryptionKey := []byte("opshub-enc-key-32-bytes-long!!!!")
 	return &Handler{
-		db:            db,
-		encryptionKey: encryptionKey,
-		agentHub:      agentHub,
+		db:         db,
+		agentHub:   agentHub,
+		jumpChains: assetbiz.NewJumpChainUseCase(assetdata.NewJumpHopRepo(db), assetdata.NewHostRepo(db), assetdata.NewAssetGroupRepo(db), assetdata.NewCredentialRepo(db)),
 	}
 }
 
@@ -559,7 +557,11 @@ func (h *Handler) ExecuteTask(c *gin.Context) {
 		return
 	}
 
-	ctx := c.Request.Context()
+	ctx := assetbiz.WithSSHIdentity(c.Request.Context(), assetbiz.SSHIdentity{
+		UserID:   rbacService.GetUserID(c),
+		Username: rbacService.GetUsername(c),
+		Source:   "task",
+	})
 
 	// 创建任务记录
 	taskName := req.Name
@@ -620,6 +622,86 @@ func (h *Handler) ExecuteTask(c *gin.Context) {
 	})
 }
 
+// ExecuteTaskStream 实时输出执行任务
+// @Summary 实时输出执行任务
+// @Description 通过WebSocket在单台主机的Agent上执行Shell命令并实时返回输出，与执行任务相同的命令安全检查和执行记录
+// @Tags 任务管理-任务执行
+// @Security Bearer
+// @Param hostId query int true "主机ID"
+// @Router /task/execute/stream [get]
+func (h *Handler) ExecuteTaskStream(c *gin.Context) {
+	hostID, err := strconv.ParseUint(c.Query("hostId"), 10, 64)
+	if err != nil {
+		response.ErrorCode(c, http.StatusBadRequest, "无效的主机ID")
+		return
+	}
+	if h.agentHub == nil {
+		response.ErrorCode(c, http.StatusServiceUnavailable, "Agent服务未启用")
+		return
+	}
+
+	var host assetbiz.Host
+	if err := h.db.Where("id = ? AND deleted_at IS NULL", hostID).First(&host).Error; err != nil {
+		response.ErrorCode(c, http.StatusNotFound, "主机不存在")
+		return
+	}
+
+	createdBy := rbacService.GetUserID(c)
+	if createdBy == 0 {
+		createdBy = 1
+	}
+
+	var jobTask model.JobTask
+	agentserver.ServeCommandStream(c, h.agentHub, host.ID, agentserver.CommandStreamHooks{
+		// 安全检查通过后创建任务记录，之后才下发命令
+		Begin: func(command string) error {
+			if err := h.checkCommandSafety(command); err != nil {
+				return err
+			}
+			hostIDsJSON, _ := json.Marshal([]uint{host.ID})
+			jobTask = model.JobTask{
+				Name:        fmt.Sprintf("实时执行任务 - %s", time.Now().Format("2006-01-02 15:04:05")),
+				TaskType:    "manual",
+				Status:      "running",
+				TargetHosts: string(hostIDsJSON),
+				Parameters:  "{}",
+				Result:      "{}",
+				CreatedBy:   createdBy,
+				ExecuteTime: ptrTime(time.Now()),
+			}
+			if err := h.db.Create(&jobTask).Error; err != nil {
+				return fmt.Errorf("创建任务记录失败: %v", err)
+			}
+			return nil
+		},
+		Finish: func(r *agentserver.CommandStreamResult) {
+			result := HostExecutionResult{
+				HostID:   host.ID,
+				HostName: host.Name,
+				HostIP:   host.IP,
+				Status:   "failed",
+				Output:   r.Output,
+			}
+			switch {
+			case r.Error != "":
+				result.Error = r.Error
+			case r.Canceled:
+				result.Error = "已取消"
+			case r.TimedOut:
+				result.Error = "执行超时"
+			case r.ExitCode != 0:
+				result.Error = fmt.Sprintf("退出码: %d", r.ExitCode)
+			default:
+				result.Status = "success"
+			}
+			resultJSON, _ := json.Marshal([]HostExecutionResult{result})
+			jobTask.Status = result.Status
+			jobTask.Result = string(resultJSON)
+			h.db.Save(&jobTask)
+		},
+	})
+}
+
 // checkCommandSafety 检查命令安全性
 func (h *Handler) checkCommandSafety(content string) error {
 	// 转换为小写以便不区分大小写检查
@@ -1072,13 +1154,13 @@ func (h *Handler) executeOnHostViaSSH(ctx context.Context, host *assetbiz.Host,
 	}
 
 	// 解密凭证
-	if err := h.decryptCredential(&credential); err != nil {
+	if err := h.decryptCredential(ctx, &credential); err != nil {
 		result.Error = fmt.Sprintf("解密凭证失败: %v", err)
 		return result
 	}
 
 	// 建立SSH连接
-	sshClient, err := h.createSSHClient(host, &credential)
+	sshClient, err := h.createSSHClient(ctx, host, &credential)
 	if err != nil {
 		result.Error = fmt.Sprintf("SSH连接失败: %v", err)
 		return result
@@ -1115,98 +1197,29 @@ func (h *Handler) executeOnHostViaSSH(ctx context.Context, host *assetbiz.Host,
 	return result
 }
 
-// createSSHClient 创建SSH客户端
-func (h *Handler) createSSHClient(host *assetbiz.Host, credential *assetbiz.Credential) (*ssh.Client, error) {
-	var authMethods []ssh.AuthMethod
-
-	// 根据凭证类型选择认证方式
-	switch credential.Type {
-	case "password":
-		authMethods = append(authMethods, ssh.Password(credential.Password))
-	case "private_key":
-		signer, err := ssh.ParsePrivateKey([]byte(credential.PrivateKey))
-		if err != nil {
-			return nil, fmt.Errorf("解析私钥失败: %w", err)
-		}
-		authMethods = append(authMethods, ssh.PublicKeys(signer))
-	default:
-		return nil, fmt.Errorf("不支持的凭证类型: %s", credential.Type)
-	}
-
-	// SSH 配置
-	config := &ssh.ClientConfig{
-		User:            credential.Username,
-		Auth:            authMethods,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(), // 生产环境应该验证host key
-		Timeout:         30 * time.Second,
-	}
-
-	// 连接
-	addr := fmt.Sprintf("%s:%d", host.IP, host.Port)
-	client, err := ssh.Dial("tcp", addr, config)
-	if err != nil {
-		return nil, err
-	}
-
-	return client, nil
-}
-
-// decryptCredential 解密凭证
-func (h *Handler) decryptCredential(credential *assetbiz.Credential) error {
-	// 解密密码
-	if credential.Password != "" {
-		decrypted, err := h.decrypt(credential.Password)
-		if err != nil {
-			return fmt.Errorf("解密密码失败: %w", err)
-		}
-		credential.Password = decrypted
-	}
-
-	// 解密私钥
-	if credential.PrivateKey != "" {
-		decrypted, err := h.decrypt(credential.PrivateKey)
-		if err != nil {
-			return fmt.Errorf("解密私钥失败: %w", err)
-		}
-		credential.PrivateKey = decrypted
+// createSSHClient 创建SSH客户端，主机或所属分组配置了跳板链时经跳板连接
+func (h *Handler) createSSHClient(ctx context.Context, host *assetbiz.Host, credential *assetbiz.Credential) (*sshclient.Client, error) {
+	target := *host
+	if credential.Username != "" {
+		target.SSHUser = credential.Username
 	}
-
-	return nil
+	return h.jumpChains.Connect(ctx, &target, credential)
 }
 
-// decrypt 解密
-func (h *Handler) decrypt(ciphertext string) (string, error) {
-	if ciphertext == "" {
-		return "", nil
+// decryptCredential 从凭证存储后端取回密码和私钥，CA签发的凭证为本次执行签发临时证书
+func (h *Handler) decryptCredential(ctx context.Context, credential *assetbiz.Credential) error {
+	if credential.Type == assetbiz.CredentialTypeCA {
+		return assetbiz.IssueCertificate(ctx, credential)
 	}
-
-	data, err := base64.StdEncoding.DecodeString(ciphertext)
-	if err != nil {
-		return "", err
+	store := secret.Default()
+	var err error
+	if credential.Password, err = store.Get(ctx, credential.Password); err != nil {
+		return fmt.Errorf("解密密码失败: %w", err)
 	}
-
-	block, err := aes.NewCipher(h.encryptionKey)
-	if err != nil {
-		return "", err
-	}
-
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return "", err
-	}
-
-	nonceSize := gcm.NonceSize()
-	if len(data) < nonceSize {
-		return "", fmt.Errorf("ciphertext too short")
-	}
-
-	nonce, cipherData := data[:nonceSize], data[nonceSize:]
-	plaintext, err := gcm.Open(nil, nonce, cipherData, nil)
-	if err != nil {
-		return "", err
+	if credential.PrivateKey, err = store.Get(ctx, credential.PrivateKey); err != nil {
+		return fmt.Errorf("解密私钥失败: %w", err)
 	}
-
-	return string(plaintext), nil
+	return nil
 }
 
 // shellescape 转义shell命令
@@ -1675,7 +1688,11 @@ func (h *Handler) DistributeFiles(c *gin.Context) {
 		}
 	}
 
-	ctx := c.Request.Context()
+	ctx := assetbiz.WithSSHIdentity(c.Request.Context(), assetbiz.SSHIdentity{
+		UserID:   rbacService.GetUserID(c),
+		Username: rbacService.GetUsername(c),
+		Source:   "task",
+	})
 
 	// 创建任务记录
 	fileNames := make([]string, 0, len(files))
@@ -1767,15 +1784,14 @@ func (h *Handler) distributeToHost(ctx context.Context, hostID uint, files []*mu
 	}
 
 	if useAgent {
-		return h.distributeToHostViaAgent(hostID, files, targetPath, result)
+		return h.distributeToHostViaAgent(ctx, hostID, files, targetPath, result)
 	}
 	return h.distributeToHostViaSSH(ctx, &host, files, targetPath, result)
 }
 
-// distributeToHostViaAgent 通过Agent分发文件
-func (h *Handler) distributeToHostViaAgent(hostID uint, files []*multipart.FileHeader, targetPath string, result FileDistributionResult) FileDistributionResult {
-	as, ok := h.agentHub.GetByHostID(hostID)
-	if !ok {
+// distributeToHostViaAgent 通过Agent分发文件，分片传输并校验 SHA-256，Agent 断线重连后续传
+func (h *Handler) distributeToHostViaAgent(ctx context.Context, hostID uint, files []*multipart.FileHeader, targetPath string, result FileDistributionResult) FileDistributionResult {
+	if !h.agentHub.IsOnline(hostID) {
 		result.Error = "Agent不在线"
 		return result
 	}
@@ -1786,39 +1802,13 @@ func (h *Handler) distributeToHostViaAgent(hostID uint, files []*multipart.FileH
 			result.Error = fmt.Sprintf("打开文件 %s 失败: %v", fileHeader.Filename, err)
 			return result
 		}
-		data, err := io.ReadAll(srcFile)
-		srcFile.Close()
-		if err != nil {
-			result.Error = fmt.Sprintf("读取文件 %s 失败: %v", fileHeader.Filename, err)
-			return result
-		}
-
-		requestID := uuid.New().String()
-		as.Send(&pb.ServerMessage{
-			Payload: &pb.ServerMessage_FileRequest{
-				FileRequest: &pb.FileRequest{
-					RequestId: requestID,
-					Action:    "upload",
-					Path:      targetPath,
-					Filename:  fileHeader.Filename,
-					Data:      data,
-				},
-			},
+		err = h.agentHub.UploadFile(ctx, hostID, srcFile, fileHeader.Size, agentserver.UploadOptions{
+			Dir:      targetPath,
+			Filename: fileHeader.Filename,
 		})
-
-		resp, err := h.agentHub.WaitResponse(as, requestID, 60*time.Second)
+		srcFile.Close()
 		if err != nil {
-			result.Error = fmt.Sprintf("Agent上传文件 %s 超时: %v", fileHeader.Filename, err)
-			return result
-		}
-
-		fileResp, ok := resp.(*pb.FileChunk)
-		if !ok {
-			result.Error = fmt.Sprintf("Agent返回非预期响应类型")
-			return result
-		}
-		if fileResp.Error != "" {
-			result.Error = fmt.Sprintf("Agent上传文件 %s 失败: %s", fileHeader.Filename, fileResp.Error)
+			result.Error = fmt.Sprintf("Agent上传文件 %s 失败: %v", fileHeader.Filename, err)
 			return result
 		}
 	}
@@ -1843,13 +1833,13 @@ func (h *Handler) distributeToHostViaSSH(ctx context.Context, host *assetbiz.Hos
 	}
 
 	// 解密凭证
-	if err := h.decryptCredential(&credential); err != nil {
+	if err := h.decryptCredential(ctx, &credential); err != nil {
 		result.Error = fmt.Sprintf("解密凭证失败: %v", err)
 		return result
 	}
 
 	// 建立SSH连接
-	sshClient, err := h.createSSHClient(host, &credential)
+	sshClient, err := h.createSSHClient(ctx, host, &credential)
 	if err != nil {
 		result.Error = fmt.Sprintf("SSH连接失败: %v", err)
 		return result
@@ -1857,7 +1847,7 @@ func (h *Handler) distributeToHostViaSSH(ctx context.Context, host *assetbiz.Hos
 	defer sshClient.Close()
 
 	// 创建SFTP客户端
-	sftpClient, err := sftp.NewClient(sshClient)
+	sftpClient, err := sshClient.NewSFTPClient()
 	if err != nil {
 		result.Error = fmt.Sprintf("创建SFTP客户端失败: %v", err)
 		return result
@@ -34,6 +34,7 @@ func RegisterRoutes(router *gin.RouterGroup, db *gorm.DB, agentHub *agentserver.
 	{
 		// 任务执行
 		taskGroup.POST("/execute", handler.ExecuteTask)
+		taskGroup.GET("/execute/stream", handler.ExecuteTaskStream)
 
 		// 文件分发
 		taskGroup.POST("/distribute", handler.DistributeFiles)
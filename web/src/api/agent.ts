@@ -21,6 +21,86 @@ export const updateAgent = (hostId: number, serverAddr?: string) => {
   return request.put(`/api/v1/agents/${hostId}/update`, { serverAddr })
 }
 
+// 通过Agent通道自升级（无需SSH，失败时Agent自动回滚）；安装包版本较低时需 allowDowngrade 明确回退
+export const upgradeAgentViaChannel = (hostId: number, rollbackTimeout?: number, allowDowngrade?: boolean) => {
+  return request.post(`/api/v1/agents/${hostId}/upgrade`, { rollbackTimeout, allowDowngrade })
+}
+
+export const getUpgradePackage = () => {
+  return request.get('/api/v1/agents/upgrade/package')
+}
+
+// Agent分批升级
+export interface UpgradeRolloutRequest {
+  groupIds?: number[]
+  hostIds?: number[]
+  stages?: number[]
+  maxFailureRate?: number
+  concurrency?: number
+  rollbackTimeout?: number
+  stageInterval?: number
+}
+
+export const listUpgradeRollouts = (params?: { page?: number; pageSize?: number }) => {
+  return request.get('/api/v1/agents/upgrade/rollouts', { params })
+}
+
+export const createUpgradeRollout = (data: UpgradeRolloutRequest) => {
+  return request.post('/api/v1/agents/upgrade/rollouts', data)
+}
+
+export const getUpgradeRollout = (id: number) => {
+  return request.get(`/api/v1/agents/upgrade/rollouts/${id}`)
+}
+
+export const resumeUpgradeRollout = (id: number) => {
+  return request.post(`/api/v1/agents/upgrade/rollouts/${id}/resume`)
+}
+
+export const cancelUpgradeRollout = (id: number) => {
+  return request.post(`/api/v1/agents/upgrade/rollouts/${id}/cancel`)
+}
+
+// Agent本地策略拒绝记录
+export const listAgentPolicyViolations = (params?: { hostId?: number; kind?: 'command' | 'file' | 'terminal' | 'tunnel'; page?: number; pageSize?: number }) => {
+  return request.get('/api/v1/agents/policy-violations', { params })
+}
+
+// Agent服务发现：发现的服务及中间件/Exporter导入建议
+export const getAgentDiscovery = (hostId: number) => {
+  return request.get(`/api/v1/agents/${hostId}/discovery`)
+}
+
+export const importAgentDiscovery = (hostId: number, serviceIds: number[]) => {
+  return request.post(`/api/v1/agents/${hostId}/discovery/import`, { serviceIds })
+}
+
+export const rescanAgentDiscovery = (hostId: number) => {
+  return request.post(`/api/v1/agents/${hostId}/discovery/rescan`)
+}
+
+// Agent端口转发：服务端监听本地端口，经Agent转发到目标地址（target 可只填端口）
+export const openAgentTunnel = (hostId: number, target: string, ttlMinutes?: number) => {
+  return request.post(`/api/v1/agents/${hostId}/tunnels`, { target, ttlMinutes })
+}
+
+export const listAgentTunnels = (params?: { hostId?: number; userId?: number; status?: 'active' | 'closed' | 'expired'; page?: number; pageSize?: number }) => {
+  return request.get('/api/v1/agents/tunnels', { params })
+}
+
+export const closeAgentTunnel = (hostId: number, sessionId: string) => {
+  return request.delete(`/api/v1/agents/${hostId}/tunnels/${sessionId}`)
+}
+
+// Agent证书签发记录，吊销后立即断开连接，需重新部署才能接入
+export const listAgentCerts = (hostId: number) => {
+  return request.get(`/api/v1/agents/${hostId}/certs`)
+}
+
+export const revokeAgentCert = (hostId: number, reason?: string) => {
+  return request.post(`/api/v1/agents/${hostId}/certs/revoke`, { reason })
+}
+
 export const uninstallAgent = (hostId: number) => {
   return request.delete(`/api/v1/agents/${hostId}/uninstall`)
 }
@@ -52,6 +132,24 @@ export const executeAgentCommand = (hostId: number, command: string, timeout?: n
   return request.post(`/api/v1/agents/${hostId}/execute`, { command, timeout })
 }
 
+// Agent流式命令执行（WebSocket）
+// 连接后发送 { command, timeout, maxOutputBytes }，可发送 { type: 'cancel' } 取消；
+// 服务端推送 started / output / exit / error 消息
+export interface AgentCommandStreamEvent {
+  type: 'started' | 'output' | 'exit' | 'error'
+  requestId?: string
+  seq?: number
+  stream?: 'stdout' | 'stderr'
+  data?: string
+  exitCode?: number
+  signal?: string
+  error?: string
+  truncated?: boolean
+  canceled?: boolean
+  timedOut?: boolean
+  durationMs?: number
+}
+
 // 生成Agent安装包（手动部署）
 export const generateInstallPackage = (serverAddr: string) => {
   return request.post('/api/v1/agents/generate-install', { serverAddr })
@@ -4,7 +4,7 @@ import request from '@/utils/request'
 export interface AlertDataSource {
   id?: number
   name: string
-  type: string // prometheus | victoriametrics | influxdb
+  type: string // prometheus | victoriametrics | influxdb | loki | elasticsearch | clickhouse
   url?: string
   host?: string
   port?: number
@@ -86,6 +86,7 @@ export interface AlertRule {
   conditions?: string          // 阈值条件 JSON（新格式）
   evalInterval?: number // 秒，默认15
   duration?: string    // e.g. "5m"
+  keepFiringFor?: string // 条件不满足后保持触发时长 e.g. "5m"
   severity?: string    // critical | warning | info
   labels?: string      // JSON
   annotations?: string // JSON {title, description}
@@ -121,6 +122,19 @@ export const exportRules = (ids?: number[], format = 'json') => {
 export const adhocTestRule = (data: { dataSourceIds: number[], expr: string }) =>
   request.post('/api/v1/alert/rules/adhoc-test', data)
 
+// 规则单元测试（promtool test rules 风格），ruleId 为空时测试文件中定义的 rule
+export interface RuleTestCaseResult {
+  name: string
+  passed: boolean
+  failures?: string[]
+}
+export interface RuleTestResult {
+  passed: boolean
+  cases: RuleTestCaseResult[]
+}
+export const unitTestRule = (data: { ruleId?: number, content: string }) =>
+  request.post('/api/v1/alert/rules/unit-test', data)
+
 export const importRules = (file: File) => {
   const form = new FormData()
   form.append('file', file)
@@ -128,6 +142,28 @@ export const importRules = (file: File) => {
   return request.post('/api/v1/alert/rules/import', form)
 }
 
+// Prometheus 规则文件导入导出（按规则分类）
+export const previewPromRules = (ruleGroupId: number, file: File) => {
+  const form = new FormData()
+  form.append('file', file)
+  return request.post(`/api/v1/alert/rule-groups/${ruleGroupId}/prometheus/preview`, form)
+}
+export const importPromRules = (
+  ruleGroupId: number,
+  file: File,
+  opts: { onConflict?: 'skip' | 'overwrite'; dataSourceIds?: number[]; enabled?: boolean } = {}
+) => {
+  const form = new FormData()
+  form.append('file', file)
+  const params = new URLSearchParams()
+  params.set('onConflict', opts.onConflict || 'skip')
+  if (opts.dataSourceIds?.length) params.set('dataSourceIds', opts.dataSourceIds.join(','))
+  if (opts.enabled) params.set('enabled', 'true')
+  return request.post(`/api/v1/alert/rule-groups/${ruleGroupId}/prometheus/import?${params.toString()}`, form)
+}
+export const exportPromRules = (ruleGroupId: number) =>
+  request.get(`/api/v1/alert/rule-groups/${ruleGroupId}/prometheus/export`, { responseType: 'blob' })
+
 // ==================== 告警事件 ====================
 export interface AlertEvent {
   id?: number
@@ -151,6 +187,64 @@ export interface AlertEvent {
   handledBy?: number
   handledAt?: string
   handledNote?: string
+  acknowledged?: boolean
+  ackedBy?: number
+  ackedAt?: string
+  assigneeId?: number
+  assignedAt?: string
+  deliveries?: AlertNotifyDelivery[]
+  escalations?: AlertEventEscalation[]
+  timeline?: AlertEventTimeline[]
+}
+
+// 告警处理时间线
+export interface AlertEventTimeline {
+  id: number
+  createdAt: string
+  eventId: number
+  action: string // ack | unack | claim | assign | silence | resolve | handle | escalate
+  source: string // web | link | dingtalk | wechat_work | system
+  operatorId: number
+  operator: string
+  assigneeId: number
+  note?: string
+}
+
+// 通知投递记录
+export interface AlertNotifyDelivery {
+  id: number
+  createdAt: string
+  eventId: number
+  subscriptionId: number
+  subscriptionLogId: number
+  channelId: number
+  channelName: string
+  channelType: string
+  isResolve: boolean
+  status: string // pending | sent | failed | dead
+  attempts: number
+  maxAttempts: number
+  lastError?: string
+  result?: string
+  nextRetryAt?: string
+  sentAt?: string
+}
+
+export interface AlertNotifyDeadLetter {
+  id: number
+  createdAt: string
+  deliveryId: number
+  eventId: number
+  ruleName: string
+  channelId: number
+  channelName: string
+  channelType: string
+  isResolve: boolean
+  attempts: number
+  lastError: string
+  resent: boolean
+  resentAt?: string
+  resentBy?: number
 }
 
 export interface EventListParams {
@@ -172,6 +266,17 @@ export const silenceEvent = (id: number, data: { duration: string; reason?: stri
   request.post(`/api/v1/alert/events/${id}/silence`, data)
 export const handleEvent = (id: number, data: { note: string; userId?: number }) =>
   request.post(`/api/v1/alert/events/${id}/handle`, data)
+export const getEvent = (id: number) => request.get(`/api/v1/alert/events/${id}`)
+export const ackEvent = (id: number, note?: string) => request.post(`/api/v1/alert/events/${id}/ack`, { note })
+export const unackEvent = (id: number, note?: string) => request.post(`/api/v1/alert/events/${id}/unack`, { note })
+export const claimEvent = (id: number, note?: string) => request.post(`/api/v1/alert/events/${id}/claim`, { note })
+export const assignEvent = (id: number, data: { assigneeId: number; note?: string }) =>
+  request.post(`/api/v1/alert/events/${id}/assign`, data)
+export const resolveEvent = (id: number, note?: string) => request.post(`/api/v1/alert/events/${id}/resolve`, { note })
+export const getEventTimeline = (id: number) => request.get(`/api/v1/alert/events/${id}/timeline`)
+export const getDeadLetters = (params?: { page?: number; pageSize?: number; eventId?: number; resent?: boolean }) =>
+  request.get('/api/v1/alert/dead-letters', { params })
+export const resendDeadLetter = (id: number) => request.post(`/api/v1/alert/dead-letters/${id}/resend`)
 export const getEventStats = (days = 7) => request.get('/api/v1/alert/events/stats', { params: { days } })
 export const getEventTrend = (days = 30) => request.get('/api/v1/alert/events/trend', { params: { days } })
 
@@ -225,6 +330,19 @@ export const deleteSilenceRule = (id: number) =>
 export const toggleSilenceRule = (id: number) =>
   request.put(`/api/v1/alert/silence-rules/${id}/toggle`)
 
+// ==================== 标签匹配器 ====================
+export interface LabelMatcherPreview {
+  matchers: { key: string; op: string; value: string }[]
+  selector: string
+  total: number
+  firingTotal: number
+  events: AlertEvent[]
+}
+
+// 预览匹配器命中的 firing 告警，matchers 支持 JSON 对象/数组或 {k=~"v"} 选择器文本
+export const previewLabelMatchers = (matchers: string | object) =>
+  request.post('/api/v1/alert/label-matchers/preview', { matchers })
+
 
 // ==================== 通知通道 ====================
 export interface AlertNotifyChannel {
@@ -243,7 +361,7 @@ export const createChannel = (data: Partial<AlertNotifyChannel>) => request.post
 export const getChannel = (id: number) => request.get(`/api/v1/alert/channels/${id}`)
 export const updateChannel = (id: number, data: Partial<AlertNotifyChannel>) => request.put(`/api/v1/alert/channels/${id}`, data)
 export const deleteChannel = (id: number) => request.delete(`/api/v1/alert/channels/${id}`)
-export const testChannel = (id: number) => request.post(`/api/v1/alert/channels/${id}/test`)
+export const testChannel = (id: number, phones?: string) => request.post(`/api/v1/alert/channels/${id}/test`, null, { params: phones ? { phones } : undefined })
 
 // ==================== 告警订阅 ====================
 export interface TimeRange {
@@ -266,6 +384,8 @@ export interface AlertSubscription {
   rules?: SubscriptionRuleItem[]
   channelIds?: number[]
   userIds?: number[]
+  scheduleId?: number // 值班表，>0 时通知当前值班人
+  escalationPolicyId?: number // 升级策略
   ruleCount?: number
   channelCount?: number
 }
@@ -279,3 +399,107 @@ export const updateSubscription = (id: number, data: Partial<AlertSubscription>)
 export const toggleSubscription = (id: number, enabled: boolean) =>
   request.patch(`/api/v1/alert/subscriptions/${id}/toggle`, { enabled })
 export const deleteSubscription = (id: number) => request.delete(`/api/v1/alert/subscriptions/${id}`)
+
+// ==================== 值班表 ====================
+export interface OnCallLayer {
+  name: string
+  rotationType: string // daily | weekly
+  interval?: number // 每班天数/周数
+  start: string // "2026-01-05 09:00"，决定交接时刻
+  userIds: number[]
+}
+
+export interface AlertOnCallSchedule {
+  id?: number
+  name: string
+  description?: string
+  timezone?: string
+  layers: string // JSON: OnCallLayer[]
+}
+
+export interface AlertOnCallOverride {
+  id?: number
+  scheduleId?: number
+  userId: number
+  startAt: string
+  endAt: string
+  reason?: string
+}
+
+export interface OnCallShift {
+  start: string
+  end: string
+  userId: number
+  layer: string
+  override: boolean
+}
+
+export const getOnCallSchedules = () => request.get('/api/v1/alert/oncall-schedules')
+export const createOnCallSchedule = (data: Partial<AlertOnCallSchedule>) => request.post('/api/v1/alert/oncall-schedules', data)
+export const updateOnCallSchedule = (id: number, data: Partial<AlertOnCallSchedule>) =>
+  request.put(`/api/v1/alert/oncall-schedules/${id}`, data)
+export const deleteOnCallSchedule = (id: number) => request.delete(`/api/v1/alert/oncall-schedules/${id}`)
+export const getOnCallCurrent = (id: number, at?: string) =>
+  request.get(`/api/v1/alert/oncall-schedules/${id}/current`, { params: at ? { at } : undefined })
+export const getOnCallShifts = (id: number, params?: { from?: string; to?: string }) =>
+  request.get(`/api/v1/alert/oncall-schedules/${id}/shifts`, { params })
+export const getOnCallOverrides = (id: number) => request.get(`/api/v1/alert/oncall-schedules/${id}/overrides`)
+export const createOnCallOverride = (id: number, data: AlertOnCallOverride) =>
+  request.post(`/api/v1/alert/oncall-schedules/${id}/overrides`, data)
+export const deleteOnCallOverride = (id: number) => request.delete(`/api/v1/alert/oncall-schedules/overrides/${id}`)
+
+// ==================== 升级策略 ====================
+export interface EscalationStep {
+  delayMinutes: number // 距上一次通知的等待分钟数
+  scheduleId?: number
+  userIds?: number[]
+  channelIds?: number[] // 为空沿用首次通知通道
+}
+
+export interface AlertEscalationPolicy {
+  id?: number
+  name: string
+  description?: string
+  enabled?: boolean
+  steps: string // JSON: EscalationStep[]
+}
+
+export interface AlertEventEscalation {
+  id: number
+  eventId: number
+  subscriptionId: number
+  policyId: number
+  step: number
+  status: string // active | acknowledged | resolved | completed
+  nextAt?: string
+  lastNotifiedAt?: string
+}
+
+export const getEscalationPolicies = () => request.get('/api/v1/alert/escalation-policies')
+export const createEscalationPolicy = (data: Partial<AlertEscalationPolicy>) => request.post('/api/v1/alert/escalation-policies', data)
+export const updateEscalationPolicy = (id: number, data: Partial<AlertEscalationPolicy>) =>
+  request.put(`/api/v1/alert/escalation-policies/${id}`, data)
+export const deleteEscalationPolicy = (id: number) => request.delete(`/api/v1/alert/escalation-policies/${id}`)
+
+// ==================== 外部告警接收器 ====================
+export interface AlertReceiver {
+  id?: number
+  name: string
+  type: string // alertmanager | generic
+  token?: string // 接收地址 /api/v1/alert/receivers/{token}
+  assetGroupId?: number
+  enabled?: boolean
+  notifyOnResolve?: boolean
+  defaultSeverity?: string
+  labels?: string // JSON 附加标签
+  fieldMapping?: string // 通用 JSON 字段映射
+  description?: string
+  lastReceivedAt?: string
+}
+
+export const getReceivers = () => request.get('/api/v1/alert/receivers')
+export const createReceiver = (data: Partial<AlertReceiver>) => request.post('/api/v1/alert/receivers', data)
+export const getReceiver = (id: number) => request.get(`/api/v1/alert/receivers/${id}`)
+export const updateReceiver = (id: number, data: Partial<AlertReceiver>) => request.put(`/api/v1/alert/receivers/${id}`, data)
+export const deleteReceiver = (id: number) => request.delete(`/api/v1/alert/receivers/${id}`)
+export const resetReceiverToken = (id: number) => request.put(`/api/v1/alert/receivers/${id}/token`)
@@ -29,3 +29,12 @@ export const deleteGroup = (id: number) => {
 export const getParentOptions = () => {
   return request.get('/api/v1/asset-groups/parent-options')
 }
+
+// 跳板链（对分组及下级分组中未单独配置的主机生效）
+export const getGroupJumpChain = (id: number) => {
+  return request.get(`/api/v1/asset-groups/${id}/jump-chain`)
+}
+
+export const updateGroupJumpChain = (id: number, hops: any[]) => {
+  return request.put(`/api/v1/asset-groups/${id}/jump-chain`, { hops })
+}
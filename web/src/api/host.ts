@@ -83,6 +83,11 @@ export const getCloudRegions = (accountId: number) => {
   return request.get(`/api/v1/cloud-accounts/${accountId}/regions`)
 }
 
+// 同步云账号的主机清单
+export const syncCloudAccount = (accountId: number) => {
+  return request.post(`/api/v1/cloud-accounts/${accountId}/sync`)
+}
+
 // 采集主机信息
 export const collectHostInfo = (id: number) => {
   return request.post(`/api/v1/hosts/${id}/collect`)
@@ -153,3 +158,63 @@ export const getHostStatistics = (params?: {
 }) => {
   return request.get('/api/v1/hosts/statistics', { params })
 }
+
+// 主机SSH密钥
+export const getHostKey = (id: number) => {
+  return request.get(`/api/v1/hosts/${id}/host-key`)
+}
+
+export const listHostKeyAlerts = (params?: { page?: number; pageSize?: number; hostId?: number; status?: string }) => {
+  return request.get('/api/v1/hosts/host-key-alerts', { params })
+}
+
+export const retrustHostKey = (alertId: number, fingerprint: string) => {
+  return request.post(`/api/v1/hosts/host-key-alerts/${alertId}/retrust`, { fingerprint })
+}
+
+// 升级后批量信任尚未记录密钥的现有主机
+export const trustExistingHostKeys = () => {
+  return request.post('/api/v1/hosts/host-keys/trust-existing')
+}
+
+// 主机跳板链
+export interface JumpHop {
+  ip: string
+  port?: number
+  sshUser: string
+  credentialId: number
+}
+
+export const getHostJumpChain = (id: number) => {
+  return request.get(`/api/v1/hosts/${id}/jump-chain`)
+}
+
+export const updateHostJumpChain = (id: number, hops: JumpHop[]) => {
+  return request.put(`/api/v1/hosts/${id}/jump-chain`, { hops })
+}
+
+// SSH CA：为会话签发短期用户证书
+export interface SSHCAAccount {
+  account: string
+  principals: string[]
+}
+
+export const getSSHCA = () => {
+  return request.get('/api/v1/ssh-ca')
+}
+
+export const generateSSHCA = () => {
+  return request.post('/api/v1/ssh-ca/generate')
+}
+
+export const updateSSHCA = (certTtl: number) => {
+  return request.put('/api/v1/ssh-ca', { certTtl })
+}
+
+export const downloadSSHCAInstallScript = (accounts?: SSHCAAccount[]) => {
+  return request.post('/api/v1/ssh-ca/install-script', { accounts }, { responseType: 'blob' })
+}
+
+export const installSSHCA = (hostIds: number[], accounts?: SSHCAAccount[]) => {
+  return request.post('/api/v1/ssh-ca/install', { hostIds, accounts })
+}
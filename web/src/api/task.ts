@@ -27,6 +27,15 @@ export const executeTask = (data: ExecuteTaskRequest) => {
   return request.post<any, ExecuteTaskResponse>('/api/v1/plugins/task/execute', data)
 }
 
+// 实时输出执行：经任务中心的命令安全检查并记录执行历史，事件格式见 AgentCommandStreamEvent
+export const executeTaskStreamURL = (hostId: number) => {
+  const token = localStorage.getItem('srehubtoken') || ''
+  const isDev = window.location.hostname === 'localhost' || window.location.hostname === '127.0.0.1'
+  const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:'
+  const port = isDev ? ':9876' : (window.location.port ? ':' + window.location.port : '')
+  return `${protocol}//${window.location.hostname}${port}/api/v1/plugins/task/execute/stream?hostId=${hostId}&token=${token}`
+}
+
 // ==================== 任务作业 ====================
 
 export interface JobTask {
@@ -295,6 +295,12 @@ const router = createRouter({
           name: 'AlertMgmtSubscriptions',
           component: () => import('@/views/alert/Subscriptions.vue'),
           meta: { title: '告警订阅' }
+        },
+        {
+          path: 'alert/oncall',
+          name: 'AlertMgmtOnCall',
+          component: () => import('@/views/alert/OnCall.vue'),
+          meta: { title: '值班与升级' }
         }
       ]
     }
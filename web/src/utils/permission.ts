@@ -6,7 +6,8 @@ export const PERMISSION = {
   TERMINAL: 1 << 3, // 8 - 终端
   FILE: 1 << 4,     // 16 - 文件管理
   COLLECT: 1 << 5,  // 32 - 采集信息
-  ALL: 0x3F,        // 63 - 所有权限
+  TUNNEL: 1 << 6,   // 64 - 端口转发
+  ALL: 0x7F,        // 127 - 所有权限
 } as const
 
 /**
@@ -38,6 +39,8 @@ export function getPermissionName(permission: number): string {
       return '文件管理'
     case PERMISSION.COLLECT:
       return '采集信息'
+    case PERMISSION.TUNNEL:
+      return '端口转发'
     default:
       return '未知'
   }
@@ -56,6 +59,7 @@ export function getPermissionNames(permissions: number): string[] {
   if ((permissions & PERMISSION.TERMINAL) > 0) names.push('终端')
   if ((permissions & PERMISSION.FILE) > 0) names.push('文件管理')
   if ((permissions & PERMISSION.COLLECT) > 0) names.push('采集信息')
+  if ((permissions & PERMISSION.TUNNEL) > 0) names.push('端口转发')
   return names
 }
 